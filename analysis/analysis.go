@@ -0,0 +1,405 @@
+// Package analysis computes aggregate reports from the telemetry files of
+// many games, so the performance of a robot can be measured across a
+// tournament instead of a single match.
+//
+// The per-game statistics are computed with the stats package, replaying the
+// recorded messages and commands. On top of them, a Report includes an
+// estimation of the hit rate, the distribution of the survival time, the
+// damage taken by source and a heatmap of the positions of the robot.
+package analysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/stats"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+// Config is the configuration of an Analyzer.
+type Config struct {
+	// SurvivalBin is the width of the bins of the survival time
+	// histogram, in seconds of game time. If zero, 10 is used.
+	SurvivalBin float64
+
+	// CellSize is the size of the cells of the heatmap. If zero, 1 is
+	// used.
+	CellSize float64
+
+	// HitWindow is the game time after a shot during which a drop in the
+	// energy of the robots detected by the radar is counted as a hit. If
+	// zero, 2 is used.
+	HitWindow float64
+}
+
+// Game are the statistics of a game.
+type Game struct {
+	// File is the name of the telemetry file of the game.
+	File string
+
+	// Stats are the statistics computed by the stats package.
+	Stats stats.Stats
+
+	// HitsEstimated is the estimated number of shots that hit a robot.
+	HitsEstimated int
+}
+
+// Report is the aggregate report of a set of games.
+type Report struct {
+	// Games are the statistics of every game, in the order they were
+	// added.
+	Games []Game
+
+	// ShotsFired is the total number of shots fired.
+	ShotsFired int
+
+	// HitsEstimated is the total number of estimated hits.
+	HitsEstimated int
+
+	// HitRate is HitsEstimated divided by ShotsFired. It is zero if no
+	// shot was fired.
+	HitRate float64
+
+	// Survival is the distribution of the survival time.
+	Survival Survival
+
+	// DamageTaken is the total energy lost by source. The keys are the
+	// names of the objects, e.g. "Shot". Losses that cannot be attributed
+	// to a collision are accounted to "NoObject".
+	DamageTaken map[string]float64
+
+	// Heatmap is the number of positions reported in every cell of the
+	// arena.
+	Heatmap Heatmap
+}
+
+// Survival is the distribution of the survival time.
+type Survival struct {
+	// Mean, Median, Min and Max are the statistics of the survival time
+	// of the games.
+	Mean, Median, Min, Max float64
+
+	// Deaths is the number of games in which the robot died.
+	Deaths int
+
+	// Bins is the histogram of the survival time.
+	Bins []Bin
+}
+
+// Bin is a bin of a histogram.
+type Bin struct {
+	// From and To are the limits of the bin. From is included and To is
+	// excluded.
+	From, To float64
+
+	// Count is the number of values in the bin.
+	Count int
+}
+
+// Heatmap is the number of positions of the robot reported in every cell of
+// the arena. It is only available if the server sends the coordinates of the
+// robot (game option SendRobotCoordinates).
+type Heatmap struct {
+	// CellSize is the size of the cells.
+	CellSize float64
+
+	// Cells are the cells with at least one position, sorted by Y and X.
+	Cells []Cell
+}
+
+// Cell is a cell of a heatmap.
+type Cell struct {
+	// X and Y are the indexes of the cell. The cell covers the positions
+	// from X*CellSize to (X+1)*CellSize, and the same for Y.
+	X, Y int
+
+	// Count is the number of positions in the cell.
+	Count int
+}
+
+// Analyzer computes reports from telemetry records.
+type Analyzer struct {
+	cfg   Config
+	games []Game
+	cells map[[2]int]int
+}
+
+// New returns an Analyzer with the given configuration.
+func New(cfg Config) *Analyzer {
+	if cfg.SurvivalBin == 0 {
+		cfg.SurvivalBin = 10
+	}
+	if cfg.CellSize == 0 {
+		cfg.CellSize = 1
+	}
+	if cfg.HitWindow == 0 {
+		cfg.HitWindow = 2
+	}
+	return &Analyzer{cfg: cfg, cells: map[[2]int]int{}}
+}
+
+// AddFile adds the games of the telemetry file at path.
+func (a *Analyzer) AddFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open telemetry: %v", err)
+	}
+	defer f.Close()
+
+	recs, err := telemetry.Read(f)
+	if err != nil {
+		return fmt.Errorf("could not read %v: %v", path, err)
+	}
+	return a.Add(path, recs)
+}
+
+// Add adds the games of the records recs, read from the file name. Games
+// that are not finished are ignored.
+func (a *Analyzer) Add(name string, recs []telemetry.Record) error {
+	c := stats.New(stats.Config{})
+	var (
+		hits    []int
+		h       hitEstimator
+		started bool
+		cells   = map[[2]int]int{}
+	)
+	for i, rec := range recs {
+		switch rec.Kind {
+		case telemetry.KindMessage:
+			msg, err := rec.Message()
+			if err != nil {
+				return fmt.Errorf("could not parse record %v of %v: %v", i, name, err)
+			}
+			c.Message(msg)
+
+			switch m := msg.(type) {
+			case rtb.MessageGameStarts:
+				h = hitEstimator{window: a.cfg.HitWindow}
+				started = true
+			case rtb.MessageInfo:
+				h.time = m.Time
+			case rtb.MessageRobotInfo:
+				h.robotInfo(m)
+			case rtb.MessageCoordinates:
+				x := int(math.Floor(m.X / a.cfg.CellSize))
+				y := int(math.Floor(m.Y / a.cfg.CellSize))
+				cells[[2]int{x, y}]++
+			case rtb.MessageGameFinishes:
+				if started {
+					hits = append(hits, h.hits)
+					started = false
+				}
+			}
+		case telemetry.KindCommand:
+			c.Command(rec.Raw)
+			h.command(rec.Raw)
+		}
+	}
+
+	for i, s := range c.Games() {
+		a.games = append(a.games, Game{File: name, Stats: s, HitsEstimated: hits[i]})
+	}
+	for k, n := range cells {
+		a.cells[k] += n
+	}
+	return nil
+}
+
+// Report returns the report of the games added so far.
+func (a *Analyzer) Report() Report {
+	rep := Report{
+		Games:       append([]Game(nil), a.games...),
+		DamageTaken: map[string]float64{},
+		Heatmap:     Heatmap{CellSize: a.cfg.CellSize},
+	}
+
+	var (
+		times  []float64
+		deaths int
+	)
+	for _, g := range a.games {
+		rep.ShotsFired += g.Stats.ShotsFired
+		rep.HitsEstimated += g.HitsEstimated
+		for obj, d := range g.Stats.DamageTaken {
+			rep.DamageTaken[obj.String()] += d
+		}
+		if g.Stats.Dead {
+			deaths++
+		}
+		times = append(times, g.Stats.TimeSurvived)
+	}
+	if rep.ShotsFired > 0 {
+		rep.HitRate = float64(rep.HitsEstimated) / float64(rep.ShotsFired)
+	}
+	rep.Survival = survival(times, a.cfg.SurvivalBin, deaths)
+
+	for k, n := range a.cells {
+		rep.Heatmap.Cells = append(rep.Heatmap.Cells, Cell{X: k[0], Y: k[1], Count: n})
+	}
+	sort.Slice(rep.Heatmap.Cells, func(i, j int) bool {
+		ci, cj := rep.Heatmap.Cells[i], rep.Heatmap.Cells[j]
+		if ci.Y != cj.Y {
+			return ci.Y < cj.Y
+		}
+		return ci.X < cj.X
+	})
+	return rep
+}
+
+// survival returns the distribution of the survival times.
+func survival(times []float64, bin float64, deaths int) Survival {
+	s := Survival{Deaths: deaths}
+	if len(times) == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	s.Min, s.Max = sorted[0], sorted[len(sorted)-1]
+	for _, t := range sorted {
+		s.Mean += t
+	}
+	s.Mean /= float64(len(sorted))
+	if n := len(sorted); n%2 == 1 {
+		s.Median = sorted[n/2]
+	} else {
+		s.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	nbins := int(math.Floor(s.Max/bin)) + 1
+	s.Bins = make([]Bin, nbins)
+	for i := range s.Bins {
+		s.Bins[i] = Bin{From: float64(i) * bin, To: float64(i+1) * bin}
+	}
+	for _, t := range sorted {
+		s.Bins[int(math.Floor(t/bin))].Count++
+	}
+	return s
+}
+
+// hitEstimator estimates the shots that hit a robot. The server does not
+// report hits, so a drop in the energy of the robots detected by the radar
+// shortly after a shot is counted as a hit. Since the energy levels are
+// discretized and robots also lose energy for other reasons, it is an
+// approximation.
+type hitEstimator struct {
+	window float64
+	time   float64
+	hits   int
+
+	// shots are the times of the shots not yet counted as hits.
+	shots []float64
+
+	// energy is the last energy level detected and ok reports whether
+	// there is one.
+	energy float64
+	ok     bool
+}
+
+// command records the shots.
+func (h *hitEstimator) command(cmd string) {
+	var energy float64
+	if _, err := fmt.Sscanf(cmd, "Shoot %g", &energy); err != nil {
+		return
+	}
+	h.shots = append(h.shots, h.time)
+}
+
+// robotInfo counts a hit if the energy of the detected robot dropped.
+func (h *hitEstimator) robotInfo(m rtb.MessageRobotInfo) {
+	// Shots older than the window can no longer be counted.
+	for len(h.shots) > 0 && h.time-h.shots[0] > h.window {
+		h.shots = h.shots[1:]
+	}
+	if h.ok && m.EnergyLevel < h.energy && len(h.shots) > 0 {
+		h.hits++
+		h.shots = h.shots[1:]
+	}
+	h.energy, h.ok = m.EnergyLevel, true
+}
+
+// WriteJSON writes the report as indented JSON.
+func (rep Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(rep)
+}
+
+// Tables of a report, written by WriteCSV.
+const (
+	// TableGames has a row per game.
+	TableGames = "games"
+
+	// TableSurvival has a row per bin of the survival time histogram.
+	TableSurvival = "survival"
+
+	// TableDamage has a row per damage source.
+	TableDamage = "damage"
+
+	// TableHeatmap has a row per cell of the heatmap.
+	TableHeatmap = "heatmap"
+)
+
+// WriteCSV writes a table of the report as CSV, with a header row. table
+// must be one of the Table* constants.
+func (rep Report) WriteCSV(w io.Writer, table string) error {
+	var rows [][]string
+	switch table {
+	case TableGames:
+		rows = append(rows, []string{"file", "game", "time_survived", "dead", "shots_fired", "shot_energy", "hits_estimated", "damage_taken", "cookies_eaten"})
+		for _, g := range rep.Games {
+			rows = append(rows, []string{
+				g.File,
+				strconv.Itoa(g.Stats.Game),
+				formatFloat(g.Stats.TimeSurvived),
+				strconv.FormatBool(g.Stats.Dead),
+				strconv.Itoa(g.Stats.ShotsFired),
+				formatFloat(g.Stats.ShotEnergy),
+				strconv.Itoa(g.HitsEstimated),
+				formatFloat(g.Stats.TotalDamage()),
+				strconv.Itoa(g.Stats.CookiesEaten),
+			})
+		}
+	case TableSurvival:
+		rows = append(rows, []string{"from", "to", "count"})
+		for _, b := range rep.Survival.Bins {
+			rows = append(rows, []string{formatFloat(b.From), formatFloat(b.To), strconv.Itoa(b.Count)})
+		}
+	case TableDamage:
+		rows = append(rows, []string{"source", "damage"})
+		var sources []string
+		for src := range rep.DamageTaken {
+			sources = append(sources, src)
+		}
+		sort.Strings(sources)
+		for _, src := range sources {
+			rows = append(rows, []string{src, formatFloat(rep.DamageTaken[src])})
+		}
+	case TableHeatmap:
+		rows = append(rows, []string{"x", "y", "count"})
+		for _, c := range rep.Heatmap.Cells {
+			rows = append(rows, []string{strconv.Itoa(c.X), strconv.Itoa(c.Y), strconv.Itoa(c.Count)})
+		}
+	default:
+		return fmt.Errorf("unknown table %q", table)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("could not write CSV: %v", err)
+	}
+	return nil
+}
+
+// formatFloat formats v using the minimum number of digits necessary to
+// represent it exactly.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+// records returns the telemetry records of lines. Lines starting with '>'
+// are commands and the rest are messages.
+func records(lines ...string) []telemetry.Record {
+	var recs []telemetry.Record
+	for _, line := range lines {
+		if line[0] == '>' {
+			recs = append(recs, telemetry.Record{Kind: telemetry.KindCommand, Raw: line[1:]})
+			continue
+		}
+		recs = append(recs, telemetry.Record{Kind: telemetry.KindMessage, Raw: line})
+	}
+	return recs
+}
+
+func TestAnalyzer(t *testing.T) {
+	a := New(Config{SurvivalBin: 5, CellSize: 10})
+
+	err := a.Add("game-0001.jsonl", records(
+		"GameOption 5 100",
+		"GameStarts",
+		"Info 1 0 0",
+		"Coordinates 1 2 0",
+		">Shoot 5.000000",
+		"Radar 5 0 0",
+		"RobotInfo 100 0",
+		"Info 2 0 0",
+		"Coordinates 12 2 0",
+		"Radar 5 0 0",
+		"RobotInfo 90 0",
+		">Shoot 5.000000",
+		"Collision 1 0",
+		"Energy 90",
+		"Info 3 0 0",
+		"Dead",
+		"GameFinishes",
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = a.Add("game-0002.jsonl", records(
+		"GameOption 5 100",
+		"GameStarts",
+		"Info 7 0 0",
+		"Coordinates 3 4 0",
+		"GameFinishes",
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rep := a.Report()
+	if len(rep.Games) != 2 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(rep.Games), 2)
+	}
+	if rep.ShotsFired != 2 || rep.HitsEstimated != 1 || rep.HitRate != 0.5 {
+		t.Errorf("unexpected shots: fired=%v hits=%v rate=%v", rep.ShotsFired, rep.HitsEstimated, rep.HitRate)
+	}
+	wantSurvival := Survival{
+		Mean: 5, Median: 5, Min: 3, Max: 7,
+		Deaths: 1,
+		Bins:   []Bin{{0, 5, 1}, {5, 10, 1}},
+	}
+	if !reflect.DeepEqual(rep.Survival, wantSurvival) {
+		t.Errorf("unexpected survival: got=%+v want=%+v", rep.Survival, wantSurvival)
+	}
+	if want := map[string]float64{"Shot": 10}; !reflect.DeepEqual(rep.DamageTaken, want) {
+		t.Errorf("unexpected damage: got=%v want=%v", rep.DamageTaken, want)
+	}
+	wantCells := []Cell{{0, 0, 2}, {1, 0, 1}}
+	if !reflect.DeepEqual(rep.Heatmap.Cells, wantCells) {
+		t.Errorf("unexpected heatmap: got=%v want=%v", rep.Heatmap.Cells, wantCells)
+	}
+
+	var buf bytes.Buffer
+	if err := rep.WriteCSV(&buf, TableSurvival); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "from,to,count\n0,5,1\n5,10,1\n"; got != want {
+		t.Errorf("unexpected CSV: got=%q want=%q", got, want)
+	}
+
+	buf.Reset()
+	if err := rep.WriteCSV(&buf, TableGames); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantGames := "file,game,time_survived,dead,shots_fired,shot_energy,hits_estimated,damage_taken,cookies_eaten\n" +
+		"game-0001.jsonl,1,3,true,2,10,1,10,0\n" +
+		"game-0002.jsonl,1,7,false,0,0,0,0,0\n"
+	if got := buf.String(); got != wantGames {
+		t.Errorf("unexpected CSV: got=%q want=%q", got, wantGames)
+	}
+
+	if err := rep.WriteCSV(&buf, "foo"); err == nil {
+		t.Errorf("expected error for unknown table")
+	}
+
+	buf.Reset()
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"HitRate": 0.5`)) {
+		t.Errorf("unexpected JSON: %s", buf.Bytes())
+	}
+}
@@ -0,0 +1,654 @@
+// Package arena implements parsing and generation of RealTimeBattle arena
+// files.
+//
+// An arena file is a sequence of whitespace separated commands. Comments start
+// with "//" and extend until the end of the line. The following commands are
+// supported:
+//
+//	scale <value>
+//	angle_unit <radians|degrees>
+//	boundary <left> <top> <right> <bottom>
+//	exclusion_point <x> <y>
+//	line <bounce> <hardness> <thickness> <x1> <y1> <x2> <y2>
+//	circle <bounce> <hardness> <x> <y> <radius>
+//	inner_circle <bounce> <hardness> <x> <y> <radius>
+//	arc <bounce> <hardness> <thickness> <x> <y> <inner radius> <outer radius> <angle1> <angle2>
+//	polygon <bounce> <hardness> <thickness> <n> <x1> <y1> ... <xn> <yn>
+//	closed_polygon <bounce> <hardness> <thickness> <n> <x1> <y1> ... <xn> <yn>
+//	poly_curve <bounce> <hardness> <thickness> <x> <y> <dir x> <dir y> <commands>
+//
+// The commands of a poly_curve are "L <length>" (line), "T <angle>" (turn),
+// "A <angle> <radius>" (arc), "C" (close the curve) and "Q" (quit).
+//
+// Once parsed, all the coordinates are scaled and all the angles are given in
+// radians.
+package arena
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point is a point in the arena.
+type Point struct {
+	X, Y float64
+}
+
+// Add returns the vector p+q.
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+// Sub returns the vector p-q.
+func (p Point) Sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+// Mul returns the vector p*k.
+func (p Point) Mul(k float64) Point {
+	return Point{p.X * k, p.Y * k}
+}
+
+// Dot returns the dot product of p and q.
+func (p Point) Dot(q Point) float64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+// Len returns the length of the vector p.
+func (p Point) Len() float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+// Polar returns the point at distance r in the direction given by angle.
+func Polar(angle, r float64) Point {
+	return Point{r * math.Cos(angle), r * math.Sin(angle)}
+}
+
+// SegmentDistance returns the distance from p to the segment ab.
+func SegmentDistance(p, a, b Point) float64 {
+	ab := b.Sub(a)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return p.Sub(a).Len()
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l2))
+	return p.Sub(a.Add(ab.Mul(t))).Len()
+}
+
+// Rect is an axis-aligned rectangle.
+type Rect struct {
+	Min, Max Point
+}
+
+// Dx returns the width of r.
+func (r Rect) Dx() float64 {
+	return r.Max.X - r.Min.X
+}
+
+// Dy returns the height of r.
+func (r Rect) Dy() float64 {
+	return r.Max.Y - r.Min.Y
+}
+
+// Contains reports whether p is inside r.
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+// Material describes how a wall reacts to collisions.
+type Material struct {
+	// BounceCoeff is the bounce coefficient of the wall.
+	BounceCoeff float64
+
+	// Hardness determines the damage inflicted on the robots colliding
+	// with the wall.
+	Hardness float64
+}
+
+// DefaultMaterial is the material used by the arena generators.
+var DefaultMaterial = Material{BounceCoeff: 0.5, Hardness: 0.5}
+
+// DefaultThickness is the wall thickness used by the arena generators.
+const DefaultThickness = 0.1
+
+type (
+	// Line is a straight wall.
+	Line struct {
+		Material
+		Thickness float64
+		Start     Point
+		End       Point
+	}
+
+	// Circle is a solid circular wall.
+	Circle struct {
+		Material
+		Center Point
+		Radius float64
+	}
+
+	// InnerCircle is a circular wall that keeps the robots inside of
+	// it. It is usually used to build circular arenas.
+	InnerCircle struct {
+		Material
+		Center Point
+		Radius float64
+	}
+
+	// Arc is a ring sector wall. The sector goes counterclockwise from
+	// Angle1 to Angle2.
+	Arc struct {
+		Material
+		Thickness   float64
+		Center      Point
+		InnerRadius float64
+		OuterRadius float64
+		Angle1      float64
+		Angle2      float64
+	}
+
+	// Polygon is a chain of straight walls. If Closed is true, the last
+	// vertex is joined with the first one.
+	Polygon struct {
+		Material
+		Thickness float64
+		Vertices  []Point
+		Closed    bool
+	}
+)
+
+// Wall is implemented by all the wall types: Line, Circle, InnerCircle, Arc
+// and Polygon.
+type Wall interface {
+	isWall()
+}
+
+func (Line) isWall()        {}
+func (Circle) isWall()      {}
+func (InnerCircle) isWall() {}
+func (Arc) isWall()         {}
+func (Polygon) isWall()     {}
+
+// Segment is a straight segment of a wall.
+type Segment struct {
+	Material
+	Thickness float64
+	A, B      Point
+}
+
+// Segments returns the straight segments of p.
+func (p Polygon) Segments() []Segment {
+	var segs []Segment
+	for i := 0; i+1 < len(p.Vertices); i++ {
+		segs = append(segs, Segment{p.Material, p.Thickness, p.Vertices[i], p.Vertices[i+1]})
+	}
+	if p.Closed && len(p.Vertices) > 2 {
+		segs = append(segs, Segment{p.Material, p.Thickness, p.Vertices[len(p.Vertices)-1], p.Vertices[0]})
+	}
+	return segs
+}
+
+// Arena is a RealTimeBattle arena.
+type Arena struct {
+	// Boundary is the area of the arena shown by the server. Robots
+	// should never leave it.
+	Boundary Rect
+
+	// ExclusionPoints are points that mark inaccessible places. Robots
+	// are never placed in an area from where an exclusion point is
+	// reachable.
+	ExclusionPoints []Point
+
+	// Walls are the walls of the arena.
+	Walls []Wall
+}
+
+// Segments returns the straight segments of all the Line and Polygon walls of
+// the arena. It is useful to compute intersections with the walls.
+func (a *Arena) Segments() []Segment {
+	var segs []Segment
+	for _, w := range a.Walls {
+		switch w := w.(type) {
+		case Line:
+			segs = append(segs, Segment{w.Material, w.Thickness, w.Start, w.End})
+		case Polygon:
+			segs = append(segs, w.Segments()...)
+		}
+	}
+	return segs
+}
+
+// Parse parses an arena file.
+func Parse(r io.Reader) (*Arena, error) {
+	toks, err := tokenize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks, scale: 1, angleUnit: math.Pi / 180}
+	return p.parse()
+}
+
+// tokenize splits an arena file into tokens, removing comments.
+func tokenize(r io.Reader) ([]string, error) {
+	var toks []string
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		toks = append(toks, strings.Fields(line)...)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("could not read arena: %v", err)
+	}
+
+	return toks, nil
+}
+
+// parser keeps the state of the arena parser.
+type parser struct {
+	toks      []string
+	pos       int
+	scale     float64
+	angleUnit float64
+	boundary  bool
+}
+
+func (p *parser) parse() (*Arena, error) {
+	a := &Arena{}
+
+	for p.pos < len(p.toks) {
+		cmd := p.toks[p.pos]
+		p.pos++
+
+		var err error
+		switch cmd {
+		case "scale":
+			if p.boundary {
+				return nil, errors.New("scale must be set before boundary")
+			}
+			p.scale, err = p.float("scale")
+		case "angle_unit":
+			err = p.parseAngleUnit()
+		case "boundary":
+			err = p.parseBoundary(a)
+		case "exclusion_point":
+			var pt Point
+			if pt, err = p.point("exclusion point"); err == nil {
+				a.ExclusionPoints = append(a.ExclusionPoints, pt)
+			}
+		case "line":
+			err = p.parseLine(a)
+		case "circle":
+			err = p.parseCircle(a, false)
+		case "inner_circle":
+			err = p.parseCircle(a, true)
+		case "arc":
+			err = p.parseArc(a)
+		case "polygon":
+			err = p.parsePolygon(a, false)
+		case "closed_polygon":
+			err = p.parsePolygon(a, true)
+		case "poly_curve":
+			err = p.parsePolyCurve(a)
+		default:
+			return nil, fmt.Errorf("unknown command %q", cmd)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", cmd, err)
+		}
+	}
+
+	if !p.boundary {
+		return nil, errors.New("missing boundary")
+	}
+
+	return a, nil
+}
+
+// next returns the next token.
+func (p *parser) next(what string) (string, error) {
+	if p.pos >= len(p.toks) {
+		return "", fmt.Errorf("missing %v", what)
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	return tok, nil
+}
+
+// float parses the next token as a float.
+func (p *parser) float(what string) (float64, error) {
+	tok, err := p.next(what)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %v %q: %v", what, tok, err)
+	}
+	return v, nil
+}
+
+// length parses the next token as a length and scales it.
+func (p *parser) length(what string) (float64, error) {
+	v, err := p.float(what)
+	return v * p.scale, err
+}
+
+// angle parses the next token as an angle and converts it to radians.
+func (p *parser) angle(what string) (float64, error) {
+	v, err := p.float(what)
+	return v * p.angleUnit, err
+}
+
+// point parses the next two tokens as a scaled point.
+func (p *parser) point(what string) (Point, error) {
+	x, err := p.length(what + " x")
+	if err != nil {
+		return Point{}, err
+	}
+	y, err := p.length(what + " y")
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{x, y}, nil
+}
+
+// material parses the next two tokens as a Material.
+func (p *parser) material() (Material, error) {
+	bounce, err := p.float("bounce coefficient")
+	if err != nil {
+		return Material{}, err
+	}
+	hardness, err := p.float("hardness")
+	if err != nil {
+		return Material{}, err
+	}
+	return Material{BounceCoeff: bounce, Hardness: hardness}, nil
+}
+
+func (p *parser) parseAngleUnit() error {
+	unit, err := p.next("unit")
+	if err != nil {
+		return err
+	}
+	switch unit {
+	case "radians":
+		p.angleUnit = 1
+	case "degrees":
+		p.angleUnit = math.Pi / 180
+	default:
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	return nil
+}
+
+func (p *parser) parseBoundary(a *Arena) error {
+	if p.boundary {
+		return errors.New("boundary already set")
+	}
+	min, err := p.point("top-left corner")
+	if err != nil {
+		return err
+	}
+	max, err := p.point("bottom-right corner")
+	if err != nil {
+		return err
+	}
+	if max.X <= min.X || max.Y <= min.Y {
+		return errors.New("empty boundary")
+	}
+	a.Boundary = Rect{Min: min, Max: max}
+	p.boundary = true
+	return nil
+}
+
+func (p *parser) parseLine(a *Arena) error {
+	m, err := p.material()
+	if err != nil {
+		return err
+	}
+	thickness, err := p.length("thickness")
+	if err != nil {
+		return err
+	}
+	start, err := p.point("start")
+	if err != nil {
+		return err
+	}
+	end, err := p.point("end")
+	if err != nil {
+		return err
+	}
+	a.Walls = append(a.Walls, Line{m, thickness, start, end})
+	return nil
+}
+
+func (p *parser) parseCircle(a *Arena, inner bool) error {
+	m, err := p.material()
+	if err != nil {
+		return err
+	}
+	center, err := p.point("center")
+	if err != nil {
+		return err
+	}
+	radius, err := p.length("radius")
+	if err != nil {
+		return err
+	}
+	if inner {
+		a.Walls = append(a.Walls, InnerCircle{m, center, radius})
+	} else {
+		a.Walls = append(a.Walls, Circle{m, center, radius})
+	}
+	return nil
+}
+
+func (p *parser) parseArc(a *Arena) error {
+	m, err := p.material()
+	if err != nil {
+		return err
+	}
+	thickness, err := p.length("thickness")
+	if err != nil {
+		return err
+	}
+	center, err := p.point("center")
+	if err != nil {
+		return err
+	}
+	inner, err := p.length("inner radius")
+	if err != nil {
+		return err
+	}
+	outer, err := p.length("outer radius")
+	if err != nil {
+		return err
+	}
+	angle1, err := p.angle("angle1")
+	if err != nil {
+		return err
+	}
+	angle2, err := p.angle("angle2")
+	if err != nil {
+		return err
+	}
+	a.Walls = append(a.Walls, Arc{m, thickness, center, inner, outer, angle1, angle2})
+	return nil
+}
+
+// maxVertices is the maximum number of vertices of a polygon. It prevents
+// huge allocations when parsing malformed files.
+const maxVertices = 10000
+
+func (p *parser) parsePolygon(a *Arena, closed bool) error {
+	m, err := p.material()
+	if err != nil {
+		return err
+	}
+	thickness, err := p.length("thickness")
+	if err != nil {
+		return err
+	}
+	tok, err := p.next("number of vertices")
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(tok)
+	if err != nil {
+		return fmt.Errorf("could not parse number of vertices %q: %v", tok, err)
+	}
+	if n < 2 || n > maxVertices {
+		return fmt.Errorf("invalid number of vertices %v", n)
+	}
+	vertices := make([]Point, n)
+	for i := range vertices {
+		if vertices[i], err = p.point("vertex"); err != nil {
+			return err
+		}
+	}
+	a.Walls = append(a.Walls, Polygon{m, thickness, vertices, closed})
+	return nil
+}
+
+// arcSteps is the number of straight segments used to approximate a full
+// turn of a poly_curve arc.
+const arcSteps = 32
+
+func (p *parser) parsePolyCurve(a *Arena) error {
+	m, err := p.material()
+	if err != nil {
+		return err
+	}
+	thickness, err := p.length("thickness")
+	if err != nil {
+		return err
+	}
+	pos, err := p.point("start")
+	if err != nil {
+		return err
+	}
+	dx, err := p.float("direction x")
+	if err != nil {
+		return err
+	}
+	dy, err := p.float("direction y")
+	if err != nil {
+		return err
+	}
+	dir := math.Atan2(dy, dx)
+
+	poly := Polygon{Material: m, Thickness: thickness, Vertices: []Point{pos}}
+	for done := false; !done; {
+		cmd, err := p.next("poly_curve command")
+		if err != nil {
+			return err
+		}
+		switch cmd {
+		case "L":
+			length, err := p.length("length")
+			if err != nil {
+				return err
+			}
+			pos = pos.Add(Polar(dir, length))
+			poly.Vertices = append(poly.Vertices, pos)
+		case "T":
+			angle, err := p.angle("angle")
+			if err != nil {
+				return err
+			}
+			dir += angle
+		case "A":
+			angle, err := p.angle("angle")
+			if err != nil {
+				return err
+			}
+			radius, err := p.length("radius")
+			if err != nil {
+				return err
+			}
+			n := int(math.Ceil(math.Abs(angle) / (2 * math.Pi) * arcSteps))
+			if n > maxVertices {
+				return fmt.Errorf("arc angle too big %v", angle)
+			}
+			side := math.Copysign(math.Pi/2, angle)
+			center := pos.Add(Polar(dir+side, radius))
+			start := dir - side
+			for i := 1; i <= n; i++ {
+				pos = center.Add(Polar(start+angle*float64(i)/float64(n), radius))
+				poly.Vertices = append(poly.Vertices, pos)
+			}
+			dir += angle
+		case "C":
+			poly.Closed = true
+			done = true
+		case "Q":
+			done = true
+		default:
+			return fmt.Errorf("unknown poly_curve command %q", cmd)
+		}
+		if len(poly.Vertices) > maxVertices {
+			return errors.New("too many vertices")
+		}
+	}
+	a.Walls = append(a.Walls, poly)
+	return nil
+}
+
+// WriteTo writes a in the arena file format. Angles are written in radians.
+// It implements the io.WriterTo interface.
+func (a *Arena) WriteTo(w io.Writer) (n int64, err error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "angle_unit radians\n")
+	fmt.Fprintf(&b, "boundary %v %v %v %v\n", f(a.Boundary.Min.X), f(a.Boundary.Min.Y), f(a.Boundary.Max.X), f(a.Boundary.Max.Y))
+	for _, pt := range a.ExclusionPoints {
+		fmt.Fprintf(&b, "exclusion_point %v %v\n", f(pt.X), f(pt.Y))
+	}
+	for _, wall := range a.Walls {
+		switch wall := wall.(type) {
+		case Line:
+			fmt.Fprintf(&b, "line %v %v %v %v %v %v %v\n", f(wall.BounceCoeff), f(wall.Hardness), f(wall.Thickness),
+				f(wall.Start.X), f(wall.Start.Y), f(wall.End.X), f(wall.End.Y))
+		case Circle:
+			fmt.Fprintf(&b, "circle %v %v %v %v %v\n", f(wall.BounceCoeff), f(wall.Hardness),
+				f(wall.Center.X), f(wall.Center.Y), f(wall.Radius))
+		case InnerCircle:
+			fmt.Fprintf(&b, "inner_circle %v %v %v %v %v\n", f(wall.BounceCoeff), f(wall.Hardness),
+				f(wall.Center.X), f(wall.Center.Y), f(wall.Radius))
+		case Arc:
+			fmt.Fprintf(&b, "arc %v %v %v %v %v %v %v %v %v\n", f(wall.BounceCoeff), f(wall.Hardness), f(wall.Thickness),
+				f(wall.Center.X), f(wall.Center.Y), f(wall.InnerRadius), f(wall.OuterRadius), f(wall.Angle1), f(wall.Angle2))
+		case Polygon:
+			cmd := "polygon"
+			if wall.Closed {
+				cmd = "closed_polygon"
+			}
+			fmt.Fprintf(&b, "%v %v %v %v %v", cmd, f(wall.BounceCoeff), f(wall.Hardness), f(wall.Thickness), len(wall.Vertices))
+			for _, v := range wall.Vertices {
+				fmt.Fprintf(&b, " %v %v", f(v.X), f(v.Y))
+			}
+			fmt.Fprintf(&b, "\n")
+		default:
+			return 0, fmt.Errorf("unknown wall type %T", wall)
+		}
+	}
+
+	nw, err := io.WriteString(w, b.String())
+	return int64(nw), err
+}
+
+// f formats a float using the minimum number of digits necessary to
+// represent it exactly.
+func f(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
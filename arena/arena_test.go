@@ -0,0 +1,147 @@
+package arena
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	const arenaFile = `
+		// Test arena
+		scale 2
+		angle_unit degrees
+		boundary 0 0 10 10
+		exclusion_point 1 1
+
+		line 0.4 0.5 0.1 0 0 10 0
+		circle 0.4 0.5 5 5 1
+		inner_circle 0.4 0.5 5 5 5
+		arc 0.4 0.5 0.1 5 5 1 2 0 90
+		closed_polygon 0.4 0.5 0.1 3
+			0 0
+			1 0
+			1 1
+		poly_curve 0.4 0.5 0.1 0 0 1 0 L 1 T 90 L 1 C
+	`
+
+	got, err := Parse(strings.NewReader(arenaFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := Material{BounceCoeff: 0.4, Hardness: 0.5}
+	want := &Arena{
+		Boundary:        Rect{Min: Point{0, 0}, Max: Point{20, 20}},
+		ExclusionPoints: []Point{{2, 2}},
+		Walls: []Wall{
+			Line{m, 0.2, Point{0, 0}, Point{20, 0}},
+			Circle{m, Point{10, 10}, 2},
+			InnerCircle{m, Point{10, 10}, 10},
+			Arc{m, 0.2, Point{10, 10}, 2, 4, 0, math.Pi / 2},
+			Polygon{m, 0.2, []Point{{0, 0}, {2, 0}, {2, 2}}, true},
+			Polygon{m, 0.2, []Point{{0, 0}, {2, 0}, {2, 2}}, true},
+		},
+	}
+
+	// poly_curve vertices are computed using trigonometric functions, so
+	// they are rounded before comparing.
+	pc := got.Walls[len(got.Walls)-1].(Polygon)
+	for i, v := range pc.Vertices {
+		pc.Vertices[i] = Point{math.Round(v.X*1e9) / 1e9, math.Round(v.Y*1e9) / 1e9}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected arena:\ngot=%#v\nwant=%#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"Missing boundary", "line 0.4 0.5 0.1 0 0 10 0"},
+		{"Empty boundary", "boundary 0 0 0 0"},
+		{"Duplicated boundary", "boundary 0 0 1 1 boundary 0 0 1 1"},
+		{"Scale after boundary", "boundary 0 0 1 1 scale 2"},
+		{"Unknown command", "boundary 0 0 1 1 foo"},
+		{"Missing argument", "boundary 0 0 1 1 circle 0.4 0.5 5 5"},
+		{"Invalid number", "boundary 0 0 1 1 circle 0.4 0.5 5 foo 1"},
+		{"Unknown unit", "angle_unit foo boundary 0 0 1 1"},
+		{"Too many vertices", "boundary 0 0 1 1 polygon 0.4 0.5 0.1 1000000000"},
+		{"Unterminated poly_curve", "boundary 0 0 1 1 poly_curve 0.4 0.5 0.1 0 0 1 0 L 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.s)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	m := Material{BounceCoeff: 0.4, Hardness: 0.5}
+	a := &Arena{
+		Boundary:        Rect{Min: Point{-1, -1}, Max: Point{20, 20}},
+		ExclusionPoints: []Point{{2, 2}},
+		Walls: []Wall{
+			Line{m, 0.2, Point{0, 0}, Point{20, 0}},
+			Circle{m, Point{10, 10}, 2},
+			InnerCircle{m, Point{10, 10}, 10},
+			Arc{m, 0.2, Point{10, 10}, 2, 4, 0, math.Pi / 2},
+			Polygon{m, 0.2, []Point{{0, 0}, {2, 0}, {2, 2}}, false},
+			Polygon{m, 0.2, []Point{{0, 0}, {2, 0}, {2, 2}}, true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := a.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, a) {
+		t.Errorf("unexpected arena:\ngot=%#v\nwant=%#v", got, a)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	a := Rectangle(10, 5)
+	a.Walls = append(a.Walls, Line{DefaultMaterial, DefaultThickness, Point{1, 1}, Point{2, 2}})
+
+	segs := a.Segments()
+	if len(segs) != 5 {
+		t.Fatalf("wrong number of segments: got=%v want=%v", len(segs), 5)
+	}
+
+	want := Segment{DefaultMaterial, DefaultThickness, Point{0, 5}, Point{0, 0}}
+	if segs[3] != want {
+		t.Errorf("unexpected closing segment: got=%#v want=%#v", segs[3], want)
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	tests := []struct {
+		p, a, b Point
+		want    float64
+	}{
+		{Point{X: 1, Y: 1}, Point{X: 0, Y: 0}, Point{X: 2, Y: 0}, 1},
+		{Point{X: -3, Y: 4}, Point{X: 0, Y: 0}, Point{X: 2, Y: 0}, 5},
+		{Point{X: 1, Y: 1}, Point{X: 0, Y: 0}, Point{X: 0, Y: 0}, 1.4142135623730951},
+	}
+
+	for _, tt := range tests {
+		if got := SegmentDistance(tt.p, tt.a, tt.b); got != tt.want {
+			t.Errorf("unexpected distance from %v to %v-%v: got=%v want=%v", tt.p, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package arena
+
+import (
+	"math/rand"
+)
+
+// Rectangle returns a rectangular arena with the given width and height. The
+// top-left corner of the arena is (0, 0).
+func Rectangle(width, height float64) *Arena {
+	return &Arena{
+		Boundary: Rect{Max: Point{width, height}},
+		Walls:    []Wall{rectangleWall(width, height)},
+	}
+}
+
+// rectangleWall returns a closed polygon surrounding the rectangle
+// (0, 0)-(width, height).
+func rectangleWall(width, height float64) Polygon {
+	return Polygon{
+		Material:  DefaultMaterial,
+		Thickness: DefaultThickness,
+		Vertices: []Point{
+			{0, 0},
+			{width, 0},
+			{width, height},
+			{0, height},
+		},
+		Closed: true,
+	}
+}
+
+// Circular returns a circular arena with the given radius. The top-left corner
+// of its boundary is (0, 0).
+func Circular(radius float64) *Arena {
+	return &Arena{
+		Boundary: Rect{Max: Point{2 * radius, 2 * radius}},
+		Walls: []Wall{
+			InnerCircle{
+				Material: DefaultMaterial,
+				Center:   Point{radius, radius},
+				Radius:   radius,
+			},
+		},
+	}
+}
+
+// Maze returns a rectangular arena divided in cols×rows cells of the given
+// size, forming a perfect maze. That is, there is exactly one path between
+// any two cells. The maze is generated using a randomized depth-first search
+// seeded with seed, so the same seed always generates the same arena.
+func Maze(cols, rows int, cellSize float64, seed int64) *Arena {
+	if cols < 1 || rows < 1 {
+		return Rectangle(float64(cols)*cellSize, float64(rows)*cellSize)
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+
+	// east[y][x] and south[y][x] are true if the cell (x, y) has a wall
+	// in the east or south side respectively.
+	east := make([][]bool, rows)
+	south := make([][]bool, rows)
+	visited := make([][]bool, rows)
+	for y := 0; y < rows; y++ {
+		east[y] = make([]bool, cols)
+		south[y] = make([]bool, cols)
+		visited[y] = make([]bool, cols)
+		for x := 0; x < cols; x++ {
+			east[y][x] = true
+			south[y][x] = true
+		}
+	}
+
+	type cell struct{ x, y int }
+	stack := []cell{{0, 0}}
+	visited[0][0] = true
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+
+		var next []cell
+		for _, d := range []cell{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			n := cell{c.x + d.x, c.y + d.y}
+			if n.x >= 0 && n.x < cols && n.y >= 0 && n.y < rows && !visited[n.y][n.x] {
+				next = append(next, n)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		n := next[rnd.Intn(len(next))]
+		switch {
+		case n.x > c.x:
+			east[c.y][c.x] = false
+		case n.x < c.x:
+			east[n.y][n.x] = false
+		case n.y > c.y:
+			south[c.y][c.x] = false
+		case n.y < c.y:
+			south[n.y][n.x] = false
+		}
+		visited[n.y][n.x] = true
+		stack = append(stack, n)
+	}
+
+	width := float64(cols) * cellSize
+	height := float64(rows) * cellSize
+	a := Rectangle(width, height)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			x0, y0 := float64(x)*cellSize, float64(y)*cellSize
+			x1, y1 := x0+cellSize, y0+cellSize
+			if east[y][x] && x < cols-1 {
+				a.Walls = append(a.Walls, mazeLine(Point{x1, y0}, Point{x1, y1}))
+			}
+			if south[y][x] && y < rows-1 {
+				a.Walls = append(a.Walls, mazeLine(Point{x0, y1}, Point{x1, y1}))
+			}
+		}
+	}
+
+	return a
+}
+
+// mazeLine returns a maze wall from start to end.
+func mazeLine(start, end Point) Line {
+	return Line{
+		Material:  DefaultMaterial,
+		Thickness: DefaultThickness,
+		Start:     start,
+		End:       end,
+	}
+}
@@ -0,0 +1,57 @@
+package arena
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRectangle(t *testing.T) {
+	a := Rectangle(10, 5)
+
+	if got, want := a.Boundary, (Rect{Max: Point{10, 5}}); got != want {
+		t.Errorf("unexpected boundary: got=%v want=%v", got, want)
+	}
+	if len(a.Walls) != 1 {
+		t.Fatalf("wrong number of walls: got=%v want=%v", len(a.Walls), 1)
+	}
+	if p, ok := a.Walls[0].(Polygon); !ok || !p.Closed || len(p.Vertices) != 4 {
+		t.Errorf("unexpected wall: %#v", a.Walls[0])
+	}
+}
+
+func TestCircular(t *testing.T) {
+	a := Circular(5)
+
+	want := InnerCircle{DefaultMaterial, Point{5, 5}, 5}
+	if len(a.Walls) != 1 || a.Walls[0] != want {
+		t.Errorf("unexpected walls: got=%#v want=%#v", a.Walls, want)
+	}
+}
+
+func TestMaze(t *testing.T) {
+	const cols, rows = 6, 4
+
+	a := Maze(cols, rows, 2, 1)
+
+	// A perfect maze of n cells has n-1 passages. The total number of
+	// inner cell sides is cols*(rows-1) + rows*(cols-1), so the number
+	// of inner walls is that minus the passages.
+	n := cols * rows
+	inner := cols*(rows-1) + rows*(cols-1)
+	if got, want := len(a.Walls)-1, inner-(n-1); got != want {
+		t.Errorf("wrong number of inner walls: got=%v want=%v", got, want)
+	}
+
+	if b := Maze(cols, rows, 2, 1); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed generated different mazes")
+	}
+
+	var buf bytes.Buffer
+	if _, err := a.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Parse(&buf); err != nil {
+		t.Errorf("could not parse generated maze: %v", err)
+	}
+}
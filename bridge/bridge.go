@@ -0,0 +1,351 @@
+// Package bridge carries the RealTimeBattle protocol over a network
+// connection, so a robot can run on a different machine, or in a container,
+// than the server.
+//
+// Both ends of the bridge call Run. Next to the server, Run forwards the
+// standard input and output of the process started by the server. Next to
+// the robot, Run forwards the standard input and output of the robot process,
+// or the streams passed to rtb.ListenSettings.
+//
+// Connections are monitored with heartbeats and re-established when they
+// fail. The lines exchanged by the ends are the protocol messages and
+// commands, plus control lines starting with '#', which are never sent by the
+// server or the robots.
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Control lines.
+const (
+	// lineHeartbeat is sent periodically to keep the connection alive.
+	lineHeartbeat = "#heartbeat"
+
+	// lineClose is sent when the local input is closed, so the other
+	// end stops too.
+	lineClose = "#close"
+)
+
+// Config is the configuration of a bridge.
+type Config struct {
+	// Heartbeat is the interval between heartbeats. If zero, 1s is
+	// used.
+	Heartbeat time.Duration
+
+	// Timeout is the time without receiving anything from the other end
+	// after which the connection is considered broken. If zero, 5s is
+	// used.
+	Timeout time.Duration
+
+	// RetryDelay is the time between connection attempts. If zero, 1s is
+	// used.
+	RetryDelay time.Duration
+
+	// Buffer is the maximum number of lines kept while disconnected. When
+	// it is exceeded, the oldest lines are dropped. If zero, 1024 is
+	// used.
+	Buffer int
+
+	// Logger receives the connection events. If nil, they are
+	// discarded.
+	Logger *slog.Logger
+}
+
+// Dialer establishes a connection to the other end of the bridge. It is
+// called again every time the connection fails.
+type Dialer func(ctx context.Context) (io.ReadWriteCloser, error)
+
+// DialTCP returns a Dialer that connects to the TCP address addr.
+func DialTCP(addr string) Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// Accept returns a Dialer that waits for the other end to connect to l.
+func Accept(l net.Listener) Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		type result struct {
+			conn net.Conn
+			err  error
+		}
+		c := make(chan result, 1)
+		go func() {
+			conn, err := l.Accept()
+			c <- result{conn, err}
+		}()
+
+		select {
+		case res := <-c:
+			return res.conn, res.err
+		case <-ctx.Done():
+			// The connection accepted after returning is closed,
+			// so it is not leaked.
+			go func() {
+				if res := <-c; res.conn != nil {
+					res.conn.Close()
+				}
+			}()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ErrClosed is returned by Run when the other end of the bridge has closed
+// its input.
+var ErrClosed = errors.New("bridge closed by the other end")
+
+// Run sends the lines read from in to the other end of the bridge and writes
+// the lines received from it to out. Connections are established with dial.
+//
+// Run returns nil when in is closed, after sending the pending lines, and
+// ErrClosed when the input of the other end is closed. It also returns when
+// ctx is done.
+func Run(ctx context.Context, in io.Reader, out io.Writer, dial Dialer, cfg Config) error {
+	if cfg.Heartbeat == 0 {
+		cfg.Heartbeat = time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.Buffer == 0 {
+		cfg.Buffer = 1024
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(rtb.DiscardHandler{})
+	}
+
+	q := newQueue(cfg.Buffer)
+	go func() {
+		br := bufio.NewReader(in)
+		for {
+			line, err := br.ReadString('\n')
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				if q.push(line) {
+					cfg.Logger.Warn("line dropped", "line", line)
+				}
+			}
+			if err != nil {
+				q.close()
+				return
+			}
+		}
+	}()
+
+	b := &bridge{cfg: cfg, q: q, out: out}
+	for {
+		conn, err := dial(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			cfg.Logger.Warn("could not connect", "err", err)
+			select {
+			case <-time.After(cfg.RetryDelay):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		cfg.Logger.Info("connected")
+		done, err := b.session(ctx, conn)
+		if done {
+			return err
+		}
+		cfg.Logger.Warn("connection lost", "err", err)
+	}
+}
+
+// bridge is the state shared by the sessions of Run.
+type bridge struct {
+	cfg Config
+	q   *queue
+	out io.Writer
+}
+
+// session forwards lines through conn until it fails. It returns true if Run
+// must return with the returned error.
+func (b *bridge) session(ctx context.Context, conn io.ReadWriteCloser) (done bool, err error) {
+	defer conn.Close()
+
+	// The reader reports every line received, so the timeout is reset,
+	// and finishes when the connection is closed.
+	alive := make(chan struct{}, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- b.read(conn, alive)
+	}()
+	defer func() {
+		// The reader must finish before the next session, so the
+		// writes to out are not interleaved.
+		conn.Close()
+		<-readErr
+	}()
+
+	heartbeat := time.NewTicker(b.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	timeout := time.NewTimer(b.cfg.Timeout)
+	defer timeout.Stop()
+
+	for {
+		for {
+			line, ok := b.q.pop()
+			if !ok {
+				break
+			}
+			if _, err := io.WriteString(conn, line+"\n"); err != nil {
+				b.q.unpop(line)
+				return false, err
+			}
+		}
+		if b.q.finished() {
+			io.WriteString(conn, lineClose+"\n")
+			return true, nil
+		}
+
+		select {
+		case <-b.q.notify:
+		case <-heartbeat.C:
+			if _, err := io.WriteString(conn, lineHeartbeat+"\n"); err != nil {
+				return false, err
+			}
+		case <-alive:
+			if !timeout.Stop() {
+				<-timeout.C
+			}
+			timeout.Reset(b.cfg.Timeout)
+		case <-timeout.C:
+			return false, errors.New("heartbeat timeout")
+		case err := <-readErr:
+			// The deferred function waits for the reader.
+			readErr <- err
+			return errors.Is(err, ErrClosed), err
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// read writes the lines received through conn to b.out. It reports every
+// line in alive. It returns ErrClosed if the other end closes its input, or
+// the error of conn.
+func (b *bridge) read(conn io.Reader, alive chan<- struct{}) error {
+	br := bufio.NewReader(conn)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			select {
+			case alive <- struct{}{}:
+			default:
+			}
+		}
+		switch strings.TrimRight(line, "\r\n") {
+		case "", lineHeartbeat:
+		case lineClose:
+			return ErrClosed
+		default:
+			if _, err := io.WriteString(b.out, line); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// queue is a bounded queue of lines. When it is full, the oldest lines are
+// dropped.
+type queue struct {
+	// notify receives a value when lines are pushed or the queue is
+	// closed.
+	notify chan struct{}
+
+	mu     sync.Mutex
+	lines  []string
+	max    int
+	closed bool
+}
+
+// newQueue returns a queue with room for max lines.
+func newQueue(max int) *queue {
+	return &queue{notify: make(chan struct{}, 1), max: max}
+}
+
+// push adds a line to the queue. It returns true if a line was dropped.
+func (q *queue) push(line string) (dropped bool) {
+	q.mu.Lock()
+	if len(q.lines) == q.max {
+		q.lines, dropped = q.lines[1:], true
+	}
+	q.lines = append(q.lines, line)
+	q.mu.Unlock()
+
+	q.signal()
+	return dropped
+}
+
+// pop removes the oldest line. It returns false if the queue is empty.
+func (q *queue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.lines) == 0 {
+		return "", false
+	}
+	line := q.lines[0]
+	q.lines = q.lines[1:]
+	return line, true
+}
+
+// unpop puts back a line removed by pop, so it is sent in the next session.
+func (q *queue) unpop(line string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lines = append([]string{line}, q.lines...)
+}
+
+// close marks the end of the input.
+func (q *queue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// finished returns true if the queue is closed and empty.
+func (q *queue) finished() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.closed && len(q.lines) == 0
+}
+
+// signal notifies a change without blocking.
+func (q *queue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,170 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// end is one end of a bridge, with pipes to write its input and read its
+// output.
+type end struct {
+	in  *io.PipeWriter
+	out *bufio.Reader
+	err chan error
+}
+
+// start runs one end of a bridge.
+func start(ctx context.Context, dial Dialer, cfg Config) *end {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	e := &end{in: inW, out: bufio.NewReader(outR), err: make(chan error, 1)}
+	go func() {
+		e.err <- Run(ctx, inR, outW, dial, cfg)
+		outW.Close()
+	}()
+	return e
+}
+
+// expect reads a line from the output of e.
+func (e *end) expect(t *testing.T, want string) {
+	t.Helper()
+
+	line, err := e.out.ReadString('\n')
+	if err != nil {
+		t.Fatalf("could not read line: %v", err)
+	}
+	if got := strings.TrimSuffix(line, "\n"); got != want {
+		t.Errorf("unexpected line: got=%q want=%q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen func(l net.Listener) (server, robot Dialer)
+	}{
+		{
+			"TCP",
+			func(l net.Listener) (Dialer, Dialer) {
+				return Accept(l), DialTCP(l.Addr().String())
+			},
+		},
+		{
+			"WebSocket",
+			func(l net.Listener) (Dialer, Dialer) {
+				return ListenWebSocket(l, "/rtb"), DialWebSocket("ws://" + l.Addr().String() + "/rtb")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("could not listen: %v", err)
+			}
+			defer l.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			cfg := Config{Heartbeat: 10 * time.Millisecond, RetryDelay: 10 * time.Millisecond}
+			serverDial, robotDial := tt.listen(l)
+			server := start(ctx, serverDial, cfg)
+			robot := start(ctx, robotDial, cfg)
+
+			io.WriteString(server.in, "Initialize 1\n")
+			robot.expect(t, "Initialize 1")
+			io.WriteString(robot.in, "Name foo\n")
+			server.expect(t, "Name foo")
+
+			// Closing the input of the server stops both ends.
+			server.in.Close()
+			if err := <-server.err; err != nil {
+				t.Errorf("unexpected server error: %v", err)
+			}
+			if err := <-robot.err; !errors.Is(err, ErrClosed) {
+				t.Errorf("unexpected robot error: got=%v want=%v", err, ErrClosed)
+			}
+		})
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Every connection of the robot is a new pipe, whose other end is
+	// sent to the server.
+	conns := make(chan net.Conn)
+	robotDial := func(ctx context.Context) (io.ReadWriteCloser, error) {
+		a, b := net.Pipe()
+		select {
+		case conns <- b:
+			return a, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var first net.Conn
+	serverDial := func(ctx context.Context) (io.ReadWriteCloser, error) {
+		select {
+		case conn := <-conns:
+			if first == nil {
+				first = conn
+			}
+			return conn, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	cfg := Config{Heartbeat: 10 * time.Millisecond, Timeout: 100 * time.Millisecond, RetryDelay: 10 * time.Millisecond}
+	server := start(ctx, serverDial, cfg)
+	robot := start(ctx, robotDial, cfg)
+
+	io.WriteString(server.in, "GameStarts\n")
+	robot.expect(t, "GameStarts")
+
+	// The lines sent while the connection is broken are delivered
+	// after reconnecting.
+	first.Close()
+	io.WriteString(server.in, "Info 1 0 0\n")
+	robot.expect(t, "Info 1 0 0")
+
+	robot.in.Close()
+	if err := <-robot.err; err != nil {
+		t.Errorf("unexpected robot error: %v", err)
+	}
+	if err := <-server.err; !errors.Is(err, ErrClosed) {
+		t.Errorf("unexpected server error: got=%v want=%v", err, ErrClosed)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := newQueue(2)
+	q.push("a")
+	q.push("b")
+	if dropped := q.push("c"); !dropped {
+		t.Errorf("line not dropped")
+	}
+
+	line, _ := q.pop()
+	q.unpop(line)
+	q.close()
+
+	var got []string
+	for !q.finished() {
+		line, _ := q.pop()
+		got = append(got, line)
+	}
+	if strings.Join(got, "|") != "b|c" {
+		t.Errorf("unexpected lines: got=%q want=%q", got, []string{"b", "c"})
+	}
+}
@@ -0,0 +1,306 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// This file implements the subset of the WebSocket protocol (RFC 6455) needed
+// by the bridge: text frames, fragmentation, ping/pong and close.
+
+// wsGUID is the GUID used to compute the Sec-WebSocket-Accept header.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// wsMaxPayload is the maximum payload of a received frame. Protocol lines are
+// much shorter.
+const wsMaxPayload = 1 << 20
+
+// ListenWebSocket serves WebSocket connections on l at the given path and
+// returns a Dialer that waits for the other end to connect.
+func ListenWebSocket(l net.Listener, path string) Dialer {
+	conns := make(chan io.ReadWriteCloser)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		conn, err := wsAccept(w, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		select {
+		case conns <- conn:
+		case <-req.Context().Done():
+			conn.Close()
+		}
+	})
+	go http.Serve(l, mux)
+
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		select {
+		case conn := <-conns:
+			return conn, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// DialWebSocket returns a Dialer that connects to the WebSocket URL rawURL,
+// e.g. "ws://host:port/path". Only the ws scheme is supported.
+func DialWebSocket(rawURL string) Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse URL: %v", err)
+		}
+		if u.Scheme != "ws" {
+			return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		ws, err := wsHandshake(conn, u)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return ws, nil
+	}
+}
+
+// wsAccept upgrades an HTTP request to a WebSocket connection.
+func wsAccept(w http.ResponseWriter, req *http.Request) (*wsConn, error) {
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return nil, errors.New("not a websocket handshake")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection cannot be hijacked")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("could not hijack connection: %v", err)
+	}
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n")
+	fmt.Fprintf(brw, "Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(brw, "Sec-WebSocket-Accept: %v\r\n\r\n", wsAcceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: brw.Reader}, nil
+}
+
+// wsHandshake sends the opening handshake of a client through conn.
+func wsHandshake(conn net.Conn, u *url.URL) (*wsConn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	path := u.RequestURI()
+	fmt.Fprintf(conn, "GET %v HTTP/1.1\r\nHost: %v\r\n", path, u.Host)
+	fmt.Fprintf(conn, "Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(conn, "Sec-WebSocket-Key: %v\r\nSec-WebSocket-Version: 13\r\n\r\n", key)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return nil, fmt.Errorf("could not read handshake response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("unexpected handshake status: %v", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, errors.New("invalid Sec-WebSocket-Accept")
+	}
+	return &wsConn{conn: conn, br: br, client: true}, nil
+}
+
+// wsAcceptKey returns the Sec-WebSocket-Accept value for key.
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains returns true if the comma-separated header name contains
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsConn is a WebSocket connection. Every Write is sent as a text frame, and
+// Read returns the payload of the received data frames as a stream.
+type wsConn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool
+
+	// payload is the unread payload of the current frame.
+	payload []byte
+
+	// wmu serializes the writes of frames.
+	wmu sync.Mutex
+}
+
+// Read reads the payload of the received data frames.
+func (c *wsConn) Read(p []byte) (int, error) {
+	for len(c.payload) == 0 {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case wsText, wsBinary, wsContinuation:
+			c.payload = payload
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, err
+			}
+		case wsClose:
+			c.writeFrame(wsClose, nil)
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, c.payload)
+	c.payload = c.payload[n:]
+	return n, nil
+}
+
+// Write sends p as a text frame.
+func (c *wsConn) Write(p []byte) (int, error) {
+	if err := c.writeFrame(wsText, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection.
+func (c *wsConn) Close() error {
+	c.writeFrame(wsClose, nil)
+	return c.conn.Close()
+}
+
+// readFrame reads a frame and returns its opcode and unmasked payload.
+func (c *wsConn) readFrame() (op byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	op = hdr[0] & 0x0f
+	masked := hdr[1]&0x80 != 0
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxPayload {
+		return 0, nil, fmt.Errorf("frame too big (%v)", n)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
+
+// writeFrame writes a final frame. Frames sent by clients are masked, as
+// required by the protocol.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	buf := []byte{0x80 | op}
+	maskBit := byte(0)
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		for i, b := range payload {
+			buf = append(buf, b^mask[i%4])
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+
+	_, err := c.conn.Write(buf)
+	return err
+}
@@ -0,0 +1,211 @@
+// Package budget monitors the CPU time used by a strategy.
+//
+// In competition mode, the server kills the robots that use too much CPU
+// time, warning them with WarningProcessTimeLow first. A Monitor measures the
+// time spent by the strategy handling the messages of every tick and, when
+// the server warns or a tick exceeds its budget, switches to a degraded mode
+// in which strategies should skip their expensive work.
+package budget
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Config is the configuration of a Monitor.
+type Config struct {
+	// TickBudget is the maximum time the strategy should spend handling
+	// the messages of a tick. Exceeding it switches to degraded mode. If
+	// zero, only the server warnings switch to degraded mode.
+	TickBudget time.Duration
+
+	// RecoverTicks is the number of consecutive ticks within the budget
+	// after which the monitor leaves the degraded mode. If zero, the
+	// degraded mode is never left automatically.
+	RecoverTicks int
+
+	// Fallback, if not nil, is the strategy that receives the messages in
+	// degraded mode instead of the monitored strategy. It is usually a
+	// cheaper version of it.
+	Fallback rtb.Strategy
+
+	// OnChange, if not nil, is called when the monitor enters or leaves
+	// the degraded mode.
+	OnChange func(degraded bool)
+}
+
+// Stats are the utilization statistics of a strategy.
+type Stats struct {
+	// Ticks is the number of ticks measured.
+	Ticks int
+
+	// Total is the time spent handling messages.
+	Total time.Duration
+
+	// Max is the maximum time spent handling the messages of a tick.
+	Max time.Duration
+
+	// Elapsed is the wall time since the first message.
+	Elapsed time.Duration
+
+	// Warnings is the number of WarningProcessTimeLow warnings received.
+	Warnings int
+
+	// DegradedTicks is the number of ticks in degraded mode.
+	DegradedTicks int
+}
+
+// Mean returns the mean time spent handling the messages of a tick.
+func (s Stats) Mean() time.Duration {
+	if s.Ticks == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Ticks)
+}
+
+// Utilization returns the fraction of the wall time spent handling
+// messages.
+func (s Stats) Utilization() float64 {
+	if s.Elapsed == 0 {
+		return 0
+	}
+	return float64(s.Total) / float64(s.Elapsed)
+}
+
+// Monitor measures the time spent by a strategy. It implements the
+// rtb.Strategy interface, passing the messages to the monitored strategy.
+// Monitor methods can be called concurrently.
+type Monitor struct {
+	cfg Config
+	s   rtb.Strategy
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	stats    Stats
+	started  bool
+	start    time.Time
+	tick     time.Duration
+	degraded bool
+	good     int
+}
+
+// New returns a Monitor for s.
+func New(s rtb.Strategy, cfg Config) *Monitor {
+	return &Monitor{cfg: cfg, s: s, now: time.Now}
+}
+
+// Degraded returns true if the monitor is in degraded mode, so the strategy
+// should skip expensive work.
+func (m *Monitor) Degraded() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.degraded
+}
+
+// SetDegraded enters or leaves the degraded mode.
+func (m *Monitor) SetDegraded(degraded bool) {
+	m.mu.Lock()
+	changed := m.setDegraded(degraded)
+	m.mu.Unlock()
+
+	m.notify(changed, degraded)
+}
+
+// setDegraded sets the degraded mode. It returns true if it changed. m.mu
+// must be held.
+func (m *Monitor) setDegraded(degraded bool) bool {
+	if m.degraded == degraded {
+		return false
+	}
+	m.degraded, m.good = degraded, 0
+	return true
+}
+
+// notify calls OnChange if changed is true.
+func (m *Monitor) notify(changed, degraded bool) {
+	if changed && m.cfg.OnChange != nil {
+		m.cfg.OnChange(degraded)
+	}
+}
+
+// Stats returns the utilization statistics.
+func (m *Monitor) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+// Handle passes msg to the monitored strategy, or to the fallback strategy
+// in degraded mode, and measures the time spent.
+func (m *Monitor) Handle(r *rtb.Robot, msg rtb.Message) {
+	start := m.now()
+
+	if w, ok := msg.(rtb.MessageWarning); ok && w.Warning == rtb.WarningProcessTimeLow {
+		m.mu.Lock()
+		m.stats.Warnings++
+		changed := m.setDegraded(true)
+		m.mu.Unlock()
+
+		m.notify(changed, true)
+	}
+
+	m.mu.Lock()
+	if !m.started {
+		m.started, m.start = true, start
+	}
+	s := m.s
+	if m.degraded && m.cfg.Fallback != nil {
+		s = m.cfg.Fallback
+	}
+	m.mu.Unlock()
+
+	s.Handle(r, msg)
+
+	end := m.now()
+	m.mu.Lock()
+	m.tick += end.Sub(start)
+	m.stats.Total += end.Sub(start)
+	m.stats.Elapsed = end.Sub(m.start)
+	if _, ok := msg.(rtb.MessageInfo); !ok {
+		m.mu.Unlock()
+		return
+	}
+	changed, degraded := m.endTick()
+	m.mu.Unlock()
+
+	m.notify(changed, degraded)
+}
+
+// endTick updates the statistics and the mode at the end of a tick. It
+// returns true if the mode changed and the new mode. m.mu must be held.
+func (m *Monitor) endTick() (changed, degraded bool) {
+	tick := m.tick
+	m.tick = 0
+
+	m.stats.Ticks++
+	if tick > m.stats.Max {
+		m.stats.Max = tick
+	}
+	if m.degraded {
+		m.stats.DegradedTicks++
+	}
+
+	over := m.cfg.TickBudget > 0 && tick > m.cfg.TickBudget
+	switch {
+	case over:
+		changed = m.setDegraded(true)
+		m.good = 0
+	case m.degraded && m.cfg.RecoverTicks > 0:
+		m.good++
+		if m.good >= m.cfg.RecoverTicks {
+			changed = m.setDegraded(false)
+		}
+	}
+	return changed, m.degraded
+}
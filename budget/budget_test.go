@@ -0,0 +1,123 @@
+package budget
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestMonitor(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          Config
+		costs        []time.Duration
+		warnAt       int
+		wantDegraded bool
+		wantChanges  int
+		wantFallback int
+	}{
+		{
+			"Within budget",
+			Config{TickBudget: 10 * time.Millisecond},
+			[]time.Duration{time.Millisecond, 2 * time.Millisecond},
+			-1,
+			false,
+			0,
+			0,
+		},
+		{
+			"Over budget",
+			Config{TickBudget: 10 * time.Millisecond},
+			[]time.Duration{time.Millisecond, 20 * time.Millisecond, time.Millisecond},
+			-1,
+			true,
+			1,
+			1,
+		},
+		{
+			"Recover",
+			Config{TickBudget: 10 * time.Millisecond, RecoverTicks: 2},
+			[]time.Duration{20 * time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond},
+			-1,
+			false,
+			2,
+			2,
+		},
+		{
+			"Warning",
+			Config{},
+			[]time.Duration{time.Millisecond, time.Millisecond},
+			1,
+			true,
+			1,
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				clock    time.Time
+				cost     time.Duration
+				fallback int
+				changes  int
+			)
+			s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) { clock = clock.Add(cost) })
+			cfg := tt.cfg
+			cfg.Fallback = rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+				fallback++
+				clock = clock.Add(cost)
+			})
+			cfg.OnChange = func(bool) { changes++ }
+			m := New(s, cfg)
+			m.now = func() time.Time { return clock }
+
+			r := rtb.NewRobot(nil, io.Discard)
+			for i, c := range tt.costs {
+				cost = c
+				if i == tt.warnAt {
+					r.Deliver(m, rtb.MessageWarning{Warning: rtb.WarningProcessTimeLow})
+				}
+				r.Deliver(m, rtb.MessageInfo{Time: float64(i)})
+			}
+
+			if got := m.Degraded(); got != tt.wantDegraded {
+				t.Errorf("unexpected mode: got=%v want=%v", got, tt.wantDegraded)
+			}
+			if changes != tt.wantChanges {
+				t.Errorf("unexpected number of changes: got=%v want=%v", changes, tt.wantChanges)
+			}
+			if fallback != tt.wantFallback {
+				t.Errorf("unexpected fallback messages: got=%v want=%v", fallback, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	var clock time.Time
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) { clock = clock.Add(time.Millisecond) })
+	m := New(s, Config{})
+	m.now = func() time.Time { return clock }
+
+	r := rtb.NewRobot(nil, io.Discard)
+	for i := 0; i < 4; i++ {
+		r.Deliver(m, rtb.MessageInfo{Time: float64(i)})
+		// Time spent outside the strategy.
+		clock = clock.Add(time.Millisecond)
+	}
+
+	st := m.Stats()
+	if st.Ticks != 4 || st.Total != 4*time.Millisecond || st.Max != time.Millisecond {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if got := st.Mean(); got != time.Millisecond {
+		t.Errorf("unexpected mean: got=%v want=%v", got, time.Millisecond)
+	}
+	// The elapsed time ends with the last message, so it is 7ms.
+	if got, want := st.Utilization(), 4.0/7; got != want {
+		t.Errorf("unexpected utilization: got=%v want=%v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+// Package camp implements primitives for positional play, commonly used in
+// RealTimeBattle tournaments: going to the nearest corner, hugging the walls
+// and holding a position.
+//
+// The primitives use the boundary of the arena map, so the robot must know
+// its absolute position, i.e. the SendRobotCoordinates game option must be 2.
+package camp
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Camper.
+type Config struct {
+	// Margin is the distance from the boundary of the arena to the
+	// corners returned by NearestCorner. If zero, 2 is used.
+	Margin float64
+}
+
+// mode is the current behavior of a Camper.
+type mode int
+
+const (
+	modeIdle mode = iota
+	modeHug
+	modeHold
+)
+
+// Camper implements positional behaviors on top of a navigator. It
+// implements the rtb.Observer interface and must be added to the robot after
+// the world model and the navigator. Camper methods can be called
+// concurrently.
+type Camper struct {
+	rtb.NopCommand
+
+	cfg Config
+	a   *arena.Arena
+	w   *world.World
+	n   *nav.Navigator
+
+	mu       sync.Mutex
+	mode     mode
+	distance float64
+	hold     arena.Point
+	radius   float64
+	moving   bool
+}
+
+// New returns a Camper for the arena a.
+func New(a *arena.Arena, w *world.World, n *nav.Navigator, cfg Config) *Camper {
+	if cfg.Margin == 0 {
+		cfg.Margin = 2
+	}
+	return &Camper{cfg: cfg, a: a, w: w, n: n}
+}
+
+// corners returns the corners of the boundary of the arena inset by d, in
+// counterclockwise order.
+func (c *Camper) corners(d float64) []arena.Point {
+	b := c.a.Boundary
+	return []arena.Point{
+		{X: b.Min.X + d, Y: b.Min.Y + d},
+		{X: b.Max.X - d, Y: b.Min.Y + d},
+		{X: b.Max.X - d, Y: b.Max.Y - d},
+		{X: b.Min.X + d, Y: b.Max.Y - d},
+	}
+}
+
+// NearestCorner returns the corner of the arena closest to the robot, inset
+// by the configured margin.
+func (c *Camper) NearestCorner() arena.Point {
+	pos := c.w.State().Pos
+	corners := c.corners(c.cfg.Margin)
+
+	best := corners[0]
+	for _, p := range corners[1:] {
+		if p.Sub(pos).Len() < best.Sub(pos).Len() {
+			best = p
+		}
+	}
+	return best
+}
+
+// HugWall makes the robot go around the arena counterclockwise, keeping the
+// given distance to the boundary. The robot starts with the corner that
+// follows the closest side of the boundary and keeps going around until
+// another behavior is selected or Stop is called.
+func (c *Camper) HugWall(distance float64) {
+	c.mu.Lock()
+	c.mode, c.distance = modeHug, distance
+	c.mu.Unlock()
+
+	c.n.GoTo(c.wallPath(distance)...)
+}
+
+// wallPath returns a lap around the arena at the given distance from the
+// boundary, starting with the corner that follows the closest side.
+func (c *Camper) wallPath(distance float64) []arena.Point {
+	pos := c.w.State().Pos
+	corners := c.corners(distance)
+
+	start, best := 0, math.Inf(1)
+	for i := range corners {
+		a, b := corners[i], corners[(i+1)%len(corners)]
+		if d := arena.SegmentDistance(pos, a, b); d < best {
+			start, best = (i+1)%len(corners), d
+		}
+	}
+
+	path := make([]arena.Point, len(corners))
+	for i := range path {
+		path[i] = corners[(start+i)%len(corners)]
+	}
+	return path
+}
+
+// HoldPosition makes the robot stay within radius of p. The robot goes back
+// to p every time it is pushed out.
+func (c *Camper) HoldPosition(p arena.Point, radius float64) {
+	c.mu.Lock()
+	c.mode, c.hold, c.radius, c.moving = modeHold, p, radius, false
+	c.mu.Unlock()
+
+	c.check()
+}
+
+// Stop stops the current behavior and the navigator.
+func (c *Camper) Stop() {
+	c.mu.Lock()
+	c.mode = modeIdle
+	c.mu.Unlock()
+
+	c.n.Stop()
+}
+
+// Message keeps the current behavior going when msg is an Info message. The
+// behavior is stopped when a new game starts.
+func (c *Camper) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		c.mu.Lock()
+		c.mode = modeIdle
+		c.mu.Unlock()
+	case rtb.MessageInfo:
+		c.check()
+	}
+}
+
+// check updates the plan of the navigator according to the current
+// behavior.
+func (c *Camper) check() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.mode {
+	case modeHug:
+		if c.n.Done() {
+			c.n.GoTo(c.wallPath(c.distance)...)
+		}
+	case modeHold:
+		dist := c.hold.Sub(c.w.State().Pos).Len()
+		switch {
+		case !c.moving && dist > c.radius:
+			// Aim at the center, so the robot does not stop at
+			// the edge.
+			c.moving = true
+			c.n.GoTo(c.hold)
+		case c.moving && c.n.Done():
+			c.moving = false
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package camp
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/world"
+)
+
+// setup returns a camper for a 20x10 arena and a function that moves the
+// robot to a position and delivers an Info message.
+func setup() (*nav.Navigator, *Camper, func(p arena.Point)) {
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	n := nav.New(r, w, nav.Config{})
+	c := New(arena.Rectangle(20, 10), w, n, Config{})
+	for _, obs := range []rtb.Observer{w, n, c} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+
+	var time float64
+	move := func(p arena.Point) {
+		time++
+		r.Deliver(nop, rtb.MessageCoordinates{X: p.X, Y: p.Y})
+		r.Deliver(nop, rtb.MessageInfo{Time: time})
+	}
+	return n, c, move
+}
+
+func TestNearestCorner(t *testing.T) {
+	tests := []struct {
+		pos  arena.Point
+		want arena.Point
+	}{
+		{arena.Point{X: 1, Y: 1}, arena.Point{X: 2, Y: 2}},
+		{arena.Point{X: 15, Y: 2}, arena.Point{X: 18, Y: 2}},
+		{arena.Point{X: 12, Y: 9}, arena.Point{X: 18, Y: 8}},
+		{arena.Point{X: 3, Y: 6}, arena.Point{X: 2, Y: 8}},
+	}
+
+	for _, tt := range tests {
+		_, c, move := setup()
+		move(tt.pos)
+		if got := c.NearestCorner(); got != tt.want {
+			t.Errorf("unexpected corner for %v: got=%v want=%v", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestHugWall(t *testing.T) {
+	n, c, move := setup()
+	move(arena.Point{X: 10, Y: 1})
+	c.HugWall(1)
+
+	want := []arena.Point{{X: 19, Y: 1}, {X: 19, Y: 9}, {X: 1, Y: 9}, {X: 1, Y: 1}}
+	got := n.Waypoints()
+	if len(got) != len(want) {
+		t.Fatalf("unexpected waypoints: got=%v want=%v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("unexpected waypoint %v: got=%v want=%v", i, got[i], want[i])
+		}
+	}
+
+	// A new lap is planned after finishing the current one.
+	for _, p := range want {
+		move(p)
+	}
+	move(arena.Point{X: 1, Y: 1})
+	if got := n.Waypoints(); len(got) != 4 {
+		t.Errorf("unexpected waypoints after a lap: %v", got)
+	}
+
+	c.Stop()
+	move(arena.Point{X: 1, Y: 1})
+	if !n.Done() {
+		t.Errorf("unexpected waypoints after stopping: %v", n.Waypoints())
+	}
+}
+
+func TestHoldPosition(t *testing.T) {
+	n, c, move := setup()
+	hold := arena.Point{X: 5, Y: 5}
+
+	move(arena.Point{X: 5.5, Y: 5})
+	c.HoldPosition(hold, 2)
+	if !n.Done() {
+		t.Errorf("unexpected waypoints inside the radius: %v", n.Waypoints())
+	}
+
+	move(arena.Point{X: 8, Y: 5})
+	if got := n.Waypoints(); len(got) != 1 || got[0] != hold {
+		t.Errorf("unexpected waypoints outside the radius: %v", got)
+	}
+
+	move(arena.Point{X: 5.2, Y: 5})
+	move(arena.Point{X: 5.2, Y: 5})
+	if !n.Done() {
+		t.Errorf("unexpected waypoints after going back: %v", n.Waypoints())
+	}
+}
@@ -0,0 +1,68 @@
+package rtb
+
+// Envelope is a message together with the game time at which it was
+// delivered.
+type Envelope struct {
+	// Time is the latest game time known when the message was
+	// delivered, i.e. the time of the last Info message of the current
+	// game. Messages received before the first Info message of a game
+	// have time zero.
+	Time float64
+
+	// Msg is the delivered message.
+	Msg Message
+}
+
+// EnvelopeFunc is an adapter to allow the use of ordinary functions that
+// take an Envelope as strategies.
+type EnvelopeFunc func(r *Robot, e Envelope)
+
+// Handle calls f with msg and the current game time of r.
+func (f EnvelopeFunc) Handle(r *Robot, msg Message) {
+	f(r, Envelope{Time: r.GameClock(), Msg: msg})
+}
+
+// GameClock returns the latest game time known by r, i.e. the time of the
+// last Info message delivered in the current game. It is reset to zero when
+// a game starts.
+func (r *Robot) GameClock() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.clock
+}
+
+// GameClock calls GameClock on the default Robot.
+func GameClock() float64 {
+	return std.GameClock()
+}
+
+// Current returns the message being delivered and the game time at which it
+// was delivered. It returns false if no message is being delivered. It is
+// meant to be called by observers and strategies.
+func (r *Robot) Current() (Envelope, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current == nil {
+		return Envelope{}, false
+	}
+	return Envelope{Time: r.clock, Msg: r.current}, true
+}
+
+// Current calls Current on the default Robot.
+func Current() (Envelope, bool) {
+	return std.Current()
+}
+
+// tick updates the game clock with msg. r.mu must be held.
+func (r *Robot) tick(msg Message) {
+	switch m := msg.(type) {
+	case MessageGameStarts, *MessageGameStarts:
+		r.clock = 0
+	case MessageInfo:
+		r.clock = m.Time
+	case *MessageInfo:
+		r.clock = m.Time
+	}
+}
@@ -0,0 +1,154 @@
+// rtbbridge runs a robot on a different machine, or in a container, than the
+// RealTimeBattle server. It carries the protocol over a TCP or WebSocket
+// connection, with heartbeats and reconnection.
+//
+// Usage:
+//
+//	rtbbridge [flags] -listen address
+//	rtbbridge [flags] -connect address robot [args...]
+//
+// Next to the server, rtbbridge is used as the robot and waits for the other
+// end to connect to address. Next to the robot, rtbbridge runs the robot and
+// connects to address.
+//
+// With -ws, the WebSocket path "/rtb" is served or requested instead of
+// using plain TCP.
+//
+// The RealTimeBattle server does not pass arguments to the robots, so
+// rtbbridge is usually called from a shell script that is used as the robot:
+//
+//	#!/bin/sh
+//	exec rtbbridge -listen :9000
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/jroimartin/rtb/bridge"
+)
+
+func main() {
+	listen := flag.String("listen", "", "wait for the robot side to connect to `address`")
+	connect := flag.String("connect", "", "connect to the server side at `address`")
+	ws := flag.Bool("ws", false, "use WebSocket instead of TCP")
+	heartbeat := flag.Duration("heartbeat", 0, "heartbeat `interval` (default 1s)")
+	timeout := flag.Duration("timeout", 0, "connection `timeout` (default 5s)")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetPrefix("rtbbridge: ")
+	log.SetFlags(0)
+
+	cfg := bridge.Config{
+		Heartbeat: *heartbeat,
+		Timeout:   *timeout,
+		// The standard output is used by the protocol, so the logs
+		// go to the standard error.
+		Logger: slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	}
+
+	var err error
+	switch {
+	case *listen != "" && *connect == "" && flag.NArg() == 0:
+		err = serve(*listen, *ws, cfg)
+	case *connect != "" && *listen == "" && flag.NArg() > 0:
+		var code int
+		code, err = run(*connect, *ws, cfg, flag.Arg(0), flag.Args()[1:])
+		if err == nil {
+			os.Exit(code)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbbridge [flags] -listen address\n")
+	fmt.Fprintf(os.Stderr, "       rtbbridge [flags] -connect address robot [args...]\n")
+	flag.PrintDefaults()
+}
+
+// serve bridges the standard input and output of the process with the robot
+// side.
+func serve(addr string, ws bool, cfg bridge.Config) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen: %v", err)
+	}
+	defer l.Close()
+
+	dial := bridge.Accept(l)
+	if ws {
+		dial = bridge.ListenWebSocket(l, "/rtb")
+	}
+
+	err = bridge.Run(context.Background(), os.Stdin, os.Stdout, dial, cfg)
+	if errors.Is(err, bridge.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// run runs the robot and bridges its standard input and output with the
+// server side. It returns the exit code of the robot.
+func run(addr string, ws bool, cfg bridge.Config, name string, args []string) (int, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 1, fmt.Errorf("could not get robot stdin: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 1, fmt.Errorf("could not get robot stdout: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 1, fmt.Errorf("could not start robot: %v", err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	dial := bridge.DialTCP(addr)
+	if ws {
+		dial = bridge.DialWebSocket("ws://" + addr + "/rtb")
+	}
+
+	// The bridge finishes when the robot closes its standard output or
+	// the server side closes its input. In the latter case, the
+	// standard input of the robot is closed, so it exits.
+	if err := bridge.Run(context.Background(), stdout, stdin, dial, cfg); err != nil && !errors.Is(err, bridge.ErrClosed) {
+		log.Printf("bridge error: %v", err)
+	}
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
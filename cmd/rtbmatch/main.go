@@ -0,0 +1,158 @@
+// rtbmatch runs a match between robots with the RealTimeBattle server and
+// prints the results. It gives tournament organizers a reproducible
+// pipeline, from the sources of the robots to the standings.
+//
+// Usage:
+//
+//	rtbmatch [flags] robot...
+//
+// Every robot is either a directory with a Go package, which is built, or
+// an executable. The match is run without graphics, in a work directory
+// that also contains the log of the server. It is removed at the end,
+// unless -work is given.
+//
+// With -image, every robot runs in a container of that image, created with
+// docker run, without network and with the limits given by -memory and
+// -cpus. The Go robots are statically linked, so they can run in minimal
+// images.
+//
+// The results are written to the standard output as a table or, with
+// -json, as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jroimartin/rtb/serverlog"
+)
+
+// stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func main() {
+	var arenas stringList
+	flag.Var(&arenas, "arena", "arena `file` (can be given multiple times)")
+	server := flag.String("server", "realtimebattle", "`path` of the server")
+	options := flag.String("options", "", "options `file` of the server")
+	games := flag.Int("games", 1, "`number` of games per sequence")
+	sequences := flag.Int("sequences", 1, "`number` of sequences")
+	image := flag.String("image", "", "run every robot in a container of `image`")
+	memory := flag.String("memory", "", "memory `limit` of every container, e.g. 256m")
+	cpus := flag.String("cpus", "", "CPU `limit` of every container, e.g. 0.5")
+	work := flag.String("work", "", "keep the work files in `dir`")
+	jsonOut := flag.Bool("json", false, "write the results as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetPrefix("rtbmatch: ")
+	log.SetFlags(0)
+
+	if flag.NArg() < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	m := match{
+		server:    *server,
+		arenas:    arenas,
+		options:   *options,
+		games:     *games,
+		sequences: *sequences,
+		sandbox:   sandbox{image: *image, memory: *memory, cpus: *cpus},
+	}
+	if err := run(m, *work, *jsonOut, flag.Args()); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbmatch [flags] robot...\n")
+	flag.PrintDefaults()
+}
+
+// run runs the match between robots and writes the results to the standard
+// output. If work is empty, the work files are written to a temporary
+// directory, which is removed at the end.
+func run(m match, work string, jsonOut bool, robots []string) error {
+	dir := work
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "rtbmatch")
+		if err != nil {
+			return fmt.Errorf("could not create work directory: %v", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	res, err := runMatch(m, dir, robots)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		err = writeJSON(os.Stdout, res)
+	} else {
+		err = writeTable(os.Stdout, res)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write results: %v", err)
+	}
+	return nil
+}
+
+// runMatch prepares the robots in dir, runs the match and returns the
+// standings.
+func runMatch(m match, dir string, robots []string) ([]standing, error) {
+	execs, err := m.prepare(dir, robots)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "tournament.txt")
+	if err := os.WriteFile(path, []byte(m.tournament(execs)), 0644); err != nil {
+		return nil, fmt.Errorf("could not write tournament file: %v", err)
+	}
+
+	logPath := filepath.Join(dir, "match.log")
+	if err := m.run(path, logPath); err != nil {
+		return nil, err
+	}
+
+	l, err := serverlog.Load(logPath)
+	if err != nil {
+		return nil, err
+	}
+	return standings(l), nil
+}
+
+// writeTable writes res as a table.
+func writeTable(w io.Writer, res []standing) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ROBOT\tGAMES\tWINS\tPOINTS\n")
+	for _, s := range res {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", s.Name, s.Games, s.Wins, s.Points)
+	}
+	return tw.Flush()
+}
+
+// writeJSON writes res as JSON.
+func writeJSON(w io.Writer, res []standing) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(res)
+}
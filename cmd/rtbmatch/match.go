@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jroimartin/rtb/serverlog"
+)
+
+// sandbox is the container configuration of the robots.
+type sandbox struct {
+	// image is the container image. If empty, the robots run directly
+	// on the host.
+	image string
+
+	// memory and cpus are the resource limits of every robot, as
+	// accepted by docker run. If empty, there is no limit.
+	memory, cpus string
+}
+
+// match is the configuration of a match.
+type match struct {
+	server    string
+	arenas    []string
+	options   string
+	games     int
+	sequences int
+	sandbox   sandbox
+}
+
+// buildRobot builds the robot in the Go package dir and writes the binary
+// to out. Binaries are statically linked, so they can run in minimal
+// container images.
+func buildRobot(dir, out string) error {
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not build robot %v: %v", dir, err)
+	}
+	return nil
+}
+
+// wrapper returns a shell script that runs the robot binary bin in a
+// container, without network and with the limits of sb. The directory of
+// bin is mounted read-only.
+func wrapper(bin string, sb sandbox) string {
+	args := []string{"docker", "run", "--rm", "-i", "--network", "none", "--read-only"}
+	if sb.memory != "" {
+		args = append(args, "--memory", sb.memory)
+	}
+	if sb.cpus != "" {
+		args = append(args, "--cpus", sb.cpus)
+	}
+	dir, name := filepath.Split(bin)
+	args = append(args, "-v", filepath.Clean(dir)+":/robot:ro", sb.image, "/robot/"+name)
+
+	for i, a := range args {
+		args[i] = shellQuote(a)
+	}
+	return "#!/bin/sh\nexec " + strings.Join(args, " ") + "\n"
+}
+
+// shellQuote quotes s for the shell, if needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// tournament returns the tournament file of the server for the robots, the
+// executables that the server runs.
+func (m match) tournament(robots []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Robots: %v\n", strings.Join(robots, " "))
+	if len(m.arenas) > 0 {
+		fmt.Fprintf(&b, "Arenas: %v\n", strings.Join(m.arenas, " "))
+	}
+	fmt.Fprintf(&b, "Robots/Sequence: %v\n", len(robots))
+	fmt.Fprintf(&b, "Games/Sequence: %v\n", m.games)
+	fmt.Fprintf(&b, "Sequences: %v\n", m.sequences)
+	return b.String()
+}
+
+// prepare writes the executables of the robots to dir, building the Go
+// packages and wrapping the binaries in containers if a sandbox image is
+// configured. It returns the paths of the executables.
+func (m match) prepare(dir string, robots []string) ([]string, error) {
+	binDir := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create directory: %v", err)
+	}
+
+	seen := make(map[string]int)
+	var execs []string
+	for _, robot := range robots {
+		abs, err := filepath.Abs(robot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid robot path: %v", err)
+		}
+
+		// The server identifies the robots by the name of their
+		// executable.
+		name := filepath.Base(abs)
+		if n := seen[name]; n > 0 {
+			name = fmt.Sprintf("%v_%v", name, n+1)
+		}
+		seen[filepath.Base(abs)]++
+
+		bin := abs
+		if fi, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("invalid robot: %v", err)
+		} else if fi.IsDir() {
+			bin = filepath.Join(binDir, name)
+			if err := buildRobot(abs, bin); err != nil {
+				return nil, err
+			}
+		}
+
+		if m.sandbox.image == "" {
+			execs = append(execs, bin)
+			continue
+		}
+		if bin == abs {
+			// Only the binary directory is mounted in the
+			// container.
+			data, err := os.ReadFile(abs)
+			if err != nil {
+				return nil, fmt.Errorf("could not read robot: %v", err)
+			}
+			bin = filepath.Join(binDir, name)
+			if err := os.WriteFile(bin, data, 0755); err != nil {
+				return nil, fmt.Errorf("could not copy robot: %v", err)
+			}
+		}
+		script := filepath.Join(dir, name)
+		if err := os.WriteFile(script, []byte(wrapper(bin, m.sandbox)), 0755); err != nil {
+			return nil, fmt.Errorf("could not write wrapper: %v", err)
+		}
+		execs = append(execs, script)
+	}
+	return execs, nil
+}
+
+// run runs the server without graphics, with the tournament file at path,
+// and writes the log to logPath.
+func (m match) run(path, logPath string) error {
+	args := []string{"-n", "-t", path, "-l", logPath}
+	if m.options != "" {
+		args = append(args, "-o", m.options)
+	}
+	cmd := exec.Command(m.server, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("server error: %v", err)
+	}
+	return nil
+}
+
+// standing are the results of a robot in a match.
+type standing struct {
+	Name   string  `json:"name"`
+	Games  int     `json:"games"`
+	Wins   int     `json:"wins"`
+	Points float64 `json:"points"`
+}
+
+// standings returns the results of the robots in l, sorted by points.
+func standings(l *serverlog.Log) []standing {
+	byID := make(map[int]*standing)
+	var all []*standing
+	get := func(r serverlog.Robot) *standing {
+		s, ok := byID[r.ID]
+		if !ok {
+			s = &standing{Name: r.Name}
+			byID[r.ID] = s
+			all = append(all, s)
+		}
+		return s
+	}
+
+	for _, r := range l.Robots {
+		get(r)
+	}
+	for _, g := range l.Games {
+		for _, r := range g.Robots {
+			get(r).Games++
+		}
+		for _, d := range g.Deaths {
+			s := get(serverlog.Robot{ID: d.ID})
+			s.Points += d.Points
+			if d.Position == 1 {
+				s.Wins++
+			}
+		}
+	}
+
+	res := make([]standing, len(all))
+	for i, s := range all {
+		res[i] = *s
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Points > res[j].Points
+	})
+	return res
+}
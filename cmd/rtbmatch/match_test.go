@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb/serverlog"
+)
+
+func TestWrapper(t *testing.T) {
+	got := wrapper("/work/bin/my robot", sandbox{image: "alpine:3", memory: "256m"})
+	want := "#!/bin/sh\nexec docker run --rm -i --network none --read-only --memory 256m " +
+		"-v /work/bin:/robot:ro alpine:3 '/robot/my robot'\n"
+	if got != want {
+		t.Errorf("unexpected wrapper: got=%q want=%q", got, want)
+	}
+}
+
+func TestTournament(t *testing.T) {
+	m := match{arenas: []string{"a.arena"}, games: 3, sequences: 2}
+	got := m.tournament([]string{"/bin/a", "/bin/b"})
+	want := "Robots: /bin/a /bin/b\nArenas: a.arena\nRobots/Sequence: 2\nGames/Sequence: 3\nSequences: 2\n"
+	if got != want {
+		t.Errorf("unexpected tournament: got=%q want=%q", got, want)
+	}
+}
+
+func TestStandings(t *testing.T) {
+	l, err := serverlog.Load("../../serverlog/testdata/game.log")
+	if err != nil {
+		t.Fatalf("could not load log: %v", err)
+	}
+
+	got := standings(l)
+	want := []standing{
+		{Name: "turret bot", Games: 1, Wins: 1, Points: 1},
+		{Name: "spinner", Games: 2, Wins: 0, Points: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected standings: got=%+v want=%+v", got, want)
+	}
+}
+
+func TestRunMatch(t *testing.T) {
+	dir := t.TempDir()
+
+	// The fake server copies a log to the path given with -l.
+	server := filepath.Join(dir, "server")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do\n" +
+		"\tif [ \"$1\" = -l ]; then cp ../../serverlog/testdata/game.log \"$2\"; fi\n" +
+		"\tshift\ndone\n"
+	if err := os.WriteFile(server, []byte(script), 0755); err != nil {
+		t.Fatalf("could not write server: %v", err)
+	}
+	robot := filepath.Join(dir, "robot")
+	if err := os.WriteFile(robot, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("could not write robot: %v", err)
+	}
+
+	m := match{server: server, games: 1, sequences: 1, sandbox: sandbox{image: "alpine"}}
+	work := filepath.Join(dir, "work")
+	res, err := runMatch(m, work, []string{robot, robot})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 2 || res[0].Name != "turret bot" {
+		t.Errorf("unexpected results: %+v", res)
+	}
+
+	tournament, err := os.ReadFile(filepath.Join(work, "tournament.txt"))
+	if err != nil {
+		t.Fatalf("could not read tournament file: %v", err)
+	}
+	wantRobots := "Robots: " + filepath.Join(work, "robot") + " " + filepath.Join(work, "robot_2") + "\n"
+	if !strings.HasPrefix(string(tournament), wantRobots) {
+		t.Errorf("unexpected tournament file: %q", tournament)
+	}
+	for _, name := range []string{"robot", "robot_2", "bin/robot", "bin/robot_2"} {
+		if _, err := os.Stat(filepath.Join(work, name)); err != nil {
+			t.Errorf("missing work file: %v", err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+type point = arena.Point
+
+// radarHit is an object detected by the radar.
+type radarHit struct {
+	pos    point
+	object rtb.Object
+}
+
+// shotMark is a shot fired by the robot.
+type shotMark struct {
+	pos    point
+	angle  float64
+	energy float64
+}
+
+// collisionMark is a collision suffered by the robot.
+type collisionMark struct {
+	pos    point
+	object rtb.Object
+}
+
+// game is the inferred state of a game.
+type game struct {
+	path       []point
+	radar      []radarHit
+	shots      []shotMark
+	collisions []collisionMark
+	dead       bool
+}
+
+// points returns all the points of the game.
+func (g *game) points() []point {
+	pts := append([]point(nil), g.path...)
+	for _, h := range g.radar {
+		pts = append(pts, h.pos)
+	}
+	return pts
+}
+
+// inferrer infers the games played by a robot from its message and command
+// logs.
+//
+// If the server sends Coordinates messages, they are used as the position
+// of the robot. Otherwise, the position is estimated by dead reckoning,
+// using the speed reported by the Info messages and the robot rotations
+// requested with Rotate. In that case, the start position is (0, 0) and the
+// initial angle is 0.
+type inferrer struct {
+	games []*game
+	cur   *game
+
+	time        float64
+	pos         point
+	angle       float64
+	speed       float64
+	cannon      float64
+	robotRotate float64
+	maxRotate   float64
+	coords      bool
+}
+
+// parseLog reads a log with the format of rtbwatch or, if the lines are not
+// timestamped, a log with the messages sent by the server, like the ones
+// recorded by the replay package.
+func parseLog(r io.Reader) ([]*game, error) {
+	in := &inferrer{maxRotate: math.Inf(1)}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		dir, line := splitLine(s.Text())
+		switch dir {
+		case "<":
+			msg, err := rtb.ParseMessage(line)
+			if err != nil {
+				continue
+			}
+			in.message(msg)
+		case ">":
+			in.command(strings.Fields(line))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("could not read log: %v", err)
+	}
+
+	return in.games, nil
+}
+
+// splitLine returns the direction and the message of a log line. Lines
+// without timestamp are considered messages sent by the server.
+func splitLine(line string) (dir, msg string) {
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+	if len(fields) == 3 {
+		if _, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+			return fields[1], fields[2]
+		}
+	}
+	return "<", line
+}
+
+// message updates the inferred state with a message sent by the server.
+func (in *inferrer) message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameOption:
+		if m.Option == rtb.GOptionRobotMaxRotate {
+			in.maxRotate = m.Value
+		}
+	case rtb.MessageGameStarts:
+		in.cur = &game{}
+		in.games = append(in.games, in.cur)
+		in.time, in.pos, in.angle, in.speed, in.robotRotate = 0, point{}, 0, 0, 0
+		in.coords = false
+	}
+
+	if in.cur == nil {
+		return
+	}
+
+	switch m := msg.(type) {
+	case rtb.MessageInfo:
+		dt := m.Time - in.time
+		if !in.coords && dt > 0 {
+			in.angle += in.robotRotate * dt
+			in.pos = in.pos.Add(arena.Polar(in.angle, in.speed*dt))
+			in.cur.path = append(in.cur.path, in.pos)
+		}
+		in.time, in.speed, in.cannon = m.Time, m.Speed, m.CannonAngle
+	case rtb.MessageCoordinates:
+		in.coords = true
+		in.pos, in.angle = point{X: m.X, Y: m.Y}, m.Angle
+		in.cur.path = append(in.cur.path, in.pos)
+	case rtb.MessageRadar:
+		pos := in.pos.Add(arena.Polar(in.angle+m.RadarAngle, m.Distance))
+		in.cur.radar = append(in.cur.radar, radarHit{pos, m.Object})
+	case rtb.MessageCollision:
+		pos := in.pos.Add(arena.Polar(in.angle+m.Angle, physics.RobotRadius))
+		in.cur.collisions = append(in.cur.collisions, collisionMark{pos, m.Object})
+	case rtb.MessageDead:
+		in.cur.dead = true
+	}
+}
+
+// command updates the inferred state with a command sent by the robot.
+func (in *inferrer) command(fields []string) {
+	if in.cur == nil || len(fields) == 0 {
+		return
+	}
+
+	switch fields[0] {
+	case "Rotate":
+		if len(fields) != 3 {
+			return
+		}
+		part, err1 := strconv.Atoi(fields[1])
+		v, err2 := strconv.ParseFloat(fields[2], 64)
+		if err1 != nil || err2 != nil || rtb.Part(part)&rtb.PartRobot == 0 {
+			return
+		}
+		in.robotRotate = math.Max(-in.maxRotate, math.Min(v, in.maxRotate))
+	case "Shoot":
+		if len(fields) != 2 {
+			return
+		}
+		energy, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return
+		}
+		in.cur.shots = append(in.cur.shots, shotMark{in.pos, in.angle + in.cannon, energy})
+	}
+}
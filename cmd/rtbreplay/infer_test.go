@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestParseLog(t *testing.T) {
+	const logData = `2022-01-01T00:00:00Z < GameStarts
+2022-01-01T00:00:00Z > Rotate 1 1.000000
+2022-01-01T00:00:00Z < Info 1 2 0
+2022-01-01T00:00:00Z < Info 2 2 0
+2022-01-01T00:00:00Z < Radar 3 2 0
+2022-01-01T00:00:00Z > Shoot 5.000000
+2022-01-01T00:00:00Z < Dead
+GameStarts
+Coordinates 1 2 0
+Collision 0 0
+`
+
+	games, err := parseLog(strings.NewReader(logData))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(games), 2)
+	}
+
+	g := games[0]
+	if len(g.path) != 2 || !g.dead {
+		t.Fatalf("unexpected game: %#v", g)
+	}
+	// The robot starts rotating at 1 rad/s, so after 1 second its angle
+	// is 1 and it has moved 0 units (speed was 0). After 2 seconds it has
+	// moved 2 units with angle 2.
+	want := point{X: 2 * math.Cos(2), Y: 2 * math.Sin(2)}
+	if p := g.path[1]; math.Abs(p.X-want.X) > 1e-9 || math.Abs(p.Y-want.Y) > 1e-9 {
+		t.Errorf("unexpected position: got=%v want=%v", p, want)
+	}
+	if len(g.radar) != 1 || g.radar[0].object != rtb.ObjectWall {
+		t.Errorf("unexpected radar hits: %#v", g.radar)
+	}
+	if len(g.shots) != 1 || g.shots[0].energy != 5 || g.shots[0].angle != 2 {
+		t.Errorf("unexpected shots: %#v", g.shots)
+	}
+
+	g = games[1]
+	if len(g.path) != 1 || g.path[0] != (point{X: 1, Y: 2}) {
+		t.Errorf("unexpected path: %#v", g.path)
+	}
+	if len(g.collisions) != 1 || g.collisions[0].pos != (point{X: 1.5, Y: 2}) {
+		t.Errorf("unexpected collisions: %#v", g.collisions)
+	}
+}
+
+func TestRender(t *testing.T) {
+	g := &game{
+		path:  []point{{X: 0, Y: 0}, {X: 5, Y: 5}},
+		radar: []radarHit{{point{X: 10, Y: 0}, rtb.ObjectWall}},
+		shots: []shotMark{{point{X: 0, Y: 0}, 0, 5}},
+	}
+
+	var svg, text strings.Builder
+	if err := renderSVG(&svg, g); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(svg.String(), "<svg") || !strings.Contains(svg.String(), "<polyline") {
+		t.Errorf("unexpected SVG:\n%v", svg.String())
+	}
+
+	if err := renderText(&text, g, 40); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range "SE#" {
+		if !strings.ContainsRune(text.String(), r) {
+			t.Errorf("missing %q in text output:\n%v", r, text.String())
+		}
+	}
+}
+
+func TestParseServerLog(t *testing.T) {
+	f, err := os.Open("../../serverlog/testdata/game.log")
+	if err != nil {
+		t.Fatalf("could not open log: %v", err)
+	}
+	defer f.Close()
+
+	games, err := parseServerLog(f, "turret bot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(games), 1)
+	}
+
+	g := games[0]
+	if len(g.path) != 4 || g.path[0] != (point{X: 5, Y: 5}) || g.dead {
+		t.Errorf("unexpected game: %#v", g)
+	}
+	if len(g.shots) != 1 || g.shots[0].angle != 0 {
+		t.Errorf("unexpected shots: %#v", g.shots)
+	}
+
+	if _, err := parseServerLog(strings.NewReader(""), "turret bot"); err == nil {
+		t.Errorf("expected error for missing robot")
+	}
+}
@@ -0,0 +1,88 @@
+// rtbreplay renders the games recorded in a log, showing the path of the
+// robot, the objects detected by its radar, the shots it fired and the
+// collisions it suffered. It helps to understand why a robot lost.
+//
+// Usage:
+//
+//	rtbreplay [-game n] [-format svg|text] [-width n] [-robot name] [file]
+//
+// The log can be generated by rtbwatch or by the replay package. Since the
+// game is inferred from the point of view of the robot, it is more accurate
+// when the server sends robot coordinates (game option
+// SendRobotCoordinates). If file is not given, the log is read from the
+// standard input.
+//
+// If -robot is given, the log is read as a log file of the RealTimeBattle
+// server instead, and the games of the robot name are rendered. Their
+// positions are exact, but the server does not log radar hits nor
+// collisions.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+	gameNum := flag.Int("game", 1, "render game `n` of the log")
+	format := flag.String("format", "svg", "output format (svg or text)")
+	width := flag.Int("width", 78, "width of the text output")
+	robot := flag.String("robot", "", "read a server log and render the robot `name`")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetPrefix("rtbreplay: ")
+	log.SetFlags(0)
+
+	var r io.Reader = os.Stdin
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatalf("could not open log: %v", err)
+		}
+		defer f.Close()
+		r = f
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	var (
+		games []*game
+		err   error
+	)
+	if *robot != "" {
+		games, err = parseServerLog(r, *robot)
+	} else {
+		games, err = parseLog(r)
+	}
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	if *gameNum < 1 || *gameNum > len(games) {
+		log.Fatalf("game %v not found (the log contains %v games)", *gameNum, len(games))
+	}
+	g := games[*gameNum-1]
+
+	switch *format {
+	case "svg":
+		err = renderSVG(os.Stdout, g)
+	case "text":
+		err = renderText(os.Stdout, g, *width)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("could not render game: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbreplay [flags] [file]\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// bounds returns the bounding box of pts, enlarged by margin.
+func bounds(pts []point, margin float64) arena.Rect {
+	if len(pts) == 0 {
+		return arena.Rect{Max: point{X: 1, Y: 1}}
+	}
+	r := arena.Rect{Min: pts[0], Max: pts[0]}
+	for _, p := range pts[1:] {
+		r.Min.X, r.Min.Y = math.Min(r.Min.X, p.X), math.Min(r.Min.Y, p.Y)
+		r.Max.X, r.Max.Y = math.Max(r.Max.X, p.X), math.Max(r.Max.Y, p.Y)
+	}
+	r.Min = r.Min.Sub(point{X: margin, Y: margin})
+	r.Max = r.Max.Add(point{X: margin, Y: margin})
+	return r
+}
+
+// objectColours maps object types to SVG colours.
+var objectColours = map[rtb.Object]string{
+	rtb.ObjectRobot:  "red",
+	rtb.ObjectShot:   "gold",
+	rtb.ObjectWall:   "gray",
+	rtb.ObjectCookie: "green",
+	rtb.ObjectMine:   "orange",
+}
+
+// renderSVG renders g as an SVG image.
+func renderSVG(w io.Writer, g *game) error {
+	b := bounds(g.points(), 1)
+	unit := math.Max(b.Dx(), b.Dy()) / 200
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%g %g %g %g" width="800" height="%g">`+"\n",
+		b.Min.X, b.Min.Y, b.Dx(), b.Dy(), math.Round(800*b.Dy()/b.Dx()))
+	fmt.Fprintf(&sb, `<rect x="%g" y="%g" width="%g" height="%g" fill="white"/>`+"\n", b.Min.X, b.Min.Y, b.Dx(), b.Dy())
+
+	for _, h := range g.radar {
+		colour, ok := objectColours[h.object]
+		if !ok {
+			colour = "black"
+		}
+		fmt.Fprintf(&sb, `<circle cx="%g" cy="%g" r="%g" fill="%v" fill-opacity="0.5"/>`+"\n", h.pos.X, h.pos.Y, unit, colour)
+	}
+
+	if len(g.path) > 0 {
+		fmt.Fprintf(&sb, `<polyline fill="none" stroke="blue" stroke-width="%g" points="`, unit/2)
+		for _, p := range g.path {
+			fmt.Fprintf(&sb, "%g,%g ", p.X, p.Y)
+		}
+		fmt.Fprintf(&sb, `"/>`+"\n")
+
+		start, end := g.path[0], g.path[len(g.path)-1]
+		fmt.Fprintf(&sb, `<circle cx="%g" cy="%g" r="%g" fill="green"/>`+"\n", start.X, start.Y, physics.RobotRadius)
+		endColour := "blue"
+		if g.dead {
+			endColour = "black"
+		}
+		fmt.Fprintf(&sb, `<circle cx="%g" cy="%g" r="%g" fill="%v"/>`+"\n", end.X, end.Y, physics.RobotRadius, endColour)
+	}
+
+	for _, s := range g.shots {
+		end := s.pos.Add(arena.Polar(s.angle, 1+s.energy/10))
+		fmt.Fprintf(&sb, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="red" stroke-width="%g"/>`+"\n",
+			s.pos.X, s.pos.Y, end.X, end.Y, unit/2)
+	}
+
+	for _, c := range g.collisions {
+		d := 2 * unit
+		fmt.Fprintf(&sb, `<path d="M%g %gL%g %gM%g %gL%g %g" stroke="black" stroke-width="%g"/>`+"\n",
+			c.pos.X-d, c.pos.Y-d, c.pos.X+d, c.pos.Y+d, c.pos.X-d, c.pos.Y+d, c.pos.X+d, c.pos.Y-d, unit/2)
+	}
+
+	fmt.Fprintf(&sb, "</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// objectRunes maps object types to the runes used by renderText.
+var objectRunes = map[rtb.Object]rune{
+	rtb.ObjectRobot:  'R',
+	rtb.ObjectShot:   '*',
+	rtb.ObjectWall:   '#',
+	rtb.ObjectCookie: 'c',
+	rtb.ObjectMine:   'm',
+}
+
+// renderText renders g as text, using a grid with the given width.
+func renderText(w io.Writer, g *game, width int) error {
+	b := bounds(g.points(), 0.5)
+	height := int(math.Ceil(float64(width) * b.Dy() / b.Dx() / 2))
+	if height < 1 {
+		height = 1
+	}
+
+	grid := make([][]rune, height)
+	for i := range grid {
+		grid[i] = []rune(strings.Repeat(" ", width))
+	}
+	plot := func(p point, r rune) {
+		x := int((p.X - b.Min.X) / b.Dx() * float64(width))
+		y := int((p.Y - b.Min.Y) / b.Dy() * float64(height))
+		if x >= 0 && x < width && y >= 0 && y < height {
+			grid[y][x] = r
+		}
+	}
+
+	for _, h := range g.radar {
+		r, ok := objectRunes[h.object]
+		if !ok {
+			r = '?'
+		}
+		plot(h.pos, r)
+	}
+	for _, p := range g.path {
+		plot(p, '.')
+	}
+	for _, s := range g.shots {
+		plot(s.pos, '>')
+	}
+	for _, c := range g.collisions {
+		plot(c.pos, 'x')
+	}
+	if len(g.path) > 0 {
+		plot(g.path[0], 'S')
+		plot(g.path[len(g.path)-1], 'E')
+	}
+
+	var sb strings.Builder
+	border := "+" + strings.Repeat("-", width) + "+\n"
+	sb.WriteString(border)
+	for _, row := range grid {
+		fmt.Fprintf(&sb, "|%v|\n", string(row))
+	}
+	sb.WriteString(border)
+	fmt.Fprintf(&sb, "S: start, E: end, .: path, >: shot, x: collision\n")
+	fmt.Fprintf(&sb, "radar: R: robot, #: wall, c: cookie, m: mine, *: shot\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jroimartin/rtb/serverlog"
+)
+
+// parseServerLog reads a RealTimeBattle server log and returns the games
+// played by the robot name, from its point of view. Shots are attributed to
+// the closest robot when they are fired. Radar hits and collisions are not
+// logged by the server.
+func parseServerLog(r io.Reader, name string) ([]*game, error) {
+	l, err := serverlog.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []*game
+	for _, sg := range l.Games {
+		id, ok := robotID(sg, name)
+		if !ok {
+			continue
+		}
+		g := &game{}
+		seen := make(map[int]bool)
+		for _, t := range sg.Ticks {
+			for _, rs := range t.Robots {
+				if rs.ID == id {
+					g.path = append(g.path, rs.Pos)
+				}
+			}
+			for _, s := range t.Shots {
+				if seen[s.ID] {
+					continue
+				}
+				seen[s.ID] = true
+				if closest(t.Robots, s.Pos) == id {
+					g.shots = append(g.shots, shotMark{pos: s.Pos, angle: math.Atan2(s.Vel.Y, s.Vel.X)})
+				}
+			}
+		}
+		for _, d := range sg.Deaths {
+			// The last robot alive is also logged as dead when the
+			// game finishes, with position 1.
+			if d.ID == id && d.Position != 1 {
+				g.dead = true
+			}
+		}
+		games = append(games, g)
+	}
+	if len(games) == 0 {
+		return nil, fmt.Errorf("robot %q not found", name)
+	}
+	return games, nil
+}
+
+// robotID returns the identifier of the robot name in g.
+func robotID(g *serverlog.Game, name string) (int, bool) {
+	for _, r := range g.Robots {
+		if r.Name == name {
+			return r.ID, true
+		}
+	}
+	return 0, false
+}
+
+// closest returns the identifier of the robot closest to p, or -1 if there
+// are no robots.
+func closest(robots []serverlog.RobotState, p point) int {
+	id, min := -1, math.Inf(1)
+	for _, r := range robots {
+		if d := r.Pos.Sub(p).Len(); d < min {
+			id, min = r.ID, d
+		}
+	}
+	return id
+}
@@ -0,0 +1,91 @@
+// rtbscenario runs scenario files and checks their assertions. See the
+// scenario package for a description of the format.
+//
+// Usage:
+//
+//	rtbscenario [-v] file...
+//
+// The robots of the scenarios can use the scripted opponents of the
+// opponents package as strategies: duck, spinner, wallhugger, drunkard and
+// rammer.
+//
+// rtbscenario exits with status 1 if any assertion fails.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/opponents"
+	"github.com/jroimartin/rtb/scenario"
+)
+
+// strategies returns the built-in strategies.
+func strategies() map[string]func() rtb.Strategy {
+	m := make(map[string]func() rtb.Strategy)
+	for _, name := range opponents.Names() {
+		name := name
+		m[name] = func() rtb.Strategy {
+			s, _ := opponents.New(name)
+			return s
+		}
+	}
+	return m
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "print the result of every robot")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetPrefix("rtbscenario: ")
+	log.SetFlags(0)
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		s, err := scenario.Load(path)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		rep, err := s.Run(strategies())
+		if err != nil {
+			log.Fatalf("error: %v: %v", path, err)
+		}
+
+		name := s.Name
+		if name == "" {
+			name = path
+		}
+		if rep.Err() != nil {
+			failed = true
+			fmt.Printf("FAIL\t%v\n", name)
+			for _, f := range rep.Failures {
+				fmt.Printf("\t%v\n", f)
+			}
+		} else {
+			fmt.Printf("ok\t%v\t%.2fs\n", name, rep.Result.Time)
+		}
+		if *verbose {
+			for i, rr := range rep.Result.Robots {
+				fmt.Printf("\t%v: alive=%v energy=%.2f damage=%.2f cookies=%v mines=%v\n",
+					s.Robots[i].Name, rr.Alive, rr.Energy, rr.DamageTaken, rr.CookiesEaten, rr.MinesHit)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbscenario [flags] file...\n")
+	flag.PrintDefaults()
+}
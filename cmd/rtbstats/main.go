@@ -0,0 +1,68 @@
+// rtbstats computes an aggregate report from the telemetry files of many
+// games, e.g. the files written by the telemetry package during a
+// tournament.
+//
+// Usage:
+//
+//	rtbstats [-format json|csv] [-table name] [flags] file...
+//
+// The report is written to the standard output. With -format csv, only the
+// table given by -table is written: games, survival, damage or heatmap.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jroimartin/rtb/analysis"
+)
+
+func main() {
+	format := flag.String("format", "json", "output format (json or csv)")
+	table := flag.String("table", analysis.TableGames, "CSV `table` (games, survival, damage or heatmap)")
+	bin := flag.Float64("bin", 0, "`width` of the survival time bins (default 10)")
+	cell := flag.Float64("cell", 0, "`size` of the heatmap cells (default 1)")
+	window := flag.Float64("window", 0, "hit estimation `window` in game seconds (default 2)")
+	flag.Usage = usage
+	flag.Parse()
+
+	log.SetPrefix("rtbstats: ")
+	log.SetFlags(0)
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	a := analysis.New(analysis.Config{
+		SurvivalBin: *bin,
+		CellSize:    *cell,
+		HitWindow:   *window,
+	})
+	for _, path := range flag.Args() {
+		if err := a.AddFile(path); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+	rep := a.Report()
+
+	var err error
+	switch *format {
+	case "json":
+		err = rep.WriteJSON(os.Stdout)
+	case "csv":
+		err = rep.WriteCSV(os.Stdout, *table)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbstats [flags] file...\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,154 @@
+// rtbwatch is a wrapper that sits between the RealTimeBattle server and a
+// robot. It passes the messages through untouched while mirroring the
+// protocol traffic, timestamped, to a log file and/or to the clients
+// connected to a TCP port.
+//
+// Usage:
+//
+//	rtbwatch [-log file] [-addr address] robot [args...]
+//
+// Every mirrored line has the format "<timestamp> <direction> <message>",
+// where direction is "<" for messages sent by the server and ">" for
+// commands sent by the robot.
+//
+// The RealTimeBattle server does not pass arguments to the robots, so
+// rtbwatch is usually called from a shell script that is used as the robot:
+//
+//	#!/bin/sh
+//	exec rtbwatch -log /tmp/robot.log /path/to/robot
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	logFile := flag.String("log", "", "mirror traffic to `file`")
+	addr := flag.String("addr", "", "mirror traffic to the clients connected to `address`")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	log.SetPrefix("rtbwatch: ")
+	log.SetFlags(0)
+
+	m := &mirror{}
+
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("could not open log file: %v", err)
+		}
+		m.file = f
+	}
+
+	if *addr != "" {
+		l, err := net.Listen("tcp", *addr)
+		if err != nil {
+			log.Fatalf("could not listen: %v", err)
+		}
+		go m.accept(l)
+	}
+
+	code, err := run(m, flag.Arg(0), flag.Args()[1:])
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+	m.close()
+	os.Exit(code)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbwatch [flags] robot [args...]\n")
+	flag.PrintDefaults()
+}
+
+// run runs the robot and proxies its standard input and output. It returns
+// the exit code of the robot.
+func run(m *mirror, name string, args []string) (int, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 1, fmt.Errorf("could not get robot stdin: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 1, fmt.Errorf("could not get robot stdout: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 1, fmt.Errorf("could not start robot: %v", err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	forward(stdin, stdout, os.Stdin, os.Stdout, m)
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// forward passes the messages read from server to robotIn and the commands
+// read from robotOut to server, mirroring both directions. robotIn is closed
+// when server is closed. It returns when robotOut is closed.
+func forward(robotIn io.WriteCloser, robotOut io.Reader, server io.Reader, serverOut io.Writer, m *mirror) {
+	go func() {
+		defer robotIn.Close()
+		if err := proxy(robotIn, server, m, "<"); err != nil {
+			log.Printf("server proxy error: %v", err)
+		}
+	}()
+
+	if err := proxy(serverOut, robotOut, m, ">"); err != nil {
+		log.Printf("robot proxy error: %v", err)
+	}
+}
+
+// proxy copies lines from src to dst, mirroring them with the given
+// direction. It returns when src is closed.
+func proxy(dst io.Writer, src io.Reader, m *mirror, dir string) error {
+	r := bufio.NewReader(src)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			if _, err := io.WriteString(dst, line); err != nil {
+				return err
+			}
+			m.write(dir, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buffer is a bytes.Buffer that implements io.Closer.
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) Close() error { return nil }
+
+// mirrored returns the directions and the lines mirrored in log, checking
+// their timestamps.
+func mirrored(t *testing.T, log string) []string {
+	var lines []string
+	for _, s := range strings.Split(strings.TrimSuffix(log, "\n"), "\n") {
+		ts, rest, ok := strings.Cut(s, " ")
+		if !ok {
+			t.Fatalf("malformed mirrored line: %q", s)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+			t.Errorf("malformed timestamp %q: %v", ts, err)
+		}
+		lines = append(lines, rest)
+	}
+	return lines
+}
+
+func TestForward(t *testing.T) {
+	robotIn, robotInW := io.Pipe()
+	robotOutR, robotOut := io.Pipe()
+
+	// The robot answers every message with a command.
+	go func() {
+		defer robotOut.Close()
+		sc := bufio.NewScanner(robotIn)
+		for sc.Scan() {
+			io.WriteString(robotOut, "Print "+sc.Text()+"\n")
+		}
+	}()
+
+	var log buffer
+	m := &mirror{file: &log}
+	var serverOut bytes.Buffer
+	forward(robotInW, robotOutR, strings.NewReader("Initialize 1\nGameStarts"), &serverOut, m)
+
+	if got, want := serverOut.String(), "Print Initialize 1\nPrint GameStarts\n"; got != want {
+		t.Errorf("unexpected robot output: got=%q want=%q", got, want)
+	}
+
+	got := mirrored(t, log.String())
+	sort.Strings(got)
+	want := []string{"< GameStarts", "< Initialize 1", "> Print GameStarts", "> Print Initialize 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected mirrored lines: got=%q want=%q", got, want)
+	}
+}
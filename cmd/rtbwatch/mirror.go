@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// mirror sends timestamped lines to a log file and to TCP clients.
+type mirror struct {
+	mu      sync.Mutex
+	file    io.WriteCloser
+	clients []chan string
+}
+
+// clientBuffer is the number of lines buffered per TCP client. If a client
+// is slower than the protocol traffic, lines are dropped instead of delaying
+// the robot.
+const clientBuffer = 1024
+
+// write mirrors a line.
+func (m *mirror) write(dir, line string) {
+	if line[len(line)-1] != '\n' {
+		line += "\n"
+	}
+	s := fmt.Sprintf("%v %v %v", time.Now().UTC().Format(time.RFC3339Nano), dir, line)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file != nil {
+		io.WriteString(m.file, s)
+	}
+	for _, c := range m.clients {
+		select {
+		case c <- s:
+		default:
+		}
+	}
+}
+
+// accept accepts TCP clients.
+func (m *mirror) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		c := make(chan string, clientBuffer)
+		m.mu.Lock()
+		m.clients = append(m.clients, c)
+		m.mu.Unlock()
+
+		go func() {
+			defer conn.Close()
+			for s := range c {
+				if _, err := io.WriteString(conn, s); err != nil {
+					m.remove(c)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// remove stops mirroring to the client with channel c.
+func (m *mirror) remove(c chan string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, cc := range m.clients {
+		if cc == c {
+			m.clients = append(m.clients[:i], m.clients[i+1:]...)
+			close(c)
+			return
+		}
+	}
+}
+
+// close closes the log file and stops mirroring to the TCP clients.
+func (m *mirror) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file != nil {
+		m.file.Close()
+	}
+
+	for _, c := range m.clients {
+		close(c)
+	}
+	m.clients = nil
+}
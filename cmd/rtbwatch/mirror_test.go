@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer l.Close()
+
+	var log buffer
+	m := &mirror{file: &log}
+	go m.accept(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	defer conn.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		m.mu.Lock()
+		n := len(m.clients)
+		m.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client not accepted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	m.write("<", "Radar 1 0 0\n")
+	m.write(">", "Shoot 1")
+	m.close()
+
+	b, err := io.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatalf("could not read mirrored lines: %v", err)
+	}
+
+	want := []string{"< Radar 1 0 0", "> Shoot 1"}
+	if got := mirrored(t, string(b)); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected client lines: got=%q want=%q", got, want)
+	}
+	if got := mirrored(t, log.String()); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected log lines: got=%q want=%q", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package rtb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jroimartin/rtb/physics"
+)
+
+// RGB is a colour of a robot.
+type RGB struct {
+	R, G, B uint8
+}
+
+// ParseColour parses a hex colour of the form "11aa22".
+func ParseColour(s string) (RGB, error) {
+	if !physics.IsValidColour(s) {
+		return RGB{}, fmt.Errorf("invalid colour %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid colour %q: %v", s, err)
+	}
+	return RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// String returns c as a hex string, as expected by Colour.
+func (c RGB) String() string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Distance returns the euclidean distance between c and other in the RGB
+// space, between 0 and about 441.
+func (c RGB) Distance(other RGB) float64 {
+	dr := float64(c.R) - float64(other.R)
+	dg := float64(c.G) - float64(other.G)
+	db := float64(c.B) - float64(other.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// HSL returns the colour with hue h, in degrees, and saturation s and
+// lightness l, between 0 and 1.
+func HSL(h, s, l float64) RGB {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	conv := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v+m)) * 255))
+	}
+	return RGB{R: conv(r), G: conv(g), B: conv(b)}
+}
+
+// HSL returns the hue, in degrees, and the saturation and the lightness,
+// between 0 and 1, of c.
+func (c RGB) HSL() (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	d := max - min
+	if d == 0 {
+		return 0, 0, l
+	}
+	s = d / (1 - math.Abs(2*l-1))
+	switch max {
+	case r:
+		h = 60 * math.Mod((g-b)/d, 6)
+	case g:
+		h = 60 * ((b-r)/d + 2)
+	default:
+		h = 60 * ((r-g)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// Palette returns n colours with evenly spaced hues, starting with the hue
+// start, in degrees, so every team of a tournament can get a distinct
+// colour. The colours are saturated and of medium lightness, so they are
+// visible on the background of the arena. It returns nil if n is not
+// positive.
+func Palette(n int, start float64) []RGB {
+	if n <= 0 {
+		return nil
+	}
+	colours := make([]RGB, n)
+	for i := range colours {
+		colours[i] = HSL(start+360*float64(i)/float64(n), 0.8, 0.5)
+	}
+	return colours
+}
+
+// AwayColour returns the colour with the complementary hue of c, which is a
+// good away colour for the home colour c.
+func AwayColour(c RGB) RGB {
+	h, s, l := c.HSL()
+	return HSL(h+180, s, l)
+}
+
+// ColourPolicy decides how the robot reacts to the colour assigned by the
+// server with MessageYourColour, which is not always the requested one.
+// The reaction takes place before the message is passed to the strategy.
+type ColourPolicy struct {
+	// Accept returns true if the assigned colour is acceptable. If nil,
+	// any colour is accepted.
+	Accept func(colour string) bool
+
+	// Home and Away are the colours requested when the assigned colour
+	// is not accepted. If empty, the last colours sent with Colour are
+	// requested again.
+	Home, Away string
+
+	// MaxRequests is the maximum number of colour requests per
+	// sequence. If zero, 1 is used.
+	MaxRequests int
+}
+
+// ColourNear returns an accept function for ColourPolicy that accepts the
+// colours at most at distance tolerance of c.
+func ColourNear(c RGB, tolerance float64) func(colour string) bool {
+	return func(colour string) bool {
+		got, err := ParseColour(colour)
+		return err == nil && got.Distance(c) <= tolerance
+	}
+}
+
+// SetColourPolicy sets the colour policy of r. By default, the colours
+// assigned by the server are accepted.
+func (r *Robot) SetColourPolicy(p ColourPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.colourPolicy = &p
+}
+
+// SetColourPolicy calls SetColourPolicy on the default Robot.
+func SetColourPolicy(p ColourPolicy) {
+	std.SetColourPolicy(p)
+}
+
+// yourColour requests other colours if the colour assigned by the server is
+// not accepted by the colour policy.
+func (r *Robot) yourColour(msg MessageYourColour) {
+	r.mu.Lock()
+	p := r.colourPolicy
+	r.mu.Unlock()
+
+	if p == nil || p.Accept == nil || p.Accept(msg.Colour) {
+		return
+	}
+
+	max := p.MaxRequests
+	if max == 0 {
+		max = 1
+	}
+	r.mu.Lock()
+	if r.colourRequests >= max {
+		r.mu.Unlock()
+		return
+	}
+	r.colourRequests++
+	home, away := r.homeColour, r.awayColour
+	r.mu.Unlock()
+
+	if p.Home != "" {
+		home, away = p.Home, p.Away
+		if away == "" {
+			away = home
+		}
+	}
+	if home == "" {
+		return
+	}
+	if err := r.Colour(home, away); err != nil {
+		r.Logger().Warn("could not request colour", "err", err)
+	}
+}
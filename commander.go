@@ -0,0 +1,268 @@
+package rtb
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// parts are the individual parts of the robot, in the order their commands
+// are sent by a Commander.
+var parts = [...]Part{PartRobot, PartCannon, PartRadar}
+
+// rotation is a rotation command for a part.
+type rotation struct {
+	keyword string
+	v, a, b float64
+}
+
+// send sends the rotation for the parts in what through r.
+func (rot rotation) send(r *Robot, what Part) error {
+	switch rot.keyword {
+	case "RotateTo":
+		return r.RotateTo(what, rot.v, rot.a)
+	case "RotateAmount":
+		return r.RotateAmount(what, rot.v, rot.a)
+	case "Sweep":
+		return r.Sweep(what, rot.v, rot.a, rot.b)
+	default:
+		return r.Rotate(what, rot.v)
+	}
+}
+
+// Commander stages the commands sent during a tick and sends them at once
+// when the tick ends, so strategies can set their intents in any order.
+//
+// Only the last command staged for every part, the acceleration, the brake
+// and the shot is sent. Commands that would not change the state of the
+// robot, like repeating the last acceleration, are not sent. Commands that
+// could not be sent are sent again in the next commit. Values are limited by
+// the Robot, see ErrOutOfRange. Commander implements the Observer interface
+// and must be added to the robot, so it forgets the state of the robot when a
+// new game starts. All the rotation and movement commands of the robot must
+// be sent through the Commander. Commander methods can be called
+// concurrently.
+type Commander struct {
+	NopCommand
+
+	r *Robot
+
+	mu sync.Mutex
+
+	rot   [len(parts)]*rotation
+	accel *float64
+	brake *float64
+	shoot *float64
+
+	sentRot   [len(parts)]*rotation
+	sentAccel *float64
+	sentBrake *float64
+}
+
+// NewCommander returns a Commander that sends commands through r. If r is
+// nil, the default Robot is used.
+func NewCommander(r *Robot) *Commander {
+	if r == nil {
+		r = std
+	}
+	return &Commander{r: r}
+}
+
+// stageRotation stages a rotation for the parts in what.
+func (c *Commander) stageRotation(what Part, rot rotation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, p := range parts {
+		if what&p == 0 {
+			continue
+		}
+		r := rot
+		c.rot[i] = &r
+	}
+}
+
+// Rotate stages a Rotate command.
+func (c *Commander) Rotate(what Part, v float64) {
+	c.stageRotation(what, rotation{keyword: "Rotate", v: v})
+}
+
+// RotateTo stages a RotateTo command.
+func (c *Commander) RotateTo(what Part, v, end float64) {
+	c.stageRotation(what, rotation{keyword: "RotateTo", v: v, a: end})
+}
+
+// RotateAmount stages a RotateAmount command.
+func (c *Commander) RotateAmount(what Part, v, angle float64) {
+	c.stageRotation(what, rotation{keyword: "RotateAmount", v: v, a: angle})
+}
+
+// Sweep stages a Sweep command.
+func (c *Commander) Sweep(what Part, v, rightAngle, leftAngle float64) {
+	c.stageRotation(what, rotation{keyword: "Sweep", v: v, a: rightAngle, b: leftAngle})
+}
+
+// Accelerate stages an Accelerate command.
+func (c *Commander) Accelerate(value float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.accel = &value
+}
+
+// Brake stages a Brake command.
+func (c *Commander) Brake(portion float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	portion = math.Max(0, math.Min(portion, 1))
+	c.brake = &portion
+}
+
+// Shoot stages a Shoot command.
+func (c *Commander) Shoot(energy float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shoot = &energy
+}
+
+// Discard discards the staged commands.
+func (c *Commander) Discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.discard()
+}
+
+// discard discards the staged commands. c.mu must be held.
+func (c *Commander) discard() {
+	c.rot = [len(parts)]*rotation{}
+	c.accel, c.brake, c.shoot = nil, nil, nil
+}
+
+// Commit sends the staged commands. It returns the first error found.
+func (c *Commander) Commit() error {
+	type rotCmd struct {
+		what Part
+		rot  rotation
+	}
+
+	c.mu.Lock()
+	// Parts with the same rotation are sent in a single command.
+	var rots []rotCmd
+	for i, rot := range c.rot {
+		if rot == nil {
+			continue
+		}
+		// RotateAmount is relative to the current angle, so it is
+		// never redundant.
+		if sent := c.sentRot[i]; sent != nil && *sent == *rot && rot.keyword != "RotateAmount" {
+			continue
+		}
+		merged := false
+		for j := range rots {
+			if rots[j].rot == *rot {
+				rots[j].what |= parts[i]
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			rots = append(rots, rotCmd{parts[i], *rot})
+		}
+	}
+	accel, brake, shoot := c.accel, c.brake, c.shoot
+	if accel != nil && c.sentAccel != nil && *accel == *c.sentAccel {
+		accel = nil
+	}
+	if brake != nil && c.sentBrake != nil && *brake == *c.sentBrake {
+		brake = nil
+	}
+	c.discard()
+	c.mu.Unlock()
+
+	// Commands are sent without holding the lock, because the observers
+	// of the robot are called. The sent values are only recorded once
+	// the commands are sent, so the failed ones are not taken as
+	// redundant in the next commit.
+	var errs []error
+	for _, rc := range rots {
+		err := rc.rot.send(c.r, rc.what)
+		if sent(err) {
+			c.mu.Lock()
+			for i, p := range parts {
+				if rc.what&p != 0 {
+					rot := rc.rot
+					c.sentRot[i] = &rot
+				}
+			}
+			c.mu.Unlock()
+		}
+		errs = append(errs, err)
+	}
+	if accel != nil {
+		err := c.r.Accelerate(*accel)
+		if sent(err) {
+			c.mu.Lock()
+			c.sentAccel = accel
+			c.mu.Unlock()
+		}
+		errs = append(errs, err)
+	}
+	if brake != nil {
+		err := c.r.Brake(*brake)
+		if sent(err) {
+			c.mu.Lock()
+			c.sentBrake = brake
+			c.mu.Unlock()
+		}
+		errs = append(errs, err)
+	}
+	if shoot != nil {
+		errs = append(errs, c.r.Shoot(*shoot))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sent reports whether a command that returned err was sent.
+func sent(err error) bool {
+	var errRange ErrOutOfRange
+	if errors.As(err, &errRange) {
+		return errRange.Sent
+	}
+	return err == nil
+}
+
+// Strategy returns a strategy that passes the messages to s and commits the
+// staged commands after every Info message, which ends a tick. Commit errors
+// are logged.
+func (c *Commander) Strategy(s Strategy) Strategy {
+	return StrategyFunc(func(r *Robot, msg Message) {
+		s.Handle(r, msg)
+		if _, ok := msg.(MessageInfo); !ok {
+			return
+		}
+		if err := c.Commit(); err != nil {
+			r.Logger().Warn("could not commit commands", "err", err)
+		}
+	})
+}
+
+// Message forgets the state of the robot when a new game starts.
+func (c *Commander) Message(msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch msg.(type) {
+	case MessageGameStarts:
+		c.discard()
+		c.sentRot = [len(parts)]*rotation{}
+		c.sentAccel, c.sentBrake = nil, nil
+	}
+}
@@ -0,0 +1,187 @@
+// Package config loads the tuning parameters of a robot from a file, so they
+// can be changed without recompiling the robot.
+//
+// The file is divided in sections, one per subsystem. A subsystem registers
+// a section with a pointer to a struct holding its default values, which is
+// updated when the file is loaded:
+//
+//	type Gun struct {
+//		Aggression float64    `json:"aggression"`
+//		Gains      [3]float64 `json:"gains"`
+//	}
+//
+//	gun := Gun{Aggression: 0.5}
+//	config.Register("gun", &gun)
+//	if err := config.Load(config.Path("RTB_CONFIG", os.Args[1:])); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Files with the extension .json are JSON objects with an object per
+// section. Files with any other extension are TOML documents with a table
+// per section:
+//
+//	[gun]
+//	aggression = 0.8
+//	gains = [1.0, 0.1, 0.5]
+//
+// In both cases, the sections are decoded like JSON, so field names are
+// taken from the json struct tags. Unknown sections and fields are errors,
+// which catches typos. Sections that implement Validator are validated after
+// loading.
+//
+// Only a subset of TOML is supported: tables, dotted keys, strings, numbers,
+// booleans, arrays and inline tables. Arrays of tables, dates, multiline
+// strings and non-finite floats are not.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Validator is implemented by sections that can check their values.
+type Validator interface {
+	// Validate returns an error if the values are not valid.
+	Validate() error
+}
+
+// Config is a set of configuration sections. Config methods can be called
+// concurrently.
+type Config struct {
+	mu       sync.Mutex
+	sections map[string]any
+}
+
+// New returns an empty Config.
+func New() *Config {
+	return &Config{sections: make(map[string]any)}
+}
+
+// std is the default Config.
+var std = New()
+
+// Register registers the section name. v must be a pointer to the values of
+// the section, initialized with their defaults. It panics if the section is
+// already registered.
+func (c *Config) Register(name string, v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.sections[name]; ok {
+		panic(fmt.Sprintf("config: section %q registered twice", name))
+	}
+	c.sections[name] = v
+}
+
+// Register calls Register on the default Config.
+func Register(name string, v any) {
+	std.Register(name, v)
+}
+
+// Load loads the file at path into the registered sections and validates
+// them. If path is empty, the sections keep their defaults, but they are
+// still validated.
+func (c *Config) Load(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read config: %v", err)
+		}
+		if err := c.decode(b, filepath.Ext(path) == ".json"); err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+	}
+	return c.validate()
+}
+
+// Load calls Load on the default Config.
+func Load(path string) error {
+	return std.Load(path)
+}
+
+// decode decodes the TOML or JSON document b into the registered sections.
+func (c *Config) decode(b []byte, isJSON bool) error {
+	raw := make(map[string]json.RawMessage)
+	if isJSON {
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return fmt.Errorf("could not parse JSON: %v", err)
+		}
+	} else {
+		doc, err := parseTOML(string(b))
+		if err != nil {
+			return fmt.Errorf("could not parse TOML: %v", err)
+		}
+		for name, v := range doc {
+			if raw[name], err = json.Marshal(v); err != nil {
+				return fmt.Errorf("section %q: %v", name, err)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(raw) {
+		v, ok := c.sections[name]
+		if !ok {
+			return fmt.Errorf("unknown section %q", name)
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw[name]))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("section %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validate validates the registered sections.
+func (c *Config) validate() error {
+	for _, name := range sortedKeys(c.sections) {
+		v, ok := c.sections[name].(Validator)
+		if !ok {
+			continue
+		}
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("section %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, so errors are deterministic.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Path returns the path of the configuration file. It is the value of the
+// -config flag in args, in the forms "-config path" or "-config=path", with
+// one or two dashes, or else the value of the environment variable env. The
+// RealTimeBattle server does not pass arguments to the robots, so the
+// environment variable is usually set in a wrapper script.
+func Path(env string, args []string) string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if name != "-config" && name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(env)
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gun is a test section.
+type gun struct {
+	Aggression float64    `json:"aggression"`
+	Gains      [3]float64 `json:"gains"`
+	Policy     string     `json:"policy"`
+	Enabled    bool       `json:"enabled"`
+}
+
+func (g *gun) Validate() error {
+	if g.Aggression < 0 || g.Aggression > 1 {
+		return errors.New("aggression must be between 0 and 1")
+	}
+	return nil
+}
+
+// look is a test section.
+type look struct {
+	Colour string `json:"colour"`
+	Pid    struct {
+		P float64 `json:"p"`
+		I float64 `json:"i"`
+	} `json:"pid"`
+}
+
+// writeFile writes a file with the given name and contents in a temporary
+// directory and returns its path.
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	files := []struct {
+		name     string
+		contents string
+	}{
+		{
+			name: "robot.toml",
+			contents: `
+# Tuning.
+[gun]
+aggression = 0.8
+gains = [
+	1, 0.5, # Proportional and integral.
+	2e-1,
+]
+policy = "sweep\tfast"
+
+[look]
+colour = 'ff0000'
+pid = { p = 1_000, i = 0.25 }
+`,
+		},
+		{
+			name: "robot.json",
+			contents: `{
+	"gun": {"aggression": 0.8, "gains": [1, 0.5, 0.2], "policy": "sweep\tfast"},
+	"look": {"colour": "ff0000", "pid": {"p": 1000, "i": 0.25}}
+}`,
+		},
+	}
+
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			c := New()
+			g := gun{Enabled: true}
+			var l look
+			c.Register("gun", &g)
+			c.Register("look", &l)
+
+			if err := c.Load(writeFile(t, f.name, f.contents)); err != nil {
+				t.Fatalf("could not load config: %v", err)
+			}
+
+			wantGun := gun{Aggression: 0.8, Gains: [3]float64{1, 0.5, 0.2}, Policy: "sweep\tfast", Enabled: true}
+			if g != wantGun {
+				t.Errorf("wrong gun section: got=%+v want=%+v", g, wantGun)
+			}
+			if l.Colour != "ff0000" || l.Pid.P != 1000 || l.Pid.I != 0.25 {
+				t.Errorf("wrong look section: got=%+v", l)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"unknown.toml", "[cannon]\nx = 1\n", `unknown section "cannon"`},
+		{"field.toml", "[gun]\naggresion = 1\n", `unknown field "aggresion"`},
+		{"type.toml", "[gun]\npolicy = 1\n", `section "gun"`},
+		{"invalid.toml", "[gun]\naggression = 2\n", "aggression must be between 0 and 1"},
+		{"duplicate.toml", "[gun]\npolicy = 'a'\npolicy = 'b'\n", `line 3: duplicate key "policy"`},
+		{"syntax.toml", "[gun]\naggression 1\n", "line 2: expected '='"},
+		{"value.toml", "[gun]\naggression = 1.5.2\n", `invalid value "1.5.2"`},
+		{"trailing.toml", "[gun]\naggression = 1 2\n", "unexpected '2' after value"},
+		{"tables.toml", "[[gun]]\n", "arrays of tables are not supported"},
+		{"string.toml", "[gun]\npolicy = \"a\n", "unterminated string"},
+		{"nan.toml", "[gun]\naggression = nan\n", `unsupported value "nan"`},
+		{"bad.json", `{"gun": 1`, "could not parse JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.Register("gun", &gun{})
+			err := c.Load(writeFile(t, tt.name, tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("wrong error: got=%v want=%v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c := New()
+	g := gun{Aggression: 2}
+	c.Register("gun", &g)
+	if err := c.Load(""); err == nil {
+		t.Errorf("invalid defaults were not reported")
+	}
+
+	g.Aggression = 0.5
+	if err := c.Load(""); err != nil {
+		t.Errorf("could not load defaults: %v", err)
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	doc, err := parseTOML(`
+top = true
+a.b = -3
+[x."y z".w]
+n = 0x10
+s = "é"
+e = []
+`)
+	if err != nil {
+		t.Fatalf("could not parse TOML: %v", err)
+	}
+	want := map[string]any{
+		"top": true,
+		"a":   map[string]any{"b": int64(-3)},
+		"x": map[string]any{
+			"y z": map[string]any{
+				"w": map[string]any{"n": int64(16), "s": "é", "e": []any{}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("wrong document: got=%v want=%v", doc, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("RTB_TEST_CONFIG", "env.toml")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "env.toml"},
+		{[]string{"-config", "a.toml"}, "a.toml"},
+		{[]string{"-v", "--config=b.json"}, "b.json"},
+		{[]string{"--config", "c.toml", "x"}, "c.toml"},
+		{[]string{"-configure", "d"}, "env.toml"},
+		{[]string{"-config"}, "env.toml"},
+	}
+	for _, tt := range tests {
+		if got := Path("RTB_TEST_CONFIG", tt.args); got != tt.want {
+			t.Errorf("wrong path for %q: got=%v want=%v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a section twice did not panic")
+		}
+	}()
+	c := New()
+	c.Register("gun", &gun{})
+	c.Register("gun", &gun{})
+}
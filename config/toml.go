@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses a TOML document into nested maps. Integers are int64,
+// floats are float64 and arrays are []any.
+func parseTOML(s string) (map[string]any, error) {
+	p := &tomlParser{s: s, line: 1}
+	doc, err := p.document()
+	if err != nil {
+		return nil, fmt.Errorf("line %v: %v", p.line, err)
+	}
+	return doc, nil
+}
+
+// tomlParser is a TOML parser.
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+// document parses the whole document.
+func (p *tomlParser) document() (map[string]any, error) {
+	doc := make(map[string]any)
+	table := doc
+	for {
+		p.skip(true)
+		if p.eof() {
+			return doc, nil
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, errors.New("arrays of tables are not supported")
+			}
+			p.skip(false)
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skip(false)
+			if !p.consume(']') {
+				return nil, errors.New("expected ']'")
+			}
+			if table, err = subtable(doc, keys, true); err != nil {
+				return nil, err
+			}
+		} else if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+
+		p.skip(false)
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, fmt.Errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+// keyValue parses a key/value pair and sets it in table.
+func (p *tomlParser) keyValue(table map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skip(false)
+	if !p.consume('=') {
+		return errors.New("expected '='")
+	}
+	p.skip(false)
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	t, err := subtable(table, keys[:len(keys)-1], false)
+	if err != nil {
+		return err
+	}
+	k := keys[len(keys)-1]
+	if _, ok := t[k]; ok {
+		return fmt.Errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	t[k] = v
+	return nil
+}
+
+// subtable returns the table at the path keys under t, creating the missing
+// tables. If header is true, the table is defined by a table header, which
+// cannot redefine an existing value.
+func subtable(t map[string]any, keys []string, header bool) (map[string]any, error) {
+	for i, k := range keys {
+		v, ok := t[k]
+		if !ok {
+			sub := make(map[string]any)
+			t[k] = sub
+			t = sub
+			continue
+		}
+		sub, ok := v.(map[string]any)
+		if !ok || (header && i == len(keys)-1) {
+			return nil, fmt.Errorf("duplicate key %q", strings.Join(keys[:i+1], "."))
+		}
+		t = sub
+	}
+	return t, nil
+}
+
+// key parses a dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		var (
+			k   string
+			err error
+		)
+		switch p.peek() {
+		case '"':
+			k, err = p.basicString()
+		case '\'':
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, errors.New("expected key")
+			}
+			k = p.s[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+
+		p.skip(false)
+		if !p.consume('.') {
+			return keys, nil
+		}
+		p.skip(false)
+	}
+}
+
+// isBareKeyChar reports whether c can be part of a bare key.
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses a value.
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case p.eof():
+		return nil, errors.New("expected value")
+	case c == '"':
+		return p.basicString()
+	case c == '\'':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	return parseScalar(p.s[start:p.pos])
+}
+
+// parseScalar parses a boolean or a number.
+func parseScalar(s string) (any, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	// With base 0, ParseInt accepts the base prefixes and the
+	// underscores of TOML integers.
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+	if err != nil || strings.ContainsAny(s, "xXpP") {
+		return nil, fmt.Errorf("invalid value %q", s)
+	}
+	// Infinities and NaNs cannot be decoded like JSON.
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("unsupported value %q", s)
+	}
+	return f, nil
+}
+
+// basicString parses a string in double quotes.
+func (p *tomlParser) basicString() (string, error) {
+	start := p.pos
+	p.pos++
+	for !p.eof() {
+		switch p.peek() {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			return "", errors.New("unterminated string")
+		case '"':
+			p.pos++
+			// TOML escapes are a subset of the Go ones.
+			s, err := strconv.Unquote(p.s[start:p.pos])
+			if err != nil {
+				return "", fmt.Errorf("invalid string %v", p.s[start:p.pos])
+			}
+			return s, nil
+		}
+		p.pos++
+	}
+	return "", errors.New("unterminated string")
+}
+
+// literalString parses a string in single quotes, which has no escapes.
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	start := p.pos
+	for !p.eof() {
+		switch p.peek() {
+		case '\n':
+			return "", errors.New("unterminated string")
+		case '\'':
+			s := p.s[start:p.pos]
+			p.pos++
+			return s, nil
+		}
+		p.pos++
+	}
+	return "", errors.New("unterminated string")
+}
+
+// array parses an array, which may span several lines.
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++
+	a := []any{}
+	for {
+		p.skip(true)
+		if p.consume(']') {
+			return a, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+
+		p.skip(true)
+		if p.consume(']') {
+			return a, nil
+		}
+		if !p.consume(',') {
+			return nil, errors.New("expected ',' or ']'")
+		}
+	}
+}
+
+// inlineTable parses an inline table, which must be on a single line.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++
+	t := make(map[string]any)
+	p.skip(false)
+	if p.consume('}') {
+		return t, nil
+	}
+	for {
+		p.skip(false)
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skip(false)
+		if p.consume('}') {
+			return t, nil
+		}
+		if !p.consume(',') {
+			return nil, errors.New("expected ',' or '}'")
+		}
+	}
+}
+
+// skip skips spaces and comments. If newlines is true, it skips newlines
+// too.
+func (p *tomlParser) skip(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case newlines && c == '\r':
+			p.pos++
+		case newlines && c == '\n':
+			p.pos++
+			p.line++
+		default:
+			return
+		}
+	}
+}
+
+// eof reports whether the end of the document was reached.
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+// peek returns the next byte, or 0 at the end of the document.
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// consume consumes the next byte if it is c and reports whether it did.
+func (p *tomlParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
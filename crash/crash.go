@@ -0,0 +1,183 @@
+// Package crash writes crash reports when a robot panics, so crashes during
+// a match can be debugged afterwards.
+//
+// A Reporter observes the robot, keeping the last messages and commands, and
+// wraps its strategy. When the strategy panics, it writes a report with the
+// panic value, the stack trace, the recorded messages and commands and the
+// state of the world model, and exits:
+//
+//	rep := crash.New(crash.Config{Dir: "/tmp", World: w})
+//	r.AddObserver(rep)
+//	r.Run(settings, rep.Wrap(strategy))
+package crash
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Reporter.
+type Config struct {
+	// History is the number of messages and commands kept for the
+	// report. If zero, 50 is used.
+	History int
+
+	// Dir is the directory where the reports are written. If empty,
+	// the default directory for temporary files is used.
+	Dir string
+
+	// World is the world model whose state is included in the report. If
+	// nil, the report does not include it.
+	World *world.World
+
+	// Exit is called after writing the report with the panic, wrapped in
+	// an error. It can be used to shut down the robot in an orderly way,
+	// e.g. with shutdown.Manager.Fatal. If nil, the process exits with
+	// status 2, like after an unrecovered panic.
+	Exit func(err error)
+}
+
+// Reporter records the activity of a robot and writes crash reports. It
+// implements the rtb.Observer interface. Reporter methods can be called
+// concurrently.
+type Reporter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	messages ring
+	commands ring
+}
+
+// New returns a Reporter with the configuration cfg.
+func New(cfg Config) *Reporter {
+	if cfg.History == 0 {
+		cfg.History = 50
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = os.TempDir()
+	}
+	if cfg.Exit == nil {
+		cfg.Exit = func(error) { os.Exit(2) }
+	}
+	return &Reporter{
+		cfg:      cfg,
+		messages: newRing(cfg.History),
+		commands: newRing(cfg.History),
+	}
+}
+
+// Message records msg.
+func (rep *Reporter) Message(msg rtb.Message) {
+	s, err := rtb.EncodeMessage(msg)
+	if err != nil {
+		s = fmt.Sprintf("%T", msg)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.messages.add(s)
+}
+
+// Command records cmd.
+func (rep *Reporter) Command(cmd string) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.commands.add(cmd)
+}
+
+// Wrap returns a strategy that calls s and reports its panics.
+func (rep *Reporter) Wrap(s rtb.Strategy) rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		defer rep.Recover()
+		s.Handle(r, msg)
+	})
+}
+
+// Recover reports the panic of the current goroutine, if any. It must be
+// deferred directly, e.g. in the goroutines started by the strategy:
+//
+//	defer rep.Recover()
+func (rep *Reporter) Recover() {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic: %v", v)
+	path, werr := rep.Write(v, debug.Stack())
+	if werr != nil {
+		fmt.Fprintf(os.Stderr, "crash: could not write report: %v\n", werr)
+	} else {
+		fmt.Fprintf(os.Stderr, "crash: report written to %v\n", path)
+		err = fmt.Errorf("%w (report: %v)", err, path)
+	}
+	rep.cfg.Exit(err)
+}
+
+// Write writes a report of the panic v with the stack trace stack and
+// returns its path.
+func (rep *Reporter) Write(v any, stack []byte) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "panic: %v\n\n%s\n", v, stack)
+
+	rep.mu.Lock()
+	fmt.Fprintf(&b, "last messages (oldest first):\n")
+	for _, s := range rep.messages.items() {
+		fmt.Fprintf(&b, "\t%v\n", s)
+	}
+	fmt.Fprintf(&b, "\nlast commands (oldest first):\n")
+	for _, s := range rep.commands.items() {
+		fmt.Fprintf(&b, "\t%v\n", s)
+	}
+	rep.mu.Unlock()
+
+	if rep.cfg.World != nil {
+		fmt.Fprintf(&b, "\nworld state:\n\t%+v\n", rep.cfg.World.State())
+	}
+
+	name := fmt.Sprintf("rtb-crash-%v-%v.txt", time.Now().Format("20060102-150405"), os.Getpid())
+	path := filepath.Join(rep.cfg.Dir, name)
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ring is a ring buffer of strings.
+type ring struct {
+	buf  []string
+	next int
+	full bool
+}
+
+// newRing returns a ring buffer with capacity n.
+func newRing(n int) ring {
+	return ring{buf: make([]string, n)}
+}
+
+// add adds s, overwriting the oldest item if the buffer is full.
+func (r *ring) add(s string) {
+	r.buf[r.next] = s
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// items returns the items, oldest first.
+func (r *ring) items() []string {
+	if !r.full {
+		return append([]string(nil), r.buf[:r.next]...)
+	}
+	return append(append([]string(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
@@ -0,0 +1,87 @@
+package crash
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestWrap(t *testing.T) {
+	w := world.New()
+	var exitErr error
+	rep := New(Config{
+		History: 2,
+		Dir:     t.TempDir(),
+		World:   w,
+		Exit:    func(err error) { exitErr = err },
+	})
+
+	r := rtb.NewRobot(nil, &strings.Builder{})
+	r.AddObserver(w)
+	r.AddObserver(rep)
+	s := rep.Wrap(rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		switch m := msg.(type) {
+		case rtb.MessageInfo:
+			r.Accelerate(m.Speed)
+		case rtb.MessageRobotInfo:
+			panic("boom")
+		}
+	}))
+	for _, msg := range []rtb.Message{
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 1, Speed: 1},
+		rtb.MessageInfo{Time: 2, Speed: 2},
+		rtb.MessageInfo{Time: 3, Speed: 3},
+		rtb.MessageRobotInfo{EnergyLevel: 10},
+	} {
+		r.Deliver(s, msg)
+	}
+
+	if exitErr == nil {
+		t.Fatalf("panic was not reported")
+	}
+	_, path, ok := strings.Cut(strings.TrimSuffix(exitErr.Error(), ")"), "report: ")
+	if !ok {
+		t.Fatalf("report path not in error: %v", exitErr)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read report: %v", err)
+	}
+	report := string(b)
+
+	for _, want := range []string{
+		"panic: boom\n",
+		"crash_test.go",
+		"last messages (oldest first):\n\tInfo 3 3 0\n\tRobotInfo 10 0\n\n",
+		"last commands (oldest first):\n\tAccelerate 2.000000\n\tAccelerate 3.000000\n\n",
+		"world state:\n\t{Time:3 ",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%v", want, report)
+		}
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := newRing(3)
+	var got []string
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		r.add(s)
+		got = append(got, strings.Join(r.items(), ""))
+	}
+	want := []string{"a", "ab", "abc", "bcd", "cde"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("wrong items: got=%v want=%v", got, want)
+	}
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	rep := New(Config{Exit: func(err error) { t.Errorf("unexpected exit: %v", err) }})
+	func() {
+		defer rep.Recover()
+	}()
+}
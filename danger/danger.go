@@ -0,0 +1,226 @@
+// Package danger implements a decaying danger map built from the enemy shots
+// detected by the radar. The movement planner can use it to prefer safer
+// headings and positions.
+//
+// Every detected shot is recorded with its bearing from the robot and, if
+// its shooter can be guessed from the enemy tracker, with its lane: the
+// segment from the shooter through the shot. The danger of every shot decays
+// exponentially with its age.
+package danger
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/draw"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Map.
+type Config struct {
+	// HalfLife is the time after which the danger of a shot is halved.
+	// If zero, 2 is used.
+	HalfLife float64
+
+	// LaneWidth is the distance from a lane at which it is no longer
+	// dangerous. If zero, 2 is used.
+	LaneWidth float64
+
+	// LaneLength is how far the lane extends beyond the detected shot.
+	// If zero, 10 is used.
+	LaneLength float64
+
+	// ShooterRadius is the maximum distance from an enemy to the lane of
+	// a shot to consider it the shooter. If zero, 20 is used.
+	ShooterRadius float64
+}
+
+// Shot is an enemy shot detected by the radar.
+type Shot struct {
+	// Time is the game time of the detection.
+	Time float64
+
+	// Pos is the position of the shot.
+	Pos arena.Point
+
+	// Bearing is the absolute direction from the robot to the shot.
+	Bearing float64
+
+	// Lane is the segment from the guessed shooter through the shot. If
+	// the shooter is unknown, both ends are the position of the shot.
+	Lane [2]arena.Point
+}
+
+// Map is a danger map. It implements the rtb.Observer interface and must be
+// added to the robot after the world model and the tracker. Map methods can
+// be called concurrently.
+type Map struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+
+	mu    sync.Mutex
+	shots []Shot
+	time  float64
+}
+
+// New returns a danger map. If tr is nil, lanes are not computed and the
+// danger is concentrated on the position of the shots.
+func New(w *world.World, tr *track.Tracker, cfg Config) *Map {
+	if cfg.HalfLife == 0 {
+		cfg.HalfLife = 2
+	}
+	if cfg.LaneWidth == 0 {
+		cfg.LaneWidth = 2
+	}
+	if cfg.LaneLength == 0 {
+		cfg.LaneLength = 10
+	}
+	if cfg.ShooterRadius == 0 {
+		cfg.ShooterRadius = 20
+	}
+	return &Map{cfg: cfg, w: w, tr: tr}
+}
+
+// Message records the shots detected by the radar and discards the ones
+// whose danger is negligible.
+func (m *Map) Message(msg rtb.Message) {
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts:
+		m.mu.Lock()
+		m.shots, m.time = nil, 0
+		m.mu.Unlock()
+	case rtb.MessageRadar:
+		if msg.Object != rtb.ObjectShot {
+			return
+		}
+		m.Record(m.w.Absolute(msg.RadarAngle, msg.Distance))
+	case rtb.MessageInfo:
+		m.prune(msg.Time)
+	}
+}
+
+// Record records a shot detected at p.
+func (m *Map) Record(p arena.Point) {
+	s := m.w.State()
+	d := p.Sub(s.Pos)
+	shot := Shot{
+		Time:    s.Time,
+		Pos:     p,
+		Bearing: math.Atan2(d.Y, d.X),
+		Lane:    [2]arena.Point{p, p},
+	}
+	if shooter, ok := m.shooter(p, s.Time); ok {
+		dir := p.Sub(shooter)
+		if l := dir.Len(); l > 0 {
+			shot.Lane = [2]arena.Point{shooter, p.Add(dir.Mul(m.cfg.LaneLength / l))}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.shots = append(m.shots, shot)
+}
+
+// shooter returns the position of the enemy closest to p, if it is within
+// the shooter radius.
+func (m *Map) shooter(p arena.Point, time float64) (arena.Point, bool) {
+	if m.tr == nil {
+		return arena.Point{}, false
+	}
+	t, ok := m.tr.Nearest(p)
+	if !ok {
+		return arena.Point{}, false
+	}
+	pos := t.PositionAt(time)
+	if pos.Sub(p).Len() > m.cfg.ShooterRadius {
+		return arena.Point{}, false
+	}
+	return pos, true
+}
+
+// prune discards the shots whose danger is below 1%.
+func (m *Map) prune(now float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.time = now
+	live := m.shots[:0]
+	for _, s := range m.shots {
+		if m.weight(s) >= 0.01 {
+			live = append(live, s)
+		}
+	}
+	m.shots = live
+}
+
+// weight returns the decayed weight of a shot. m.mu must be held.
+func (m *Map) weight(s Shot) float64 {
+	return math.Exp2(-(m.time - s.Time) / m.cfg.HalfLife)
+}
+
+// Shots returns the recorded shots.
+func (m *Map) Shots() []Shot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Shot(nil), m.shots...)
+}
+
+// At returns the danger at p: the sum of the decayed weights of the lanes,
+// scaled by how close p is to each of them.
+func (m *Map) At(p arena.Point) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var danger float64
+	for _, s := range m.shots {
+		d := arena.SegmentDistance(p, s.Lane[0], s.Lane[1])
+		danger += m.weight(s) * math.Max(0, 1-d/m.cfg.LaneWidth)
+	}
+	return danger
+}
+
+// Heading returns the danger of moving with the given absolute heading: the
+// sum of the decayed weights of the shots, scaled by how much the heading
+// points towards their bearing.
+func (m *Map) Heading(angle float64) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var danger float64
+	for _, s := range m.shots {
+		danger += m.weight(s) * math.Max(0, math.Cos(angle-s.Bearing))
+	}
+	return danger
+}
+
+// SafestHeading returns the candidate heading with the lowest danger. The
+// first candidate wins ties. It panics if there are no candidates.
+func (m *Map) SafestHeading(candidates []float64) float64 {
+	best, bestDanger := candidates[0], m.Heading(candidates[0])
+	for _, c := range candidates[1:] {
+		if d := m.Heading(c); d < bestDanger {
+			best, bestDanger = c, d
+		}
+	}
+	return best
+}
+
+// Grid returns a grid with the danger at the center of each cell, which can
+// be rendered with draw.Canvas.Heatmap.
+func (m *Map) Grid(origin arena.Point, cellSize float64, cols, rows int) *draw.Grid {
+	g := draw.NewGrid(origin, cellSize, cols, rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			g.Set(col, row, m.At(g.Center(col, row)))
+		}
+	}
+	return g
+}
@@ -0,0 +1,104 @@
+package danger
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// setup returns a danger map for a robot at (0, 0). If enemy is not nil, an
+// enemy is detected at that position.
+func setup(enemy *arena.Point) (*Map, func(msg rtb.Message)) {
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	m := New(w, tr, Config{})
+	observers := []rtb.Observer{w, tr, m}
+
+	deliver := func(msg rtb.Message) {
+		for _, o := range observers {
+			o.Message(msg)
+		}
+	}
+	deliver(rtb.MessageGameStarts{})
+	deliver(rtb.MessageCoordinates{})
+	deliver(rtb.MessageInfo{Time: 0})
+	if enemy != nil {
+		deliver(rtb.MessageRadar{Distance: enemy.Len(), Object: rtb.ObjectRobot, RadarAngle: math.Atan2(enemy.Y, enemy.X)})
+	}
+	return m, deliver
+}
+
+func TestLane(t *testing.T) {
+	m, deliver := setup(&arena.Point{X: 10, Y: 0})
+	deliver(rtb.MessageRadar{Distance: 6, Object: rtb.ObjectShot, RadarAngle: 0})
+
+	shots := m.Shots()
+	if len(shots) != 1 {
+		t.Fatalf("wrong number of shots: got=%v want=%v", len(shots), 1)
+	}
+	want := [2]arena.Point{{X: 10, Y: 0}, {X: -4, Y: 0}}
+	if shots[0].Lane[0].Sub(want[0]).Len() > 1e-9 || shots[0].Lane[1].Sub(want[1]).Len() > 1e-9 {
+		t.Errorf("unexpected lane: got=%v want=%v", shots[0].Lane, want)
+	}
+
+	tests := []struct {
+		p    arena.Point
+		want float64
+	}{
+		{arena.Point{X: 0, Y: 0}, 1},
+		{arena.Point{X: 0, Y: 1}, 0.5},
+		{arena.Point{X: 0, Y: 3}, 0},
+		{arena.Point{X: -10, Y: 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := m.At(tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("unexpected danger at %v: got=%v want=%v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestDecay(t *testing.T) {
+	m, deliver := setup(nil)
+	deliver(rtb.MessageRadar{Distance: 5, Object: rtb.ObjectShot, RadarAngle: math.Pi / 2})
+
+	p := arena.Point{X: 0, Y: 5}
+	if got := m.At(p); math.Abs(got-1) > 1e-9 {
+		t.Errorf("unexpected initial danger: got=%v want=%v", got, 1)
+	}
+
+	deliver(rtb.MessageInfo{Time: 2})
+	if got := m.At(p); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("unexpected danger after a half-life: got=%v want=%v", got, 0.5)
+	}
+
+	deliver(rtb.MessageInfo{Time: 20})
+	if len(m.Shots()) != 0 {
+		t.Errorf("negligible shots not discarded")
+	}
+}
+
+func TestHeading(t *testing.T) {
+	m, deliver := setup(nil)
+	deliver(rtb.MessageRadar{Distance: 5, Object: rtb.ObjectShot, RadarAngle: 0})
+
+	if got := m.Heading(0); math.Abs(got-1) > 1e-9 {
+		t.Errorf("unexpected danger towards the shot: got=%v want=%v", got, 1)
+	}
+	if got := m.Heading(math.Pi); got != 0 {
+		t.Errorf("unexpected danger away from the shot: got=%v want=%v", got, 0)
+	}
+
+	candidates := []float64{0, math.Pi / 4, math.Pi / 2}
+	if got := m.SafestHeading(candidates); got != math.Pi/2 {
+		t.Errorf("unexpected safest heading: got=%v want=%v", got, math.Pi/2)
+	}
+
+	g := m.Grid(arena.Point{X: 4, Y: -1}, 2, 2, 1)
+	if g.At(0, 0) != 1 || g.At(1, 0) != 0 {
+		t.Errorf("unexpected grid: %v", g.Values)
+	}
+}
@@ -0,0 +1,118 @@
+package rtb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is a variant of the RTB protocol. RealTimeBattle exists in several
+// forks that differ slightly in the keywords they use. A dialect translates
+// the commands sent by the robot and the messages received from the server,
+// so the rest of the package, and the strategies, only deal with the
+// keywords of RealTimeBattle 1.0.8.
+//
+// The zero value is the dialect of RealTimeBattle 1.0.8.
+type Dialect struct {
+	// Name is the name of the dialect.
+	Name string
+
+	// Commands maps the keywords of the commands of RealTimeBattle
+	// 1.0.8 to the keywords of the dialect. Missing keywords are sent
+	// unchanged. If a keyword is mapped to an empty string, the command
+	// is not supported and sending it returns ErrUnsupportedCommand.
+	Commands map[string]string
+
+	// Aliases maps the keywords of the commands of RealTimeBattle 1.0.8
+	// to additional keywords. The command is sent once with its keyword
+	// and once with every alias, so robots work against servers that
+	// accept any of them. The server warns about the keywords it does
+	// not know, which the default warning policy only logs.
+	Aliases map[string][]string
+
+	// Messages maps the keywords of the messages of the dialect to the
+	// keywords of RealTimeBattle 1.0.8. Missing keywords are parsed
+	// unchanged. If a keyword is mapped to an empty string, the message
+	// is not accepted and it is discarded by Listen.
+	Messages map[string]string
+}
+
+// Built-in dialects.
+var (
+	// Dialect108 is the dialect of RealTimeBattle 1.0.8.
+	Dialect108 = Dialect{Name: "rtb-1.0.8"}
+
+	// DialectBreak is the dialect of the forks that kept the "Break"
+	// spelling of the Brake command used by the early versions of
+	// RealTimeBattle.
+	DialectBreak = Dialect{
+		Name:     "break",
+		Commands: map[string]string{"Brake": "Break"},
+	}
+
+	// DialectBrakeCompat sends the Brake command with both spellings,
+	// "Brake" and "Break", for robots that must work against servers
+	// of unknown origin.
+	DialectBrakeCompat = Dialect{
+		Name:    "brake-compat",
+		Aliases: map[string][]string{"Brake": {"Break"}},
+	}
+)
+
+// command translates cmd, which must not include the trailing newline, to
+// the dialect. It returns the lines to send, including their newlines.
+func (d Dialect) command(cmd string) (string, error) {
+	keyword, rest, found := strings.Cut(cmd, " ")
+	kw, ok := d.Commands[keyword]
+	if !ok {
+		kw = keyword
+	} else if kw == "" {
+		return "", fmt.Errorf("%w %v (dialect %v)", ErrUnsupportedCommand, keyword, d.Name)
+	}
+
+	var b strings.Builder
+	for _, kw := range append([]string{kw}, d.Aliases[keyword]...) {
+		line := kw
+		if found {
+			line += " " + rest
+		}
+		line += "\n"
+
+		if len(line) > 128 {
+			return "", fmt.Errorf("%w (%v)", ErrMessageTooLong, len(line))
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+// message translates the message line from the dialect. It returns false if
+// the message is not accepted.
+func (d Dialect) message(line string) (string, bool) {
+	keyword, rest, found := strings.Cut(line, " ")
+	kw, ok := d.Messages[keyword]
+	if !ok {
+		return line, true
+	}
+	if kw == "" {
+		return "", false
+	}
+	if !found {
+		return kw, true
+	}
+	return kw + " " + rest, true
+}
+
+// SetDialect sets the dialect spoken by the server. It must be called
+// before Listen to affect the received messages. Observers always see the
+// commands of RealTimeBattle 1.0.8, so they do not depend on the dialect.
+func (r *Robot) SetDialect(d Dialect) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dialect = d
+}
+
+// SetDialect calls SetDialect on the default Robot.
+func SetDialect(d Dialect) {
+	std.SetDialect(d)
+}
@@ -0,0 +1,204 @@
+// Package dodge implements an evasive movement triggered by enemy fire.
+//
+// Enemy shots are usually detected too late by the radar, so the Dodger uses
+// a heuristic: a drop in the energy level reported for an enemy means it has
+// fired. Then, the robot moves perpendicularly to the line of fire, to the
+// side where the danger map reports less danger. Note that the energy of an
+// enemy also drops when it is hit, which causes false alarms.
+package dodge
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/danger"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/rng"
+	"github.com/jroimartin/rtb/trace"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Dodger.
+type Config struct {
+	// Aggressiveness, between 0 and 1, trades safety for position. An
+	// aggressive robot dodges shorter distances and ignores the shots of
+	// distant enemies. With 1, the robot never dodges. If zero, 0.5 is
+	// used.
+	Aggressiveness float64
+
+	// Distance is the dodge distance with an aggressiveness of 0.5. If
+	// zero, 4 is used.
+	Distance float64
+
+	// MinDrop is the minimum energy drop of an enemy to consider it has
+	// fired. If zero, 0.5 is used.
+	MinDrop float64
+
+	// Range is the distance from which shots are dodged with an
+	// aggressiveness of 0.5. If zero, 30 is used.
+	Range float64
+
+	// Danger, if not nil, is used to choose the side of the dodge.
+	Danger *danger.Map
+
+	// Jitter, between 0 and 1, randomizes the dodge distance, so the
+	// enemies cannot predict where the robot goes. The distance is
+	// multiplied by a random factor between 1-Jitter and 1+Jitter. If
+	// zero, the distance is not randomized.
+	Jitter float64
+
+	// Rand is the source of the jitter. If nil, the default generator of
+	// the rng package is used.
+	Rand *rand.Rand
+
+	// Trace, if not nil, records the dodges.
+	Trace *trace.Tracer
+}
+
+// Dodger dodges enemy fire. It implements the rtb.Observer interface and must
+// be added to the robot after the world model, the tracker and the
+// navigator. Dodger methods can be called concurrently.
+type Dodger struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+	n   *nav.Navigator
+
+	mu       sync.Mutex
+	energies map[int]float64
+	target   *arena.Point
+	dodges   int
+}
+
+// OnEnemyFire returns a Dodger that watches the tracks of tr and dodges
+// through n.
+func OnEnemyFire(tr *track.Tracker, n *nav.Navigator, cfg Config) *Dodger {
+	if cfg.Aggressiveness == 0 {
+		cfg.Aggressiveness = 0.5
+	}
+	if cfg.Distance == 0 {
+		cfg.Distance = 4
+	}
+	if cfg.MinDrop == 0 {
+		cfg.MinDrop = 0.5
+	}
+	if cfg.Range == 0 {
+		cfg.Range = 30
+	}
+	return &Dodger{
+		cfg:      cfg,
+		w:        n.World(),
+		tr:       tr,
+		n:        n,
+		energies: map[int]float64{},
+	}
+}
+
+// Dodges returns the number of dodges started in the current game.
+func (d *Dodger) Dodges() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dodges
+}
+
+// Message checks the energy of the enemies when msg is an Info message and
+// dodges if any of them has fired.
+func (d *Dodger) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		d.mu.Lock()
+		d.energies, d.target, d.dodges = map[int]float64{}, nil, 0
+		d.mu.Unlock()
+	case rtb.MessageInfo:
+		d.check()
+	}
+}
+
+// check looks for enemies that have fired.
+func (d *Dodger) check() {
+	s := d.w.State()
+	wps := d.n.Waypoints()
+
+	d.mu.Lock()
+	// The current dodge is finished when the navigator discards its
+	// waypoint.
+	if d.target != nil && (len(wps) == 0 || wps[0] != *d.target) {
+		d.target = nil
+	}
+
+	var shooter *track.Track
+	for _, t := range d.tr.Tracks() {
+		if t.TeamMate {
+			continue
+		}
+		last, ok := d.energies[t.ID]
+		d.energies[t.ID] = t.Energy
+		if !ok || last-t.Energy < d.cfg.MinDrop {
+			continue
+		}
+		// An aggressive robot ignores distant enemies.
+		dist := t.Pos.Sub(s.Pos).Len()
+		if dist > d.cfg.Range*2*(1-d.cfg.Aggressiveness) {
+			continue
+		}
+		if shooter == nil || dist < shooter.Pos.Sub(s.Pos).Len() {
+			t := t
+			shooter = &t
+		}
+	}
+	if shooter == nil || d.target != nil {
+		d.mu.Unlock()
+		return
+	}
+
+	p := d.dodgePoint(s, shooter.Pos)
+	d.target = &p
+	d.dodges++
+	d.mu.Unlock()
+
+	d.n.GoTo(append([]arena.Point{p}, wps...)...)
+	d.cfg.Trace.Recordf("dodge", "enemy %v fired from %.1f, dodging to (%.1f, %.1f)", shooter.ID, shooter.Pos.Sub(s.Pos).Len(), p.X, p.Y)
+}
+
+// dodgePoint returns the point where the robot dodges a shot fired from
+// shooter.
+func (d *Dodger) dodgePoint(s world.State, shooter arena.Point) arena.Point {
+	fire := s.Pos.Sub(shooter)
+	perp := arena.Point{X: -fire.Y, Y: fire.X}
+	if l := perp.Len(); l > 0 {
+		perp = perp.Mul(1 / l)
+	} else {
+		perp = arena.Polar(s.Heading+math.Pi/2, 1)
+	}
+
+	dist := d.cfg.Distance * (1.5 - d.cfg.Aggressiveness)
+	if d.cfg.Jitter > 0 {
+		dist *= 1 + d.cfg.Jitter*(2*rng.Or(d.cfg.Rand).Float64()-1)
+	}
+	left, right := s.Pos.Add(perp.Mul(dist)), s.Pos.Sub(perp.Mul(dist))
+
+	if d.cfg.Danger != nil {
+		dl, dr := d.cfg.Danger.At(left), d.cfg.Danger.At(right)
+		if dl < dr {
+			return left
+		}
+		if dr < dl {
+			return right
+		}
+	}
+
+	// Prefer the side that requires less turning.
+	al, _ := d.w.Relative(left)
+	ar, _ := d.w.Relative(right)
+	if math.Abs(al) <= math.Abs(ar) {
+		return left
+	}
+	return right
+}
@@ -0,0 +1,124 @@
+package dodge
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/danger"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// constSource is a rand.Source that always returns the same number.
+type constSource int64
+
+func (s constSource) Int63() int64 { return int64(s) }
+func (s constSource) Seed(int64)   {}
+
+func TestDodger(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		enemy     float64
+		shot      bool
+		energies  []float64
+		wantFirst arena.Point
+		wantCount int
+	}{
+		{
+			"No fire",
+			Config{},
+			10,
+			false,
+			[]float64{50, 50},
+			arena.Point{X: 0, Y: 20},
+			0,
+		},
+		{
+			"Fire",
+			Config{},
+			10,
+			false,
+			[]float64{50, 40},
+			arena.Point{X: 0, Y: 4},
+			1,
+		},
+		{
+			"Aggressive",
+			Config{Aggressiveness: 0.75},
+			10,
+			false,
+			[]float64{50, 40},
+			arena.Point{X: 0, Y: 3},
+			1,
+		},
+		{
+			"Out of range",
+			Config{Aggressiveness: 0.9},
+			10,
+			false,
+			[]float64{50, 40},
+			arena.Point{X: 0, Y: 20},
+			0,
+		},
+		{
+			"Jitter",
+			Config{Jitter: 0.5, Rand: rand.New(constSource(3 << 61))},
+			10,
+			false,
+			[]float64{50, 40},
+			arena.Point{X: 0, Y: 5},
+			1,
+		},
+		{
+			"Danger",
+			Config{},
+			10,
+			true,
+			[]float64{50, 40},
+			arena.Point{X: 0, Y: -4},
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rtb.NewRobot(nil, io.Discard)
+			w := world.New()
+			tr := track.New(w, track.Config{})
+			n := nav.New(r, w, nav.Config{})
+			dm := danger.New(w, tr, danger.Config{})
+			cfg := tt.cfg
+			cfg.Danger = dm
+			d := OnEnemyFire(tr, n, cfg)
+			for _, obs := range []rtb.Observer{w, tr, dm, n, d} {
+				r.AddObserver(obs)
+			}
+
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			r.Deliver(nop, rtb.MessageGameStarts{})
+			r.Deliver(nop, rtb.MessageCoordinates{Angle: 1})
+			n.GoTo(arena.Point{X: 0, Y: 20})
+			if tt.shot {
+				dm.Record(arena.Point{X: 0, Y: 3})
+			}
+
+			for i, e := range tt.energies {
+				r.Deliver(nop, rtb.MessageRadar{Distance: tt.enemy, Object: rtb.ObjectRobot, RadarAngle: -1})
+				r.Deliver(nop, rtb.MessageRobotInfo{EnergyLevel: e})
+				r.Deliver(nop, rtb.MessageInfo{Time: float64(i)})
+			}
+
+			if got := n.Waypoints(); got[0] != tt.wantFirst {
+				t.Errorf("unexpected first waypoint: got=%v want=%v", got[0], tt.wantFirst)
+			}
+			if got := d.Dodges(); got != tt.wantCount {
+				t.Errorf("unexpected number of dodges: got=%v want=%v", got, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Package dossier keeps what a robot learns about its opponents across the
+// games of a tournament, so it can adapt to them over a sequence.
+//
+// For every opponent, a Dossier records the hit rate of the motion models of
+// the virtual gun, the speed and the turn rate of the opponent and the rate
+// at which it loses energy. The data is stored in a JSON file, which is
+// loaded on Initialize and written when every game finishes:
+//
+//	tr := track.New(w, track.Config{})
+//	gun := vgun.New(w, tr, vgun.Config{})
+//	d := dossier.New(tr, dossier.Config{Path: "/tmp/mybot.json", Gun: gun})
+//	r.AddObserver(w)
+//	r.AddObserver(tr)
+//	r.AddObserver(gun)
+//	r.AddObserver(d)
+//
+// The RealTimeBattle protocol does not tell the robots the names of their
+// opponents, so the tracks are mapped to names by Config.Identify, e.g. with
+// the names shared by the organizer of a duel. By default, all the enemies
+// share the same entry, which is accurate in duels, since the opponent does
+// not change during a sequence.
+package dossier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/vgun"
+)
+
+// DefaultName is the name of the enemies when Config.Identify is nil.
+const DefaultName = "enemy"
+
+// Config is the configuration of a Dossier.
+type Config struct {
+	// Path is the path of the file where the data is stored. If empty,
+	// the data is kept in memory only.
+	Path string
+
+	// Identify returns the name of the opponent of a track. If nil, all
+	// the enemies are called DefaultName.
+	Identify func(t track.Track) string
+
+	// Gun is the virtual gun whose statistics are recorded. If nil, no
+	// aiming data is recorded.
+	Gun *vgun.Gun
+
+	// Models are the models returned by BestModel. If empty,
+	// vgun.DefaultModels is used. They must be the models of Gun.
+	Models []vgun.Model
+
+	// MinShots is the number of virtual shots per model needed by
+	// BestModel to select a model. If zero, 20 is used.
+	MinShots int
+}
+
+// Mean is a running mean.
+type Mean struct {
+	N     int     `json:"n"`
+	Value float64 `json:"value"`
+}
+
+// Add adds a sample to the mean.
+func (m *Mean) Add(v float64) {
+	m.N++
+	m.Value += (v - m.Value) / float64(m.N)
+}
+
+// Entry is the data learned about an opponent.
+type Entry struct {
+	// Games is the number of games played against the opponent.
+	Games int `json:"games"`
+
+	// Aim are the accumulated statistics of the virtual gun, by model
+	// name.
+	Aim map[string]vgun.Stats `json:"aim,omitempty"`
+
+	// Speed is the mean speed of the opponent.
+	Speed Mean `json:"speed"`
+
+	// TurnRate is the mean absolute rate of change of the direction of
+	// movement of the opponent, in radians per second.
+	TurnRate Mean `json:"turn_rate"`
+
+	// EnergyRate is the mean energy lost by the opponent per second,
+	// including the energy spent on shots and the damage taken.
+	EnergyRate Mean `json:"energy_rate"`
+}
+
+// sample is the last sample of a track.
+type sample struct {
+	time    float64
+	heading float64
+	moving  bool
+	energy  float64
+}
+
+// Dossier records data about the opponents. It implements the rtb.Observer
+// interface and must be added to the robot after the tracker and the
+// virtual gun. Dossier methods can be called concurrently.
+type Dossier struct {
+	rtb.NopCommand
+
+	cfg Config
+	tr  *track.Tracker
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+	names   map[int]string
+	samples map[int]sample
+	err     error
+}
+
+// New returns a Dossier that records the tracks of tr.
+func New(tr *track.Tracker, cfg Config) *Dossier {
+	if cfg.Identify == nil {
+		cfg.Identify = func(track.Track) string { return DefaultName }
+	}
+	if len(cfg.Models) == 0 {
+		cfg.Models = vgun.DefaultModels()
+	}
+	if cfg.MinShots == 0 {
+		cfg.MinShots = 20
+	}
+	return &Dossier{
+		cfg:     cfg,
+		tr:      tr,
+		entries: map[string]*Entry{},
+		names:   map[int]string{},
+		samples: map[int]sample{},
+	}
+}
+
+// Message loads the file on Initialize, records the tracks on Info and
+// writes the file on GameFinishes.
+func (d *Dossier) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageInitialize:
+		d.setErr(d.Load())
+	case rtb.MessageGameStarts:
+		d.mu.Lock()
+		d.names, d.samples = map[int]string{}, map[int]sample{}
+		d.mu.Unlock()
+	case rtb.MessageInfo:
+		d.observe()
+	case rtb.MessageGameFinishes:
+		d.finish()
+		d.setErr(d.Save())
+	}
+}
+
+// observe records the observations of the enemies since the previous
+// call.
+func (d *Dossier) observe() {
+	tracks := d.tr.Tracks()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, t := range tracks {
+		prev, seen := d.samples[t.ID]
+		if t.TeamMate || seen && t.LastSeen <= prev.time {
+			continue
+		}
+		name, ok := d.names[t.ID]
+		if !ok {
+			name = d.cfg.Identify(t)
+			d.names[t.ID] = name
+		}
+		e := d.entry(name)
+
+		speed := t.Vel.Len()
+		cur := sample{time: t.LastSeen, heading: math.Atan2(t.Vel.Y, t.Vel.X), moving: speed > 0, energy: t.Energy}
+		e.Speed.Add(speed)
+		if seen {
+			dt := cur.time - prev.time
+			if prev.moving && cur.moving {
+				e.TurnRate.Add(math.Abs(physics.AngleDiff(cur.heading, prev.heading)) / dt)
+			}
+			if prev.energy > 0 && cur.energy > 0 {
+				e.EnergyRate.Add((prev.energy - cur.energy) / dt)
+			}
+		}
+		d.samples[t.ID] = cur
+	}
+}
+
+// finish adds the game and the statistics of the virtual gun to the entries
+// of the opponents of the game.
+func (d *Dossier) finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	played := map[string]bool{}
+	for id, name := range d.names {
+		played[name] = true
+		if d.cfg.Gun == nil {
+			continue
+		}
+		e := d.entry(name)
+		if e.Aim == nil {
+			e.Aim = map[string]vgun.Stats{}
+		}
+		for _, s := range d.cfg.Gun.Stats(id) {
+			acc := e.Aim[s.Model]
+			acc.Model = s.Model
+			acc.Shots += s.Shots
+			acc.Hits += s.Hits
+			e.Aim[s.Model] = acc
+		}
+	}
+	for name := range played {
+		d.entry(name).Games++
+	}
+	d.names = map[int]string{}
+}
+
+// entry returns the entry of an opponent, creating it if needed. d.mu must
+// be held.
+func (d *Dossier) entry(name string) *Entry {
+	e, ok := d.entries[name]
+	if !ok {
+		e = &Entry{}
+		d.entries[name] = e
+	}
+	return e
+}
+
+// Entry returns the data learned about the opponent name. It returns false
+// if there is none.
+func (d *Dossier) Entry(name string) (Entry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[name]
+	if !ok {
+		return Entry{}, false
+	}
+	c := *e
+	if e.Aim != nil {
+		c.Aim = make(map[string]vgun.Stats, len(e.Aim))
+		for k, v := range e.Aim {
+			c.Aim[k] = v
+		}
+	}
+	return c, true
+}
+
+// BestModel returns the model with the best hit rate against the opponent
+// name, among the models with at least Config.MinShots virtual shots. It
+// returns false if there is none. It can be used to select the motion model
+// of the new tracks of the opponent before the virtual gun has enough
+// statistics:
+//
+//	if m, ok := d.BestModel(name); ok {
+//		tr.SetPredictor(t.ID, m.Predictor)
+//	}
+func (d *Dossier) BestModel(name string) (vgun.Model, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e, ok := d.entries[name]
+	if !ok {
+		return vgun.Model{}, false
+	}
+	best, found := vgun.Model{}, false
+	var rate float64
+	for _, m := range d.cfg.Models {
+		s, ok := e.Aim[m.Name]
+		if !ok || s.Shots < d.cfg.MinShots {
+			continue
+		}
+		if !found || s.HitRate() > rate {
+			best, rate, found = m, s.HitRate(), true
+		}
+	}
+	return best, found
+}
+
+// Load replaces the data with the contents of the file. A missing file is
+// not an error.
+func (d *Dossier) Load() error {
+	if d.cfg.Path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(d.cfg.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read dossier: %v", err)
+	}
+	entries := map[string]*Entry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("could not parse dossier: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = entries
+	return nil
+}
+
+// Save writes the data to the file. The file is replaced atomically, so it
+// is not corrupted if the robot is killed while writing it.
+func (d *Dossier) Save() error {
+	if d.cfg.Path == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	data, err := json.MarshalIndent(d.entries, "", "\t")
+	d.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("could not encode dossier: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.cfg.Path), filepath.Base(d.cfg.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write dossier: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write dossier: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write dossier: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), d.cfg.Path); err != nil {
+		return fmt.Errorf("could not write dossier: %v", err)
+	}
+	return nil
+}
+
+// Err returns the last error found loading or saving the file when handling
+// the messages.
+func (d *Dossier) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.err
+}
+
+// setErr records err, if not nil.
+func (d *Dossier) setErr(err error) {
+	if err == nil {
+		return
+	}
+	d.mu.Lock()
+	d.err = err
+	d.mu.Unlock()
+}
@@ -0,0 +1,120 @@
+package dossier
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/vgun"
+	"github.com/jroimartin/rtb/world"
+)
+
+// playGame plays a game against an enemy that moves in a circle of radius 5
+// at speed 1 and loses 1 energy per second.
+func playGame(observers []rtb.Observer) {
+	deliver := func(msg rtb.Message) {
+		for _, o := range observers {
+			o.Message(msg)
+		}
+	}
+	deliver(rtb.MessageInitialize{})
+	deliver(rtb.MessageGameOption{Option: rtb.GOptionShotSpeed, Value: 10})
+	deliver(rtb.MessageGameStarts{})
+	deliver(rtb.MessageCoordinates{})
+	for i := 0; i < 200; i++ {
+		time := float64(i) * 0.1
+		p := arena.Point{X: 15, Y: 0}.Add(arena.Polar(time/5, 5))
+		deliver(rtb.MessageInfo{Time: time})
+		deliver(rtb.MessageRadar{Distance: p.Len(), Object: rtb.ObjectRobot, RadarAngle: math.Atan2(p.Y, p.X)})
+		deliver(rtb.MessageRobotInfo{EnergyLevel: 100 - time})
+	}
+	deliver(rtb.MessageGameFinishes{})
+}
+
+func TestDossier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dossier.json")
+
+	for game := 1; game <= 2; game++ {
+		w := world.New()
+		tr := track.New(w, track.Config{})
+		gun := vgun.New(w, tr, vgun.Config{})
+		d := New(tr, Config{Path: path, Gun: gun})
+		playGame([]rtb.Observer{w, tr, gun, d})
+		if err := d.Err(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		e, ok := d.Entry(DefaultName)
+		if !ok {
+			t.Fatalf("game %v: missing entry", game)
+		}
+		if e.Games != game {
+			t.Errorf("game %v: unexpected games: got=%v want=%v", game, e.Games, game)
+		}
+		if math.Abs(e.Speed.Value-1) > 0.1 || e.Speed.N < 190*game {
+			t.Errorf("game %v: unexpected speed: %+v", game, e.Speed)
+		}
+		if math.Abs(e.TurnRate.Value-0.2) > 0.05 {
+			t.Errorf("game %v: unexpected turn rate: %+v", game, e.TurnRate)
+		}
+		if math.Abs(e.EnergyRate.Value-1) > 1e-6 {
+			t.Errorf("game %v: unexpected energy rate: %+v", game, e.EnergyRate)
+		}
+		if s := e.Aim["circular"]; s.Shots == 0 || s.Hits == 0 {
+			t.Errorf("game %v: unexpected aim stats: %+v", game, e.Aim)
+		}
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("missing dossier file: %v", err)
+	}
+}
+
+func TestBestModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dossier.json")
+	data := `{
+	"spinner": {"games": 3, "aim": {
+		"linear": {"Model": "linear", "Shots": 100, "Hits": 10},
+		"circular": {"Model": "circular", "Shots": 100, "Hits": 60},
+		"pattern": {"Model": "pattern", "Shots": 10, "Hits": 10}
+	}},
+	"duck": {"games": 1, "aim": {"linear": {"Model": "linear", "Shots": 5, "Hits": 5}}}
+}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("could not write dossier: %v", err)
+	}
+
+	d := New(nil, Config{Path: path})
+	d.Message(rtb.MessageInitialize{First: true})
+	if err := d.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// pattern has the best hit rate, but not enough shots.
+	if m, ok := d.BestModel("spinner"); !ok || m.Name != "circular" {
+		t.Errorf("unexpected model: got=%v, %v want=circular", m.Name, ok)
+	}
+	if _, ok := d.BestModel("duck"); ok {
+		t.Errorf("unexpected model for duck")
+	}
+	if _, ok := d.BestModel("rammer"); ok {
+		t.Errorf("unexpected model for rammer")
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dossier.json")
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatalf("could not write dossier: %v", err)
+	}
+
+	d := New(nil, Config{Path: path})
+	d.Message(rtb.MessageInitialize{First: true})
+	if d.Err() == nil {
+		t.Errorf("expected error")
+	}
+}
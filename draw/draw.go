@@ -0,0 +1,218 @@
+// Package draw implements a debug drawing layer on top of DebugLine and
+// DebugCircle.
+//
+// Shapes are given in world coordinates and converted to the robot-relative
+// polar form expected by the server using a world model. Every shape has a
+// lifetime. A Canvas redraws the live shapes on every tick, so they stay
+// fixed in the arena while the robot moves, and discards them when they
+// expire, so overlays are refreshed instead of accumulated.
+//
+// A Canvas is usually attached to a robot as an observer, after the world
+// model:
+//
+//	w := world.New()
+//	c := draw.New(r, w)
+//	r.AddObserver(w)
+//	r.AddObserver(c)
+package draw
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Lifetimes with special meaning.
+const (
+	// Once draws a shape only in the next tick.
+	Once = 0
+
+	// Forever draws a shape until the canvas is cleared.
+	Forever = math.MaxFloat64
+)
+
+// arcStep is the maximum angle covered by each of the segments used to draw
+// an arc.
+const arcStep = math.Pi / 8
+
+// segment is a line segment in world coordinates.
+type segment struct {
+	a, b arena.Point
+}
+
+// circle is a circle in world coordinates.
+type circle struct {
+	center arena.Point
+	radius float64
+}
+
+// shape is a shape in a canvas.
+type shape struct {
+	segments []segment
+	circles  []circle
+
+	// expires is the game time when the shape expires.
+	expires float64
+
+	// drawn is true if the shape has been drawn at least once.
+	drawn bool
+}
+
+// Canvas draws shapes in the arena. It implements the rtb.Observer interface
+// and draws the live shapes every time an Info message, which is sent once
+// per tick, is received. Canvas methods can be called concurrently.
+type Canvas struct {
+	rtb.NopCommand
+
+	r *rtb.Robot
+	w *world.World
+
+	mu     sync.Mutex
+	shapes []*shape
+}
+
+// New returns a Canvas that draws through r, using w to convert world
+// coordinates to robot-relative ones.
+func New(r *rtb.Robot, w *world.World) *Canvas {
+	return &Canvas{r: r, w: w}
+}
+
+// add adds a shape that lives for lifetime seconds of game time.
+func (c *Canvas) add(s *shape, lifetime float64) {
+	s.expires = c.w.State().Time + lifetime
+	if lifetime == Forever {
+		s.expires = Forever
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shapes = append(c.shapes, s)
+}
+
+// Line draws a line from a to b.
+func (c *Canvas) Line(a, b arena.Point, lifetime float64) {
+	c.add(&shape{segments: []segment{{a, b}}}, lifetime)
+}
+
+// Polyline draws the lines joining pts.
+func (c *Canvas) Polyline(pts []arena.Point, lifetime float64) {
+	s := &shape{}
+	for i := 1; i < len(pts); i++ {
+		s.segments = append(s.segments, segment{pts[i-1], pts[i]})
+	}
+	c.add(s, lifetime)
+}
+
+// Arrow draws an arrow from a to b. The length of the head is a quarter of
+// the length of the arrow.
+func (c *Canvas) Arrow(a, b arena.Point, lifetime float64) {
+	d := b.Sub(a)
+	angle := math.Atan2(d.Y, d.X)
+	head := d.Len() / 4
+	s := &shape{segments: []segment{
+		{a, b},
+		{b, b.Sub(arena.Polar(angle+math.Pi/6, head))},
+		{b, b.Sub(arena.Polar(angle-math.Pi/6, head))},
+	}}
+	c.add(s, lifetime)
+}
+
+// Cross draws an X centered at p. size is the length of the arms.
+func (c *Canvas) Cross(p arena.Point, size float64, lifetime float64) {
+	d := size / 2
+	s := &shape{segments: []segment{
+		{arena.Point{X: p.X - d, Y: p.Y - d}, arena.Point{X: p.X + d, Y: p.Y + d}},
+		{arena.Point{X: p.X - d, Y: p.Y + d}, arena.Point{X: p.X + d, Y: p.Y - d}},
+	}}
+	c.add(s, lifetime)
+}
+
+// Circle draws a circle.
+func (c *Canvas) Circle(center arena.Point, radius float64, lifetime float64) {
+	c.add(&shape{circles: []circle{{center, radius}}}, lifetime)
+}
+
+// Arc draws the arc of the circle with the given center and radius that goes
+// counterclockwise from angle1 to angle2. Angles are given in radians. The
+// arc is approximated with line segments.
+func (c *Canvas) Arc(center arena.Point, radius, angle1, angle2 float64, lifetime float64) {
+	for angle2 < angle1 {
+		angle2 += 2 * math.Pi
+	}
+	n := int(math.Max(1, math.Ceil((angle2-angle1)/arcStep)))
+	pts := make([]arena.Point, n+1)
+	for i := range pts {
+		angle := angle1 + (angle2-angle1)*float64(i)/float64(n)
+		pts[i] = center.Add(arena.Polar(angle, radius))
+	}
+	c.Polyline(pts, lifetime)
+}
+
+// Text draws a cross at p and prints text in the message window, prefixed
+// with the position. Since printed messages cannot be removed, the text is
+// printed only once, but the cross lives for lifetime.
+func (c *Canvas) Text(p arena.Point, text string, lifetime float64) error {
+	c.Cross(p, 1, lifetime)
+	return c.r.Printf("(%.1f, %.1f) %v", p.X, p.Y, text)
+}
+
+// Clear removes all the shapes.
+func (c *Canvas) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shapes = nil
+}
+
+// Flush draws the live shapes and discards the expired ones. It is called
+// automatically when an Info message is received.
+func (c *Canvas) Flush() {
+	now := c.w.State().Time
+
+	c.mu.Lock()
+	var draw []*shape
+	live := c.shapes[:0]
+	for _, s := range c.shapes {
+		if !s.drawn || now <= s.expires {
+			draw = append(draw, s)
+			s.drawn = true
+		}
+		if now < s.expires {
+			live = append(live, s)
+		}
+	}
+	for i := len(live); i < len(c.shapes); i++ {
+		c.shapes[i] = nil
+	}
+	c.shapes = live
+	c.mu.Unlock()
+
+	// Commands are sent without holding the lock, because observers
+	// could call back the canvas.
+	for _, s := range draw {
+		for _, seg := range s.segments {
+			a1, r1 := c.w.Relative(seg.a)
+			a2, r2 := c.w.Relative(seg.b)
+			c.r.DebugLine(a1, r1, a2, r2)
+		}
+		for _, circ := range s.circles {
+			a, r := c.w.Relative(circ.center)
+			c.r.DebugCircle(a, r, circ.radius)
+		}
+	}
+}
+
+// Message draws the live shapes when msg is an Info message and clears the
+// canvas when a new game starts.
+func (c *Canvas) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		c.Clear()
+	case rtb.MessageInfo:
+		c.Flush()
+	}
+}
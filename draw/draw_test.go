@@ -0,0 +1,176 @@
+package draw
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+// newCanvas returns a canvas for a robot at (0, 0) heading to the positive Y
+// axis. Commands are written to out.
+func newCanvas(out *bytes.Buffer) (*rtb.Robot, *Canvas) {
+	r := rtb.NewRobot(nil, out)
+	w := world.New()
+	c := New(r, w)
+	r.AddObserver(w)
+	r.AddObserver(c)
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	r.Deliver(nop, rtb.MessageCoordinates{X: 0, Y: 0, Angle: 1.5707963267948966})
+	return r, c
+}
+
+func TestCanvas(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(c *Canvas)
+		want []string
+	}{
+		{
+			"Line",
+			func(c *Canvas) { c.Line(arena.Point{X: 0, Y: 1}, arena.Point{X: 0, Y: 2}, Once) },
+			[]string{"DebugLine 0.000000 1.000000 0.000000 2.000000"},
+		},
+		{
+			"Circle",
+			func(c *Canvas) { c.Circle(arena.Point{X: -3, Y: 0}, 1, Once) },
+			[]string{"DebugCircle 1.570796 3.000000 1.000000"},
+		},
+		{
+			"Cross",
+			func(c *Canvas) { c.Cross(arena.Point{X: 0, Y: 2}, 2, Once) },
+			[]string{
+				"DebugLine 0.785398 1.414214 -0.321751 3.162278",
+				"DebugLine 0.321751 3.162278 -0.785398 1.414214",
+			},
+		},
+		{
+			"Arc",
+			func(c *Canvas) { c.Arc(arena.Point{}, 1, 0, 3.141592653589793, Once) },
+			[]string{
+				"DebugLine -1.570796 1.000000 -1.178097 1.000000",
+				"DebugLine -1.178097 1.000000 -0.785398 1.000000",
+				"DebugLine -0.785398 1.000000 -0.392699 1.000000",
+				"DebugLine -0.392699 1.000000 0.000000 1.000000",
+				"DebugLine 0.000000 1.000000 0.392699 1.000000",
+				"DebugLine 0.392699 1.000000 0.785398 1.000000",
+				"DebugLine 0.785398 1.000000 1.178097 1.000000",
+				"DebugLine 1.178097 1.000000 1.570796 1.000000",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r, c := newCanvas(&out)
+			tt.draw(c)
+			out.Reset()
+
+			r.Deliver(rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {}), rtb.MessageInfo{Time: 1})
+
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(got) != len(tt.want) {
+				t.Fatalf("wrong number of commands: got=%q want=%q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("unexpected command: got=%q want=%q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	var out bytes.Buffer
+	r, c := newCanvas(&out)
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+
+	c.Line(arena.Point{X: 0, Y: 1}, arena.Point{X: 0, Y: 2}, Once)
+	c.Circle(arena.Point{X: 0, Y: 1}, 1, 1)
+	c.Circle(arena.Point{X: 0, Y: 1}, 2, Forever)
+
+	tests := []struct {
+		time float64
+		want int
+	}{
+		{0.5, 3},
+		{1, 2},
+		{1.5, 1},
+		{100, 1},
+	}
+
+	for _, tt := range tests {
+		out.Reset()
+		r.Deliver(nop, rtb.MessageInfo{Time: tt.time})
+		if got := strings.Count(out.String(), "\n"); got != tt.want {
+			t.Errorf("unexpected number of commands at time %v: got=%v want=%v", tt.time, got, tt.want)
+		}
+	}
+
+	c.Clear()
+	out.Reset()
+	r.Deliver(nop, rtb.MessageInfo{Time: 101})
+	if out.Len() != 0 {
+		t.Errorf("unexpected commands after clear: %q", out.String())
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	g := NewGrid(arena.Point{X: -2, Y: 0}, 2, 2, 2)
+	g.Set(0, 0, 4)
+	g.Set(1, 0, -2)
+	g.Set(0, 1, 1)
+	g.Set(1, 1, 0.1)
+
+	tests := []struct {
+		name string
+		cfg  HeatmapConfig
+		want []string
+	}{
+		{
+			"All",
+			HeatmapConfig{},
+			[]string{
+				"DebugCircle 0.785398 1.414214 1.000000",
+				"DebugCircle -0.785398 1.414214 0.500000",
+				"DebugCircle 0.321751 3.162278 0.250000",
+			},
+		},
+		{
+			"Limited",
+			HeatmapConfig{MaxCircles: 1},
+			[]string{"DebugCircle 0.785398 1.414214 1.000000"},
+		},
+		{
+			"Scale",
+			HeatmapConfig{Scale: 2, Threshold: 0.6},
+			[]string{
+				"DebugCircle 0.785398 1.414214 1.000000",
+				"DebugCircle -0.785398 1.414214 1.000000",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r, c := newCanvas(&out)
+			c.Heatmap(g, tt.cfg)
+			out.Reset()
+
+			r.Deliver(rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {}), rtb.MessageInfo{Time: 1})
+
+			got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package draw
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jroimartin/rtb/arena"
+)
+
+// Grid is a grid of scalar values in world coordinates, e.g. a potential
+// field or a danger map.
+type Grid struct {
+	// Origin is the corner of the cell (0, 0) with the lowest
+	// coordinates.
+	Origin arena.Point
+
+	// CellSize is the length of the side of the cells.
+	CellSize float64
+
+	// Cols and Rows are the dimensions of the grid.
+	Cols, Rows int
+
+	// Values are the values of the cells in row-major order.
+	Values []float64
+}
+
+// NewGrid returns a grid with all the values set to zero.
+func NewGrid(origin arena.Point, cellSize float64, cols, rows int) *Grid {
+	return &Grid{
+		Origin:   origin,
+		CellSize: cellSize,
+		Cols:     cols,
+		Rows:     rows,
+		Values:   make([]float64, cols*rows),
+	}
+}
+
+// At returns the value of a cell.
+func (g *Grid) At(col, row int) float64 {
+	return g.Values[row*g.Cols+col]
+}
+
+// Set sets the value of a cell.
+func (g *Grid) Set(col, row int, v float64) {
+	g.Values[row*g.Cols+col] = v
+}
+
+// Center returns the center of a cell.
+func (g *Grid) Center(col, row int) arena.Point {
+	return g.Origin.Add(arena.Point{
+		X: (float64(col) + 0.5) * g.CellSize,
+		Y: (float64(row) + 0.5) * g.CellSize,
+	})
+}
+
+// HeatmapConfig is the configuration of Canvas.Heatmap.
+type HeatmapConfig struct {
+	// MaxCircles is the maximum number of circles drawn. Since the
+	// canvas redraws the live shapes every tick, it limits the number of
+	// commands sent per tick. If zero, 16 is used.
+	MaxCircles int
+
+	// Scale is the magnitude drawn with a circle as big as the cell. If
+	// zero, the maximum magnitude of the grid is used.
+	Scale float64
+
+	// Threshold is the fraction of Scale under which cells are not
+	// drawn. If zero, 0.05 is used.
+	Threshold float64
+
+	// Lifetime is the lifetime of the circles.
+	Lifetime float64
+}
+
+// Heatmap draws a circle centered in each cell of g with a radius
+// proportional to the magnitude of its value. If there are more cells than
+// allowed by cfg.MaxCircles, only the cells with the highest magnitudes are
+// drawn.
+func (c *Canvas) Heatmap(g *Grid, cfg HeatmapConfig) {
+	if cfg.MaxCircles == 0 {
+		cfg.MaxCircles = 16
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = 0.05
+	}
+
+	scale := cfg.Scale
+	if scale == 0 {
+		for _, v := range g.Values {
+			scale = math.Max(scale, math.Abs(v))
+		}
+	}
+	if scale == 0 {
+		return
+	}
+
+	var cells []int
+	for i, v := range g.Values {
+		if math.Abs(v) > cfg.Threshold*scale {
+			cells = append(cells, i)
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool {
+		return math.Abs(g.Values[cells[i]]) > math.Abs(g.Values[cells[j]])
+	})
+	if len(cells) > cfg.MaxCircles {
+		cells = cells[:cfg.MaxCircles]
+	}
+
+	for _, i := range cells {
+		k := math.Min(math.Abs(g.Values[i])/scale, 1)
+		c.Circle(g.Center(i%g.Cols, i/g.Cols), k*g.CellSize/2, cfg.Lifetime)
+	}
+}
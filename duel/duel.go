@@ -0,0 +1,191 @@
+// Package duel implements a duelist, a strategy for one-on-one games that
+// combines a radar lock, a fire control driven by a virtual gun and an
+// oscillating lateral movement. It is intended as a strong baseline that can
+// be embedded or extended.
+package duel
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/fire"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/radar"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/vgun"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Duelist.
+type Config struct {
+	// Name is the name of the robot. If empty, "duelist" is used.
+	Name string
+
+	// HomeColour and AwayColour are the colours of the robot. If empty,
+	// "ff8000" and "0080ff" are used.
+	HomeColour, AwayColour string
+
+	// PreferredDistance is the distance to the enemy the robot tries to
+	// keep. If zero, 10 is used.
+	PreferredDistance float64
+
+	// Amplitude is the distance the robot moves to each side of the line
+	// to the enemy. If zero, 4 is used.
+	Amplitude float64
+
+	// Period is the time after which the robot changes the direction of
+	// the lateral movement. If zero, 1.5 is used.
+	Period float64
+
+	// Track, Energy, Gun, Nav, Radar and Fire are the configurations of
+	// the components of the duelist.
+	Track  track.Config
+	Energy energy.Config
+	Gun    vgun.Config
+	Nav    nav.Config
+	Radar  radar.Config
+	Fire   fire.Config
+}
+
+// Duelist is a strategy for one-on-one games. It implements the rtb.Strategy
+// interface. Duelist methods can be called concurrently.
+type Duelist struct {
+	cfg Config
+
+	w  *world.World
+	tr *track.Tracker
+	e  *energy.Manager
+	g  *vgun.Gun
+	n  *nav.Navigator
+	rl *radar.Lock
+	fc *fire.Controller
+
+	mu       sync.Mutex
+	side     float64
+	switched float64
+}
+
+// New returns a Duelist for r. It creates the components of the duelist and
+// adds them to r as observers, so they must not be added again.
+func New(r *rtb.Robot, cfg Config) *Duelist {
+	if cfg.Name == "" {
+		cfg.Name = "duelist"
+	}
+	if cfg.HomeColour == "" {
+		cfg.HomeColour = "ff8000"
+	}
+	if cfg.AwayColour == "" {
+		cfg.AwayColour = "0080ff"
+	}
+	if cfg.PreferredDistance == 0 {
+		cfg.PreferredDistance = 10
+	}
+	if cfg.Amplitude == 0 {
+		cfg.Amplitude = 4
+	}
+	if cfg.Period == 0 {
+		cfg.Period = 1.5
+	}
+
+	d := &Duelist{cfg: cfg, side: 1}
+	d.w = world.New()
+	d.tr = track.New(d.w, cfg.Track)
+	d.e = energy.New(cfg.Energy)
+	d.g = vgun.New(d.w, d.tr, cfg.Gun)
+	d.n = nav.New(r, d.w, cfg.Nav)
+	d.rl = radar.New(r, d.w, d.tr, cfg.Radar)
+	d.fc = fire.New(r, d.w, d.tr, d.e, cfg.Fire)
+	for _, o := range []rtb.Observer{d.w, d.tr, d.e, d.g, d.n, d.rl, d.fc} {
+		r.AddObserver(o)
+	}
+	return d
+}
+
+// World returns the world model of the duelist.
+func (d *Duelist) World() *world.World {
+	return d.w
+}
+
+// Tracker returns the tracker of the duelist.
+func (d *Duelist) Tracker() *track.Tracker {
+	return d.tr
+}
+
+// Energy returns the energy manager of the duelist.
+func (d *Duelist) Energy() *energy.Manager {
+	return d.e
+}
+
+// Gun returns the virtual gun of the duelist.
+func (d *Duelist) Gun() *vgun.Gun {
+	return d.g
+}
+
+// Navigator returns the navigator of the duelist.
+func (d *Duelist) Navigator() *nav.Navigator {
+	return d.n
+}
+
+// Radar returns the radar lock of the duelist.
+func (d *Duelist) Radar() *radar.Lock {
+	return d.rl
+}
+
+// Fire returns the fire control of the duelist.
+func (d *Duelist) Fire() *fire.Controller {
+	return d.fc
+}
+
+// Handle sends the name and colour of the robot when it is initialized and
+// moves it every time an Info message is received.
+func (d *Duelist) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if m.First {
+			r.Name(d.cfg.Name)
+			r.Colour(d.cfg.HomeColour, d.cfg.AwayColour)
+		}
+	case rtb.MessageGameStarts:
+		d.mu.Lock()
+		d.side, d.switched = 1, 0
+		d.mu.Unlock()
+	case rtb.MessageInfo:
+		d.move(m.Time)
+	}
+}
+
+// move drives the robot to a point at the preferred distance from the
+// enemy, displaced perpendicularly to the line to the enemy. The side of the
+// displacement changes every period, so the robot oscillates laterally.
+func (d *Duelist) move(now float64) {
+	s := d.w.State()
+	t, ok := d.tr.Nearest(s.Pos)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	if now-d.switched >= d.cfg.Period {
+		d.side, d.switched = -d.side, now
+	}
+	side := d.side
+	d.mu.Unlock()
+
+	enemy := t.PredictPosition(now - t.LastSeen)
+	off := s.Pos.Sub(enemy)
+	dist := off.Len()
+	if dist == 0 {
+		return
+	}
+	radial := off.Mul(1 / dist)
+	lateral := arena.Point{X: -radial.Y, Y: radial.X}
+
+	target := enemy.Add(radial.Mul(d.cfg.PreferredDistance)).Add(lateral.Mul(side * d.cfg.Amplitude))
+	if math.IsNaN(target.X) || math.IsNaN(target.Y) {
+		return
+	}
+	d.n.GoTo(target)
+}
@@ -0,0 +1,70 @@
+package duel
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+)
+
+func TestDuelistInitialize(t *testing.T) {
+	tests := []struct {
+		name  string
+		first bool
+		want  string
+	}{
+		{"First", true, "Name duelist\nColour ff8000 0080ff\n"},
+		{"Not first", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			d := New(r, Config{})
+			r.Deliver(d, rtb.MessageInitialize{First: tt.first})
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuelistMove(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []float64
+		want  arena.Point
+	}{
+		{"Start", []float64{0, 0.5}, arena.Point{X: 0, Y: -4}},
+		{"Switched", []float64{0, 1, 2}, arena.Point{X: 0, Y: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			d := New(r, Config{})
+
+			r.Deliver(d, rtb.MessageGameStarts{})
+			for _, time := range tt.times {
+				r.Deliver(d, rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0})
+				r.Deliver(d, rtb.MessageInfo{Time: time})
+			}
+
+			wps := d.Navigator().Waypoints()
+			if len(wps) != 1 {
+				t.Fatalf("unexpected number of waypoints: got=%v want=1", len(wps))
+			}
+			if got := wps[0]; math.Abs(got.X-tt.want.X) > 1e-9 || math.Abs(got.Y-tt.want.Y) > 1e-9 {
+				t.Errorf("unexpected waypoint: got=%v want=%v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "Sweep 4") {
+				t.Errorf("radar is not locked: %q", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package rtb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DumpState sends state to the message window as a series of Debug
+// messages in a compact key=value format, e.g.
+//
+//	pos.x=10.5 pos.y=3 speed=1.2 targets[0].dist=4.25 targets[0].robot=true
+//
+// Nested structs, pointers, slices, arrays and maps are flattened. Map keys
+// are sorted, so the dumps of consecutive turns can be compared. Values
+// implementing fmt.Stringer are rendered with String, floats with 4
+// significant digits and unexported fields are skipped. The pairs are packed
+// in as few messages as possible.
+func (r *Robot) DumpState(state any) error {
+	var pairs []string
+	flatten(&pairs, "", reflect.ValueOf(state))
+
+	r.mu.Lock()
+	split := DefaultTextSplit()
+	if r.textSplit != nil {
+		split = *r.textSplit
+	}
+	r.mu.Unlock()
+
+	// The keyword, the separators and the trailing newline must also fit
+	// in the message. Longer pairs are split by sendText.
+	max := 128 - len("Debug") - 2 - len(split.Prefix)
+
+	var line string
+	for _, p := range pairs {
+		if line != "" && len(line)+1+len(p) > max {
+			if err := r.sendText("Debug", line); err != nil {
+				return err
+			}
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += p
+	}
+	return r.sendText("Debug", line)
+}
+
+// DumpState calls DumpState on the default Robot.
+func DumpState(state any) error {
+	return std.DumpState(state)
+}
+
+// flatten appends the key=value pairs of v to pairs. key is the key of v.
+func flatten(pairs *[]string, key string, v reflect.Value) {
+	if !v.IsValid() {
+		*pairs = append(*pairs, pair(key, "nil"))
+		return
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok && v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+		*pairs = append(*pairs, pair(key, s.String()))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			*pairs = append(*pairs, pair(key, "nil"))
+			return
+		}
+		flatten(pairs, key, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			flatten(pairs, join(key, lowerFirst(f.Name)), v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			flatten(pairs, fmt.Sprintf("%v[%d]", key, i), v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(byName{names, keys})
+		for i, k := range keys {
+			flatten(pairs, fmt.Sprintf("%v[%v]", key, names[i]), v.MapIndex(k))
+		}
+	case reflect.Float32, reflect.Float64:
+		*pairs = append(*pairs, pair(key, strconv.FormatFloat(v.Float(), 'g', 4, 64)))
+	case reflect.String:
+		*pairs = append(*pairs, pair(key, strconv.Quote(v.String())))
+	default:
+		*pairs = append(*pairs, pair(key, fmt.Sprint(v.Interface())))
+	}
+}
+
+// byName sorts map keys by their names.
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (s byName) Len() int           { return len(s.names) }
+func (s byName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s byName) Swap(i, j int) {
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// join joins the key of a struct and the name of one of its fields.
+func join(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+// pair returns the pair key=value, or value if key is empty.
+func pair(key, value string) string {
+	if key == "" {
+		return value
+	}
+	return key + "=" + value
+}
@@ -0,0 +1,88 @@
+package rtb
+
+import (
+	"sort"
+	"sync"
+)
+
+// Endgame is a strategy that passes the messages to Strategy and calls
+// OnThreshold when the number of robots left crosses one of Thresholds, so
+// strategies can swap tactics for the endgame. A threshold is crossed when a
+// RobotsLeft message reports at most threshold robots for the first time in
+// a game, so it is also crossed when a game starts with few robots:
+//
+//	e := &rtb.Endgame{
+//		Strategy:   s,
+//		Thresholds: []int{3, 2},
+//		OnThreshold: func(r *rtb.Robot, threshold, left int) {
+//			...
+//		},
+//	}
+//	r.Run(settings, e)
+//
+// OnThreshold is called before passing the RobotsLeft message to Strategy.
+// If several thresholds are crossed by the same message, OnThreshold is
+// called for each of them, the greatest first. Endgame methods can be called
+// concurrently.
+type Endgame struct {
+	// Strategy receives all the messages. If nil, they are discarded.
+	Strategy Strategy
+
+	// Thresholds are the numbers of robots left that trigger
+	// OnThreshold.
+	Thresholds []int
+
+	// OnThreshold, if not nil, is called when a threshold is crossed,
+	// with the threshold and the number of robots left.
+	OnThreshold func(r *Robot, threshold, left int)
+
+	mu   sync.Mutex
+	left int
+}
+
+// Handle calls OnThreshold if msg crosses a threshold and passes msg to
+// e.Strategy.
+func (e *Endgame) Handle(r *Robot, msg Message) {
+	var crossed []int
+
+	e.mu.Lock()
+	switch m := msg.(type) {
+	case MessageGameStarts:
+		e.left = 0
+	case MessageRobotsLeft:
+		crossed = e.crossed(m.NumRobots)
+		e.left = m.NumRobots
+	}
+	e.mu.Unlock()
+
+	if e.OnThreshold != nil {
+		for _, th := range crossed {
+			e.OnThreshold(r, th, e.RobotsLeft())
+		}
+	}
+	if e.Strategy != nil {
+		e.Strategy.Handle(r, msg)
+	}
+}
+
+// crossed returns the thresholds crossed when the number of robots left
+// changes to left, the greatest first. e.mu must be held.
+func (e *Endgame) crossed(left int) []int {
+	var ths []int
+	for _, th := range e.Thresholds {
+		if left > 0 && left <= th && (e.left == 0 || e.left > th) {
+			ths = append(ths, th)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ths)))
+	return ths
+}
+
+// RobotsLeft returns the number of robots left reported by the last
+// RobotsLeft message of the current game, or zero if none has been received.
+func (e *Endgame) RobotsLeft() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.left
+}
@@ -0,0 +1,198 @@
+// Package energy implements an energy management policy. It tracks the
+// energy of the robot and the energy available for shooting, and answers the
+// questions that the fire control and navigation layers must ask before
+// spending energy.
+//
+// The energy available for shooting is a reservoir that is refilled at
+// ShotEnergyIncreaseSpeed energy/s, up to ShotMaxEnergy, and emptied by the
+// shots. The server does not report it, so it is estimated from the Shoot
+// commands sent by the robot.
+package energy
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Config is the configuration of a Manager.
+type Config struct {
+	// ShotReserve is the shot energy that is never spent, except by
+	// finishing shots. It allows to react quickly to an opportunity.
+	ShotReserve float64
+
+	// FinishThreshold is the energy level at or below which an enemy is
+	// considered finishable. Shots at a finishable enemy must carry
+	// enough energy to kill it, and the Manager saves shot energy until
+	// they do.
+	FinishThreshold float64
+
+	// CookieThreshold is the energy level of the robot below which
+	// cookies are preferred.
+	CookieThreshold float64
+}
+
+// Manager tracks the energy of the robot and applies the configured
+// policies. It implements the rtb.Observer interface. Manager methods can be
+// called concurrently.
+type Manager struct {
+	cfg Config
+
+	mu      sync.Mutex
+	options map[rtb.GOption]float64
+	energy  float64
+	shot    float64
+	time    float64
+}
+
+// New returns a Manager with the given configuration.
+func New(cfg Config) *Manager {
+	return &Manager{cfg: cfg, options: map[rtb.GOption]float64{}}
+}
+
+// Message updates the energy levels with a message received from the
+// server.
+func (m *Manager) Message(msg rtb.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch msg := msg.(type) {
+	case rtb.MessageGameOption:
+		m.options[msg.Option] = msg.Value
+	case rtb.MessageGameStarts:
+		m.energy = m.options[rtb.GOptionRobotStartEnergy]
+		m.shot = m.options[rtb.GOptionShotMaxEnergy]
+		m.time = 0
+	case rtb.MessageInfo:
+		if dt := msg.Time - m.time; dt > 0 {
+			m.shot = math.Min(m.shot+m.options[rtb.GOptionShotEnergyIncreaseSpeed]*dt, m.options[rtb.GOptionShotMaxEnergy])
+		}
+		m.time = msg.Time
+	case rtb.MessageEnergy:
+		m.energy = msg.EnergyLevel
+	}
+}
+
+// Command updates the shot energy with a Shoot command sent to the server.
+func (m *Manager) Command(cmd string) {
+	var e float64
+	if _, err := fmt.Sscanf(cmd, "Shoot %g", &e); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e = math.Min(e, m.options[rtb.GOptionShotMaxEnergy])
+	if e < m.options[rtb.GOptionShotMinEnergy] || e > m.shot {
+		// The server ignores the shot.
+		return
+	}
+	m.shot -= e
+}
+
+// ErrShotEnergy is returned by CheckShot when the energy available for
+// shooting is not enough for a shot, so the server would ignore it.
+type ErrShotEnergy struct {
+	// Energy is the energy of the shot, limited by the ShotMaxEnergy
+	// game option.
+	Energy float64
+
+	// Available is the estimated energy available for shooting.
+	Available float64
+}
+
+func (err ErrShotEnergy) Error() string {
+	return fmt.Sprintf("not enough shot energy: %v > %v", err.Energy, err.Available)
+}
+
+// CheckShot returns an ErrShotEnergy error if a shot with energy e would be
+// ignored by the server because the energy available for shooting is not
+// enough. Shots under the ShotMinEnergy game option are rejected by
+// rtb.Robot.Shoot.
+func (m *Manager) CheckShot(e float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if max, ok := m.options[rtb.GOptionShotMaxEnergy]; ok {
+		e = math.Min(e, max)
+	}
+	if e > m.shot {
+		return ErrShotEnergy{Energy: e, Available: m.shot}
+	}
+	return nil
+}
+
+// Energy returns the energy level of the robot.
+func (m *Manager) Energy() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.energy
+}
+
+// ShotEnergy returns the estimated energy available for shooting.
+func (m *Manager) ShotEnergy() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.shot
+}
+
+// TimeUntil returns the time needed until the energy available for shooting
+// reaches e. It returns +Inf if it never will.
+func (m *Manager) TimeUntil(e float64) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e <= m.shot {
+		return 0
+	}
+	speed := m.options[rtb.GOptionShotEnergyIncreaseSpeed]
+	if e > m.options[rtb.GOptionShotMaxEnergy] || speed <= 0 {
+		return math.Inf(1)
+	}
+	return (e - m.shot) / speed
+}
+
+// Shot returns the energy of a shot, given the desired energy and the energy
+// level of the target, or zero if the target is unknown. It returns false if
+// the robot should not shoot.
+//
+// The desired energy is limited by the ShotMinEnergy and ShotMaxEnergy game
+// options and reduced to keep the shot reserve. If the target is
+// finishable, the shot carries enough energy to kill it or the robot does
+// not shoot at all, saving energy for the finishing shot. Finishing shots
+// can spend the reserve.
+func (m *Manager) Shot(want, target float64) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	min, max := m.options[rtb.GOptionShotMinEnergy], m.options[rtb.GOptionShotMaxEnergy]
+
+	if target > 0 && target <= m.cfg.FinishThreshold {
+		need := math.Max(math.Max(want, target), min)
+		if need > max || need > m.shot {
+			return 0, false
+		}
+		return need, true
+	}
+
+	e := math.Min(math.Max(want, min), max)
+	e = math.Min(e, m.shot-m.cfg.ShotReserve)
+	if e < min {
+		return 0, false
+	}
+	return e, true
+}
+
+// PreferCookies returns true if the energy of the robot is below the cookie
+// threshold, so the navigation layer should go for the cookies it sees.
+func (m *Manager) PreferCookies() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.energy < m.cfg.CookieThreshold
+}
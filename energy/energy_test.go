@@ -0,0 +1,143 @@
+package energy
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+// newManager returns a Manager that has received the game options and the
+// start of a game.
+func newManager(cfg Config) *Manager {
+	m := New(cfg)
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameOption{Option: rtb.GOptionShotMinEnergy, Value: 0.5},
+		rtb.MessageGameOption{Option: rtb.GOptionShotMaxEnergy, Value: 30},
+		rtb.MessageGameOption{Option: rtb.GOptionShotEnergyIncreaseSpeed, Value: 10},
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 0},
+	}
+	for _, msg := range msgs {
+		m.Message(msg)
+	}
+	return m
+}
+
+func TestShotEnergy(t *testing.T) {
+	m := newManager(Config{})
+
+	if got := m.ShotEnergy(); got != 30 {
+		t.Errorf("unexpected initial shot energy: got=%v want=%v", got, 30)
+	}
+
+	m.Command("Shoot 20.000000")
+	m.Command("Shoot 0.100000")
+	m.Command("Shoot 50.000000")
+	if got := m.ShotEnergy(); got != 10 {
+		t.Errorf("unexpected shot energy after shooting: got=%v want=%v", got, 10)
+	}
+
+	if got := m.TimeUntil(25); math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("unexpected time until 25: got=%v want=%v", got, 1.5)
+	}
+	if got := m.TimeUntil(40); !math.IsInf(got, 1) {
+		t.Errorf("unexpected time until 40: got=%v want=%v", got, math.Inf(1))
+	}
+
+	m.Message(rtb.MessageInfo{Time: 1})
+	if got := m.ShotEnergy(); got != 20 {
+		t.Errorf("unexpected shot energy after regeneration: got=%v want=%v", got, 20)
+	}
+	m.Message(rtb.MessageInfo{Time: 3})
+	if got := m.ShotEnergy(); got != 30 {
+		t.Errorf("unexpected shot energy after full regeneration: got=%v want=%v", got, 30)
+	}
+}
+
+func TestShot(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		spent  float64
+		want   float64
+		target float64
+		wantE  float64
+		wantOk bool
+	}{
+		{"Unlimited", Config{}, 0, 10, 0, 10, true},
+		{"Max", Config{}, 0, 50, 0, 30, true},
+		{"Min", Config{}, 0, 0.1, 0, 0.5, true},
+		{"Reserve", Config{ShotReserve: 25}, 0, 10, 0, 5, true},
+		{"Reserve exhausted", Config{ShotReserve: 25}, 10, 10, 0, 0, false},
+		{"Finish", Config{ShotReserve: 25, FinishThreshold: 20}, 10, 5, 15, 15, true},
+		{"Save for finish", Config{FinishThreshold: 20}, 15, 5, 20, 0, false},
+		{"Not finishable", Config{FinishThreshold: 20}, 15, 5, 50, 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(tt.cfg)
+			if tt.spent > 0 {
+				m.Command("Shoot 30")
+				m.Message(rtb.MessageInfo{Time: (30 - tt.spent) / 10})
+			}
+
+			e, ok := m.Shot(tt.want, tt.target)
+			if e != tt.wantE || ok != tt.wantOk {
+				t.Errorf("unexpected shot: got=%v, %v want=%v, %v", e, ok, tt.wantE, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestPreferCookies(t *testing.T) {
+	m := newManager(Config{CookieThreshold: 40})
+
+	if m.PreferCookies() {
+		t.Errorf("cookies preferred with full energy")
+	}
+
+	m.Message(rtb.MessageEnergy{EnergyLevel: 30})
+	if !m.PreferCookies() {
+		t.Errorf("cookies not preferred with low energy")
+	}
+	if got := m.Energy(); got != 30 {
+		t.Errorf("unexpected energy: got=%v want=%v", got, 30)
+	}
+}
+
+func TestCheckShot(t *testing.T) {
+	m := newManager(Config{})
+	m.Command("Shoot 20.000000")
+
+	tests := []struct {
+		energy  float64
+		wantErr bool
+	}{
+		{5, false},
+		{10, false},
+		{15, true},
+		{50, true},
+	}
+
+	for _, tt := range tests {
+		err := m.CheckShot(tt.energy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for %v: got=%v want=%v", tt.energy, err, tt.wantErr)
+		}
+		if err == nil {
+			continue
+		}
+		var errEnergy ErrShotEnergy
+		if !errors.As(err, &errEnergy) {
+			t.Fatalf("unexpected error type: %T", err)
+		}
+		want := ErrShotEnergy{Energy: math.Min(tt.energy, 30), Available: 10}
+		if errEnergy != want {
+			t.Errorf("unexpected error: got=%+v want=%+v", errEnergy, want)
+		}
+	}
+}
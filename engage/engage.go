@@ -0,0 +1,205 @@
+// Package engage keeps the robot within a preferred distance band from its
+// target.
+//
+// Shots lose accuracy with the distance, so a robot with plenty of energy
+// wants to be close to its target, while a robot low on energy wants to stay
+// far, where the shots of the target are easier to dodge. A Manager computes
+// the band from the energy of the robot, interpolating between
+// Config.Close and Config.Far, and drives the navigator towards the band
+// when the robot is out of it:
+//
+//	n := nav.New(r, w, nav.Config{})
+//	m := engage.New(w, tr, n, engage.Config{Target: sel.Target})
+//	r.AddObserver(w)
+//	r.AddObserver(tr)
+//	r.AddObserver(n)
+//	r.AddObserver(m)
+package engage
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Band is a range of distances to the target.
+type Band struct {
+	Min, Max float64
+}
+
+// Contains returns true if d is within b.
+func (b Band) Contains(d float64) bool {
+	return d >= b.Min && d <= b.Max
+}
+
+// Mid returns the middle of b.
+func (b Band) Mid() float64 {
+	return (b.Min + b.Max) / 2
+}
+
+// Config is the configuration of a Manager.
+type Config struct {
+	// Close is the band used with high energy. If zero, [3, 6] is
+	// used.
+	Close Band
+
+	// Far is the band used with low energy. If zero, [12, 18] is used.
+	Far Band
+
+	// HighEnergy is the energy from which Close is used. If zero, 60 is
+	// used.
+	HighEnergy float64
+
+	// LowEnergy is the energy under which Far is used. Between
+	// LowEnergy and HighEnergy, the band is interpolated. If zero, 30 is
+	// used.
+	LowEnergy float64
+
+	// Interval is the minimum time between goals sent to the navigator.
+	// If zero, 0.5 is used.
+	Interval float64
+
+	// Target returns the target. If nil, the nearest enemy is used. It
+	// can be the Target method of a target.Selector or a radar.Lock.
+	Target func() (track.Track, bool)
+}
+
+// Manager feeds standoff and approach goals to a navigator to keep the robot
+// within the engagement band. It implements the rtb.Observer interface and
+// must be added to the robot after the world model, the tracker and the
+// navigator. Manager methods can be called concurrently.
+type Manager struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+	n   *nav.Navigator
+
+	mu       sync.Mutex
+	disabled bool
+	active   bool
+	sent     float64
+}
+
+// New returns a Manager that keeps the robot within the band from its
+// target, a track of tr, by sending goals to n.
+func New(w *world.World, tr *track.Tracker, n *nav.Navigator, cfg Config) *Manager {
+	if cfg.Close == (Band{}) {
+		cfg.Close = Band{Min: 3, Max: 6}
+	}
+	if cfg.Far == (Band{}) {
+		cfg.Far = Band{Min: 12, Max: 18}
+	}
+	if cfg.HighEnergy == 0 {
+		cfg.HighEnergy = 60
+	}
+	if cfg.LowEnergy == 0 {
+		cfg.LowEnergy = 30
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 0.5
+	}
+	return &Manager{cfg: cfg, w: w, tr: tr, n: n, sent: math.Inf(-1)}
+}
+
+// SetEnabled enables or disables the manager. A disabled manager does not
+// send goals, so the navigator can be used by other components. Managers
+// are enabled by default.
+func (m *Manager) SetEnabled(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.disabled = !enabled
+	m.active, m.sent = false, math.Inf(-1)
+}
+
+// Enabled returns true if the manager is enabled.
+func (m *Manager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return !m.disabled
+}
+
+// Band returns the band for the current energy of the robot.
+func (m *Manager) Band() Band {
+	return m.band(m.w.State().Energy)
+}
+
+// band returns the band for the given energy.
+func (m *Manager) band(energy float64) Band {
+	c, f := m.cfg.Close, m.cfg.Far
+	switch {
+	case energy >= m.cfg.HighEnergy:
+		return c
+	case energy <= m.cfg.LowEnergy:
+		return f
+	}
+	k := (energy - m.cfg.LowEnergy) / (m.cfg.HighEnergy - m.cfg.LowEnergy)
+	return Band{Min: f.Min + k*(c.Min-f.Min), Max: f.Max + k*(c.Max-f.Max)}
+}
+
+// Message updates the goal when msg is an Info message.
+func (m *Manager) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		m.mu.Lock()
+		m.active, m.sent = false, math.Inf(-1)
+		m.mu.Unlock()
+	case rtb.MessageInfo:
+		m.update()
+	}
+}
+
+// update sends a goal in the middle of the band, on the line from the target
+// through the robot, if the robot is out of the band. When the robot gets
+// into the band, the navigator is stopped.
+func (m *Manager) update() {
+	s := m.w.State()
+	if s.Dead {
+		return
+	}
+
+	var (
+		t  track.Track
+		ok bool
+	)
+	if m.cfg.Target != nil {
+		t, ok = m.cfg.Target()
+	} else {
+		t, ok = m.tr.Nearest(s.Pos)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.disabled || !ok {
+		return
+	}
+
+	b := m.band(s.Energy)
+	target := t.PredictPosition(s.Time - t.LastSeen)
+	dir := s.Pos.Sub(target)
+	dist := dir.Len()
+	if b.Contains(dist) {
+		if m.active {
+			m.n.Stop()
+			m.active = false
+		}
+		return
+	}
+	if s.Time-m.sent < m.cfg.Interval {
+		return
+	}
+	if dist == 0 {
+		dir = arena.Polar(s.Heading, 1)
+	}
+	m.n.GoTo(target.Add(dir.Mul(b.Mid() / dir.Len())))
+	m.active, m.sent = true, s.Time
+}
@@ -0,0 +1,78 @@
+package engage
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestBand(t *testing.T) {
+	tests := []struct {
+		energy float64
+		want   Band
+	}{
+		{100, Band{Min: 3, Max: 6}},
+		{60, Band{Min: 3, Max: 6}},
+		{45, Band{Min: 7.5, Max: 12}},
+		{30, Band{Min: 12, Max: 18}},
+		{5, Band{Min: 12, Max: 18}},
+	}
+
+	m := New(world.New(), nil, nil, Config{})
+	for _, tt := range tests {
+		if got := m.band(tt.energy); got != tt.want {
+			t.Errorf("unexpected band for energy %v: got=%v want=%v", tt.energy, got, tt.want)
+		}
+	}
+}
+
+func TestGoals(t *testing.T) {
+	steps := []struct {
+		energy float64
+		pos    arena.Point
+		want   []arena.Point
+	}{
+		// Too far with high energy: approach.
+		{100, arena.Point{X: 30}, []arena.Point{{X: 24.5}}},
+		// Within the band: stop.
+		{100, arena.Point{X: 24}, nil},
+		// Too close with low energy: stand off.
+		{20, arena.Point{X: 24}, []arena.Point{{X: 35}}},
+	}
+
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	n := nav.New(r, w, nav.Config{})
+	target := track.Track{ID: 1, Pos: arena.Point{X: 20}}
+	m := New(w, nil, n, Config{
+		Target: func() (track.Track, bool) { return target, true },
+	})
+	for _, obs := range []rtb.Observer{w, m} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	for i, s := range steps {
+		time := float64(i + 1)
+		target.LastSeen = time
+		r.Deliver(nop, rtb.MessageEnergy{EnergyLevel: s.energy})
+		r.Deliver(nop, rtb.MessageCoordinates{X: s.pos.X, Y: s.pos.Y})
+		r.Deliver(nop, rtb.MessageInfo{Time: time})
+
+		got := n.Waypoints()
+		if len(got) != len(s.want) {
+			t.Fatalf("step %v: unexpected waypoints: got=%v want=%v", i, got, s.want)
+		}
+		for j := range got {
+			if got[j].Sub(s.want[j]).Len() > 1e-9 {
+				t.Errorf("step %v: unexpected waypoint: got=%v want=%v", i, got[j], s.want[j])
+			}
+		}
+	}
+}
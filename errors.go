@@ -0,0 +1,92 @@
+package rtb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Protocol errors. They are usually wrapped with details, so they must be
+// checked with errors.Is.
+var (
+	// ErrMessageTooLong is returned when a message or a command exceeds
+	// the maximum length.
+	ErrMessageTooLong = errors.New("message is too long")
+
+	// ErrUnknownMessage is returned when the type of a message is
+	// unknown.
+	ErrUnknownMessage = errors.New("unknown message")
+
+	// ErrUnknownCommand is returned when the type of a command is
+	// unknown.
+	ErrUnknownCommand = errors.New("unknown command")
+
+	// ErrUnsupportedCommand is returned when a command is not supported
+	// by the dialect of the server.
+	ErrUnsupportedCommand = errors.New("unsupported command")
+
+	// ErrInvalidCommand is returned by SendRaw when the keyword or the
+	// arguments of a command are not valid.
+	ErrInvalidCommand = errors.New("invalid command")
+
+	// ErrBadFieldCount is returned when a message has the wrong number
+	// of fields.
+	ErrBadFieldCount = errors.New("wrong number of fields")
+
+	// ErrLineTooLong is emitted on the channel returned by Errors when
+	// Listen discards a line longer than ListenSettings.MaxLineLength.
+	ErrLineTooLong = errors.New("line is too long")
+)
+
+// ErrBadField is returned when a field of a message cannot be parsed.
+type ErrBadField struct {
+	// Index is the index of the field. The message type is the field 0.
+	Index int
+
+	// Value is the value of the field.
+	Value string
+
+	// Err is the parsing error.
+	Err error
+}
+
+func (err ErrBadField) Error() string {
+	return fmt.Sprintf("bad field %v %q: %v", err.Index, err.Value, err.Err)
+}
+
+// Unwrap returns the parsing error.
+func (err ErrBadField) Unwrap() error {
+	return err.Err
+}
+
+// ErrWarning is a warning sent by the server.
+type ErrWarning struct {
+	// Warning is the type of warning.
+	Warning Warning
+
+	// Message is the message related to the warning.
+	Message string
+}
+
+func (err ErrWarning) Error() string {
+	return fmt.Sprintf("server warning %v: %v", err.Warning, err.Message)
+}
+
+// ErrRotationSuperseded is returned when waiting for a rotation that was
+// superseded by another rotation of the same part.
+var ErrRotationSuperseded = errors.New("rotation superseded")
+
+// ErrHandshake is emitted on the channel returned by Errors by Handshake
+// when the server reports that the name or the colour of the robot were not
+// given.
+type ErrHandshake struct {
+	// Warning is WarningNameNotGiven or WarningColourNotGiven.
+	Warning Warning
+
+	// First is the value of MessageInitialize.First at the beginning of
+	// the sequence.
+	First bool
+}
+
+func (err ErrHandshake) Error() string {
+	return fmt.Sprintf("handshake failed: %v (first: %v)", err.Warning, err.First)
+}
@@ -0,0 +1,327 @@
+// Package evolve optimizes the parameters of a strategy with a genetic
+// algorithm. The fitness of a set of parameters is its win rate against
+// reference opponents in the simulator.
+//
+// The parameters are a user-defined struct. Every numeric field with an
+// evolve tag is a gene, which takes values in the range given by the tag:
+//
+//	type Params struct {
+//		Aggression float64 `evolve:"0,1"`
+//		MinEnergy  int     `evolve:"10,50"`
+//		Name       string
+//	}
+//
+// Fields without tag are copied from the initial parameters and never
+// change.
+package evolve
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/selfplay"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Config is the configuration of an evolution.
+type Config[T any] struct {
+	// Initial are the initial parameters. They are part of the first
+	// generation, which is completed with random individuals.
+	Initial T
+
+	// Candidate returns the contender that uses the given parameters.
+	// It is called concurrently.
+	Candidate func(p T) selfplay.Contender
+
+	// Opponents returns the reference opponents. It is called once per
+	// evaluation, concurrently.
+	Opponents func() []selfplay.Contender
+
+	// Population is the number of individuals of every generation. If
+	// zero, 20 is used.
+	Population int
+
+	// Generations is the number of generations. If zero, 10 is used.
+	Generations int
+
+	// Elite is the number of best individuals copied without changes to
+	// the next generation. If zero, 2 is used.
+	Elite int
+
+	// Games is the number of games played by every individual in every
+	// generation. Every generation plays different games, so the
+	// parameters do not overfit a few placements of the robots.
+	Games int
+
+	// MutationRate is the probability of mutating a gene. If zero, 0.1
+	// is used.
+	MutationRate float64
+
+	// MutationScale is the standard deviation of a mutation, as a
+	// fraction of the range of the gene. If zero, 0.1 is used.
+	MutationScale float64
+
+	// Seed is the seed of the random number generator and of the games.
+	Seed int64
+
+	// Workers is the number of individuals evaluated concurrently. If
+	// zero, runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	// Arena and Options are passed to the self-play runs.
+	Arena   *arena.Arena
+	Options sim.Options
+
+	// Progress, if not nil, is called after evaluating every generation
+	// with its best individual.
+	Progress func(gen int, best Individual[T])
+}
+
+// Individual is a set of parameters and its fitness.
+type Individual[T any] struct {
+	// Params are the parameters.
+	Params T
+
+	// Fitness is the win rate of the parameters in the generation in
+	// which they were evaluated.
+	Fitness float64
+}
+
+// Run runs the evolution described by cfg and returns the last generation,
+// sorted from best to worst.
+func Run[T any](cfg Config[T]) ([]Individual[T], error) {
+	if cfg.Candidate == nil || cfg.Opponents == nil {
+		return nil, errors.New("missing candidate or opponents")
+	}
+	if cfg.Games <= 0 {
+		return nil, errors.New("invalid number of games")
+	}
+	if cfg.Population == 0 {
+		cfg.Population = 20
+	}
+	if cfg.Generations == 0 {
+		cfg.Generations = 10
+	}
+	if cfg.Elite == 0 {
+		cfg.Elite = 2
+	}
+	if cfg.Elite > cfg.Population {
+		return nil, errors.New("elite larger than population")
+	}
+	if cfg.MutationRate == 0 {
+		cfg.MutationRate = 0.1
+	}
+	if cfg.MutationScale == 0 {
+		cfg.MutationScale = 0.1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	genes, err := parseGenes(reflect.TypeOf(cfg.Initial))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &evolution[T]{cfg: cfg, genes: genes, rnd: rand.New(rand.NewSource(cfg.Seed))}
+	pop := []Individual[T]{{Params: cfg.Initial}}
+	for len(pop) < cfg.Population {
+		pop = append(pop, Individual[T]{Params: e.random()})
+	}
+
+	for gen := 0; ; gen++ {
+		if err := e.evaluate(pop, gen); err != nil {
+			return nil, err
+		}
+		sort.SliceStable(pop, func(i, j int) bool {
+			return pop[i].Fitness > pop[j].Fitness
+		})
+		if cfg.Progress != nil {
+			cfg.Progress(gen, pop[0])
+		}
+		if gen == cfg.Generations-1 {
+			return pop, nil
+		}
+		pop = e.next(pop)
+	}
+}
+
+// gene is a field of the parameters that evolves.
+type gene struct {
+	index    int
+	min, max float64
+	integer  bool
+}
+
+// parseGenes returns the genes of the struct type t.
+func parseGenes(t reflect.Type) ([]gene, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parameters must be a struct, got %v", t)
+	}
+
+	var genes []gene
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("evolve")
+		if !ok {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("field %v is not exported", f.Name)
+		}
+
+		g := gene{index: i}
+		switch f.Type.Kind() {
+		case reflect.Float32, reflect.Float64:
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			g.integer = true
+		default:
+			return nil, fmt.Errorf("field %v is not numeric", f.Name)
+		}
+
+		min, max, ok := strings.Cut(tag, ",")
+		var errMin, errMax error
+		g.min, errMin = strconv.ParseFloat(strings.TrimSpace(min), 64)
+		g.max, errMax = strconv.ParseFloat(strings.TrimSpace(max), 64)
+		if !ok || errMin != nil || errMax != nil || g.min > g.max {
+			return nil, fmt.Errorf("invalid range %q of field %v", tag, f.Name)
+		}
+		genes = append(genes, g)
+	}
+	if len(genes) == 0 {
+		return nil, errors.New("parameters without genes")
+	}
+	return genes, nil
+}
+
+// evolution is the state of an evolution.
+type evolution[T any] struct {
+	cfg   Config[T]
+	genes []gene
+	rnd   *rand.Rand
+}
+
+// get returns the value of the gene g of p.
+func (e *evolution[T]) get(p *T, g gene) float64 {
+	v := reflect.ValueOf(p).Elem().Field(g.index)
+	if g.integer {
+		return float64(v.Int())
+	}
+	return v.Float()
+}
+
+// set sets the value of the gene g of p to x, clamped to the range of the
+// gene.
+func (e *evolution[T]) set(p *T, g gene, x float64) {
+	x = math.Max(g.min, math.Min(g.max, x))
+	v := reflect.ValueOf(p).Elem().Field(g.index)
+	if g.integer {
+		v.SetInt(int64(math.Round(x)))
+	} else {
+		v.SetFloat(x)
+	}
+}
+
+// random returns the initial parameters with random genes.
+func (e *evolution[T]) random() T {
+	p := e.cfg.Initial
+	for _, g := range e.genes {
+		e.set(&p, g, g.min+e.rnd.Float64()*(g.max-g.min))
+	}
+	return p
+}
+
+// evaluate computes the fitness of the individuals of the generation gen.
+func (e *evolution[T]) evaluate(pop []Individual[T], gen int) error {
+	errs := make([]error, len(pop))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < e.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pop[j].Fitness, errs[j] = e.fitness(pop[j].Params, gen)
+			}
+		}()
+	}
+	for i := range pop {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("could not evaluate %+v: %v", pop[i].Params, err)
+		}
+	}
+	return nil
+}
+
+// fitness returns the win rate of p in the games of the generation gen.
+func (e *evolution[T]) fitness(p T, gen int) (float64, error) {
+	contenders := append([]selfplay.Contender{e.cfg.Candidate(p)}, e.cfg.Opponents()...)
+	rep, err := selfplay.Run(selfplay.Config{
+		Contenders: contenders,
+		Games:      e.cfg.Games,
+		Seed:       e.cfg.Seed + int64(gen*e.cfg.Games),
+		Arena:      e.cfg.Arena,
+		Options:    e.cfg.Options,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rep.Contenders[0].WinRate, nil
+}
+
+// next returns the next generation of pop, which must be sorted from best to
+// worst.
+func (e *evolution[T]) next(pop []Individual[T]) []Individual[T] {
+	next := make([]Individual[T], 0, len(pop))
+	for _, ind := range pop[:e.cfg.Elite] {
+		next = append(next, Individual[T]{Params: ind.Params})
+	}
+	for len(next) < len(pop) {
+		a, b := e.selectParent(pop), e.selectParent(pop)
+		next = append(next, Individual[T]{Params: e.crossover(a, b)})
+	}
+	return next
+}
+
+// selectParent selects an individual of pop with a tournament of three.
+func (e *evolution[T]) selectParent(pop []Individual[T]) T {
+	best := pop[e.rnd.Intn(len(pop))]
+	for i := 0; i < 2; i++ {
+		if ind := pop[e.rnd.Intn(len(pop))]; ind.Fitness > best.Fitness {
+			best = ind
+		}
+	}
+	return best.Params
+}
+
+// crossover returns a child of a and b. Every gene is inherited from one of
+// the parents at random and then mutated with probability MutationRate.
+func (e *evolution[T]) crossover(a, b T) T {
+	child := a
+	for _, g := range e.genes {
+		x := e.get(&a, g)
+		if e.rnd.Intn(2) == 1 {
+			x = e.get(&b, g)
+		}
+		if e.rnd.Float64() < e.cfg.MutationRate {
+			x += e.rnd.NormFloat64() * e.cfg.MutationScale * (g.max - g.min)
+		}
+		e.set(&child, g, x)
+	}
+	return child
+}
@@ -0,0 +1,137 @@
+package evolve
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/internal/rtbtest"
+	"github.com/jroimartin/rtb/selfplay"
+)
+
+type params struct {
+	Energy float64 `evolve:"0,5"`
+	Speed  int     `evolve:"1, 3"`
+	Label  string
+}
+
+// turret returns a contender that rotates until the radar detects a robot and
+// then shoots it, if the energy of the shots is big enough to kill it before
+// the game ends.
+func turret(p params) selfplay.Contender {
+	energy := p.Energy
+	if energy <= 1 {
+		energy = 0
+	}
+	return selfplay.Contender{
+		Name: "turret",
+		New: func() rtb.Strategy {
+			return rtbtest.Turret{Speed: float64(p.Speed) / 2, Energy: energy}
+		},
+	}
+}
+
+// ducks returns a contender that does nothing.
+func ducks() []selfplay.Contender {
+	return []selfplay.Contender{{
+		Name: "duck",
+		New: func() rtb.Strategy {
+			return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+		},
+	}}
+}
+
+func TestRun(t *testing.T) {
+	var gens []int
+	cfg := Config[params]{
+		Initial:     params{Energy: 0, Speed: 2, Label: "turret"},
+		Candidate:   turret,
+		Opponents:   ducks,
+		Population:  6,
+		Generations: 3,
+		Games:       1,
+		Seed:        1,
+		Progress: func(gen int, best Individual[params]) {
+			gens = append(gens, gen)
+		},
+	}
+	pop, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pop) != 6 {
+		t.Fatalf("wrong population: got=%v want=6", len(pop))
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(gens, want) {
+		t.Errorf("wrong generations: got=%v want=%v", gens, want)
+	}
+	best := pop[0]
+	if best.Fitness != 1 || best.Params.Energy <= 1 {
+		t.Errorf("unexpected best individual: %+v", best)
+	}
+	for i, ind := range pop {
+		p := ind.Params
+		if p.Energy < 0 || p.Energy > 5 || p.Speed < 1 || p.Speed > 3 {
+			t.Errorf("gene out of range: %+v", p)
+		}
+		if p.Label != "turret" {
+			t.Errorf("wrong label: got=%q want=%q", p.Label, "turret")
+		}
+		if i > 0 && ind.Fitness > pop[i-1].Fitness {
+			t.Errorf("population not sorted: %+v", pop)
+		}
+	}
+}
+
+func TestParseGenes(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []gene
+		err  bool
+	}{
+		{
+			name: "valid",
+			v:    params{},
+			want: []gene{{index: 0, min: 0, max: 5}, {index: 1, min: 1, max: 3, integer: true}},
+		},
+		{name: "not struct", v: 1, err: true},
+		{name: "no genes", v: struct{ A float64 }{}, err: true},
+		{name: "not numeric", v: struct {
+			A string `evolve:"0,1"`
+		}{}, err: true},
+		{name: "bad range", v: struct {
+			A float64 `evolve:"1,0"`
+		}{}, err: true},
+		{name: "missing max", v: struct {
+			A float64 `evolve:"1"`
+		}{}, err: true},
+		{name: "unexported", v: struct {
+			a float64 `evolve:"0,1"`
+		}{}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGenes(reflect.TypeOf(tt.v))
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrong genes: got=%+v want=%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cfg := Config[params]{Candidate: turret, Opponents: ducks, Games: 1, Population: 1, Elite: 2}
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("expected error with elite larger than population")
+	}
+
+	cfg = Config[params]{Candidate: turret, Opponents: ducks}
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("expected error without games")
+	}
+}
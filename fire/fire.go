@@ -0,0 +1,293 @@
+// Package fire implements a fire control that aims the cannon at the
+// predicted intercept point of the nearest enemy and shoots when it is
+// aligned.
+//
+// The energy of the shots is taken from a reservoir that the server refills
+// at ShotEnergyIncreaseSpeed energy/s, so the rate of fire is limited by the
+// energy of the shots. The pacing mode of the controller selects how the
+// reservoir is spent: a stream of weak shots, single charged shots or bursts
+// of shots. In every mode, the shots are only sent when the energy manager
+// estimates that the reservoir holds enough energy, so the server does not
+// ignore them.
+package fire
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/trace"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Controller.
+type Config struct {
+	// Energy is the desired energy of the shots. If zero, the
+	// ShotMaxEnergy game option is used.
+	Energy float64
+
+	// Range is the maximum distance to the target. If zero, 30 is used.
+	Range float64
+
+	// TargetSize is the size of the target used to compute the aiming
+	// tolerance. The farther the target, the more precise the cannon must
+	// be. If zero, 0.5 is used.
+	TargetSize float64
+
+	// MaxAge is the maximum time since the target was seen. If zero, 1
+	// is used.
+	MaxAge float64
+
+	// Select, if not nil, selects the target among the tracks, given the
+	// position of the robot. If nil, the nearest enemy is selected.
+	Select func(pos arena.Point, tracks []track.Track) (track.Track, bool)
+
+	// Pacing is the pacing mode of the shots. If zero, PacingGreedy is
+	// used.
+	Pacing Pacing
+
+	// BurstShots is the number of shots of a burst in PacingBurst mode.
+	// If zero, 3 is used.
+	BurstShots int
+
+	// Trace, if not nil, records the decisions of the controller.
+	Trace *trace.Tracer
+}
+
+// Pacing is a pacing mode of the shots.
+type Pacing int
+
+// Pacing modes.
+const (
+	// PacingGreedy shoots as soon as the cannon is aligned, with the
+	// desired energy or the energy available if it is less.
+	PacingGreedy Pacing = iota
+
+	// PacingContinuous shoots a stream of shots with the ShotMinEnergy
+	// game option, the maximum rate of fire.
+	PacingContinuous
+
+	// PacingCharged waits until the desired energy is available and
+	// shoots a single shot with it.
+	PacingCharged
+
+	// PacingBurst waits until the energy of Config.BurstShots shots with
+	// the desired energy is available and shoots them in consecutive
+	// ticks.
+	PacingBurst
+)
+
+// Controller aims and shoots at the selected enemy, using the motion model of
+// its track. Combined with a virtual gun, which selects the motion model of
+// every track, it uses the model that hits more often. Controller implements
+// the rtb.Observer interface and must be added to the robot after the world
+// model, the tracker and the energy manager. Controller methods can be
+// called concurrently.
+type Controller struct {
+	rtb.NopCommand
+
+	cfg Config
+	r   *rtb.Robot
+	w   *world.World
+	tr  *track.Tracker
+	e   *energy.Manager
+
+	mu     sync.Mutex
+	aim    float64
+	shots  int
+	energy float64
+	pacing Pacing
+	burst  int
+}
+
+// New returns a Controller that sends commands through r.
+func New(r *rtb.Robot, w *world.World, tr *track.Tracker, e *energy.Manager, cfg Config) *Controller {
+	if cfg.Range == 0 {
+		cfg.Range = 30
+	}
+	if cfg.TargetSize == 0 {
+		cfg.TargetSize = 0.5
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 1
+	}
+	if cfg.BurstShots == 0 {
+		cfg.BurstShots = 3
+	}
+	return &Controller{cfg: cfg, r: r, w: w, tr: tr, e: e, aim: math.NaN(), energy: cfg.Energy, pacing: cfg.Pacing}
+}
+
+// SetEnergy sets the desired energy of the shots. If zero, the
+// ShotMaxEnergy game option is used.
+func (c *Controller) SetEnergy(e float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.energy = e
+}
+
+// SetPacing sets the pacing mode of the shots. A burst in progress is
+// abandoned.
+func (c *Controller) SetPacing(p Pacing) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pacing, c.burst = p, 0
+}
+
+// AvailableShotEnergy returns the energy available for shooting, as
+// estimated by the energy manager.
+func (c *Controller) AvailableShotEnergy() float64 {
+	return c.e.ShotEnergy()
+}
+
+// Shoot shoots with energy e, limited by the ShotMaxEnergy game option. If
+// the energy available for shooting is not enough, the shot is not sent and
+// an energy.ErrShotEnergy error is returned. The shots sent are counted by
+// Shots.
+func (c *Controller) Shoot(e float64) error {
+	if max, ok := c.w.Option(rtb.GOptionShotMaxEnergy); ok {
+		e = math.Min(e, max)
+	}
+	if err := c.e.CheckShot(e); err != nil {
+		return err
+	}
+	if err := c.r.Shoot(e); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.shots++
+	c.mu.Unlock()
+	return nil
+}
+
+// Shots returns the number of shots fired in the current game.
+func (c *Controller) Shots() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.shots
+}
+
+// Message aims and shoots when msg is an Info message.
+func (c *Controller) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		c.mu.Lock()
+		c.aim, c.shots, c.burst = math.NaN(), 0, 0
+		c.mu.Unlock()
+	case rtb.MessageInfo:
+		c.engage(m.CannonAngle)
+	}
+}
+
+// engage rotates the cannon towards the intercept point of the selected
+// enemy and shoots when it is aligned.
+func (c *Controller) engage(cannon float64) {
+	s := c.w.State()
+	if s.Dead {
+		return
+	}
+
+	var (
+		t  track.Track
+		ok bool
+	)
+	if c.cfg.Select != nil {
+		t, ok = c.cfg.Select(s.Pos, c.tr.Tracks())
+	} else {
+		t, ok = c.tr.Nearest(s.Pos)
+	}
+	if !ok || s.Time-t.LastSeen > c.cfg.MaxAge {
+		return
+	}
+	speed, ok := c.w.Option(rtb.GOptionShotSpeed)
+	if !ok {
+		return
+	}
+	p, _, ok := t.Intercept(s.Pos, s.Time, speed)
+	if !ok {
+		return
+	}
+	angle, dist := c.w.Relative(p)
+	if dist > c.cfg.Range {
+		c.cfg.Trace.Recordf("fire", "hold fire: target %v out of range (%.1f)", t.ID, dist)
+		return
+	}
+
+	tolerance := math.Atan2(c.cfg.TargetSize, dist)
+	aligned := math.Abs(physics.NormalizeAngle(cannon-angle)) <= tolerance
+
+	c.mu.Lock()
+	rotate := math.IsNaN(c.aim) || math.Abs(physics.NormalizeAngle(angle-c.aim)) > tolerance/2
+	if rotate {
+		c.aim = angle
+	}
+	c.mu.Unlock()
+
+	if rotate {
+		rot, _ := c.w.Option(rtb.GOptionRobotCannonMaxRotate)
+		c.r.RotateTo(rtb.PartCannon, rot, angle)
+	}
+	if !aligned {
+		return
+	}
+
+	e, ok := c.pace(t.Energy)
+	if !ok {
+		c.cfg.Trace.Recordf("fire", "hold fire: saving shot energy (%.1f available)", c.AvailableShotEnergy())
+		return
+	}
+	if err := c.Shoot(e); err != nil {
+		c.cfg.Trace.Recordf("fire", "hold fire: %v", err)
+		return
+	}
+	c.cfg.Trace.Recordf("fire", "shoot %.1f at target %v (%.1f)", e, t.ID, dist)
+}
+
+// pace returns the energy of the next shot at a target with the given
+// energy level, according to the pacing mode. It returns false if the robot
+// should not shoot yet.
+func (c *Controller) pace(target float64) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	want := c.energy
+	if want == 0 {
+		want, _ = c.w.Option(rtb.GOptionShotMaxEnergy)
+	}
+	min, _ := c.w.Option(rtb.GOptionShotMinEnergy)
+	if max, ok := c.w.Option(rtb.GOptionShotMaxEnergy); ok {
+		want = math.Min(want, max)
+	}
+	want = math.Max(want, min)
+
+	switch c.pacing {
+	case PacingContinuous:
+		return c.e.Shot(min, target)
+	case PacingCharged:
+		e, ok := c.e.Shot(want, target)
+		return e, ok && e >= want
+	case PacingBurst:
+		if c.burst == 0 {
+			if c.e.TimeUntil(want*float64(c.cfg.BurstShots)) > 0 {
+				return 0, false
+			}
+			c.burst = c.cfg.BurstShots
+		}
+		e, ok := c.e.Shot(want, target)
+		if !ok || e < want {
+			// The reservoir cannot sustain the burst.
+			c.burst = 0
+			return 0, false
+		}
+		c.burst--
+		return e, true
+	}
+	return c.e.Shot(want, target)
+}
@@ -0,0 +1,208 @@
+package fire
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestController(t *testing.T) {
+	tests := []struct {
+		name      string
+		distance  float64
+		cannon    float64
+		want      []string
+		wantShots int
+	}{
+		{
+			"Aligned",
+			10,
+			0,
+			[]string{"RotateTo 2 1.000000 0.000000", "Shoot 5.000000"},
+			1,
+		},
+		{
+			"Not aligned",
+			10,
+			1,
+			[]string{"RotateTo 2 1.000000 0.000000"},
+			0,
+		},
+		{
+			"Out of range",
+			40,
+			0,
+			nil,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			w := world.New()
+			tr := track.New(w, track.Config{})
+			e := energy.New(energy.Config{})
+			c := New(r, w, tr, e, Config{Energy: 5})
+			for _, obs := range []rtb.Observer{w, tr, e, c} {
+				r.AddObserver(obs)
+			}
+
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			opts := []rtb.MessageGameOption{
+				{Option: rtb.GOptionShotSpeed, Value: 2},
+				{Option: rtb.GOptionRobotCannonMaxRotate, Value: 1},
+				{Option: rtb.GOptionShotMinEnergy, Value: 0.5},
+				{Option: rtb.GOptionShotMaxEnergy, Value: 10},
+			}
+			for _, opt := range opts {
+				r.Deliver(nop, opt)
+			}
+			r.Deliver(nop, rtb.MessageGameStarts{})
+			r.Deliver(nop, rtb.MessageRadar{Distance: tt.distance, Object: rtb.ObjectRobot, RadarAngle: 0})
+			out.Reset()
+			r.Deliver(nop, rtb.MessageInfo{Time: 0, CannonAngle: tt.cannon})
+
+			var got []string
+			if s := strings.TrimSpace(out.String()); s != "" {
+				got = strings.Split(s, "\n")
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+			if got := c.Shots(); got != tt.wantShots {
+				t.Errorf("unexpected number of shots: got=%v want=%v", got, tt.wantShots)
+			}
+		})
+	}
+}
+
+func TestPacing(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{
+			"Greedy",
+			Config{Energy: 8},
+			[]string{"Shoot 8.000000", "Shoot 3.000000", "Shoot 1.000000", "Shoot 1.000000"},
+		},
+		{
+			"Continuous",
+			Config{Energy: 8, Pacing: PacingContinuous},
+			[]string{"Shoot 0.500000", "Shoot 0.500000", "Shoot 0.500000", "Shoot 0.500000"},
+		},
+		{
+			"Charged",
+			Config{Energy: 8, Pacing: PacingCharged},
+			[]string{"Shoot 8.000000"},
+		},
+		{
+			"Burst",
+			Config{Energy: 3, Pacing: PacingBurst},
+			[]string{"Shoot 3.000000", "Shoot 3.000000", "Shoot 3.000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			w := world.New()
+			tr := track.New(w, track.Config{})
+			e := energy.New(energy.Config{})
+			c := New(r, w, tr, e, tt.cfg)
+			for _, obs := range []rtb.Observer{w, tr, e, c} {
+				r.AddObserver(obs)
+			}
+
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			opts := []rtb.MessageGameOption{
+				{Option: rtb.GOptionShotSpeed, Value: 2},
+				{Option: rtb.GOptionRobotCannonMaxRotate, Value: 1},
+				{Option: rtb.GOptionShotMinEnergy, Value: 0.5},
+				{Option: rtb.GOptionShotMaxEnergy, Value: 10},
+				{Option: rtb.GOptionShotEnergyIncreaseSpeed, Value: 1},
+			}
+			for _, opt := range opts {
+				r.Deliver(nop, opt)
+			}
+			r.Deliver(nop, rtb.MessageGameStarts{})
+			for time := 0.0; time < 4; time++ {
+				r.Deliver(nop, rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0})
+				r.Deliver(nop, rtb.MessageInfo{Time: time, CannonAngle: 0})
+			}
+
+			var got []string
+			for _, cmd := range strings.Split(out.String(), "\n") {
+				if strings.HasPrefix(cmd, "Shoot ") {
+					got = append(got, cmd)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unexpected shots: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShoot(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	e := energy.New(energy.Config{})
+	c := New(r, w, tr, e, Config{})
+	for _, obs := range []rtb.Observer{w, tr, e, c} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	opts := []rtb.MessageGameOption{
+		{Option: rtb.GOptionShotMinEnergy, Value: 0.5},
+		{Option: rtb.GOptionShotMaxEnergy, Value: 10},
+		{Option: rtb.GOptionShotEnergyIncreaseSpeed, Value: 1},
+	}
+	for _, opt := range opts {
+		r.Deliver(nop, opt)
+	}
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	r.Deliver(nop, rtb.MessageInfo{Time: 0})
+
+	if err := c.Shoot(20); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.AvailableShotEnergy(); got != 0 {
+		t.Errorf("unexpected available energy: got=%v want=%v", got, 0)
+	}
+
+	r.Deliver(nop, rtb.MessageInfo{Time: 2})
+	if got := c.AvailableShotEnergy(); got != 2 {
+		t.Errorf("unexpected available energy: got=%v want=%v", got, 2)
+	}
+	err := c.Shoot(3)
+	var errEnergy energy.ErrShotEnergy
+	if !errors.As(err, &errEnergy) {
+		t.Fatalf("unexpected error: got=%v want=%T", err, errEnergy)
+	}
+	if err := c.Shoot(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"Shoot 10.000000", "Shoot 2.000000"}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+	if got := c.Shots(); got != 2 {
+		t.Errorf("unexpected number of shots: got=%v want=%v", got, 2)
+	}
+}
@@ -0,0 +1,216 @@
+// Package flags implements feature flags that can be changed while the robot
+// runs, e.g. to enable debug overlays, switch strategy modes or change the
+// verbosity of the logs during a tournament, without restarting the robot.
+//
+// Flags are changed with settings, which are lists of name=value pairs
+// separated by spaces or commas, like "overlay=1,mode=defensive". A name
+// without a value sets the flag to "true".
+//
+// Settings are read from two places:
+//
+//   - The messages YourName and YourColour, when used as an observer. Only
+//     the part after a '#' is read, so a robot named "bot#overlay=1" in the
+//     tournament gets the overlay enabled.
+//   - A control FIFO, with Watch. Each line written to the FIFO is a
+//     setting.
+//
+// For instance, if the robot watches /tmp/bot.ctl:
+//
+//	mkfifo /tmp/bot.ctl
+//	echo 'verbose=false' > /tmp/bot.ctl
+package flags
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Callback is called when the value of a flag changes.
+type Callback func(name, value string)
+
+// Flags is a set of feature flags. Flags methods can be called concurrently.
+type Flags struct {
+	rtb.NopCommand
+
+	mu        sync.Mutex
+	values    map[string]string
+	callbacks map[string][]Callback
+	all       []Callback
+	onError   func(setting string, err error)
+}
+
+// New returns a set of flags with the values in defaults.
+func New(defaults map[string]string) *Flags {
+	f := &Flags{
+		values:    make(map[string]string),
+		callbacks: make(map[string][]Callback),
+	}
+	for name, value := range defaults {
+		f.values[name] = value
+	}
+	return f
+}
+
+// Get returns the value of the flag name and whether it is set.
+func (f *Flags) Get(name string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v, ok := f.values[name]
+	return v, ok
+}
+
+// Bool returns the value of the flag name as a boolean. It returns false if
+// the flag is not set or it is not a boolean.
+func (f *Flags) Bool(name string) bool {
+	v, _ := f.Get(name)
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+// OnChange registers a callback that is called when the flag name changes.
+// If name is empty, the callback is called when any flag changes. Callbacks
+// are called synchronously, after the change, in the goroutine that made
+// it.
+func (f *Flags) OnChange(name string, cb Callback) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if name == "" {
+		f.all = append(f.all, cb)
+		return
+	}
+	f.callbacks[name] = append(f.callbacks[name], cb)
+}
+
+// Set sets the flag name to value. The callbacks are only called if the value
+// changes.
+func (f *Flags) Set(name, value string) {
+	f.mu.Lock()
+	if old, ok := f.values[name]; ok && old == value {
+		f.mu.Unlock()
+		return
+	}
+	f.values[name] = value
+	cbs := append(append([]Callback(nil), f.callbacks[name]...), f.all...)
+	f.mu.Unlock()
+
+	for _, cb := range cbs {
+		cb(name, value)
+	}
+}
+
+// Apply applies a setting, like "overlay=1,mode=defensive". The setting is
+// validated before changing any flag.
+func (f *Flags) Apply(setting string) error {
+	type pair struct{ name, value string }
+
+	var pairs []pair
+	fields := strings.FieldsFunc(setting, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field, "=")
+		if name == "" {
+			return fmt.Errorf("missing flag name in %q", field)
+		}
+		if !ok {
+			value = "true"
+		}
+		pairs = append(pairs, pair{name, value})
+	}
+
+	for _, p := range pairs {
+		f.Set(p.name, p.value)
+	}
+	return nil
+}
+
+// Message applies the settings in the messages YourName and YourColour. It
+// implements the rtb.Observer interface.
+func (f *Flags) Message(msg rtb.Message) {
+	var s string
+	switch m := rtb.CopyMessage(msg).(type) {
+	case rtb.MessageYourName:
+		s = m.Name
+	case rtb.MessageYourColour:
+		s = m.Colour
+	default:
+		return
+	}
+
+	_, setting, ok := strings.Cut(s, "#")
+	if !ok {
+		return
+	}
+	if err := f.Apply(setting); err != nil {
+		f.report(setting, err)
+	}
+}
+
+// SetErrorHandler sets the function called with the settings received in
+// messages or read by Watch that cannot be applied. By default, they are
+// ignored.
+func (f *Flags) SetErrorHandler(h func(setting string, err error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.onError = h
+}
+
+// report calls the error handler, if any.
+func (f *Flags) report(setting string, err error) {
+	f.mu.Lock()
+	h := f.onError
+	f.mu.Unlock()
+
+	if h != nil {
+		h(setting, err)
+	}
+}
+
+// Watch applies the settings written to the FIFO at path, one per line, until
+// ctx is done. The FIFO is opened for reading and writing, so it does not
+// block waiting for a writer and it is not closed when the writers go away.
+// If path is a regular file, Watch applies its lines and returns.
+func (f *Flags) Watch(ctx context.Context, path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("could not open control file: %v", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		file.Close()
+	}()
+
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		setting := strings.TrimSpace(s.Text())
+		if setting == "" || strings.HasPrefix(setting, "#") {
+			continue
+		}
+		if err := f.Apply(setting); err != nil {
+			f.report(setting, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("could not read control file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package flags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestApply(t *testing.T) {
+	f := New(map[string]string{"mode": "aggressive", "overlay": "false"})
+
+	var changes, modes []string
+	f.OnChange("", func(name, value string) {
+		changes = append(changes, name+"="+value)
+	})
+	f.OnChange("mode", func(name, value string) {
+		modes = append(modes, value)
+	})
+
+	if err := f.Apply("overlay, mode=defensive verbose=0"); err != nil {
+		t.Fatalf("could not apply setting: %v", err)
+	}
+	// Unchanged values do not call the callbacks.
+	if err := f.Apply("mode=defensive"); err != nil {
+		t.Fatalf("could not apply setting: %v", err)
+	}
+	if err := f.Apply("mode=x,=1"); err == nil {
+		t.Errorf("invalid setting was applied")
+	}
+
+	want := "overlay=true mode=defensive verbose=0"
+	if got := strings.Join(changes, " "); got != want {
+		t.Errorf("wrong changes: got=%v want=%v", got, want)
+	}
+	if got := strings.Join(modes, " "); got != "defensive" {
+		t.Errorf("wrong mode changes: got=%v want=defensive", got)
+	}
+	if !f.Bool("overlay") || f.Bool("verbose") || f.Bool("missing") {
+		t.Errorf("wrong booleans: overlay=%v verbose=%v missing=%v", f.Bool("overlay"), f.Bool("verbose"), f.Bool("missing"))
+	}
+	if v, ok := f.Get("mode"); !ok || v != "defensive" {
+		t.Errorf("wrong mode: got=%v,%v want=defensive,true", v, ok)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	f := New(nil)
+	var errs []string
+	f.SetErrorHandler(func(setting string, err error) {
+		errs = append(errs, setting)
+	})
+
+	r := rtb.NewRobot(nil, &strings.Builder{})
+	r.AddObserver(f)
+	strategy := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	for _, msg := range []rtb.Message{
+		rtb.MessageYourName{Name: "bot#overlay,mode=defensive"},
+		rtb.MessageYourColour{Colour: "ff0000"},
+		&rtb.MessageYourColour{Colour: "ff0000#verbose=1"},
+		rtb.MessageYourName{Name: "bot#=x"},
+	} {
+		r.Deliver(strategy, msg)
+	}
+
+	for name, want := range map[string]string{"overlay": "true", "mode": "defensive", "verbose": "1"} {
+		if got, _ := f.Get(name); got != want {
+			t.Errorf("wrong flag %v: got=%v want=%v", name, got, want)
+		}
+	}
+	if len(errs) != 1 || errs[0] != "=x" {
+		t.Errorf("wrong errors: got=%q want=[=x]", errs)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ctl")
+	contents := "# Comment.\noverlay=1\n\nmode=defensive\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	f := New(nil)
+	if err := f.Watch(context.Background(), path); err != nil {
+		t.Fatalf("could not watch file: %v", err)
+	}
+	if !f.Bool("overlay") {
+		t.Errorf("overlay was not enabled")
+	}
+	if got, _ := f.Get("mode"); got != "defensive" {
+		t.Errorf("wrong mode: got=%v want=defensive", got)
+	}
+
+	if err := f.Watch(context.Background(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("missing file was watched")
+	}
+}
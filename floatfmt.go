@@ -0,0 +1,90 @@
+package rtb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FloatFormat defines how the floats of the commands are formatted. Shorter
+// representations save message budget and keep long commands under the
+// length limit of the protocol.
+type FloatFormat struct {
+	// Fmt is the format passed to strconv.FormatFloat: 'f', 'e' or 'g'.
+	// If zero, 'f' is used.
+	Fmt byte
+
+	// Prec is the precision passed to strconv.FormatFloat, i.e. the
+	// number of digits after the decimal point with 'f' and 'e', or the
+	// number of significant digits with 'g'. With -1, the minimum number
+	// of digits necessary to represent the value exactly is used. If
+	// zero, 6 is used, so a FloatFormat that only sets TrimZeros does
+	// not round the floats to integers.
+	Prec int
+
+	// TrimZeros removes the trailing zeros of the decimals and the
+	// decimal point if no decimals are left.
+	TrimZeros bool
+}
+
+// DefaultFloatFormat returns the format used by robots without a format. It
+// is equivalent to the %f verb of the fmt package.
+func DefaultFloatFormat() FloatFormat {
+	return FloatFormat{Fmt: 'f', Prec: defaultPrec}
+}
+
+// defaultPrec is the precision used when FloatFormat.Prec is zero.
+const defaultPrec = 6
+
+// format formats v.
+func (f FloatFormat) format(v float64) string {
+	fmt := f.Fmt
+	if fmt == 0 {
+		fmt = 'f'
+	}
+	prec := f.Prec
+	if prec == 0 {
+		prec = defaultPrec
+	}
+	s := strconv.FormatFloat(v, fmt, prec, 64)
+	if f.TrimZeros {
+		s = trimZeros(s)
+	}
+	return s
+}
+
+// trimZeros removes the trailing zeros of the decimals of s, keeping the
+// exponent if any.
+func trimZeros(s string) string {
+	mant, exp := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mant, exp = s[:i], s[i:]
+	}
+	if strings.Contains(mant, ".") {
+		mant = strings.TrimSuffix(strings.TrimRight(mant, "0"), ".")
+	}
+	return mant + exp
+}
+
+// SetFloatFormat sets the format of the floats of the commands sent by r.
+func (r *Robot) SetFloatFormat(f FloatFormat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.floatFmt = &f
+}
+
+// SetFloatFormat calls SetFloatFormat on the default Robot.
+func SetFloatFormat(f FloatFormat) {
+	std.SetFloatFormat(f)
+}
+
+// floatFormat returns the float format of r.
+func (r *Robot) floatFormat() FloatFormat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.floatFmt == nil {
+		return DefaultFloatFormat()
+	}
+	return *r.floatFmt
+}
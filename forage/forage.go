@@ -0,0 +1,201 @@
+// Package forage implements a cookie foraging behavior. When the radar
+// detects a cookie, the Forager weighs the energy it would give against the
+// time spent in the detour and the threat of the enemies around it, and, if
+// it is worth it, inserts the cookie as the next waypoint of the navigator.
+package forage
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Forager.
+type Config struct {
+	// CookieEnergy is the energy given by a cookie. It is not sent by
+	// the server. If zero, 15 is used.
+	CookieEnergy float64
+
+	// Speed is the expected average speed of the robot, used to
+	// estimate the duration of the detour. If zero, 2 is used.
+	Speed float64
+
+	// TimeCost is the cost, in energy units, of every second spent in a
+	// detour. If zero, 2 is used.
+	TimeCost float64
+
+	// ThreatRadius is the distance from an enemy to a cookie at which
+	// the enemy starts being a threat. If zero, 10 is used.
+	ThreatRadius float64
+
+	// ThreatCost is the cost, in energy units, of an enemy sitting on
+	// the cookie. The cost decreases linearly with the distance from
+	// the enemy to the cookie. If zero, 20 is used.
+	ThreatCost float64
+}
+
+// Decision is the result of evaluating a detour to a cookie.
+type Decision struct {
+	// Worth is true if the detour is worth it.
+	Worth bool
+
+	// Score is the expected net gain of the detour, in energy units.
+	Score float64
+
+	// Gain is the expected energy gain, considering the energy the robot
+	// can still take.
+	Gain float64
+
+	// DetourTime is the estimated extra time needed by the detour.
+	DetourTime float64
+
+	// Threat is the threat cost of the enemies around the cookie.
+	Threat float64
+}
+
+// Forager decides whether to go for the cookies detected by the radar. It
+// implements the rtb.Observer interface and must be added to the robot after
+// the world model, the energy manager and the tracker. Forager methods can be
+// called concurrently.
+type Forager struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	e   *energy.Manager
+	tr  *track.Tracker
+	n   *nav.Navigator
+
+	mu     sync.Mutex
+	target *arena.Point
+}
+
+// New returns a Forager. tr can be nil, in which case enemies are not
+// considered a threat.
+func New(w *world.World, e *energy.Manager, tr *track.Tracker, n *nav.Navigator, cfg Config) *Forager {
+	if cfg.CookieEnergy == 0 {
+		cfg.CookieEnergy = 15
+	}
+	if cfg.Speed == 0 {
+		cfg.Speed = 2
+	}
+	if cfg.TimeCost == 0 {
+		cfg.TimeCost = 2
+	}
+	if cfg.ThreatRadius == 0 {
+		cfg.ThreatRadius = 10
+	}
+	if cfg.ThreatCost == 0 {
+		cfg.ThreatCost = 20
+	}
+	return &Forager{cfg: cfg, w: w, e: e, tr: tr, n: n}
+}
+
+// Evaluate evaluates a detour to a cookie at p.
+func (f *Forager) Evaluate(p arena.Point) Decision {
+	s := f.w.State()
+
+	// The detour is the extra distance needed to visit the cookie
+	// before the next waypoint.
+	detour := p.Sub(s.Pos).Len()
+	if wps := f.n.Waypoints(); len(wps) > 0 {
+		next := wps[0]
+		detour += next.Sub(p).Len() - next.Sub(s.Pos).Len()
+	}
+	detourTime := detour / f.cfg.Speed
+
+	gain := f.cfg.CookieEnergy
+	if max, ok := f.w.Option(rtb.GOptionRobotMaxEnergy); ok {
+		gain = math.Max(0, math.Min(gain, max-f.e.Energy()))
+	}
+	if f.e.PreferCookies() {
+		gain *= 2
+	}
+
+	var threat float64
+	if f.tr != nil {
+		for _, t := range f.tr.Tracks() {
+			if t.TeamMate {
+				continue
+			}
+			d := t.PositionAt(s.Time).Sub(p).Len()
+			threat += f.cfg.ThreatCost * math.Max(0, 1-d/f.cfg.ThreatRadius)
+		}
+	}
+
+	score := gain - f.cfg.TimeCost*detourTime - threat
+	return Decision{
+		Worth:      score > 0,
+		Score:      score,
+		Gain:       gain,
+		DetourTime: detourTime,
+		Threat:     threat,
+	}
+}
+
+// Target returns the position of the cookie the robot is going for. It
+// returns false if there is none.
+func (f *Forager) Target() (arena.Point, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.target == nil {
+		return arena.Point{}, false
+	}
+	return *f.target, true
+}
+
+// Message evaluates the cookies detected by the radar and starts a detour
+// when it is worth it.
+func (f *Forager) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		f.setTarget(nil)
+	case rtb.MessageCollision:
+		if m.Object != rtb.ObjectCookie {
+			return
+		}
+		// The cookie could have been eaten before reaching the
+		// waypoint.
+		if p, ok := f.Target(); ok {
+			if wps := f.n.Waypoints(); len(wps) > 0 && wps[0] == p {
+				f.n.GoTo(wps[1:]...)
+			}
+		}
+		f.setTarget(nil)
+	case rtb.MessageInfo:
+		// The navigator discards the cookie when it is reached.
+		if p, ok := f.Target(); ok {
+			if wps := f.n.Waypoints(); len(wps) == 0 || wps[0] != p {
+				f.setTarget(nil)
+			}
+		}
+	case rtb.MessageRadar:
+		if m.Object != rtb.ObjectCookie {
+			return
+		}
+		if _, ok := f.Target(); ok {
+			return
+		}
+		p := f.w.Absolute(m.RadarAngle, m.Distance)
+		if !f.Evaluate(p).Worth {
+			return
+		}
+		f.setTarget(&p)
+		f.n.GoTo(append([]arena.Point{p}, f.n.Waypoints()...)...)
+	}
+}
+
+// setTarget sets the current target.
+func (f *Forager) setTarget(p *arena.Point) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.target = p
+}
@@ -0,0 +1,106 @@
+package forage
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// setup returns a forager for a robot at (0, 0) heading to the positive X
+// axis with the given energy. If enemy is not nil, an enemy is detected at
+// that position.
+func setup(level float64, enemy *arena.Point) (*rtb.Robot, *nav.Navigator, *Forager) {
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	e := energy.New(energy.Config{CookieThreshold: 30})
+	tr := track.New(w, track.Config{})
+	n := nav.New(r, w, nav.Config{})
+	f := New(w, e, tr, n, Config{})
+	for _, obs := range []rtb.Observer{w, e, tr, n, f} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxEnergy, Value: 120},
+		rtb.MessageGameStarts{},
+		rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0},
+		rtb.MessageEnergy{EnergyLevel: level},
+	}
+	if enemy != nil {
+		msgs = append(msgs, rtb.MessageRadar{
+			Distance:   enemy.Len(),
+			Object:     rtb.ObjectRobot,
+			RadarAngle: math.Atan2(enemy.Y, enemy.X),
+		})
+	}
+	for _, msg := range msgs {
+		r.Deliver(nop, msg)
+	}
+	return r, n, f
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		energy    float64
+		enemy     *arena.Point
+		waypoint  *arena.Point
+		cookie    arena.Point
+		wantWorth bool
+		wantScore float64
+	}{
+		{"Close", 50, nil, nil, arena.Point{X: 4, Y: 0}, true, 11},
+		{"Far", 50, nil, nil, arena.Point{X: 20, Y: 0}, false, -5},
+		{"Full", 115, nil, nil, arena.Point{X: 4, Y: 0}, true, 1},
+		{"Hungry", 20, nil, nil, arena.Point{X: 20, Y: 0}, true, 10},
+		{"Threat", 50, &arena.Point{X: 5, Y: 0}, nil, arena.Point{X: 4, Y: 0}, false, -7},
+		{"On the way", 50, nil, &arena.Point{X: 20, Y: 0}, arena.Point{X: 10, Y: 0}, true, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, n, f := setup(tt.energy, tt.enemy)
+			if tt.waypoint != nil {
+				n.GoTo(*tt.waypoint)
+			}
+
+			d := f.Evaluate(tt.cookie)
+			if d.Worth != tt.wantWorth || math.Abs(d.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("unexpected decision: got=%+v want=%v, %v", d, tt.wantWorth, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestDetour(t *testing.T) {
+	r, n, f := setup(50, nil)
+	n.GoTo(arena.Point{X: 20, Y: 0})
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageRadar{Distance: 5, Object: rtb.ObjectCookie, RadarAngle: 0})
+
+	cookie := arena.Point{X: 5, Y: 0}
+	if got, ok := f.Target(); !ok || got != cookie {
+		t.Fatalf("unexpected target: got=%v, %v want=%v", got, ok, cookie)
+	}
+	if wps := n.Waypoints(); len(wps) != 2 || wps[0] != cookie {
+		t.Fatalf("unexpected waypoints: %v", wps)
+	}
+
+	r.Deliver(nop, rtb.MessageCollision{Object: rtb.ObjectCookie})
+
+	if _, ok := f.Target(); ok {
+		t.Errorf("unexpected target after eating the cookie")
+	}
+	if wps := n.Waypoints(); len(wps) != 1 || wps[0] != (arena.Point{X: 20, Y: 0}) {
+		t.Errorf("unexpected waypoints after eating the cookie: %v", wps)
+	}
+}
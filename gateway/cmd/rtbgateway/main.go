@@ -0,0 +1,65 @@
+// rtbgateway is a RealTimeBattle robot that is controlled through gRPC. It
+// forwards the messages sent by the server to the subscribers of the
+// ServerMessages RPC and sends the commands received through the Commands
+// RPC. The service is defined in rtb.proto.
+//
+// Usage:
+//
+//	rtbgateway [-addr address]
+//
+// The RealTimeBattle server does not pass arguments to the robots, so
+// rtbgateway is usually called from a shell script that is used as the
+// robot:
+//
+//	#!/bin/sh
+//	exec rtbgateway -addr :9000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/gateway"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "serve gRPC on `address`")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	log.SetPrefix("rtbgateway: ")
+	log.SetFlags(0)
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("could not listen: %v", err)
+	}
+
+	r := rtb.NewRobot(nil, nil)
+	g := gateway.New(r)
+	s := grpc.NewServer(gateway.ServerOption())
+	g.Register(s)
+	go func() {
+		if err := s.Serve(l); err != nil {
+			log.Printf("could not serve: %v", err)
+		}
+	}()
+
+	r.Run(rtb.ListenSettings{SendRotationReached: 2, ChanBufferCapacity: 100}, g)
+	s.Stop()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbgateway [-addr address]\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,315 @@
+// Package gateway implements a gRPC facade of the RealTimeBattle protocol.
+// The gateway runs as the robot process and translates the protocol into
+// typed RPC streams, so robots can be written in any language with gRPC
+// support. The service is defined in rtb.proto.
+//
+// The gateway lives in its own module, so the rtb module does not depend on
+// gRPC.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// subscriberBuffer is the number of messages buffered per subscriber. If a
+// subscriber is slower than the server, messages are dropped instead of
+// delaying the robot.
+const subscriberBuffer = 1024
+
+// Gateway forwards the messages delivered to it to the subscribers of the
+// ServerMessages RPC and sends the commands received through the Commands RPC
+// to the server. It implements the rtb.Strategy interface. Gateway methods
+// can be called concurrently.
+type Gateway struct {
+	r *rtb.Robot
+
+	mu      sync.Mutex
+	subs    map[chan *ServerMessage]struct{}
+	dropped int
+}
+
+// New returns a Gateway that sends commands through r.
+func New(r *rtb.Robot) *Gateway {
+	return &Gateway{r: r, subs: map[chan *ServerMessage]struct{}{}}
+}
+
+// Register registers the service in s, which must be created with
+// ServerOption.
+func (g *Gateway) Register(s *grpc.Server) {
+	s.RegisterService(&serviceDesc, g)
+}
+
+// ServerOption returns the server option needed by the service. It forces the
+// codec of the service, which only knows the messages of rtb.proto, so the
+// server cannot be shared with other services.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(codec{})
+}
+
+// DialOption returns the dial option needed by Go clients of the service.
+func DialOption() grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{}))
+}
+
+// Handle forwards msg to the subscribers.
+func (g *Gateway) Handle(r *rtb.Robot, msg rtb.Message) {
+	sm, err := newServerMessage(msg)
+	if err != nil {
+		r.Logger().Error("could not encode message", "err", err)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for c := range g.subs {
+		select {
+		case c <- sm:
+		default:
+			g.dropped++
+		}
+	}
+}
+
+// Dropped returns the number of messages dropped because a subscriber was
+// not keeping up.
+func (g *Gateway) Dropped() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.dropped
+}
+
+// subscribe adds a subscriber.
+func (g *Gateway) subscribe() chan *ServerMessage {
+	c := make(chan *ServerMessage, subscriberBuffer)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.subs[c] = struct{}{}
+	return c
+}
+
+// unsubscribe removes a subscriber.
+func (g *Gateway) unsubscribe(c chan *ServerMessage) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.subs, c)
+}
+
+// serverMessages implements the ServerMessages RPC.
+func (g *Gateway) serverMessages(stream grpc.ServerStream) error {
+	var req SubscribeRequest
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+
+	c := g.subscribe()
+	defer g.unsubscribe(c)
+
+	for {
+		select {
+		case sm := <-c:
+			if err := stream.SendMsg(sm); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// commands implements the Commands RPC.
+func (g *Gateway) commands(stream grpc.ServerStream) error {
+	var sent uint64
+	for {
+		var cmd Command
+		if err := stream.RecvMsg(&cmd); err != nil {
+			if errors.Is(err, io.EOF) {
+				return stream.SendMsg(&CommandsReply{Sent: sent})
+			}
+			return err
+		}
+		if err := g.send(&cmd); err != nil {
+			return err
+		}
+		sent++
+	}
+}
+
+// send sends cmd to the server. Values out of range are clamped by the robot,
+// so they are not reported as errors.
+func (g *Gateway) send(cmd *Command) error {
+	want := map[string]int{
+		"Name": 0, "Colour": 0, "Print": 0, "Debug": 0,
+		"Rotate": 2, "RotateTo": 3, "RotateAmount": 3, "Sweep": 4,
+		"Accelerate": 1, "Brake": 1, "Shoot": 1,
+		"DebugLine": 4, "DebugCircle": 3,
+	}
+	n, ok := want[cmd.Type]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown command %q", cmd.Type)
+	}
+	if len(cmd.Values) != n {
+		return status.Errorf(codes.InvalidArgument, "wrong number of values for %v: got=%v want=%v", cmd.Type, len(cmd.Values), n)
+	}
+
+	v := cmd.Values
+	var err error
+	switch cmd.Type {
+	case "Name":
+		err = g.r.Name(cmd.Text)
+	case "Colour":
+		colours := strings.Fields(cmd.Text)
+		if len(colours) != 2 {
+			return status.Errorf(codes.InvalidArgument, "invalid colours %q", cmd.Text)
+		}
+		err = g.r.Colour(colours[0], colours[1])
+	case "Print":
+		err = g.r.Printf("%s", cmd.Text)
+	case "Debug":
+		err = g.r.Debugf("%s", cmd.Text)
+	case "Rotate":
+		err = g.r.Rotate(rtb.Part(v[0]), v[1])
+	case "RotateTo":
+		err = g.r.RotateTo(rtb.Part(v[0]), v[1], v[2])
+	case "RotateAmount":
+		err = g.r.RotateAmount(rtb.Part(v[0]), v[1], v[2])
+	case "Sweep":
+		err = g.r.Sweep(rtb.Part(v[0]), v[1], v[2], v[3])
+	case "Accelerate":
+		err = g.r.Accelerate(v[0])
+	case "Brake":
+		err = g.r.Brake(v[0])
+	case "Shoot":
+		err = g.r.Shoot(v[0])
+	case "DebugLine":
+		err = g.r.DebugLine(v[0], v[1], v[2], v[3])
+	case "DebugCircle":
+		err = g.r.DebugCircle(v[0], v[1], v[2])
+	}
+
+	var errRange rtb.ErrOutOfRange
+	if err != nil && !errors.As(err, &errRange) {
+		return status.Errorf(codes.InvalidArgument, "could not send %v: %v", cmd.Type, err)
+	}
+	return nil
+}
+
+// newServerMessage converts msg into a ServerMessage.
+func newServerMessage(msg rtb.Message) (*ServerMessage, error) {
+	line, err := rtb.EncodeMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, rest, _ := strings.Cut(line, " ")
+	sm := &ServerMessage{Type: typ, Line: line}
+	switch m := rtb.CopyMessage(msg).(type) {
+	case rtb.MessageYourName:
+		sm.Text = m.Name
+	case rtb.MessageYourColour:
+		sm.Text = m.Colour
+	case rtb.MessageWarning:
+		sm.Values, sm.Text = []float64{float64(m.Warning)}, m.Message
+	default:
+		for _, f := range strings.Fields(rest) {
+			var v float64
+			if _, err := fmt.Sscan(f, &v); err != nil {
+				return nil, fmt.Errorf("could not parse field %q: %v", f, err)
+			}
+			sm.Values = append(sm.Values, v)
+		}
+	}
+	return sm, nil
+}
+
+// serviceDesc is the description of the service defined in rtb.proto.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "rtb.Robot",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "ServerMessages",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(*Gateway).serverMessages(stream)
+			},
+			ServerStreams: true,
+		},
+		{
+			StreamName: "Commands",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(*Gateway).commands(stream)
+			},
+			ClientStreams: true,
+		},
+	},
+	Metadata: "rtb.proto",
+}
+
+// Client is a Go client of the service. It is mostly useful for testing,
+// since Go robots can use the rtb package directly.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient returns a client that uses cc, which must be created with
+// DialOption.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// ServerMessages subscribes to the messages sent by the server. The returned
+// function receives the next message.
+func (c *Client) ServerMessages(ctx context.Context) (recv func() (*ServerMessage, error), err error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/rtb.Robot/ServerMessages")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&SubscribeRequest{}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return func() (*ServerMessage, error) {
+		var sm ServerMessage
+		if err := stream.RecvMsg(&sm); err != nil {
+			return nil, err
+		}
+		return &sm, nil
+	}, nil
+}
+
+// Commands sends cmds to the server and returns the number of commands sent.
+func (c *Client) Commands(ctx context.Context, cmds ...*Command) (uint64, error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[1], "/rtb.Robot/Commands")
+	if err != nil {
+		return 0, err
+	}
+	for _, cmd := range cmds {
+		if err := stream.SendMsg(cmd); err != nil {
+			break
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return 0, err
+	}
+	var reply CommandsReply
+	if err := stream.RecvMsg(&reply); err != nil {
+		return 0, err
+	}
+	return reply.Sent, nil
+}
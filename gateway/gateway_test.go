@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestWire(t *testing.T) {
+	sm := &ServerMessage{Type: "Info", Values: []float64{1.5, 0, -2}, Line: "Info 1.5 0 -2"}
+	var got ServerMessage
+	if err := got.unmarshal(sm.marshal()); err != nil {
+		t.Fatalf("could not unmarshal: %v", err)
+	}
+	if got.Type != sm.Type || got.Line != sm.Line || len(got.Values) != 3 || got.Values[2] != -2 {
+		t.Errorf("unexpected message: got=%+v want=%+v", got, sm)
+	}
+
+	if err := got.unmarshal([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Errorf("truncated message unmarshaled")
+	}
+}
+
+func TestGateway(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	g := New(r)
+
+	l := bufconn.Listen(1 << 16)
+	s := grpc.NewServer(ServerOption())
+	g.Register(s)
+	go s.Serve(l)
+	defer s.Stop()
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		DialOption(),
+	)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer cc.Close()
+	c := NewClient(cc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recv, err := c.ServerMessages(ctx)
+	if err != nil {
+		t.Fatalf("could not subscribe: %v", err)
+	}
+	// Wait for the subscription before delivering messages.
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		n := len(g.subs)
+		g.mu.Unlock()
+		if n > 0 {
+			break
+		}
+	}
+
+	g.Handle(r, rtb.MessageInfo{Time: 1.5, Speed: 2, CannonAngle: -0.5})
+	g.Handle(r, rtb.MessageWarning{Warning: rtb.WarningUnknownMessage, Message: "foo bar"})
+
+	sm, err := recv()
+	if err != nil {
+		t.Fatalf("could not receive message: %v", err)
+	}
+	if sm.Type != "Info" || len(sm.Values) != 3 || sm.Values[0] != 1.5 || sm.Values[2] != -0.5 {
+		t.Errorf("unexpected message: %+v", sm)
+	}
+	if sm, err = recv(); err != nil || sm.Type != "Warning" || sm.Text != "foo bar" || sm.Values[0] != float64(rtb.WarningUnknownMessage) {
+		t.Errorf("unexpected warning: %+v, %v", sm, err)
+	}
+
+	sent, err := c.Commands(ctx,
+		&Command{Type: "Name", Text: "foo"},
+		&Command{Type: "Rotate", Values: []float64{1, 0.5}},
+		&Command{Type: "Shoot", Values: []float64{10}},
+	)
+	if err != nil || sent != 3 {
+		t.Fatalf("unexpected reply: got=%v, %v want=%v", sent, err, 3)
+	}
+	if got, want := out.String(), "Name foo\nRotate 1 0.500000\nShoot 10.000000\n"; got != want {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+
+	_, err = c.Commands(ctx, &Command{Type: "Rotate", Values: []float64{1}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unexpected error: got=%v want=%v", err, codes.InvalidArgument)
+	}
+}
@@ -0,0 +1,251 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of rtb.proto are encoded by hand, so the gateway does not need
+// generated code. The encoding is the standard protobuf wire format, so
+// clients generated from rtb.proto can talk to the gateway.
+
+// SubscribeRequest is the request of the ServerMessages RPC.
+type SubscribeRequest struct{}
+
+// ServerMessage is a message sent by the server.
+type ServerMessage struct {
+	// Type is the message type, e.g. "Info".
+	Type string
+
+	// Values are the numeric fields of the message, in protocol order.
+	Values []float64
+
+	// Text is the textual field of YourName, YourColour and Warning
+	// messages.
+	Text string
+
+	// Line is the message as sent by the server.
+	Line string
+}
+
+// Command is a command sent to the server.
+type Command struct {
+	// Type is the command type, e.g. "Rotate".
+	Type string
+
+	// Values are the numeric arguments of the command, in protocol
+	// order.
+	Values []float64
+
+	// Text is the textual argument of Name, Colour ("home away"), Print
+	// and Debug commands.
+	Text string
+}
+
+// CommandsReply is the reply of the Commands RPC.
+type CommandsReply struct {
+	// Sent is the number of commands sent.
+	Sent uint64
+}
+
+// message is implemented by the messages of the service.
+type message interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+func (m *SubscribeRequest) marshal() []byte { return nil }
+
+func (m *SubscribeRequest) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		return skip(num, typ, b)
+	})
+}
+
+func (m *ServerMessage) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Type)
+	b = appendDoubles(b, 2, m.Values)
+	b = appendString(b, 3, m.Text)
+	b = appendString(b, 4, m.Line)
+	return b
+}
+
+func (m *ServerMessage) unmarshal(b []byte) error {
+	*m = ServerMessage{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Type)
+		case 2:
+			return consumeDoubles(typ, b, &m.Values)
+		case 3:
+			return consumeString(typ, b, &m.Text)
+		case 4:
+			return consumeString(typ, b, &m.Line)
+		}
+		return skip(num, typ, b)
+	})
+}
+
+func (m *Command) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Type)
+	b = appendDoubles(b, 2, m.Values)
+	b = appendString(b, 3, m.Text)
+	return b
+}
+
+func (m *Command) unmarshal(b []byte) error {
+	*m = Command{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Type)
+		case 2:
+			return consumeDoubles(typ, b, &m.Values)
+		case 3:
+			return consumeString(typ, b, &m.Text)
+		}
+		return skip(num, typ, b)
+	})
+}
+
+func (m *CommandsReply) marshal() []byte {
+	var b []byte
+	if m.Sent != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.Sent)
+	}
+	return b
+}
+
+func (m *CommandsReply) unmarshal(b []byte) error {
+	*m = CommandsReply{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(b)
+			m.Sent = v
+			return n, protowire.ParseError(n)
+		}
+		return skip(num, typ, b)
+	})
+}
+
+// codec is a gRPC codec for the messages of the service. Its name is "proto",
+// so it is compatible with the clients generated from rtb.proto.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.marshal(), nil
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.unmarshal(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+// unmarshalFields calls field for every field in b. field must return the
+// number of bytes consumed after the tag.
+func unmarshalFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// skip consumes a field that is not known.
+func skip(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	n := protowire.ConsumeFieldValue(num, typ, b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return n, nil
+}
+
+// errWireType is returned when a known field has an unexpected type.
+var errWireType = errors.New("unexpected wire type")
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func consumeString(typ protowire.Type, b []byte, s *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errWireType
+	}
+	v, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*s = v
+	return n, nil
+}
+
+// appendDoubles appends a packed repeated double field.
+func appendDoubles(b []byte, num protowire.Number, vs []float64) []byte {
+	if len(vs) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(8*len(vs)))
+	for _, v := range vs {
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+// consumeDoubles consumes a repeated double field, packed or not.
+func consumeDoubles(typ protowire.Type, b []byte, vs *[]float64) (int, error) {
+	switch typ {
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		*vs = append(*vs, math.Float64frombits(v))
+		return n, nil
+	case protowire.BytesType:
+		packed, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		for len(packed) > 0 {
+			v, m := protowire.ConsumeFixed64(packed)
+			if m < 0 {
+				return 0, protowire.ParseError(m)
+			}
+			*vs = append(*vs, math.Float64frombits(v))
+			packed = packed[m:]
+		}
+		return n, nil
+	}
+	return 0, errWireType
+}
@@ -0,0 +1,86 @@
+package gym
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/world"
+)
+
+// ObservationEncoder returns the observation of a turn, given the state of
+// the world model and the messages of the turn.
+type ObservationEncoder func(s world.State, t rtb.Tick) Observation
+
+// ActionDecoder sends the commands of the action a through r. Values out of
+// range should not be reported as errors, since the robot clamps them.
+type ActionDecoder func(r *rtb.Robot, a Action) error
+
+// DefaultObservationSize is the size of the observations encoded by
+// DefaultObservation.
+const DefaultObservationSize = 13
+
+// DefaultObservation encodes the following values, in order:
+//
+//   - 0: game time.
+//   - 1: speed.
+//   - 2: angle of the cannon.
+//   - 3: angle of the radar.
+//   - 4: energy level.
+//   - 5: robots left.
+//   - 6: distance to the object detected by the radar, or 0 if the radar
+//     did not detect anything in the turn.
+//   - 7-11: one-hot encoding of the object detected by the radar: robot,
+//     shot, wall, cookie and mine.
+//   - 12: energy level of the robot detected by the radar, or 0 if no
+//     robot was detected.
+func DefaultObservation(s world.State, t rtb.Tick) Observation {
+	obs := make(Observation, DefaultObservationSize)
+	obs[0] = s.Time
+	obs[1] = s.Speed
+	obs[2] = s.CannonAngle
+	obs[3] = s.RadarAngle
+	obs[4] = s.Energy
+	obs[5] = float64(s.RobotsLeft)
+	if t.Radar != nil {
+		obs[6] = t.Radar.Distance
+		if o := int(t.Radar.Object); o >= 0 && o < 5 {
+			obs[7+o] = 1
+		}
+	}
+	if t.RobotInfo != nil {
+		obs[12] = t.RobotInfo.EnergyLevel
+	}
+	return obs
+}
+
+// DefaultActionSize is the size of the actions decoded by DefaultAction.
+const DefaultActionSize = 4
+
+// DefaultAction decodes the following values, in order:
+//
+//   - 0: acceleration.
+//   - 1: rotation speed of the robot.
+//   - 2: rotation speed of the cannon, relative to the robot.
+//   - 3: energy of the shot. No shot is fired if it is not positive.
+func DefaultAction(r *rtb.Robot, a Action) error {
+	if len(a) != DefaultActionSize {
+		return fmt.Errorf("wrong action size: got=%v want=%v", len(a), DefaultActionSize)
+	}
+
+	errs := []error{
+		r.Accelerate(a[0]),
+		r.Rotate(rtb.PartRobot, a[1]),
+		r.Rotate(rtb.PartCannon, a[2]),
+	}
+	if a[3] > 0 {
+		errs = append(errs, r.Shoot(a[3]))
+	}
+	for _, err := range errs {
+		var errRange rtb.ErrOutOfRange
+		if err != nil && !errors.As(err, &errRange) {
+			return err
+		}
+	}
+	return nil
+}
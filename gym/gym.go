@@ -0,0 +1,279 @@
+// Package gym exposes the simulator as a step-based learning environment, in
+// the style of OpenAI Gym, so reinforcement learning algorithms can train
+// policies that are then deployed as normal strategies.
+//
+// Every step, the environment decodes the action of the agent into commands,
+// advances the game and returns the observation of the agent robot, the
+// reward and whether the episode is finished. The encodings of observations
+// and actions are configurable, and NewStrategy uses the same encodings to
+// run a trained policy in real matches.
+package gym
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/selfplay"
+	"github.com/jroimartin/rtb/sim"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Observation is the observation of the agent.
+type Observation []float64
+
+// Action is the action of the agent.
+type Action []float64
+
+// Outcome is the outcome of the game for the agent after a step.
+type Outcome struct {
+	// Result is the result of the agent robot so far.
+	Result sim.RobotResult
+
+	// Done is true if the game is finished.
+	Done bool
+
+	// Won is true if the game is finished and the agent won it.
+	Won bool
+}
+
+// RewardFunc returns the reward of a step, given the outcome before and after
+// the step.
+type RewardFunc func(prev, cur Outcome) float64
+
+// DefaultReward is the damage dealt minus the damage taken during the step,
+// plus 100 if the agent wins the game and minus 100 if it dies.
+func DefaultReward(prev, cur Outcome) float64 {
+	r := (cur.Result.DamageDealt - prev.Result.DamageDealt) -
+		(cur.Result.DamageTaken - prev.Result.DamageTaken)
+	if cur.Won {
+		r += 100
+	}
+	if prev.Result.Alive && !cur.Result.Alive {
+		r -= 100
+	}
+	return r
+}
+
+// Config is the configuration of an environment.
+type Config struct {
+	// Opponents are the robots the agent plays against. They take part
+	// in all the episodes.
+	Opponents []selfplay.Contender
+
+	// Arena and Options are the arena and the game options of the
+	// episodes.
+	Arena   *arena.Arena
+	Options sim.Options
+
+	// TimeStep is the duration of a simulation tick.
+	TimeStep float64
+
+	// Seed is the seed of the first episode. Episode i uses Seed+i.
+	Seed int64
+
+	// Observation encodes the observations. If nil,
+	// DefaultObservation is used.
+	Observation ObservationEncoder
+
+	// Action decodes the actions. If nil, DefaultAction is used.
+	Action ActionDecoder
+
+	// Reward computes the rewards. If nil, DefaultReward is used.
+	Reward RewardFunc
+
+	// Repeat is the number of ticks an action lasts. If zero, 1 is
+	// used.
+	Repeat int
+}
+
+// Env is a learning environment. Env methods must not be called
+// concurrently.
+type Env struct {
+	cfg     Config
+	agent   *agent
+	players []*sim.Player
+	game    *sim.Game
+	episode int64
+	prev    Outcome
+}
+
+// New returns an environment with the given configuration. Close must be
+// called when the environment is no longer needed.
+func New(cfg Config) *Env {
+	if cfg.Observation == nil {
+		cfg.Observation = DefaultObservation
+	}
+	if cfg.Action == nil {
+		cfg.Action = DefaultAction
+	}
+	if cfg.Reward == nil {
+		cfg.Reward = DefaultReward
+	}
+	if cfg.Repeat <= 0 {
+		cfg.Repeat = 1
+	}
+
+	a := newAgent(cfg.Observation, nil)
+	players := []*sim.Player{sim.NewPlayer(a, rtb.ListenSettings{})}
+	for _, c := range cfg.Opponents {
+		players = append(players, sim.NewPlayer(c.New(), c.Settings))
+	}
+	return &Env{cfg: cfg, agent: a, players: players}
+}
+
+// Reset starts a new episode and returns the initial observation.
+func (e *Env) Reset() (Observation, error) {
+	gcfg := sim.Config{
+		Arena:    e.cfg.Arena,
+		Options:  e.cfg.Options,
+		TimeStep: e.cfg.TimeStep,
+		Seed:     e.cfg.Seed + e.episode,
+	}
+	g, err := sim.NewGame(gcfg, e.players)
+	if err != nil {
+		return nil, fmt.Errorf("could not create game: %v", err)
+	}
+	e.game = g
+	e.episode++
+	e.prev = e.outcome()
+	return e.agent.reset(), nil
+}
+
+// Step executes the action a during Config.Repeat ticks and returns the
+// observation after the last tick, the reward and whether the episode is
+// finished.
+func (e *Env) Step(a Action) (obs Observation, reward float64, done bool, err error) {
+	if e.game == nil || e.game.Done() {
+		return nil, 0, false, errors.New("episode finished")
+	}
+
+	e.game.Do(0, func(r *rtb.Robot) {
+		err = e.cfg.Action(r, a)
+	})
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("could not decode action: %v", err)
+	}
+	for i := 0; i < e.cfg.Repeat && e.game.Step(); i++ {
+	}
+
+	cur := e.outcome()
+	reward = e.cfg.Reward(e.prev, cur)
+	e.prev = cur
+	return e.agent.observation(), reward, cur.Done, nil
+}
+
+// outcome returns the outcome of the current game for the agent.
+func (e *Env) outcome() Outcome {
+	res := e.game.Result()
+	done := e.game.Done()
+	return Outcome{
+		Result: res.Robots[0],
+		Done:   done,
+		Won:    done && res.Winner == 0,
+	}
+}
+
+// Close ends the sequence of episodes.
+func (e *Env) Close() {
+	sim.Exit(e.players)
+}
+
+// Policy maps observations to actions.
+type Policy interface {
+	Act(obs Observation) Action
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as
+// policies.
+type PolicyFunc func(obs Observation) Action
+
+// Act calls f(obs).
+func (f PolicyFunc) Act(obs Observation) Action {
+	return f(obs)
+}
+
+// NewStrategy returns a strategy that runs the policy p once per turn, using
+// the encodings enc and dec. They must be the same used to train the policy.
+// If they are nil, the default encodings are used.
+func NewStrategy(p Policy, enc ObservationEncoder, dec ActionDecoder) rtb.Strategy {
+	if enc == nil {
+		enc = DefaultObservation
+	}
+	if dec == nil {
+		dec = DefaultAction
+	}
+	return newAgent(enc, func(r *rtb.Robot, obs Observation) {
+		if err := dec(r, p.Act(obs)); err != nil {
+			r.Logger().Error("could not decode action", "err", err)
+		}
+	})
+}
+
+// agent keeps the observation of a robot up to date. It is used as the
+// strategy of the agent robot in the environment and by NewStrategy.
+type agent struct {
+	enc   ObservationEncoder
+	act   func(r *rtb.Robot, obs Observation)
+	world *world.World
+	agg   rtb.Aggregator
+	once  sync.Once
+
+	mu  sync.Mutex
+	obs Observation
+}
+
+// newAgent returns an agent that encodes the observations with enc. If act is
+// not nil, it is called with the observation at the end of every turn.
+func newAgent(enc ObservationEncoder, act func(r *rtb.Robot, obs Observation)) *agent {
+	a := &agent{enc: enc, act: act, world: world.New()}
+	a.agg.OnTick = a.onTick
+	return a
+}
+
+// Handle updates the observation with msg.
+func (a *agent) Handle(r *rtb.Robot, msg rtb.Message) {
+	a.once.Do(func() {
+		// The world model is attached as an observer, so it also sees
+		// the commands sent by the actions. It does not see the current
+		// message, which is passed explicitly.
+		r.AddObserver(a.world)
+		a.world.Message(msg)
+	})
+	a.agg.Handle(r, msg)
+}
+
+// onTick encodes the observation of the turn t and acts on it.
+func (a *agent) onTick(r *rtb.Robot, t rtb.Tick) {
+	obs := a.enc(a.world.State(), t)
+
+	a.mu.Lock()
+	a.obs = obs
+	a.mu.Unlock()
+
+	if a.act != nil {
+		a.act(r, obs)
+	}
+}
+
+// reset returns the observation at the start of a game, before the first
+// turn.
+func (a *agent) reset() Observation {
+	obs := a.enc(a.world.State(), rtb.Tick{})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.obs = obs
+	return obs
+}
+
+// observation returns the last observation.
+func (a *agent) observation() Observation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.obs
+}
@@ -0,0 +1,120 @@
+package gym
+
+import (
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/selfplay"
+	"github.com/jroimartin/rtb/world"
+)
+
+// turret is a policy that rotates until the radar detects a robot and then
+// shoots it.
+var turret = PolicyFunc(func(obs Observation) Action {
+	if obs[7] == 1 {
+		return Action{0, 0, 0, 5}
+	}
+	return Action{0, 1, 0, 0}
+})
+
+// duck returns a contender that does nothing.
+func duck() selfplay.Contender {
+	return selfplay.Contender{
+		Name: "duck",
+		New: func() rtb.Strategy {
+			return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+		},
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := New(Config{Opponents: []selfplay.Contender{duck()}})
+	defer env.Close()
+
+	for episode := 0; episode < 2; episode++ {
+		obs, err := env.Reset()
+		if err != nil {
+			t.Fatalf("could not reset: %v", err)
+		}
+		if len(obs) != DefaultObservationSize || obs[0] != 0 || obs[5] != 2 {
+			t.Fatalf("unexpected initial observation: %v", obs)
+		}
+
+		var (
+			total float64
+			done  bool
+			steps int
+		)
+		for !done {
+			var r float64
+			obs, r, done, err = env.Step(turret.Act(obs))
+			if err != nil {
+				t.Fatalf("could not step: %v", err)
+			}
+			total += r
+			steps++
+		}
+		if obs[0] <= 0 || obs[5] != 1 {
+			t.Errorf("unexpected final observation: %v", obs)
+		}
+		if total < 100 {
+			t.Errorf("reward too low: %v", total)
+		}
+		if _, _, _, err := env.Step(Action{0, 0, 0, 0}); err == nil {
+			t.Errorf("expected error after the end of the episode")
+		}
+		t.Logf("episode %v: steps=%v reward=%v", episode, steps, total)
+	}
+}
+
+func TestEnvRepeat(t *testing.T) {
+	env := New(Config{Opponents: []selfplay.Contender{duck()}, Repeat: 4, TimeStep: 0.05})
+	defer env.Close()
+
+	if _, err := env.Reset(); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+	obs, _, _, err := env.Step(Action{0, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("could not step: %v", err)
+	}
+	if want := 0.2; obs[0] < want-1e-9 || obs[0] > want+1e-9 {
+		t.Errorf("wrong time: got=%v want=%v", obs[0], want)
+	}
+
+	if _, _, _, err := env.Step(Action{0}); err == nil {
+		t.Errorf("expected error with wrong action size")
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	rep, err := selfplay.Run(selfplay.Config{
+		Contenders: []selfplay.Contender{
+			{Name: "policy", New: func() rtb.Strategy { return NewStrategy(turret, nil, nil) }},
+			duck(),
+		},
+		Games: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := rep.Contenders[0]; c.Wins != 2 {
+		t.Errorf("unexpected policy report: %+v", c)
+	}
+}
+
+func TestDefaultObservation(t *testing.T) {
+	s := world.State{Time: 1, Speed: 2, CannonAngle: 3, RadarAngle: 4, Energy: 50, RobotsLeft: 3}
+	tick := rtb.Tick{
+		Radar:     &rtb.MessageRadar{Distance: 5, Object: rtb.ObjectWall},
+		RobotInfo: &rtb.MessageRobotInfo{EnergyLevel: 20},
+	}
+	got := DefaultObservation(s, tick)
+	want := Observation{1, 2, 3, 4, 50, 3, 5, 0, 0, 1, 0, 0, 20}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrong observation: got=%v want=%v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rtb
+
+import "sync"
+
+// Handshake is a strategy that performs the Initialize handshake and passes
+// the messages to Strategy. When the Initialize message has First equal to
+// true, it sends Name and the colours before passing the message to
+// Strategy, so they are always sent first. Otherwise, the robot keeps the
+// name and the colour assigned by the server with the YourName and
+// YourColour messages:
+//
+//	h := &rtb.Handshake{
+//		Strategy: s,
+//		Name:     "foo Team: bar",
+//		Home:     "ff0000",
+//		Away:     "00ff00",
+//	}
+//	r.Run(settings, h)
+//
+// If the server warns that the name or the colour were not given, an
+// ErrHandshake is emitted on the channel returned by Robot.Errors. Handshake
+// methods can be called concurrently.
+type Handshake struct {
+	// Strategy receives all the messages. If nil, they are discarded.
+	Strategy Strategy
+
+	// Name is the name of the robot. If empty, no name is sent.
+	Name string
+
+	// Home and Away are the colours of the robot. If Home is empty, no
+	// colours are sent. If Away is empty, Home is used.
+	Home, Away string
+
+	mu     sync.Mutex
+	first  bool
+	name   string
+	colour string
+}
+
+// Handle performs the handshake for msg and passes msg to h.Strategy.
+func (h *Handshake) Handle(r *Robot, msg Message) {
+	switch m := msg.(type) {
+	case MessageInitialize:
+		h.initialize(r, m)
+	case MessageYourName:
+		h.setName(m.Name)
+	case MessageYourColour:
+		h.setColour(m.Colour)
+	case MessageWarning:
+		h.warn(r, m.Warning)
+	}
+
+	if h.Strategy != nil {
+		h.Strategy.Handle(r, msg)
+	}
+}
+
+// initialize sends the name and the colours of the robot if msg is the
+// first Initialize message of the sequence.
+func (h *Handshake) initialize(r *Robot, msg MessageInitialize) {
+	h.mu.Lock()
+	h.first = msg.First
+	h.name, h.colour = "", ""
+	h.mu.Unlock()
+
+	if !msg.First {
+		return
+	}
+
+	if h.Name != "" {
+		if err := r.Name(h.Name); err != nil {
+			r.Logger().Warn("could not send name", "err", err)
+		} else {
+			h.setName(h.Name)
+		}
+	}
+
+	if h.Home != "" {
+		away := h.Away
+		if away == "" {
+			away = h.Home
+		}
+		if err := r.Colour(h.Home, away); err != nil {
+			r.Logger().Warn("could not send colour", "err", err)
+		} else {
+			h.setColour(h.Home)
+		}
+	}
+}
+
+// setName sets the name of the robot.
+func (h *Handshake) setName(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.name = name
+}
+
+// setColour sets the colour of the robot.
+func (h *Handshake) setColour(colour string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.colour = colour
+}
+
+// warn emits an ErrHandshake if w reports that the name or the colour were
+// not given.
+func (h *Handshake) warn(r *Robot, w Warning) {
+	if w != WarningNameNotGiven && w != WarningColourNotGiven {
+		return
+	}
+
+	h.mu.Lock()
+	first := h.first
+	h.mu.Unlock()
+
+	r.emit(ErrHandshake{Warning: w, First: first})
+}
+
+// RobotName returns the name of the robot, as assigned by the server or as sent
+// in the first sequence, and false if it is not known yet.
+func (h *Handshake) RobotName() (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.name, h.name != ""
+}
+
+// RobotColour returns the colour of the robot, as assigned by the server or
+// as sent in the first sequence, and false if it is not known yet.
+func (h *Handshake) RobotColour() (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.colour, h.colour != ""
+}
+
+// Ready returns true if the name and the colour of the robot are known.
+func (h *Handshake) Ready() bool {
+	_, okName := h.RobotName()
+	_, okColour := h.RobotColour()
+	return okName && okColour
+}
@@ -0,0 +1,45 @@
+// Package rtbtest provides strategies shared by the tests of the module.
+package rtbtest
+
+import "github.com/jroimartin/rtb"
+
+// Turret is a strategy that rotates the robot until the radar detects a
+// robot and then shoots it.
+type Turret struct {
+	// Name is the name of the robot. If empty, no name is sent.
+	Name string
+
+	// Home and Away are the colours of the robot. If Home is empty, no
+	// colours are sent.
+	Home, Away string
+
+	// Speed is the rotation speed of the robot while looking for a
+	// target.
+	Speed float64
+
+	// Energy is the energy of the shots. If it is not positive, the
+	// robot never stops rotating.
+	Energy float64
+}
+
+// Handle implements the rtb.Strategy interface.
+func (t Turret) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if t.Name != "" {
+			r.Name(t.Name)
+		}
+		if t.Home != "" {
+			r.Colour(t.Home, t.Away)
+		}
+	case rtb.MessageGameStarts:
+		r.Rotate(rtb.PartRobot, t.Speed)
+	case rtb.MessageRadar:
+		if m.Object == rtb.ObjectRobot && t.Energy > 0 {
+			r.Rotate(rtb.PartRobot, 0)
+			r.Shoot(t.Energy)
+		} else {
+			r.Rotate(rtb.PartRobot, t.Speed)
+		}
+	}
+}
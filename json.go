@@ -0,0 +1,276 @@
+package rtb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The JSON encoding of messages and commands is a flat object. The "type"
+// key holds the protocol keyword, e.g. "Radar" or "Rotate", and the rest of
+// the keys hold the fields in protocol order. The keys of the message fields
+// are the names of the fields of the Message* types with the first letter
+// in lower case:
+//
+//	{"type":"Radar","distance":2.5,"object":0,"radarAngle":0.1}
+//
+// Enumerations, like Object or Part, are encoded as numbers, with the values
+// used by the protocol. The keys of the command arguments are listed in
+// commandArgs:
+//
+//	{"type":"Rotate","part":2,"speed":0.5}
+
+// MarshalMessageJSON returns the JSON encoding of msg. msg can be a message
+// or a pointer to a message.
+func MarshalMessageJSON(msg Message) ([]byte, error) {
+	msg = CopyMessage(msg)
+	if msg == nil || messageType(messageName(msg)) == nil {
+		return nil, fmt.Errorf("%w type %T", ErrUnknownMessage, msg)
+	}
+
+	v := reflect.ValueOf(msg)
+	b, _ := appendJSONField([]byte{'{'}, "type", messageName(msg))
+	for i := 0; i < v.NumField(); i++ {
+		var err error
+		key := lowerFirst(v.Type().Field(i).Name)
+		if b, err = appendJSONField(append(b, ','), key, v.Field(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+// UnmarshalMessageJSON parses a message encoded by MarshalMessageJSON. It
+// returns a message value, not a pointer. Missing fields are set to their
+// zero values.
+func UnmarshalMessageJSON(data []byte) (Message, error) {
+	var hdr struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &hdr); err != nil {
+		return nil, fmt.Errorf("could not unmarshal message: %v", err)
+	}
+	t := messageType(hdr.Type)
+	if t == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMessage, hdr.Type)
+	}
+
+	// The keys match the field names, because encoding/json ignores
+	// case. None of the message types has a field named Type.
+	p := reflect.New(t)
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return nil, fmt.Errorf("could not unmarshal %v: %v", hdr.Type, err)
+	}
+	return p.Elem().Interface().(Message), nil
+}
+
+// messageName returns the protocol keyword of msg, which must not be a
+// pointer.
+func messageName(msg Message) string {
+	return strings.TrimPrefix(reflect.TypeOf(msg).Name(), "Message")
+}
+
+// messageType returns the type of the messages with the protocol keyword
+// name, or nil if it is unknown.
+func messageType(name string) reflect.Type {
+	var msg Message
+	switch name {
+	case "Initialize":
+		msg = MessageInitialize{}
+	case "YourName":
+		msg = MessageYourName{}
+	case "YourColour":
+		msg = MessageYourColour{}
+	case "GameOption":
+		msg = MessageGameOption{}
+	case "GameStarts":
+		msg = MessageGameStarts{}
+	case "Radar":
+		msg = MessageRadar{}
+	case "Info":
+		msg = MessageInfo{}
+	case "Coordinates":
+		msg = MessageCoordinates{}
+	case "RobotInfo":
+		msg = MessageRobotInfo{}
+	case "RotationReached":
+		msg = MessageRotationReached{}
+	case "Energy":
+		msg = MessageEnergy{}
+	case "RobotsLeft":
+		msg = MessageRobotsLeft{}
+	case "Collision":
+		msg = MessageCollision{}
+	case "Warning":
+		msg = MessageWarning{}
+	case "Dead":
+		msg = MessageDead{}
+	case "GameFinishes":
+		msg = MessageGameFinishes{}
+	case "ExitRobot":
+		msg = MessageExitRobot{}
+	case "ServerStalled":
+		msg = MessageServerStalled{}
+	default:
+		return nil
+	}
+	return reflect.TypeOf(msg)
+}
+
+// argKind is the kind of a command argument.
+type argKind int
+
+const (
+	// argInt is an integer.
+	argInt argKind = iota
+
+	// argFloat is a floating point number.
+	argFloat
+
+	// argWord is a string without spaces.
+	argWord
+
+	// argText is a string that spans until the end of the command.
+	argText
+)
+
+// commandArg is an argument of a command.
+type commandArg struct {
+	key  string
+	kind argKind
+}
+
+// commandArgs are the arguments of the commands, in protocol order.
+var commandArgs = map[string][]commandArg{
+	"RobotOption":  {{"option", argInt}, {"value", argInt}},
+	"Name":         {{"name", argText}},
+	"Colour":       {{"home", argWord}, {"away", argWord}},
+	"Rotate":       {{"part", argInt}, {"speed", argFloat}},
+	"RotateTo":     {{"part", argInt}, {"speed", argFloat}, {"angle", argFloat}},
+	"RotateAmount": {{"part", argInt}, {"speed", argFloat}, {"angle", argFloat}},
+	"Sweep":        {{"part", argInt}, {"speed", argFloat}, {"right", argFloat}, {"left", argFloat}},
+	"Accelerate":   {{"value", argFloat}},
+	"Brake":        {{"portion", argFloat}},
+	"Shoot":        {{"energy", argFloat}},
+	"Print":        {{"message", argText}},
+	"Debug":        {{"message", argText}},
+	"DebugLine":    {{"angle1", argFloat}, {"radius1", argFloat}, {"angle2", argFloat}, {"radius2", argFloat}},
+	"DebugCircle":  {{"centerAngle", argFloat}, {"centerRadius", argFloat}, {"radius", argFloat}},
+}
+
+// MarshalCommandJSON returns the JSON encoding of the command cmd, as sent to
+// the server, e.g. "Rotate 2 0.500000".
+func MarshalCommandJSON(cmd string) ([]byte, error) {
+	keyword, rest, _ := strings.Cut(cmd, " ")
+	args, ok := commandArgs[keyword]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCommand, keyword)
+	}
+
+	b, _ := appendJSONField([]byte{'{'}, "type", keyword)
+	for i, arg := range args {
+		var field string
+		if arg.kind == argText {
+			field, rest = rest, ""
+		} else {
+			field, rest, _ = strings.Cut(strings.TrimLeft(rest, " "), " ")
+		}
+
+		var v any = field
+		switch arg.kind {
+		case argInt:
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, ErrBadField{Index: i + 1, Value: field, Err: err}
+			}
+			v = n
+		case argFloat:
+			f, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, ErrBadField{Index: i + 1, Value: field, Err: err}
+			}
+			v = f
+		}
+
+		var err error
+		if b, err = appendJSONField(append(b, ','), arg.key, v); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("%w in %q", ErrBadFieldCount, cmd)
+	}
+	return append(b, '}'), nil
+}
+
+// UnmarshalCommandJSON parses a command encoded by MarshalCommandJSON and
+// returns it as sent to the server. Floats are formatted with the default
+// float format, so the command is the same that was marshaled by a robot
+// using it.
+func UnmarshalCommandJSON(data []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("could not unmarshal command: %v", err)
+	}
+	var keyword string
+	if err := json.Unmarshal(fields["type"], &keyword); err != nil {
+		return "", fmt.Errorf("could not unmarshal command type: %v", err)
+	}
+	args, ok := commandArgs[keyword]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownCommand, keyword)
+	}
+
+	cmd := keyword
+	for _, arg := range args {
+		raw, ok := fields[arg.key]
+		if !ok {
+			return "", fmt.Errorf("%w: %v requires %q", ErrBadFieldCount, keyword, arg.key)
+		}
+
+		var err error
+		switch arg.kind {
+		case argInt:
+			var n int
+			err = json.Unmarshal(raw, &n)
+			cmd += fmt.Sprintf(" %d", n)
+		case argFloat:
+			var f float64
+			err = json.Unmarshal(raw, &f)
+			cmd += fmt.Sprintf(" %f", f)
+		case argWord, argText:
+			var s string
+			err = json.Unmarshal(raw, &s)
+			cmd += " " + s
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not unmarshal %v: %v", arg.key, err)
+		}
+	}
+	return cmd, nil
+}
+
+// appendJSONField appends the key-value pair "key":v to b.
+func appendJSONField(b []byte, key string, v any) ([]byte, error) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	val, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal %v: %v", key, err)
+	}
+	b = append(b, k...)
+	b = append(b, ':')
+	return append(b, val...), nil
+}
+
+// lowerFirst returns s with the first letter in lower case.
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
@@ -0,0 +1,174 @@
+// Package jsonstream writes and reads the traffic of a robot as a stream of
+// JSON Lines, so external dashboards, notebooks and tools written in other
+// languages can consume live or recorded games.
+//
+// Every line is an Event. Messages and commands use the encoding of
+// rtb.MarshalMessageJSON and rtb.MarshalCommandJSON:
+//
+//	{"time":0.5,"message":{"type":"Radar","distance":2,"object":0,"radarAngle":0}}
+//	{"time":0.5,"command":{"type":"Shoot","energy":1}}
+package jsonstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Event is a line of a stream. Exactly one of Message and Command is set.
+type Event struct {
+	// Time is the game time of the event. It is the time of the last
+	// Info message received before the event.
+	Time float64
+
+	// Message is the message received from the server. It is a message
+	// value, not a pointer.
+	Message rtb.Message
+
+	// Command is the command sent to the server, without the trailing
+	// newline.
+	Command string
+}
+
+// event is the JSON representation of an Event.
+type event struct {
+	Time    float64         `json:"time"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Command json.RawMessage `json:"command,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (ev Event) MarshalJSON() ([]byte, error) {
+	e := event{Time: ev.Time}
+	var err error
+	switch {
+	case ev.Message != nil && ev.Command == "":
+		e.Message, err = rtb.MarshalMessageJSON(ev.Message)
+	case ev.Message == nil && ev.Command != "":
+		e.Command, err = rtb.MarshalCommandJSON(ev.Command)
+	default:
+		err = errors.New("event must have either a message or a command")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ev *Event) UnmarshalJSON(data []byte) error {
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+
+	*ev = Event{Time: e.Time}
+	var err error
+	switch {
+	case e.Message != nil && e.Command == nil:
+		ev.Message, err = rtb.UnmarshalMessageJSON(e.Message)
+	case e.Message == nil && e.Command != nil:
+		ev.Command, err = rtb.UnmarshalCommandJSON(e.Command)
+	default:
+		err = errors.New("event must have either a message or a command")
+	}
+	return err
+}
+
+// Writer writes the traffic of a robot to an io.Writer. It implements the
+// rtb.Observer interface, so it can be attached to a robot with
+// rtb.Robot.AddObserver. Every event is written as soon as it happens, so
+// the stream can be consumed live.
+type Writer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	time float64
+	err  error
+}
+
+// NewWriter returns a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Message writes a message received from the server.
+func (w *Writer) Message(msg rtb.Message) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts, *rtb.MessageGameStarts:
+		w.time = 0
+	case rtb.MessageInfo:
+		w.time = m.Time
+	case *rtb.MessageInfo:
+		w.time = m.Time
+	}
+	w.write(Event{Time: w.time, Message: rtb.CopyMessage(msg)})
+}
+
+// Command writes a command sent to the server.
+func (w *Writer) Command(cmd string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.write(Event{Time: w.time, Command: cmd})
+}
+
+// write writes an event. w.mu must be held.
+func (w *Writer) write(ev Event) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		w.setErr(fmt.Errorf("could not marshal event: %v", err))
+		return
+	}
+	b = append(b, '\n')
+	if _, err := w.w.Write(b); err != nil {
+		w.setErr(fmt.Errorf("could not write event: %v", err))
+	}
+}
+
+// setErr sets the first error of the writer. w.mu must be held.
+func (w *Writer) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Err returns the first error found while writing. Errors do not stop the
+// writer, so the robot keeps working if the stream fails.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Reader reads the events of a stream.
+type Reader struct {
+	dec *json.Decoder
+}
+
+// NewReader returns a Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next event of the stream. It returns io.EOF at the end of
+// the stream.
+func (r *Reader) Next() (Event, error) {
+	var ev Event
+	err := r.dec.Decode(&ev)
+	if errors.Is(err, io.EOF) {
+		return Event{}, io.EOF
+	}
+	if err != nil {
+		return Event{}, fmt.Errorf("could not decode event: %v", err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,78 @@
+package jsonstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	r := rtb.NewRobot(nil, io.Discard)
+	r.AddObserver(w)
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageRadar); ok {
+			r.Shoot(1)
+		}
+	})
+
+	msgs := []rtb.Message{
+		rtb.MessageGameStarts{},
+		&rtb.MessageInfo{Time: 0.5},
+		rtb.MessageRadar{Distance: 2, Object: rtb.ObjectRobot},
+		rtb.MessageGameFinishes{},
+	}
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"time":0,"message":{"type":"GameStarts"}}
+{"time":0.5,"message":{"type":"Info","time":0.5,"speed":0,"cannonAngle":0}}
+{"time":0.5,"message":{"type":"Radar","distance":2,"object":0,"radarAngle":0}}
+{"time":0.5,"command":{"type":"Shoot","energy":1}}
+{"time":0.5,"message":{"type":"GameFinishes"}}
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("wrong stream:\ngot:\n%v\nwant:\n%v", got, want)
+	}
+
+	var events []Event
+	rd := NewReader(&buf)
+	for {
+		ev, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		events = append(events, ev)
+	}
+
+	wantEvents := []Event{
+		{Time: 0, Message: rtb.MessageGameStarts{}},
+		{Time: 0.5, Message: rtb.MessageInfo{Time: 0.5}},
+		{Time: 0.5, Message: rtb.MessageRadar{Distance: 2, Object: rtb.ObjectRobot}},
+		{Time: 0.5, Command: "Shoot 1.000000"},
+		{Time: 0.5, Message: rtb.MessageGameFinishes{}},
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("wrong events: got=%v want=%v", events, wantEvents)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	rd := NewReader(bytes.NewBufferString(`{"time":0}` + "\n"))
+	if _, err := rd.Next(); err == nil {
+		t.Errorf("expected error")
+	}
+}
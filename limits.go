@@ -0,0 +1,112 @@
+package rtb
+
+import (
+	"fmt"
+	"math"
+)
+
+// ErrOutOfRange is returned by the commands whose values exceed the limits
+// set by the game options or are not finite. Unless Sent is false, the
+// command is sent with the clamped value.
+type ErrOutOfRange struct {
+	// Command is the keyword of the command, e.g. "Rotate".
+	Command string
+
+	// Value is the requested value.
+	Value float64
+
+	// Clamped is the closest value within the limits. It is zero for
+	// values that are not finite.
+	Clamped float64
+
+	// Sent is true if the command was sent with the clamped value.
+	Sent bool
+}
+
+func (err ErrOutOfRange) Error() string {
+	if math.IsNaN(err.Value) || math.IsInf(err.Value, 0) {
+		return fmt.Sprintf("%v value out of range (%v): not sent, value is not finite", err.Command, err.Value)
+	}
+	if !err.Sent {
+		return fmt.Sprintf("%v value out of range (%v): not sent, minimum is %v", err.Command, err.Value, err.Clamped)
+	}
+	return fmt.Sprintf("%v value out of range (%v): clamped to %v", err.Command, err.Value, err.Clamped)
+}
+
+// maxRotateOption returns the game option limiting the rotation speed of a
+// single part.
+func maxRotateOption(p Part) GOption {
+	switch p {
+	case PartCannon:
+		return GOptionRobotCannonMaxRotate
+	case PartRadar:
+		return GOptionRobotRadarMaxRotate
+	default:
+		return GOptionRobotMaxRotate
+	}
+}
+
+// limitRotate limits the rotation speed v of the parts in what. If several
+// parts are rotated, v is limited by the lowest limit among them. Limits are
+// only applied once the corresponding game options are received.
+func (r *Robot) limitRotate(cmd string, what Part, v float64) (float64, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v, ErrOutOfRange{Command: cmd, Value: v}
+	}
+
+	r.mu.Lock()
+	max := math.Inf(1)
+	for _, p := range [...]Part{PartRobot, PartCannon, PartRadar} {
+		if what&p == 0 {
+			continue
+		}
+		if m, ok := r.options[maxRotateOption(p)]; ok {
+			max = math.Min(max, m)
+		}
+	}
+	r.mu.Unlock()
+
+	if math.Abs(v) <= max {
+		return v, nil
+	}
+	clamped := math.Copysign(max, v)
+	return clamped, ErrOutOfRange{Command: cmd, Value: v, Clamped: clamped, Sent: true}
+}
+
+// limitRange limits v to the range given by the game options min and max.
+// If min is negative, there is no lower limit. Limits are only applied once
+// the corresponding game options are received.
+func (r *Robot) limitRange(cmd string, v float64, min, max GOption) (float64, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return v, ErrOutOfRange{Command: cmd, Value: v}
+	}
+
+	r.mu.Lock()
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if m, ok := r.options[min]; ok && min >= 0 {
+		lo = m
+	}
+	if m, ok := r.options[max]; ok {
+		hi = m
+	}
+	r.mu.Unlock()
+
+	if v >= lo && v <= hi {
+		return v, nil
+	}
+	clamped := math.Max(lo, math.Min(v, hi))
+	return clamped, ErrOutOfRange{Command: cmd, Value: v, Clamped: clamped, Sent: true}
+}
+
+// sendLimited sends a command with a value limited by limitRotate or
+// limitRange, unless errRange reports that it must not be sent. It returns
+// the error of sending the command or, if it was sent, errRange.
+func (r *Robot) sendLimited(errRange error, format string, a ...any) error {
+	if err, ok := errRange.(ErrOutOfRange); ok && !err.Sent {
+		return err
+	}
+	if err := r.rawf(format, a...); err != nil {
+		return err
+	}
+	return errRange
+}
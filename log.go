@@ -0,0 +1,221 @@
+package rtb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SetLogger sets the logger used by r. If l is nil, the logs are discarded,
+// unless Debug is true.
+func (r *Robot) SetLogger(l *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger = l
+}
+
+// SetLogger calls SetLogger on the default Robot.
+func SetLogger(l *slog.Logger) {
+	std.SetLogger(l)
+}
+
+// Logger returns the logger of r. When called while a message is being
+// delivered, the returned logger adds the message to the records as the
+// "message" attribute.
+func (r *Robot) Logger() *slog.Logger {
+	r.mu.Lock()
+	l, cur := r.logger, r.current
+	r.mu.Unlock()
+
+	if l == nil {
+		if Debug {
+			l = slog.New(NewWindowHandler(r, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		} else {
+			l = slog.New(DiscardHandler{})
+		}
+	}
+	if cur != nil {
+		l = l.With(MessageAttr(cur))
+	}
+	return l
+}
+
+// Logger calls Logger on the default Robot.
+func Logger() *slog.Logger {
+	return std.Logger()
+}
+
+// MessageAttr returns an attribute with key "message" and the protocol
+// encoding of msg as value.
+func MessageAttr(msg Message) slog.Attr {
+	s, err := EncodeMessage(msg)
+	if err != nil {
+		s = fmt.Sprintf("%#v", msg)
+	}
+	return slog.String("message", s)
+}
+
+// CommandAttr returns an attribute with key "command" and cmd as value.
+func CommandAttr(cmd string) slog.Attr {
+	return slog.String("command", cmd)
+}
+
+// DiscardHandler is a slog.Handler that discards all the records. It is the
+// handler of the default logger of the robots when Debug is false.
+type DiscardHandler struct{}
+
+func (DiscardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (DiscardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h DiscardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h DiscardHandler) WithGroup(string) slog.Handler           { return h }
+
+// WindowHandler is a slog.Handler that sends the records to the message
+// window of the RTB server. Records with level Info or higher are sent with
+// Print and the others with Debug, which are only shown when the server runs
+// in debug mode. Records are formatted like slog.TextHandler does, without
+// the time, and truncated to fit in a message.
+type WindowHandler struct {
+	r    *Robot
+	text slog.Handler
+
+	// mu protects buf, which is shared by the handlers derived with
+	// WithAttrs and WithGroup.
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+// NewWindowHandler returns a WindowHandler that sends the records through
+// r. If r is nil, the default Robot is used. If opts is nil, the default
+// options are used.
+func NewWindowHandler(r *Robot, opts *slog.HandlerOptions) *WindowHandler {
+	if r == nil {
+		r = std
+	}
+
+	var o slog.HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	replace := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		if replace != nil {
+			return replace(groups, a)
+		}
+		return a
+	}
+
+	buf := &bytes.Buffer{}
+	return &WindowHandler{
+		r:    r,
+		text: slog.NewTextHandler(buf, &o),
+		mu:   &sync.Mutex{},
+		buf:  buf,
+	}
+}
+
+// Enabled reports whether h handles records at the given level.
+func (h *WindowHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.text.Enabled(ctx, level)
+}
+
+// Handle sends a record to the message window.
+func (h *WindowHandler) Handle(ctx context.Context, rec slog.Record) error {
+	h.mu.Lock()
+	h.buf.Reset()
+	err := h.text.Handle(ctx, rec)
+	line := strings.TrimSuffix(h.buf.String(), "\n")
+	h.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	keyword := "Debug"
+	if rec.Level >= slog.LevelInfo {
+		keyword = "Print"
+	}
+
+	// The keyword, the separator and the trailing newline must also
+	// fit in the message.
+	if max := 128 - len(keyword) - 2; len(line) > max {
+		n := max
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		if n == 0 {
+			n = max
+		}
+		line = line[:n]
+	}
+	return h.r.rawf("%v %v", keyword, line)
+}
+
+// WithAttrs returns a new WindowHandler whose records include attrs.
+func (h *WindowHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.text = h.text.WithAttrs(attrs)
+	return &h2
+}
+
+// WithGroup returns a new WindowHandler that qualifies the following
+// attributes with name.
+func (h *WindowHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.text = h.text.WithGroup(name)
+	return &h2
+}
+
+// FileHandler is a slog.Handler that writes the records as JSON lines to a
+// local file. Since the standard output of a robot is used to communicate
+// with the server, a file is the usual destination for verbose logs.
+type FileHandler struct {
+	slog.Handler
+	f *os.File
+}
+
+// NewFileHandler returns a FileHandler that writes to the file at path. The
+// file is created if it does not exist and appended otherwise. If opts is
+// nil, the default options are used. The handler must be closed when it is
+// no longer used.
+func NewFileHandler(path string, opts *slog.HandlerOptions) (*FileHandler, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log file: %v", err)
+	}
+	return &FileHandler{Handler: slog.NewJSONHandler(f, opts), f: f}, nil
+}
+
+// Close closes the underlying file.
+func (h *FileHandler) Close() error {
+	return h.f.Close()
+}
+
+// logObserver is an Observer that logs the traffic of a robot.
+type logObserver struct {
+	l *slog.Logger
+}
+
+// NewLogObserver returns an Observer that logs every message and command
+// with level Debug, using the "message" and "command" attributes. l must not
+// send its records to the message window of the observed robot, because
+// every logged command would generate a new one.
+func NewLogObserver(l *slog.Logger) Observer {
+	return logObserver{l: l}
+}
+
+func (o logObserver) Message(msg Message) {
+	o.l.Debug("message received", MessageAttr(msg))
+}
+
+func (o logObserver) Command(cmd string) {
+	o.l.Debug("command sent", CommandAttr(cmd))
+}
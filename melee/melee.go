@@ -0,0 +1,296 @@
+// Package melee implements a baseline strategy for games with many robots.
+//
+// While many robots are alive, the robot keeps the radar spinning to see all
+// of them, shoots the enemy with the lowest risk, moves to the positions
+// with the lowest risk, away from enemies and walls, and conserves energy.
+// When only two robots remain, it switches to the duel mode implemented by
+// the duel package. The switch is triggered by an rtb.Endgame hook, when the
+// number of robots left crosses Config.DuelFrom.
+package melee
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/duel"
+	"github.com/jroimartin/rtb/track"
+)
+
+// Config is the configuration of a Melee.
+type Config struct {
+	// Duel is the configuration of the duelist used when only two robots
+	// remain. Its components are also used in melee mode. Duel.Fire.Select
+	// is replaced by the target selection of the Melee.
+	Duel duel.Config
+
+	// Arena, if not nil, is the map of the arena. It is used to keep the
+	// robot away from the walls, so the robot must know its absolute
+	// position, i.e. the SendRobotCoordinates game option must be 2.
+	Arena *arena.Arena
+
+	// Weakness is the distance an enemy is considered closer per unit of
+	// energy it lacks, so weak enemies are preferred as targets. If zero,
+	// 0.2 is used.
+	Weakness float64
+
+	// Step is the distance to the candidate positions evaluated when
+	// moving. If zero, 5 is used.
+	Step float64
+
+	// Candidates is the number of candidate positions. If zero, 8 is
+	// used.
+	Candidates int
+
+	// Interval is the time between changes of position. If zero, 1 is
+	// used.
+	Interval float64
+
+	// WallWeight is the risk of being at distance 1 from a wall,
+	// compared with the risk of being at distance 1 from an enemy. If
+	// zero, 0.5 is used.
+	WallWeight float64
+
+	// DuelFrom is the number of robots left from which the duel mode is
+	// used. If zero, 2 is used.
+	DuelFrom int
+
+	// ConserveFrom is the number of robots left from which the robot
+	// conserves energy. If zero, 4 is used.
+	ConserveFrom int
+
+	// ConserveEnergy is the energy of the shots while conserving energy,
+	// as a fraction of the ShotMaxEnergy game option. If zero, 0.3 is
+	// used.
+	ConserveEnergy float64
+}
+
+// Melee is a strategy for games with many robots. It implements the
+// rtb.Strategy interface. Melee methods can be called concurrently.
+type Melee struct {
+	cfg Config
+	d   *duel.Duelist
+	eg  *rtb.Endgame
+
+	mu     sync.Mutex
+	final  bool
+	duel   bool
+	melee  bool
+	moved  float64
+	energy float64
+}
+
+// New returns a Melee for r. Like duel.New, it adds the components of the
+// strategy to r as observers.
+func New(r *rtb.Robot, cfg Config) *Melee {
+	if cfg.Weakness == 0 {
+		cfg.Weakness = 0.2
+	}
+	if cfg.Step == 0 {
+		cfg.Step = 5
+	}
+	if cfg.Candidates == 0 {
+		cfg.Candidates = 8
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 1
+	}
+	if cfg.WallWeight == 0 {
+		cfg.WallWeight = 0.5
+	}
+	if cfg.DuelFrom == 0 {
+		cfg.DuelFrom = 2
+	}
+	if cfg.ConserveFrom == 0 {
+		cfg.ConserveFrom = 4
+	}
+	if cfg.ConserveEnergy == 0 {
+		cfg.ConserveEnergy = 0.3
+	}
+	if cfg.Duel.Name == "" {
+		cfg.Duel.Name = "melee"
+	}
+
+	m := &Melee{cfg: cfg, moved: math.Inf(-1), energy: -1}
+	cfg.Duel.Fire.Select = m.Select
+	m.d = duel.New(r, cfg.Duel)
+	m.eg = &rtb.Endgame{
+		Strategy:    rtb.StrategyFunc(m.handle),
+		Thresholds:  []int{cfg.DuelFrom},
+		OnThreshold: m.endgame,
+	}
+	return m
+}
+
+// Duelist returns the duelist used by m. Its components are shared by both
+// modes.
+func (m *Melee) Duelist() *duel.Duelist {
+	return m.d
+}
+
+// Duel returns true if m is in duel mode.
+func (m *Melee) Duel() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.duel
+}
+
+// Select returns the enemy with the minimal risk to attack: the closest and
+// weakest. Team mates are ignored. It returns false if there are no enemy
+// tracks.
+func (m *Melee) Select(pos arena.Point, tracks []track.Track) (track.Track, bool) {
+	start, _ := m.d.World().Option(rtb.GOptionRobotStartEnergy)
+
+	var (
+		best      track.Track
+		bestScore float64
+		found     bool
+	)
+	for _, t := range tracks {
+		if t.TeamMate {
+			continue
+		}
+		score := t.Pos.Sub(pos).Len()
+		if start > 0 {
+			score -= m.cfg.Weakness * (start - t.Energy)
+		}
+		if !found || score < bestScore {
+			best, bestScore, found = t, score, true
+		}
+	}
+	return best, found
+}
+
+// Handle sends the name and colour of the robot when it is initialized and
+// moves it every time an Info message is received.
+func (m *Melee) Handle(r *rtb.Robot, msg rtb.Message) {
+	m.eg.Handle(r, msg)
+}
+
+// endgame enters the duel mode in the next tick. It is called by the
+// rtb.Endgame hook.
+func (m *Melee) endgame(r *rtb.Robot, threshold, left int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.final = true
+}
+
+// handle handles msg after the rtb.Endgame hook.
+func (m *Melee) handle(r *rtb.Robot, msg rtb.Message) {
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts:
+		m.mu.Lock()
+		m.final, m.duel, m.melee, m.moved, m.energy = false, false, false, math.Inf(-1), -1
+		m.mu.Unlock()
+		m.d.Radar().SetEnabled(true)
+	case rtb.MessageInfo:
+		if m.update(r) {
+			m.move(msg.Time)
+			return
+		}
+	}
+	m.d.Handle(r, msg)
+}
+
+// update selects the mode of the strategy and configures the components
+// accordingly. It returns true in melee mode.
+func (m *Melee) update(r *rtb.Robot) bool {
+	s := m.d.World().State()
+	m.mu.Lock()
+	duelMode := m.final
+	m.mu.Unlock()
+
+	energy := 0.0
+	if !duelMode && s.RobotsLeft >= m.cfg.ConserveFrom {
+		max, _ := m.d.World().Option(rtb.GOptionShotMaxEnergy)
+		energy = max * m.cfg.ConserveEnergy
+	}
+
+	m.mu.Lock()
+	enterMelee := !duelMode && !m.melee
+	enterDuel := duelMode && !m.duel
+	m.duel, m.melee = duelMode, !duelMode
+	setEnergy := energy != m.energy
+	m.energy = energy
+	m.mu.Unlock()
+
+	if setEnergy {
+		m.d.Fire().SetEnergy(energy)
+	}
+	switch {
+	case enterMelee:
+		m.d.Radar().SetEnabled(false)
+		speed, ok := m.d.World().Option(rtb.GOptionRobotRadarMaxRotate)
+		if !ok {
+			speed = math.Pi / 2
+		}
+		r.Rotate(rtb.PartRadar, speed)
+	case enterDuel:
+		m.d.Radar().SetEnabled(true)
+	}
+	return !duelMode
+}
+
+// move drives the robot to the candidate position with the lowest risk.
+func (m *Melee) move(now float64) {
+	n := m.d.Navigator()
+
+	m.mu.Lock()
+	if !n.Done() && now-m.moved < m.cfg.Interval {
+		m.mu.Unlock()
+		return
+	}
+	m.moved = now
+	m.mu.Unlock()
+
+	pos := m.d.World().State().Pos
+	var enemies []arena.Point
+	for _, t := range m.d.Tracker().Tracks() {
+		if !t.TeamMate {
+			enemies = append(enemies, t.PredictPosition(now-t.LastSeen))
+		}
+	}
+
+	best, bestRisk := pos, math.Inf(1)
+	for i := 0; i < m.cfg.Candidates; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(m.cfg.Candidates)
+		p := pos.Add(arena.Polar(angle, m.cfg.Step))
+		if risk := m.Risk(p, enemies); risk < bestRisk {
+			best, bestRisk = p, risk
+		}
+	}
+	n.GoTo(best)
+}
+
+// Risk returns the risk of being at p, given the positions of the enemies.
+// The risk of every enemy and wall is the inverse of the square of its
+// distance.
+func (m *Melee) Risk(p arena.Point, enemies []arena.Point) float64 {
+	var risk float64
+	for _, e := range enemies {
+		risk += 1 / math.Max(e.Sub(p).Dot(e.Sub(p)), 1e-6)
+	}
+	if m.cfg.Arena != nil {
+		if d := m.wallDistance(p); d <= 0 {
+			risk = math.Inf(1)
+		} else {
+			risk += m.cfg.WallWeight / (d * d)
+		}
+	}
+	return risk
+}
+
+// wallDistance returns the distance from p to the closest wall of the
+// arena, including its boundary. It is negative if p is outside the
+// boundary.
+func (m *Melee) wallDistance(p arena.Point) float64 {
+	b := m.cfg.Arena.Boundary
+	d := math.Min(math.Min(p.X-b.Min.X, b.Max.X-p.X), math.Min(p.Y-b.Min.Y, b.Max.Y-p.Y))
+	for _, s := range m.cfg.Arena.Segments() {
+		d = math.Min(d, arena.SegmentDistance(p, s.A, s.B)-s.Thickness/2)
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package melee
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+)
+
+func TestMeleeSelect(t *testing.T) {
+	tests := []struct {
+		name   string
+		tracks []track.Track
+		wantID int
+		wantOK bool
+	}{
+		{
+			"No tracks",
+			nil,
+			0,
+			false,
+		},
+		{
+			"Closest",
+			[]track.Track{
+				{ID: 1, Pos: arena.Point{X: 10}, Energy: 100},
+				{ID: 2, Pos: arena.Point{X: 5}, Energy: 100},
+			},
+			2,
+			true,
+		},
+		{
+			"Weakest",
+			[]track.Track{
+				{ID: 1, Pos: arena.Point{X: 10}, Energy: 20},
+				{ID: 2, Pos: arena.Point{X: 5}, Energy: 100},
+			},
+			1,
+			true,
+		},
+		{
+			"Team mate",
+			[]track.Track{
+				{ID: 1, Pos: arena.Point{X: 10}, Energy: 100},
+				{ID: 2, Pos: arena.Point{X: 5}, Energy: 100, TeamMate: true},
+			},
+			1,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rtb.NewRobot(nil, io.Discard)
+			m := New(r, Config{})
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			r.Deliver(nop, rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100})
+
+			got, ok := m.Select(arena.Point{}, tt.tracks)
+			if ok != tt.wantOK {
+				t.Fatalf("unexpected result: got=%v want=%v", ok, tt.wantOK)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("unexpected target: got=%v want=%v", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMeleeMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		robotsLeft int
+		wantDuel   bool
+		wantRadar  string
+	}{
+		{"Melee", 5, false, "Rotate 4 1.000000"},
+		{"Duel", 2, true, "Rotate 4 1.000000"},
+		{"Unknown", 0, false, "Rotate 4 1.000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			m := New(r, Config{})
+
+			r.Deliver(m, rtb.MessageGameOption{Option: rtb.GOptionRobotRadarMaxRotate, Value: 1})
+			r.Deliver(m, rtb.MessageGameStarts{})
+			r.Deliver(m, rtb.MessageRobotsLeft{NumRobots: tt.robotsLeft})
+			r.Deliver(m, rtb.MessageInfo{Time: 0})
+
+			if got := m.Duel(); got != tt.wantDuel {
+				t.Errorf("unexpected mode: got=%v want=%v", got, tt.wantDuel)
+			}
+			if got := m.Duelist().Radar().Enabled(); got != tt.wantDuel {
+				t.Errorf("unexpected radar lock: got=%v want=%v", got, tt.wantDuel)
+			}
+			if !strings.Contains(out.String(), tt.wantRadar) {
+				t.Errorf("radar is not spinning: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestMeleeRisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		arena   *arena.Arena
+		p       arena.Point
+		enemies []arena.Point
+		want    float64
+	}{
+		{"Enemy", nil, arena.Point{}, []arena.Point{{X: 2}}, 0.25},
+		{"Enemies", nil, arena.Point{}, []arena.Point{{X: 2}, {Y: -1}}, 1.25},
+		{"Wall", arena.Rectangle(10, 10), arena.Point{X: 1, Y: 5}, nil, 0.5 / (0.95 * 0.95)},
+		{"Outside", arena.Rectangle(10, 10), arena.Point{X: -1, Y: 5}, nil, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rtb.NewRobot(nil, io.Discard)
+			m := New(r, Config{Arena: tt.arena})
+			if got := m.Risk(tt.p, tt.enemies); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("unexpected risk: got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package mines implements a mine disposal behavior: mines detected by the
+// radar close to the path of the robot are shot before the robot runs into
+// them.
+package mines
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Disposer.
+type Config struct {
+	// Radius is the maximum distance from the robot to a mine to shoot
+	// it. If zero, 10 is used.
+	Radius float64
+
+	// MinDistance is the minimum distance from the robot to a mine to
+	// shoot it, so the robot is not hit by the explosion. If zero, 1 is
+	// used.
+	MinDistance float64
+
+	// Corridor is the maximum distance from the path of the robot to a
+	// mine to shoot it. If zero, 1.5 is used.
+	Corridor float64
+
+	// MineEnergy is the energy needed to destroy a mine. It is not sent
+	// by the server. If zero, the ShotMinEnergy game option is used.
+	MineEnergy float64
+
+	// Tolerance is the maximum difference between the cannon angle and
+	// the direction of the mine to shoot. If zero, 0.05 is used.
+	Tolerance float64
+}
+
+// Disposer shoots the mines found in the path of the robot. It implements
+// the rtb.Observer interface and must be added to the robot after the world
+// model and the energy manager. Disposer methods can be called
+// concurrently.
+type Disposer struct {
+	rtb.NopCommand
+
+	cfg Config
+	r   *rtb.Robot
+	w   *world.World
+	e   *energy.Manager
+	n   *nav.Navigator
+
+	mu     sync.Mutex
+	target *arena.Point
+	aim    float64
+}
+
+// New returns a Disposer that sends commands through r, uses w to locate the
+// mines and asks e for the shot energy. If n is not nil, the path of the
+// robot are its waypoints. Otherwise, it is the current heading.
+func New(r *rtb.Robot, w *world.World, e *energy.Manager, n *nav.Navigator, cfg Config) *Disposer {
+	if cfg.Radius == 0 {
+		cfg.Radius = 10
+	}
+	if cfg.MinDistance == 0 {
+		cfg.MinDistance = 1
+	}
+	if cfg.Corridor == 0 {
+		cfg.Corridor = 1.5
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 0.05
+	}
+	return &Disposer{cfg: cfg, r: r, w: w, e: e, n: n, aim: math.NaN()}
+}
+
+// Target returns the position of the mine being aimed at. It returns false
+// if there is no target.
+func (d *Disposer) Target() (arena.Point, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.target == nil {
+		return arena.Point{}, false
+	}
+	return *d.target, true
+}
+
+// Message selects a target when a mine is detected by the radar and aims and
+// shoots at it when msg is an Info message.
+func (d *Disposer) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		d.mu.Lock()
+		d.target, d.aim = nil, math.NaN()
+		d.mu.Unlock()
+	case rtb.MessageRadar:
+		if m.Object != rtb.ObjectMine {
+			return
+		}
+		if m.Distance > d.cfg.Radius || m.Distance < d.cfg.MinDistance {
+			return
+		}
+		p := d.w.Absolute(m.RadarAngle, m.Distance)
+		if !d.inPath(p) {
+			return
+		}
+		d.mu.Lock()
+		if d.target == nil {
+			d.target = &p
+		}
+		d.mu.Unlock()
+	case rtb.MessageInfo:
+		d.engage(m.CannonAngle)
+	}
+}
+
+// inPath returns true if p is close to the path of the robot.
+func (d *Disposer) inPath(p arena.Point) bool {
+	s := d.w.State()
+
+	var path []arena.Point
+	if d.n != nil {
+		path = d.n.Waypoints()
+	}
+	if len(path) == 0 {
+		path = []arena.Point{s.Pos.Add(arena.Polar(s.Heading, d.cfg.Radius))}
+	}
+	path = append([]arena.Point{s.Pos}, path...)
+
+	for i := 1; i < len(path); i++ {
+		if arena.SegmentDistance(p, path[i-1], path[i]) <= d.cfg.Corridor {
+			return true
+		}
+	}
+	return false
+}
+
+// engage rotates the cannon towards the target and shoots when it is
+// aligned.
+func (d *Disposer) engage(cannon float64) {
+	d.mu.Lock()
+	if d.target == nil {
+		d.mu.Unlock()
+		return
+	}
+	angle, dist := d.w.Relative(*d.target)
+	if dist < d.cfg.MinDistance || dist > d.cfg.Radius {
+		// The robot went too close or too far.
+		d.target, d.aim = nil, math.NaN()
+		d.mu.Unlock()
+		return
+	}
+
+	aligned := math.Abs(physics.NormalizeAngle(cannon-angle)) <= d.cfg.Tolerance
+	rotate := !aligned && (math.IsNaN(d.aim) || math.Abs(angle-d.aim) > d.cfg.Tolerance/2)
+	if rotate {
+		d.aim = angle
+	}
+	d.mu.Unlock()
+
+	if rotate {
+		speed, _ := d.w.Option(rtb.GOptionRobotCannonMaxRotate)
+		d.r.RotateTo(rtb.PartCannon, speed, angle)
+		return
+	}
+	if !aligned {
+		return
+	}
+
+	want := d.cfg.MineEnergy
+	if want == 0 {
+		want, _ = d.w.Option(rtb.GOptionShotMinEnergy)
+	}
+	e, ok := d.e.Shot(want, 0)
+	if !ok {
+		return
+	}
+	d.r.Shoot(e)
+
+	d.mu.Lock()
+	d.target, d.aim = nil, math.NaN()
+	d.mu.Unlock()
+}
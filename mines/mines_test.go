@@ -0,0 +1,80 @@
+package mines
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestDisposer(t *testing.T) {
+	tests := []struct {
+		name  string
+		radar rtb.MessageRadar
+		want  []string
+	}{
+		{
+			"In path",
+			rtb.MessageRadar{Distance: 5, Object: rtb.ObjectMine, RadarAngle: 0.1},
+			[]string{"RotateTo 2 1.000000 0.100000", "Shoot 0.500000"},
+		},
+		{
+			"Out of path",
+			rtb.MessageRadar{Distance: 5, Object: rtb.ObjectMine, RadarAngle: 1},
+			[]string{"", ""},
+		},
+		{
+			"Too far",
+			rtb.MessageRadar{Distance: 15, Object: rtb.ObjectMine, RadarAngle: 0},
+			[]string{"", ""},
+		},
+		{
+			"Not a mine",
+			rtb.MessageRadar{Distance: 5, Object: rtb.ObjectCookie, RadarAngle: 0},
+			[]string{"", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			w := world.New()
+			e := energy.New(energy.Config{})
+			d := New(r, w, e, nil, Config{})
+			for _, obs := range []rtb.Observer{w, e, d} {
+				r.AddObserver(obs)
+			}
+
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			msgs := []rtb.Message{
+				rtb.MessageGameOption{Option: rtb.GOptionRobotCannonMaxRotate, Value: 1},
+				rtb.MessageGameOption{Option: rtb.GOptionShotMinEnergy, Value: 0.5},
+				rtb.MessageGameOption{Option: rtb.GOptionShotMaxEnergy, Value: 30},
+				rtb.MessageGameStarts{},
+				rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0},
+				tt.radar,
+			}
+			for _, msg := range msgs {
+				r.Deliver(nop, msg)
+			}
+
+			var got []string
+			for _, cannon := range []float64{0, 0.1} {
+				out.Reset()
+				r.Deliver(nop, rtb.MessageInfo{Time: 1, CannonAngle: cannon})
+				got = append(got, strings.TrimSuffix(out.String(), "\n"))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+
+			if _, ok := d.Target(); ok {
+				t.Errorf("unexpected target after engagement")
+			}
+		})
+	}
+}
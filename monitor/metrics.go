@@ -0,0 +1,192 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the tick latency
+// histogram, in seconds.
+var latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1}
+
+// metrics are the values exported at /metrics. They are protected by the
+// lock of the Monitor.
+type metrics struct {
+	messages map[string]uint64
+	commands map[string]uint64
+
+	energy     float64
+	haveEnergy bool
+
+	// tick is the time spent by the strategy in the current turn and
+	// inTick reports whether a turn has started.
+	tick   time.Duration
+	inTick bool
+
+	// latency is the histogram of the tick latency. counts has one
+	// element per bucket plus the +Inf bucket and they are not
+	// cumulative.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// newMetrics returns empty metrics.
+func newMetrics() metrics {
+	return metrics{
+		messages: map[string]uint64{},
+		commands: map[string]uint64{},
+		counts:   make([]uint64, len(latencyBuckets)+1),
+	}
+}
+
+// messageMetrics updates the metrics with a message. m.mu must be held.
+func (m *Monitor) messageMetrics(msg rtb.Message) {
+	msg = rtb.CopyMessage(msg)
+	if msg == nil {
+		return
+	}
+	m.metrics.messages[strings.TrimPrefix(reflect.TypeOf(msg).Name(), "Message")]++
+	if e, ok := msg.(rtb.MessageEnergy); ok {
+		m.metrics.energy, m.metrics.haveEnergy = e.EnergyLevel, true
+	}
+}
+
+// commandMetrics updates the metrics with a command. m.mu must be held.
+func (m *Monitor) commandMetrics(cmd string) {
+	keyword, _, _ := strings.Cut(cmd, " ")
+	m.metrics.commands[keyword]++
+}
+
+// Instrument returns a strategy that calls s and measures the tick latency,
+// i.e. the time spent by s handling the messages of a turn. Turns start
+// with the Radar message, which the server sends every turn.
+func (m *Monitor) Instrument(s rtb.Strategy) rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		start := time.Now()
+		s.Handle(r, msg)
+		d := time.Since(start)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		switch msg.(type) {
+		case rtb.MessageRadar, *rtb.MessageRadar:
+			if m.metrics.inTick {
+				m.observeTick()
+			}
+			m.metrics.tick, m.metrics.inTick = 0, true
+		}
+		m.metrics.tick += d
+	})
+}
+
+// observeTick adds the current turn to the tick latency histogram. m.mu
+// must be held.
+func (m *Monitor) observeTick() {
+	secs := m.metrics.tick.Seconds()
+	i := sort.SearchFloat64s(latencyBuckets, secs)
+	m.metrics.counts[i]++
+	m.metrics.sum += secs
+	m.metrics.count++
+}
+
+func (m *Monitor) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeMetrics(w)
+}
+
+// writeMetrics writes the metrics in the Prometheus text exposition format.
+func (m *Monitor) writeMetrics(w io.Writer) {
+	m.mu.Lock()
+	mt := m.metrics
+	messages := sortedCounts(mt.messages)
+	commands := sortedCounts(mt.commands)
+	counts := append([]uint64(nil), mt.counts...)
+	gameTime := m.time
+	m.mu.Unlock()
+
+	header(w, "rtb_messages_total", "counter", "Messages received from the server.")
+	for _, c := range messages {
+		fmt.Fprintf(w, "rtb_messages_total{type=%q} %v\n", c.name, c.n)
+	}
+	header(w, "rtb_commands_total", "counter", "Commands sent to the server.")
+	for _, c := range commands {
+		fmt.Fprintf(w, "rtb_commands_total{type=%q} %v\n", c.name, c.n)
+	}
+
+	header(w, "rtb_game_time_seconds", "gauge", "Game time of the last Info message.")
+	fmt.Fprintf(w, "rtb_game_time_seconds %v\n", formatFloat(gameTime))
+	if mt.haveEnergy {
+		header(w, "rtb_energy", "gauge", "Energy level of the robot.")
+		fmt.Fprintf(w, "rtb_energy %v\n", formatFloat(mt.energy))
+	}
+
+	if r := m.cfg.Robot; r != nil {
+		header(w, "rtb_parse_errors_total", "counter", "Lines received that could not be parsed.")
+		fmt.Fprintf(w, "rtb_parse_errors_total %v\n", r.ParseErrors())
+		header(w, "rtb_messages_dropped_total", "counter", "Messages dropped by the overflow policy.")
+		fmt.Fprintf(w, "rtb_messages_dropped_total %v\n", r.Dropped())
+	}
+
+	header(w, "rtb_tick_latency_seconds", "histogram", "Time spent by the strategy handling the messages of a turn.")
+	var cum uint64
+	for i, le := range latencyBuckets {
+		cum += counts[i]
+		fmt.Fprintf(w, "rtb_tick_latency_seconds_bucket{le=%q} %v\n", formatFloat(le), cum)
+	}
+	cum += counts[len(latencyBuckets)]
+	fmt.Fprintf(w, "rtb_tick_latency_seconds_bucket{le=\"+Inf\"} %v\n", cum)
+	fmt.Fprintf(w, "rtb_tick_latency_seconds_sum %v\n", formatFloat(mt.sum))
+	fmt.Fprintf(w, "rtb_tick_latency_seconds_count %v\n", mt.count)
+
+	// The gauges are called without holding the lock, because they
+	// could be slow or call back into the robot.
+	var names []string
+	for name := range m.cfg.Gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(w, name, "gauge", "")
+		fmt.Fprintf(w, "%v %v\n", name, formatFloat(m.cfg.Gauges[name]()))
+	}
+}
+
+// header writes the HELP and TYPE lines of a metric. The HELP line is
+// omitted if help is empty.
+func header(w io.Writer, name, typ, help string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	}
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, typ)
+}
+
+// count is a labeled counter.
+type count struct {
+	name string
+	n    uint64
+}
+
+// sortedCounts returns the counters of c sorted by name.
+func sortedCounts(c map[string]uint64) []count {
+	var s []count
+	for name, n := range c {
+		s = append(s, count{name, n})
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].name < s[j].name })
+	return s
+}
+
+// formatFloat formats v as expected by Prometheus.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,231 @@
+// Package monitor serves the internal state of a running robot over HTTP, so
+// it can be watched in a browser during a real match, and its metrics in the
+// Prometheus text exposition format, for long automated tournaments.
+//
+// The monitor is opt-in: robots that do not create one do not open any port.
+// Since the standard output of a robot is used to communicate with the
+// server, the monitor never writes to it.
+//
+//	m := monitor.New(monitor.Config{
+//		Sections: map[string]func() any{
+//			"world": func() any { return w.State() },
+//			"stats": func() any { return c.Current() },
+//		},
+//	})
+//	r.AddObserver(m)
+//	srv, err := monitor.Start("localhost:8080", m)
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Config is the configuration of a Monitor.
+type Config struct {
+	// Sections are the values served, by name. The functions are called
+	// on every request, concurrently with the robot, so they must be
+	// safe for concurrent use, like world.World.State and
+	// stats.Collector.Current.
+	Sections map[string]func() any
+
+	// Decisions is the number of recent commands kept. If zero, 50 is
+	// used.
+	Decisions int
+
+	// Robot, if not nil, is the monitored robot. It is used to export
+	// the parse errors and the dropped messages.
+	Robot *rtb.Robot
+
+	// Gauges are additional metrics, by metric name, e.g.
+	// "rtb_enemies_tracked". Like Sections, the functions are called
+	// on every request and must be safe for concurrent use.
+	Gauges map[string]func() float64
+}
+
+// Decision is a command sent by the robot.
+type Decision struct {
+	// Time is the game time of the last Info message received before
+	// the command.
+	Time float64 `json:"time"`
+
+	// Command is the command, without the trailing newline.
+	Command string `json:"command"`
+}
+
+// Monitor serves the state of a robot over HTTP. It implements the
+// rtb.Observer interface, to keep the recent decisions of the robot, and the
+// http.Handler interface. The following paths are served:
+//
+//	/            a minimal HTML view that refreshes itself
+//	/state.json  the game time, the sections and the recent decisions
+//	/metrics     metrics in the Prometheus text exposition format
+//
+// The tick latency is only measured for strategies wrapped with Instrument.
+type Monitor struct {
+	cfg Config
+	mux *http.ServeMux
+
+	mu        sync.Mutex
+	time      float64
+	decisions []Decision
+	metrics   metrics
+}
+
+// New returns a Monitor with the given configuration.
+func New(cfg Config) *Monitor {
+	if cfg.Decisions == 0 {
+		cfg.Decisions = 50
+	}
+
+	m := &Monitor{cfg: cfg, mux: http.NewServeMux(), metrics: newMetrics()}
+	m.mux.HandleFunc("/", m.serveIndex)
+	m.mux.HandleFunc("/state.json", m.serveState)
+	m.mux.HandleFunc("/metrics", m.serveMetrics)
+	return m
+}
+
+// Message keeps the game time and updates the metrics.
+func (m *Monitor) Message(msg rtb.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messageMetrics(msg)
+
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts, *rtb.MessageGameStarts:
+		m.time = 0
+		m.decisions = nil
+	case rtb.MessageInfo:
+		m.time = msg.Time
+	case *rtb.MessageInfo:
+		m.time = msg.Time
+	}
+}
+
+// Command keeps the command as a recent decision and updates the metrics.
+func (m *Monitor) Command(cmd string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.commandMetrics(cmd)
+
+	if len(m.decisions) == m.cfg.Decisions {
+		m.decisions = m.decisions[1:]
+	}
+	m.decisions = append(m.decisions, Decision{Time: m.time, Command: cmd})
+}
+
+// Decisions returns the recent decisions, from oldest to newest.
+func (m *Monitor) Decisions() []Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Decision(nil), m.decisions...)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// state is the document served at /state.json.
+type state struct {
+	Time      float64        `json:"time"`
+	Sections  map[string]any `json:"sections"`
+	Decisions []Decision     `json:"decisions"`
+}
+
+func (m *Monitor) serveState(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	st := state{
+		Time:      m.time,
+		Sections:  map[string]any{},
+		Decisions: append([]Decision{}, m.decisions...),
+	}
+	m.mu.Unlock()
+
+	// The sections are called without holding the lock, because they
+	// could be slow or call back into the robot.
+	for name, f := range m.cfg.Sections {
+		st.Sections[name] = f()
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not marshal state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (m *Monitor) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+// indexHTML is the HTML view. It polls /state.json twice per second.
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>rtb monitor</title>
+<style>
+body { font-family: monospace; margin: 1em; }
+pre { background: #f4f4f4; padding: 0.5em; }
+</style>
+</head>
+<body>
+<h1>rtb monitor</h1>
+<p>Time: <span id="time">-</span></p>
+<div id="sections"></div>
+<h2>Recent decisions</h2>
+<pre id="decisions"></pre>
+<script>
+async function refresh() {
+	try {
+		const st = await (await fetch("state.json")).json();
+		document.getElementById("time").textContent = st.time;
+		const sections = document.getElementById("sections");
+		sections.textContent = "";
+		for (const name of Object.keys(st.sections).sort()) {
+			const h = document.createElement("h2");
+			h.textContent = name;
+			const pre = document.createElement("pre");
+			pre.textContent = JSON.stringify(st.sections[name], null, 2);
+			sections.append(h, pre);
+		}
+		document.getElementById("decisions").textContent =
+			st.decisions.map(d => d.time.toFixed(2) + " " + d.command).reverse().join("\n");
+	} catch (e) {
+	}
+	setTimeout(refresh, 500);
+}
+refresh();
+</script>
+</body>
+</html>
+`
+
+// Start serves h at the TCP address addr in a new goroutine, usually a
+// Monitor. It returns the server, which must be closed when the robot
+// exits.
+func Start(addr string, h http.Handler) (*http.Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen: %v", err)
+	}
+	srv := &http.Server{Handler: h}
+	go srv.Serve(l)
+	return srv, nil
+}
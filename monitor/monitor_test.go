@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestMonitor(t *testing.T) {
+	n := 0
+	m := New(Config{
+		Sections:  map[string]func() any{"counter": func() any { n++; return map[string]int{"n": n} }},
+		Decisions: 2,
+	})
+
+	r := rtb.NewRobot(nil, io.Discard)
+	r.AddObserver(m)
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		switch msg.(type) {
+		case rtb.MessageInfo, *rtb.MessageInfo:
+			r.Shoot(1)
+		}
+	})
+	for _, msg := range []rtb.Message{
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 0.5},
+		rtb.MessageInfo{Time: 1},
+		&rtb.MessageInfo{Time: 1.5},
+	} {
+		r.Deliver(s, msg)
+	}
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/state.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got struct {
+		Time      float64
+		Sections  map[string]map[string]int
+		Decisions []Decision
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("could not decode state: %v", err)
+	}
+	if got.Time != 1.5 {
+		t.Errorf("wrong time: got=%v want=%v", got.Time, 1.5)
+	}
+	if got.Sections["counter"]["n"] != 1 {
+		t.Errorf("wrong sections: got=%v", got.Sections)
+	}
+	want := []Decision{{1, "Shoot 1.000000"}, {1.5, "Shoot 1.000000"}}
+	if !reflect.DeepEqual(got.Decisions, want) {
+		t.Errorf("wrong decisions: got=%v want=%v", got.Decisions, want)
+	}
+
+	resp, err = http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "state.json") {
+		t.Errorf("unexpected index: %s", body)
+	}
+
+	resp, err = http.Get(srv.URL + "/foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("wrong status: got=%v want=%v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	r := rtb.NewRobot(nil, nil)
+	m := New(Config{
+		Robot:  r,
+		Gauges: map[string]func() float64{"rtb_enemies_tracked": func() float64 { return 2 }},
+	})
+	r.AddObserver(m)
+
+	settings := rtb.ListenSettings{
+		Input:  strings.NewReader("Foo\nGameStarts\nRadar 1 0 0\nInfo 1 0 0\nEnergy 90\nRadar 1 0 0\nExitRobot\n"),
+		Output: io.Discard,
+	}
+	r.Run(settings, m.Instrument(rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageRadar); ok {
+			r.Shoot(1)
+		}
+	})))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`rtb_messages_total{type="Radar"} 2`,
+		`rtb_messages_total{type="Info"} 1`,
+		`rtb_commands_total{type="Shoot"} 2`,
+		`rtb_commands_total{type="RobotOption"} 2`,
+		"rtb_game_time_seconds 1\n",
+		"rtb_energy 90\n",
+		"rtb_parse_errors_total 1\n",
+		"rtb_messages_dropped_total 0\n",
+		"rtb_tick_latency_seconds_count 1\n",
+		`rtb_tick_latency_seconds_bucket{le="+Inf"} 1`,
+		"# TYPE rtb_enemies_tracked gauge\nrtb_enemies_tracked 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in metrics:\n%v", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("wrong content type: %v", ct)
+	}
+}
@@ -0,0 +1,135 @@
+package rtb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultNameTemplate is the template used by SetName if the template is
+// empty.
+const DefaultNameTemplate = "{base} v{version} Team: {team}"
+
+// teamMarker is the marker that assigns a robot to a team when it is part of
+// its name.
+const teamMarker = "Team:"
+
+// maxNameLength is the maximum length of a name, so the Name command fits in
+// a message.
+const maxNameLength = 128 - len("Name") - 2
+
+// NameParts are the components of a robot name.
+type NameParts struct {
+	// Base is the name of the robot without metadata.
+	Base string
+
+	// Version is the version of the robot.
+	Version string
+
+	// Team is the team of the robot. If empty, the robot is not in a
+	// team.
+	Team string
+}
+
+// FormatName expands the placeholders {base}, {version} and {team} of tmpl
+// with the parts p. The words of tmpl with placeholders whose values are
+// empty are removed, so "{base} v{version}" becomes "foo" if the version is
+// empty. Everything after the "Team:" marker is only kept if p.Team is not
+// empty, because the server would assign the robot to a team otherwise.
+//
+// If the name does not fit in a message, the part before the "Team:" marker
+// is truncated, so the robot stays in its team. It returns an error wrapping
+// ErrMessageTooLong if the team does not fit either, and an error if the
+// base name or the version contain the "Team:" marker.
+func FormatName(tmpl string, p NameParts) (string, error) {
+	if strings.Contains(p.Base, teamMarker) || strings.Contains(p.Version, teamMarker) {
+		return "", fmt.Errorf("invalid name parts: %q is reserved", teamMarker)
+	}
+
+	head, tail, hasTeam := strings.Cut(tmpl, teamMarker)
+	name := expandName(head, p)
+	if !hasTeam || p.Team == "" {
+		return truncateName(name, 0), nil
+	}
+
+	suffix := teamMarker + " " + expandName(tail, p)
+	if len(suffix) > maxNameLength {
+		return "", fmt.Errorf("%w: no room for %q", ErrMessageTooLong, suffix)
+	}
+	if name == "" {
+		return suffix, nil
+	}
+	return truncateName(name, len(suffix)+1) + " " + suffix, nil
+}
+
+// expandName expands the placeholders of tmpl, removing the words whose
+// placeholders are empty.
+func expandName(tmpl string, p NameParts) string {
+	r := strings.NewReplacer("{base}", p.Base, "{version}", p.Version, "{team}", p.Team)
+	var words []string
+	for _, w := range strings.Fields(tmpl) {
+		if (strings.Contains(w, "{base}") && p.Base == "") ||
+			(strings.Contains(w, "{version}") && p.Version == "") ||
+			(strings.Contains(w, "{team}") && p.Team == "") {
+			continue
+		}
+		words = append(words, r.Replace(w))
+	}
+	return strings.Join(words, " ")
+}
+
+// truncateName truncates name so it fits in a message together with reserve
+// more bytes.
+func truncateName(name string, reserve int) string {
+	max := maxNameLength - reserve
+	if len(name) <= max {
+		return name
+	}
+	for max > 0 && !utf8.RuneStart(name[max]) {
+		max--
+	}
+	return strings.TrimRightFunc(name[:max], unicode.IsSpace)
+}
+
+// ParseName returns the components of a name formatted with
+// DefaultNameTemplate, like the one received with MessageYourName. The
+// version is the last word of the name before the team that starts with "v"
+// followed by a digit.
+func ParseName(name string) NameParts {
+	var p NameParts
+
+	head, team, ok := strings.Cut(name, teamMarker)
+	if ok {
+		p.Team = strings.TrimSpace(team)
+	}
+
+	words := strings.Fields(head)
+	if n := len(words); n > 1 {
+		w := words[n-1]
+		if len(w) > 1 && w[0] == 'v' && w[1] >= '0' && w[1] <= '9' {
+			p.Version = w[1:]
+			words = words[:n-1]
+		}
+	}
+	p.Base = strings.Join(words, " ")
+	return p
+}
+
+// SetName formats the name of the robot with FormatName and sends it with
+// Name. If tmpl is empty, DefaultNameTemplate is used.
+func (r *Robot) SetName(tmpl string, p NameParts) error {
+	if tmpl == "" {
+		tmpl = DefaultNameTemplate
+	}
+	name, err := FormatName(tmpl, p)
+	if err != nil {
+		return fmt.Errorf("could not format name: %w", err)
+	}
+	return r.Name(name)
+}
+
+// SetName calls SetName on the default Robot.
+func SetName(tmpl string, p NameParts) error {
+	return std.SetName(tmpl, p)
+}
@@ -0,0 +1,209 @@
+// Package nav implements a navigator that drives a robot through a list of
+// waypoints.
+package nav
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Navigator.
+type Config struct {
+	// Tolerance is the distance at which a waypoint is considered
+	// reached. If zero, 1 is used.
+	Tolerance float64
+
+	// Acceleration is the acceleration used to move towards the
+	// waypoints. If zero, the RobotMaxAcceleration game option is used.
+	Acceleration float64
+
+	// TurnGain is the rotation speed, in radians/s, per radian of
+	// heading error. The rotation speed is limited by the RobotMaxRotate
+	// game option. If zero, 2 is used.
+	TurnGain float64
+
+	// BrakeDistance is the distance to the last waypoint at which the
+	// robot starts braking. If zero, 2 is used.
+	BrakeDistance float64
+}
+
+// Navigator drives a robot through a list of waypoints, given in world
+// coordinates. It implements the rtb.Observer interface and steers the robot
+// every time an Info message is received. It must be added to the robot
+// after the world model. Navigator methods can be called concurrently.
+type Navigator struct {
+	rtb.NopCommand
+
+	cfg Config
+	r   *rtb.Robot
+	w   *world.World
+
+	mu        sync.Mutex
+	waypoints []arena.Point
+
+	// rotate, accel and brake are the last values sent, so commands
+	// are only sent when they change.
+	rotate, accel, brake float64
+	sent                 bool
+
+	// suspended is true if steering is suspended.
+	suspended bool
+}
+
+// New returns a Navigator that sends commands through r and uses w to know
+// the position of the robot.
+func New(r *rtb.Robot, w *world.World, cfg Config) *Navigator {
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 1
+	}
+	if cfg.TurnGain == 0 {
+		cfg.TurnGain = 2
+	}
+	if cfg.BrakeDistance == 0 {
+		cfg.BrakeDistance = 2
+	}
+	return &Navigator{cfg: cfg, r: r, w: w}
+}
+
+// World returns the world model used by n.
+func (n *Navigator) World() *world.World {
+	return n.w
+}
+
+// GoTo replaces the planned waypoints.
+func (n *Navigator) GoTo(waypoints ...arena.Point) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.waypoints = append([]arena.Point(nil), waypoints...)
+}
+
+// Add appends a waypoint to the plan.
+func (n *Navigator) Add(p arena.Point) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.waypoints = append(n.waypoints, p)
+}
+
+// Stop discards the planned waypoints. The robot brakes in the next tick.
+func (n *Navigator) Stop() {
+	n.GoTo()
+}
+
+// Waypoints returns the planned waypoints. The first one is the current
+// target.
+func (n *Navigator) Waypoints() []arena.Point {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]arena.Point(nil), n.waypoints...)
+}
+
+// SetSuspended suspends or resumes steering. While suspended, the navigator
+// does not send any command but keeps its waypoints, so other components can
+// take control of the robot temporarily.
+func (n *Navigator) SetSuspended(suspended bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.suspended = suspended
+
+	// Other components could have changed the commands, so all of them
+	// are sent again when resuming.
+	n.sent = false
+}
+
+// Suspended returns true if steering is suspended.
+func (n *Navigator) Suspended() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.suspended
+}
+
+// Done returns true if there are no waypoints left.
+func (n *Navigator) Done() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.waypoints) == 0
+}
+
+// Message steers the robot when msg is an Info message. The plan is
+// discarded when a new game starts.
+func (n *Navigator) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		n.mu.Lock()
+		n.waypoints, n.sent = nil, false
+		n.mu.Unlock()
+	case rtb.MessageInfo:
+		n.Steer()
+	}
+}
+
+// Steer sends the commands needed to move towards the current waypoint. It is
+// called automatically when an Info message is received.
+func (n *Navigator) Steer() {
+	n.mu.Lock()
+	if n.suspended {
+		n.mu.Unlock()
+		return
+	}
+
+	for len(n.waypoints) > 0 {
+		if _, dist := n.w.Relative(n.waypoints[0]); dist > n.cfg.Tolerance {
+			break
+		}
+		n.waypoints = n.waypoints[1:]
+	}
+
+	var rotate, accel, brake float64
+	if len(n.waypoints) == 0 {
+		brake = 1
+	} else {
+		angle, _ := n.w.Relative(n.waypoints[0])
+		rotate = angle * n.cfg.TurnGain
+		if max, ok := n.w.Option(rtb.GOptionRobotMaxRotate); ok {
+			rotate = math.Max(-max, math.Min(rotate, max))
+		}
+
+		// Only accelerate when the robot is roughly facing the
+		// target, so it does not orbit around it.
+		if math.Abs(angle) < math.Pi/4 {
+			accel = n.cfg.Acceleration
+			if accel == 0 {
+				accel, _ = n.w.Option(rtb.GOptionRobotMaxAcceleration)
+			}
+		}
+
+		last := n.waypoints[len(n.waypoints)-1]
+		if _, dist := n.w.Relative(last); dist < n.cfg.BrakeDistance {
+			accel, brake = 0, 1
+		}
+	}
+
+	first := !n.sent
+	sendRotate := first || rotate != n.rotate
+	sendAccel := first || accel != n.accel
+	sendBrake := first || brake != n.brake
+	n.rotate, n.accel, n.brake, n.sent = rotate, accel, brake, true
+	n.mu.Unlock()
+
+	// Commands are sent without holding the lock, because observers
+	// could call back the navigator.
+	if sendRotate {
+		n.r.Rotate(rtb.PartRobot, rotate)
+	}
+	if sendAccel {
+		n.r.Accelerate(accel)
+	}
+	if sendBrake {
+		n.r.Brake(brake)
+	}
+}
@@ -0,0 +1,124 @@
+package nav
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestNavigator(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	n := New(r, w, Config{})
+	r.AddObserver(w)
+	r.AddObserver(n)
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	deliver := func(msgs ...rtb.Message) []string {
+		out.Reset()
+		for _, msg := range msgs {
+			r.Deliver(nop, msg)
+		}
+		return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	}
+
+	deliver(
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxRotate, Value: 0.5},
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxAcceleration, Value: 2},
+		rtb.MessageGameStarts{},
+	)
+	n.GoTo(arena.Point{X: 10, Y: 0}, arena.Point{X: 10, Y: 10})
+
+	tests := []struct {
+		name string
+		msgs []rtb.Message
+		want []string
+	}{
+		{
+			"Start",
+			[]rtb.Message{
+				rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0.1},
+				rtb.MessageInfo{Time: 1},
+			},
+			[]string{"Rotate 1 -0.200000", "Accelerate 2.000000", "Brake 0.000000"},
+		},
+		{
+			"Unchanged",
+			[]rtb.Message{
+				rtb.MessageCoordinates{X: 1, Y: 0, Angle: 0.1},
+				rtb.MessageInfo{Time: 2},
+			},
+			[]string{""},
+		},
+		{
+			"Turn",
+			[]rtb.Message{
+				rtb.MessageCoordinates{X: 9.5, Y: 0, Angle: 0},
+				rtb.MessageInfo{Time: 3},
+			},
+			[]string{"Rotate 1 0.500000", "Accelerate 0.000000"},
+		},
+		{
+			"Arrive",
+			[]rtb.Message{
+				rtb.MessageCoordinates{X: 10, Y: 9, Angle: 1.5707963267948966},
+				rtb.MessageInfo{Time: 4},
+			},
+			[]string{"Rotate 1 0.000000", "Brake 1.000000"},
+		},
+		{
+			"Done",
+			[]rtb.Message{
+				rtb.MessageCoordinates{X: 10, Y: 9.5, Angle: 1.5707963267948966},
+				rtb.MessageInfo{Time: 5},
+			},
+			[]string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		got := deliver(tt.msgs...)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%v: unexpected commands: got=%q want=%q", tt.name, got, tt.want)
+		}
+	}
+
+	if !n.Done() {
+		t.Errorf("unexpected waypoints: %v", n.Waypoints())
+	}
+}
+
+func TestNavigatorSuspended(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	n := New(r, w, Config{Acceleration: 1})
+	r.AddObserver(w)
+	r.AddObserver(n)
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	n.GoTo(arena.Point{X: 10, Y: 0})
+	r.Deliver(nop, rtb.MessageInfo{Time: 1})
+
+	n.SetSuspended(true)
+	out.Reset()
+	r.Deliver(nop, rtb.MessageInfo{Time: 2})
+	if out.Len() != 0 {
+		t.Errorf("unexpected commands while suspended: %q", out.String())
+	}
+
+	// All the commands are sent again after resuming.
+	n.SetSuspended(false)
+	out.Reset()
+	r.Deliver(nop, rtb.MessageInfo{Time: 3})
+	want := "Rotate 1 0.000000\nAccelerate 1.000000\nBrake 0.000000\n"
+	if out.String() != want {
+		t.Errorf("unexpected commands after resuming: got=%q want=%q", out.String(), want)
+	}
+}
@@ -0,0 +1,387 @@
+// Package onnx runs policies trained outside Go, e.g. with the gym package
+// and a Python reinforcement learning framework, as normal strategies.
+//
+// The models are read from ONNX files and evaluated in pure Go, so robots do
+// not need cgo or a machine learning runtime. Only the operators used by
+// small fully connected networks are supported: Gemm, MatMul, Add, Sub, Mul,
+// Div, Relu, LeakyRelu, Tanh, Sigmoid and Identity, with float or double
+// tensors. TensorFlow Lite models can be converted to ONNX with tf2onnx.
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/gym"
+)
+
+// Model is an ONNX model with a single input and a single output.
+type Model struct {
+	g      *graph
+	input  string
+	output string
+}
+
+// Load reads the model in the ONNX file at path.
+func Load(path string) (*Model, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read model: %v", err)
+	}
+	return Parse(b)
+}
+
+// Parse parses an ONNX model.
+func Parse(b []byte) (*Model, error) {
+	g, err := parseModel(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse model: %v", err)
+	}
+
+	m := &Model{g: g}
+
+	// Older versions of ONNX list the initializers as inputs too.
+	var inputs []string
+	for _, in := range g.inputs {
+		if _, ok := g.initializers[in]; !ok {
+			inputs = append(inputs, in)
+		}
+	}
+	if len(inputs) != 1 || len(g.outputs) != 1 {
+		return nil, fmt.Errorf("model must have one input and one output, got %v and %v", len(inputs), len(g.outputs))
+	}
+	m.input, m.output = inputs[0], g.outputs[0]
+
+	for _, n := range g.nodes {
+		if _, ok := ops[n.op]; !ok {
+			return nil, fmt.Errorf("unsupported operator %q", n.op)
+		}
+	}
+	return m, nil
+}
+
+// Run evaluates the model with the input in. The input is passed as a tensor
+// with shape [1, len(in)], i.e. a batch of size one. The output tensor is
+// returned flattened.
+func (m *Model) Run(in []float64) ([]float64, error) {
+	values := make(map[string]*tensor, len(m.g.initializers)+len(m.g.nodes)+1)
+	for name, t := range m.g.initializers {
+		values[name] = t
+	}
+	values[m.input] = &tensor{shape: []int{1, len(in)}, data: in}
+
+	for _, n := range m.g.nodes {
+		args := make([]*tensor, len(n.inputs))
+		for i, name := range n.inputs {
+			// Optional inputs that are omitted have an empty name.
+			if name == "" {
+				continue
+			}
+			t, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("node %q: unknown input %q", n.name, name)
+			}
+			args[i] = t
+		}
+		out, err := ops[n.op](n, args)
+		if err != nil {
+			return nil, fmt.Errorf("node %q (%v): %v", n.name, n.op, err)
+		}
+		if len(n.outputs) == 0 {
+			return nil, fmt.Errorf("node %q has no outputs", n.name)
+		}
+		values[n.outputs[0]] = out
+	}
+
+	out, ok := values[m.output]
+	if !ok {
+		return nil, fmt.Errorf("output %q not computed", m.output)
+	}
+	return append([]float64(nil), out.data...), nil
+}
+
+// Act returns the output of the model for the observation obs. It implements
+// the gym.Policy interface. If the model cannot be evaluated, it returns a
+// nil action, which is rejected by the action decoder.
+func (m *Model) Act(obs gym.Observation) gym.Action {
+	out, err := m.Run(obs)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// NewStrategy loads the model at path and returns a strategy that runs it as
+// a policy, using the encodings enc and dec. See gym.NewStrategy.
+func NewStrategy(path string, enc gym.ObservationEncoder, dec gym.ActionDecoder) (rtb.Strategy, error) {
+	m, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return gym.NewStrategy(m, enc, dec), nil
+}
+
+// graph is a decoded GraphProto.
+type graph struct {
+	nodes        []node
+	initializers map[string]*tensor
+	inputs       []string
+	outputs      []string
+}
+
+// node is a decoded NodeProto.
+type node struct {
+	name    string
+	op      string
+	inputs  []string
+	outputs []string
+	attrs   map[string]attr
+}
+
+// attr is a numeric attribute of a node.
+type attr struct {
+	f float64
+	i int64
+}
+
+// float returns the float attribute name or def if it is not set.
+func (n node) float(name string, def float64) float64 {
+	if a, ok := n.attrs[name]; ok {
+		return a.f
+	}
+	return def
+}
+
+// int returns the int attribute name or def if it is not set.
+func (n node) int(name string, def int64) int64 {
+	if a, ok := n.attrs[name]; ok {
+		return a.i
+	}
+	return def
+}
+
+// tensor is a dense tensor in row-major order.
+type tensor struct {
+	shape []int
+	data  []float64
+}
+
+// size returns the number of values of a tensor with the shape of t.
+func (t *tensor) size() int {
+	n := 1
+	for _, d := range t.shape {
+		n *= d
+	}
+	return n
+}
+
+// matrix returns the dimensions of t as a matrix. Vectors are rows.
+func (t *tensor) matrix() (rows, cols int, err error) {
+	switch len(t.shape) {
+	case 1:
+		return 1, t.shape[0], nil
+	case 2:
+		return t.shape[0], t.shape[1], nil
+	}
+	return 0, 0, fmt.Errorf("expected matrix, got shape %v", t.shape)
+}
+
+// op evaluates an operator.
+type op func(n node, args []*tensor) (*tensor, error)
+
+// ops are the supported operators.
+var ops = map[string]op{
+	"Gemm":   gemm,
+	"MatMul": matMul,
+	"Add":    elementwise(func(a, b float64) float64 { return a + b }),
+	"Sub":    elementwise(func(a, b float64) float64 { return a - b }),
+	"Mul":    elementwise(func(a, b float64) float64 { return a * b }),
+	"Div":    elementwise(func(a, b float64) float64 { return a / b }),
+	"Relu":   unary(func(n node, x float64) float64 { return math.Max(x, 0) }),
+	"LeakyRelu": unary(func(n node, x float64) float64 {
+		if x < 0 {
+			return n.float("alpha", 0.01) * x
+		}
+		return x
+	}),
+	"Tanh":     unary(func(n node, x float64) float64 { return math.Tanh(x) }),
+	"Sigmoid":  unary(func(n node, x float64) float64 { return 1 / (1 + math.Exp(-x)) }),
+	"Identity": unary(func(n node, x float64) float64 { return x }),
+}
+
+// errArgs is returned when an operator is called with missing arguments.
+var errArgs = errors.New("missing arguments")
+
+// unary returns an operator that applies f to every value of its argument.
+func unary(f func(n node, x float64) float64) op {
+	return func(n node, args []*tensor) (*tensor, error) {
+		if len(args) < 1 || args[0] == nil {
+			return nil, errArgs
+		}
+		x := args[0]
+		out := &tensor{shape: x.shape, data: make([]float64, len(x.data))}
+		for i, v := range x.data {
+			out.data[i] = f(n, v)
+		}
+		return out, nil
+	}
+}
+
+// elementwise returns an operator that applies f to the values of its
+// arguments, with multidirectional broadcasting.
+func elementwise(f func(a, b float64) float64) op {
+	return func(n node, args []*tensor) (*tensor, error) {
+		if len(args) < 2 || args[0] == nil || args[1] == nil {
+			return nil, errArgs
+		}
+		return broadcast(args[0], args[1], f)
+	}
+}
+
+// broadcast applies f to the values of a and b, broadcasting them to a common
+// shape like NumPy does.
+func broadcast(a, b *tensor, f func(a, b float64) float64) (*tensor, error) {
+	rank := max(len(a.shape), len(b.shape))
+	shape := make([]int, rank)
+	sa, sb := strides(a.shape, rank), strides(b.shape, rank)
+	for i := range shape {
+		da, db := dim(a.shape, rank, i), dim(b.shape, rank, i)
+		switch {
+		case da == db || db == 1:
+			shape[i] = da
+		case da == 1:
+			shape[i] = db
+		default:
+			return nil, fmt.Errorf("shapes %v and %v cannot be broadcast", a.shape, b.shape)
+		}
+		// Broadcast dimensions do not advance the offset.
+		if da == 1 {
+			sa[i] = 0
+		}
+		if db == 1 {
+			sb[i] = 0
+		}
+	}
+
+	out := &tensor{shape: shape}
+	out.data = make([]float64, out.size())
+	idx := make([]int, rank)
+	for i := range out.data {
+		oa, ob := 0, 0
+		for j, k := range idx {
+			oa += k * sa[j]
+			ob += k * sb[j]
+		}
+		out.data[i] = f(a.data[oa], b.data[ob])
+
+		for j := rank - 1; j >= 0; j-- {
+			if idx[j]++; idx[j] < shape[j] {
+				break
+			}
+			idx[j] = 0
+		}
+	}
+	return out, nil
+}
+
+// dim returns the dimension i of shape, aligned to the right to rank
+// dimensions.
+func dim(shape []int, rank, i int) int {
+	if i -= rank - len(shape); i < 0 {
+		return 1
+	}
+	return shape[i]
+}
+
+// strides returns the row-major strides of shape, aligned to the right to
+// rank dimensions.
+func strides(shape []int, rank int) []int {
+	s := make([]int, rank)
+	n := 1
+	for i := rank - 1; i >= 0; i-- {
+		s[i] = n
+		n *= dim(shape, rank, i)
+	}
+	return s
+}
+
+// matMul multiplies two matrices. If the first argument is a vector, the
+// result is a vector.
+func matMul(n node, args []*tensor) (*tensor, error) {
+	if len(args) < 2 || args[0] == nil || args[1] == nil {
+		return nil, errArgs
+	}
+	out, err := mul(args[0], args[1], false, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(args[0].shape) == 1 {
+		out.shape = out.shape[1:]
+	}
+	return out, nil
+}
+
+// gemm computes alpha*A*B + beta*C, where A and B may be transposed.
+func gemm(n node, args []*tensor) (*tensor, error) {
+	if len(args) < 2 || args[0] == nil || args[1] == nil {
+		return nil, errArgs
+	}
+	out, err := mul(args[0], args[1], n.int("transA", 0) != 0, n.int("transB", 0) != 0)
+	if err != nil {
+		return nil, err
+	}
+	alpha := n.float("alpha", 1)
+	for i := range out.data {
+		out.data[i] *= alpha
+	}
+	if len(args) < 3 || args[2] == nil {
+		return out, nil
+	}
+	beta := n.float("beta", 1)
+	return broadcast(out, args[2], func(ab, c float64) float64 { return ab + beta*c })
+}
+
+// mul multiplies the matrices a and b, transposing them first if requested.
+func mul(a, b *tensor, transA, transB bool) (*tensor, error) {
+	ar, ac, err := a.matrix()
+	if err != nil {
+		return nil, err
+	}
+	br, bc, err := b.matrix()
+	if err != nil {
+		return nil, err
+	}
+
+	// at and bt return the element (i, j) of the matrices, after
+	// transposing them. They use the number of columns of the stored
+	// matrices.
+	acols, bcols := ac, bc
+	at := func(i, j int) float64 { return a.data[i*acols+j] }
+	if transA {
+		ar, ac = ac, ar
+		at = func(i, j int) float64 { return a.data[j*acols+i] }
+	}
+	bt := func(i, j int) float64 { return b.data[i*bcols+j] }
+	if transB {
+		br, bc = bc, br
+		bt = func(i, j int) float64 { return b.data[j*bcols+i] }
+	}
+	if ac != br {
+		return nil, fmt.Errorf("cannot multiply %vx%v and %vx%v matrices", ar, ac, br, bc)
+	}
+
+	out := &tensor{shape: []int{ar, bc}, data: make([]float64, ar*bc)}
+	for i := 0; i < ar; i++ {
+		for j := 0; j < bc; j++ {
+			var s float64
+			for k := 0; k < ac; k++ {
+				s += at(i, k) * bt(k, j)
+			}
+			out.data[i*bc+j] = s
+		}
+	}
+	return out, nil
+}
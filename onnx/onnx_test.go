@@ -0,0 +1,305 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/gym"
+	"github.com/jroimartin/rtb/selfplay"
+)
+
+// The models of the tests are encoded by hand, like the ONNX exporters do.
+
+func appendTag(b []byte, num, typ int) []byte {
+	return binary.AppendUvarint(b, uint64(num<<3|typ))
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendVarint(b []byte, num int, v int64) []byte {
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), uint64(v))
+}
+
+// floatTensor encodes a float tensor. If raw is true, the values are stored
+// in raw_data instead of float_data.
+func floatTensor(name string, dims []int64, vs []float64, raw bool) []byte {
+	var b []byte
+	for _, d := range dims {
+		b = appendVarint(b, 1, d)
+	}
+	b = appendVarint(b, 2, dataFloat)
+	var data []byte
+	for _, v := range vs {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(v)))
+	}
+	if raw {
+		b = appendBytes(b, 9, data)
+	} else {
+		b = appendBytes(b, 4, data)
+	}
+	return appendBytes(b, 8, []byte(name))
+}
+
+// doubleTensor encodes a double tensor.
+func doubleTensor(name string, dims []int64, vs []float64) []byte {
+	var b []byte
+	b = appendBytes(b, 1, func() []byte {
+		var packed []byte
+		for _, d := range dims {
+			packed = binary.AppendUvarint(packed, uint64(d))
+		}
+		return packed
+	}())
+	b = appendVarint(b, 2, dataDouble)
+	var data []byte
+	for _, v := range vs {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+	}
+	b = appendBytes(b, 10, data)
+	return appendBytes(b, 8, []byte(name))
+}
+
+// nodeProto encodes a node. attrs are int attributes, and fattrs float
+// attributes.
+func nodeProto(op string, inputs []string, output string, attrs map[string]int64, fattrs map[string]float32) []byte {
+	var b []byte
+	for _, in := range inputs {
+		b = appendBytes(b, 1, []byte(in))
+	}
+	b = appendBytes(b, 2, []byte(output))
+	b = appendBytes(b, 3, []byte(output))
+	b = appendBytes(b, 4, []byte(op))
+	for name, v := range attrs {
+		a := appendBytes(nil, 1, []byte(name))
+		a = appendVarint(a, 3, v)
+		b = appendBytes(b, 5, a)
+	}
+	for name, v := range fattrs {
+		a := appendBytes(nil, 1, []byte(name))
+		a = binary.LittleEndian.AppendUint32(appendTag(a, 2, wireFixed32), math.Float32bits(v))
+		b = appendBytes(b, 5, a)
+	}
+	return b
+}
+
+// valueInfo encodes a ValueInfoProto.
+func valueInfo(name string) []byte {
+	return appendBytes(nil, 1, []byte(name))
+}
+
+// model encodes a model with the given nodes and initializers.
+func model(input, output string, nodes, inits [][]byte) []byte {
+	var g []byte
+	for _, n := range nodes {
+		g = appendBytes(g, 1, n)
+	}
+	g = appendBytes(g, 2, []byte("test"))
+	for _, t := range inits {
+		g = appendBytes(g, 5, t)
+	}
+	g = appendBytes(g, 11, valueInfo(input))
+	g = appendBytes(g, 12, valueInfo(output))
+
+	m := appendVarint(nil, 1, 8)
+	m = appendBytes(m, 2, []byte("rtb"))
+	return appendBytes(m, 7, g)
+}
+
+var (
+	w1 = []float64{1, -1, 0.5, 2, 1, -0.5}
+	b1 = []float64{0.5, 0, -1}
+	w2 = []float64{1, 2, -1}
+)
+
+// mlp returns a network with a hidden layer: tanh(relu(x*W1+b1)*W2 + 0.25).
+func mlp() []byte {
+	return model("obs", "out",
+		[][]byte{
+			nodeProto("Gemm", []string{"obs", "W1", "b1"}, "h", nil, nil),
+			nodeProto("Relu", []string{"h"}, "r", nil, nil),
+			nodeProto("MatMul", []string{"r", "W2"}, "y0", nil, nil),
+			nodeProto("Add", []string{"y0", "c"}, "y", nil, nil),
+			nodeProto("Tanh", []string{"y"}, "out", nil, nil),
+		},
+		[][]byte{
+			floatTensor("W1", []int64{2, 3}, w1, false),
+			floatTensor("b1", []int64{3}, b1, true),
+			doubleTensor("W2", []int64{3, 1}, w2),
+			floatTensor("c", nil, []float64{0.25}, false),
+		},
+	)
+}
+
+// mlpWant computes the output of mlp.
+func mlpWant(x []float64) float64 {
+	var y float64
+	for j := 0; j < 3; j++ {
+		h := b1[j]
+		for i := 0; i < 2; i++ {
+			h += x[i] * w1[i*3+j]
+		}
+		y += math.Max(h, 0) * w2[j]
+	}
+	return math.Tanh(y + 0.25)
+}
+
+func TestRun(t *testing.T) {
+	m, err := Parse(mlp())
+	if err != nil {
+		t.Fatalf("could not parse model: %v", err)
+	}
+
+	for _, x := range [][]float64{{0, 0}, {1, 2}, {-1, 0.5}, {3, -2}} {
+		got, err := m.Run(x)
+		if err != nil {
+			t.Fatalf("could not run model: %v", err)
+		}
+		if want := mlpWant(x); len(got) != 1 || math.Abs(got[0]-want) > 1e-6 {
+			t.Errorf("wrong output for %v: got=%v want=%v", x, got, want)
+		}
+	}
+}
+
+func TestGemmAttributes(t *testing.T) {
+	// out = 2 * x * W^T + 0.5 * b, with W stored as 2x2.
+	b := model("x", "out",
+		[][]byte{
+			nodeProto("Gemm", []string{"x", "W", "b"}, "out", map[string]int64{"transB": 1}, map[string]float32{"alpha": 2, "beta": 0.5}),
+		},
+		[][]byte{
+			floatTensor("W", []int64{2, 2}, []float64{1, 2, 3, 4}, false),
+			floatTensor("b", []int64{1, 2}, []float64{2, 4}, false),
+		},
+	)
+	m, err := Parse(b)
+	if err != nil {
+		t.Fatalf("could not parse model: %v", err)
+	}
+	got, err := m.Run([]float64{1, 1})
+	if err != nil {
+		t.Fatalf("could not run model: %v", err)
+	}
+	want := []float64{2*3 + 1, 2*7 + 2}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("wrong output: got=%v want=%v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"truncated", mlp()[:20]},
+		{"no graph", appendVarint(nil, 1, 8)},
+		{
+			"unsupported operator",
+			model("x", "y", [][]byte{nodeProto("Conv", []string{"x"}, "y", nil, nil)}, nil),
+		},
+		{
+			"bad tensor size",
+			model("x", "y", nil, [][]byte{floatTensor("W", []int64{2, 2}, []float64{1}, false)}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.b); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	m, err := Parse(mlp())
+	if err != nil {
+		t.Fatalf("could not parse model: %v", err)
+	}
+	if _, err := m.Run([]float64{1, 2, 3}); err == nil {
+		t.Errorf("expected error with wrong input size")
+	}
+	if a := m.Act(gym.Observation{1}); a != nil {
+		t.Errorf("expected nil action: got=%v", a)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	a := &tensor{shape: []int{2, 3}, data: []float64{1, 2, 3, 4, 5, 6}}
+	b := &tensor{shape: []int{2, 1}, data: []float64{10, 20}}
+	got, err := broadcast(a, b, func(x, y float64) float64 { return x + y })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []float64{11, 12, 13, 24, 25, 26}
+	for i := range want {
+		if got.data[i] != want[i] {
+			t.Fatalf("wrong result: got=%v want=%v", got.data, want)
+		}
+	}
+
+	c := &tensor{shape: []int{2}, data: []float64{1, 2}}
+	if _, err := broadcast(a, c, func(x, y float64) float64 { return x + y }); err == nil {
+		t.Errorf("expected error with incompatible shapes")
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	// The policy ignores the observation except for the detection of
+	// robots, feature 7: it rotates until it detects a robot and then
+	// stops and shoots.
+	const n = gym.DefaultObservationSize
+	w := make([]float64, n*gym.DefaultActionSize)
+	w[7*gym.DefaultActionSize+1] = -1
+	w[7*gym.DefaultActionSize+3] = 5
+	b := model("obs", "act",
+		[][]byte{nodeProto("Gemm", []string{"obs", "W", "b"}, "act", nil, nil)},
+		[][]byte{
+			floatTensor("W", []int64{n, gym.DefaultActionSize}, w, true),
+			floatTensor("b", []int64{gym.DefaultActionSize}, []float64{0, 1, 0, 0}, true),
+		},
+	)
+	path := filepath.Join(t.TempDir(), "policy.onnx")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatalf("could not write model: %v", err)
+	}
+
+	rep, err := selfplay.Run(selfplay.Config{
+		Contenders: []selfplay.Contender{
+			{
+				Name: "policy",
+				New: func() rtb.Strategy {
+					s, err := NewStrategy(path, nil, nil)
+					if err != nil {
+						t.Fatalf("could not load strategy: %v", err)
+					}
+					return s
+				},
+			},
+			{
+				Name: "duck",
+				New: func() rtb.Strategy {
+					return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+				},
+			},
+		},
+		Games: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := rep.Contenders[0]; c.Wins != 2 {
+		t.Errorf("unexpected policy report: %+v", c)
+	}
+
+	if _, err := NewStrategy(filepath.Join(t.TempDir(), "missing.onnx"), nil, nil); err == nil {
+		t.Errorf("expected error with missing model")
+	}
+}
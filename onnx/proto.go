@@ -0,0 +1,302 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The ONNX format is a protobuf message. Only the fields needed to evaluate
+// the supported operators are decoded, so the package does not depend on a
+// protobuf library. The field numbers are the ones of onnx.proto.
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned when a message ends in the middle of a field.
+var errTruncated = errors.New("truncated message")
+
+// field is a decoded protobuf field. v holds the value of varint and fixed
+// fields, and b the value of length-delimited ones.
+type field struct {
+	num int
+	typ int
+	v   uint64
+	b   []byte
+}
+
+// parseFields calls fn for every field of the message b.
+func parseFields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+
+		f := field{num: int(tag >> 3), typ: int(tag & 7)}
+		switch f.typ {
+		case wireVarint:
+			if f.v, n = binary.Uvarint(b); n <= 0 {
+				return errTruncated
+			}
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.v, n = binary.LittleEndian.Uint64(b), 8
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.v, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		case wireBytes:
+			l, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < l {
+				return errTruncated
+			}
+			f.b, n = b[m:m+int(l)], m+int(l)
+		default:
+			return fmt.Errorf("unsupported wire type %v", f.typ)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ints appends the values of a repeated int64 field, packed or not, to vs.
+func (f field) ints(vs []int64) ([]int64, error) {
+	if f.typ == wireVarint {
+		return append(vs, int64(f.v)), nil
+	}
+	if f.typ != wireBytes {
+		return nil, fmt.Errorf("unexpected wire type %v of field %v", f.typ, f.num)
+	}
+	for b := f.b; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		vs = append(vs, int64(v))
+		b = b[n:]
+	}
+	return vs, nil
+}
+
+// floats appends the values of a repeated float field, packed or not, to vs.
+func (f field) floats(vs []float64) ([]float64, error) {
+	if f.typ == wireFixed32 {
+		return append(vs, float64(math.Float32frombits(uint32(f.v)))), nil
+	}
+	if f.typ != wireBytes || len(f.b)%4 != 0 {
+		return nil, fmt.Errorf("invalid float field %v", f.num)
+	}
+	for b := f.b; len(b) > 0; b = b[4:] {
+		vs = append(vs, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	}
+	return vs, nil
+}
+
+// doubles appends the values of a repeated double field, packed or not, to
+// vs.
+func (f field) doubles(vs []float64) ([]float64, error) {
+	if f.typ == wireFixed64 {
+		return append(vs, math.Float64frombits(f.v)), nil
+	}
+	if f.typ != wireBytes || len(f.b)%8 != 0 {
+		return nil, fmt.Errorf("invalid double field %v", f.num)
+	}
+	for b := f.b; len(b) > 0; b = b[8:] {
+		vs = append(vs, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	}
+	return vs, nil
+}
+
+// Data types of the tensors.
+const (
+	dataFloat  = 1
+	dataDouble = 11
+)
+
+// parseModel decodes a ModelProto and returns its graph.
+func parseModel(b []byte) (*graph, error) {
+	var g *graph
+	err := parseFields(b, func(f field) error {
+		if f.num != 7 || f.typ != wireBytes {
+			return nil
+		}
+		var err error
+		g, err = parseGraph(f.b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, errors.New("missing graph")
+	}
+	return g, nil
+}
+
+// parseGraph decodes a GraphProto.
+func parseGraph(b []byte) (*graph, error) {
+	g := &graph{initializers: map[string]*tensor{}}
+	err := parseFields(b, func(f field) error {
+		if f.typ != wireBytes {
+			return nil
+		}
+		switch f.num {
+		case 1:
+			n, err := parseNode(f.b)
+			if err != nil {
+				return fmt.Errorf("could not parse node: %v", err)
+			}
+			g.nodes = append(g.nodes, n)
+		case 5:
+			name, t, err := parseTensor(f.b)
+			if err != nil {
+				return fmt.Errorf("could not parse initializer: %v", err)
+			}
+			g.initializers[name] = t
+		case 11, 12:
+			name, err := parseValueInfo(f.b)
+			if err != nil {
+				return fmt.Errorf("could not parse value info: %v", err)
+			}
+			if f.num == 11 {
+				g.inputs = append(g.inputs, name)
+			} else {
+				g.outputs = append(g.outputs, name)
+			}
+		}
+		return nil
+	})
+	return g, err
+}
+
+// parseNode decodes a NodeProto.
+func parseNode(b []byte) (node, error) {
+	n := node{attrs: map[string]attr{}}
+	err := parseFields(b, func(f field) error {
+		if f.typ != wireBytes {
+			return nil
+		}
+		switch f.num {
+		case 1:
+			n.inputs = append(n.inputs, string(f.b))
+		case 2:
+			n.outputs = append(n.outputs, string(f.b))
+		case 3:
+			n.name = string(f.b)
+		case 4:
+			n.op = string(f.b)
+		case 5:
+			name, a, err := parseAttr(f.b)
+			if err != nil {
+				return err
+			}
+			n.attrs[name] = a
+		}
+		return nil
+	})
+	return n, err
+}
+
+// parseAttr decodes the float and int fields of an AttributeProto.
+func parseAttr(b []byte) (string, attr, error) {
+	var (
+		name string
+		a    attr
+	)
+	err := parseFields(b, func(f field) error {
+		switch {
+		case f.num == 1 && f.typ == wireBytes:
+			name = string(f.b)
+		case f.num == 2 && f.typ == wireFixed32:
+			a.f = float64(math.Float32frombits(uint32(f.v)))
+		case f.num == 3 && f.typ == wireVarint:
+			a.i = int64(f.v)
+		}
+		return nil
+	})
+	return name, a, err
+}
+
+// parseTensor decodes a TensorProto of floats or doubles.
+func parseTensor(b []byte) (string, *tensor, error) {
+	var (
+		name     string
+		dims     []int64
+		dataType int
+		data     []float64
+		raw      []byte
+	)
+	err := parseFields(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			dims, err = f.ints(dims)
+		case 2:
+			dataType = int(f.v)
+		case 4:
+			data, err = f.floats(data)
+		case 8:
+			name = string(f.b)
+		case 9:
+			raw = f.b
+		case 10:
+			data, err = f.doubles(data)
+		}
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if raw != nil {
+		// Raw data is always little endian.
+		switch dataType {
+		case dataFloat:
+			data, err = field{typ: wireBytes, b: raw}.floats(nil)
+		case dataDouble:
+			data, err = field{typ: wireBytes, b: raw}.doubles(nil)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if dataType != dataFloat && dataType != dataDouble {
+		return "", nil, fmt.Errorf("unsupported data type %v of tensor %q", dataType, name)
+	}
+
+	t := &tensor{data: data}
+	for _, d := range dims {
+		t.shape = append(t.shape, int(d))
+	}
+	if t.size() != len(data) {
+		return "", nil, fmt.Errorf("tensor %q has %v values, want %v", name, len(data), t.size())
+	}
+	return name, t, nil
+}
+
+// parseValueInfo returns the name of a ValueInfoProto.
+func parseValueInfo(b []byte) (string, error) {
+	var name string
+	err := parseFields(b, func(f field) error {
+		if f.num == 1 && f.typ == wireBytes {
+			name = string(f.b)
+		}
+		return nil
+	})
+	return name, err
+}
@@ -0,0 +1,187 @@
+// Package opponents provides simple scripted opponents, to measure
+// strategies against a standard ladder in the simulator. From the easiest to
+// the hardest:
+//
+//   - duck: a sitting duck that does nothing.
+//   - spinner: spins in place and shoots the robots its radar sees.
+//   - wallhugger: runs along the walls, turning when it hits one, and
+//     shoots the robots its radar sees.
+//   - drunkard: wanders randomly and shoots the robots its radar sees.
+//   - rammer: searches the closest robot and rams it, shooting at it.
+//
+// Opponents are selected by name:
+//
+//	s, err := opponents.New("rammer")
+package opponents
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/rng"
+)
+
+// shotEnergy is the energy of the shots of the opponents.
+const shotEnergy = 2
+
+// ladder are the names of the opponents, from the easiest to the hardest,
+// and their constructors.
+var ladder = []struct {
+	name string
+	new  func() rtb.Strategy
+}{
+	{"duck", Duck},
+	{"spinner", Spinner},
+	{"wallhugger", WallHugger},
+	{"drunkard", func() rtb.Strategy { return Drunkard(rng.New()) }},
+	{"rammer", Rammer},
+}
+
+// Names returns the names of the opponents, from the easiest to the
+// hardest.
+func Names() []string {
+	names := make([]string, len(ladder))
+	for i, o := range ladder {
+		names[i] = o.name
+	}
+	return names
+}
+
+// New returns a new instance of the opponent name. The drunkard uses a
+// generator created with rng.New.
+func New(name string) (rtb.Strategy, error) {
+	for _, o := range ladder {
+		if o.name == name {
+			return o.new(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown opponent %q", name)
+}
+
+// introduce sends the name and the colours of the opponent on the first
+// initialization.
+func introduce(r *rtb.Robot, msg rtb.Message, name, colour string) {
+	if m, ok := msg.(rtb.MessageInitialize); ok && m.First {
+		r.Name(name)
+		r.Colour(colour, colour)
+	}
+}
+
+// shootRobots shoots when the radar sees a robot. It is used by the
+// opponents that rotate the cannon with the radar.
+func shootRobots(r *rtb.Robot, msg rtb.Message) {
+	if m, ok := msg.(rtb.MessageRadar); ok && m.Object == rtb.ObjectRobot {
+		r.Shoot(shotEnergy)
+	}
+}
+
+// Duck returns an opponent that does nothing.
+func Duck() rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		introduce(r, msg, "duck", "ffff00")
+	})
+}
+
+// Spinner returns an opponent that spins in place and shoots the robots its
+// radar sees.
+func Spinner() rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		introduce(r, msg, "spinner", "00ffff")
+		if _, ok := msg.(rtb.MessageGameStarts); ok {
+			r.Rotate(rtb.PartRobot, math.Pi)
+		}
+		shootRobots(r, msg)
+	})
+}
+
+// WallHugger returns an opponent that runs along the walls, turning when it
+// hits one, and shoots the robots its radar sees.
+func WallHugger() rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		introduce(r, msg, "wallhugger", "808080")
+		switch m := msg.(type) {
+		case rtb.MessageGameStarts:
+			r.Accelerate(1)
+			r.Rotate(rtb.PartCannon|rtb.PartRadar, math.Pi)
+		case rtb.MessageCollision:
+			if m.Object == rtb.ObjectWall {
+				r.RotateAmount(rtb.PartRobot, math.Pi, math.Pi/2)
+			}
+		}
+		shootRobots(r, msg)
+	})
+}
+
+// drunkard wanders randomly.
+type drunkard struct {
+	rnd  *rand.Rand
+	next float64
+}
+
+// Drunkard returns an opponent that wanders randomly, using rnd, and shoots
+// the robots its radar sees. If rnd is nil, the default generator of the
+// rng package is used.
+func Drunkard(rnd *rand.Rand) rtb.Strategy {
+	return &drunkard{rnd: rng.Or(rnd)}
+}
+
+func (d *drunkard) Handle(r *rtb.Robot, msg rtb.Message) {
+	introduce(r, msg, "drunkard", "ff00ff")
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		d.next = 0
+		r.Rotate(rtb.PartCannon|rtb.PartRadar, math.Pi)
+	case rtb.MessageInfo:
+		if m.Time < d.next {
+			break
+		}
+		r.Rotate(rtb.PartRobot, (2*d.rnd.Float64()-1)*math.Pi/4)
+		r.Accelerate(2.5*d.rnd.Float64() - 0.5)
+		d.next = m.Time + 0.5 + 1.5*d.rnd.Float64()
+	case rtb.MessageCollision:
+		if m.Object == rtb.ObjectWall {
+			// Back off and change the course soon.
+			r.Accelerate(-0.5)
+			d.next = 0
+		}
+	}
+	shootRobots(r, msg)
+}
+
+// rammer searches the closest robot and rams it.
+type rammer struct {
+	chasing bool
+}
+
+// Rammer returns an opponent that searches the closest robot and rams it,
+// shooting at it. The radar and the cannon are fixed to the front of the
+// robot, so it turns until it sees a robot and then accelerates at full
+// speed.
+func Rammer() rtb.Strategy {
+	return &rammer{}
+}
+
+func (ram *rammer) Handle(r *rtb.Robot, msg rtb.Message) {
+	introduce(r, msg, "rammer", "ff0000")
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		ram.chasing = false
+		r.Rotate(rtb.PartRobot, math.Pi)
+	case rtb.MessageRadar:
+		switch {
+		case m.Object == rtb.ObjectRobot:
+			r.Shoot(shotEnergy)
+			if !ram.chasing {
+				ram.chasing = true
+				r.Rotate(rtb.PartRobot, 0)
+				r.Accelerate(2)
+			}
+		case ram.chasing:
+			ram.chasing = false
+			r.Accelerate(0)
+			r.Rotate(rtb.PartRobot, math.Pi)
+		}
+	}
+}
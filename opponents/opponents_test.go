@@ -0,0 +1,99 @@
+package opponents
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/sim"
+)
+
+func TestNames(t *testing.T) {
+	want := []string{"duck", "spinner", "wallhugger", "drunkard", "rammer"}
+	if got := Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected names: got=%v want=%v", got, want)
+	}
+	for _, name := range want {
+		if _, err := New(name); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, err := New("sniper"); err == nil {
+		t.Errorf("expected error for unknown opponent")
+	}
+}
+
+// warnings is a strategy that records the warnings received by s.
+type warnings struct {
+	s    rtb.Strategy
+	msgs []string
+}
+
+func (w *warnings) Handle(r *rtb.Robot, msg rtb.Message) {
+	if m, ok := msg.(rtb.MessageWarning); ok {
+		w.msgs = append(w.msgs, m.Message)
+	}
+	w.s.Handle(r, msg)
+}
+
+func TestBehavior(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy rtb.Strategy
+		moves    bool
+		turns    bool
+		shoots   bool
+	}{
+		{name: "duck", strategy: Duck()},
+		{name: "spinner", strategy: Spinner(), turns: true, shoots: true},
+		{name: "wallhugger", strategy: WallHugger(), moves: true, turns: true, shoots: true},
+		{name: "drunkard", strategy: Drunkard(rand.New(rand.NewSource(1))), moves: true, turns: true, shoots: true},
+		{name: "rammer", strategy: Rammer(), moves: true, turns: true, shoots: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &warnings{s: tt.strategy}
+			players := []*sim.Player{
+				sim.NewPlayer(w, rtb.ListenSettings{}),
+				sim.NewPlayer(Duck(), rtb.ListenSettings{}),
+			}
+			var frames []sim.Frame
+			cfg := sim.Config{
+				Seed:   1,
+				Frames: func(f sim.Frame) { frames = append(frames, f) },
+			}
+			g, err := sim.NewGame(cfg, players)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := 0; i < 400 && g.Step(); i++ {
+			}
+			res := g.Result()
+
+			if players[0].Name() != tt.name {
+				t.Errorf("unexpected name: got=%v want=%v", players[0].Name(), tt.name)
+			}
+			if len(w.msgs) != 0 {
+				t.Errorf("unexpected warnings: %v", w.msgs)
+			}
+			first, last := frames[0].Robots[0], frames[len(frames)-1].Robots[0]
+			if moved := first.Pos.Sub(last.Pos).Len() > 1; moved != tt.moves {
+				t.Errorf("unexpected movement: got=%v want=%v", moved, tt.moves)
+			}
+			turned := false
+			for _, f := range frames {
+				if f.Robots[0].Angle != first.Angle {
+					turned = true
+				}
+			}
+			if turned != tt.turns {
+				t.Errorf("unexpected rotation: got=%v want=%v", turned, tt.turns)
+			}
+			if shot := res.Robots[0].ShotsFired > 0; shot != tt.shoots {
+				t.Errorf("unexpected shots: got=%v want=%v", shot, tt.shoots)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package overlay draws what a robot "thinks": the enemy tracks, the
+// predicted intercept points, the planned waypoints and the current radar
+// direction.
+//
+// The overlay draws on a canvas every tick. It must be added to the robot
+// after the components it draws and before the canvas, so the shapes are
+// drawn in the same tick:
+//
+//	r.AddObserver(w)
+//	r.AddObserver(tracker)
+//	r.AddObserver(navigator)
+//	r.AddObserver(overlay.New(canvas, w, tracker, navigator, overlay.Config{}))
+//	r.AddObserver(canvas)
+//
+// Remember that the server only shows debug shapes at the highest debug
+// level.
+package overlay
+
+import (
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/draw"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of an Overlay.
+type Config struct {
+	// RadarLength is the length of the line showing the radar
+	// direction. If zero, 10 is used.
+	RadarLength float64
+
+	// Disabled disables the overlay initially.
+	Disabled bool
+}
+
+// Overlay draws the state of the tracker and the navigator. It implements
+// the rtb.Observer interface. Overlay methods can be called concurrently.
+type Overlay struct {
+	rtb.NopCommand
+
+	cfg Config
+	c   *draw.Canvas
+	w   *world.World
+	tr  *track.Tracker
+	n   *nav.Navigator
+
+	mu      sync.Mutex
+	enabled bool
+}
+
+// New returns an Overlay that draws on c. tr and n can be nil, in which case
+// the tracks and the waypoints are not drawn respectively.
+func New(c *draw.Canvas, w *world.World, tr *track.Tracker, n *nav.Navigator, cfg Config) *Overlay {
+	if cfg.RadarLength == 0 {
+		cfg.RadarLength = 10
+	}
+	return &Overlay{cfg: cfg, c: c, w: w, tr: tr, n: n, enabled: !cfg.Disabled}
+}
+
+// SetEnabled enables or disables the overlay.
+func (o *Overlay) SetEnabled(enabled bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.enabled = enabled
+}
+
+// Enabled returns true if the overlay is enabled.
+func (o *Overlay) Enabled() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.enabled
+}
+
+// Toggle enables the overlay if it is disabled and vice versa.
+func (o *Overlay) Toggle() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.enabled = !o.enabled
+}
+
+// Message draws the overlay when msg is an Info message.
+func (o *Overlay) Message(msg rtb.Message) {
+	if _, ok := msg.(rtb.MessageInfo); !ok || !o.Enabled() {
+		return
+	}
+	o.Draw()
+}
+
+// Draw draws the overlay for the next tick. It is called automatically when
+// an Info message is received.
+func (o *Overlay) Draw() {
+	s := o.w.State()
+
+	o.c.Line(s.Pos, o.w.Absolute(s.RadarAngle, o.cfg.RadarLength), draw.Once)
+
+	if o.tr != nil {
+		shotSpeed, _ := o.w.Option(rtb.GOptionShotSpeed)
+		for _, t := range o.tr.Tracks() {
+			pos := t.PositionAt(s.Time)
+			o.c.Circle(pos, physics.RobotRadius, draw.Once)
+			if t.Vel != (arena.Point{}) {
+				o.c.Arrow(pos, pos.Add(t.Vel), draw.Once)
+			}
+			if t.TeamMate || shotSpeed == 0 {
+				continue
+			}
+			if p, _, ok := t.Intercept(s.Pos, s.Time, shotSpeed); ok {
+				o.c.Cross(p, 1, draw.Once)
+			}
+		}
+	}
+
+	if o.n != nil {
+		if wps := o.n.Waypoints(); len(wps) > 0 {
+			o.c.Polyline(append([]arena.Point{s.Pos}, wps...), draw.Once)
+			for _, p := range wps {
+				o.c.Circle(p, 0.2, draw.Once)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package overlay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/draw"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestOverlay(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	n := nav.New(r, w, nav.Config{})
+	c := draw.New(r, w)
+	o := New(c, w, tr, n, Config{})
+	for _, obs := range []rtb.Observer{w, tr, o, c} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionShotSpeed, Value: 10},
+		rtb.MessageGameStarts{},
+		rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0},
+		rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+		rtb.MessageRobotInfo{EnergyLevel: 50},
+	}
+	for _, msg := range msgs {
+		r.Deliver(nop, msg)
+	}
+	n.GoTo(arena.Point{X: 5, Y: 5}, arena.Point{X: 10, Y: 5})
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    map[string]int
+	}{
+		// Radar line, track circle, intercept cross, waypoint path and
+		// waypoint circles.
+		{"Enabled", true, map[string]int{"DebugLine": 5, "DebugCircle": 3}},
+		{"Disabled", false, map[string]int{}},
+	}
+
+	for i, tt := range tests {
+		o.SetEnabled(tt.enabled)
+		out.Reset()
+		r.Deliver(nop, rtb.MessageInfo{Time: float64(i + 1)})
+
+		got := map[string]int{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if keyword, _, ok := strings.Cut(line, " "); ok {
+				got[keyword]++
+			}
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%v: unexpected number of %v commands: got=%v want=%v", tt.name, k, got[k], v)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: unexpected commands: %v", tt.name, got)
+		}
+	}
+
+	o.Toggle()
+	if !o.Enabled() {
+		t.Errorf("overlay not enabled after toggle")
+	}
+}
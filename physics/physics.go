@@ -0,0 +1,117 @@
+// Package physics encodes the physical conventions of RealTimeBattle, so the
+// packages of the library agree on them:
+//
+//   - Angles are in radians. Absolute angles, like the heading of a robot,
+//     are measured counterclockwise from the x axis, like math.Atan2.
+//   - The angles of the cannon and the radar are relative to the front of
+//     the robot. The absolute angle of a part is the heading of the robot
+//     plus its relative angle.
+//   - Normalized angles are in the range (-π, π].
+//   - Distances are in the length units of the arena, in which the radius
+//     of a robot is RobotRadius.
+//   - Energies are in the energy units of the game options, e.g. robots
+//     start with RobotStartEnergy units and shots carry between
+//     ShotMinEnergy and ShotMaxEnergy units.
+//   - The coordinates sent to the robots depend on the SendRobotCoordinates
+//     game option, whose values are the CoordinateMode constants.
+//   - Colours are hex strings of the form "11aa22".
+package physics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// RobotRadius is the radius of the robots.
+const RobotRadius = 0.5
+
+// NormalizeAngle returns a normalized to the range (-π, π].
+func NormalizeAngle(a float64) float64 {
+	a = math.Mod(a, 2*math.Pi)
+	if a > math.Pi {
+		a -= 2 * math.Pi
+	} else if a <= -math.Pi {
+		a += 2 * math.Pi
+	}
+	return a
+}
+
+// AngleDiff returns a-b normalized to the range (-π, π], i.e. the rotation
+// from b to a.
+func AngleDiff(a, b float64) float64 {
+	return NormalizeAngle(a - b)
+}
+
+// Absolute returns the normalized absolute angle of a part at the angle rel
+// relative to the front of a robot with the given heading.
+func Absolute(heading, rel float64) float64 {
+	return NormalizeAngle(heading + rel)
+}
+
+// Relative returns the normalized angle, relative to the front of a robot
+// with the given heading, of the absolute angle abs. It is the inverse of
+// Absolute.
+func Relative(heading, abs float64) float64 {
+	return NormalizeAngle(abs - heading)
+}
+
+// Degrees converts an angle from radians to degrees.
+func Degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// Radians converts an angle from degrees to radians.
+func Radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// CoordinateMode is a value of the SendRobotCoordinates game option.
+type CoordinateMode int
+
+// Coordinate modes.
+const (
+	// CoordinatesNone means that the server does not send coordinates.
+	// The robot can only estimate its position by dead reckoning.
+	CoordinatesNone CoordinateMode = 0
+
+	// CoordinatesRelative means that the coordinates are relative to the
+	// start position of the robot.
+	CoordinatesRelative CoordinateMode = 1
+
+	// CoordinatesAbsolute means that the coordinates are absolute, so
+	// they can be used with the arena map.
+	CoordinatesAbsolute CoordinateMode = 2
+)
+
+// ParseCoordinateMode returns the coordinate mode of a value of the
+// SendRobotCoordinates game option.
+func ParseCoordinateMode(v float64) (CoordinateMode, error) {
+	m := CoordinateMode(v)
+	if float64(m) != v || m < CoordinatesNone || m > CoordinatesAbsolute {
+		return 0, fmt.Errorf("invalid coordinate mode: %v", v)
+	}
+	return m, nil
+}
+
+func (m CoordinateMode) String() string {
+	switch m {
+	case CoordinatesNone:
+		return "None"
+	case CoordinatesRelative:
+		return "Relative"
+	case CoordinatesAbsolute:
+		return "Absolute"
+	default:
+		return "unknown"
+	}
+}
+
+// hexColourRe is a regexp that matches a valid hex colour.
+var hexColourRe = regexp.MustCompile(`^[[:xdigit:]]{6}$`)
+
+// IsValidColour returns true if s is a valid colour, a hex string of the
+// form "11aa22".
+func IsValidColour(s string) bool {
+	return hexColourRe.MatchString(s)
+}
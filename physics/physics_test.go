@@ -0,0 +1,86 @@
+package physics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeAngle(t *testing.T) {
+	tests := []struct {
+		angle float64
+		want  float64
+	}{
+		{0, 0},
+		{math.Pi, math.Pi},
+		{-math.Pi, math.Pi},
+		{3 * math.Pi / 2, -math.Pi / 2},
+		{-3 * math.Pi / 2, math.Pi / 2},
+		{5 * math.Pi, math.Pi},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAngle(tt.angle); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("unexpected angle for %v: got=%v want=%v", tt.angle, got, tt.want)
+		}
+	}
+}
+
+func TestAngles(t *testing.T) {
+	if got, want := AngleDiff(0.1, 2*math.Pi-0.1), 0.2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected difference: got=%v want=%v", got, want)
+	}
+	if got, want := Absolute(3, 1), 4-2*math.Pi; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected absolute angle: got=%v want=%v", got, want)
+	}
+	if got, want := Relative(3, Absolute(3, 1)), 1.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected relative angle: got=%v want=%v", got, want)
+	}
+	if got, want := Degrees(Radians(90)), 90.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected degrees: got=%v want=%v", got, want)
+	}
+}
+
+func TestParseCoordinateMode(t *testing.T) {
+	tests := []struct {
+		value   float64
+		want    CoordinateMode
+		wantErr bool
+	}{
+		{0, CoordinatesNone, false},
+		{1, CoordinatesRelative, false},
+		{2, CoordinatesAbsolute, false},
+		{3, 0, true},
+		{1.5, 0, true},
+		{-1, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCoordinateMode(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for %v: got=%v want=%v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("unexpected mode for %v: got=%v want=%v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidColour(t *testing.T) {
+	tests := []struct {
+		colour string
+		want   bool
+	}{
+		{"11aa22", true},
+		{"FFFFFF", true},
+		{"11aa2", false},
+		{"11aa223", false},
+		{"11aag2", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidColour(tt.colour); got != tt.want {
+			t.Errorf("unexpected result for %q: got=%v want=%v", tt.colour, got, tt.want)
+		}
+	}
+}
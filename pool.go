@@ -0,0 +1,145 @@
+package rtb
+
+import "sync"
+
+// msgPool is a pool of messages of type T.
+type msgPool[T any] struct {
+	p sync.Pool
+}
+
+// get returns a message from the pool or a new one if the pool is empty.
+func (p *msgPool[T]) get() *T {
+	if v := p.p.Get(); v != nil {
+		return v.(*T)
+	}
+	return new(T)
+}
+
+// put returns m to the pool.
+func (p *msgPool[T]) put(m *T) {
+	var zero T
+	*m = zero
+	p.p.Put(m)
+}
+
+// Message pools, one per message type.
+var (
+	poolInitialize      msgPool[MessageInitialize]
+	poolYourName        msgPool[MessageYourName]
+	poolYourColour      msgPool[MessageYourColour]
+	poolGameOption      msgPool[MessageGameOption]
+	poolGameStarts      msgPool[MessageGameStarts]
+	poolRadar           msgPool[MessageRadar]
+	poolInfo            msgPool[MessageInfo]
+	poolCoordinates     msgPool[MessageCoordinates]
+	poolRobotInfo       msgPool[MessageRobotInfo]
+	poolRotationReached msgPool[MessageRotationReached]
+	poolEnergy          msgPool[MessageEnergy]
+	poolRobotsLeft      msgPool[MessageRobotsLeft]
+	poolCollision       msgPool[MessageCollision]
+	poolWarning         msgPool[MessageWarning]
+	poolDead            msgPool[MessageDead]
+	poolGameFinishes    msgPool[MessageGameFinishes]
+	poolExitRobot       msgPool[MessageExitRobot]
+)
+
+// emit returns the message m, or a pointer to a copy of it taken from p if
+// pooled is true. It returns a nil message if err is not nil.
+func emit[T Message, P interface {
+	*T
+	Message
+}](m T, err error, pooled bool, p *msgPool[T]) (Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	if !pooled {
+		return m, nil
+	}
+	ptr := p.get()
+	*ptr = m
+	return P(ptr), nil
+}
+
+// Release returns a message received in pooling mode to its pool. It does
+// nothing if msg is not a pointer to a message.
+func Release(msg Message) {
+	switch m := msg.(type) {
+	case *MessageInitialize:
+		poolInitialize.put(m)
+	case *MessageYourName:
+		poolYourName.put(m)
+	case *MessageYourColour:
+		poolYourColour.put(m)
+	case *MessageGameOption:
+		poolGameOption.put(m)
+	case *MessageGameStarts:
+		poolGameStarts.put(m)
+	case *MessageRadar:
+		poolRadar.put(m)
+	case *MessageInfo:
+		poolInfo.put(m)
+	case *MessageCoordinates:
+		poolCoordinates.put(m)
+	case *MessageRobotInfo:
+		poolRobotInfo.put(m)
+	case *MessageRotationReached:
+		poolRotationReached.put(m)
+	case *MessageEnergy:
+		poolEnergy.put(m)
+	case *MessageRobotsLeft:
+		poolRobotsLeft.put(m)
+	case *MessageCollision:
+		poolCollision.put(m)
+	case *MessageWarning:
+		poolWarning.put(m)
+	case *MessageDead:
+		poolDead.put(m)
+	case *MessageGameFinishes:
+		poolGameFinishes.put(m)
+	case *MessageExitRobot:
+		poolExitRobot.put(m)
+	}
+}
+
+// CopyMessage returns msg as a value. If msg is a pointer to a message, e.g.
+// received in pooling mode, it returns a copy of the pointed message, which
+// can be retained after the message is released. Otherwise, it returns msg.
+func CopyMessage(msg Message) Message {
+	switch m := msg.(type) {
+	case *MessageInitialize:
+		return *m
+	case *MessageYourName:
+		return *m
+	case *MessageYourColour:
+		return *m
+	case *MessageGameOption:
+		return *m
+	case *MessageGameStarts:
+		return *m
+	case *MessageRadar:
+		return *m
+	case *MessageInfo:
+		return *m
+	case *MessageCoordinates:
+		return *m
+	case *MessageRobotInfo:
+		return *m
+	case *MessageRotationReached:
+		return *m
+	case *MessageEnergy:
+		return *m
+	case *MessageRobotsLeft:
+		return *m
+	case *MessageCollision:
+		return *m
+	case *MessageWarning:
+		return *m
+	case *MessageDead:
+		return *m
+	case *MessageGameFinishes:
+		return *m
+	case *MessageExitRobot:
+		return *m
+	}
+	return msg
+}
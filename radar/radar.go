@@ -0,0 +1,171 @@
+// Package radar implements a radar lock: the radar spins until an enemy is
+// detected and then sweeps a narrow sector around its predicted position, so
+// it is observed every few ticks. It also implements sweeping over sectors given
+// in absolute bearings.
+package radar
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/rng"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Lock.
+type Config struct {
+	// Width is the width of the swept sector in radians. If zero, 0.4
+	// is used.
+	Width float64
+
+	// LostTime is the time without observing the target after which it
+	// is considered lost and the radar spins again. If zero, 1 is used.
+	LostTime float64
+
+	// Jitter is the maximum random offset in radians added to the center
+	// of the swept sector, so the edges of the sector vary and the target
+	// is scanned from slightly different angles. If zero, the sector is
+	// centered on the target.
+	Jitter float64
+
+	// Rand is the source of the jitter. If nil, the default generator of
+	// the rng package is used.
+	Rand *rand.Rand
+}
+
+// Lock keeps the radar on the nearest enemy. It implements the rtb.Observer
+// interface and must be added to the robot after the world model and the
+// tracker. Lock methods can be called concurrently.
+type Lock struct {
+	rtb.NopCommand
+
+	cfg Config
+	r   *rtb.Robot
+	w   *world.World
+	tr  *track.Tracker
+
+	mu       sync.Mutex
+	disabled bool
+	spinning bool
+	center   float64
+	target   int
+}
+
+// New returns a Lock that sends commands through r and locks onto the tracks
+// of tr.
+func New(r *rtb.Robot, w *world.World, tr *track.Tracker, cfg Config) *Lock {
+	if cfg.Width == 0 {
+		cfg.Width = 0.4
+	}
+	if cfg.LostTime == 0 {
+		cfg.LostTime = 1
+	}
+	return &Lock{cfg: cfg, r: r, w: w, tr: tr, center: math.NaN()}
+}
+
+// Target returns the locked track. It returns false if the radar is not
+// locked.
+func (l *Lock) Target() (track.Track, bool) {
+	l.mu.Lock()
+	id := l.target
+	l.mu.Unlock()
+
+	if id == 0 {
+		return track.Track{}, false
+	}
+	for _, t := range l.tr.Tracks() {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return track.Track{}, false
+}
+
+// SetEnabled enables or disables the lock. A disabled lock does not send
+// commands, so the radar can be controlled by other components. Locks are
+// enabled by default.
+func (l *Lock) SetEnabled(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.disabled == !enabled {
+		return
+	}
+	l.disabled = !enabled
+	l.spinning, l.center, l.target = false, math.NaN(), 0
+}
+
+// Enabled returns true if the lock is enabled.
+func (l *Lock) Enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return !l.disabled
+}
+
+// Message updates the radar when msg is an Info message.
+func (l *Lock) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		l.mu.Lock()
+		l.spinning, l.center, l.target = false, math.NaN(), 0
+		l.mu.Unlock()
+	case rtb.MessageInfo:
+		l.update()
+	}
+}
+
+// update spins the radar or sweeps the sector of the target.
+func (l *Lock) update() {
+	s := l.w.State()
+	speed, ok := l.w.Option(rtb.GOptionRobotRadarMaxRotate)
+	if !ok {
+		speed = math.Pi / 2
+	}
+
+	t, found := l.tr.Nearest(s.Pos)
+	if found && s.Time-t.LastSeen > l.cfg.LostTime {
+		found = false
+	}
+
+	l.mu.Lock()
+	if l.disabled {
+		l.mu.Unlock()
+		return
+	}
+	if !found {
+		spin := !l.spinning
+		l.spinning, l.center, l.target = true, math.NaN(), 0
+		l.mu.Unlock()
+
+		if spin {
+			l.r.Rotate(rtb.PartRadar, speed)
+		}
+		return
+	}
+
+	center, _ := l.w.Relative(t.PredictPosition(s.Time - t.LastSeen))
+
+	// The sector is only updated when it has moved noticeably, so
+	// commands are not sent every tick.
+	update := l.spinning || l.target != t.ID || math.IsNaN(l.center) ||
+		math.Abs(physics.NormalizeAngle(center-l.center)) > l.cfg.Width/4
+	if update {
+		l.spinning, l.center = false, center
+	}
+	l.target = t.ID
+	jitter := 0.0
+	if update && l.cfg.Jitter > 0 {
+		jitter = l.cfg.Jitter * (2*rng.Or(l.cfg.Rand).Float64() - 1)
+	}
+	l.mu.Unlock()
+
+	if update {
+		center += jitter
+		l.r.Sweep(rtb.PartRadar, speed, center-l.cfg.Width/2, center+l.cfg.Width/2)
+	}
+}
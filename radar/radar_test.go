@@ -0,0 +1,164 @@
+package radar
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestLock(t *testing.T) {
+	tests := []struct {
+		name   string
+		msgs   []rtb.Message
+		want   []string
+		locked bool
+	}{
+		{
+			"Spin",
+			[]rtb.Message{
+				rtb.MessageInfo{Time: 0},
+				rtb.MessageInfo{Time: 0.1},
+			},
+			[]string{"Rotate 4 1.000000"},
+			false,
+		},
+		{
+			"Lock",
+			[]rtb.Message{
+				rtb.MessageInfo{Time: 0},
+				rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+				rtb.MessageInfo{Time: 0.1},
+				rtb.MessageInfo{Time: 0.2},
+			},
+			[]string{"Rotate 4 1.000000", "Sweep 4 1.000000 -0.200000 0.200000"},
+			true,
+		},
+		{
+			"Lost",
+			[]rtb.Message{
+				rtb.MessageInfo{Time: 0},
+				rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+				rtb.MessageInfo{Time: 0.1},
+				rtb.MessageInfo{Time: 2},
+			},
+			[]string{"Rotate 4 1.000000", "Sweep 4 1.000000 -0.200000 0.200000", "Rotate 4 1.000000"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := rtb.NewRobot(nil, &out)
+			w := world.New()
+			tr := track.New(w, track.Config{})
+			l := New(r, w, tr, Config{})
+			for _, obs := range []rtb.Observer{w, tr, l} {
+				r.AddObserver(obs)
+			}
+
+			nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+			r.Deliver(nop, rtb.MessageGameOption{Option: rtb.GOptionRobotRadarMaxRotate, Value: 1})
+			r.Deliver(nop, rtb.MessageGameStarts{})
+			for _, msg := range tt.msgs {
+				r.Deliver(nop, msg)
+			}
+
+			got := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+			if _, got := l.Target(); got != tt.locked {
+				t.Errorf("unexpected lock: got=%v want=%v", got, tt.locked)
+			}
+		})
+	}
+}
+
+// constSource is a rand.Source that always returns the same number.
+type constSource int64
+
+func (s constSource) Int63() int64 { return int64(s) }
+func (s constSource) Seed(int64)   {}
+
+func TestLockJitter(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	l := New(r, w, tr, Config{Jitter: 0.2, Rand: rand.New(constSource(3 << 61))})
+	for _, obs := range []rtb.Observer{w, tr, l} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	for _, msg := range []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotRadarMaxRotate, Value: 1},
+		rtb.MessageGameStarts{},
+		rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+		rtb.MessageInfo{Time: 0.1},
+	} {
+		r.Deliver(nop, msg)
+	}
+
+	// The random factor is 0.75, so the sector is moved 0.1 radians.
+	if got, want := strings.TrimSpace(out.String()), "Sweep 4 1.000000 -0.100000 0.300000"; got != want {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+}
+
+func TestSector(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	s := NewSector(r, w, SectorConfig{})
+	for _, obs := range []rtb.Observer{w, s} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	r.Deliver(nop, rtb.MessageCoordinates{Angle: 1})
+	s.SweepSector(rtb.PartRadar|rtb.PartRobot, 1, 1.5, 0.4)
+	r.Deliver(nop, rtb.MessageInfo{Time: 0.1})
+	r.Deliver(nop, rtb.MessageCoordinates{Angle: 1.01})
+	r.Deliver(nop, rtb.MessageInfo{Time: 0.2})
+	r.Deliver(nop, rtb.MessageCoordinates{Angle: 0.5})
+	r.Deliver(nop, rtb.MessageInfo{Time: 0.3})
+	s.Stop(rtb.PartRadar)
+	r.Deliver(nop, rtb.MessageCoordinates{Angle: 0})
+	r.Deliver(nop, rtb.MessageInfo{Time: 0.4})
+
+	want := []string{"Sweep 4 1.000000 0.300000 0.700000", "Sweep 4 1.000000 0.800000 1.200000"}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+}
+
+func TestRelative(t *testing.T) {
+	tests := []struct {
+		name                   string
+		heading, center, width float64
+		wantRight, wantLeft    float64
+	}{
+		{"Ahead", 0, 0, 1, -0.5, 0.5},
+		{"Turned", math.Pi / 2, math.Pi, 0.2, math.Pi/2 - 0.1, math.Pi/2 + 0.1},
+		{"Wrapped", 3, -3, 0.2, 2*math.Pi - 6 - 0.1, 2*math.Pi - 6 + 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			right, left := Relative(tt.heading, tt.center, tt.width)
+			if math.Abs(right-tt.wantRight) > 1e-9 || math.Abs(left-tt.wantLeft) > 1e-9 {
+				t.Errorf("unexpected angles: got=%v, %v want=%v, %v", right, left, tt.wantRight, tt.wantLeft)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package radar
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/world"
+)
+
+// SectorConfig is the configuration of a Sector.
+type SectorConfig struct {
+	// Tolerance is the change of the heading of the robot, in radians,
+	// after which the sweep is sent again. If zero, 0.02 is used.
+	Tolerance float64
+}
+
+// sector is an absolute sector swept by a part.
+type sector struct {
+	v, center, width float64
+
+	// heading is the heading of the robot when the sweep was sent.
+	heading float64
+	sent    bool
+}
+
+// Sector sweeps the radar and/or the cannon over sectors given in absolute
+// arena bearings. The Sweep command takes angles relative to the robot, so
+// the sweep is sent again every time the robot turns. Sector implements the
+// rtb.Observer interface and must be added to the robot after the world
+// model. Sector methods can be called concurrently.
+type Sector struct {
+	rtb.NopCommand
+
+	cfg SectorConfig
+	r   *rtb.Robot
+	w   *world.World
+
+	mu      sync.Mutex
+	sectors map[rtb.Part]*sector
+}
+
+// NewSector returns a Sector that sends commands through r and uses w to know
+// the heading of the robot.
+func NewSector(r *rtb.Robot, w *world.World, cfg SectorConfig) *Sector {
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 0.02
+	}
+	return &Sector{cfg: cfg, r: r, w: w, sectors: map[rtb.Part]*sector{}}
+}
+
+// SweepSector sweeps the given parts at speed v over the sector centered at
+// the absolute bearing centerAbsAngle with the given width, in radians. The
+// robot cannot sweep, so PartRobot is ignored. The sweep is kept until
+// another one is set for the same parts or Stop is called.
+func (s *Sector) SweepSector(what rtb.Part, v, centerAbsAngle, width float64) {
+	what &^= rtb.PartRobot
+	if what == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	s.remove(what)
+	s.sectors[what] = &sector{v: v, center: centerAbsAngle, width: width}
+	s.mu.Unlock()
+
+	s.update()
+}
+
+// Stop stops sweeping the given parts. The parts keep the last command sent.
+func (s *Sector) Stop(what rtb.Part) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(what)
+}
+
+// remove removes the given parts from the sectors. A sector swept by other
+// parts too is kept for them. s.mu must be held.
+func (s *Sector) remove(what rtb.Part) {
+	for p, sec := range s.sectors {
+		if p&what == 0 {
+			continue
+		}
+		delete(s.sectors, p)
+		if rest := p &^ what; rest != 0 {
+			s.sectors[rest] = sec
+		}
+	}
+}
+
+// Relative returns the right and left angles, relative to the robot, of the
+// sector centered at the absolute bearing center with the given width, for a
+// robot with the given heading. They are the angles expected by the Sweep
+// command.
+func Relative(heading, center, width float64) (right, left float64) {
+	c := physics.NormalizeAngle(center - heading)
+	return c - width/2, c + width/2
+}
+
+// Message sends the sweeps again when msg is an Info message and the robot
+// has turned. The sectors are discarded when a new game starts.
+func (s *Sector) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		s.mu.Lock()
+		s.sectors = map[rtb.Part]*sector{}
+		s.mu.Unlock()
+	case rtb.MessageInfo, rtb.MessageCoordinates:
+		s.update()
+	}
+}
+
+// update sends the sweeps that are outdated.
+func (s *Sector) update() {
+	heading := s.w.State().Heading
+
+	type sweep struct {
+		what        rtb.Part
+		v           float64
+		right, left float64
+	}
+	var sweeps []sweep
+
+	s.mu.Lock()
+	for p, sec := range s.sectors {
+		if sec.sent && math.Abs(physics.NormalizeAngle(heading-sec.heading)) <= s.cfg.Tolerance {
+			continue
+		}
+		sec.heading, sec.sent = heading, true
+		right, left := Relative(heading, sec.center, sec.width)
+		sweeps = append(sweeps, sweep{p, sec.v, right, left})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(sweeps, func(i, j int) bool { return sweeps[i].what < sweeps[j].what })
+
+	// Commands are sent without holding the lock, because observers
+	// could call back the sector.
+	for _, sw := range sweeps {
+		s.r.Sweep(sw.what, sw.v, sw.right, sw.left)
+	}
+}
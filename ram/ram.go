@@ -0,0 +1,266 @@
+// Package ram implements deliberate ramming, a legitimate tactic in
+// RealTimeBattle: robots lose energy when they collide, and the front of a
+// robot is harder and better protected than its side, so ramming an enemy
+// with the front hurts it more than it hurts the rammer.
+//
+// EstimateImpact estimates the damage of a collision from the closing speed,
+// following the collision model of the server. A Rammer decides when ramming
+// is favorable versus shooting and, when engaged, pursues the target with
+// the navigator until it hits it:
+//
+//	rm := ram.New(w, tr, n, e, ram.Config{})
+//	r.AddObserver(rm)
+//	...
+//	if t, ok := tr.Nearest(w.State().Pos); ok && rm.Favorable(t) {
+//		rm.Engage(t.ID)
+//	}
+package ram
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/sim"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Impact is the estimated outcome of a collision.
+type Impact struct {
+	// Closing is the speed at which the robots approach each other
+	// along the line between them.
+	Closing float64
+
+	// Dealt is the energy lost by the target.
+	Dealt float64
+
+	// Taken is the energy lost by the rammer.
+	Taken float64
+}
+
+// EstimateImpact returns the outcome of a collision at the given closing
+// speed, in which the front of the rammer hits the side of the target, given
+// the collision options c of the server.
+func EstimateImpact(c sim.Collisions, closing float64) Impact {
+	if closing <= 0 {
+		return Impact{}
+	}
+	return Impact{
+		Closing: closing,
+		Dealt:   (1 - c.Protection) * c.FrontHardness * closing,
+		Taken:   (1 - c.FrontProtection) * c.Hardness * closing,
+	}
+}
+
+// Config is the configuration of a Rammer.
+type Config struct {
+	// Collisions are the collision options of the server. If zero,
+	// sim.DefaultCollisions is used.
+	Collisions sim.Collisions
+
+	// Speed is the closing speed expected when ramming, if the robots
+	// are not already approaching faster. If zero, 4 is used.
+	Speed float64
+
+	// Range is the maximum distance to the target at which ramming is
+	// considered. If zero, 10 is used.
+	Range float64
+
+	// EnergyMargin is the energy the robot must have over the target to
+	// ram it, since the robots lose energy in every collision. If zero,
+	// 10 is used.
+	EnergyMargin float64
+
+	// Overshoot is the distance beyond the target of the last waypoint
+	// given to the navigator, so the robot does not brake before the
+	// impact. If zero, 3 is used.
+	Overshoot float64
+
+	// MaxAge is the maximum time since the target was seen to keep
+	// pursuing it. If zero, 1 is used.
+	MaxAge float64
+}
+
+// Rammer pursues and rams a target. It implements the rtb.Observer interface
+// and must be added to the robot after the world model, the tracker, the
+// energy manager and the navigator. Rammer methods can be called
+// concurrently.
+type Rammer struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+	n   *nav.Navigator
+	e   *energy.Manager
+
+	mu     sync.Mutex
+	target int
+	hits   int
+}
+
+// New returns a Rammer that pursues the tracks of tr by sending goals to n.
+// If e is nil, the energy available for shooting is not considered by
+// Favorable.
+func New(w *world.World, tr *track.Tracker, n *nav.Navigator, e *energy.Manager, cfg Config) *Rammer {
+	if cfg.Collisions == (sim.Collisions{}) {
+		cfg.Collisions = sim.DefaultCollisions()
+	}
+	if cfg.Speed == 0 {
+		cfg.Speed = 4
+	}
+	if cfg.Range == 0 {
+		cfg.Range = 10
+	}
+	if cfg.EnergyMargin == 0 {
+		cfg.EnergyMargin = 10
+	}
+	if cfg.Overshoot == 0 {
+		cfg.Overshoot = 3
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 1
+	}
+	return &Rammer{cfg: cfg, w: w, tr: tr, n: n, e: e}
+}
+
+// Closing returns the speed at which the robot and t are approaching each
+// other, which is negative if they are moving apart.
+func (rm *Rammer) Closing(t track.Track) float64 {
+	s := rm.w.State()
+	los := t.Pos.Sub(s.Pos)
+	if los.Len() == 0 {
+		return 0
+	}
+	u := los.Mul(1 / los.Len())
+	vel := arena.Polar(s.Heading, s.Speed)
+	return vel.Sub(t.Vel).Dot(u)
+}
+
+// Estimate returns the outcome of ramming t at the current closing speed or
+// at Config.Speed, whichever is greater.
+func (rm *Rammer) Estimate(t track.Track) Impact {
+	return EstimateImpact(rm.cfg.Collisions, math.Max(rm.Closing(t), rm.cfg.Speed))
+}
+
+// Favorable returns true if ramming t is favorable versus shooting it. It
+// is when t is within Config.Range, the robot has Config.EnergyMargin more
+// energy than t and either the impact kills t or it deals more damage than
+// a shot with the energy available for shooting.
+func (rm *Rammer) Favorable(t track.Track) bool {
+	s := rm.w.State()
+	if t.TeamMate || t.Pos.Sub(s.Pos).Len() > rm.cfg.Range {
+		return false
+	}
+	if t.Energy > 0 && s.Energy < t.Energy+rm.cfg.EnergyMargin {
+		return false
+	}
+
+	impact := rm.Estimate(t)
+	if impact.Dealt <= impact.Taken {
+		return false
+	}
+	if t.Energy > 0 && impact.Dealt >= t.Energy {
+		return true
+	}
+	return rm.e == nil || impact.Dealt > rm.e.ShotEnergy()
+}
+
+// Engage starts pursuing the track with the given ID.
+func (rm *Rammer) Engage(id int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.target = id
+}
+
+// Disengage stops pursuing the target. The navigator is stopped.
+func (rm *Rammer) Disengage() {
+	rm.mu.Lock()
+	engaged := rm.target != 0
+	rm.target = 0
+	rm.mu.Unlock()
+
+	if engaged {
+		rm.n.Stop()
+	}
+}
+
+// Target returns the ID of the pursued track. It returns false if the
+// rammer is not engaged.
+func (rm *Rammer) Target() (int, bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.target, rm.target != 0
+}
+
+// Hits returns the number of collisions with robots while engaged in the
+// current game.
+func (rm *Rammer) Hits() int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.hits
+}
+
+// Message pursues the target when msg is an Info message and counts the
+// hits.
+func (rm *Rammer) Message(msg rtb.Message) {
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts:
+		rm.mu.Lock()
+		rm.target, rm.hits = 0, 0
+		rm.mu.Unlock()
+	case rtb.MessageCollision:
+		if msg.Object != rtb.ObjectRobot {
+			return
+		}
+		rm.mu.Lock()
+		if rm.target != 0 {
+			rm.hits++
+		}
+		rm.mu.Unlock()
+	case rtb.MessageInfo:
+		rm.pursue()
+	}
+}
+
+// pursue drives the robot through the intercept point of the target. The
+// target is dropped when it is lost.
+func (rm *Rammer) pursue() {
+	id, ok := rm.Target()
+	if !ok {
+		return
+	}
+
+	s := rm.w.State()
+	var (
+		t     track.Track
+		found bool
+	)
+	for _, tt := range rm.tr.Tracks() {
+		if tt.ID == id {
+			t, found = tt, true
+			break
+		}
+	}
+	if s.Dead || !found || s.Time-t.LastSeen > rm.cfg.MaxAge {
+		rm.Disengage()
+		return
+	}
+
+	p, _, ok := t.Intercept(s.Pos, s.Time, rm.cfg.Speed)
+	if !ok {
+		p = t.PredictPosition(s.Time - t.LastSeen)
+	}
+	dir := p.Sub(s.Pos)
+	if dir.Len() == 0 {
+		return
+	}
+	rm.n.GoTo(p, p.Add(dir.Mul(rm.cfg.Overshoot/dir.Len())))
+}
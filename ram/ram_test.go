@@ -0,0 +1,123 @@
+package ram
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/sim"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestEstimateImpact(t *testing.T) {
+	c := sim.DefaultCollisions()
+	tests := []struct {
+		closing float64
+		want    Impact
+	}{
+		{0, Impact{}},
+		{-1, Impact{}},
+		{2, Impact{Closing: 2, Dealt: 0.9, Taken: 0.1}},
+	}
+
+	for _, tt := range tests {
+		got := EstimateImpact(c, tt.closing)
+		if got.Closing != tt.want.Closing || math.Abs(got.Dealt-tt.want.Dealt) > 1e-9 || math.Abs(got.Taken-tt.want.Taken) > 1e-9 {
+			t.Errorf("unexpected impact for %v: got=%+v want=%+v", tt.closing, got, tt.want)
+		}
+	}
+}
+
+func TestFavorable(t *testing.T) {
+	tests := []struct {
+		name       string
+		energy     float64
+		shotEnergy float64
+		target     track.Track
+		want       bool
+	}{
+		{"Weak target", 50, 30, track.Track{ID: 1, Pos: arena.Point{X: 5}, Energy: 1}, true},
+		{"No shot energy", 50, 0, track.Track{ID: 1, Pos: arena.Point{X: 5}, Energy: 30}, true},
+		{"Shot is better", 50, 30, track.Track{ID: 1, Pos: arena.Point{X: 5}, Energy: 30}, false},
+		{"Out of range", 50, 0, track.Track{ID: 1, Pos: arena.Point{X: 20}, Energy: 1}, false},
+		{"Stronger target", 50, 0, track.Track{ID: 1, Pos: arena.Point{X: 5}, Energy: 45}, false},
+		{"Team mate", 50, 0, track.Track{ID: 1, Pos: arena.Point{X: 5}, Energy: 1, TeamMate: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := world.New()
+			e := energy.New(energy.Config{})
+			msgs := []rtb.Message{
+				rtb.MessageGameOption{Option: rtb.GOptionShotMaxEnergy, Value: tt.shotEnergy},
+				rtb.MessageGameStarts{},
+				rtb.MessageCoordinates{},
+				rtb.MessageEnergy{EnergyLevel: tt.energy},
+				rtb.MessageInfo{Time: 0},
+			}
+			for _, msg := range msgs {
+				w.Message(msg)
+				e.Message(msg)
+			}
+
+			rm := New(w, nil, nil, e, Config{})
+			if got := rm.Favorable(tt.target); got != tt.want {
+				t.Errorf("got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPursue(t *testing.T) {
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	n := nav.New(r, w, nav.Config{})
+	rm := New(w, tr, n, nil, Config{})
+	for _, obs := range []rtb.Observer{w, tr, rm} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	r.Deliver(nop, rtb.MessageCoordinates{})
+	r.Deliver(nop, rtb.MessageRadar{Distance: 8, Object: rtb.ObjectRobot, RadarAngle: 0})
+	r.Deliver(nop, rtb.MessageInfo{Time: 0})
+
+	tracks := tr.Tracks()
+	if len(tracks) != 1 {
+		t.Fatalf("unexpected number of tracks: got=%v want=%v", len(tracks), 1)
+	}
+	rm.Engage(tracks[0].ID)
+	r.Deliver(nop, rtb.MessageInfo{Time: 0.1})
+
+	want := []arena.Point{{X: 8}, {X: 11}}
+	got := n.Waypoints()
+	if len(got) != len(want) {
+		t.Fatalf("unexpected waypoints: got=%v want=%v", got, want)
+	}
+	for i := range want {
+		if got[i].Sub(want[i]).Len() > 1e-9 {
+			t.Errorf("unexpected waypoint: got=%v want=%v", got[i], want[i])
+		}
+	}
+
+	r.Deliver(nop, rtb.MessageCollision{Object: rtb.ObjectRobot})
+	if got := rm.Hits(); got != 1 {
+		t.Errorf("unexpected hits: got=%v want=%v", got, 1)
+	}
+
+	// The target is lost.
+	r.Deliver(nop, rtb.MessageInfo{Time: 2})
+	if _, ok := rm.Target(); ok {
+		t.Errorf("target not dropped")
+	}
+	if !n.Done() {
+		t.Errorf("navigator not stopped")
+	}
+}
@@ -0,0 +1,364 @@
+// Package ratings computes ratings of strategy versions from the match
+// results persisted by the results package, so the versions can be ranked
+// and a change can be accepted only if it is a significant improvement.
+//
+// Matches with more than two robots are decomposed in pairwise games: a robot
+// wins against every robot with a lower score, loses against every robot with
+// a higher score and draws with the rest.
+package ratings
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jroimartin/rtb/results"
+)
+
+// Key identifies a version of a robot.
+type Key struct {
+	Name, Version string
+}
+
+// Rating is the rating of a version of a robot.
+type Rating struct {
+	Key
+
+	// Rating is the value used to rank the robots. For TrueSkill, it is
+	// the conservative estimation Mu-3*Sigma.
+	Rating float64
+
+	// Mu and Sigma are the mean and the standard deviation of the skill
+	// estimated by TrueSkill. They are zero for Elo.
+	Mu, Sigma float64
+
+	// Matches is the number of matches played.
+	Matches int
+}
+
+// EloConfig is the configuration of the Elo rating system.
+type EloConfig struct {
+	// Initial is the rating of the robots without matches. If zero, 1500
+	// is used.
+	Initial float64
+
+	// K is the maximum change of the rating in a match. If zero, 32 is
+	// used. In matches with more than two robots, it is divided by the
+	// number of opponents.
+	K float64
+}
+
+// Elo returns the Elo ratings of the robots in ms, which are processed in
+// order, sorted from best to worst.
+func Elo(ms []results.Match, cfg EloConfig) []Rating {
+	if cfg.Initial == 0 {
+		cfg.Initial = 1500
+	}
+	if cfg.K == 0 {
+		cfg.K = 32
+	}
+
+	rs := newRatingSet(func(k Key) *Rating {
+		return &Rating{Key: k, Rating: cfg.Initial}
+	})
+	for _, m := range ms {
+		if len(m.Robots) < 2 {
+			continue
+		}
+
+		// The expected scores are computed with the ratings before
+		// the match.
+		k := cfg.K / float64(len(m.Robots)-1)
+		deltas := make([]float64, len(m.Robots))
+		for i, ri := range m.Robots {
+			a := rs.get(key(ri))
+			for j, rj := range m.Robots {
+				if i == j {
+					continue
+				}
+				b := rs.get(key(rj))
+				expected := 1 / (1 + math.Pow(10, (b.Rating-a.Rating)/400))
+				deltas[i] += k * (outcome(ri, rj) - expected)
+			}
+		}
+		for i, r := range m.Robots {
+			a := rs.get(key(r))
+			a.Rating += deltas[i]
+			a.Matches++
+		}
+	}
+	return rs.sorted()
+}
+
+// TrueSkillConfig is the configuration of the TrueSkill rating system.
+type TrueSkillConfig struct {
+	// Mu and Sigma are the initial mean and standard deviation of the
+	// skill. If zero, 25 and 25/3 are used.
+	Mu, Sigma float64
+
+	// Beta is the standard deviation of the performance in a match. If
+	// zero, Sigma/2 is used.
+	Beta float64
+
+	// Tau is the dynamic factor added to the standard deviation before
+	// every match, so the skill can change over time. If zero,
+	// Sigma/100 is used.
+	Tau float64
+
+	// DrawProbability is the probability of a draw between robots of
+	// the same skill. If zero, 0.1 is used.
+	DrawProbability float64
+}
+
+// TrueSkill returns the TrueSkill ratings of the robots in ms, which are
+// processed in order, sorted from best to worst. Matches with more than two
+// robots are approximated by updating the ratings after every pairwise game,
+// instead of using the full factor graph.
+func TrueSkill(ms []results.Match, cfg TrueSkillConfig) []Rating {
+	if cfg.Mu == 0 {
+		cfg.Mu = 25
+	}
+	if cfg.Sigma == 0 {
+		cfg.Sigma = 25.0 / 3
+	}
+	if cfg.Beta == 0 {
+		cfg.Beta = cfg.Sigma / 2
+	}
+	if cfg.Tau == 0 {
+		cfg.Tau = cfg.Sigma / 100
+	}
+	if cfg.DrawProbability == 0 {
+		cfg.DrawProbability = 0.1
+	}
+	margin := normQuantile((cfg.DrawProbability+1)/2) * math.Sqrt2 * cfg.Beta
+
+	rs := newRatingSet(func(k Key) *Rating {
+		return &Rating{Key: k, Mu: cfg.Mu, Sigma: cfg.Sigma}
+	})
+	for _, m := range ms {
+		if len(m.Robots) < 2 {
+			continue
+		}
+
+		for _, r := range m.Robots {
+			a := rs.get(key(r))
+			a.Sigma = math.Sqrt(a.Sigma*a.Sigma + cfg.Tau*cfg.Tau)
+			a.Matches++
+		}
+		for i, ri := range m.Robots {
+			for _, rj := range m.Robots[i+1:] {
+				a, b := rs.get(key(ri)), rs.get(key(rj))
+				switch outcome(ri, rj) {
+				case 0:
+					trueSkillUpdate(b, a, cfg.Beta, margin, false)
+				case 0.5:
+					trueSkillUpdate(a, b, cfg.Beta, margin, true)
+				case 1:
+					trueSkillUpdate(a, b, cfg.Beta, margin, false)
+				}
+			}
+		}
+	}
+
+	for _, r := range rs.ratings {
+		r.Rating = r.Mu - 3*r.Sigma
+	}
+	return rs.sorted()
+}
+
+// trueSkillUpdate updates the ratings of the winner w and the loser l of a
+// game. If draw is true, the game was a draw.
+func trueSkillUpdate(w, l *Rating, beta, margin float64, draw bool) {
+	c2 := 2*beta*beta + w.Sigma*w.Sigma + l.Sigma*l.Sigma
+	c := math.Sqrt(c2)
+	t := (w.Mu - l.Mu) / c
+	eps := margin / c
+
+	var v, f float64
+	if draw {
+		d := normCDF(eps-t) - normCDF(-eps-t)
+		if d <= 0 {
+			return
+		}
+		v = (normPDF(-eps-t) - normPDF(eps-t)) / d
+		f = v*v + ((eps-t)*normPDF(eps-t)+(eps+t)*normPDF(eps+t))/d
+	} else {
+		// Use the asymptote of v for very unexpected wins, when the
+		// CDF underflows.
+		v = -(t - eps)
+		if d := normCDF(t - eps); d > 0 {
+			v = normPDF(t-eps) / d
+		}
+		f = v * (v + t - eps)
+	}
+
+	w2, l2 := w.Sigma*w.Sigma, l.Sigma*l.Sigma
+	w.Mu += w2 / c * v
+	l.Mu -= l2 / c * v
+	w.Sigma = math.Sqrt(w2 * math.Max(1-w2/c2*f, 0))
+	l.Sigma = math.Sqrt(l2 * math.Max(1-l2/c2*f, 0))
+}
+
+// Comparison is the comparison of the scores of a candidate version of a
+// robot with a baseline.
+type Comparison struct {
+	// Candidate and Baseline are the compared versions.
+	Candidate, Baseline Key
+
+	// CandidateMatches and BaselineMatches are the number of matches
+	// played by every version.
+	CandidateMatches, BaselineMatches int
+
+	// CandidateScore and BaselineScore are the average scores per match.
+	CandidateScore, BaselineScore float64
+
+	// Diff is CandidateScore minus BaselineScore.
+	Diff float64
+
+	// Z is the test statistic of Welch's test of the difference of the
+	// average scores.
+	Z float64
+
+	// PValue is the one-sided p-value of the hypothesis that the
+	// candidate is better than the baseline. It is computed with the
+	// normal approximation, so it requires a few dozens of matches per
+	// version to be accurate.
+	PValue float64
+}
+
+// Significant reports whether the candidate is better than the baseline at
+// the significance level alpha, e.g. 0.05.
+func (c Comparison) Significant(alpha float64) bool {
+	return c.Diff > 0 && c.PValue < alpha
+}
+
+// Compare compares the scores of candidate and baseline in ms. To be fair,
+// both versions should have played against the same opponents.
+func Compare(ms []results.Match, candidate, baseline Key) Comparison {
+	var cs, bs []float64
+	for _, m := range ms {
+		for _, r := range m.Robots {
+			switch key(r) {
+			case candidate:
+				cs = append(cs, r.Score)
+			case baseline:
+				bs = append(bs, r.Score)
+			}
+		}
+	}
+
+	cmp := Comparison{
+		Candidate:        candidate,
+		Baseline:         baseline,
+		CandidateMatches: len(cs),
+		BaselineMatches:  len(bs),
+		PValue:           1,
+	}
+	if len(cs) < 2 || len(bs) < 2 {
+		return cmp
+	}
+
+	cm, cv := meanVar(cs)
+	bm, bv := meanVar(bs)
+	cmp.CandidateScore, cmp.BaselineScore = cm, bm
+	cmp.Diff = cm - bm
+
+	se := math.Sqrt(cv/float64(len(cs)) + bv/float64(len(bs)))
+	switch {
+	case se > 0:
+		cmp.Z = cmp.Diff / se
+		cmp.PValue = 1 - normCDF(cmp.Z)
+	case cmp.Diff > 0:
+		// Both versions always got the same score.
+		cmp.Z, cmp.PValue = math.Inf(1), 0
+	}
+	return cmp
+}
+
+// meanVar returns the mean and the sample variance of xs.
+func meanVar(xs []float64) (mean, variance float64) {
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean)
+	}
+	variance /= float64(len(xs) - 1)
+	return mean, variance
+}
+
+// key returns the key of r.
+func key(r results.RobotResult) Key {
+	return Key{Name: r.Name, Version: r.Version}
+}
+
+// outcome returns the result of the pairwise game between a and b, from the
+// point of view of a: 1 for a win, 0.5 for a draw and 0 for a loss.
+func outcome(a, b results.RobotResult) float64 {
+	switch {
+	case a.Score > b.Score:
+		return 1
+	case a.Score < b.Score:
+		return 0
+	}
+	return 0.5
+}
+
+// ratingSet is a set of ratings.
+type ratingSet struct {
+	init    func(Key) *Rating
+	ratings map[Key]*Rating
+}
+
+// newRatingSet returns a ratingSet that uses init to create the ratings of
+// new robots.
+func newRatingSet(init func(Key) *Rating) *ratingSet {
+	return &ratingSet{init: init, ratings: map[Key]*Rating{}}
+}
+
+// get returns the rating of k, creating it if needed.
+func (rs *ratingSet) get(k Key) *Rating {
+	r, ok := rs.ratings[k]
+	if !ok {
+		r = rs.init(k)
+		rs.ratings[k] = r
+	}
+	return r
+}
+
+// sorted returns the ratings sorted from best to worst. Ties are sorted by
+// name and version, so the order is deterministic.
+func (rs *ratingSet) sorted() []Rating {
+	var ratings []Rating
+	for _, r := range rs.ratings {
+		ratings = append(ratings, *r)
+	}
+	sort.Slice(ratings, func(i, j int) bool {
+		ri, rj := ratings[i], ratings[j]
+		if ri.Rating != rj.Rating {
+			return ri.Rating > rj.Rating
+		}
+		if ri.Name != rj.Name {
+			return ri.Name < rj.Name
+		}
+		return ri.Version < rj.Version
+	})
+	return ratings
+}
+
+// normPDF is the probability density function of the standard normal
+// distribution.
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+// normCDF is the cumulative distribution function of the standard normal
+// distribution.
+func normCDF(x float64) float64 {
+	return math.Erfc(-x/math.Sqrt2) / 2
+}
+
+// normQuantile is the inverse of normCDF.
+func normQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -0,0 +1,153 @@
+package ratings
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb/results"
+)
+
+// duel returns a match between a and b with the given score of a.
+func duel(a, b Key, score float64) results.Match {
+	return results.Match{
+		Robots: []results.RobotResult{
+			{Name: a.Name, Version: a.Version, Score: score},
+			{Name: b.Name, Version: b.Version, Score: 1 - score},
+		},
+	}
+}
+
+var (
+	turret1 = Key{"turret", "v1"}
+	turret2 = Key{"turret", "v2"}
+	duck    = Key{"duck", ""}
+)
+
+func TestElo(t *testing.T) {
+	ms := []results.Match{duel(turret1, duck, 1)}
+	got := Elo(ms, EloConfig{})
+	want := []Rating{
+		{Key: turret1, Rating: 1516, Matches: 1},
+		{Key: duck, Rating: 1484, Matches: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of ratings: got=%v want=%v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrong rating %v: got=%+v want=%+v", i, got[i], want[i])
+		}
+	}
+
+	ms = append(ms, duel(turret1, duck, 0.5))
+	got = Elo(ms, EloConfig{})
+	if d := got[0].Rating - 1516; d >= 0 || d < -2 {
+		t.Errorf("unexpected rating after draw: %v", got[0].Rating)
+	}
+	if sum := got[0].Rating + got[1].Rating; math.Abs(sum-3000) > 1e-9 {
+		t.Errorf("ratings are not zero-sum: got=%v want=3000", sum)
+	}
+}
+
+func TestEloMultiplayer(t *testing.T) {
+	m := results.Match{
+		Robots: []results.RobotResult{
+			{Name: "a", Score: 1},
+			{Name: "b", Score: 0},
+			{Name: "c", Score: 0},
+		},
+	}
+	got := Elo([]results.Match{m}, EloConfig{K: 20})
+	want := map[string]float64{"a": 1510, "b": 1495, "c": 1495}
+	for _, r := range got {
+		if r.Rating != want[r.Name] {
+			t.Errorf("wrong rating of %v: got=%v want=%v", r.Name, r.Rating, want[r.Name])
+		}
+	}
+	if got[0].Name != "a" || got[1].Name != "b" || got[2].Name != "c" {
+		t.Errorf("wrong order: %+v", got)
+	}
+}
+
+func TestTrueSkill(t *testing.T) {
+	ms := []results.Match{duel(turret1, duck, 1)}
+	got := TrueSkill(ms, TrueSkillConfig{Tau: 1e-9})
+
+	// Reference values of a 1 vs 1 game with the default settings and
+	// no dynamics.
+	want := map[Key][2]float64{
+		turret1: {29.396, 7.171},
+		duck:    {20.604, 7.171},
+	}
+	for _, r := range got {
+		w := want[r.Key]
+		if math.Abs(r.Mu-w[0]) > 1e-3 || math.Abs(r.Sigma-w[1]) > 1e-3 {
+			t.Errorf("wrong rating of %v: got=(%v, %v) want=%v", r.Key, r.Mu, r.Sigma, w)
+		}
+		if r.Rating != r.Mu-3*r.Sigma {
+			t.Errorf("wrong conservative rating: %+v", r)
+		}
+	}
+	if got[0].Key != turret1 {
+		t.Errorf("wrong order: %+v", got)
+	}
+
+	// A draw between equal robots only reduces the uncertainty.
+	got = TrueSkill([]results.Match{duel(turret1, duck, 0.5)}, TrueSkillConfig{Tau: 1e-9})
+	for _, r := range got {
+		if math.Abs(r.Mu-25) > 1e-9 || r.Sigma >= 25.0/3 {
+			t.Errorf("unexpected rating after draw: %+v", r)
+		}
+	}
+
+	// An unexpected win must not produce NaNs.
+	for i := 0; i < 100; i++ {
+		ms = append(ms, duel(turret1, duck, 1))
+	}
+	ms = append(ms, duel(duck, turret1, 1))
+	for _, r := range TrueSkill(ms, TrueSkillConfig{}) {
+		if math.IsNaN(r.Mu) || math.IsNaN(r.Sigma) {
+			t.Errorf("invalid rating: %+v", r)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// v1 wins half of the matches and v2 three out of four.
+	var ms []results.Match
+	for i := 0; i < 40; i++ {
+		v1, v2 := 0.0, 0.0
+		if i%2 == 0 {
+			v1 = 1
+		}
+		if i%4 != 0 {
+			v2 = 1
+		}
+		ms = append(ms, duel(turret1, duck, v1))
+		ms = append(ms, duel(turret2, duck, v2))
+	}
+
+	cmp := Compare(ms, turret2, turret1)
+	if cmp.CandidateMatches != 40 || cmp.BaselineMatches != 40 {
+		t.Errorf("wrong number of matches: %+v", cmp)
+	}
+	if cmp.CandidateScore != 0.75 || cmp.BaselineScore != 0.5 || cmp.Diff != 0.25 {
+		t.Errorf("wrong scores: %+v", cmp)
+	}
+	if !cmp.Significant(0.05) {
+		t.Errorf("improvement not significant: %+v", cmp)
+	}
+	if cmp.Significant(0.001) {
+		t.Errorf("improvement significant at 0.001: %+v", cmp)
+	}
+
+	rev := Compare(ms, turret1, turret2)
+	if rev.Significant(0.05) || rev.PValue < 0.95 {
+		t.Errorf("regression reported as improvement: %+v", rev)
+	}
+
+	few := Compare(ms[:2], turret2, turret1)
+	if few.PValue != 1 || few.Significant(0.05) {
+		t.Errorf("significant with few matches: %+v", few)
+	}
+}
@@ -0,0 +1,212 @@
+// Package recovery implements an automatic recovery maneuver for collisions.
+//
+// When the robot collides with a wall, a robot or a mine, the Handler takes
+// control of the robot: it backs off, rotates away from the collision and
+// gives the control back to the navigator, which resumes its previous goal.
+// It can be dropped into any strategy by adding it as an observer after the
+// world model and the navigator:
+//
+//	r.AddObserver(w)
+//	r.AddObserver(n)
+//	r.AddObserver(recovery.New(r, w, n, recovery.Config{}))
+package recovery
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Class is the class of a collision.
+type Class int
+
+// Collision classes.
+const (
+	// ClassIgnore is the class of the collisions that do not require
+	// any response, like eating a cookie.
+	ClassIgnore Class = iota
+
+	// ClassObstacle is the class of the collisions with static
+	// obstacles: walls and mines. The robot must move away.
+	ClassObstacle
+
+	// ClassRobot is the class of the collisions with other robots. The
+	// robot must move away, since both robots are damaged.
+	ClassRobot
+
+	// ClassShot is the class of the collisions with shots. The robot
+	// cannot avoid a shot that has already hit it, so there is no
+	// maneuver.
+	ClassShot
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassIgnore:
+		return "Ignore"
+	case ClassObstacle:
+		return "Obstacle"
+	case ClassRobot:
+		return "Robot"
+	case ClassShot:
+		return "Shot"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify returns the class of a collision with obj.
+func Classify(obj rtb.Object) Class {
+	switch obj {
+	case rtb.ObjectWall, rtb.ObjectMine:
+		return ClassObstacle
+	case rtb.ObjectRobot:
+		return ClassRobot
+	case rtb.ObjectShot:
+		return ClassShot
+	default:
+		return ClassIgnore
+	}
+}
+
+// Config is the configuration of a Handler.
+type Config struct {
+	// BackOffTime is the time spent backing off. If zero, 0.5 is used.
+	BackOffTime float64
+
+	// TurnAngle is the angle the robot rotates away from the collision.
+	// If zero, pi/2 is used.
+	TurnAngle float64
+
+	// OnCollision, if not nil, is called for every collision with its
+	// class and its angle relative to the robot front.
+	OnCollision func(class Class, angle float64)
+}
+
+// phase is a phase of the maneuver.
+type phase int
+
+const (
+	phaseIdle phase = iota
+	phaseBackOff
+	phaseTurn
+)
+
+// Handler responds to collisions with a recovery maneuver. It implements the
+// rtb.Observer interface. Handler methods can be called concurrently.
+type Handler struct {
+	rtb.NopCommand
+
+	cfg Config
+	r   *rtb.Robot
+	w   *world.World
+	n   *nav.Navigator
+
+	mu    sync.Mutex
+	phase phase
+	angle float64
+	until float64
+}
+
+// New returns a Handler that sends commands through r and uses w to know the
+// game time and options. If n is not nil, it is suspended during the
+// maneuver.
+func New(r *rtb.Robot, w *world.World, n *nav.Navigator, cfg Config) *Handler {
+	if cfg.BackOffTime == 0 {
+		cfg.BackOffTime = 0.5
+	}
+	if cfg.TurnAngle == 0 {
+		cfg.TurnAngle = math.Pi / 2
+	}
+	return &Handler{cfg: cfg, r: r, w: w, n: n}
+}
+
+// Active returns true if a maneuver is in progress.
+func (h *Handler) Active() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.phase != phaseIdle
+}
+
+// Message starts a maneuver when msg is a collision that requires it and
+// advances the maneuver when msg is an Info message.
+func (h *Handler) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		h.mu.Lock()
+		h.phase = phaseIdle
+		h.mu.Unlock()
+	case rtb.MessageCollision:
+		class := Classify(m.Object)
+		if h.cfg.OnCollision != nil {
+			h.cfg.OnCollision(class, m.Angle)
+		}
+		if class == ClassObstacle || class == ClassRobot {
+			h.start(m.Angle)
+		}
+	case rtb.MessageInfo:
+		h.advance(m.Time)
+	}
+}
+
+// start starts the back off phase. A new collision restarts the maneuver.
+func (h *Handler) start(angle float64) {
+	h.mu.Lock()
+	h.phase = phaseBackOff
+	h.angle = angle
+	h.until = h.w.State().Time + h.cfg.BackOffTime
+	h.mu.Unlock()
+
+	if h.n != nil {
+		h.n.SetSuspended(true)
+	}
+
+	// Back off in the direction opposite to the collision. If the
+	// collision was behind, move forward instead.
+	accel, _ := h.w.Option(rtb.GOptionRobotMinAcceleration)
+	if math.Abs(angle) > math.Pi/2 {
+		accel, _ = h.w.Option(rtb.GOptionRobotMaxAcceleration)
+	}
+	h.r.Brake(0)
+	h.r.Rotate(rtb.PartRobot, 0)
+	h.r.Accelerate(accel)
+}
+
+// advance moves the maneuver to the next phase when the current one is
+// finished.
+func (h *Handler) advance(now float64) {
+	h.mu.Lock()
+	if h.phase == phaseIdle || now < h.until {
+		h.mu.Unlock()
+		return
+	}
+
+	switch h.phase {
+	case phaseBackOff:
+		// Rotate so the collision is left further behind.
+		turn := -math.Copysign(h.cfg.TurnAngle, h.angle)
+		speed, ok := h.w.Option(rtb.GOptionRobotMaxRotate)
+		if !ok || speed <= 0 {
+			speed = math.Pi / 4
+		}
+		h.phase = phaseTurn
+		h.until = now + math.Abs(turn)/speed
+		h.mu.Unlock()
+
+		h.r.Accelerate(0)
+		h.r.Brake(1)
+		h.r.RotateAmount(rtb.PartRobot, speed, turn)
+	case phaseTurn:
+		h.phase = phaseIdle
+		h.mu.Unlock()
+
+		h.r.Brake(0)
+		if h.n != nil {
+			h.n.SetSuspended(false)
+		}
+	}
+}
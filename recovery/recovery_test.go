@@ -0,0 +1,119 @@
+package recovery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		obj  rtb.Object
+		want Class
+	}{
+		{rtb.ObjectWall, ClassObstacle},
+		{rtb.ObjectMine, ClassObstacle},
+		{rtb.ObjectRobot, ClassRobot},
+		{rtb.ObjectShot, ClassShot},
+		{rtb.ObjectCookie, ClassIgnore},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.obj); got != tt.want {
+			t.Errorf("unexpected class for %v: got=%v want=%v", tt.obj, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	w := world.New()
+	n := nav.New(r, w, nav.Config{})
+
+	var classes []Class
+	h := New(r, w, n, Config{
+		OnCollision: func(class Class, angle float64) { classes = append(classes, class) },
+	})
+	for _, obs := range []rtb.Observer{w, n, h} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	deliver := func(msgs ...rtb.Message) string {
+		out.Reset()
+		for _, msg := range msgs {
+			r.Deliver(nop, msg)
+		}
+		return strings.TrimSuffix(out.String(), "\n")
+	}
+
+	deliver(
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxRotate, Value: 0.5},
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxAcceleration, Value: 2},
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMinAcceleration, Value: -0.5},
+		rtb.MessageGameStarts{},
+		rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0},
+	)
+	n.GoTo(arena.Point{X: 10, Y: 0})
+
+	tests := []struct {
+		name string
+		msgs []rtb.Message
+		want string
+	}{
+		{
+			"Navigate",
+			[]rtb.Message{rtb.MessageInfo{Time: 0}},
+			"Rotate 1 0.000000\nAccelerate 2.000000\nBrake 0.000000",
+		},
+		{
+			"Cookie",
+			[]rtb.Message{rtb.MessageCollision{Object: rtb.ObjectCookie, Angle: 0}},
+			"",
+		},
+		{
+			"Collision",
+			[]rtb.Message{rtb.MessageCollision{Object: rtb.ObjectWall, Angle: 0.1}},
+			"Brake 0.000000\nRotate 1 0.000000\nAccelerate -0.500000",
+		},
+		{
+			"Back off",
+			[]rtb.Message{rtb.MessageInfo{Time: 0.25}},
+			"",
+		},
+		{
+			"Turn",
+			[]rtb.Message{rtb.MessageInfo{Time: 0.5}},
+			"Accelerate 0.000000\nBrake 1.000000\nRotateAmount 1 0.500000 -1.570796",
+		},
+		{
+			"Resume",
+			[]rtb.Message{rtb.MessageInfo{Time: 3.75}},
+			"Brake 0.000000",
+		},
+		{
+			"Navigate again",
+			[]rtb.Message{rtb.MessageInfo{Time: 4}},
+			"Rotate 1 0.000000\nAccelerate 2.000000\nBrake 0.000000",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := deliver(tt.msgs...); got != tt.want {
+			t.Errorf("%v: unexpected commands: got=%q want=%q", tt.name, got, tt.want)
+		}
+	}
+
+	if len(classes) != 2 || classes[0] != ClassIgnore || classes[1] != ClassObstacle {
+		t.Errorf("unexpected classes: %v", classes)
+	}
+	if h.Active() {
+		t.Errorf("maneuver still active")
+	}
+}
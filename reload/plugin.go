@@ -0,0 +1,87 @@
+package reload
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"plugin"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// pluginSource is a Source that loads Go plugins.
+type pluginSource struct {
+	path string
+}
+
+// Plugin returns a Source that loads the Go plugin at path. The plugin must
+// export a function named NewStrategy of type func() rtb.Strategy, and must
+// be built with the same version of Go and of the packages it shares with
+// the robot.
+//
+// Go plugins cannot be unloaded, so every reload keeps the previous version
+// in memory. Plugins are only supported on some platforms and require cgo.
+func Plugin(path string) Source {
+	return pluginSource{path: path}
+}
+
+// Load copies the plugin to a temporary file and opens the copy. The plugin
+// package caches plugins by path, so opening the original file again would
+// return the first version.
+func (src pluginSource) Load() (rtb.Strategy, func() error, error) {
+	tmp, err := copyTemp(src.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.Remove(tmp)
+
+	p, err := plugin.Open(tmp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open plugin: %v", err)
+	}
+	sym, err := p.Lookup("NewStrategy")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not find NewStrategy: %v", err)
+	}
+	newStrategy, ok := sym.(func() rtb.Strategy)
+	if !ok {
+		return nil, nil, fmt.Errorf("NewStrategy has type %T, want func() rtb.Strategy", sym)
+	}
+	return newStrategy(), func() error { return nil }, nil
+}
+
+// Modified returns the modification time of the plugin file.
+func (src pluginSource) Modified() (time.Time, error) {
+	fi, err := os.Stat(src.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// copyTemp copies the file at path to a new temporary file with the same
+// extension and returns its name.
+func copyTemp(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("could not open plugin: %v", err)
+	}
+	defer f.Close()
+
+	tmp, err := os.CreateTemp("", "rtb-*"+filepath.Ext(path))
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %v", err)
+	}
+	if _, err := io.Copy(tmp, f); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not copy plugin: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("could not copy plugin: %v", err)
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,137 @@
+package reload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// killTimeout is the time a process is given to exit after its input is
+// closed, before it is killed.
+const killTimeout = time.Second
+
+// processSource is a Source that runs executables.
+type processSource struct {
+	path string
+	args []string
+}
+
+// Process returns a Source that runs the executable at path with the
+// arguments args. The process receives the messages of the robot on its
+// standard input, one per line, as sent by the server, and each line it
+// writes to its standard output is sent as a command. Its standard error is
+// the standard error of the robot. The process is stopped by closing its
+// standard input and killed if it does not exit within a second.
+func Process(path string, args ...string) Source {
+	return processSource{path: path, args: args}
+}
+
+// Load starts the process.
+func (src processSource) Load() (rtb.Strategy, func() error, error) {
+	cmd := exec.Command(src.path, src.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("could not start process: %v", err)
+	}
+
+	p := &process{cmd: cmd, stdin: stdin, done: make(chan error, 1)}
+	go p.forward(stdout)
+	return p, p.stop, nil
+}
+
+// Modified returns the modification time of the executable.
+func (src processSource) Modified() (time.Time, error) {
+	path, err := exec.LookPath(src.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// process is a strategy implemented by a running process.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	done  chan error
+
+	mu sync.Mutex
+	r  *rtb.Robot
+}
+
+// Handle writes msg to the standard input of the process.
+func (p *process) Handle(r *rtb.Robot, msg rtb.Message) {
+	p.mu.Lock()
+	p.r = r
+	p.mu.Unlock()
+
+	line, err := rtb.EncodeMessage(msg)
+	if err != nil {
+		r.Logger().Error("could not encode message", "err", err)
+		return
+	}
+	if _, err := io.WriteString(p.stdin, line+"\n"); err != nil {
+		r.Logger().Error("could not write to process", "err", err)
+	}
+}
+
+// forward sends the lines written by the process as commands, until the
+// process closes its standard output.
+func (p *process) forward(stdout io.Reader) {
+	s := bufio.NewScanner(stdout)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		p.mu.Lock()
+		r := p.r
+		p.mu.Unlock()
+
+		// The process does not receive messages before it is
+		// loaded, so it has nothing to reply to yet.
+		if r == nil {
+			continue
+		}
+		args := make([]any, len(fields)-1)
+		for i, f := range fields[1:] {
+			args[i] = f
+		}
+		if err := r.SendRaw(fields[0], args...); err != nil {
+			r.Logger().Error("could not send command", "err", err)
+		}
+	}
+	p.done <- p.cmd.Wait()
+}
+
+// stop closes the standard input of the process and waits for it to exit.
+func (p *process) stop() error {
+	p.stdin.Close()
+	select {
+	case err := <-p.done:
+		return err
+	case <-time.After(killTimeout):
+		p.cmd.Process.Kill()
+		<-p.done
+		return fmt.Errorf("process killed after %v", killTimeout)
+	}
+}
@@ -0,0 +1,131 @@
+// Package reload runs strategies that are loaded at run time and reloaded
+// between games, so a robot connected to a live server picks up a new
+// version of its strategy without restarting the robot process.
+//
+// Strategies are loaded from a Source. Plugin loads a Go plugin built with
+// "go build -buildmode=plugin" that exports a NewStrategy function:
+//
+//	func NewStrategy() rtb.Strategy
+//
+// Process runs an executable that speaks the RealTimeBattle protocol on its
+// standard input and output, so any robot, written in Go or not, can be
+// reloaded. The messages received by the robot are forwarded to the process
+// and the lines written by the process are sent as commands.
+//
+// When a game finishes, the Strategy checks if the source was modified and,
+// if so, loads it again. The new strategy receives the Initialize (with
+// First set to false), YourName, YourColour and GameOption messages received
+// so far, followed by the messages of the next game. If the source cannot be
+// loaded, the error is logged and the previous strategy is kept.
+package reload
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// A Source loads strategies.
+type Source interface {
+	// Load loads the strategy. The returned function releases the
+	// resources of the strategy and is called before loading it
+	// again.
+	Load() (s rtb.Strategy, release func() error, err error)
+
+	// Modified returns the time the source was last modified.
+	Modified() (time.Time, error)
+}
+
+// Strategy is a strategy that reloads its source between games.
+type Strategy struct {
+	src Source
+
+	mu       sync.Mutex
+	cur      rtb.Strategy
+	release  func() error
+	modified time.Time
+	setup    []rtb.Message
+}
+
+// New loads src and returns a Strategy that runs it.
+func New(src Source) (*Strategy, error) {
+	s := &Strategy{src: src}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load loads the source, replacing the current strategy. It returns the
+// release function of the previous strategy, if any.
+func (s *Strategy) load() (release func() error, err error) {
+	modified, err := s.src.Modified()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat source: %v", err)
+	}
+	cur, rel, err := s.src.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load strategy: %v", err)
+	}
+	release = s.release
+	s.cur, s.release, s.modified = cur, rel, modified
+	return release, nil
+}
+
+// Handle passes msg to the current strategy. After a MessageGameFinishes, it
+// reloads the source if it was modified.
+func (s *Strategy) Handle(r *rtb.Robot, msg rtb.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur.Handle(r, msg)
+
+	switch m := rtb.CopyMessage(msg).(type) {
+	case rtb.MessageInitialize:
+		s.setup = append(s.setup, rtb.MessageInitialize{First: false})
+	case rtb.MessageYourName, rtb.MessageYourColour, rtb.MessageGameOption:
+		s.setup = append(s.setup, m)
+	case rtb.MessageGameFinishes:
+		s.reload(r)
+	}
+}
+
+// reload loads the source again if it was modified, and replays the setup
+// messages to the new strategy.
+func (s *Strategy) reload(r *rtb.Robot) {
+	modified, err := s.src.Modified()
+	if err != nil {
+		r.Logger().Error("could not stat source", "err", err)
+		return
+	}
+	if !modified.After(s.modified) {
+		return
+	}
+	release, err := s.load()
+	if err != nil {
+		r.Logger().Error("could not reload strategy", "err", err)
+		return
+	}
+	if err := release(); err != nil {
+		r.Logger().Warn("could not release previous strategy", "err", err)
+	}
+	r.Logger().Info("strategy reloaded", "modified", modified)
+	for _, msg := range s.setup {
+		s.cur.Handle(r, msg)
+	}
+}
+
+// Close releases the current strategy.
+func (s *Strategy) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.release == nil {
+		return nil
+	}
+	err := s.release()
+	s.release = nil
+	return err
+}
@@ -0,0 +1,167 @@
+package reload
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// fakeSource is a Source whose strategies print their version and the
+// messages they receive.
+type fakeSource struct {
+	version  int
+	modified time.Time
+	fail     bool
+	released []int
+}
+
+func (src *fakeSource) Load() (rtb.Strategy, func() error, error) {
+	if src.fail {
+		return nil, nil, fmt.Errorf("load error")
+	}
+	v := src.version
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		line, _ := rtb.EncodeMessage(msg)
+		r.Printf("v%v %v", v, line)
+	})
+	release := func() error {
+		src.released = append(src.released, v)
+		return nil
+	}
+	return s, release, nil
+}
+
+func (src *fakeSource) Modified() (time.Time, error) {
+	return src.modified, nil
+}
+
+func TestStrategy(t *testing.T) {
+	src := &fakeSource{version: 1, modified: time.Unix(1, 0)}
+	s, err := New(src)
+	if err != nil {
+		t.Fatalf("could not create strategy: %v", err)
+	}
+
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	for _, msg := range []rtb.Message{
+		rtb.MessageInitialize{First: true},
+		rtb.MessageGameOption{Option: rtb.GOptionRobotMaxRotate, Value: 0.5},
+		rtb.MessageGameStarts{},
+		rtb.MessageGameFinishes{},
+	} {
+		r.Deliver(s, msg)
+	}
+
+	// A failed load keeps the previous version.
+	src.version, src.modified, src.fail = 2, time.Unix(2, 0), true
+	r.Deliver(s, rtb.MessageGameFinishes{})
+
+	src.fail = false
+	r.Deliver(s, rtb.MessageGameFinishes{})
+	r.Deliver(s, rtb.MessageGameStarts{})
+
+	// The source was not modified, so it is not loaded again.
+	src.version = 3
+	r.Deliver(s, rtb.MessageGameFinishes{})
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("could not close strategy: %v", err)
+	}
+
+	want := []string{
+		"Print v1 Initialize 1",
+		"Print v1 GameOption 0 0.5",
+		"Print v1 GameStarts",
+		"Print v1 GameFinishes",
+		"Print v1 GameFinishes",
+		"Print v1 GameFinishes",
+		"Print v2 Initialize 0",
+		"Print v2 GameOption 0 0.5",
+		"Print v2 GameStarts",
+		"Print v2 GameFinishes",
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrong commands:\ngot:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if fmt.Sprint(src.released) != "[1 2]" {
+		t.Errorf("wrong released versions: got=%v want=[1 2]", src.released)
+	}
+}
+
+// syncBuffer is a buffer that can be written and read concurrently.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestProcess(t *testing.T) {
+	t.Setenv("RELOAD_HELPER", "1")
+	// With the race detector, processes sleep for a second before
+	// exiting by default, which would get the helper killed.
+	t.Setenv("GORACE", "atexit_sleep_ms=0")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("could not get executable: %v", err)
+	}
+	s, err := New(Process(exe, "-test.run=TestHelperProcess"))
+	if err != nil {
+		t.Fatalf("could not create strategy: %v", err)
+	}
+
+	var out syncBuffer
+	r := rtb.NewRobot(nil, &out)
+	r.Deliver(s, rtb.MessageInitialize{First: true})
+	r.Deliver(s, rtb.MessageRadar{Distance: 2, Object: rtb.ObjectRobot, RadarAngle: 0})
+
+	want := "Name helper\nShoot 2\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("wrong commands: got=%q want=%q", got, want)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("could not close strategy: %v", err)
+	}
+}
+
+// TestHelperProcess is the robot run by TestProcess. It is not a real test.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("RELOAD_HELPER") != "1" {
+		return
+	}
+
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		switch fields := strings.Fields(s.Text()); fields[0] {
+		case "Initialize":
+			fmt.Println("Name helper")
+		case "Radar":
+			fmt.Println("Shoot 2")
+		}
+	}
+	os.Exit(0)
+}
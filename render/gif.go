@@ -0,0 +1,132 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"math"
+
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// GIF renders the frames of a game as an animated GIF. The delay between
+// frames follows the game time, so the animation runs in real time.
+type GIF struct {
+	cfg    Config
+	n      int
+	images []*image.Paletted
+	times  []float64
+}
+
+// NewGIF returns an empty GIF.
+func NewGIF(cfg Config) *GIF {
+	return &GIF{cfg: cfg.withDefaults()}
+}
+
+// Add renders f, if it is one of the rendered frames. Add can be used as
+// sim.Config.Frames.
+func (g *GIF) Add(f sim.Frame) {
+	n := g.n
+	g.n++
+	if n%g.cfg.Every != 0 {
+		return
+	}
+	g.images = append(g.images, Image(f, g.cfg))
+	g.times = append(g.times, f.Time)
+}
+
+// Encode writes the animation to w.
+func (g *GIF) Encode(w io.Writer) error {
+	if len(g.images) == 0 {
+		return errors.New("no frames")
+	}
+
+	anim := &gif.GIF{Image: g.images}
+	for i := range g.images {
+		var dt float64
+		switch {
+		case i+1 < len(g.times):
+			dt = g.times[i+1] - g.times[i]
+		case i > 0:
+			dt = g.times[i] - g.times[i-1]
+		}
+		// Most viewers do not honor delays under 2.
+		anim.Delay = append(anim.Delay, max(2, int(math.Round(dt*100))))
+	}
+	if err := gif.EncodeAll(w, anim); err != nil {
+		return fmt.Errorf("could not encode GIF: %v", err)
+	}
+	return nil
+}
+
+// Image renders f as a paletted image.
+func Image(f sim.Frame, cfg Config) *image.Paletted {
+	cfg = cfg.withDefaults()
+	b := f.Arena.Boundary
+
+	rect := image.Rect(0, 0, int(math.Ceil(b.Dx()*cfg.Scale)), int(math.Ceil(b.Dy()*cfg.Scale)))
+	img := image.NewPaletted(rect, palette.Plan9)
+	bg := uint8(img.Palette.Index(background))
+	for i := range img.Pix {
+		img.Pix[i] = bg
+	}
+
+	p := &rasterPainter{img: img, origin: b.Min, scale: cfg.Scale}
+	paint(p, f, cfg)
+	return img
+}
+
+// rasterPainter is a painter that draws on a paletted image.
+type rasterPainter struct {
+	img    *image.Paletted
+	origin arena.Point
+	scale  float64
+}
+
+// pixel returns the pixel coordinates of p.
+func (p *rasterPainter) pixel(q arena.Point) (float64, float64) {
+	return (q.X - p.origin.X) * p.scale, (q.Y - p.origin.Y) * p.scale
+}
+
+// dot fills the pixels within radius r of (x, y), in pixels.
+func (p *rasterPainter) dot(x, y, r float64, c color.Color) {
+	r = math.Max(r, 0.5)
+	for py := int(math.Floor(y - r)); py <= int(math.Ceil(y+r)); py++ {
+		for px := int(math.Floor(x - r)); px <= int(math.Ceil(x+r)); px++ {
+			dx, dy := float64(px)+0.5-x, float64(py)+0.5-y
+			if dx*dx+dy*dy <= r*r {
+				p.img.Set(px, py, c)
+			}
+		}
+	}
+}
+
+func (p *rasterPainter) line(a, b arena.Point, c color.RGBA, width float64) {
+	x1, y1 := p.pixel(a)
+	x2, y2 := p.pixel(b)
+	n := int(math.Max(1, math.Ceil(math.Hypot(x2-x1, y2-y1))))
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		p.dot(x1+(x2-x1)*t, y1+(y2-y1)*t, width/2, c)
+	}
+}
+
+func (p *rasterPainter) circle(center arena.Point, radius float64, c color.RGBA, width float64) {
+	x, y := p.pixel(center)
+	r := radius * p.scale
+	n := int(math.Max(8, math.Ceil(2*math.Pi*r)))
+	for i := 0; i < n; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		p.dot(x+r*math.Cos(angle), y+r*math.Sin(angle), width/2, c)
+	}
+}
+
+func (p *rasterPainter) disc(center arena.Point, radius float64, c color.RGBA) {
+	x, y := p.pixel(center)
+	p.dot(x, y, radius*p.scale, c)
+}
@@ -0,0 +1,159 @@
+// Package render draws the games of the simulator, for debugging strategies
+// offline. It draws the walls, the robots with their cannon and radar beam,
+// the shots, the cookies, the mines and the debug shapes sent by the robots
+// with DebugLine and DebugCircle.
+//
+// Games are rendered as SVG images, one per tick, or as an animated GIF. The
+// renderers are usually attached to a game through sim.Config.Frames:
+//
+//	anim := render.NewGIF(render.Config{Every: 2})
+//	g, err := sim.NewGame(sim.Config{Frames: anim.Add}, players)
+//	...
+//	g.Run()
+//	err = anim.Encode(f)
+package render
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Config is the configuration of a renderer.
+type Config struct {
+	// Scale is the number of pixels per unit of length. If zero, 20 is
+	// used.
+	Scale float64
+
+	// Every is the number of frames per rendered frame, so only one of
+	// every Every frames is rendered. If zero, 1 is used.
+	Every int
+
+	// NoRadar disables the radar beams.
+	NoRadar bool
+
+	// NoDebug disables the debug shapes.
+	NoDebug bool
+}
+
+// withDefaults returns cfg with the zero fields set to their defaults.
+func (cfg Config) withDefaults() Config {
+	if cfg.Scale == 0 {
+		cfg.Scale = 20
+	}
+	if cfg.Every == 0 {
+		cfg.Every = 1
+	}
+	return cfg
+}
+
+// Colours of the elements of the arena.
+var (
+	background   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	wallColour   = color.RGBA{0x80, 0x80, 0x80, 0xff}
+	cookieColour = color.RGBA{0x00, 0x80, 0x00, 0xff}
+	mineColour   = color.RGBA{0xff, 0xa5, 0x00, 0xff}
+	shotColour   = color.RGBA{0xff, 0xd7, 0x00, 0xff}
+	detailColour = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	debugColour  = color.RGBA{0xff, 0x00, 0xff, 0xff}
+)
+
+// robotColours are the colours of the robots that do not send a valid home
+// colour.
+var robotColours = []color.RGBA{
+	{0xff, 0x00, 0x00, 0xff},
+	{0x00, 0x00, 0xff, 0xff},
+	{0x00, 0xc0, 0xc0, 0xff},
+	{0x80, 0x00, 0x80, 0xff},
+}
+
+// robotColour returns the colour of the i-th robot.
+func robotColour(i int, r sim.RobotFrame) color.RGBA {
+	if v, err := strconv.ParseUint(r.Colour, 16, 32); err == nil && len(r.Colour) == 6 {
+		return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+	}
+	return robotColours[i%len(robotColours)]
+}
+
+// painter draws shapes in world coordinates. Widths are given in pixels.
+type painter interface {
+	line(a, b arena.Point, c color.RGBA, width float64)
+	circle(center arena.Point, radius float64, c color.RGBA, width float64)
+	disc(center arena.Point, radius float64, c color.RGBA)
+}
+
+// paint draws f with p.
+func paint(p painter, f sim.Frame, cfg Config) {
+	for _, w := range f.Arena.Walls {
+		paintWall(p, w, cfg)
+	}
+
+	for _, o := range f.Objects {
+		c := cookieColour
+		if o.Kind == rtb.ObjectMine {
+			c = mineColour
+		}
+		p.disc(o.Pos, o.Radius, c)
+	}
+
+	for _, s := range f.Shots {
+		p.disc(s.Pos, 0.1, shotColour)
+	}
+
+	for i, r := range f.Robots {
+		if !r.Alive {
+			continue
+		}
+		c := robotColour(i, r)
+		if !cfg.NoRadar && r.RadarHit != nil {
+			p.line(r.Pos, *r.RadarHit, c, 1)
+		}
+		p.disc(r.Pos, physics.RobotRadius, c)
+		p.line(r.Pos, r.Pos.Add(arena.Polar(r.Angle, physics.RobotRadius)), detailColour, 2)
+		p.line(r.Pos, r.Pos.Add(arena.Polar(r.CannonAngle, 1.5*physics.RobotRadius)), detailColour, 1)
+		if !cfg.NoDebug {
+			for _, l := range r.Lines {
+				p.line(l.A, l.B, debugColour, 1)
+			}
+			for _, circ := range r.Circles {
+				p.circle(circ.Center, circ.Radius, debugColour, 1)
+			}
+		}
+	}
+}
+
+// paintWall draws w with p.
+func paintWall(p painter, w arena.Wall, cfg Config) {
+	switch w := w.(type) {
+	case arena.Line:
+		p.line(w.Start, w.End, wallColour, math.Max(1, w.Thickness*cfg.Scale))
+	case arena.Polygon:
+		for _, s := range w.Segments() {
+			p.line(s.A, s.B, wallColour, math.Max(1, s.Thickness*cfg.Scale))
+		}
+	case arena.Circle:
+		p.disc(w.Center, w.Radius, wallColour)
+	case arena.InnerCircle:
+		p.circle(w.Center, w.Radius, wallColour, 2)
+	case arena.Arc:
+		// The ring sector is drawn as its outline.
+		a1, a2 := w.Angle1, w.Angle2
+		for a2 < a1 {
+			a2 += 2 * math.Pi
+		}
+		n := int(math.Max(1, math.Ceil((a2-a1)/(math.Pi/16))))
+		for i := 0; i < n; i++ {
+			t1 := a1 + (a2-a1)*float64(i)/float64(n)
+			t2 := a1 + (a2-a1)*float64(i+1)/float64(n)
+			p.line(w.Center.Add(arena.Polar(t1, w.InnerRadius)), w.Center.Add(arena.Polar(t2, w.InnerRadius)), wallColour, 1)
+			p.line(w.Center.Add(arena.Polar(t1, w.OuterRadius)), w.Center.Add(arena.Polar(t2, w.OuterRadius)), wallColour, 1)
+		}
+		p.line(w.Center.Add(arena.Polar(a1, w.InnerRadius)), w.Center.Add(arena.Polar(a1, w.OuterRadius)), wallColour, 1)
+		p.line(w.Center.Add(arena.Polar(a2, w.InnerRadius)), w.Center.Add(arena.Polar(a2, w.OuterRadius)), wallColour, 1)
+	}
+}
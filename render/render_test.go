@@ -0,0 +1,167 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// frame returns a frame with a robot that draws a debug circle, a cookie and
+// a shot.
+func frame() sim.Frame {
+	hit := arena.Point{X: 20, Y: 10}
+	return sim.Frame{
+		Time:  1,
+		Arena: arena.Rectangle(20, 20),
+		Robots: []sim.RobotFrame{
+			{
+				Colour:   "0000ff",
+				Alive:    true,
+				Pos:      arena.Point{X: 10, Y: 10},
+				RadarHit: &hit,
+				Circles:  []sim.Circle{{Center: arena.Point{X: 5, Y: 5}, Radius: 1}},
+			},
+			{Pos: arena.Point{X: 3, Y: 3}},
+		},
+		Shots:   []sim.ShotFrame{{Pos: arena.Point{X: 12, Y: 10}}},
+		Objects: []sim.Object{{Kind: rtb.ObjectCookie, Pos: arena.Point{X: 15, Y: 15}, Radius: 0.3}},
+	}
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "default",
+			want: []string{
+				`width="400" height="400"`,
+				`<circle cx="10" cy="10" r="0.5" fill="#0000ff"/>`,
+				`<line x1="10" y1="10" x2="20" y2="10" stroke="#0000ff"`,
+				`<circle cx="5" cy="5" r="1" fill="none" stroke="#ff00ff"`,
+				`<circle cx="15" cy="15" r="0.3" fill="#008000"/>`,
+				`<circle cx="12" cy="10" r="0.1" fill="#ffd700"/>`,
+			},
+			notWant: []string{`cx="3" cy="3"`},
+		},
+		{
+			name:    "no radar or debug",
+			cfg:     Config{Scale: 10, NoRadar: true, NoDebug: true},
+			want:    []string{`width="200" height="200"`},
+			notWant: []string{`x2="20" y2="10" stroke="#0000ff"`, `#ff00ff`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := SVG(&b, frame(), tt.cfg); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(b.String(), s) {
+					t.Errorf("missing %q in:\n%v", s, b.String())
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(b.String(), s) {
+					t.Errorf("unexpected %q in:\n%v", s, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestGIF(t *testing.T) {
+	anim := NewGIF(Config{Every: 2})
+	for i := 0; i < 5; i++ {
+		f := frame()
+		f.Time = float64(i) * 0.05
+		anim.Add(f)
+	}
+
+	var b bytes.Buffer
+	if err := anim.Encode(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g, err := gif.DecodeAll(&b)
+	if err != nil {
+		t.Fatalf("could not decode GIF: %v", err)
+	}
+
+	if len(g.Image) != 3 {
+		t.Fatalf("unexpected number of frames: got=%v want=%v", len(g.Image), 3)
+	}
+	if g.Delay[0] != 10 {
+		t.Errorf("unexpected delay: got=%v want=%v", g.Delay[0], 10)
+	}
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{200, 207, color.RGBA{0x00, 0x00, 0xff, 0xff}},
+		{300, 300, color.RGBA{0x00, 0x80, 0x00, 0xff}},
+		{60, 60, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		got := color.RGBAModel.Convert(g.Image[0].At(tt.x, tt.y)).(color.RGBA)
+		want := color.RGBAModel.Convert(g.Image[0].Palette.Convert(tt.want)).(color.RGBA)
+		if got != want {
+			t.Errorf("unexpected colour at (%v, %v): got=%v want=%v", tt.x, tt.y, got, want)
+		}
+	}
+
+	if err := NewGIF(Config{}).Encode(&b); err == nil {
+		t.Errorf("expected error encoding an empty GIF")
+	}
+}
+
+func TestSVGDir(t *testing.T) {
+	dir := t.TempDir()
+	players := []*sim.Player{sim.NewPlayer(rtb.StrategyFunc(func(*rtb.Robot, rtb.Message) {}), rtb.ListenSettings{})}
+	d := NewSVGDir(dir, Config{Every: 3})
+	g, err := sim.NewGame(sim.Config{Frames: d.Add}, players)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		g.Step()
+	}
+	if err := d.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.svg"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"frame-00000.svg", "frame-00003.svg", "frame-00006.svg"}
+	if len(files) != len(want) {
+		t.Fatalf("unexpected files: got=%v want=%v", files, want)
+	}
+	for i, f := range files {
+		if filepath.Base(f) != want[i] {
+			t.Errorf("unexpected file: got=%v want=%v", filepath.Base(f), want[i])
+		}
+		if b, err := os.ReadFile(f); err != nil || !bytes.HasPrefix(b, []byte("<svg")) {
+			t.Errorf("invalid frame %v: %v", f, err)
+		}
+	}
+
+	d = NewSVGDir(filepath.Join(dir, "missing"), Config{})
+	d.Add(g.Frame())
+	if d.Err() == nil {
+		t.Errorf("expected error writing to a missing directory")
+	}
+}
@@ -0,0 +1,101 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// SVG writes f to w as an SVG image.
+func SVG(w io.Writer, f sim.Frame, cfg Config) error {
+	cfg = cfg.withDefaults()
+	b := f.Arena.Boundary
+
+	p := &svgPainter{scale: cfg.Scale}
+	fmt.Fprintf(&p.sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%g %g %g %g" width="%g" height="%g">`+"\n",
+		b.Min.X, b.Min.Y, b.Dx(), b.Dy(), b.Dx()*cfg.Scale, b.Dy()*cfg.Scale)
+	fmt.Fprintf(&p.sb, `<rect x="%g" y="%g" width="%g" height="%g" fill="%v"/>`+"\n", b.Min.X, b.Min.Y, b.Dx(), b.Dy(), hex(background))
+	fmt.Fprintf(&p.sb, "<!-- time %.2f -->\n", f.Time)
+	paint(p, f, cfg)
+	fmt.Fprintf(&p.sb, "</svg>\n")
+
+	_, err := io.WriteString(w, p.sb.String())
+	return err
+}
+
+// svgPainter is a painter that writes SVG elements.
+type svgPainter struct {
+	sb    strings.Builder
+	scale float64
+}
+
+func (p *svgPainter) line(a, b arena.Point, c color.RGBA, width float64) {
+	fmt.Fprintf(&p.sb, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%v" stroke-width="%g" stroke-linecap="round"/>`+"\n",
+		a.X, a.Y, b.X, b.Y, hex(c), width/p.scale)
+}
+
+func (p *svgPainter) circle(center arena.Point, radius float64, c color.RGBA, width float64) {
+	fmt.Fprintf(&p.sb, `<circle cx="%g" cy="%g" r="%g" fill="none" stroke="%v" stroke-width="%g"/>`+"\n",
+		center.X, center.Y, radius, hex(c), width/p.scale)
+}
+
+func (p *svgPainter) disc(center arena.Point, radius float64, c color.RGBA) {
+	fmt.Fprintf(&p.sb, `<circle cx="%g" cy="%g" r="%g" fill="%v"/>`+"\n", center.X, center.Y, radius, hex(c))
+}
+
+// hex returns c in the hexadecimal notation used by SVG.
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// SVGDir writes the frames of a game as SVG images in a directory, one file
+// per rendered frame.
+type SVGDir struct {
+	dir string
+	cfg Config
+	n   int
+	err error
+}
+
+// NewSVGDir returns an SVGDir that writes the images in dir, which must
+// exist.
+func NewSVGDir(dir string, cfg Config) *SVGDir {
+	return &SVGDir{dir: dir, cfg: cfg.withDefaults()}
+}
+
+// Add writes f to the file frame-NNNNN.svg, where NNNNN is the number of the
+// frame, if it is one of the rendered frames. It does nothing after an
+// error, which is returned by Err. Add can be used as sim.Config.Frames.
+func (d *SVGDir) Add(f sim.Frame) {
+	n := d.n
+	d.n++
+	if d.err != nil || n%d.cfg.Every != 0 {
+		return
+	}
+
+	path := filepath.Join(d.dir, fmt.Sprintf("frame-%05d.svg", n))
+	file, err := os.Create(path)
+	if err != nil {
+		d.err = fmt.Errorf("could not create frame: %v", err)
+		return
+	}
+	if err := SVG(file, f, d.cfg); err != nil {
+		file.Close()
+		d.err = fmt.Errorf("could not write frame: %v", err)
+		return
+	}
+	if err := file.Close(); err != nil {
+		d.err = fmt.Errorf("could not write frame: %v", err)
+	}
+}
+
+// Err returns the first error found writing the frames.
+func (d *SVGDir) Err() error {
+	return d.err
+}
@@ -0,0 +1,222 @@
+// Package replay allows to record the messages received during a real match
+// and replay them against a strategy. It is useful to catch behavioral
+// regressions, comparing the commands sent by the strategy with a golden
+// file or with the commands recorded in a telemetry file.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+// Recorder is an io.Reader that copies everything read from the underlying
+// reader to a log.
+type Recorder struct {
+	r   io.Reader
+	log io.Writer
+}
+
+// NewRecorder returns a Recorder that reads from r and writes to log.
+func NewRecorder(r io.Reader, log io.Writer) *Recorder {
+	return &Recorder{r: r, log: log}
+}
+
+// Read reads from the underlying reader and copies the read data to the log.
+func (rec *Recorder) Read(p []byte) (int, error) {
+	n, err := rec.r.Read(p)
+	if n > 0 {
+		if _, err := rec.log.Write(p[:n]); err != nil {
+			return n, fmt.Errorf("could not write log: %v", err)
+		}
+	}
+	return n, err
+}
+
+// RecordRobot returns a Robot that communicates with the server through the
+// standard input and output of the process and records all the received
+// messages in the file at path. The returned file must be closed at the end
+// of the match.
+func RecordRobot(path string) (*rtb.Robot, *os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create log: %v", err)
+	}
+	r := rtb.NewRobot(NewRecorder(os.Stdin, f), nil)
+	return r, f, nil
+}
+
+// Replay passes the messages in the log read from r to s, in order, and
+// returns the commands sent by s. Lines that cannot be parsed are ignored,
+// like rtb.Robot.Listen does.
+func Replay(r io.Reader, s rtb.Strategy) ([]byte, error) {
+	return ReplayFunc(r, func(*rtb.Robot) rtb.Strategy { return s })
+}
+
+// ReplayFunc is like Replay, but the strategy is returned by newStrategy,
+// which receives the robot used during the replay. It allows to replay
+// strategies that add observers to the robot, like the ones built on top
+// of the world model.
+func ReplayFunc(r io.Reader, newStrategy func(robot *rtb.Robot) rtb.Strategy) ([]byte, error) {
+	var out bytes.Buffer
+	robot := rtb.NewRobot(nil, &out)
+	s := newStrategy(robot)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		msg, err := rtb.ParseMessage(sc.Text())
+		if err != nil {
+			continue
+		}
+		robot.Deliver(s, msg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read log: %v", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// TB is the part of testing.TB used by Golden. It allows to use Golden
+// without linking the testing package into the programs importing replay.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// Golden replays the log file at logPath against s and compares the sent
+// commands with the golden file at goldenPath. The test fails if they differ.
+// If update is true, the golden file is overwritten with the sent commands
+// instead. Tests usually set update using a command-line flag.
+func Golden(t TB, s rtb.Strategy, logPath, goldenPath string, update bool) {
+	t.Helper()
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("could not open log: %v", err)
+	}
+	defer f.Close()
+
+	got, err := Replay(f, s)
+	if err != nil {
+		t.Fatalf("replay error: %v", err)
+	}
+
+	if update {
+		if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+			t.Fatalf("could not update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("could not read golden file: %v", err)
+	}
+
+	if line, ok := firstDiff(got, want); ok {
+		t.Errorf("commands differ from golden file %v at line %v", goldenPath, line)
+	}
+}
+
+// firstDiff returns the number of the first line that differs between a and
+// b. Lines are numbered starting at 1. It returns false if a and b are equal.
+func firstDiff(a, b []byte) (int, bool) {
+	la := bytes.SplitAfter(a, []byte("\n"))
+	lb := bytes.SplitAfter(b, []byte("\n"))
+	for i := 0; i < len(la) || i < len(lb); i++ {
+		if i >= len(la) || i >= len(lb) || !bytes.Equal(la[i], lb[i]) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// Divergence is a point of a replay where the strategy sends different
+// commands than the recorded ones.
+type Divergence struct {
+	// Time is the game time of the message.
+	Time float64
+
+	// Record is the index of the record of the message.
+	Record int
+
+	// Message is the message, as sent through the protocol, after which
+	// the commands differ.
+	Message string
+
+	// Want are the recorded commands sent after the message.
+	Want []string
+
+	// Got are the commands sent by the replayed strategy after the
+	// message.
+	Got []string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("time %v, record %v, after %q: got=%q want=%q", d.Time, d.Record, d.Message, d.Got, d.Want)
+}
+
+// Diff passes the messages of the telemetry records recs to the strategy
+// returned by newStrategy, like ReplayFunc does, and compares the commands
+// sent after every message with the recorded ones. It returns the messages
+// after which they differ, in order. Commands recorded before the first
+// message, usually sent by rtb.Robot.Listen, are ignored.
+func Diff(recs []telemetry.Record, newStrategy func(robot *rtb.Robot) rtb.Strategy) ([]Divergence, error) {
+	robot := rtb.NewRobot(nil, io.Discard)
+	s := newStrategy(robot)
+
+	var got []string
+	robot.AddObserver(commandObserver(func(cmd string) { got = append(got, cmd) }))
+
+	var (
+		divs []Divergence
+		cur  = -1
+		want []string
+	)
+	check := func() {
+		if cur >= 0 && !slices.Equal(got, want) {
+			divs = append(divs, Divergence{
+				Time:    recs[cur].Time,
+				Record:  cur,
+				Message: recs[cur].Raw,
+				Want:    want,
+				Got:     got,
+			})
+		}
+	}
+
+	for i, rec := range recs {
+		switch rec.Kind {
+		case telemetry.KindMessage:
+			check()
+			msg, err := rec.Message()
+			if err != nil {
+				return nil, fmt.Errorf("could not parse record %v: %v", i, err)
+			}
+			cur, want, got = i, nil, nil
+			robot.Deliver(s, msg)
+		case telemetry.KindCommand:
+			if cur >= 0 {
+				want = append(want, rec.Raw)
+			}
+		}
+	}
+	check()
+
+	return divs, nil
+}
+
+// commandObserver is an rtb.Observer that calls f for every command.
+type commandObserver func(cmd string)
+
+func (f commandObserver) Message(msg rtb.Message) {}
+
+func (f commandObserver) Command(cmd string) { f(cmd) }
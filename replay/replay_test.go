@@ -0,0 +1,203 @@
+package replay
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/internal/rtbtest"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+// turret is a strategy that rotates until the radar detects a robot and then
+// shoots it. It accelerates on collisions.
+var turret = rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+	rtbtest.Turret{Name: "turret", Speed: 1, Energy: 5}.Handle(r, msg)
+	if _, ok := msg.(rtb.MessageCollision); ok {
+		r.Accelerate(1)
+	}
+})
+
+func TestReplayFunc(t *testing.T) {
+	log := "GameStarts\nRadar 1 0 0\n"
+
+	var n int
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	got, err := ReplayFunc(strings.NewReader(log), func(r *rtb.Robot) rtb.Strategy {
+		r.AddObserver(observerFunc(func(rtb.Message) { n++ }))
+		r.Printf("ready")
+		return nop
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("wrong number of observed messages: got=%v want=%v", n, 2)
+	}
+	if want := "Print ready\n"; string(got) != want {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+}
+
+// observerFunc is an rtb.Observer that calls f for every message.
+type observerFunc func(msg rtb.Message)
+
+func (f observerFunc) Message(msg rtb.Message) { f(msg) }
+
+func (f observerFunc) Command(cmd string) {}
+
+func TestRecorder(t *testing.T) {
+	var log bytes.Buffer
+	r := rtb.NewRobot(NewRecorder(strings.NewReader("GameStarts\nDead\n"), &log), &bytes.Buffer{})
+
+	var n int
+	for range r.Listen(rtb.ListenSettings{}) {
+		n++
+	}
+
+	if n != 2 {
+		t.Errorf("wrong number of messages: got=%v want=%v", n, 2)
+	}
+	if got, want := log.String(), "GameStarts\nDead\n"; got != want {
+		t.Errorf("unexpected log: got=%q want=%q", got, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	log := "GameStarts\nRadar 1 0 0\nFoo\nCollision 2 0\n"
+
+	got, err := Replay(strings.NewReader(log), turret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Rotate 1 1.000000\nRotate 1 0.000000\nShoot 5.000000\nAccelerate 1.000000\n"
+	if string(got) != want {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, turret, "testdata/game.log", "testdata/game.golden", false)
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "game.golden")
+
+	Golden(t, turret, "testdata/game.log", golden, true)
+
+	got, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("could not read golden file: %v", err)
+	}
+	want, err := os.ReadFile("testdata/game.golden")
+	if err != nil {
+		t.Fatalf("could not read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("unexpected golden file: got=%q want=%q", got, want)
+	}
+}
+
+func TestFirstDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		line int
+		diff bool
+	}{
+		{"Equal", "a\nb\n", "a\nb\n", 0, false},
+		{"Different line", "a\nb\n", "a\nc\n", 2, true},
+		{"Shorter", "a\n", "a\nb\n", 2, true},
+		{"Missing newline", "a\nb", "a\nb\n", 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, diff := firstDiff([]byte(tt.a), []byte(tt.b))
+			if line != tt.line || diff != tt.diff {
+				t.Errorf("unexpected result: got=(%v, %v) want=(%v, %v)", line, diff, tt.line, tt.diff)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := telemetry.New(telemetry.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := rtb.NewRobot(nil, io.Discard)
+	r.AddObserver(rec)
+	for _, line := range []string{"GameStarts", "Info 0.5 0 0", "Radar 1 0 0", "Info 1 0 0", "Collision 2 0"} {
+		msg, err := rtb.ParseMessage(line)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.Deliver(turret, msg)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "game-0001.jsonl"))
+	if err != nil {
+		t.Fatalf("could not open telemetry: %v", err)
+	}
+	defer f.Close()
+	recs, err := telemetry.Read(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	divs, err := Diff(recs, func(*rtb.Robot) rtb.Strategy { return turret })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(divs) != 0 {
+		t.Errorf("unexpected divergences: %v", divs)
+	}
+
+	// The new strategy shoots with less energy and does not accelerate
+	// on collisions.
+	changed := func(r *rtb.Robot, msg rtb.Message) {
+		switch m := msg.(type) {
+		case rtb.MessageGameStarts:
+			r.Rotate(rtb.PartRobot, 1)
+		case rtb.MessageRadar:
+			if m.Object == rtb.ObjectRobot {
+				r.Rotate(rtb.PartRobot, 0)
+				r.Shoot(4)
+			}
+		}
+	}
+	divs, err = Diff(recs, func(*rtb.Robot) rtb.Strategy { return rtb.StrategyFunc(changed) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Divergence{
+		{
+			Time:    0.5,
+			Record:  3,
+			Message: "Radar 1 0 0",
+			Want:    []string{"Rotate 1 0.000000", "Shoot 5.000000"},
+			Got:     []string{"Rotate 1 0.000000", "Shoot 4.000000"},
+		},
+		{
+			Time:    1,
+			Record:  7,
+			Message: "Collision 2 0",
+			Want:    []string{"Accelerate 1.000000"},
+		},
+	}
+	if !reflect.DeepEqual(divs, want) {
+		t.Errorf("unexpected divergences: got=%v want=%v", divs, want)
+	}
+}
@@ -0,0 +1,267 @@
+// Package results persists the results of tournaments, so leaderboards and
+// historical comparisons across strategy versions can be built.
+//
+// The store is an embedded, append-only database written in pure Go: every
+// match is a JSON line in a single file, which is loaded in memory when the
+// store is opened. It keeps the module free of dependencies and the file is
+// easy to inspect and to process with other tools.
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Match is the result of a game.
+type Match struct {
+	// ID identifies the match in the store. It is assigned by Add.
+	ID int64 `json:"id"`
+
+	// Tournament is the name of the tournament the match belongs to.
+	Tournament string `json:"tournament,omitempty"`
+
+	// Time is the time at which the match was played.
+	Time time.Time `json:"time"`
+
+	// Seed is the seed of the simulated game, if any.
+	Seed int64 `json:"seed,omitempty"`
+
+	// Robots are the results of the robots that took part in the
+	// match.
+	Robots []RobotResult `json:"robots"`
+}
+
+// RobotResult is the result of a robot in a match.
+type RobotResult struct {
+	// Name is the name of the robot.
+	Name string `json:"name"`
+
+	// Version is the version of the strategy of the robot, e.g. a
+	// commit hash.
+	Version string `json:"version,omitempty"`
+
+	// Score are the points earned by the robot in the match.
+	Score float64 `json:"score"`
+
+	// Won is true if the robot won the match.
+	Won bool `json:"won,omitempty"`
+
+	// DamageTaken is the energy lost by the robot.
+	DamageTaken float64 `json:"damageTaken"`
+
+	// DamageDealt is the energy taken by the robot from the opponents.
+	DamageDealt float64 `json:"damageDealt"`
+
+	// ShotsFired is the number of shots fired by the robot.
+	ShotsFired int `json:"shotsFired"`
+
+	// Telemetry is a reference to the telemetry file of the robot, if
+	// any.
+	Telemetry string `json:"telemetry,omitempty"`
+}
+
+// Store is a database of match results. Store methods can be called
+// concurrently.
+type Store struct {
+	mu      sync.Mutex
+	f       *os.File
+	matches []Match
+}
+
+// Open opens the store at path. The file is created if it does not exist. A
+// partial last match, as left by a crash in the middle of a write, is
+// removed from the file.
+func Open(path string) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open store: %v", err)
+	}
+
+	var (
+		matches []Match
+		off     int64
+	)
+	br := bufio.NewReader(f)
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Matches are always written with their trailing
+			// newline, so a line without it was not completely
+			// written.
+			if len(line) > 0 {
+				if err := f.Truncate(off); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("could not remove partial match: %v", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read store: %v", err)
+		}
+
+		var m Match
+		if err := json.Unmarshal(line, &m); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not decode match %v: %v", len(matches)+1, err)
+		}
+		matches = append(matches, m)
+		off += int64(len(line))
+	}
+	return &Store{f: f, matches: matches}, nil
+}
+
+// Add adds m to the store and returns it with its ID.
+func (s *Store) Add(m Match) (Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.ID = 1
+	if n := len(s.matches); n > 0 {
+		m.ID = s.matches[n-1].ID + 1
+	}
+	m.Robots = append([]RobotResult(nil), m.Robots...)
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Match{}, fmt.Errorf("could not marshal match: %v", err)
+	}
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return Match{}, fmt.Errorf("could not write match: %v", err)
+	}
+	s.matches = append(s.matches, m)
+	return m, nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+// Query selects matches. Zero fields match everything.
+type Query struct {
+	// Tournament selects the matches of a tournament.
+	Tournament string
+
+	// Robot selects the matches in which a robot took part.
+	Robot string
+
+	// Version selects the matches in which a version of a strategy took
+	// part.
+	Version string
+
+	// Since and Until select the matches played in [Since, Until).
+	Since, Until time.Time
+}
+
+// match reports whether m is selected by q.
+func (q Query) match(m Match) bool {
+	if q.Tournament != "" && m.Tournament != q.Tournament {
+		return false
+	}
+	if !q.Since.IsZero() && m.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !m.Time.Before(q.Until) {
+		return false
+	}
+	if q.Robot == "" && q.Version == "" {
+		return true
+	}
+	for _, r := range m.Robots {
+		if (q.Robot == "" || r.Name == q.Robot) && (q.Version == "" || r.Version == q.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns the matches selected by q, in the order they were added.
+func (s *Store) Matches(q Query) []Match {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ms []Match
+	for _, m := range s.matches {
+		if q.match(m) {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// Standing is the aggregate result of a version of a robot.
+type Standing struct {
+	// Name and Version identify the robot.
+	Name, Version string
+
+	// Matches is the number of matches played.
+	Matches int
+
+	// Wins is the number of matches won.
+	Wins int
+
+	// Score is the total score.
+	Score float64
+
+	// AvgScore, AvgDamageTaken and AvgDamageDealt are the averages per
+	// match.
+	AvgScore, AvgDamageTaken, AvgDamageDealt float64
+}
+
+// Leaderboard returns the standings of the robots in the matches selected by
+// q, sorted by average score. Every version of a robot has its own
+// standing. If q selects a robot or a version, only the matching standings
+// are returned.
+func (s *Store) Leaderboard(q Query) []Standing {
+	type key struct{ name, version string }
+	byKey := map[key]*Standing{}
+	var order []key
+
+	for _, m := range s.Matches(q) {
+		for _, r := range m.Robots {
+			if (q.Robot != "" && r.Name != q.Robot) || (q.Version != "" && r.Version != q.Version) {
+				continue
+			}
+			k := key{r.Name, r.Version}
+			st, ok := byKey[k]
+			if !ok {
+				st = &Standing{Name: r.Name, Version: r.Version}
+				byKey[k] = st
+				order = append(order, k)
+			}
+			st.Matches++
+			if r.Won {
+				st.Wins++
+			}
+			st.Score += r.Score
+			st.AvgDamageTaken += r.DamageTaken
+			st.AvgDamageDealt += r.DamageDealt
+		}
+	}
+
+	standings := make([]Standing, len(order))
+	for i, k := range order {
+		st := byKey[k]
+		n := float64(st.Matches)
+		st.AvgScore = st.Score / n
+		st.AvgDamageTaken /= n
+		st.AvgDamageDealt /= n
+		standings[i] = *st
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].AvgScore > standings[j].AvgScore
+	})
+	return standings
+}
@@ -0,0 +1,186 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var matches = []Match{
+	{
+		Tournament: "a",
+		Time:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Robots: []RobotResult{
+			{Name: "turret", Version: "v1", Score: 1, Won: true, DamageDealt: 100},
+			{Name: "duck", Score: 0, DamageTaken: 100},
+		},
+	},
+	{
+		Tournament: "a",
+		Time:       time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Robots: []RobotResult{
+			{Name: "turret", Version: "v2", Score: 0.5, DamageTaken: 20},
+			{Name: "duck", Score: 0.5, DamageTaken: 40},
+		},
+	},
+	{
+		Tournament: "b",
+		Time:       time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		Robots: []RobotResult{
+			{Name: "turret", Version: "v2", Score: 1, Won: true, Telemetry: "turret.jsonl"},
+			{Name: "duck", Score: 0},
+		},
+	},
+}
+
+// openStore returns a store with matches.
+func openStore(t *testing.T) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("could not open store: %v", err)
+	}
+	for i, m := range matches {
+		got, err := s.Add(m)
+		if err != nil {
+			t.Fatalf("could not add match: %v", err)
+		}
+		if want := int64(i + 1); got.ID != want {
+			t.Errorf("wrong ID: got=%v want=%v", got.ID, want)
+		}
+	}
+	return s, path
+}
+
+func TestReopen(t *testing.T) {
+	s, path := openStore(t)
+	if err := s.Close(); err != nil {
+		t.Fatalf("could not close store: %v", err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("could not reopen store: %v", err)
+	}
+	defer s.Close()
+
+	ms := s.Matches(Query{})
+	if len(ms) != len(matches) {
+		t.Fatalf("wrong number of matches: got=%v want=%v", len(ms), len(matches))
+	}
+	if got := ms[2].Robots[0].Telemetry; got != "turret.jsonl" {
+		t.Errorf("wrong telemetry: got=%q want=%q", got, "turret.jsonl")
+	}
+
+	m, err := s.Add(Match{})
+	if err != nil {
+		t.Fatalf("could not add match: %v", err)
+	}
+	if m.ID != 4 {
+		t.Errorf("wrong ID: got=%v want=4", m.ID)
+	}
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	if err := os.WriteFile(path, []byte("{\n{\"id\":1}\n"), 0644); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	if err := os.WriteFile(path, []byte("{\"id\":1}\n{\"id\":2,\"rob"), 0644); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("could not open store: %v", err)
+	}
+	defer s.Close()
+
+	if n := len(s.Matches(Query{})); n != 1 {
+		t.Errorf("wrong number of matches: got=%v want=1", n)
+	}
+	m, err := s.Add(Match{})
+	if err != nil {
+		t.Fatalf("could not add match: %v", err)
+	}
+	if m.ID != 2 {
+		t.Errorf("wrong ID: got=%v want=2", m.ID)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	if want := "{\"id\":1}\n{\"id\":2,"; !strings.HasPrefix(string(b), want) {
+		t.Errorf("partial match not removed: %q", b)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	s, _ := openStore(t)
+	defer s.Close()
+
+	tests := []struct {
+		name string
+		q    Query
+		want []int64
+	}{
+		{"all", Query{}, []int64{1, 2, 3}},
+		{"tournament", Query{Tournament: "a"}, []int64{1, 2}},
+		{"robot", Query{Robot: "duck"}, []int64{1, 2, 3}},
+		{"version", Query{Robot: "turret", Version: "v2"}, []int64{2, 3}},
+		{"since", Query{Since: matches[1].Time}, []int64{2, 3}},
+		{"until", Query{Until: matches[1].Time}, []int64{1}},
+		{"none", Query{Robot: "ghost"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int64
+			for _, m := range s.Matches(tt.q) {
+				got = append(got, m.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("wrong matches: got=%v want=%v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("wrong matches: got=%v want=%v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	s, _ := openStore(t)
+	defer s.Close()
+
+	want := []Standing{
+		{Name: "turret", Version: "v1", Matches: 1, Wins: 1, Score: 1, AvgScore: 1, AvgDamageDealt: 100},
+		{Name: "turret", Version: "v2", Matches: 2, Wins: 1, Score: 1.5, AvgScore: 0.75, AvgDamageTaken: 10},
+		{Name: "duck", Matches: 3, Score: 0.5, AvgScore: 0.5 / 3, AvgDamageTaken: 140.0 / 3},
+	}
+	got := s.Leaderboard(Query{})
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of standings: got=%v want=%v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrong standing %v: got=%+v want=%+v", i, got[i], want[i])
+		}
+	}
+
+	got = s.Leaderboard(Query{Robot: "turret"})
+	if len(got) != 2 || got[0].Version != "v1" || got[1].Version != "v2" {
+		t.Errorf("unexpected turret standings: %+v", got)
+	}
+}
@@ -0,0 +1,245 @@
+// Package retreat implements a retreat-and-heal behavior for robots that are
+// outnumbered and low on energy.
+//
+// A Retreat decorates the main strategy of a robot. While many robots are
+// left and the energy of the robot is low, it takes control of the robot:
+// it disengages towards the safest region of the risk map, going for the
+// cookies detected by the radar first, and zigzags instead of flying in a
+// straight line, so the enemies cannot predict where it is going. When the
+// robot has recovered or few robots are left, the main strategy takes
+// control again:
+//
+//	m := risk.New(a, w, tr, dm, risk.Config{})
+//	r.AddObserver(m)
+//	s := retreat.New(main, w, n, m, retreat.Config{})
+//	r.Run(settings, s)
+package retreat
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/risk"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Retreat.
+type Config struct {
+	// LowEnergy is the energy under which the robot retreats. If zero,
+	// 30 is used.
+	LowEnergy float64
+
+	// RecoverEnergy is the energy at which the robot stops retreating.
+	// If zero, 50 is used.
+	RecoverEnergy float64
+
+	// MinRobots is the minimum number of robots left, including the
+	// robot, for the robot to be outnumbered. If zero, 3 is used.
+	MinRobots int
+
+	// Radius is the maximum distance to the points where the robot
+	// retreats. If zero, 10 is used.
+	Radius float64
+
+	// Leg is the length of every leg of the zigzag. If zero, 3 is used.
+	Leg float64
+
+	// Zigzag is the distance of the turns of the zigzag to the straight
+	// line. If zero, 1.5 is used.
+	Zigzag float64
+
+	// Interval is the time between changes of the retreat point. If
+	// zero, 2 is used.
+	Interval float64
+
+	// OnChange, if not nil, is called when the robot starts or stops
+	// retreating.
+	OnChange func(retreating bool)
+}
+
+// Retreat is a strategy that passes the messages to a main strategy, except
+// while the robot retreats. It implements the rtb.Strategy interface. The
+// world model, the navigator and the risk map must be added to the robot as
+// observers. Retreat methods can be called concurrently.
+type Retreat struct {
+	cfg Config
+	s   rtb.Strategy
+	w   *world.World
+	n   *nav.Navigator
+	m   *risk.Map
+
+	mu         sync.Mutex
+	retreating bool
+	planned    float64
+	cookie     *arena.Point
+	side       float64
+}
+
+// New returns a Retreat that decorates s. While retreating, the robot is
+// driven through n towards the safest points of m.
+func New(s rtb.Strategy, w *world.World, n *nav.Navigator, m *risk.Map, cfg Config) *Retreat {
+	if cfg.LowEnergy == 0 {
+		cfg.LowEnergy = 30
+	}
+	if cfg.RecoverEnergy == 0 {
+		cfg.RecoverEnergy = 50
+	}
+	if cfg.MinRobots == 0 {
+		cfg.MinRobots = 3
+	}
+	if cfg.Radius == 0 {
+		cfg.Radius = 10
+	}
+	if cfg.Leg == 0 {
+		cfg.Leg = 3
+	}
+	if cfg.Zigzag == 0 {
+		cfg.Zigzag = 1.5
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 2
+	}
+	return &Retreat{cfg: cfg, s: s, w: w, n: n, m: m, planned: math.Inf(-1), side: 1}
+}
+
+// Retreating returns true if the robot is retreating.
+func (rt *Retreat) Retreating() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return rt.retreating
+}
+
+// Handle passes msg to the main strategy, unless the robot is retreating.
+// The messages that set up the robot and the games are always passed, so
+// the main strategy can resume when the retreat ends.
+func (rt *Retreat) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts:
+		rt.mu.Lock()
+		rt.retreating, rt.planned, rt.cookie = false, math.Inf(-1), nil
+		rt.mu.Unlock()
+	case rtb.MessageInfo:
+		rt.update()
+	case rtb.MessageRadar:
+		if msg.Object == rtb.ObjectCookie && rt.Retreating() {
+			rt.seeCookie(rt.w.Absolute(msg.RadarAngle, msg.Distance))
+		}
+	case rtb.MessageCollision:
+		if msg.Object == rtb.ObjectCookie {
+			rt.mu.Lock()
+			rt.cookie = nil
+			rt.mu.Unlock()
+		}
+	}
+
+	if rt.Retreating() && !control(msg) {
+		return
+	}
+	rt.s.Handle(r, msg)
+}
+
+// control returns true if msg sets up the robot or the games.
+func control(msg rtb.Message) bool {
+	switch msg.(type) {
+	case rtb.MessageInitialize, *rtb.MessageInitialize, rtb.MessageYourName,
+		rtb.MessageYourColour, rtb.MessageGameOption, rtb.MessageGameStarts,
+		rtb.MessageRobotsLeft, rtb.MessageWarning, rtb.MessageDead,
+		rtb.MessageGameFinishes, rtb.MessageExitRobot:
+		return true
+	}
+	return false
+}
+
+// update starts or stops the retreat and, while retreating, plans the path
+// of the robot.
+func (rt *Retreat) update() {
+	s := rt.w.State()
+	outnumbered := s.RobotsLeft >= rt.cfg.MinRobots
+
+	rt.mu.Lock()
+	prev := rt.retreating
+	switch {
+	case !rt.retreating && outnumbered && s.Energy < rt.cfg.LowEnergy:
+		rt.retreating, rt.planned, rt.cookie = true, math.Inf(-1), nil
+	case rt.retreating && (!outnumbered || s.Energy >= rt.cfg.RecoverEnergy):
+		rt.retreating = false
+	}
+	retreating := rt.retreating
+	replan := retreating && s.Time-rt.planned >= rt.cfg.Interval
+	if replan {
+		rt.planned = s.Time
+	}
+	rt.mu.Unlock()
+
+	if prev != retreating {
+		if !retreating {
+			rt.n.Stop()
+		}
+		if rt.cfg.OnChange != nil {
+			rt.cfg.OnChange(retreating)
+		}
+	}
+	if replan || retreating && rt.n.Done() {
+		rt.plan()
+	}
+}
+
+// seeCookie goes for the cookie at p if the robot is not going for another
+// one.
+func (rt *Retreat) seeCookie(p arena.Point) {
+	rt.mu.Lock()
+	if rt.cookie != nil {
+		rt.mu.Unlock()
+		return
+	}
+	rt.cookie = &p
+	rt.mu.Unlock()
+
+	rt.plan()
+}
+
+// plan drives the robot along a zigzag towards the cookie it is going for
+// or, if there is none, towards the safest reachable point.
+func (rt *Retreat) plan() {
+	rt.mu.Lock()
+	cookie := rt.cookie
+	rt.side = -rt.side
+	side := rt.side
+	rt.mu.Unlock()
+
+	pos := rt.w.State().Pos
+	var goal arena.Point
+	if cookie != nil {
+		goal = *cookie
+	} else {
+		goal = rt.m.SafestReachablePoint(rt.cfg.Radius)
+	}
+	rt.n.GoTo(Zigzag(pos, goal, rt.cfg.Leg, side*rt.cfg.Zigzag)...)
+}
+
+// Zigzag returns the waypoints of a zigzag from a to b, with legs of length
+// leg and turns at distance offset to each side of the straight line,
+// starting with the left side, or the right side if offset is negative. The
+// last waypoint is b.
+func Zigzag(a, b arena.Point, leg, offset float64) []arena.Point {
+	dir := b.Sub(a)
+	dist := dir.Len()
+	n := int(math.Ceil(dist / leg))
+	if n <= 1 {
+		return []arena.Point{b}
+	}
+
+	u := dir.Mul(1 / dist)
+	normal := arena.Point{X: -u.Y, Y: u.X}
+	wps := make([]arena.Point, 0, n)
+	for i := 1; i < n; i++ {
+		p := a.Add(dir.Mul(float64(i) / float64(n)))
+		wps = append(wps, p.Add(normal.Mul(offset)))
+		offset = -offset
+	}
+	return append(wps, b)
+}
@@ -0,0 +1,117 @@
+package retreat
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/risk"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestZigzag(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   arena.Point
+		leg    float64
+		offset float64
+		want   []arena.Point
+	}{
+		{
+			"Short",
+			arena.Point{}, arena.Point{X: 2},
+			3, 1,
+			[]arena.Point{{X: 2}},
+		},
+		{
+			"Left first",
+			arena.Point{}, arena.Point{X: 9},
+			3, 1,
+			[]arena.Point{{X: 3, Y: 1}, {X: 6, Y: -1}, {X: 9}},
+		},
+		{
+			"Right first",
+			arena.Point{}, arena.Point{Y: 6},
+			3, -1,
+			[]arena.Point{{X: 1, Y: 3}, {Y: 6}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Zigzag(tt.a, tt.b, tt.leg, tt.offset)
+			if len(got) != len(tt.want) {
+				t.Fatalf("unexpected waypoints: got=%v want=%v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Sub(tt.want[i]).Len() > 1e-9 {
+					t.Errorf("unexpected waypoint %v: got=%v want=%v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetreat(t *testing.T) {
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	n := nav.New(r, w, nav.Config{})
+	m := risk.New(arena.Rectangle(40, 40), w, nil, nil, risk.Config{})
+	for _, obs := range []rtb.Observer{w, n, m} {
+		r.AddObserver(obs)
+	}
+
+	var infos int
+	main := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageInfo); ok {
+			infos++
+		}
+	})
+	var changes []bool
+	rt := New(main, w, n, m, Config{
+		OnChange: func(retreating bool) { changes = append(changes, retreating) },
+	})
+
+	time := 0.0
+	tick := func(energy float64, robots int) {
+		time++
+		r.Deliver(rt, rtb.MessageRobotsLeft{NumRobots: robots})
+		r.Deliver(rt, rtb.MessageEnergy{EnergyLevel: energy})
+		r.Deliver(rt, rtb.MessageCoordinates{X: 20, Y: 20})
+		r.Deliver(rt, rtb.MessageInfo{Time: time})
+	}
+
+	r.Deliver(rt, rtb.MessageGameStarts{})
+	tick(80, 5)
+	if rt.Retreating() || infos != 1 {
+		t.Fatalf("unexpected state with high energy: retreating=%v infos=%v", rt.Retreating(), infos)
+	}
+
+	tick(20, 5)
+	if !rt.Retreating() || infos != 1 {
+		t.Fatalf("unexpected state with low energy: retreating=%v infos=%v", rt.Retreating(), infos)
+	}
+	if n.Done() {
+		t.Errorf("no retreat path")
+	}
+
+	r.Deliver(rt, rtb.MessageRadar{Distance: 5, Object: rtb.ObjectCookie, RadarAngle: math.Pi / 2})
+	wps := n.Waypoints()
+	cookie := arena.Point{X: 20, Y: 25}
+	if len(wps) == 0 || wps[len(wps)-1].Sub(cookie).Len() > 1e-9 {
+		t.Errorf("unexpected path to cookie: got=%v want=%v", wps, cookie)
+	}
+
+	tick(20, 2)
+	if rt.Retreating() || infos != 2 {
+		t.Fatalf("unexpected state in duel: retreating=%v infos=%v", rt.Retreating(), infos)
+	}
+
+	want := []bool{true, false}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("unexpected changes: got=%v want=%v", changes, want)
+	}
+}
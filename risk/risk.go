@@ -0,0 +1,336 @@
+// Package risk implements a composite risk map of the arena, which blends the
+// threats known by the robot: the proximity of the enemies, their lines of
+// fire, the mines detected by the radar, the walls and the lanes of the
+// enemy shots recorded by a danger map. The movement planner uses it to
+// choose where to go:
+//
+//	m := risk.New(a, w, tr, dm, risk.Config{})
+//	r.AddObserver(m)
+//	...
+//	n.GoTo(m.SafestReachablePoint(5))
+//
+// Enemies usually aim at the robot, so their line of fire is estimated as
+// the line from each enemy through the current position of the robot.
+package risk
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/danger"
+	"github.com/jroimartin/rtb/draw"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Config is the configuration of a Map. The weights scale the contribution
+// of every threat to the risk, which is between 0 and the weight for every
+// enemy, mine, wall or shot lane.
+type Config struct {
+	// EnemyWeight is the weight of the proximity of the enemies. If
+	// zero, 1 is used.
+	EnemyWeight float64
+
+	// EnemyRange is the distance from an enemy at which it is no longer
+	// a threat. If zero, 10 is used.
+	EnemyRange float64
+
+	// AimWeight is the weight of the lines of fire of the enemies. If
+	// zero, 1 is used.
+	AimWeight float64
+
+	// AimWidth is the distance from a line of fire at which it is no
+	// longer dangerous. If zero, 1.5 is used.
+	AimWidth float64
+
+	// MineWeight is the weight of the mines. If zero, 2 is used.
+	MineWeight float64
+
+	// MineRange is the distance from a mine at which it is no longer
+	// dangerous. If zero, 2 is used.
+	MineRange float64
+
+	// WallWeight is the weight of the walls. If zero, 1 is used.
+	WallWeight float64
+
+	// WallRange is the distance from a wall at which it is no longer
+	// dangerous. If zero, 2 is used.
+	WallRange float64
+
+	// ShotWeight is the weight of the shot lanes of the danger map. If
+	// zero, 1 is used.
+	ShotWeight float64
+
+	// Directions is the number of directions evaluated by
+	// SafestDirection and SafestReachablePoint. If zero, 16 is used.
+	Directions int
+
+	// Lookahead is the distance along every direction evaluated by
+	// SafestDirection. If zero, 3 is used.
+	Lookahead float64
+}
+
+// Map is a risk map. It implements the rtb.Observer interface, recording the
+// mines detected by the radar, and must be added to the robot after the
+// world model, the tracker and the danger map. Map methods can be called
+// concurrently.
+type Map struct {
+	rtb.NopCommand
+
+	cfg Config
+	a   *arena.Arena
+	w   *world.World
+	tr  *track.Tracker
+	dm  *danger.Map
+
+	mu    sync.Mutex
+	mines []arena.Point
+}
+
+// New returns a risk map for the arena a. The robot must know its absolute
+// position, i.e. the SendRobotCoordinates game option must be 2. If a is
+// nil, the walls are ignored. If tr is nil, the enemies are ignored. If dm
+// is nil, the shot lanes are ignored.
+func New(a *arena.Arena, w *world.World, tr *track.Tracker, dm *danger.Map, cfg Config) *Map {
+	if cfg.EnemyWeight == 0 {
+		cfg.EnemyWeight = 1
+	}
+	if cfg.EnemyRange == 0 {
+		cfg.EnemyRange = 10
+	}
+	if cfg.AimWeight == 0 {
+		cfg.AimWeight = 1
+	}
+	if cfg.AimWidth == 0 {
+		cfg.AimWidth = 1.5
+	}
+	if cfg.MineWeight == 0 {
+		cfg.MineWeight = 2
+	}
+	if cfg.MineRange == 0 {
+		cfg.MineRange = 2
+	}
+	if cfg.WallWeight == 0 {
+		cfg.WallWeight = 1
+	}
+	if cfg.WallRange == 0 {
+		cfg.WallRange = 2
+	}
+	if cfg.ShotWeight == 0 {
+		cfg.ShotWeight = 1
+	}
+	if cfg.Directions == 0 {
+		cfg.Directions = 16
+	}
+	if cfg.Lookahead == 0 {
+		cfg.Lookahead = 3
+	}
+	return &Map{cfg: cfg, a: a, w: w, tr: tr, dm: dm}
+}
+
+// Message records the mines detected by the radar. They are forgotten when
+// a new game starts.
+func (m *Map) Message(msg rtb.Message) {
+	switch msg := msg.(type) {
+	case rtb.MessageGameStarts:
+		m.mu.Lock()
+		m.mines = nil
+		m.mu.Unlock()
+	case rtb.MessageRadar:
+		if msg.Object != rtb.ObjectMine {
+			return
+		}
+		m.AddMine(m.w.Absolute(msg.RadarAngle, msg.Distance))
+	}
+}
+
+// AddMine records a mine at p. Mines closer than a robot radius to a known
+// mine are considered the same mine.
+func (m *Map) AddMine(p arena.Point) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, q := range m.mines {
+		if q.Sub(p).Len() < physics.RobotRadius {
+			return
+		}
+	}
+	m.mines = append(m.mines, p)
+}
+
+// Mines returns the recorded mines.
+func (m *Map) Mines() []arena.Point {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]arena.Point(nil), m.mines...)
+}
+
+// threats is a snapshot of the threats, so the risk of many points can be
+// computed consistently.
+type threats struct {
+	pos     arena.Point
+	enemies []arena.Point
+	mines   []arena.Point
+}
+
+// snapshot returns the current threats.
+func (m *Map) snapshot() threats {
+	t := threats{pos: m.w.State().Pos, mines: m.Mines()}
+	if m.tr != nil {
+		for _, tt := range m.tr.Tracks() {
+			if !tt.TeamMate {
+				t.enemies = append(t.enemies, tt.Pos)
+			}
+		}
+	}
+	return t
+}
+
+// At returns the risk at p.
+func (m *Map) At(p arena.Point) float64 {
+	return m.risk(m.snapshot(), p)
+}
+
+// risk returns the risk at p given the threats t.
+func (m *Map) risk(t threats, p arena.Point) float64 {
+	var risk float64
+	for _, e := range t.enemies {
+		risk += m.cfg.EnemyWeight * falloff(p.Sub(e).Len(), m.cfg.EnemyRange)
+
+		// The line of fire goes from the enemy through the robot and
+		// beyond, up to the range of the enemy.
+		if dir := t.pos.Sub(e); dir.Len() > 0 {
+			end := e.Add(dir.Mul(math.Max(1, m.cfg.EnemyRange/dir.Len())))
+			d := arena.SegmentDistance(p, e, end)
+			risk += m.cfg.AimWeight * falloff(d, m.cfg.AimWidth)
+		}
+	}
+	for _, mine := range t.mines {
+		risk += m.cfg.MineWeight * falloff(p.Sub(mine).Len(), m.cfg.MineRange)
+	}
+	if m.a != nil {
+		risk += m.cfg.WallWeight * falloff(wallDistance(m.a, p), m.cfg.WallRange)
+	}
+	if m.dm != nil {
+		risk += m.cfg.ShotWeight * m.dm.At(p)
+	}
+	return risk
+}
+
+// directions returns the candidate directions.
+func (m *Map) directions() []float64 {
+	dirs := make([]float64, m.cfg.Directions)
+	for i := range dirs {
+		dirs[i] = 2 * math.Pi * float64(i) / float64(len(dirs))
+	}
+	return dirs
+}
+
+// SafestDirection returns the absolute heading, among Config.Directions
+// evenly spaced ones, with the lowest mean risk along the next
+// Config.Lookahead units of length. The directions blocked by a wall within
+// that distance are avoided, unless all of them are.
+func (m *Map) SafestDirection() float64 {
+	t := m.snapshot()
+
+	best, bestRisk, bestBlocked := 0.0, math.Inf(1), true
+	for _, dir := range m.directions() {
+		end := t.pos.Add(arena.Polar(dir, m.cfg.Lookahead))
+		blocked := !m.reachable(t.pos, end)
+		var risk float64
+		const samples = 4
+		for i := 1; i <= samples; i++ {
+			risk += m.risk(t, t.pos.Add(arena.Polar(dir, m.cfg.Lookahead*float64(i)/samples))) / samples
+		}
+		if blocked == bestBlocked && risk < bestRisk || bestBlocked && !blocked {
+			best, bestRisk, bestBlocked = dir, risk, blocked
+		}
+	}
+	return best
+}
+
+// SafestReachablePoint returns the point with the lowest risk within radius
+// of the robot that can be reached in a straight line without hitting a
+// wall. The candidates are the position of the robot and the points of
+// Config.Directions rays at every unit of length up to radius. Ties are
+// broken by distance to the robot.
+func (m *Map) SafestReachablePoint(radius float64) arena.Point {
+	t := m.snapshot()
+
+	best, bestRisk := t.pos, m.risk(t, t.pos)
+	for _, dir := range m.directions() {
+		for r := 1.0; r <= radius; r++ {
+			p := t.pos.Add(arena.Polar(dir, r))
+			if !m.reachable(t.pos, p) {
+				// The rest of the ray is blocked too.
+				break
+			}
+			if risk := m.risk(t, p); risk < bestRisk {
+				best, bestRisk = p, risk
+			}
+		}
+	}
+	return best
+}
+
+// reachable returns true if a robot can go in a straight line from a to b
+// without hitting a wall.
+func (m *Map) reachable(a, b arena.Point) bool {
+	if m.a == nil {
+		return true
+	}
+	n := int(math.Ceil(b.Sub(a).Len() / (physics.RobotRadius / 2)))
+	for i := 1; i <= n; i++ {
+		p := a.Add(b.Sub(a).Mul(float64(i) / float64(n)))
+		if wallDistance(m.a, p) < physics.RobotRadius {
+			return false
+		}
+	}
+	return true
+}
+
+// Grid returns a grid with the risk at the center of each cell, which can be
+// rendered with draw.Canvas.Heatmap.
+func (m *Map) Grid(origin arena.Point, cellSize float64, cols, rows int) *draw.Grid {
+	t := m.snapshot()
+	g := draw.NewGrid(origin, cellSize, cols, rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			g.Set(col, row, m.risk(t, g.Center(col, row)))
+		}
+	}
+	return g
+}
+
+// falloff returns 1 at distance 0, decreasing linearly to 0 at distance
+// max.
+func falloff(d, max float64) float64 {
+	return math.Max(0, 1-d/max)
+}
+
+// wallDistance returns the distance from p to the closest wall of a or to
+// the boundary of a. It is zero outside of the boundary. The Arc walls are
+// ignored.
+func wallDistance(a *arena.Arena, p arena.Point) float64 {
+	b := a.Boundary
+	if !b.Contains(p) {
+		return 0
+	}
+	d := math.Min(math.Min(p.X-b.Min.X, b.Max.X-p.X), math.Min(p.Y-b.Min.Y, b.Max.Y-p.Y))
+	for _, s := range a.Segments() {
+		d = math.Min(d, arena.SegmentDistance(p, s.A, s.B)-s.Thickness/2)
+	}
+	for _, w := range a.Walls {
+		switch w := w.(type) {
+		case arena.Circle:
+			d = math.Min(d, p.Sub(w.Center).Len()-w.Radius)
+		case arena.InnerCircle:
+			d = math.Min(d, w.Radius-p.Sub(w.Center).Len())
+		}
+	}
+	return math.Max(0, d)
+}
@@ -0,0 +1,115 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// setup returns a risk map for a, with the robot at (10, 10) and the given
+// enemies detected by the radar.
+func setup(a *arena.Arena, enemies ...arena.Point) *Map {
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	m := New(a, w, tr, nil, Config{})
+	for _, o := range []rtb.Observer{w, tr, m} {
+		o.Message(rtb.MessageGameStarts{})
+		o.Message(rtb.MessageCoordinates{X: 10, Y: 10})
+		o.Message(rtb.MessageInfo{Time: 0})
+		for _, e := range enemies {
+			d := e.Sub(arena.Point{X: 10, Y: 10})
+			o.Message(rtb.MessageRadar{Distance: d.Len(), Object: rtb.ObjectRobot, RadarAngle: math.Atan2(d.Y, d.X)})
+		}
+	}
+	return m
+}
+
+func TestAt(t *testing.T) {
+	m := setup(arena.Rectangle(20, 20), arena.Point{X: 15, Y: 10})
+	m.AddMine(arena.Point{X: 10, Y: 15})
+	m.AddMine(arena.Point{X: 10.1, Y: 15})
+	if n := len(m.Mines()); n != 1 {
+		t.Errorf("wrong number of mines: got=%v want=%v", n, 1)
+	}
+
+	tests := []struct {
+		name        string
+		safe, risky arena.Point
+	}{
+		{"Enemy", arena.Point{X: 10, Y: 7}, arena.Point{X: 14, Y: 8}},
+		{"Line of fire", arena.Point{X: 6, Y: 7}, arena.Point{X: 6, Y: 10}},
+		{"Mine", arena.Point{X: 5, Y: 15}, arena.Point{X: 10, Y: 14.5}},
+		{"Wall", arena.Point{X: 5, Y: 5}, arena.Point{X: 0.5, Y: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if safe, risky := m.At(tt.safe), m.At(tt.risky); safe >= risky {
+				t.Errorf("unexpected risk: safe=%v risky=%v", safe, risky)
+			}
+		})
+	}
+}
+
+func TestSafestDirection(t *testing.T) {
+	m := setup(arena.Rectangle(20, 20), arena.Point{X: 15, Y: 10})
+
+	// Moving towards the enemy or along its line of fire is risky.
+	dir := m.SafestDirection()
+	if math.Cos(dir) > 0 || math.Abs(math.Sin(dir)) < 0.5 {
+		t.Errorf("unexpected direction: %v", dir)
+	}
+}
+
+func TestSafestReachablePoint(t *testing.T) {
+	// The wall at x=12 blocks the way away from the enemy.
+	a := arena.Rectangle(20, 20)
+	a.Walls = append(a.Walls, arena.Line{
+		Material:  arena.DefaultMaterial,
+		Thickness: arena.DefaultThickness,
+		Start:     arena.Point{X: 12, Y: 0},
+		End:       arena.Point{X: 12, Y: 20},
+	})
+	m := setup(a, arena.Point{X: 7, Y: 10})
+
+	p := m.SafestReachablePoint(5)
+	pos := arena.Point{X: 10, Y: 10}
+	if d := p.Sub(pos).Len(); d > 5 || d == 0 {
+		t.Errorf("unexpected distance: %v", d)
+	}
+	if p.X > 12-physics.RobotRadius {
+		t.Errorf("unreachable point: %v", p)
+	}
+	if m.At(p) >= m.At(pos) {
+		t.Errorf("the point is not safer: risk=%v current=%v", m.At(p), m.At(pos))
+	}
+}
+
+func TestWallDistance(t *testing.T) {
+	a := arena.Rectangle(20, 20)
+	a.Walls = append(a.Walls,
+		arena.Circle{Center: arena.Point{X: 10, Y: 10}, Radius: 2},
+		arena.InnerCircle{Center: arena.Point{X: 10, Y: 10}, Radius: 9},
+	)
+
+	tests := []struct {
+		p    arena.Point
+		want float64
+	}{
+		{arena.Point{X: 10, Y: 13}, 1},
+		{arena.Point{X: 10, Y: 18}, 1},
+		{arena.Point{X: 10, Y: 9}, 0},
+		{arena.Point{X: -1, Y: 5}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := wallDistance(a, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("unexpected distance at %v: got=%v want=%v", tt.p, got, tt.want)
+		}
+	}
+}
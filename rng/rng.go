@@ -0,0 +1,123 @@
+// Package rng provides the random numbers used by the components of this
+// module, so games replayed with the same seed and the same messages are
+// reproducible.
+//
+// Components that use random numbers, like the dodge jitter or the radar
+// jitter, accept a *rand.Rand in their configuration. If it is nil, they use
+// the default generator of this package, whose seed is taken from the
+// environment variable RTB_SEED or, if it is not set, from the current time.
+// The seed is logged by robots that want to reproduce a game later:
+//
+//	r.Logger().Info("random seed", "seed", rng.CurrentSeed())
+//
+// The simulator and the tuning harnesses take explicit seeds instead, so
+// their runs are always reproducible.
+package rng
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lockedSource is a rand.Source64 that can be used concurrently.
+type lockedSource struct {
+	mu   sync.Mutex
+	src  rand.Source64
+	seed int64
+}
+
+// newLockedSource returns a lockedSource seeded with seed.
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+	s.seed = seed
+}
+
+// std is the default source.
+var std = newLockedSource(initialSeed())
+
+// def is the default generator. rand.Rand keeps no state besides its
+// source, except for Read, so it can be shared when the source is locked.
+var def = rand.New(std)
+
+// initialSeed returns the seed in RTB_SEED or, if it is not set or it is not
+// valid, the current time.
+func initialSeed() int64 {
+	if seed, ok := parseSeed(os.Getenv("RTB_SEED")); ok {
+		return seed
+	}
+	return time.Now().UnixNano()
+}
+
+// parseSeed parses a seed in decimal or with a base prefix.
+func parseSeed(s string) (int64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	seed, err := strconv.ParseInt(s, 0, 64)
+	return seed, err == nil
+}
+
+// Seed seeds the default generator.
+func Seed(seed int64) {
+	std.Seed(seed)
+}
+
+// CurrentSeed returns the last seed of the default generator.
+func CurrentSeed() int64 {
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	return std.seed
+}
+
+// Source returns the source of the default generator. It can be used
+// concurrently.
+func Source() rand.Source64 {
+	return std
+}
+
+// Default returns the default generator. It can be used concurrently, except
+// for the Read method.
+func Default() *rand.Rand {
+	return def
+}
+
+// New returns a new generator seeded from the default one. It is meant for
+// components that need their own stream of numbers, which is reproducible
+// as long as the components are created in the same order. The returned
+// generator cannot be used concurrently.
+func New() *rand.Rand {
+	return rand.New(rand.NewSource(std.Int63()))
+}
+
+// Or returns r or, if it is nil, the default generator.
+func Or(r *rand.Rand) *rand.Rand {
+	if r == nil {
+		return def
+	}
+	return r
+}
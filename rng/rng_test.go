@@ -0,0 +1,75 @@
+package rng
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestSeed(t *testing.T) {
+	Seed(42)
+	if got := CurrentSeed(); got != 42 {
+		t.Errorf("wrong seed: got=%v want=42", got)
+	}
+	a := []float64{Default().Float64(), Default().Float64()}
+	child := New().Int63()
+
+	Seed(42)
+	b := []float64{Default().Float64(), Default().Float64()}
+	if a[0] != b[0] || a[1] != b[1] {
+		t.Errorf("sequences differ with the same seed: %v %v", a, b)
+	}
+	if got := New().Int63(); got != child {
+		t.Errorf("child generators differ with the same seed: got=%v want=%v", got, child)
+	}
+
+	want := rand.New(rand.NewSource(42)).Float64()
+	if a[0] != want {
+		t.Errorf("wrong first number: got=%v want=%v", a[0], want)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				Default().Float64()
+				Source().Uint64()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOr(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	if Or(r) != r {
+		t.Errorf("Or did not return the given generator")
+	}
+	if Or(nil) != Default() {
+		t.Errorf("Or did not return the default generator")
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		ok   bool
+	}{
+		{"", 0, false},
+		{"123", 123, true},
+		{"-5", -5, true},
+		{"0x10", 16, true},
+		{"abc", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSeed(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("wrong seed for %q: got=%v,%v want=%v,%v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
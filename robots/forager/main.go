@@ -0,0 +1,155 @@
+// forager is an example robot that explores the arena collecting cookies and
+// disposing of the mines in its path, without fighting. It shows how to
+// build a movement stack on top of the navigator: exploration, foraging,
+// mine disposal and collision recovery.
+package main
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/draw"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/forage"
+	"github.com/jroimartin/rtb/mines"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/recovery"
+	"github.com/jroimartin/rtb/world"
+)
+
+const (
+	// cellSize is the size of the cells of the map.
+	cellSize = 2
+
+	// mapSize is the number of cells of each side of the map. The map
+	// is centered on the start position, so it covers arenas up to
+	// twice its size.
+	mapSize = 60
+
+	// step is the distance to the exploration waypoints.
+	step = 8
+)
+
+// explorer moves the robot to the least visited places of the arena, avoiding
+// the walls found by the radar.
+type explorer struct {
+	w  *world.World
+	n  *nav.Navigator
+	f  *forage.Forager
+	rh *recovery.Handler
+	c  *draw.Canvas
+
+	// walls and visited map the arena. walls counts the radar
+	// detections of walls per cell and visited the ticks spent in every
+	// cell.
+	walls, visited *draw.Grid
+}
+
+// newExplorer returns an explorer with empty maps.
+func newExplorer(w *world.World, n *nav.Navigator, f *forage.Forager, rh *recovery.Handler, c *draw.Canvas) *explorer {
+	x := &explorer{w: w, n: n, f: f, rh: rh, c: c}
+	x.reset()
+	return x
+}
+
+// reset clears the maps.
+func (x *explorer) reset() {
+	origin := arena.Point{X: -mapSize * cellSize / 2, Y: -mapSize * cellSize / 2}
+	x.walls = draw.NewGrid(origin, cellSize, mapSize, mapSize)
+	x.visited = draw.NewGrid(origin, cellSize, mapSize, mapSize)
+}
+
+// cell returns the cell of the maps containing p. It returns false if p is
+// outside the maps.
+func (x *explorer) cell(p arena.Point) (col, row int, ok bool) {
+	d := p.Sub(x.walls.Origin)
+	col, row = int(math.Floor(d.X/cellSize)), int(math.Floor(d.Y/cellSize))
+	if col < 0 || col >= mapSize || row < 0 || row >= mapSize {
+		return 0, 0, false
+	}
+	return col, row, true
+}
+
+// Handle updates the maps and chooses the next exploration waypoint when the
+// robot is idle.
+func (x *explorer) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if !m.First {
+			return
+		}
+		r.Name("forager")
+		r.Colour("00ff80", "8000ff")
+	case rtb.MessageGameStarts:
+		x.reset()
+		r.Rotate(rtb.PartRadar, math.Pi)
+	case rtb.MessageRadar:
+		if m.Object != rtb.ObjectWall {
+			return
+		}
+		if col, row, ok := x.cell(x.w.Absolute(m.RadarAngle, m.Distance)); ok {
+			x.walls.Set(col, row, x.walls.At(col, row)+1)
+		}
+	case rtb.MessageInfo:
+		pos := x.w.State().Pos
+		if col, row, ok := x.cell(pos); ok {
+			x.visited.Set(col, row, x.visited.At(col, row)+1)
+		}
+		if math.Mod(m.Time, 1) < 0.1 {
+			x.c.Heatmap(x.walls, draw.HeatmapConfig{Lifetime: 1})
+		}
+
+		_, foraging := x.f.Target()
+		if foraging || x.rh.Active() || !x.n.Done() {
+			return
+		}
+		x.n.GoTo(x.next(pos))
+	}
+}
+
+// next returns the next exploration waypoint: the least visited of the
+// points around pos whose path is free of known walls.
+func (x *explorer) next(pos arena.Point) arena.Point {
+	best, bestScore := pos, math.Inf(1)
+	for i := 0; i < 12; i++ {
+		p := pos.Add(arena.Polar(2*math.Pi*float64(i)/12, step))
+
+		score := 0.0
+		for d := cellSize / 2.0; d <= step; d += cellSize / 2.0 {
+			q := pos.Add(p.Sub(pos).Mul(d / step))
+			col, row, ok := x.cell(q)
+			if !ok {
+				score = math.Inf(1)
+				break
+			}
+			score += x.visited.At(col, row) + 100*x.walls.At(col, row)
+		}
+		if score < bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+func main() {
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	// Observers are called in the order they are added, so the world
+	// model and the energy manager are updated before they are used.
+	w := world.New()
+	e := energy.New(energy.Config{CookieThreshold: 100})
+	n := nav.New(r, w, nav.Config{})
+	f := forage.New(w, e, nil, n, forage.Config{})
+	md := mines.New(r, w, e, n, mines.Config{})
+	rh := recovery.New(r, w, n, recovery.Config{})
+	c := draw.New(r, w)
+	for _, o := range []rtb.Observer{w, e, n, f, md, rh, c} {
+		r.AddObserver(o)
+	}
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, newExplorer(w, n, f, rh, c))
+}
@@ -0,0 +1,49 @@
+// policybot is an example robot that plays with a policy trained with the gym
+// package and exported to ONNX. The model is loaded at startup from the file
+// given by -model, which defaults to policy.onnx in the directory of the
+// executable, since the RealTimeBattle server runs robots without arguments.
+//
+// The policy must use the default encodings of the gym package.
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/onnx"
+)
+
+var modelPath = flag.String("model", filepath.Join(filepath.Dir(os.Args[0]), "policy.onnx"), "load the policy from the ONNX file at `path`")
+
+// named sends the name and colour of the robot and passes every message to
+// the policy.
+type named struct {
+	policy rtb.Strategy
+}
+
+func (n named) Handle(r *rtb.Robot, msg rtb.Message) {
+	if m, ok := msg.(rtb.MessageInitialize); ok && m.First {
+		r.Name("policybot")
+		r.Colour("ff00ff", "00ff00")
+	}
+	n.policy.Handle(r, msg)
+}
+
+func main() {
+	flag.Parse()
+
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	s, err := onnx.NewStrategy(*modelPath, nil, nil)
+	if err != nil {
+		r.Logger().Error("could not load policy", "err", err)
+		return
+	}
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, named{policy: s})
+}
@@ -0,0 +1,139 @@
+// replaybot is an example robot that records its matches, so its decision
+// logic can be re-run offline against the recorded messages.
+//
+// When run by the server, it plays with the duelist strategy and writes two
+// kinds of logs to the directory given by -dir: the raw messages received
+// from the server, in messages.log, and a telemetry file per game, with the
+// messages, the commands and a snapshot of the world model every tick.
+//
+// To replay a match, run it with -replay pointing to a messages log:
+//
+//	replaybot -replay /tmp/replaybot/messages.log
+//
+// The commands sent by the strategy are written to the standard output.
+//
+// To check whether the current strategy still takes the same decisions, run
+// it with -diff pointing to a telemetry file:
+//
+//	replaybot -diff /tmp/replaybot/game-0001.jsonl
+//
+// The points where the commands differ from the recorded ones are written to
+// the standard output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/duel"
+	"github.com/jroimartin/rtb/replay"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+var (
+	dir        = flag.String("dir", filepath.Join(os.TempDir(), "replaybot"), "directory of the logs")
+	replayPath = flag.String("replay", "", "replay the messages log at `path`")
+	diffPath   = flag.String("diff", "", "compare the commands with the telemetry file at `path`")
+)
+
+// newStrategy returns the strategy of the robot. It is used both in live
+// matches and replays, so the decision logic is the same.
+func newStrategy(r *rtb.Robot) *duel.Duelist {
+	return duel.New(r, duel.Config{Name: "replaybot"})
+}
+
+func main() {
+	flag.Parse()
+
+	if *replayPath != "" {
+		if err := replayLog(*replayPath); err != nil {
+			fmt.Fprintf(os.Stderr, "replaybot: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *diffPath != "" {
+		if err := diffTelemetry(*diffPath); err != nil {
+			fmt.Fprintf(os.Stderr, "replaybot: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		rtb.Logger().Error("could not create directory", "err", err)
+		return
+	}
+
+	r, f, err := replay.RecordRobot(filepath.Join(*dir, "messages.log"))
+	if err != nil {
+		rtb.Logger().Error("could not record messages", "err", err)
+		return
+	}
+	defer f.Close()
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	s := newStrategy(r)
+
+	// The recorder is added after the components of the strategy, so the
+	// snapshots reflect the state after every Info message.
+	rec, err := telemetry.New(telemetry.Config{
+		Dir:      *dir,
+		Snapshot: func() any { return s.World().State() },
+	})
+	if err != nil {
+		r.Logger().Error("could not create recorder", "err", err)
+		return
+	}
+	defer rec.Close()
+	r.AddObserver(rec)
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, s)
+}
+
+// replayLog replays the messages log at path and writes the commands sent by
+// the strategy to the standard output.
+func replayLog(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open log: %v", err)
+	}
+	defer f.Close()
+
+	cmds, err := replay.ReplayFunc(f, func(r *rtb.Robot) rtb.Strategy { return newStrategy(r) })
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(cmds)
+	return err
+}
+
+// diffTelemetry replays the telemetry file at path and writes the points
+// where the commands sent by the strategy differ from the recorded ones to
+// the standard output.
+func diffTelemetry(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open telemetry: %v", err)
+	}
+	defer f.Close()
+
+	recs, err := telemetry.Read(f)
+	if err != nil {
+		return err
+	}
+	divs, err := replay.Diff(recs, func(r *rtb.Robot) rtb.Strategy { return newStrategy(r) })
+	if err != nil {
+		return err
+	}
+	for _, d := range divs {
+		fmt.Println(d)
+	}
+	return nil
+}
@@ -2,13 +2,14 @@
 package main
 
 import (
+	"log/slog"
 	"math"
 
 	"github.com/jroimartin/rtb"
 )
 
 func main() {
-	rtb.Debug = true
+	rtb.SetLogger(slog.New(rtb.NewWindowHandler(nil, &slog.HandlerOptions{Level: slog.LevelDebug})))
 
 	settings := rtb.ListenSettings{
 		SendRotationReached: 2,
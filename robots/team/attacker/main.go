@@ -0,0 +1,35 @@
+// attacker is one of the robots of the "example" team. It fights with the
+// duelist strategy and shares the enemies it tracks with its team mate,
+// support, which also feeds it with the enemies it sees.
+//
+// Team members share their positions, so the SendRobotCoordinates game
+// option must be 2.
+package main
+
+import (
+	"log/slog"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/duel"
+	"github.com/jroimartin/rtb/team"
+)
+
+func main() {
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	sock, err := team.Listen(team.Dir("example"), "attacker")
+	if err != nil {
+		r.Logger().Error("could not join team", "err", err)
+		return
+	}
+	defer sock.Close()
+
+	d := duel.New(r, duel.Config{Name: "attacker Team: example"})
+	tm := team.New(sock, d.World(), d.Tracker(), team.Config{Name: "attacker"})
+	tm.SetRole("attacker")
+	r.AddObserver(tm)
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, d)
+}
@@ -0,0 +1,98 @@
+// support is one of the robots of the "example" team. It keeps the radar
+// spinning to spot enemies for its team mate, attacker, stays behind it and
+// shoots at the nearest enemy.
+//
+// Team members share their positions, so the SendRobotCoordinates game
+// option must be 2.
+package main
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/fire"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/team"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// behind is the distance the robot keeps behind the attacker.
+const behind = 6
+
+// support follows the attacker.
+type support struct {
+	w  *world.World
+	tr *track.Tracker
+	n  *nav.Navigator
+	tm *team.Team
+}
+
+// Handle sends the name and colour of the robot and, on every Info message,
+// drives the robot behind the attacker, on the side away from the nearest
+// enemy.
+func (s *support) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if !m.First {
+			return
+		}
+		r.Name("support Team: example")
+		r.Colour("ff8000", "0080ff")
+	case rtb.MessageGameStarts:
+		r.Rotate(rtb.PartRadar, math.Pi)
+	case rtb.MessageInfo:
+		var (
+			attacker team.Report
+			found    bool
+		)
+		for _, rep := range s.tm.Mates() {
+			if rep.Role == "attacker" {
+				attacker, found = rep, true
+			}
+		}
+		if !found {
+			return
+		}
+
+		enemy, ok := s.tr.Nearest(attacker.Pos)
+		if !ok {
+			s.n.GoTo(attacker.Pos)
+			return
+		}
+		d := attacker.Pos.Sub(enemy.Pos)
+		if dist := d.Len(); dist > 0 {
+			s.n.GoTo(attacker.Pos.Add(d.Mul(behind / dist)))
+		}
+	}
+}
+
+func main() {
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	sock, err := team.Listen(team.Dir("example"), "support")
+	if err != nil {
+		r.Logger().Error("could not join team", "err", err)
+		return
+	}
+	defer sock.Close()
+
+	// Observers are called in the order they are added, so the world
+	// model, the tracker and the team are updated before they are used.
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	tm := team.New(sock, w, tr, team.Config{Name: "support"})
+	tm.SetRole("support")
+	e := energy.New(energy.Config{ShotReserve: 2})
+	n := nav.New(r, w, nav.Config{})
+	fc := fire.New(r, w, tr, e, fire.Config{})
+	for _, o := range []rtb.Observer{w, tr, tm, e, n, fc} {
+		r.AddObserver(o)
+	}
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, &support{w: w, tr: tr, n: n, tm: tm})
+}
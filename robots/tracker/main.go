@@ -0,0 +1,82 @@
+// tracker is an example robot that chases and shoots the nearest enemy. It
+// shows how to combine the world model, the enemy tracker, the radar lock
+// and the fire control, which aims at the intercept point of the target.
+package main
+
+import (
+	"log/slog"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/energy"
+	"github.com/jroimartin/rtb/fire"
+	"github.com/jroimartin/rtb/nav"
+	"github.com/jroimartin/rtb/radar"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// chaseDistance is the distance to the target the robot tries to keep.
+const chaseDistance = 6
+
+// chaser chases the nearest enemy.
+type chaser struct {
+	w  *world.World
+	tr *track.Tracker
+	n  *nav.Navigator
+}
+
+// Handle sends the name and colour of the robot and, on every Info message,
+// drives the robot towards the nearest enemy.
+func (c *chaser) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if !m.First {
+			return
+		}
+		r.Name("tracker")
+		r.Colour("ffff00", "00ffff")
+	case rtb.MessageInfo:
+		s := c.w.State()
+		t, ok := c.tr.Nearest(s.Pos)
+		if !ok {
+			c.n.Stop()
+			return
+		}
+
+		// The target is chased at its predicted position, not at
+		// the position where it was last seen.
+		p := t.PredictPosition(m.Time - t.LastSeen)
+		if speed, ok := c.w.Option(rtb.GOptionShotSpeed); ok {
+			if ip, tau, ok := t.Intercept(s.Pos, m.Time, speed); ok {
+				r.Logger().Debug("intercept", "target", t.ID, "point", ip, "flight", tau)
+			}
+		}
+
+		d := p.Sub(s.Pos)
+		if dist := d.Len(); dist > chaseDistance {
+			c.n.GoTo(s.Pos.Add(d.Mul((dist - chaseDistance) / dist)))
+		} else {
+			c.n.Stop()
+		}
+	}
+}
+
+func main() {
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// Observers are called in the order they are added, so the world
+	// model and the tracker are updated before they are used.
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	e := energy.New(energy.Config{ShotReserve: 1})
+	n := nav.New(r, w, nav.Config{})
+	l := radar.New(r, w, tr, radar.Config{})
+	fc := fire.New(r, w, tr, e, fire.Config{})
+	for _, o := range []rtb.Observer{w, tr, e, n, l, fc} {
+		r.AddObserver(o)
+	}
+
+	settings := rtb.ListenSettings{ChanBufferCapacity: 100}
+	r.Run(settings, &chaser{w: w, tr: tr, n: n})
+}
@@ -3,13 +3,21 @@ package rtb
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"os"
-	"regexp"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jroimartin/rtb/physics"
 )
 
 var (
@@ -22,23 +30,207 @@ var (
 	osStdout io.Writer = os.Stdout
 )
 
+// Robot is a client of the RTB server. It receives messages from the server
+// and sends commands to it. Robot methods can be called concurrently.
+type Robot struct {
+	in  io.Reader
+	out io.Writer
+
+	// mu serializes the writes to out and protects the fields below.
+	mu        sync.Mutex
+	observers []Observer
+	logger    *slog.Logger
+
+	// options are the game options received, used to validate the
+	// commands.
+	options map[GOption]float64
+
+	// name, homeColour and awayColour are the last name and colours
+	// sent, so they can be sent again when the server warns.
+	name, homeColour, awayColour string
+
+	// warnPolicy is the warning policy. If nil, the default policy is
+	// used.
+	warnPolicy *WarningPolicy
+
+	// colourPolicy is the colour policy. If nil, the assigned colours
+	// are accepted.
+	colourPolicy *ColourPolicy
+
+	// colourRequests is the number of colour requests made by the
+	// colour policy in the current sequence.
+	colourRequests int
+
+	// errs is the channel returned by Errors. It is created on demand.
+	errs chan error
+
+	// waiters are the rotations waiting for a RotationReached message.
+	waiters []*rotationWaiter
+
+	// current is the message being delivered.
+	current Message
+
+	// dropped is the number of messages dropped by Listen.
+	dropped int
+
+	// parseErrors is the number of lines received by Listen that could
+	// not be parsed.
+	parseErrors int
+
+	// clock is the game time of the last Info message of the current
+	// game.
+	clock float64
+
+	// dialect is the dialect spoken by the server.
+	dialect Dialect
+
+	// floatFmt is the format of the floats of the commands. If nil, the
+	// default format is used.
+	floatFmt *FloatFormat
+
+	// textSplit defines how long texts are sent. If nil, the default
+	// text split is used.
+	textSplit *TextSplit
+}
+
+// NewRobot returns a Robot that receives messages from in and sends commands
+// to out. It allows to run robots in the same process, e.g. in a simulator.
+// If in or out are nil, the standard input or output of the process are used
+// respectively.
+func NewRobot(in io.Reader, out io.Writer) *Robot {
+	return &Robot{in: in, out: out}
+}
+
+// std is the default robot used by the package-level functions. It
+// communicates with the server through the standard input and output of the
+// process.
+var std = &Robot{}
+
+// reader returns the reader used to receive messages.
+func (r *Robot) reader() io.Reader {
+	if r.in == nil {
+		return osStdin
+	}
+	return r.in
+}
+
+// writer returns the writer used to send commands.
+func (r *Robot) writer() io.Writer {
+	if r.out == nil {
+		return osStdout
+	}
+	return r.out
+}
+
 // rawf sends a raw message. It returns error if the message is longer than 128
 // characters.
-func rawf(format string, a ...any) error {
-	s := fmt.Sprintf(format, a...)
-	if !strings.HasSuffix(s, "\n") {
-		s += "\n"
-	}
+func (r *Robot) rawf(format string, a ...any) error {
+	cmd := strings.TrimSuffix(fmt.Sprintf(format, a...), "\n")
 
-	if len(s) > 128 {
-		return fmt.Errorf("message is too long (%v)", len(s))
+	r.mu.Lock()
+	s, err := r.dialect.command(cmd)
+	if err != nil {
+		r.mu.Unlock()
+		return err
 	}
 
-	fmt.Fprint(osStdout, s)
+	fmt.Fprint(r.writer(), s)
+	observers := r.observers
+	r.mu.Unlock()
+
+	for _, o := range observers {
+		o.Command(cmd)
+	}
 
 	return nil
 }
 
+// Observer observes the traffic of a Robot.
+type Observer interface {
+	// Message is called for every message delivered to the strategy.
+	Message(msg Message)
+
+	// Command is called for every command sent to the server. cmd does
+	// not include the trailing newline and it uses the keywords of
+	// RealTimeBattle 1.0.8, whatever the dialect of the server.
+	Command(cmd string)
+}
+
+// NopCommand implements the Command method of the Observer interface doing
+// nothing. It can be embedded in the observers that are only interested in
+// the messages.
+type NopCommand struct{}
+
+// Command does nothing.
+func (NopCommand) Command(cmd string) {}
+
+// AddObserver adds an observer to r. Observers are called synchronously, in
+// the order they were added.
+func (r *Robot) AddObserver(o Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// observers is copied before modifying it, because it could be
+	// being iterated.
+	r.observers = append(r.observers[:len(r.observers):len(r.observers)], o)
+}
+
+// Deliver notifies the observers of r about msg and passes it to s.
+func (r *Robot) Deliver(s Strategy, msg Message) {
+	r.mu.Lock()
+	observers := r.observers
+	prev := r.current
+	r.current = msg
+	r.tick(msg)
+	switch m := msg.(type) {
+	case MessageGameOption:
+		r.setOption(m)
+	case *MessageGameOption:
+		r.setOption(*m)
+	case MessageInitialize, *MessageInitialize:
+		r.colourRequests = 0
+	}
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.current = prev
+		r.mu.Unlock()
+	}()
+
+	for _, o := range observers {
+		o.Message(msg)
+	}
+	switch m := msg.(type) {
+	case MessageWarning:
+		r.warn(m)
+	case *MessageWarning:
+		r.warn(*m)
+	case MessageRotationReached:
+		r.rotationReached(m.Part)
+	case *MessageRotationReached:
+		r.rotationReached(m.Part)
+	case MessageYourColour:
+		r.yourColour(m)
+	case *MessageYourColour:
+		r.yourColour(*m)
+	}
+	s.Handle(r, msg)
+}
+
+// setOption records a game option. r.mu must be held.
+func (r *Robot) setOption(m MessageGameOption) {
+	if r.options == nil {
+		r.options = map[GOption]float64{}
+	}
+	r.options[m.Option] = m.Value
+}
+
+// rawf calls rawf on the default Robot.
+func rawf(format string, a ...any) error {
+	return std.rawf(format, a...)
+}
+
 // rOption represents a robot option.
 type rOption int
 
@@ -68,8 +260,13 @@ const (
 )
 
 // robotOption sets option to value.
+func (r *Robot) robotOption(option rOption, value int) error {
+	return r.rawf("RobotOption %d %d", option, value)
+}
+
+// robotOption calls robotOption on the default Robot.
 func robotOption(option rOption, value int) error {
-	return rawf("RobotOption %d %d", option, value)
+	return std.robotOption(option, value)
 }
 
 // Name sets the name of the robot. When receiving a MessageInitialize, if
@@ -77,12 +274,22 @@ func robotOption(option rOption, value int) error {
 // name ends with the string "Team: teamname", you will be in the team
 // "teamname". For example "foo Team: bar" will assign you to the team "bar"
 // and your name will be "foo".
-func Name(name string) error {
-	return rawf("Name %s", name)
+func (r *Robot) Name(name string) error {
+	if err := r.rawf("Name %s", name); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.name = name
+	r.mu.Unlock()
+
+	return nil
 }
 
-// hexColourRe is a regexp that matches a valid hex colour.
-var hexColourRe = regexp.MustCompile(`^[[:xdigit:]]{6}$`)
+// Name calls Name on the default Robot.
+func Name(name string) error {
+	return std.Name(name)
+}
 
 // Colour sets your colour. When receiving a MessageInitialize, if
 // MessageInitialize.First is equal to true, you should send your colour. The
@@ -90,11 +297,24 @@ var hexColourRe = regexp.MustCompile(`^[[:xdigit:]]{6}$`)
 // is already used. Otherwise the away colour or, as a last resort, a
 // non-occupied colour is selected randomly. Colours are specified using a hex
 // string of the form "11aa22".
-func Colour(homeColour, awayColour string) error {
-	if !hexColourRe.MatchString(homeColour) || !hexColourRe.MatchString(awayColour) {
+func (r *Robot) Colour(homeColour, awayColour string) error {
+	if !physics.IsValidColour(homeColour) || !physics.IsValidColour(awayColour) {
 		return errors.New("invalid colour")
 	}
-	return rawf("Colour %s %s", homeColour, awayColour)
+	if err := r.rawf("Colour %s %s", homeColour, awayColour); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.homeColour, r.awayColour = homeColour, awayColour
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Colour calls Colour on the default Robot.
+func Colour(homeColour, awayColour string) error {
+	return std.Colour(homeColour, awayColour)
 }
 
 // Part represents a part of the robot. Part values can be or'ed to specify
@@ -134,69 +354,201 @@ func (p Part) String() string {
 
 // Rotate sets the angular velocity for the robot, its cannon and/or its radar.
 // The angular velocity is given in radians per second and is limited by Robot
-// (cannon/radar) max rotate speed.
+// (cannon/radar) max rotate speed. If v exceeds the limit, the command is
+// sent with the clamped value and an ErrOutOfRange is returned.
+func (r *Robot) Rotate(what Part, v float64) error {
+	v, errRange := r.limitRotate("Rotate", what, v)
+	f := r.floatFormat()
+	return r.sendLimited(errRange, "Rotate %d %s", what, f.format(v))
+}
+
+// Rotate calls Rotate on the default Robot.
 func Rotate(what Part, v float64) error {
-	return rawf("Rotate %d %f", what, v)
+	return std.Rotate(what, v)
 }
 
 // RotateTo is like Rotate, but will rotate to a given angle. Note that radar
 // and cannon angles are relative to the robot angle. You cannot use this
 // command to rotate the robot itself, use RotateAmount instead.
+func (r *Robot) RotateTo(what Part, v, end float64) error {
+	v, errRange := r.limitRotate("RotateTo", what, v)
+	f := r.floatFormat()
+	return r.sendLimited(errRange, "RotateTo %d %s %s", what, f.format(v), f.format(end))
+}
+
+// RotateTo calls RotateTo on the default Robot.
 func RotateTo(what Part, v, end float64) error {
-	return rawf("RotateTo %d %f %f", what, v, end)
+	return std.RotateTo(what, v, end)
 }
 
 // RotateAmount is like Rotate, but will rotate relative to the current angle.
+func (r *Robot) RotateAmount(what Part, v, angle float64) error {
+	v, errRange := r.limitRotate("RotateAmount", what, v)
+	f := r.floatFormat()
+	return r.sendLimited(errRange, "RotateAmount %d %s %s", what, f.format(v), f.format(angle))
+}
+
+// RotateAmount calls RotateAmount on the default Robot.
 func RotateAmount(what Part, v, angle float64) error {
-	return rawf("RotateAmount %d %f %f", what, v, angle)
+	return std.RotateAmount(what, v, angle)
 }
 
 // Sweep is like Rotate, but sets the radar and/or the cannon (not available
 // for the robot itself) in a sweep mode.
+func (r *Robot) Sweep(what Part, v, rightAngle, leftAngle float64) error {
+	v, errRange := r.limitRotate("Sweep", what, v)
+	f := r.floatFormat()
+	return r.sendLimited(errRange, "Sweep %d %s %s %s", what, f.format(v), f.format(rightAngle), f.format(leftAngle))
+}
+
+// Sweep calls Sweep on the default Robot.
 func Sweep(what Part, v, rightAngle, leftAngle float64) error {
-	return rawf("Sweep %d %f %f %f", what, v, rightAngle, leftAngle)
+	return std.Sweep(what, v, rightAngle, leftAngle)
 }
 
 // Accelerate sets the robot acceleration. Value is bounded by Robot max/min
-// acceleration.
+// acceleration. If value is out of bounds, the command is sent with the
+// clamped value and an ErrOutOfRange is returned.
+func (r *Robot) Accelerate(value float64) error {
+	value, errRange := r.limitRange("Accelerate", value, GOptionRobotMinAcceleration, GOptionRobotMaxAcceleration)
+	return r.sendLimited(errRange, "Accelerate %s", r.floatFormat().format(value))
+}
+
+// Accelerate calls Accelerate on the default Robot.
 func Accelerate(value float64) error {
-	return rawf("Accelerate %f", value)
+	return std.Accelerate(value)
 }
 
 // Brake sets the brake. Full brake (portion = 1.0) means that the friction in
-// the robot direction is equal to Slide friction.
+// the robot direction is equal to Slide friction. Some servers spell the
+// command "Break", see DialectBreak and DialectBrakeCompat.
+func (r *Robot) Brake(portion float64) error {
+	return r.rawf("Brake %s", r.floatFormat().format(portion))
+}
+
+// Brake calls Brake on the default Robot.
 func Brake(portion float64) error {
-	return rawf("Brake %f", portion)
+	return std.Brake(portion)
 }
 
-// Shoot with the given energy.
+// Shoot with the given energy. Energy above ShotMaxEnergy is clamped. Shots
+// with energy below ShotMinEnergy are not sent, because the server ignores
+// them.
+func (r *Robot) Shoot(energy float64) error {
+	r.mu.Lock()
+	min, ok := r.options[GOptionShotMinEnergy]
+	r.mu.Unlock()
+	if ok && energy < min {
+		return ErrOutOfRange{Command: "Shoot", Value: energy, Clamped: min}
+	}
+
+	energy, errRange := r.limitRange("Shoot", energy, -1, GOptionShotMaxEnergy)
+	return r.sendLimited(errRange, "Shoot %s", r.floatFormat().format(energy))
+}
+
+// Shoot calls Shoot on the default Robot.
 func Shoot(energy float64) error {
-	return rawf("Shoot %f", energy)
+	return std.Shoot(energy)
 }
 
-// Printf prints a message on the message window.
+// Printf prints a message on the message window. Long texts are split in
+// several messages, see SetTextSplit.
+func (r *Robot) Printf(format string, a ...any) error {
+	return r.sendText("Print", fmt.Sprintf(format, a...))
+}
+
+// Printf calls Printf on the default Robot.
 func Printf(format string, a ...any) error {
-	return rawf("Print "+format, a...)
+	return std.Printf(format, a...)
+}
+
+// Debugf prints a message on the message window if in debug-mode. Long
+// texts are split in several messages, see SetTextSplit.
+func (r *Robot) Debugf(format string, a ...any) error {
+	return r.sendText("Debug", fmt.Sprintf(format, a...))
 }
 
-// Debugf prints a message on the message window if in debug-mode.
+// Debugf calls Debugf on the default Robot.
 func Debugf(format string, a ...any) error {
-	return rawf("Debug "+format, a...)
+	return std.Debugf(format, a...)
 }
 
 // DebugLine draws a line direct to the arena. This is only allowed in the
 // highest debug level (5), otherwise a warning message is sent. The arguments
 // are the start and end point of the line given in polar coordinates relative
 // to the robot.
+func (r *Robot) DebugLine(angle1, radius1, angle2, radius2 float64) error {
+	f := r.floatFormat()
+	return r.rawf("DebugLine %s %s %s %s", f.format(angle1), f.format(radius1), f.format(angle2), f.format(radius2))
+}
+
+// DebugLine calls DebugLine on the default Robot.
 func DebugLine(angle1, radius1, angle2, radius2 float64) error {
-	return rawf("DebugLine %f %f %f %f", angle1, radius1, angle2, radius2)
+	return std.DebugLine(angle1, radius1, angle2, radius2)
 }
 
 // DebugCircle is similar to DebugLine, but draws a circle. The first two
 // arguments are the angle and radius of the central point of the circle
 // relative to the robot. The third argument gives the radius of the circle.
+func (r *Robot) DebugCircle(centerAngle, centerRadius, circleRadius float64) error {
+	f := r.floatFormat()
+	return r.rawf("DebugCircle %s %s %s", f.format(centerAngle), f.format(centerRadius), f.format(circleRadius))
+}
+
+// DebugCircle calls DebugCircle on the default Robot.
 func DebugCircle(centerAngle, centerRadius, circleRadius float64) error {
-	return rawf("DebugCircle %f %f %f", centerAngle, centerRadius, circleRadius)
+	return std.DebugCircle(centerAngle, centerRadius, circleRadius)
+}
+
+// SendRaw sends a command that is not wrapped by the package, e.g. a server
+// extension or a command added by a newer version of the protocol. Floats
+// are formatted with the float format of the robot, integers and types
+// based on them, like Part, in decimal, booleans as 1 or 0 and strings
+// unchanged. The keyword must be a single word and the arguments must not
+// contain newlines. The command goes through the dialect and the length
+// checks like
+// any other command.
+func (r *Robot) SendRaw(keyword string, args ...any) error {
+	if keyword == "" || strings.ContainsAny(keyword, " \t\r\n") {
+		return fmt.Errorf("%w: bad keyword %q", ErrInvalidCommand, keyword)
+	}
+
+	cmd := keyword
+	for i, arg := range args {
+		s, err := r.formatArg(arg)
+		if err != nil {
+			return fmt.Errorf("%w: argument %v: %v", ErrInvalidCommand, i, err)
+		}
+		cmd += " " + s
+	}
+	return r.rawf("%s", cmd)
+}
+
+// SendRaw calls SendRaw on the default Robot.
+func SendRaw(keyword string, args ...any) error {
+	return std.SendRaw(keyword, args...)
+}
+
+// formatArg formats an argument of SendRaw.
+func (r *Robot) formatArg(arg any) (string, error) {
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return r.floatFormat().format(v.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Bool:
+		return encodeBool(v.Bool()), nil
+	case reflect.String:
+		if strings.ContainsAny(v.String(), "\r\n") {
+			return "", fmt.Errorf("newline in %q", v.String())
+		}
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", arg)
+	}
 }
 
 // GOption represents a game option.
@@ -407,6 +759,12 @@ func (warn Warning) String() string {
 	}
 }
 
+// Message is a message sent by the RTB server. It is implemented by all the
+// Message* types.
+type Message interface {
+	isMessage()
+}
+
 type (
 	// MessageInitialize is the very first message the robot will get.
 	MessageInitialize struct {
@@ -553,8 +911,36 @@ type (
 	// MessageExitRobot means that you have to exit immediately. Otherwise
 	// the robot program will be killed forcefully.
 	MessageExitRobot struct{}
+
+	// MessageServerStalled is not sent by the server. Listen delivers it
+	// when no line has been received for ListenSettings.StallTimeout,
+	// which usually means that the server died or the pipe broke.
+	MessageServerStalled struct {
+		// Elapsed is the time in seconds since the last line was
+		// received.
+		Elapsed float64
+	}
 )
 
+func (MessageInitialize) isMessage()      {}
+func (MessageYourName) isMessage()        {}
+func (MessageYourColour) isMessage()      {}
+func (MessageGameOption) isMessage()      {}
+func (MessageGameStarts) isMessage()      {}
+func (MessageRadar) isMessage()           {}
+func (MessageInfo) isMessage()            {}
+func (MessageCoordinates) isMessage()     {}
+func (MessageRobotInfo) isMessage()       {}
+func (MessageRotationReached) isMessage() {}
+func (MessageEnergy) isMessage()          {}
+func (MessageRobotsLeft) isMessage()      {}
+func (MessageCollision) isMessage()       {}
+func (MessageWarning) isMessage()         {}
+func (MessageDead) isMessage()            {}
+func (MessageGameFinishes) isMessage()    {}
+func (MessageExitRobot) isMessage()       {}
+func (MessageServerStalled) isMessage()   {}
+
 // ListenSettings defines the settings passed to Listen.
 type ListenSettings struct {
 	// SendRotationReached tells the server to send a RotationReached
@@ -564,160 +950,561 @@ type ListenSettings struct {
 	// i.e. no messages are sent.
 	SendRotationReached int
 
+	// Input and Output are the streams used to receive messages from the
+	// server and send commands to it. If not nil, they replace the ones
+	// of the robot, so robots can be driven over pipes, sockets or
+	// in-memory streams. By default, the robot uses the streams passed to
+	// NewRobot or, if nil, the standard input and output of the process.
+	Input  io.Reader
+	Output io.Writer
+
 	// ChanBufferCapacity is the buffer capacity of the channel returned by
 	// Listen. If zero, an unbuffered channel is used.
 	ChanBufferCapacity int
+
+	// Overflow is the policy applied when the channel returned by Listen
+	// is full. Default is OverflowBlock.
+	Overflow OverflowPolicy
+
+	// MaxLineLength is the maximum length of a line received from the
+	// server, without the line terminator. Longer lines are handled
+	// according to LongLines. If zero, 1024 is used, which is also the
+	// maximum length of a message accepted by ParseMessage.
+	MaxLineLength int
+
+	// LongLines is the policy applied to the lines longer than
+	// MaxLineLength. Default is LongLineError.
+	LongLines LongLinePolicy
+
+	// Pool makes Listen deliver pointers to messages taken from pools of
+	// reusable messages, e.g. *MessageInfo instead of MessageInfo, so no
+	// message is allocated per line received. It reduces the pressure
+	// on the garbage collector with high message rates.
+	//
+	// Run releases every message after delivering it, so observers and
+	// strategies must not retain the messages after returning. Use
+	// CopyMessage to keep a message. Callers of Listen must call Release
+	// when they are done with a message. The Robot handles both values
+	// and pointers, but the other helpers and the components of this
+	// module expect values, so pooling mode is meant for strategies
+	// written for it.
+	Pool bool
+
+	// StallTimeout is the time without receiving any line from the server
+	// after which Listen delivers a MessageServerStalled. The message is
+	// delivered once per stall. If zero, stalls are not detected.
+	StallTimeout time.Duration
 }
 
+// LongLinePolicy tells Listen what to do with a line longer than
+// ListenSettings.MaxLineLength.
+type LongLinePolicy int
+
+const (
+	// LongLineError discards the line, logs it and emits an error
+	// wrapping ErrLineTooLong on the channel returned by Errors.
+	LongLineError LongLinePolicy = iota
+
+	// LongLineTruncate truncates the line to MaxLineLength and parses
+	// it as usual.
+	LongLineTruncate
+)
+
+// OverflowPolicy tells Listen what to do with a received message when the
+// channel of messages is full, i.e. the strategy is not keeping up with the
+// server.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the message can be delivered. The
+	// messages back up in the standard input and the robot lags more
+	// and more behind the game.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest message in the channel to
+	// make room for the received one. With an unbuffered channel, it
+	// behaves like OverflowDropNewest.
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the received message.
+	OverflowDropNewest
+)
+
 // Listen initializes the RTB communication channel and listens to RTB
 // messages. It returns a channel on which the received messages are delivered.
-func Listen(settings ListenSettings) <-chan any {
+func (r *Robot) Listen(settings ListenSettings) <-chan Message {
+	r.mu.Lock()
+	if settings.Input != nil {
+		r.in = settings.Input
+	}
+	if settings.Output != nil {
+		r.out = settings.Output
+	}
+	dialect := r.dialect
+	r.mu.Unlock()
+
 	// We dedicate a goroutine to read from stdin, so we use blocking mode.
 	// Blocking mode is also simpler and more predictable.
-	robotOption(rOptionUseNonBlocking, 0)
+	r.robotOption(rOptionUseNonBlocking, 0)
 
-	robotOption(rOptionSendRotationReached, settings.SendRotationReached)
+	r.robotOption(rOptionSendRotationReached, settings.SendRotationReached)
 
-	stdin := stdinReader()
-	msgs := make(chan any, settings.ChanBufferCapacity)
+	stdin := r.stdinReader(settings.MaxLineLength, settings.LongLines)
+	msgs := make(chan Message, settings.ChanBufferCapacity)
 	go func() {
 		defer close(msgs)
 
+		// The watchdog timer is stopped while the server is stalled,
+		// so the stall is reported only once.
+		var (
+			watchdog *time.Timer
+			stalled  <-chan time.Time
+			last     = time.Now()
+		)
+		if settings.StallTimeout > 0 {
+			watchdog = time.NewTimer(settings.StallTimeout)
+			defer watchdog.Stop()
+			stalled = watchdog.C
+		}
+
 		for {
-			line, ok := <-stdin
+			var (
+				line string
+				ok   bool
+			)
+			select {
+			case line, ok = <-stdin:
+			case <-stalled:
+				elapsed := time.Since(last)
+				r.Logger().Warn("server stalled", "elapsed", elapsed)
+				r.push(msgs, MessageServerStalled{Elapsed: elapsed.Seconds()}, settings.Overflow)
+				continue
+			}
 			if !ok {
-				dbgf("stdin channel is closed")
+				r.Logger().Debug("stdin channel is closed")
 				return
 			}
-			msg, err := parseMessage(line)
+			if watchdog != nil {
+				last = time.Now()
+				if !watchdog.Stop() {
+					select {
+					case <-watchdog.C:
+					default:
+					}
+				}
+				watchdog.Reset(settings.StallTimeout)
+			}
+			line, ok = dialect.message(line)
+			if !ok {
+				r.Logger().Debug("message not accepted by the dialect", "dialect", dialect.Name)
+				continue
+			}
+			msg, err := parseMessage(line, settings.Pool)
 			if err != nil {
-				dbgf("error parsing message")
+				r.mu.Lock()
+				r.parseErrors++
+				r.mu.Unlock()
+
+				r.Logger().Debug("could not parse message", "line", line, "err", err)
 				continue
 			}
-			msgs <- msg
+			r.push(msgs, msg, settings.Overflow)
 		}
 	}()
 
 	return msgs
 }
 
+// push sends msg to msgs applying the given overflow policy. ExitRobot
+// messages are never dropped, so strategies always know when to exit.
+func (r *Robot) push(msgs chan Message, msg Message, policy OverflowPolicy) {
+	switch msg.(type) {
+	case MessageExitRobot, *MessageExitRobot:
+		msgs <- msg
+		return
+	}
+	if policy == OverflowBlock {
+		msgs <- msg
+		return
+	}
+
+	for {
+		select {
+		case msgs <- msg:
+			return
+		default:
+		}
+
+		if policy == OverflowDropNewest || cap(msgs) == 0 {
+			r.drop(msg)
+			return
+		}
+
+		// The consumer could take the oldest message before it is
+		// discarded, so the send is retried.
+		select {
+		case old := <-msgs:
+			r.drop(old)
+		default:
+		}
+	}
+}
+
+// drop records that msg has been dropped.
+func (r *Robot) drop(msg Message) {
+	r.mu.Lock()
+	r.dropped++
+	r.mu.Unlock()
+
+	r.Logger().Debug("message dropped", MessageAttr(msg))
+	Release(msg)
+}
+
+// Dropped returns the number of messages received by Listen that have been
+// dropped due to the overflow policy.
+func (r *Robot) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.dropped
+}
+
+// Dropped calls Dropped on the default Robot.
+func Dropped() int {
+	return std.Dropped()
+}
+
+// ParseErrors returns the number of lines received by Listen that could not
+// be parsed and were discarded.
+func (r *Robot) ParseErrors() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.parseErrors
+}
+
+// ParseErrors calls ParseErrors on the default Robot.
+func ParseErrors() int {
+	return std.ParseErrors()
+}
+
+// Listen calls Listen on the default Robot.
+func Listen(settings ListenSettings) <-chan Message {
+	return std.Listen(settings)
+}
+
 // stdinReader reads lines from standard input. It returns a channel on which
-// the lines are delivered.
-func stdinReader() <-chan string {
+// the lines are delivered. Lines longer than max are handled according to
+// policy. If max is zero, maxMessageLength is used.
+func (r *Robot) stdinReader(max int, policy LongLinePolicy) <-chan string {
+	if max <= 0 {
+		max = maxMessageLength
+	}
+
+	r.mu.Lock()
+	in := r.reader()
+	r.mu.Unlock()
+
 	c := make(chan string)
 
 	go func() {
 		defer close(c)
 
-		s := bufio.NewScanner(osStdin)
-		for s.Scan() {
-			c <- s.Text()
-		}
-		if err := s.Err(); err != nil {
-			dbgf("error reading from stdin")
-			return
+		// The buffer has room for the line terminator, so lines of
+		// max length are not reported as oversized.
+		br := bufio.NewReaderSize(in, max+2)
+		for {
+			line, err := br.ReadSlice('\n')
+			if errors.Is(err, bufio.ErrBufferFull) {
+				r.longLine(c, line, max, policy)
+				// Discard the rest of the line.
+				for errors.Is(err, bufio.ErrBufferFull) {
+					_, err = br.ReadSlice('\n')
+				}
+			} else if len(line) > 0 {
+				line = dropCR(bytes.TrimSuffix(line, []byte("\n")))
+				if len(line) > max {
+					r.longLine(c, line, max, policy)
+				} else {
+					c <- string(line)
+				}
+			}
+
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				r.Logger().Error("could not read from stdin", "err", err)
+				return
+			}
 		}
 	}()
 
 	return c
 }
 
-// parsers maps a message type to the corresponding parser.
-var parsers = map[string]func([]string) (any, error){
-	"Initialize":      parseInitialize,
-	"YourName":        parseYourName,
-	"YourColour":      parseYourColour,
-	"GameOption":      parseGameOption,
-	"GameStarts":      parseGameStarts,
-	"Radar":           parseRadar,
-	"Info":            parseInfo,
-	"Coordinates":     parseCoordinates,
-	"RobotInfo":       parseRobotInfo,
-	"RotationReached": parseRotationReached,
-	"Energy":          parseEnergy,
-	"RobotsLeft":      parseRobotsLeft,
-	"Collision":       parseCollision,
-	"Warning":         parseWarning,
-	"Dead":            parseDead,
-	"GameFinishes":    parseGameFinishes,
-	"ExitRobot":       parseExitRobot,
-}
-
-// parseMessage parses a message string.
-func parseMessage(s string) (msg any, err error) {
+// longLine handles a line longer than max according to policy. line can be
+// the beginning of the line.
+func (r *Robot) longLine(c chan<- string, line []byte, max int, policy LongLinePolicy) {
+	if policy == LongLineTruncate {
+		c <- string(line[:max])
+		return
+	}
+
+	r.Logger().Warn("line too long", "max", max, "prefix", string(line[:max]))
+	r.emit(fmt.Errorf("%w (max %v)", ErrLineTooLong, max))
+}
+
+// dropCR drops a terminal \r from data, like bufio.ScanLines does.
+func dropCR(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == '\r' {
+		return data[:len(data)-1]
+	}
+	return data
+}
+
+// Strategy implements the logic of a robot.
+type Strategy interface {
+	// Handle is called for every message received from the server.
+	// Commands must be sent through r.
+	Handle(r *Robot, msg Message)
+}
+
+// StrategyFunc is an adapter to allow the use of ordinary functions as
+// strategies.
+type StrategyFunc func(r *Robot, msg Message)
+
+// Handle calls f(r, msg).
+func (f StrategyFunc) Handle(r *Robot, msg Message) {
+	f(r, msg)
+}
+
+// Run listens to RTB messages and passes them to s. It returns after passing
+// a MessageExitRobot to s or when there are no more messages.
+func (r *Robot) Run(settings ListenSettings, s Strategy) {
+	for msg := range r.Listen(settings) {
+		r.Deliver(s, msg)
+		exit := false
+		switch msg.(type) {
+		case MessageExitRobot, *MessageExitRobot:
+			exit = true
+		}
+		if settings.Pool {
+			Release(msg)
+		}
+		if exit {
+			return
+		}
+	}
+}
+
+// Run calls Run on the default Robot.
+func Run(settings ListenSettings, s Strategy) {
+	std.Run(settings, s)
+}
+
+// maxMessageLength is the maximum length of a message accepted by
+// ParseMessage. Messages sent by the server are much shorter, so longer
+// messages are considered malformed.
+const maxMessageLength = 1024
+
+// ParseMessage parses a message sent by the RTB server. It never panics, even
+// with malformed or adversarial input. Errors wrap ErrMessageTooLong,
+// ErrUnknownMessage or ErrBadFieldCount, or are an ErrBadField.
+func ParseMessage(s string) (msg Message, err error) {
+	return parseMessage(s, false)
+}
+
+// parseMessage parses a message sent by the RTB server. If pooled is true,
+// the returned message is a pointer taken from the message pools.
+func parseMessage(s string, pooled bool) (msg Message, err error) {
+	if len(s) > maxMessageLength {
+		return nil, fmt.Errorf("%w (%v)", ErrMessageTooLong, len(s))
+	}
+
 	s = strings.TrimSpace(s)
 
 	if s == "" {
-		return nil, errors.New("empty string")
+		return nil, fmt.Errorf("%w: empty string", ErrUnknownMessage)
+	}
+
+	// The fields are stored in an array on the stack, so parsing does
+	// not allocate but for the returned message.
+	var buf [maxFields]string
+	fields := splitFields(s, buf[:0])
+
+	// A switch is used instead of a map of parsers, because calling a
+	// function value would make the fields escape to the heap.
+	switch fields[0] {
+	case "Initialize":
+		m, err := parseInitialize(fields)
+		return emit(m, err, pooled, &poolInitialize)
+	case "YourName":
+		m, err := parseYourName(fields)
+		return emit(m, err, pooled, &poolYourName)
+	case "YourColour":
+		m, err := parseYourColour(fields)
+		return emit(m, err, pooled, &poolYourColour)
+	case "GameOption":
+		m, err := parseGameOption(fields)
+		return emit(m, err, pooled, &poolGameOption)
+	case "GameStarts":
+		m, err := parseGameStarts(fields)
+		return emit(m, err, pooled, &poolGameStarts)
+	case "Radar":
+		m, err := parseRadar(fields)
+		return emit(m, err, pooled, &poolRadar)
+	case "Info":
+		m, err := parseInfo(fields)
+		return emit(m, err, pooled, &poolInfo)
+	case "Coordinates":
+		m, err := parseCoordinates(fields)
+		return emit(m, err, pooled, &poolCoordinates)
+	case "RobotInfo":
+		m, err := parseRobotInfo(fields)
+		return emit(m, err, pooled, &poolRobotInfo)
+	case "RotationReached":
+		m, err := parseRotationReached(fields)
+		return emit(m, err, pooled, &poolRotationReached)
+	case "Energy":
+		m, err := parseEnergy(fields)
+		return emit(m, err, pooled, &poolEnergy)
+	case "RobotsLeft":
+		m, err := parseRobotsLeft(fields)
+		return emit(m, err, pooled, &poolRobotsLeft)
+	case "Collision":
+		m, err := parseCollision(fields)
+		return emit(m, err, pooled, &poolCollision)
+	case "Warning":
+		m, err := parseWarning(fields)
+		return emit(m, err, pooled, &poolWarning)
+	case "Dead":
+		m, err := parseDead(fields)
+		return emit(m, err, pooled, &poolDead)
+	case "GameFinishes":
+		m, err := parseGameFinishes(fields)
+		return emit(m, err, pooled, &poolGameFinishes)
+	case "ExitRobot":
+		m, err := parseExitRobot(fields)
+		return emit(m, err, pooled, &poolExitRobot)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownMessage, fields[0])
+}
+
+// maxFields is the number of fields that splitFields can store without
+// allocating. No message sent by the server has more fields, except names
+// and warnings with many words.
+const maxFields = 8
+
+// splitFields splits s around runs of white space, like strings.Fields, and
+// appends the fields to buf. It only allocates if s has more fields than the
+// capacity of buf or contains non-ASCII characters, in which case it falls
+// back to strings.Fields.
+func splitFields(s string, buf []string) []string {
+	fields := buf
+	start := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			return strings.Fields(s)
+		}
+		if asciiSpace[c] {
+			if start >= 0 {
+				if len(fields) == cap(fields) {
+					return strings.Fields(s)
+				}
+				fields = append(fields, s[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		if len(fields) == cap(fields) {
+			return strings.Fields(s)
+		}
+		fields = append(fields, s[start:])
 	}
+	return fields
+}
 
-	fields := strings.Fields(s)
+// asciiSpace are the ASCII white space characters, as defined by
+// unicode.IsSpace.
+var asciiSpace = [utf8.RuneSelf]bool{'\t': true, '\n': true, '\v': true, '\f': true, '\r': true, ' ': true}
 
-	f, ok := parsers[fields[0]]
-	if !ok {
-		return nil, errors.New("unknown message")
+// parseFloat parses a finite float. Infinities and NaNs are rejected, so
+// strategies never have to deal with them.
+func parseFloat(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
 	}
-
-	return f(fields)
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, errors.New("non-finite value")
+	}
+	return v, nil
 }
 
-func parseInitialize(fields []string) (msg any, err error) {
+func parseInitialize(fields []string) (MessageInitialize, error) {
 	if len(fields) != 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageInitialize{}, ErrBadFieldCount
 	}
 
 	first, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse first %q: %v", fields[1], err)
+		return MessageInitialize{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	msg = MessageInitialize{
+	msg := MessageInitialize{
 		First: first == 1,
 	}
 
 	return msg, nil
 }
 
-func parseYourName(fields []string) (msg any, err error) {
+func parseYourName(fields []string) (MessageYourName, error) {
 	if len(fields) < 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageYourName{}, ErrBadFieldCount
 	}
 
-	msg = MessageYourName{
+	msg := MessageYourName{
 		Name: strings.Join(fields[1:], " "),
 	}
 
 	return msg, nil
 }
 
-func parseYourColour(fields []string) (msg any, err error) {
+func parseYourColour(fields []string) (MessageYourColour, error) {
 	if len(fields) != 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageYourColour{}, ErrBadFieldCount
 	}
 
-	msg = MessageYourColour{
+	msg := MessageYourColour{
 		Colour: fields[1],
 	}
 
 	return msg, nil
 }
 
-func parseGameOption(fields []string) (msg any, err error) {
+func parseGameOption(fields []string) (MessageGameOption, error) {
 	if len(fields) != 3 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageGameOption{}, ErrBadFieldCount
 	}
 
 	option, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse option %q: %v", fields[1], err)
+		return MessageGameOption{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	value, err := strconv.ParseFloat(fields[2], 64)
+	value, err := parseFloat(fields[2])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse value %q: %v", fields[2], err)
+		return MessageGameOption{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
-	msg = MessageGameOption{
+	msg := MessageGameOption{
 		Option: GOption(option),
 		Value:  value,
 	}
@@ -725,35 +1512,35 @@ func parseGameOption(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseGameStarts(fields []string) (msg any, err error) {
+func parseGameStarts(fields []string) (MessageGameStarts, error) {
 	if len(fields) != 1 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageGameStarts{}, ErrBadFieldCount
 	}
 
 	return MessageGameStarts{}, nil
 }
 
-func parseRadar(fields []string) (msg any, err error) {
+func parseRadar(fields []string) (MessageRadar, error) {
 	if len(fields) != 4 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageRadar{}, ErrBadFieldCount
 	}
 
-	distance, err := strconv.ParseFloat(fields[1], 64)
+	distance, err := parseFloat(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse distance %q: %v", fields[1], err)
+		return MessageRadar{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
 	object, err := strconv.ParseInt(fields[2], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse object type %q: %v", fields[2], err)
+		return MessageRadar{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
-	radarAngle, err := strconv.ParseFloat(fields[3], 64)
+	radarAngle, err := parseFloat(fields[3])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse angle %q: %v", fields[3], err)
+		return MessageRadar{}, ErrBadField{Index: 3, Value: fields[3], Err: err}
 	}
 
-	msg = MessageRadar{
+	msg := MessageRadar{
 		Distance:   distance,
 		Object:     Object(object),
 		RadarAngle: radarAngle,
@@ -762,27 +1549,27 @@ func parseRadar(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseInfo(fields []string) (msg any, err error) {
+func parseInfo(fields []string) (MessageInfo, error) {
 	if len(fields) != 4 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageInfo{}, ErrBadFieldCount
 	}
 
-	time, err := strconv.ParseFloat(fields[1], 64)
+	time, err := parseFloat(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse time %q: %v", fields[1], err)
+		return MessageInfo{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	speed, err := strconv.ParseFloat(fields[2], 64)
+	speed, err := parseFloat(fields[2])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse speed %q: %v", fields[2], err)
+		return MessageInfo{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
-	cannonAngle, err := strconv.ParseFloat(fields[3], 64)
+	cannonAngle, err := parseFloat(fields[3])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse cannon angle %q: %v", fields[3], err)
+		return MessageInfo{}, ErrBadField{Index: 3, Value: fields[3], Err: err}
 	}
 
-	msg = MessageInfo{
+	msg := MessageInfo{
 		Time:        time,
 		Speed:       speed,
 		CannonAngle: cannonAngle,
@@ -791,27 +1578,27 @@ func parseInfo(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseCoordinates(fields []string) (msg any, err error) {
+func parseCoordinates(fields []string) (MessageCoordinates, error) {
 	if len(fields) != 4 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageCoordinates{}, ErrBadFieldCount
 	}
 
-	x, err := strconv.ParseFloat(fields[1], 64)
+	x, err := parseFloat(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse x %q: %v", fields[1], err)
+		return MessageCoordinates{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	y, err := strconv.ParseFloat(fields[2], 64)
+	y, err := parseFloat(fields[2])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse y %q: %v", fields[2], err)
+		return MessageCoordinates{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
-	angle, err := strconv.ParseFloat(fields[3], 64)
+	angle, err := parseFloat(fields[3])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse angle %q: %v", fields[3], err)
+		return MessageCoordinates{}, ErrBadField{Index: 3, Value: fields[3], Err: err}
 	}
 
-	msg = MessageCoordinates{
+	msg := MessageCoordinates{
 		X:     x,
 		Y:     y,
 		Angle: angle,
@@ -820,26 +1607,26 @@ func parseCoordinates(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseRobotInfo(fields []string) (msg any, err error) {
+func parseRobotInfo(fields []string) (MessageRobotInfo, error) {
 	if len(fields) != 3 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageRobotInfo{}, ErrBadFieldCount
 	}
 
-	energyLevel, err := strconv.ParseFloat(fields[1], 64)
+	energyLevel, err := parseFloat(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse energy level %q: %v", fields[1], err)
+		return MessageRobotInfo{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
 	teamMate, err := strconv.ParseInt(fields[2], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse teammate %q: %v", fields[2], err)
+		return MessageRobotInfo{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
 	if teamMate != 0 && teamMate != 1 {
-		return nil, fmt.Errorf("unknown teammate value %v: %v", teamMate, err)
+		return MessageRobotInfo{}, ErrBadField{Index: 2, Value: fields[2], Err: errors.New("unknown teammate value")}
 	}
 
-	msg = MessageRobotInfo{
+	msg := MessageRobotInfo{
 		EnergyLevel: energyLevel,
 		TeamMate:    teamMate == 1,
 	}
@@ -847,73 +1634,73 @@ func parseRobotInfo(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseRotationReached(fields []string) (msg any, err error) {
+func parseRotationReached(fields []string) (MessageRotationReached, error) {
 	if len(fields) != 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageRotationReached{}, ErrBadFieldCount
 	}
 
 	part, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse robot part %q: %v", fields[1], err)
+		return MessageRotationReached{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	msg = MessageRotationReached{
+	msg := MessageRotationReached{
 		Part: Part(part),
 	}
 
 	return msg, nil
 }
 
-func parseEnergy(fields []string) (msg any, err error) {
+func parseEnergy(fields []string) (MessageEnergy, error) {
 	if len(fields) != 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageEnergy{}, ErrBadFieldCount
 	}
 
-	energyLevel, err := strconv.ParseFloat(fields[1], 64)
+	energyLevel, err := parseFloat(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse energy level %q: %v", fields[1], err)
+		return MessageEnergy{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	msg = MessageEnergy{
+	msg := MessageEnergy{
 		EnergyLevel: energyLevel,
 	}
 
 	return msg, nil
 }
 
-func parseRobotsLeft(fields []string) (msg any, err error) {
+func parseRobotsLeft(fields []string) (MessageRobotsLeft, error) {
 	if len(fields) != 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageRobotsLeft{}, ErrBadFieldCount
 	}
 
 	numRobots, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse number of robots %q: %v", fields[1], err)
+		return MessageRobotsLeft{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	msg = MessageRobotsLeft{
+	msg := MessageRobotsLeft{
 		NumRobots: int(numRobots),
 	}
 
 	return msg, nil
 }
 
-func parseCollision(fields []string) (msg any, err error) {
+func parseCollision(fields []string) (MessageCollision, error) {
 	if len(fields) != 3 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageCollision{}, ErrBadFieldCount
 	}
 
 	object, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse object type %q: %v", fields[1], err)
+		return MessageCollision{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
-	angle, err := strconv.ParseFloat(fields[2], 64)
+	angle, err := parseFloat(fields[2])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse angle %q: %v", fields[2], err)
+		return MessageCollision{}, ErrBadField{Index: 2, Value: fields[2], Err: err}
 	}
 
-	msg = MessageCollision{
+	msg := MessageCollision{
 		Object: Object(object),
 		Angle:  angle,
 	}
@@ -921,14 +1708,14 @@ func parseCollision(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseWarning(fields []string) (msg any, err error) {
+func parseWarning(fields []string) (MessageWarning, error) {
 	if len(fields) < 2 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageWarning{}, ErrBadFieldCount
 	}
 
 	warning, err := strconv.ParseInt(fields[1], 10, 0)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse warning type %q: %v", fields[1], err)
+		return MessageWarning{}, ErrBadField{Index: 1, Value: fields[1], Err: err}
 	}
 
 	warnMsg := ""
@@ -936,7 +1723,7 @@ func parseWarning(fields []string) (msg any, err error) {
 		warnMsg = strings.Join(fields[2:], " ")
 	}
 
-	msg = MessageWarning{
+	msg := MessageWarning{
 		Warning: Warning(warning),
 		Message: warnMsg,
 	}
@@ -944,37 +1731,97 @@ func parseWarning(fields []string) (msg any, err error) {
 	return msg, nil
 }
 
-func parseDead(fields []string) (msg any, err error) {
+func parseDead(fields []string) (MessageDead, error) {
 	if len(fields) != 1 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageDead{}, ErrBadFieldCount
 	}
 
 	return MessageDead{}, nil
 }
 
-func parseGameFinishes(fields []string) (msg any, err error) {
+func parseGameFinishes(fields []string) (MessageGameFinishes, error) {
 	if len(fields) != 1 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageGameFinishes{}, ErrBadFieldCount
 	}
 
 	return MessageGameFinishes{}, nil
 }
 
-func parseExitRobot(fields []string) (msg any, err error) {
+func parseExitRobot(fields []string) (MessageExitRobot, error) {
 	if len(fields) != 1 {
-		return nil, errors.New("wrong number of arguments")
+		return MessageExitRobot{}, ErrBadFieldCount
 	}
 
 	return MessageExitRobot{}, nil
 }
 
-// Debug allows to enable debug messages.
-var Debug = false
+// EncodeMessage returns the string representation of msg used by the RTB
+// server. It is the inverse of ParseMessage, so simulators and mock servers
+// can use it to talk to robots. MessageServerStalled, which is not part of
+// the protocol, is encoded as "ServerStalled elapsed" so it can be logged,
+// but ParseMessage does not accept it.
+func EncodeMessage(msg Message) (string, error) {
+	switch m := CopyMessage(msg).(type) {
+	case MessageInitialize:
+		return fmt.Sprintf("Initialize %v", encodeBool(m.First)), nil
+	case MessageYourName:
+		return fmt.Sprintf("YourName %v", m.Name), nil
+	case MessageYourColour:
+		return fmt.Sprintf("YourColour %v", m.Colour), nil
+	case MessageGameOption:
+		return fmt.Sprintf("GameOption %d %v", m.Option, encodeFloat(m.Value)), nil
+	case MessageGameStarts:
+		return "GameStarts", nil
+	case MessageRadar:
+		return fmt.Sprintf("Radar %v %d %v", encodeFloat(m.Distance), m.Object, encodeFloat(m.RadarAngle)), nil
+	case MessageInfo:
+		return fmt.Sprintf("Info %v %v %v", encodeFloat(m.Time), encodeFloat(m.Speed), encodeFloat(m.CannonAngle)), nil
+	case MessageCoordinates:
+		return fmt.Sprintf("Coordinates %v %v %v", encodeFloat(m.X), encodeFloat(m.Y), encodeFloat(m.Angle)), nil
+	case MessageRobotInfo:
+		return fmt.Sprintf("RobotInfo %v %v", encodeFloat(m.EnergyLevel), encodeBool(m.TeamMate)), nil
+	case MessageRotationReached:
+		return fmt.Sprintf("RotationReached %d", m.Part), nil
+	case MessageEnergy:
+		return fmt.Sprintf("Energy %v", encodeFloat(m.EnergyLevel)), nil
+	case MessageRobotsLeft:
+		return fmt.Sprintf("RobotsLeft %d", m.NumRobots), nil
+	case MessageCollision:
+		return fmt.Sprintf("Collision %d %v", m.Object, encodeFloat(m.Angle)), nil
+	case MessageWarning:
+		if m.Message == "" {
+			return fmt.Sprintf("Warning %d", m.Warning), nil
+		}
+		return fmt.Sprintf("Warning %d %v", m.Warning, m.Message), nil
+	case MessageDead:
+		return "Dead", nil
+	case MessageGameFinishes:
+		return "GameFinishes", nil
+	case MessageExitRobot:
+		return "ExitRobot", nil
+	case MessageServerStalled:
+		return fmt.Sprintf("ServerStalled %v", encodeFloat(m.Elapsed)), nil
+	default:
+		return "", fmt.Errorf("%w type %T", ErrUnknownMessage, msg)
+	}
+}
+
+// encodeFloat formats v using the minimum number of digits necessary to
+// represent it exactly.
+func encodeFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
 
-// dbg sends a debug message if Debug is true.
-func dbgf(format string, a ...any) error {
-	if !Debug {
-		return nil
+// encodeBool formats b as 1 (true) or 0 (false).
+func encodeBool(b bool) string {
+	if b {
+		return "1"
 	}
-	return Debugf(format, a...)
+	return "0"
 }
+
+// Debug allows to enable debug messages. If it is true, the robots without a
+// logger send their logs to the message window of the server.
+//
+// Deprecated: Use SetLogger with a WindowHandler instead.
+var Debug = false
@@ -2,10 +2,21 @@ package rtb
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
+	"math"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/quick"
+	"time"
+	"unicode/utf8"
 )
 
 func TestParseMessage(t *testing.T) {
@@ -226,12 +237,44 @@ func TestParseMessage(t *testing.T) {
 			MessageExitRobot{},
 			true,
 		},
+
+		// Malformed
+		{
+			"Empty",
+			"  ",
+			nil,
+			false,
+		},
+		{
+			"Unknown",
+			"Foo 1",
+			nil,
+			false,
+		},
+		{
+			"NaN",
+			"Radar NaN 3 4.5",
+			nil,
+			false,
+		},
+		{
+			"Inf",
+			"Info 1.2 +Inf 5.6",
+			nil,
+			false,
+		},
+		{
+			"Too long",
+			"YourName " + strings.Repeat("x", maxMessageLength),
+			nil,
+			false,
+		},
 	}
 
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			msg, err := parseMessage(tt.line)
+			msg, err := ParseMessage(tt.line)
 			if (err == nil) != tt.nilErr {
 				t.Errorf("unexpected error: got=%v", err)
 			}
@@ -242,6 +285,119 @@ func TestParseMessage(t *testing.T) {
 	}
 }
 
+func FuzzParseMessage(f *testing.F) {
+	seeds := []string{
+		"Initialize 1",
+		"YourName foo bar",
+		"YourColour 11aa22",
+		"GameOption 8 1.234",
+		"GameStarts",
+		"Radar 1.2 3 4.5",
+		"Info 1.2 3.4 5.6",
+		"Coordinates 1.2 3.4 5.6",
+		"RobotInfo 1.2 1",
+		"RotationReached 3",
+		"Energy 1.2",
+		"RobotsLeft 123",
+		"Collision 2 3.4",
+		"Warning 2 foo bar",
+		"Dead",
+		"GameFinishes",
+		"ExitRobot",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		msg, err := ParseMessage(s)
+		if (err == nil) != (msg != nil) {
+			t.Errorf("inconsistent result: msg=%#v err=%v", msg, err)
+		}
+	})
+}
+
+// randomWords returns a string of between min and max random words separated
+// by a single space.
+func randomWords(rnd *rand.Rand, min, max int) string {
+	words := make([]string, min+rnd.Intn(max-min+1))
+	for i := range words {
+		b := make([]byte, 1+rnd.Intn(8))
+		for j := range b {
+			b[j] = byte('a' + rnd.Intn(26))
+		}
+		words[i] = string(b)
+	}
+	return strings.Join(words, " ")
+}
+
+// randomMessage returns a random message of a random type.
+func randomMessage(rnd *rand.Rand) Message {
+	f := func() float64 { return rnd.NormFloat64() * 100 }
+
+	msgs := []Message{
+		MessageInitialize{First: rnd.Intn(2) == 1},
+		MessageYourName{Name: randomWords(rnd, 1, 3)},
+		MessageYourColour{Colour: "11aa22"},
+		MessageGameOption{Option: GOption(rnd.Intn(15)), Value: f()},
+		MessageGameStarts{},
+		MessageRadar{Distance: f(), Object: Object(rnd.Intn(6) - 1), RadarAngle: f()},
+		MessageInfo{Time: f(), Speed: f(), CannonAngle: f()},
+		MessageCoordinates{X: f(), Y: f(), Angle: f()},
+		MessageRobotInfo{EnergyLevel: f(), TeamMate: rnd.Intn(2) == 1},
+		MessageRotationReached{Part: Part(rnd.Intn(8))},
+		MessageEnergy{EnergyLevel: f()},
+		MessageRobotsLeft{NumRobots: rnd.Intn(100)},
+		MessageCollision{Object: Object(rnd.Intn(6) - 1), Angle: f()},
+		MessageWarning{Warning: Warning(rnd.Intn(7)), Message: randomWords(rnd, 0, 3)},
+		MessageDead{},
+		MessageGameFinishes{},
+		MessageExitRobot{},
+	}
+	return msgs[rnd.Intn(len(msgs))]
+}
+
+func TestEncodeMessageRoundTrip(t *testing.T) {
+	f := func(seed int64) bool {
+		rnd := rand.New(rand.NewSource(seed))
+		msg := randomMessage(rnd)
+
+		s, err := EncodeMessage(msg)
+		if err != nil {
+			t.Logf("encode error: %v", err)
+			return false
+		}
+		got, err := ParseMessage(s)
+		if err != nil {
+			t.Logf("parse error: %v", err)
+			return false
+		}
+		if got != msg {
+			t.Logf("round trip mismatch: got=%#v want=%#v", got, msg)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	if _, err := EncodeMessage(nil); err == nil {
+		t.Errorf("expected error encoding nil message")
+	}
+
+	got, err := EncodeMessage(MessageRadar{Distance: 1.5, Object: ObjectCookie, RadarAngle: -0.25})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Radar 1.5 3 -0.25"; got != want {
+		t.Errorf("unexpected string: got=%q want=%q", got, want)
+	}
+}
+
 func TestListen(t *testing.T) {
 	osStdin = bytes.NewBufferString(`
 		GameStarts
@@ -505,3 +661,1646 @@ func TestColourValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestRun(t *testing.T) {
+	in := bytes.NewBufferString(`
+		GameStarts
+		ExitRobot
+		Dead
+	`)
+	var out bytes.Buffer
+	r := NewRobot(in, &out)
+
+	var got []any
+	r.Run(ListenSettings{}, StrategyFunc(func(r *Robot, msg Message) {
+		got = append(got, msg)
+		r.Shoot(1)
+	}))
+
+	want := []any{MessageGameStarts{}, MessageExitRobot{}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected messages: got=%#v want=%#v", got, want)
+	}
+
+	wantOut := "RobotOption 3 0\nRobotOption 1 0\nShoot 1.000000\nShoot 1.000000\n"
+	if out.String() != wantOut {
+		t.Errorf("unexpected output: got=%q want=%q", out.String(), wantOut)
+	}
+}
+
+// recordObserver is an Observer that records the observed traffic.
+type recordObserver struct {
+	msgs []Message
+	cmds []string
+}
+
+func (o *recordObserver) Message(msg Message) { o.msgs = append(o.msgs, msg) }
+func (o *recordObserver) Command(cmd string)  { o.cmds = append(o.cmds, cmd) }
+
+func TestObserver(t *testing.T) {
+	r := NewRobot(nil, io.Discard)
+	o := &recordObserver{}
+	r.AddObserver(o)
+
+	r.Deliver(StrategyFunc(func(r *Robot, msg Message) { r.Brake(1) }), MessageDead{})
+
+	if len(o.msgs) != 1 || o.msgs[0] != (MessageDead{}) {
+		t.Errorf("unexpected messages: %#v", o.msgs)
+	}
+	if len(o.cmds) != 1 || o.cmds[0] != "Brake 1.000000" {
+		t.Errorf("unexpected commands: %#v", o.cmds)
+	}
+}
+
+func TestWindowHandler(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRobot(nil, &out)
+	l := slog.New(NewWindowHandler(r, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r.SetLogger(l)
+
+	r.Deliver(StrategyFunc(func(r *Robot, msg Message) {
+		r.Logger().Info("firing", "energy", 2)
+	}), MessageDead{})
+	l.WithGroup("g").Debug("tracking", "id", 1)
+	l.Warn(strings.Repeat("x", 200))
+	l.Warn("x" + strings.Repeat("ñ", 100))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{
+		"Print level=INFO msg=firing message=Dead energy=2",
+		"Debug level=DEBUG msg=tracking g.id=1",
+	}
+	if len(lines) != 4 {
+		t.Fatalf("wrong number of lines: got=%v want=%v", len(lines), 4)
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("unexpected line: got=%q want=%q", lines[i], w)
+		}
+	}
+	if n := len(lines[2]) + 1; n != 128 || !strings.HasPrefix(lines[2], "Print level=WARN msg=xxx") {
+		t.Errorf("unexpected truncated line (%v): %q", n, lines[2])
+	}
+	if !utf8.ValidString(lines[3]) || len(lines[3])+1 > 128 {
+		t.Errorf("unexpected truncated line: %q", lines[3])
+	}
+}
+
+func TestFileHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robot.log")
+	h, err := NewFileHandler(path, &slog.HandlerOptions{Level: slog.LevelDebug})
+	if err != nil {
+		t.Fatalf("could not create handler: %v", err)
+	}
+
+	r := NewRobot(nil, io.Discard)
+	r.AddObserver(NewLogObserver(slog.New(h)))
+	r.Deliver(StrategyFunc(func(r *Robot, msg Message) { r.Shoot(1) }), MessageDead{})
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("could not close handler: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read log: %v", err)
+	}
+
+	var got []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("could not decode record: %v", err)
+		}
+		got = append(got, rec)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("wrong number of records: got=%v want=%v", len(got), 2)
+	}
+	if got[0]["message"] != "Dead" {
+		t.Errorf("unexpected message attribute: got=%v want=%v", got[0]["message"], "Dead")
+	}
+	if got[1]["command"] != "Shoot 1.000000" {
+		t.Errorf("unexpected command attribute: got=%v want=%v", got[1]["command"], "Shoot 1.000000")
+	}
+}
+
+func TestLoggerDiscard(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRobot(nil, &out)
+	r.Logger().Error("lost")
+
+	if out.Len() != 0 {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestCommander(t *testing.T) {
+	tests := []struct {
+		name  string
+		ticks []func(c *Commander)
+		want  string
+	}{
+		{
+			"Last wins",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Accelerate(1)
+					c.Rotate(PartRobot, 0.5)
+					c.Accelerate(0.5)
+					c.Rotate(PartRobot, 0.2)
+				},
+			},
+			"Rotate 1 0.200000\nAccelerate 0.500000\n",
+		},
+		{
+			"Merge parts",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Rotate(PartCannon, 1)
+					c.Rotate(PartRadar, 1)
+				},
+			},
+			"Rotate 6 1.000000\n",
+		},
+		{
+			"Split parts",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Rotate(PartCannon|PartRadar, 1)
+					c.Sweep(PartRadar, 1, -1, 1)
+				},
+			},
+			"Rotate 2 1.000000\nSweep 4 1.000000 -1.000000 1.000000\n",
+		},
+		{
+			"Deduplicate",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Rotate(PartRobot, 0.2)
+					c.Accelerate(1)
+					c.Brake(0)
+				},
+				func(c *Commander) {
+					c.Rotate(PartRobot, 0.2)
+					c.Accelerate(1)
+					c.Brake(1)
+					c.RotateAmount(PartCannon, 1, 1)
+				},
+				func(c *Commander) {
+					c.RotateAmount(PartCannon, 1, 1)
+				},
+			},
+			"Rotate 1 0.200000\nAccelerate 1.000000\nBrake 0.000000\n" +
+				"RotateAmount 2 1.000000 1.000000\nBrake 1.000000\n" +
+				"RotateAmount 2 1.000000 1.000000\n",
+		},
+		{
+			"Limits",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Rotate(PartRobot|PartRadar, 10)
+					c.Accelerate(-5)
+					c.Brake(2)
+					c.Shoot(100)
+				},
+			},
+			"Rotate 5 0.500000\nAccelerate -1.000000\nBrake 1.000000\nShoot 30.000000\n",
+		},
+		{
+			"Discard",
+			[]func(c *Commander){
+				func(c *Commander) {
+					c.Shoot(1)
+					c.Discard()
+				},
+			},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+			c := NewCommander(r)
+			r.AddObserver(c)
+
+			var tick int
+			s := c.Strategy(StrategyFunc(func(r *Robot, msg Message) {
+				if _, ok := msg.(MessageInfo); ok {
+					tt.ticks[tick](c)
+					tick++
+				}
+			}))
+
+			opts := []MessageGameOption{
+				{Option: GOptionRobotMaxRotate, Value: 0.5},
+				{Option: GOptionRobotRadarMaxRotate, Value: 2},
+				{Option: GOptionRobotMinAcceleration, Value: -1},
+				{Option: GOptionRobotMaxAcceleration, Value: 2},
+				{Option: GOptionShotMaxEnergy, Value: 30},
+			}
+			for _, opt := range opts {
+				r.Deliver(s, opt)
+			}
+			r.Deliver(s, MessageGameStarts{})
+			for i := range tt.ticks {
+				r.Deliver(s, MessageInfo{Time: float64(i)})
+			}
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected commands: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommanderRetry(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRobot(nil, &out)
+	c := NewCommander(r)
+	r.AddObserver(c)
+
+	r.SetDialect(Dialect{Name: "none", Commands: map[string]string{"Rotate": "", "Accelerate": "", "Brake": ""}})
+	c.Rotate(PartRadar, 1)
+	c.Accelerate(1)
+	c.Brake(0.5)
+	if err := c.Commit(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	r.SetDialect(Dialect{})
+	c.Rotate(PartRadar, 1)
+	c.Accelerate(1)
+	c.Brake(0.5)
+	if err := c.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Rotate 4 1.000000\nAccelerate 1.000000\nBrake 0.500000\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected commands: got=%q want=%q", got, want)
+	}
+}
+
+func TestCommandLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		send    func(r *Robot) error
+		want    string
+		wantErr error
+	}{
+		{
+			"Rotate",
+			func(r *Robot) error { return r.Rotate(PartRobot, -2) },
+			"Rotate 1 -1.000000\n",
+			ErrOutOfRange{Command: "Rotate", Value: -2, Clamped: -1, Sent: true},
+		},
+		{
+			"Rotate parts",
+			func(r *Robot) error { return r.Rotate(PartRobot|PartRadar, 1.5) },
+			"Rotate 5 1.000000\n",
+			ErrOutOfRange{Command: "Rotate", Value: 1.5, Clamped: 1, Sent: true},
+		},
+		{
+			"Sweep",
+			func(r *Robot) error { return r.Sweep(PartRadar, 1.5, -1, 1) },
+			"Sweep 4 1.500000 -1.000000 1.000000\n",
+			nil,
+		},
+		{
+			"Unknown limit",
+			func(r *Robot) error { return r.RotateTo(PartCannon, 10, 1) },
+			"RotateTo 2 10.000000 1.000000\n",
+			nil,
+		},
+		{
+			"Accelerate",
+			func(r *Robot) error { return r.Accelerate(-3) },
+			"Accelerate -0.500000\n",
+			ErrOutOfRange{Command: "Accelerate", Value: -3, Clamped: -0.5, Sent: true},
+		},
+		{
+			"Shoot max",
+			func(r *Robot) error { return r.Shoot(40) },
+			"Shoot 30.000000\n",
+			ErrOutOfRange{Command: "Shoot", Value: 40, Clamped: 30, Sent: true},
+		},
+		{
+			"Shoot min",
+			func(r *Robot) error { return r.Shoot(0.1) },
+			"",
+			ErrOutOfRange{Command: "Shoot", Value: 0.1, Clamped: 0.5},
+		},
+		{
+			"In range",
+			func(r *Robot) error { return r.Shoot(10) },
+			"Shoot 10.000000\n",
+			nil,
+		},
+		{
+			"Infinite rotation",
+			func(r *Robot) error { return r.RotateTo(PartCannon, math.Inf(1), 1) },
+			"",
+			ErrOutOfRange{Command: "RotateTo", Value: math.Inf(1)},
+		},
+		{
+			"Infinite acceleration",
+			func(r *Robot) error { return r.Accelerate(math.Inf(-1)) },
+			"",
+			ErrOutOfRange{Command: "Accelerate", Value: math.Inf(-1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+
+			opts := []MessageGameOption{
+				{Option: GOptionRobotMaxRotate, Value: 1},
+				{Option: GOptionRobotRadarMaxRotate, Value: 2},
+				{Option: GOptionRobotMinAcceleration, Value: -0.5},
+				{Option: GOptionRobotMaxAcceleration, Value: 2},
+				{Option: GOptionShotMinEnergy, Value: 0.5},
+				{Option: GOptionShotMaxEnergy, Value: 30},
+			}
+			nop := StrategyFunc(func(r *Robot, msg Message) {})
+			for _, opt := range opts {
+				r.Deliver(nop, opt)
+			}
+
+			err := tt.send(r)
+			if err != tt.wantErr {
+				t.Errorf("unexpected error: got=%v want=%v", err, tt.wantErr)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected output: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandLimitsNaN(t *testing.T) {
+	sends := map[string]func(r *Robot) error{
+		"Rotate":     func(r *Robot) error { return r.Rotate(PartRobot, math.NaN()) },
+		"Accelerate": func(r *Robot) error { return r.Accelerate(math.NaN()) },
+		"Shoot":      func(r *Robot) error { return r.Shoot(math.NaN()) },
+	}
+	for name, send := range sends {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+
+			var errRange ErrOutOfRange
+			if err := send(r); !errors.As(err, &errRange) || errRange.Sent || errRange.Command != name {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if got := out.String(); got != "" {
+				t.Errorf("unexpected output: %q", got)
+			}
+		})
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want error
+	}{
+		{"Too long", strings.Repeat("x", 2000), ErrMessageTooLong},
+		{"Empty", "", ErrUnknownMessage},
+		{"Unknown", "Foo 1", ErrUnknownMessage},
+		{"Field count", "Radar 1 2", ErrBadFieldCount},
+		{"Bad field", "Radar 1 x 3", ErrBadField{Index: 2, Value: "x"}},
+		{"Bad teammate", "RobotInfo 1 2", ErrBadField{Index: 2, Value: "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMessage(tt.line)
+
+			if want, ok := tt.want.(ErrBadField); ok {
+				var got ErrBadField
+				if !errors.As(err, &got) {
+					t.Fatalf("unexpected error: got=%v want=%v", err, tt.want)
+				}
+				if got.Index != want.Index || got.Value != want.Value || got.Err == nil {
+					t.Errorf("unexpected field: got=%+v want=%+v", got, want)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error: got=%v want=%v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarningPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *WarningPolicy
+		warning   Warning
+		want      string
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			"Default no resend",
+			nil,
+			WarningNameNotGiven,
+			"",
+			false,
+			0,
+		},
+		{
+			"Resend name",
+			&WarningPolicy{Actions: map[Warning]WarningAction{WarningNameNotGiven: WarningResend}},
+			WarningNameNotGiven,
+			"Name foo\n",
+			false,
+			0,
+		},
+		{
+			"Resend colour",
+			&WarningPolicy{Actions: map[Warning]WarningAction{WarningColourNotGiven: WarningResend}},
+			WarningColourNotGiven,
+			"Colour 112233 445566\n",
+			false,
+			0,
+		},
+		{
+			"Default log",
+			nil,
+			WarningProcessTimeLow,
+			"",
+			false,
+			0,
+		},
+		{
+			"Emit",
+			&WarningPolicy{Actions: map[Warning]WarningAction{WarningProcessTimeLow: WarningEmit}},
+			WarningProcessTimeLow,
+			"",
+			true,
+			0,
+		},
+		{
+			"Callback",
+			&WarningPolicy{Default: WarningCallback},
+			WarningUnknownOption,
+			"",
+			false,
+			1,
+		},
+		{
+			"Ignore",
+			&WarningPolicy{},
+			WarningNameNotGiven,
+			"",
+			false,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+			r.Name("foo")
+			r.Colour("112233", "445566")
+			out.Reset()
+
+			var calls int
+			if tt.policy != nil {
+				p := *tt.policy
+				p.Callback = func(r *Robot, msg MessageWarning) { calls++ }
+				r.SetWarningPolicy(p)
+			}
+			errs := r.Errors()
+
+			nop := StrategyFunc(func(r *Robot, msg Message) {})
+			r.Deliver(nop, MessageWarning{Warning: tt.warning, Message: "test"})
+
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected output: got=%q want=%q", got, tt.want)
+			}
+			select {
+			case err := <-errs:
+				if !tt.wantErr {
+					t.Errorf("unexpected error: %v", err)
+				} else if want := (ErrWarning{tt.warning, "test"}); err != want {
+					t.Errorf("unexpected error: got=%v want=%v", err, want)
+				}
+			default:
+				if tt.wantErr {
+					t.Errorf("error not emitted")
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("unexpected number of calls: got=%v want=%v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRotateToAndWait(t *testing.T) {
+	nop := StrategyFunc(func(r *Robot, msg Message) {})
+
+	t.Run("Reached", func(t *testing.T) {
+		var out bytes.Buffer
+		r := NewRobot(nil, &out)
+
+		c := r.RotateToAndWait(context.Background(), PartCannon|PartRadar, 1, 2)
+		r.Deliver(nop, MessageRotationReached{Part: PartCannon})
+		select {
+		case err := <-c:
+			t.Fatalf("resolved before all parts reached: %v", err)
+		default:
+		}
+		r.Deliver(nop, MessageRotationReached{Part: PartRadar})
+		if err := <-c; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if got, want := out.String(), "RotateTo 6 1.000000 2.000000\n"; got != want {
+			t.Errorf("unexpected output: got=%q want=%q", got, want)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r := NewRobot(nil, io.Discard)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		if err := <-r.RotateToAndWait(ctx, PartCannon, 1, 2); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("unexpected error: got=%v want=%v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Superseded", func(t *testing.T) {
+		r := NewRobot(nil, io.Discard)
+
+		c1 := r.RotateAmountAndWait(context.Background(), PartRobot, 1, 2)
+		c2 := r.RotateAmountAndWait(context.Background(), PartRobot, 1, -2)
+		if err := <-c1; !errors.Is(err, ErrRotationSuperseded) {
+			t.Errorf("unexpected error: got=%v want=%v", err, ErrRotationSuperseded)
+		}
+		r.Deliver(nop, MessageRotationReached{Part: PartRobot})
+		if err := <-c2; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestGameClock(t *testing.T) {
+	r := NewRobot(nil, io.Discard)
+
+	msgs := []Message{
+		MessageRadar{Distance: 1},
+		MessageInfo{Time: 1.5},
+		MessageRadar{Distance: 2},
+		MessageGameFinishes{},
+		MessageGameStarts{},
+		MessageRadar{Distance: 3},
+		MessageInfo{Time: 0.5},
+	}
+	var got []Envelope
+	s := EnvelopeFunc(func(r *Robot, e Envelope) {
+		if cur, ok := r.Current(); !ok || cur != e {
+			t.Errorf("unexpected current envelope: got=%v, %v want=%v, %v", cur, ok, e, true)
+		}
+		got = append(got, e)
+	})
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+
+	want := []Envelope{
+		{0, MessageRadar{Distance: 1}},
+		{1.5, MessageInfo{Time: 1.5}},
+		{1.5, MessageRadar{Distance: 2}},
+		{1.5, MessageGameFinishes{}},
+		{0, MessageGameStarts{}},
+		{0, MessageRadar{Distance: 3}},
+		{0.5, MessageInfo{Time: 0.5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected envelopes: got=%v want=%v", got, want)
+	}
+	if got := r.GameClock(); got != 0.5 {
+		t.Errorf("unexpected game clock: got=%v want=%v", got, 0.5)
+	}
+	if _, ok := r.Current(); ok {
+		t.Errorf("unexpected current message after delivering")
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	r := NewRobot(nil, io.Discard)
+
+	var (
+		ticks  []Tick
+		others []Message
+	)
+	a := &Aggregator{
+		OnTick: func(r *Robot, t Tick) { ticks = append(ticks, t) },
+		Other:  StrategyFunc(func(r *Robot, msg Message) { others = append(others, msg) }),
+	}
+	msgs := []Message{
+		MessageGameStarts{},
+		MessageRadar{Distance: 5, Object: ObjectRobot},
+		MessageRobotInfo{EnergyLevel: 50},
+		MessageInfo{Time: 1},
+		MessageCoordinates{X: 1, Y: 2},
+		MessageEnergy{EnergyLevel: 90},
+		MessageCollision{Object: ObjectWall},
+		MessageInfo{Time: 2},
+		MessageInfo{Time: 3},
+		MessageDead{},
+	}
+	for _, msg := range msgs {
+		r.Deliver(a, msg)
+	}
+
+	if len(ticks) != 3 {
+		t.Fatalf("wrong number of ticks: got=%v want=%v", len(ticks), 3)
+	}
+	if tk := ticks[0]; tk.Time != 1 || tk.Radar == nil || tk.Radar.Distance != 5 ||
+		tk.RobotInfo == nil || tk.Coordinates == nil || tk.Energy == nil || len(tk.Events) != 0 {
+		t.Errorf("unexpected first tick: %+v", tk)
+	}
+	if tk := ticks[1]; tk.Time != 2 || tk.Radar != nil || tk.Energy != nil ||
+		!reflect.DeepEqual(tk.Events, []Message{MessageCollision{Object: ObjectWall}}) {
+		t.Errorf("unexpected second tick: %+v", tk)
+	}
+	if tk := ticks[2]; tk.Time != 3 || tk.Info == nil {
+		t.Errorf("unexpected third tick: %+v", tk)
+	}
+	if want := []Message{MessageGameStarts{}, MessageDead{}}; !reflect.DeepEqual(others, want) {
+		t.Errorf("unexpected other messages: got=%v want=%v", others, want)
+	}
+}
+
+func TestListenOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      OverflowPolicy
+		want        []Message
+		wantDropped int
+	}{
+		{
+			"Block",
+			OverflowBlock,
+			[]Message{MessageInfo{Time: 1}, MessageInfo{Time: 2}, MessageInfo{Time: 3}, MessageExitRobot{}},
+			0,
+		},
+		{
+			"Drop oldest",
+			OverflowDropOldest,
+			[]Message{MessageInfo{Time: 3}, MessageExitRobot{}},
+			2,
+		},
+		{
+			"Drop newest",
+			OverflowDropNewest,
+			[]Message{MessageInfo{Time: 1}, MessageExitRobot{}},
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := "Info 1 0 0\nInfo 2 0 0\nInfo 3 0 0\nExitRobot\n"
+			pr, pw := io.Pipe()
+			r := NewRobot(pr, io.Discard)
+			msgs := r.Listen(ListenSettings{ChanBufferCapacity: 1, Overflow: tt.policy})
+
+			// The Info messages are written before consuming the
+			// channel, so it overflows.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				io.WriteString(pw, in[:len(in)-len("ExitRobot\n")])
+			}()
+			if tt.policy != OverflowBlock {
+				<-done
+				// Wait for the reader goroutine to process
+				// the last line.
+				for deadline := time.Now().Add(time.Second); r.Dropped() < tt.wantDropped && time.Now().Before(deadline); {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			go func() {
+				<-done
+				io.WriteString(pw, "ExitRobot\n")
+				pw.Close()
+			}()
+
+			var got []Message
+			for msg := range msgs {
+				got = append(got, msg)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected messages: got=%v want=%v", got, tt.want)
+			}
+			if got := r.Dropped(); got != tt.wantDropped {
+				t.Errorf("unexpected dropped messages: got=%v want=%v", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestListenStall(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := NewRobot(pr, io.Discard)
+	msgs := r.Listen(ListenSettings{StallTimeout: 20 * time.Millisecond})
+
+	next := func() Message {
+		select {
+		case msg := <-msgs:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for message")
+		}
+		return nil
+	}
+
+	go io.WriteString(pw, "GameStarts\n")
+	if msg := next(); msg != (MessageGameStarts{}) {
+		t.Fatalf("unexpected message: got=%v want=GameStarts", msg)
+	}
+	msg, ok := next().(MessageServerStalled)
+	if !ok || msg.Elapsed < 0.02 {
+		t.Fatalf("unexpected message: got=%#v want=ServerStalled", msg)
+	}
+
+	// The stall is reported once, and the watchdog is rearmed by the
+	// next line.
+	go io.WriteString(pw, "GameFinishes\n")
+	if msg := next(); msg != (MessageGameFinishes{}) {
+		t.Fatalf("unexpected message: got=%v want=GameFinishes", msg)
+	}
+	if _, ok := next().(MessageServerStalled); !ok {
+		t.Fatalf("stall after GameFinishes not reported")
+	}
+
+	pw.Close()
+	if msg, ok := <-msgs; ok {
+		t.Errorf("unexpected message after closing the input: %v", msg)
+	}
+}
+
+func BenchmarkParseMessage(b *testing.B) {
+	msgs := []struct {
+		name string
+		s    string
+	}{
+		{"Info", "Info 12.345 0.5 -1.25"},
+		{"Radar", "Radar 10.5 0 0.785398"},
+		{"Coordinates", "Coordinates 1.5 -2.25 3.14159"},
+		{"RobotInfo", "RobotInfo 85 0"},
+		{"YourName", "YourName foo bar"},
+	}
+
+	for _, m := range msgs {
+		b.Run(m.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseMessage(m.s); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"Info 1 2 3",
+		"  Info\t1  2\r\n3 ",
+		"YourName a b c d e f g h i j",
+		"YourName ñandú bar",
+	}
+
+	for _, s := range tests {
+		var buf [maxFields]string
+		got := splitFields(s, buf[:0])
+		if want := strings.Fields(s); len(got) != len(want) || strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("unexpected fields for %q: got=%q want=%q", s, got, want)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	in := bytes.NewBufferString("GameOption 2 1.5\nInfo 1 2 3\nWarning 4 foo\nExitRobot\n")
+	r := NewRobot(in, io.Discard)
+
+	var got []Message
+	r.Run(ListenSettings{Pool: true}, StrategyFunc(func(r *Robot, msg Message) {
+		got = append(got, CopyMessage(msg))
+		if m, ok := msg.(*MessageInfo); ok && r.GameClock() != m.Time {
+			t.Errorf("unexpected game clock: got=%v want=%v", r.GameClock(), m.Time)
+		}
+	}))
+
+	want := []Message{
+		MessageGameOption{Option: 2, Value: 1.5},
+		MessageInfo{Time: 1, Speed: 2, CannonAngle: 3},
+		MessageWarning{Warning: 4, Message: "foo"},
+		MessageExitRobot{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected messages: got=%v want=%v", got, want)
+	}
+	if v, ok := r.options[2]; !ok || v != 1.5 {
+		t.Errorf("unexpected option: got=%v, %v want=%v, %v", v, ok, 1.5, true)
+	}
+
+	msg, err := parseMessage("Info 1 2 3", true)
+	if err != nil {
+		t.Fatalf("could not parse message: %v", err)
+	}
+	if s, err := EncodeMessage(msg); err != nil || s != "Info 1 2 3" {
+		t.Errorf("unexpected encoding: got=%q, %v want=%q", s, err, "Info 1 2 3")
+	}
+	m := msg.(*MessageInfo)
+	Release(m)
+	if *m != (MessageInfo{}) {
+		t.Errorf("released message not cleared: %+v", *m)
+	}
+}
+
+func BenchmarkParseMessagePooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg, err := parseMessage("Info 12.345 0.5 -1.25", true)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		Release(msg)
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		policy     LongLinePolicy
+		want       []string
+		wantErrors int
+	}{
+		{
+			"Limit",
+			"0123456789\n0123456789\r\n",
+			LongLineError,
+			[]string{"0123456789", "0123456789"},
+			0,
+		},
+		{
+			"Error",
+			"0123456789a\nfoo\n0123456789abcdefghijklmnopqrstuvwxyz\nbar",
+			LongLineError,
+			[]string{"foo", "bar"},
+			2,
+		},
+		{
+			"Truncate",
+			"0123456789a\r\nfoo\n0123456789abcdefghijklmnopqrstuvwxyz\n",
+			LongLineTruncate,
+			[]string{"0123456789", "foo", "0123456789"},
+			0,
+		},
+		{
+			"Empty lines",
+			"\n\r\nfoo\n",
+			LongLineError,
+			[]string{"", "", "foo"},
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRobot(bytes.NewBufferString(tt.in), io.Discard)
+			errs := r.Errors()
+
+			var got []string
+			for line := range r.stdinReader(10, tt.policy) {
+				got = append(got, line)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("unexpected lines: got=%q want=%q", got, tt.want)
+			}
+			if got := len(errs); got != tt.wantErrors {
+				t.Errorf("unexpected number of errors: got=%v want=%v", got, tt.wantErrors)
+			}
+			for i := 0; i < tt.wantErrors; i++ {
+				if err := <-errs; !errors.Is(err, ErrLineTooLong) {
+					t.Errorf("unexpected error: got=%v want=%v", err, ErrLineTooLong)
+				}
+			}
+		})
+	}
+}
+
+func TestListenStreams(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRobot(nil, nil)
+	settings := ListenSettings{
+		Input:  bytes.NewBufferString("GameStarts\nExitRobot\n"),
+		Output: &out,
+	}
+
+	var got []Message
+	r.Run(settings, StrategyFunc(func(r *Robot, msg Message) {
+		if _, ok := msg.(MessageGameStarts); ok {
+			r.Shoot(1)
+		}
+		got = append(got, msg)
+	}))
+
+	if want := []Message{MessageGameStarts{}, MessageExitRobot{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected messages: got=%v want=%v", got, want)
+	}
+	if got, want := out.String(), "RobotOption 3 0\nRobotOption 1 0\nShoot 1.000000\n"; got != want {
+		t.Errorf("unexpected output: got=%q want=%q", got, want)
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	msgs := []Message{
+		MessageInitialize{First: true},
+		MessageYourName{Name: "robot"},
+		MessageYourColour{Colour: "ff0000"},
+		MessageGameOption{Option: GOptionRobotMaxAcceleration, Value: 2},
+		MessageGameStarts{},
+		MessageRadar{Distance: 2.5, Object: ObjectWall, RadarAngle: 0.1},
+		MessageInfo{Time: 1, Speed: 2, CannonAngle: 3},
+		MessageCoordinates{X: 1, Y: 2, Angle: 3},
+		MessageRobotInfo{EnergyLevel: 50, TeamMate: true},
+		MessageRotationReached{Part: PartRadar},
+		MessageEnergy{EnergyLevel: 80},
+		MessageRobotsLeft{NumRobots: 3},
+		MessageCollision{Object: ObjectShot, Angle: 1},
+		MessageWarning{Warning: WarningUnknownOption, Message: "bad option"},
+		MessageDead{},
+		MessageGameFinishes{},
+		MessageExitRobot{},
+		MessageServerStalled{Elapsed: 5},
+	}
+	for _, msg := range msgs {
+		data, err := MarshalMessageJSON(msg)
+		if err != nil {
+			t.Fatalf("unexpected error marshaling %T: %v", msg, err)
+		}
+		got, err := UnmarshalMessageJSON(data)
+		if err != nil {
+			t.Fatalf("unexpected error unmarshaling %s: %v", data, err)
+		}
+		if got != msg {
+			t.Errorf("wrong message: got=%v want=%v", got, msg)
+		}
+	}
+
+	data, err := MarshalMessageJSON(&MessageRadar{Distance: 2.5, Object: ObjectWall, RadarAngle: 0.1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"type":"Radar","distance":2.5,"object":2,"radarAngle":0.1}`; string(data) != want {
+		t.Errorf("wrong encoding: got=%s want=%v", data, want)
+	}
+
+	if _, err := UnmarshalMessageJSON([]byte(`{"type":"Foo"}`)); !errors.Is(err, ErrUnknownMessage) {
+		t.Errorf("wrong error: got=%v want=%v", err, ErrUnknownMessage)
+	}
+}
+
+func TestCommandJSON(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"RobotOption 3 1", `{"type":"RobotOption","option":3,"value":1}`},
+		{"Name my robot", `{"type":"Name","name":"my robot"}`},
+		{"Colour ff0000 00ff00", `{"type":"Colour","home":"ff0000","away":"00ff00"}`},
+		{"Rotate 2 0.500000", `{"type":"Rotate","part":2,"speed":0.5}`},
+		{"RotateTo 4 1.000000 -0.500000", `{"type":"RotateTo","part":4,"speed":1,"angle":-0.5}`},
+		{"RotateAmount 1 1.000000 0.250000", `{"type":"RotateAmount","part":1,"speed":1,"angle":0.25}`},
+		{"Sweep 4 1.000000 -0.500000 0.500000", `{"type":"Sweep","part":4,"speed":1,"right":-0.5,"left":0.5}`},
+		{"Accelerate 1.000000", `{"type":"Accelerate","value":1}`},
+		{"Brake 0.500000", `{"type":"Brake","portion":0.5}`},
+		{"Shoot 2.000000", `{"type":"Shoot","energy":2}`},
+		{"Print hello world", `{"type":"Print","message":"hello world"}`},
+		{"Debug hello", `{"type":"Debug","message":"hello"}`},
+		{"DebugLine 0.000000 1.000000 2.000000 3.000000", `{"type":"DebugLine","angle1":0,"radius1":1,"angle2":2,"radius2":3}`},
+		{"DebugCircle 0.000000 1.000000 2.000000", `{"type":"DebugCircle","centerAngle":0,"centerRadius":1,"radius":2}`},
+	}
+	for _, tt := range tests {
+		data, err := MarshalCommandJSON(tt.cmd)
+		if err != nil {
+			t.Fatalf("unexpected error marshaling %q: %v", tt.cmd, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("wrong encoding: got=%s want=%v", data, tt.want)
+		}
+		cmd, err := UnmarshalCommandJSON(data)
+		if err != nil {
+			t.Fatalf("unexpected error unmarshaling %s: %v", data, err)
+		}
+		if cmd != tt.cmd {
+			t.Errorf("wrong command: got=%q want=%q", cmd, tt.cmd)
+		}
+	}
+
+	if _, err := MarshalCommandJSON("Foo 1"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("wrong error: got=%v want=%v", err, ErrUnknownCommand)
+	}
+	if _, err := MarshalCommandJSON("Shoot 1 2"); !errors.Is(err, ErrBadFieldCount) {
+		t.Errorf("wrong error: got=%v want=%v", err, ErrBadFieldCount)
+	}
+	if _, err := UnmarshalCommandJSON([]byte(`{"type":"Shoot"}`)); !errors.Is(err, ErrBadFieldCount) {
+		t.Errorf("wrong error: got=%v want=%v", err, ErrBadFieldCount)
+	}
+}
+
+func TestDialect(t *testing.T) {
+	var out bytes.Buffer
+	r := NewRobot(nil, nil)
+	r.SetDialect(Dialect{
+		Name:     "test",
+		Commands: map[string]string{"Brake": "Break", "Shoot": ""},
+		Messages: map[string]string{"Begin": "GameStarts", "Dead": ""},
+	})
+	o := &recordObserver{}
+	r.AddObserver(o)
+	settings := ListenSettings{
+		Input:  bytes.NewBufferString("Begin\nDead\nExitRobot\n"),
+		Output: &out,
+	}
+
+	var got []Message
+	r.Run(settings, StrategyFunc(func(r *Robot, msg Message) {
+		if _, ok := msg.(MessageGameStarts); ok {
+			r.Brake(1)
+			if err := r.Shoot(1); !errors.Is(err, ErrUnsupportedCommand) {
+				t.Errorf("wrong error: got=%v want=%v", err, ErrUnsupportedCommand)
+			}
+		}
+		got = append(got, msg)
+	}))
+
+	if want := []Message{MessageGameStarts{}, MessageExitRobot{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected messages: got=%v want=%v", got, want)
+	}
+	if got, want := out.String(), "RobotOption 3 0\nRobotOption 1 0\nBreak 1.000000\n"; got != want {
+		t.Errorf("unexpected output: got=%q want=%q", got, want)
+	}
+	if want := []string{"RobotOption 3 0", "RobotOption 1 0", "Brake 1.000000"}; !reflect.DeepEqual(o.cmds, want) {
+		t.Errorf("unexpected commands: got=%q want=%q", o.cmds, want)
+	}
+}
+
+func TestBuiltinDialects(t *testing.T) {
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{Dialect{}, "Brake 0.500000\n"},
+		{Dialect108, "Brake 0.500000\n"},
+		{DialectBreak, "Break 0.500000\n"},
+		{DialectBrakeCompat, "Brake 0.500000\nBreak 0.500000\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		r := NewRobot(nil, &out)
+		r.SetDialect(tt.d)
+		if err := r.Brake(0.5); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("wrong command for %v: got=%q want=%q", tt.d.Name, got, tt.want)
+		}
+	}
+}
+
+func TestSendRaw(t *testing.T) {
+	tests := []struct {
+		keyword string
+		args    []any
+		want    string
+		wantErr error
+	}{
+		{"Teleport", []any{1.5, 2, PartRadar, true, "fast"}, "Teleport 1.500000 2 4 1 fast\n", nil},
+		{"Ping", nil, "Ping\n", nil},
+		{"", nil, "", ErrInvalidCommand},
+		{"Two words", nil, "", ErrInvalidCommand},
+		{"Print", []any{"a\nShoot 1"}, "", ErrInvalidCommand},
+		{"Foo", []any{[]int{1}}, "", ErrInvalidCommand},
+		{"Print", []any{strings.Repeat("x", 128)}, "", ErrMessageTooLong},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		r := NewRobot(nil, &out)
+		if err := r.SendRaw(tt.keyword, tt.args...); !errors.Is(err, tt.wantErr) {
+			t.Errorf("wrong error for %q: got=%v want=%v", tt.keyword, err, tt.wantErr)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("wrong output for %q: got=%q want=%q", tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	tests := []struct {
+		f    FloatFormat
+		v    float64
+		want string
+	}{
+		{DefaultFloatFormat(), 0.5, "0.500000"},
+		{FloatFormat{}, 0.5, "0.500000"},
+		{FloatFormat{TrimZeros: true}, 0.5, "0.5"},
+		{FloatFormat{Prec: 3}, 0.5, "0.500"},
+		{FloatFormat{Prec: 3, TrimZeros: true}, 0.5, "0.5"},
+		{FloatFormat{Prec: 3, TrimZeros: true}, 2, "2"},
+		{FloatFormat{Prec: 3, TrimZeros: true}, 100, "100"},
+		{FloatFormat{Fmt: 'g', Prec: 3}, 1.23456, "1.23"},
+		{FloatFormat{Fmt: 'g', Prec: -1}, 1e21, "1e+21"},
+		{FloatFormat{Fmt: 'e', Prec: 3, TrimZeros: true}, 1500, "1.5e+03"},
+		{FloatFormat{Prec: -1}, 0.1, "0.1"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.format(tt.v); got != tt.want {
+			t.Errorf("wrong format of %v with %+v: got=%q want=%q", tt.v, tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestFloatFormatLength(t *testing.T) {
+	// With 26 decimals, "DebugLine 10.(26) 10.(26) 1.(26) 1.(26)\n" is
+	// exactly 128 characters long.
+	tests := []struct {
+		f       FloatFormat
+		vs      [4]float64
+		wantLen int
+		wantErr error
+	}{
+		{FloatFormat{Prec: 26}, [4]float64{10, 10, 1, 1}, 128, nil},
+		{FloatFormat{Prec: 26}, [4]float64{10, 10, 10, 1}, 0, ErrMessageTooLong},
+		{FloatFormat{Prec: 26, TrimZeros: true}, [4]float64{10, 10, 10, 1}, 21, nil},
+		{DefaultFloatFormat(), [4]float64{1e21, 1e21, 1e21, 1e21}, 0, ErrMessageTooLong},
+		{FloatFormat{Fmt: 'g', Prec: -1}, [4]float64{1e21, 1e21, 1e21, 1e21}, 34, nil},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		r := NewRobot(nil, &out)
+		r.SetFloatFormat(tt.f)
+		if err := r.DebugLine(tt.vs[0], tt.vs[1], tt.vs[2], tt.vs[3]); !errors.Is(err, tt.wantErr) {
+			t.Errorf("wrong error for %v with %+v: got=%v want=%v", tt.vs, tt.f, err, tt.wantErr)
+		}
+		if got := out.Len(); got != tt.wantLen {
+			t.Errorf("wrong length for %v with %+v: got=%v want=%v (%q)", tt.vs, tt.f, got, tt.wantLen, out.String())
+		}
+	}
+}
+
+func TestTextSplit(t *testing.T) {
+	long := strings.Repeat("x", 130)
+	tests := []struct {
+		name    string
+		split   *TextSplit
+		text    string
+		want    string
+		wantErr error
+	}{
+		{"Short", nil, "hello", "Print hello\n", nil},
+		{"Default", nil, long, "Print " + long[:121] + "\nPrint + " + long[121:] + "\n", nil},
+		{"Newlines", nil, "a\nb\n", "Print a\nPrint + b\n", nil},
+		{"Prefix", &TextSplit{Prefix: "[r] ", Continuation: "... "}, long, "Print [r] " + long[:117] + "\nPrint [r] ... " + long[117:] + "\n", nil},
+		{"UTF-8", &TextSplit{}, strings.Repeat("x", 120) + "ñ", "Print " + strings.Repeat("x", 120) + "\nPrint ñ\n", nil},
+		{"Invalid UTF-8", nil, strings.Repeat("\x80", 200), "Print " + strings.Repeat("\x80", 121) + "\nPrint + " + strings.Repeat("\x80", 79) + "\n", nil},
+		{"Disabled", &TextSplit{Disabled: true}, long, "", ErrMessageTooLong},
+		{"No room", &TextSplit{Prefix: strings.Repeat("p", 128)}, "a", "", ErrMessageTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+			if tt.split != nil {
+				r.SetTextSplit(*tt.split)
+			}
+			if err := r.Printf("%v", tt.text); !errors.Is(err, tt.wantErr) {
+				t.Errorf("unexpected error: got=%v want=%v", err, tt.wantErr)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected output: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	type target struct {
+		Dist  float64
+		Robot bool
+	}
+	type state struct {
+		Pos     struct{ X, Y float64 }
+		Part    Part
+		Name    string
+		Targets []target
+		Seen    map[string]int
+		Last    *target
+		hidden  int
+	}
+
+	var out bytes.Buffer
+	r := NewRobot(nil, &out)
+	st := state{
+		Part:    PartRadar,
+		Name:    "a b",
+		Targets: []target{{4.25, true}},
+		Seen:    map[string]int{"b": 2, "a": 1},
+		hidden:  1,
+	}
+	st.Pos.X, st.Pos.Y = 10.5, 1.0/3
+	if err := r.DumpState(st); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Debug pos.x=10.5 pos.y=0.3333 part=Radar name=\"a b\" targets[0].dist=4.25 targets[0].robot=true seen[a]=1 seen[b]=2 last=nil\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected output: got=%q want=%q", got, want)
+	}
+
+	out.Reset()
+	if err := r.DumpState(make([]int, 40)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Errorf("wrong number of messages: got=%v want=%v", len(lines), 3)
+	}
+	for _, line := range lines {
+		if len(line) >= 128 {
+			t.Errorf("message too long: %q", line)
+		}
+	}
+}
+
+func TestEndgame(t *testing.T) {
+	r := NewRobot(nil, io.Discard)
+
+	var (
+		got  [][2]int
+		msgs int
+	)
+	e := &Endgame{
+		Strategy:   StrategyFunc(func(r *Robot, msg Message) { msgs++ }),
+		Thresholds: []int{2, 3},
+		OnThreshold: func(r *Robot, threshold, left int) {
+			got = append(got, [2]int{threshold, left})
+		},
+	}
+	steps := []Message{
+		MessageGameStarts{},
+		MessageRobotsLeft{NumRobots: 5},
+		MessageRobotsLeft{NumRobots: 3},
+		MessageRobotsLeft{NumRobots: 3},
+		MessageRobotsLeft{NumRobots: 2},
+		MessageGameFinishes{},
+		MessageGameStarts{},
+		MessageRobotsLeft{NumRobots: 2},
+	}
+	for _, msg := range steps {
+		r.Deliver(e, msg)
+	}
+
+	want := [][2]int{{3, 3}, {2, 2}, {3, 2}, {2, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected thresholds: got=%v want=%v", got, want)
+	}
+	if msgs != len(steps) {
+		t.Errorf("unexpected number of messages: got=%v want=%v", msgs, len(steps))
+	}
+	if left := e.RobotsLeft(); left != 2 {
+		t.Errorf("unexpected robots left: got=%v want=%v", left, 2)
+	}
+}
+
+func TestParseColour(t *testing.T) {
+	tests := []struct {
+		colour  string
+		want    RGB
+		wantErr bool
+	}{
+		{"11aa22", RGB{0x11, 0xaa, 0x22}, false},
+		{"FFFFFF", RGB{0xff, 0xff, 0xff}, false},
+		{"11aa2", RGB{}, true},
+		{"11aag2", RGB{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColour(tt.colour)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error for %q: got=%v want=%v", tt.colour, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("unexpected colour for %q: got=%v want=%v", tt.colour, got, tt.want)
+		}
+		if err == nil && got.String() != strings.ToLower(tt.colour) {
+			t.Errorf("unexpected string: got=%v want=%v", got.String(), strings.ToLower(tt.colour))
+		}
+	}
+}
+
+func TestHSL(t *testing.T) {
+	tests := []struct {
+		h, s, l float64
+		want    RGB
+	}{
+		{0, 1, 0.5, RGB{0xff, 0, 0}},
+		{120, 1, 0.5, RGB{0, 0xff, 0}},
+		{240, 1, 0.5, RGB{0, 0, 0xff}},
+		{-120, 1, 0.5, RGB{0, 0, 0xff}},
+		{0, 0, 1, RGB{0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range tests {
+		got := HSL(tt.h, tt.s, tt.l)
+		if got != tt.want {
+			t.Errorf("unexpected colour for %v,%v,%v: got=%v want=%v", tt.h, tt.s, tt.l, got, tt.want)
+		}
+		if back := HSL(got.HSL()); back != got {
+			t.Errorf("unexpected round trip for %v: got=%v", got, back)
+		}
+	}
+
+	if got, want := AwayColour(RGB{0xff, 0, 0}), (RGB{0, 0xff, 0xff}); got != want {
+		t.Errorf("unexpected away colour: got=%v want=%v", got, want)
+	}
+
+	p := Palette(4, 0)
+	for i, c := range p {
+		for _, other := range p[i+1:] {
+			if c.Distance(other) < 100 {
+				t.Errorf("palette colours too close: %v %v", c, other)
+			}
+		}
+	}
+	if p := Palette(-1, 0); p != nil {
+		t.Errorf("unexpected palette: %v", p)
+	}
+}
+
+func TestColourPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *ColourPolicy
+		assigned []string
+		want     string
+	}{
+		{
+			"Default",
+			nil,
+			[]string{"ff0000"},
+			"",
+		},
+		{
+			"Accepted",
+			&ColourPolicy{Accept: ColourNear(RGB{0x11, 0x22, 0x33}, 10)},
+			[]string{"112234"},
+			"",
+		},
+		{
+			"Rejected",
+			&ColourPolicy{Accept: ColourNear(RGB{0x11, 0x22, 0x33}, 10)},
+			[]string{"ff0000", "ff0000"},
+			"Colour 112233 445566\n",
+		},
+		{
+			"Alternative",
+			&ColourPolicy{Accept: ColourNear(RGB{0x11, 0x22, 0x33}, 10), Home: "aabbcc", MaxRequests: 2},
+			[]string{"ff0000", "ff0000", "ff0000"},
+			"Colour aabbcc aabbcc\nColour aabbcc aabbcc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+			r.Colour("112233", "445566")
+			out.Reset()
+			if tt.policy != nil {
+				r.SetColourPolicy(*tt.policy)
+			}
+
+			nop := StrategyFunc(func(r *Robot, msg Message) {})
+			for _, c := range tt.assigned {
+				r.Deliver(nop, MessageYourColour{Colour: c})
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected output: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatName(t *testing.T) {
+	long := strings.Repeat("x", 200)
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		parts   NameParts
+		want    string
+		wantErr bool
+	}{
+		{
+			"Full",
+			DefaultNameTemplate,
+			NameParts{Base: "foo", Version: "1.2", Team: "bar"},
+			"foo v1.2 Team: bar",
+			false,
+		},
+		{
+			"No version",
+			DefaultNameTemplate,
+			NameParts{Base: "foo", Team: "bar"},
+			"foo Team: bar",
+			false,
+		},
+		{
+			"No team",
+			DefaultNameTemplate,
+			NameParts{Base: "foo", Version: "1.2"},
+			"foo v1.2",
+			false,
+		},
+		{
+			"Truncated",
+			DefaultNameTemplate,
+			NameParts{Base: long, Version: "1.2", Team: "bar"},
+			strings.Repeat("x", maxNameLength-len(" Team: bar")) + " Team: bar",
+			false,
+		},
+		{
+			"Truncated without team",
+			"{base}",
+			NameParts{Base: long},
+			strings.Repeat("x", maxNameLength),
+			false,
+		},
+		{
+			"Team too long",
+			DefaultNameTemplate,
+			NameParts{Base: "foo", Team: long},
+			"",
+			true,
+		},
+		{
+			"Reserved marker",
+			DefaultNameTemplate,
+			NameParts{Base: "foo Team: baz", Team: "bar"},
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatName(tt.tmpl, tt.parts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unexpected error: got=%v want=%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected name: got=%q want=%q", got, tt.want)
+			}
+			if len("Name "+got) >= 128 {
+				t.Errorf("name too long: %v", len(got))
+			}
+		})
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name string
+		want NameParts
+	}{
+		{"foo v1.2 Team: bar", NameParts{Base: "foo", Version: "1.2", Team: "bar"}},
+		{"foo bar v2", NameParts{Base: "foo bar", Version: "2"}},
+		{"foo Team: bar", NameParts{Base: "foo", Team: "bar"}},
+		{"v2", NameParts{Base: "v2"}},
+		{"foo vx", NameParts{Base: "foo vx"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseName(tt.name); got != tt.want {
+			t.Errorf("unexpected parts for %q: got=%+v want=%+v", tt.name, got, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	r := NewRobot(nil, &out)
+	if err := r.SetName("", NameParts{Base: "foo", Version: "1", Team: "bar"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := out.String(), "Name foo v1 Team: bar\n"; got != want {
+		t.Errorf("unexpected output: got=%q want=%q", got, want)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		name       string
+		msgs       []Message
+		want       string
+		wantName   string
+		wantColour string
+		wantErr    error
+	}{
+		{
+			"First",
+			[]Message{MessageInitialize{First: true}},
+			"Name foo\nColour 112233 445566\n",
+			"foo",
+			"112233",
+			nil,
+		},
+		{
+			"Not first",
+			[]Message{
+				MessageInitialize{First: false},
+				MessageYourName{Name: "foo(2)"},
+				MessageYourColour{Colour: "aabbcc"},
+			},
+			"",
+			"foo(2)",
+			"aabbcc",
+			nil,
+		},
+		{
+			"Name not given",
+			[]Message{
+				MessageInitialize{First: false},
+				MessageWarning{Warning: WarningNameNotGiven},
+			},
+			"",
+			"",
+			"",
+			ErrHandshake{Warning: WarningNameNotGiven, First: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			r := NewRobot(nil, &out)
+			r.SetWarningPolicy(WarningPolicy{})
+			errs := r.Errors()
+
+			var got []Message
+			h := &Handshake{
+				Strategy: StrategyFunc(func(r *Robot, msg Message) { got = append(got, msg) }),
+				Name:     "foo",
+				Home:     "112233",
+				Away:     "445566",
+			}
+			for _, msg := range tt.msgs {
+				r.Deliver(h, msg)
+			}
+
+			if !reflect.DeepEqual(got, tt.msgs) {
+				t.Errorf("unexpected messages: got=%v want=%v", got, tt.msgs)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("unexpected output: got=%q want=%q", got, tt.want)
+			}
+			if got, _ := h.RobotName(); got != tt.wantName {
+				t.Errorf("unexpected name: got=%q want=%q", got, tt.wantName)
+			}
+			if got, _ := h.RobotColour(); got != tt.wantColour {
+				t.Errorf("unexpected colour: got=%q want=%q", got, tt.wantColour)
+			}
+			if got, want := h.Ready(), tt.wantName != "" && tt.wantColour != ""; got != want {
+				t.Errorf("unexpected ready: got=%v want=%v", got, want)
+			}
+			select {
+			case err := <-errs:
+				if err != tt.wantErr {
+					t.Errorf("unexpected error: got=%v want=%v", err, tt.wantErr)
+				}
+			default:
+				if tt.wantErr != nil {
+					t.Errorf("error not emitted")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,537 @@
+// Package scenario runs simulations described in scenario files, to codify
+// acceptance tests for the behaviors of strategies.
+//
+// A scenario is a JSON file that describes the arena, the initial placement
+// of the robots, the actions of the scripted robots, the cookies and mines
+// in the arena at the beginning of the game and the assertions checked when
+// the game finishes. Positions are given as [x, y] and angles in radians:
+//
+//	{
+//		"name": "survive a rammer",
+//		"duration": 30,
+//		"robots": [
+//			{"name": "bot", "strategy": "bot", "pos": [5, 10]},
+//			{"name": "rammer", "pos": [15, 10], "angle": 3.14159,
+//			 "script": [{"time": 0, "command": "Accelerate 2"}]}
+//		],
+//		"objects": [{"kind": "mine", "pos": [10, 5], "energy": 20}],
+//		"assertions": [
+//			{"robot": "bot", "check": "survives"},
+//			{"robot": "bot", "check": "no-collision", "object": "wall"}
+//		]
+//	}
+//
+// Robots with a strategy run the strategy registered with that name. Robots
+// without a strategy send the commands of their script, in the RealTimeBattle
+// protocol, when the game time reaches the time of each action.
+//
+// Scenarios are usually run from tests, with the strategies under test:
+//
+//	func TestScenarios(t *testing.T) {
+//		paths, _ := filepath.Glob("testdata/*.json")
+//		for _, path := range paths {
+//			s, err := scenario.Load(path)
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			rep, err := s.Run(map[string]func() rtb.Strategy{"bot": newBot})
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			if err := rep.Err(); err != nil {
+//				t.Errorf("%v: %v", s.Name, err)
+//			}
+//		}
+//	}
+//
+// The rtbscenario command runs scenarios with the built-in strategies.
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Checks of the assertions.
+const (
+	// CheckSurvives checks that the robot is alive at the time of the
+	// assertion or, if zero, at the end of the game.
+	CheckSurvives = "survives"
+
+	// CheckDies checks that the robot is dead at the time of the
+	// assertion or, if zero, at the end of the game.
+	CheckDies = "dies"
+
+	// CheckWins checks that the robot is the only one alive at the end
+	// of the game.
+	CheckWins = "wins"
+
+	// CheckNoCollision checks that the robot does not collide with the
+	// object of the assertion, or with anything if it is empty, before
+	// the time of the assertion or, if zero, during the whole game.
+	CheckNoCollision = "no-collision"
+
+	// CheckMinEnergy checks that the energy of the robot at the end of
+	// the game is at least the value of the assertion.
+	CheckMinEnergy = "min-energy"
+
+	// CheckMaxDamage checks that the damage taken by the robot is at
+	// most the value of the assertion.
+	CheckMaxDamage = "max-damage"
+
+	// CheckMinCookies checks that the robot eats at least the value of
+	// the assertion cookies.
+	CheckMinCookies = "min-cookies"
+
+	// CheckMaxMines checks that the robot touches at most the value of
+	// the assertion mines.
+	CheckMaxMines = "max-mines"
+)
+
+// checks are the valid checks.
+var checks = map[string]bool{
+	CheckSurvives:    true,
+	CheckDies:        true,
+	CheckWins:        true,
+	CheckNoCollision: true,
+	CheckMinEnergy:   true,
+	CheckMaxDamage:   true,
+	CheckMinCookies:  true,
+	CheckMaxMines:    true,
+}
+
+// objects maps the object names used in scenarios to objects.
+var objects = map[string]rtb.Object{
+	"robot":  rtb.ObjectRobot,
+	"shot":   rtb.ObjectShot,
+	"wall":   rtb.ObjectWall,
+	"cookie": rtb.ObjectCookie,
+	"mine":   rtb.ObjectMine,
+}
+
+// Scenario is a simulation with assertions.
+type Scenario struct {
+	// Name is the name of the scenario.
+	Name string `json:"name"`
+
+	// Arena is the path of the arena file. Relative paths are relative
+	// to the directory of the scenario file. If empty, a 20x20
+	// rectangular arena is used.
+	Arena string `json:"arena"`
+
+	// Seed is the seed of the simulation.
+	Seed int64 `json:"seed"`
+
+	// Duration is the duration of the game in seconds. If zero, the
+	// default timeout of the server is used.
+	Duration float64 `json:"duration"`
+
+	// Robots are the robots of the game.
+	Robots []Robot `json:"robots"`
+
+	// Objects are the cookies and mines in the arena at the beginning
+	// of the game.
+	Objects []Object `json:"objects"`
+
+	// Assertions are checked when the game finishes.
+	Assertions []Assertion `json:"assertions"`
+
+	// dir is the directory of the scenario file.
+	dir string
+}
+
+// Robot is a robot of a scenario.
+type Robot struct {
+	// Name identifies the robot in the assertions.
+	Name string `json:"name"`
+
+	// Strategy is the name of the strategy of the robot. If empty, the
+	// robot runs its script.
+	Strategy string `json:"strategy"`
+
+	// Pos is the initial position of the robot. If nil, the robot is
+	// placed randomly.
+	Pos *[2]float64 `json:"pos"`
+
+	// Angle is the initial direction of the robot. It is ignored if Pos
+	// is nil.
+	Angle float64 `json:"angle"`
+
+	// Energy is the initial energy of the robot. If zero, the default
+	// start energy is used. It is ignored if Pos is nil.
+	Energy float64 `json:"energy"`
+
+	// Script are the actions of a robot without strategy.
+	Script []Action `json:"script"`
+}
+
+// Action is a command sent by a scripted robot.
+type Action struct {
+	// Time is the game time when the command is sent.
+	Time float64 `json:"time"`
+
+	// Command is the command in the RealTimeBattle protocol, e.g.
+	// "Rotate 1 0.5".
+	Command string `json:"command"`
+}
+
+// Object is a cookie or a mine.
+type Object struct {
+	// Kind is "cookie" or "mine".
+	Kind string `json:"kind"`
+
+	// Pos is the position of the object.
+	Pos [2]float64 `json:"pos"`
+
+	// Energy is the energy of the object.
+	Energy float64 `json:"energy"`
+
+	// Radius is the radius of the object. If zero, 0.3 is used.
+	Radius float64 `json:"radius"`
+}
+
+// Assertion is a condition checked when the game finishes.
+type Assertion struct {
+	// Robot is the name of the robot checked.
+	Robot string `json:"robot"`
+
+	// Check is one of the checks defined by this package, like
+	// "survives".
+	Check string `json:"check"`
+
+	// Time is the game time used by the check, if any.
+	Time float64 `json:"time"`
+
+	// Value is the value used by the check, if any.
+	Value float64 `json:"value"`
+
+	// Object is the object used by the check, if any.
+	Object string `json:"object"`
+}
+
+// Load loads the scenario file at path.
+func Load(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open scenario: %v", err)
+	}
+	defer f.Close()
+
+	s, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	return s, nil
+}
+
+// Parse parses a scenario. Relative arena paths are relative to the
+// current directory.
+func Parse(r io.Reader) (*Scenario, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var s Scenario
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("could not decode scenario: %v", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// validate checks the scenario and sorts the scripts by time.
+func (s *Scenario) validate() error {
+	if len(s.Robots) == 0 {
+		return errors.New("no robots")
+	}
+	names := make(map[string]bool)
+	for i := range s.Robots {
+		r := &s.Robots[i]
+		if r.Name == "" {
+			return fmt.Errorf("robot %v has no name", i)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("duplicate robot %q", r.Name)
+		}
+		names[r.Name] = true
+		if r.Strategy != "" && len(r.Script) > 0 {
+			return fmt.Errorf("robot %q has both strategy and script", r.Name)
+		}
+		for _, a := range r.Script {
+			if strings.TrimSpace(a.Command) == "" {
+				return fmt.Errorf("robot %q has an empty command", r.Name)
+			}
+		}
+		sort.SliceStable(r.Script, func(i, j int) bool {
+			return r.Script[i].Time < r.Script[j].Time
+		})
+	}
+	for _, o := range s.Objects {
+		if o.Kind != "cookie" && o.Kind != "mine" {
+			return fmt.Errorf("invalid object kind %q", o.Kind)
+		}
+	}
+	for _, a := range s.Assertions {
+		if !names[a.Robot] {
+			return fmt.Errorf("assertion on unknown robot %q", a.Robot)
+		}
+		if !checks[a.Check] {
+			return fmt.Errorf("unknown check %q", a.Check)
+		}
+		if _, ok := objects[a.Object]; a.Object != "" && !ok {
+			return fmt.Errorf("unknown object %q", a.Object)
+		}
+	}
+	return nil
+}
+
+// Report is the outcome of a scenario.
+type Report struct {
+	// Result is the result of the game.
+	Result sim.Result
+
+	// Failures describes the assertions that failed.
+	Failures []string
+}
+
+// Err returns an error describing the failures, or nil if all the
+// assertions passed.
+func (rep Report) Err() error {
+	if len(rep.Failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(rep.Failures, "; "))
+}
+
+// Run runs the scenario. strategies maps the names of the strategies used
+// by the robots to the functions that create them.
+func (s *Scenario) Run(strategies map[string]func() rtb.Strategy) (Report, error) {
+	a, err := s.arena()
+	if err != nil {
+		return Report{}, err
+	}
+
+	opts := sim.DefaultOptions()
+	if s.Duration > 0 {
+		opts.Timeout = s.Duration
+	}
+
+	var g *sim.Game
+	now := func() float64 {
+		if g == nil {
+			return 0
+		}
+		return g.Time()
+	}
+
+	players := make([]*sim.Player, len(s.Robots))
+	recorders := make([]*recorder, len(s.Robots))
+	placements := make([]*sim.Placement, len(s.Robots))
+	for i, r := range s.Robots {
+		var strategy rtb.Strategy
+		if r.Strategy != "" {
+			newStrategy, ok := strategies[r.Strategy]
+			if !ok {
+				return Report{}, fmt.Errorf("unknown strategy %q", r.Strategy)
+			}
+			strategy = newStrategy()
+		} else {
+			strategy = &script{name: r.Name, actions: r.Script}
+		}
+		recorders[i] = &recorder{s: strategy, now: now}
+		players[i] = sim.NewPlayer(recorders[i], rtb.ListenSettings{})
+		if r.Pos != nil {
+			placements[i] = &sim.Placement{
+				Pos:    arena.Point{X: r.Pos[0], Y: r.Pos[1]},
+				Angle:  r.Angle,
+				Energy: r.Energy,
+			}
+		}
+	}
+	defer sim.Exit(players)
+
+	var objs []sim.Object
+	for _, o := range s.Objects {
+		objs = append(objs, sim.Object{
+			Kind:   objects[o.Kind],
+			Pos:    arena.Point{X: o.Pos[0], Y: o.Pos[1]},
+			Energy: o.Energy,
+			Radius: o.Radius,
+		})
+	}
+
+	cfg := sim.Config{
+		Arena:      a,
+		Options:    opts,
+		Seed:       s.Seed,
+		Placements: placements,
+		Objects:    objs,
+	}
+	g, err = sim.NewGame(cfg, players)
+	if err != nil {
+		return Report{}, fmt.Errorf("could not create game: %v", err)
+	}
+	res := g.Run()
+
+	rep := Report{Result: res}
+	for _, a := range s.Assertions {
+		if err := s.check(a, res, recorders); err != nil {
+			rep.Failures = append(rep.Failures, fmt.Sprintf("robot %q: %v", a.Robot, err))
+		}
+	}
+	return rep, nil
+}
+
+// arena returns the arena of the scenario.
+func (s *Scenario) arena() (*arena.Arena, error) {
+	if s.Arena == "" {
+		return arena.Rectangle(20, 20), nil
+	}
+	path := s.Arena
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.dir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open arena: %v", err)
+	}
+	defer f.Close()
+
+	a, err := arena.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse arena: %v", err)
+	}
+	return a, nil
+}
+
+// check returns an error if the assertion a does not hold.
+func (s *Scenario) check(a Assertion, res sim.Result, recorders []*recorder) error {
+	i := s.robot(a.Robot)
+	rr := res.Robots[i]
+
+	// at is the time checked by survives and dies.
+	at := a.Time
+	if at == 0 {
+		at = res.Time
+	}
+	died := !rr.Alive && rr.DeathTime <= at
+
+	switch a.Check {
+	case CheckSurvives:
+		if died {
+			return fmt.Errorf("died at %.2fs", rr.DeathTime)
+		}
+		if at > res.Time {
+			return fmt.Errorf("game finished at %.2fs, before %.2fs", res.Time, at)
+		}
+	case CheckDies:
+		if !died {
+			return fmt.Errorf("alive at %.2fs", at)
+		}
+	case CheckWins:
+		if res.Winner != i {
+			return errors.New("did not win")
+		}
+	case CheckNoCollision:
+		for _, c := range recorders[i].collisions {
+			if a.Time != 0 && c.time > a.Time {
+				break
+			}
+			if a.Object == "" || objects[a.Object] == c.object {
+				return fmt.Errorf("collision with %v at %.2fs", c.object, c.time)
+			}
+		}
+	case CheckMinEnergy:
+		if rr.Energy < a.Value {
+			return fmt.Errorf("energy %.2f, want at least %.2f", rr.Energy, a.Value)
+		}
+	case CheckMaxDamage:
+		if rr.DamageTaken > a.Value {
+			return fmt.Errorf("damage taken %.2f, want at most %.2f", rr.DamageTaken, a.Value)
+		}
+	case CheckMinCookies:
+		if float64(rr.CookiesEaten) < a.Value {
+			return fmt.Errorf("%v cookies eaten, want at least %v", rr.CookiesEaten, a.Value)
+		}
+	case CheckMaxMines:
+		if float64(rr.MinesHit) > a.Value {
+			return fmt.Errorf("%v mines hit, want at most %v", rr.MinesHit, a.Value)
+		}
+	}
+	return nil
+}
+
+// robot returns the index of the robot name.
+func (s *Scenario) robot(name string) int {
+	for i, r := range s.Robots {
+		if r.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// collision is a collision observed by a robot.
+type collision struct {
+	time   float64
+	object rtb.Object
+}
+
+// recorder is a strategy that records the collisions of the robot before
+// passing the messages to its strategy.
+type recorder struct {
+	s          rtb.Strategy
+	now        func() float64
+	collisions []collision
+}
+
+func (rec *recorder) Handle(r *rtb.Robot, msg rtb.Message) {
+	if m, ok := msg.(rtb.MessageCollision); ok {
+		rec.collisions = append(rec.collisions, collision{rec.now(), m.Object})
+	}
+	rec.s.Handle(r, msg)
+}
+
+// script is the strategy of a scripted robot.
+type script struct {
+	name    string
+	actions []Action
+	next    int
+}
+
+func (s *script) Handle(r *rtb.Robot, msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageInitialize:
+		if m.First {
+			r.Name(s.name)
+		}
+	case rtb.MessageGameStarts:
+		s.next = 0
+		s.run(r, 0)
+	case rtb.MessageInfo:
+		s.run(r, m.Time)
+	}
+}
+
+// run sends the commands of the actions up to time t.
+func (s *script) run(r *rtb.Robot, t float64) {
+	for ; s.next < len(s.actions) && s.actions[s.next].Time <= t; s.next++ {
+		fields := strings.Fields(s.actions[s.next].Command)
+		args := make([]any, len(fields)-1)
+		for i, f := range fields[1:] {
+			args[i] = f
+		}
+		r.SendRaw(fields[0], args...)
+	}
+}
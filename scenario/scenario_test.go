@@ -0,0 +1,150 @@
+package scenario
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+// forward is a strategy that moves forward.
+func forward() rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageGameStarts); ok {
+			r.Accelerate(2)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{
+			path: "testdata/forage.json",
+			want: nil,
+		},
+		{
+			path: "testdata/rammer.json",
+			want: []string{
+				`robot "target": collision with Robot at [0-9.]+s`,
+				`robot "rammer": alive at [0-9.]+s`,
+				`robot "rammer": did not win`,
+				`robot "rammer": damage taken [0-9.]+, want at most 0.00`,
+			},
+		},
+	}
+
+	strategies := map[string]func() rtb.Strategy{"forward": forward}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			s, err := Load(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rep, err := s.Run(strategies)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(rep.Failures) != len(tt.want) {
+				t.Fatalf("unexpected failures: got=%q want=%q", rep.Failures, tt.want)
+			}
+			for i, f := range rep.Failures {
+				if !regexp.MustCompile("^" + tt.want[i] + "$").MatchString(f) {
+					t.Errorf("unexpected failure: got=%q want=%q", f, tt.want[i])
+				}
+			}
+			if (rep.Err() == nil) != (len(tt.want) == 0) {
+				t.Errorf("unexpected error: %v", rep.Err())
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{
+			name:   "no robots",
+			input:  `{"name": "empty"}`,
+			errMsg: "no robots",
+		},
+		{
+			name:   "unknown field",
+			input:  `{"robots": [{"name": "a", "speed": 1}]}`,
+			errMsg: "unknown field",
+		},
+		{
+			name:   "duplicate robot",
+			input:  `{"robots": [{"name": "a"}, {"name": "a"}]}`,
+			errMsg: `duplicate robot "a"`,
+		},
+		{
+			name:   "strategy and script",
+			input:  `{"robots": [{"name": "a", "strategy": "s", "script": [{"command": "Shoot 1"}]}]}`,
+			errMsg: "both strategy and script",
+		},
+		{
+			name:   "empty command",
+			input:  `{"robots": [{"name": "a", "script": [{"command": " "}]}]}`,
+			errMsg: "empty command",
+		},
+		{
+			name:   "invalid object",
+			input:  `{"robots": [{"name": "a"}], "objects": [{"kind": "shot"}]}`,
+			errMsg: `invalid object kind "shot"`,
+		},
+		{
+			name:   "unknown robot",
+			input:  `{"robots": [{"name": "a"}], "assertions": [{"robot": "b", "check": "wins"}]}`,
+			errMsg: `unknown robot "b"`,
+		},
+		{
+			name:   "unknown check",
+			input:  `{"robots": [{"name": "a"}], "assertions": [{"robot": "a", "check": "flies"}]}`,
+			errMsg: `unknown check "flies"`,
+		},
+		{
+			name:   "unknown object",
+			input:  `{"robots": [{"name": "a"}], "assertions": [{"robot": "a", "check": "no-collision", "object": "tree"}]}`,
+			errMsg: `unknown object "tree"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("unexpected error: got=%v want=%v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestScriptOrder(t *testing.T) {
+	s, err := Load("testdata/rammer.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Action{{Time: 0, Command: "Accelerate 2"}, {Time: 1, Command: "Brake 0"}}
+	if got := s.Robots[1].Script; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected script: got=%v want=%v", got, want)
+	}
+}
+
+func TestUnknownStrategy(t *testing.T) {
+	s, err := Parse(strings.NewReader(`{"robots": [{"name": "a", "strategy": "missing"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Run(nil); err == nil || !strings.Contains(err.Error(), `unknown strategy "missing"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
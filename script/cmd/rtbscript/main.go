@@ -0,0 +1,50 @@
+// rtbscript is a RealTimeBattle robot whose strategy is a Starlark script.
+// The script is loaded at startup, so it can be changed without recompiling
+// the robot. See the script package for the API available to scripts.
+//
+// Usage:
+//
+//	rtbscript script.star
+//
+// The RealTimeBattle server does not pass arguments to the robots, so
+// rtbscript is usually called from a shell script that is used as the robot:
+//
+//	#!/bin/sh
+//	exec rtbscript /path/to/turret.star
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/script"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	r := rtb.NewRobot(nil, nil)
+	r.SetLogger(slog.New(rtb.NewWindowHandler(r, nil)))
+
+	s, err := script.Load(flag.Arg(0))
+	if err != nil {
+		r.Logger().Error("could not load script", "err", err)
+		os.Exit(1)
+	}
+
+	r.Run(rtb.ListenSettings{SendRotationReached: 2, ChanBufferCapacity: 100}, s)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: rtbscript script\n")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,12 @@
+module github.com/jroimartin/rtb/script
+
+go 1.25.0
+
+require (
+	github.com/jroimartin/rtb v0.0.0
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
+)
+
+require golang.org/x/sys v0.42.0 // indirect
+
+replace github.com/jroimartin/rtb => ../
@@ -0,0 +1,129 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jroimartin/rtb"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// robotModule returns the robot module, which sends commands through the
+// robot of the current call.
+func (s *Script) robotModule() *starlarkstruct.Module {
+	members := starlark.StringDict{
+		"PART_ROBOT":       starlark.MakeInt(int(rtb.PartRobot)),
+		"PART_CANNON":      starlark.MakeInt(int(rtb.PartCannon)),
+		"PART_RADAR":       starlark.MakeInt(int(rtb.PartRadar)),
+		"OBJECT_NO_OBJECT": starlark.MakeInt(int(rtb.ObjectNoObject)),
+		"OBJECT_ROBOT":     starlark.MakeInt(int(rtb.ObjectRobot)),
+		"OBJECT_SHOT":      starlark.MakeInt(int(rtb.ObjectShot)),
+		"OBJECT_WALL":      starlark.MakeInt(int(rtb.ObjectWall)),
+		"OBJECT_COOKIE":    starlark.MakeInt(int(rtb.ObjectCookie)),
+		"OBJECT_MINE":      starlark.MakeInt(int(rtb.ObjectMine)),
+	}
+
+	texts := []struct {
+		name string
+		n    int
+		f    func(r *rtb.Robot, a []string) error
+	}{
+		{"name", 1, func(r *rtb.Robot, a []string) error { return r.Name(a[0]) }},
+		{"colour", 2, func(r *rtb.Robot, a []string) error { return r.Colour(a[0], a[1]) }},
+		{"print", 1, func(r *rtb.Robot, a []string) error { return r.Printf("%s", a[0]) }},
+		{"debug", 1, func(r *rtb.Robot, a []string) error { return r.Debugf("%s", a[0]) }},
+	}
+	for _, c := range texts {
+		members[c.name] = s.command(c.name, c.n, func(r *rtb.Robot, args starlark.Tuple) error {
+			strs := make([]string, len(args))
+			for i, a := range args {
+				str, ok := starlark.AsString(a)
+				if !ok {
+					return fmt.Errorf("argument %v must be a string, got %v", i+1, a.Type())
+				}
+				strs[i] = str
+			}
+			return c.f(r, strs)
+		})
+	}
+
+	floats := []struct {
+		name string
+		n    int
+		f    func(r *rtb.Robot, v []float64) error
+	}{
+		{"rotate", 2, func(r *rtb.Robot, v []float64) error { return r.Rotate(rtb.Part(v[0]), v[1]) }},
+		{"rotate_to", 3, func(r *rtb.Robot, v []float64) error { return r.RotateTo(rtb.Part(v[0]), v[1], v[2]) }},
+		{"rotate_amount", 3, func(r *rtb.Robot, v []float64) error { return r.RotateAmount(rtb.Part(v[0]), v[1], v[2]) }},
+		{"sweep", 4, func(r *rtb.Robot, v []float64) error { return r.Sweep(rtb.Part(v[0]), v[1], v[2], v[3]) }},
+		{"accelerate", 1, func(r *rtb.Robot, v []float64) error { return r.Accelerate(v[0]) }},
+		{"brake", 1, func(r *rtb.Robot, v []float64) error { return r.Brake(v[0]) }},
+		{"shoot", 1, func(r *rtb.Robot, v []float64) error { return r.Shoot(v[0]) }},
+		{"debug_line", 4, func(r *rtb.Robot, v []float64) error { return r.DebugLine(v[0], v[1], v[2], v[3]) }},
+		{"debug_circle", 3, func(r *rtb.Robot, v []float64) error { return r.DebugCircle(v[0], v[1], v[2]) }},
+	}
+	for _, c := range floats {
+		members[c.name] = s.command(c.name, c.n, func(r *rtb.Robot, args starlark.Tuple) error {
+			vs := make([]float64, len(args))
+			for i, a := range args {
+				v, ok := starlark.AsFloat(a)
+				if !ok {
+					return fmt.Errorf("argument %v must be a number, got %v", i+1, a.Type())
+				}
+				vs[i] = v
+			}
+			return c.f(r, vs)
+		})
+	}
+
+	return &starlarkstruct.Module{Name: "robot", Members: members}
+}
+
+// command returns a builtin that calls f with n positional arguments. Values
+// out of range are clamped by the robot, so they are not reported as errors.
+func (s *Script) command(name string, n int, f func(r *rtb.Robot, args starlark.Tuple) error) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%v: unexpected keyword arguments", b.Name())
+		}
+		if len(args) != n {
+			return nil, fmt.Errorf("%v: got %v arguments, want %v", b.Name(), len(args), n)
+		}
+		if s.r == nil {
+			return nil, fmt.Errorf("%v: commands can only be sent by handlers", b.Name())
+		}
+
+		var errRange rtb.ErrOutOfRange
+		if err := f(s.r, args); err != nil && !errors.As(err, &errRange) {
+			return nil, fmt.Errorf("%v: %v", b.Name(), err)
+		}
+		return starlark.None, nil
+	})
+}
+
+// worldModule returns the world module, which exposes the world model.
+func (s *Script) worldModule() *starlarkstruct.Module {
+	state := starlark.NewBuiltin("state", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+			return nil, err
+		}
+		return value(reflect.ValueOf(s.world.State())), nil
+	})
+	option := starlark.NewBuiltin("option", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var opt int
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &opt); err != nil {
+			return nil, err
+		}
+		v, ok := s.world.Option(rtb.GOption(opt))
+		if !ok {
+			return starlark.None, nil
+		}
+		return starlark.Float(v), nil
+	})
+	return &starlarkstruct.Module{
+		Name:    "world",
+		Members: starlark.StringDict{"state": state, "option": option},
+	}
+}
@@ -0,0 +1,189 @@
+// Package script runs robot strategies written in Starlark, a dialect of
+// Python designed to be embedded, so strategies can be changed without
+// recompiling the robot.
+//
+// A script handles a message by defining a function named after it, in snake
+// case with the prefix "on_", e.g. on_game_starts or on_radar. The function
+// receives the message as a struct with the fields of the rtb.Message* type
+// in snake case and a type field with the protocol keyword:
+//
+//	def on_radar(msg):
+//	    if msg.object == robot.OBJECT_ROBOT:
+//	        robot.shoot(5)
+//
+// Messages without a handler are passed to on_message, if it is defined.
+//
+// The following names are predeclared:
+//
+//   - robot: the commands of the rtb.Robot, in snake case, and the
+//     PART_* and OBJECT_* constants.
+//   - world: state() returns the state of the world model, as a struct
+//     with the fields of world.State in snake case, and option(n) returns
+//     the value of a game option or None.
+//   - state: a dictionary that keeps its contents between calls. The
+//     global variables of a script are frozen after loading it, so
+//     mutable state must be stored here.
+//
+// The output of the print function is logged at the info level.
+//
+// The package lives in its own module, so the rtb module does not depend on
+// Starlark.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/world"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Script is a strategy implemented by a Starlark script. Script methods can
+// be called concurrently.
+type Script struct {
+	mu      sync.Mutex
+	thread  *starlark.Thread
+	globals starlark.StringDict
+	world   *world.World
+	once    sync.Once
+
+	// r is the robot of the current call. It is nil when the script is
+	// not handling a message.
+	r *rtb.Robot
+}
+
+// Load loads the script at path.
+func Load(path string) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read script: %v", err)
+	}
+	return New(path, src)
+}
+
+// New loads the script src. filename is used in error messages.
+func New(filename string, src []byte) (*Script, error) {
+	s := &Script{world: world.New()}
+	s.thread = &starlark.Thread{
+		Name: filename,
+		Print: func(_ *starlark.Thread, msg string) {
+			if s.r != nil {
+				s.r.Logger().Info(msg)
+			}
+		},
+	}
+
+	predeclared := starlark.StringDict{
+		"robot": s.robotModule(),
+		"world": s.worldModule(),
+		"state": starlark.NewDict(0),
+	}
+	opts := &syntax.FileOptions{While: true, Set: true}
+	globals, err := starlark.ExecFileOptions(opts, s.thread, filename, src, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("could not load script: %v", err)
+	}
+	s.globals = globals
+	return s, nil
+}
+
+// Handle calls the handler of msg.
+func (s *Script) Handle(r *rtb.Robot, msg rtb.Message) {
+	s.once.Do(func() {
+		// The world model is attached as an observer, so it also sees
+		// the commands sent by the script. It does not see the
+		// current message, which is passed explicitly.
+		r.AddObserver(s.world)
+		s.world.Message(msg)
+	})
+
+	msg = rtb.CopyMessage(msg)
+	if msg == nil {
+		return
+	}
+	name := strings.TrimPrefix(reflect.TypeOf(msg).Name(), "Message")
+	handler := "on_" + snakeCase(name)
+	fn, ok := s.globals[handler]
+	if !ok {
+		handler = "on_message"
+		if fn, ok = s.globals[handler]; !ok {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.r = r
+	defer func() { s.r = nil }()
+
+	if _, err := starlark.Call(s.thread, fn, starlark.Tuple{messageValue(name, msg)}, nil); err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			err = errors.New(evalErr.Backtrace())
+		}
+		r.Logger().Error("script error", "handler", handler, "err", err)
+	}
+}
+
+// messageValue returns the Starlark struct of msg, which must not be a
+// pointer.
+func messageValue(name string, msg rtb.Message) starlark.Value {
+	fields := starlark.StringDict{"type": starlark.String(name)}
+	addFields(fields, reflect.ValueOf(msg))
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+// addFields adds the exported fields of the struct v to fields, with their
+// names in snake case.
+func addFields(fields starlark.StringDict, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() {
+			fields[snakeCase(f.Name)] = value(v.Field(i))
+		}
+	}
+}
+
+// value returns the Starlark value of v.
+func value(v reflect.Value) starlark.Value {
+	switch v.Kind() {
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float())
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Struct:
+		fields := starlark.StringDict{}
+		addFields(fields, v)
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+	}
+	return starlark.None
+}
+
+// snakeCase converts a name in camel case to snake case, e.g. RadarAngle to
+// radar_angle.
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package script
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/selfplay"
+)
+
+func TestHandle(t *testing.T) {
+	src := `
+def on_radar(msg):
+    robot.debug("%s %s %d %s" % (msg.type, msg.distance, msg.object, msg.radar_angle))
+    robot.accelerate(100)
+
+def on_info(msg):
+    s = world.state()
+    robot.debug("time=%s energy=%s x=%s" % (s.time, s.energy, s.pos.x))
+    robot.debug("options=%s %s" % (world.option(5), world.option(1)))
+
+def on_message(msg):
+    state["others"] = state.get("others", 0) + 1
+    robot.print("%s %d" % (msg.type, state["others"]))
+`
+	s, err := New("test.star", []byte(src))
+	if err != nil {
+		t.Fatalf("could not load script: %v", err)
+	}
+
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameStarts{},
+		&rtb.MessageRadar{Distance: 2.5, Object: rtb.ObjectWall, RadarAngle: 0.5},
+		rtb.MessageInfo{Time: 1.5},
+	}
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+
+	want := []string{
+		"Print GameOption 1",
+		"Print GameStarts 2",
+		"Debug Radar 2.5 2 0.5",
+		"Accelerate 100.000000",
+		"Debug time=1.5 energy=100.0 x=0.0",
+		"Debug options=100.0 None",
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("wrong commands:\ngot:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrong command %v: got=%q want=%q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := New("syntax.star", []byte("def on_radar(msg)\n")); err == nil {
+		t.Errorf("expected syntax error")
+	}
+	if _, err := New("top.star", []byte("robot.shoot(1)\n")); err == nil {
+		t.Errorf("expected error sending commands while loading")
+	}
+	if _, err := Load("testdata/missing.star"); err == nil {
+		t.Errorf("expected error with missing script")
+	}
+
+	s, err := New("args.star", []byte("def on_game_starts(msg):\n    robot.shoot(\"x\")\n    robot.accelerate(1)\n"))
+	if err != nil {
+		t.Fatalf("could not load script: %v", err)
+	}
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	r.Deliver(s, rtb.MessageGameStarts{})
+	if out.Len() != 0 {
+		t.Errorf("unexpected commands after error: %q", out.String())
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"Radar", "radar"},
+		{"GameStarts", "game_starts"},
+		{"RadarAngle", "radar_angle"},
+		{"X", "x"},
+	}
+	for _, tt := range tests {
+		if got := snakeCase(tt.s); got != tt.want {
+			t.Errorf("wrong snake case of %q: got=%q want=%q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTurret(t *testing.T) {
+	rep, err := selfplay.Run(selfplay.Config{
+		Contenders: []selfplay.Contender{
+			{
+				Name: "turret",
+				New: func() rtb.Strategy {
+					s, err := Load("testdata/turret.star")
+					if err != nil {
+						t.Fatalf("could not load script: %v", err)
+					}
+					return s
+				},
+			},
+			{
+				Name: "duck",
+				New: func() rtb.Strategy {
+					return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+				},
+			},
+		},
+		Games: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := rep.Contenders[0]; c.Wins != 2 {
+		t.Errorf("unexpected turret report: %+v", c)
+	}
+}
@@ -0,0 +1,275 @@
+// Package selfplay pits robot strategies against each other in the simulator
+// and reports how they perform. It is the core loop of strategy development
+// and regression testing.
+package selfplay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/opponents"
+	"github.com/jroimartin/rtb/results"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Contender is a strategy taking part in the self-play.
+type Contender struct {
+	// Name identifies the contender in the report.
+	Name string
+
+	// New returns a new instance of the strategy. It is called once per
+	// run, so the same instance plays all the games, like a robot
+	// process does during a RealTimeBattle sequence.
+	New func() rtb.Strategy
+
+	// Settings are the settings passed to the simulator, as if they
+	// were passed to rtb.Listen.
+	Settings rtb.ListenSettings
+
+	// Version is the version of the strategy, e.g. a commit hash. It is
+	// stored with the results, so different versions of a strategy can
+	// be compared.
+	Version string
+
+	// Telemetry returns the reference to the telemetry file of the
+	// contender in the given game, if any. It is stored with the
+	// results.
+	Telemetry func(game int) string
+}
+
+// Opponent returns a contender that plays the scripted opponent name of the
+// opponents package, e.g. "rammer". The opponents form a standard ladder to
+// measure strategies against.
+func Opponent(name string) (Contender, error) {
+	if _, err := opponents.New(name); err != nil {
+		return Contender{}, err
+	}
+	return Contender{
+		Name: name,
+		New: func() rtb.Strategy {
+			s, _ := opponents.New(name)
+			return s
+		},
+	}, nil
+}
+
+// Config is the configuration of a self-play run.
+type Config struct {
+	// Contenders are the strategies that play against each other. All
+	// of them take part in every game.
+	Contenders []Contender
+
+	// Games is the number of games to run.
+	Games int
+
+	// Seed is the seed of the first game. Game i uses Seed+i, so every
+	// game starts with a different placement of the robots.
+	Seed int64
+
+	// Arena is the arena used in all the games. If nil, the simulator
+	// default is used.
+	Arena *arena.Arena
+
+	// Options are the game options. If zero, the simulator defaults are
+	// used.
+	Options sim.Options
+
+	// Store is where the results of every game are persisted. If nil,
+	// the results are not persisted.
+	Store *results.Store
+
+	// Tournament is the name of the tournament stored with the results.
+	Tournament string
+
+	// Concurrent runs the strategies of the contenders in parallel. See
+	// sim.Config.
+	Concurrent bool
+
+	// CPU limits the processing time of the contenders during the run.
+	// See sim.Config.
+	CPU *sim.CPULimits
+
+	// Cookies and Mines configure the cookies and mines placed in the
+	// arena. See sim.Config.
+	Cookies, Mines *sim.Spawn
+
+	// Control controls the pace of the games, e.g. to pause the run and
+	// inspect a game. If nil, the games run as fast as possible.
+	Control *sim.Control
+}
+
+// ContenderReport summarizes the performance of a contender.
+type ContenderReport struct {
+	// Name is the name of the contender.
+	Name string
+
+	// Wins is the number of games won by the contender.
+	Wins int
+
+	// WinRate is the ratio of games won by the contender.
+	WinRate float64
+
+	// AvgDamageTaken is the average energy lost per game.
+	AvgDamageTaken float64
+
+	// AvgDamageDealt is the average energy taken to the opponents per
+	// game.
+	AvgDamageDealt float64
+
+	// AvgCPUTime is the average time spent handling messages per game.
+	AvgCPUTime time.Duration
+
+	// CPUExceeded is the number of games in which the contender was
+	// killed for exceeding its CPU time.
+	CPUExceeded int
+}
+
+// Report is the outcome of a self-play run.
+type Report struct {
+	// Games is the number of games played.
+	Games int
+
+	// Draws is the number of games without winner.
+	Draws int
+
+	// Contenders contains the report of each contender, in the same
+	// order as Config.Contenders.
+	Contenders []ContenderReport
+}
+
+// Run runs the self-play described by cfg.
+func Run(cfg Config) (Report, error) {
+	if len(cfg.Contenders) == 0 {
+		return Report{}, errors.New("no contenders")
+	}
+	if cfg.Games <= 0 {
+		return Report{}, errors.New("invalid number of games")
+	}
+
+	players := make([]*sim.Player, len(cfg.Contenders))
+	for i, c := range cfg.Contenders {
+		players[i] = sim.NewPlayer(c.New(), c.Settings)
+	}
+	defer sim.Exit(players)
+
+	rep := Report{
+		Games:      cfg.Games,
+		Contenders: make([]ContenderReport, len(cfg.Contenders)),
+	}
+	for i, c := range cfg.Contenders {
+		rep.Contenders[i].Name = c.Name
+	}
+
+	for i := 0; i < cfg.Games; i++ {
+		gcfg := sim.Config{
+			Arena:      cfg.Arena,
+			Options:    cfg.Options,
+			Seed:       cfg.Seed + int64(i),
+			Concurrent: cfg.Concurrent,
+			CPU:        cfg.CPU,
+			Cookies:    cfg.Cookies,
+			Mines:      cfg.Mines,
+			Control:    cfg.Control,
+		}
+		g, err := sim.NewGame(gcfg, players)
+		if err != nil {
+			return Report{}, fmt.Errorf("could not create game %v: %v", i, err)
+		}
+		res := g.Run()
+
+		if res.Winner < 0 {
+			rep.Draws++
+		} else {
+			rep.Contenders[res.Winner].Wins++
+		}
+		for j, rr := range res.Robots {
+			rep.Contenders[j].AvgDamageTaken += rr.DamageTaken
+			rep.Contenders[j].AvgDamageDealt += rr.DamageDealt
+			rep.Contenders[j].AvgCPUTime += rr.CPUTime
+			if rr.CPUExceeded {
+				rep.Contenders[j].CPUExceeded++
+			}
+		}
+
+		if cfg.Store != nil {
+			if _, err := cfg.Store.Add(match(cfg, i, res)); err != nil {
+				return Report{}, fmt.Errorf("could not store game %v: %v", i, err)
+			}
+		}
+	}
+
+	for i := range rep.Contenders {
+		c := &rep.Contenders[i]
+		c.WinRate = float64(c.Wins) / float64(cfg.Games)
+		c.AvgDamageTaken /= float64(cfg.Games)
+		c.AvgDamageDealt /= float64(cfg.Games)
+		c.AvgCPUTime /= time.Duration(cfg.Games)
+	}
+
+	return rep, nil
+}
+
+// match returns the result of the game i, to be persisted. The winner scores
+// one point. In a draw, the point is shared by all the contenders.
+func match(cfg Config, i int, res sim.Result) results.Match {
+	m := results.Match{
+		Tournament: cfg.Tournament,
+		Time:       time.Now(),
+		Seed:       cfg.Seed + int64(i),
+		Robots:     make([]results.RobotResult, len(res.Robots)),
+	}
+	for j, rr := range res.Robots {
+		c := cfg.Contenders[j]
+		r := results.RobotResult{
+			Name:        c.Name,
+			Version:     c.Version,
+			DamageTaken: rr.DamageTaken,
+			DamageDealt: rr.DamageDealt,
+			ShotsFired:  rr.ShotsFired,
+		}
+		switch {
+		case res.Winner < 0:
+			r.Score = 1 / float64(len(res.Robots))
+		case res.Winner == j:
+			r.Score, r.Won = 1, true
+		}
+		if c.Telemetry != nil {
+			r.Telemetry = c.Telemetry(i)
+		}
+		m.Robots[j] = r
+	}
+	return m
+}
+
+// WriteTo writes a human readable table with the report. It implements the
+// io.WriterTo interface.
+func (rep Report) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	tw := tabwriter.NewWriter(cw, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "NAME\tWINS\tWIN RATE\tDAMAGE TAKEN\tDAMAGE DEALT\tCPU TIME\n")
+	for _, c := range rep.Contenders {
+		fmt.Fprintf(tw, "%v\t%v\t%.2f\t%.2f\t%.2f\t%v\n", c.Name, c.Wins, c.WinRate, c.AvgDamageTaken, c.AvgDamageDealt, c.AvgCPUTime.Round(time.Microsecond))
+	}
+	fmt.Fprintf(tw, "\ngames: %v, draws: %v\n", rep.Games, rep.Draws)
+
+	err := tw.Flush()
+	return cw.n, err
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,195 @@
+package selfplay
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/internal/rtbtest"
+	"github.com/jroimartin/rtb/results"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// turret is a strategy that rotates until the radar detects a robot and then
+// shoots it.
+func turret() rtb.Strategy {
+	return rtbtest.Turret{Speed: 1, Energy: 5}
+}
+
+// duck is a strategy that does nothing.
+func duck() rtb.Strategy {
+	return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+}
+
+func TestRun(t *testing.T) {
+	cfg := Config{
+		Contenders: []Contender{
+			{Name: "turret", New: turret},
+			{Name: "duck", New: duck},
+		},
+		Games: 3,
+	}
+
+	rep, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rep.Games != 3 || rep.Draws != 0 {
+		t.Errorf("unexpected games: games=%v draws=%v", rep.Games, rep.Draws)
+	}
+	if c := rep.Contenders[0]; c.Wins != 3 || c.WinRate != 1 || c.AvgDamageTaken != 0 {
+		t.Errorf("unexpected turret report: %#v", c)
+	}
+	if c := rep.Contenders[1]; c.Wins != 0 || c.AvgDamageDealt != 0 || c.AvgDamageTaken < 100 {
+		t.Errorf("unexpected duck report: %#v", c)
+	}
+
+	var buf bytes.Buffer
+	if _, err := rep.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "turret") {
+		t.Errorf("missing contender in report:\n%v", buf.String())
+	}
+}
+
+func TestRunConcurrent(t *testing.T) {
+	cfg := Config{
+		Contenders: []Contender{
+			{Name: "turret", New: turret},
+			{Name: "duck", New: duck},
+		},
+		Games:      3,
+		Concurrent: true,
+		CPU:        &sim.CPULimits{},
+	}
+
+	rep, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := rep.Contenders[0]; c.Wins != 3 || c.CPUExceeded != 0 || c.AvgCPUTime <= 0 {
+		t.Errorf("unexpected turret report: %#v", c)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if _, err := Run(Config{Games: 1}); err == nil {
+		t.Errorf("expected error without contenders")
+	}
+	if _, err := Run(Config{Contenders: []Contender{{Name: "duck", New: duck}}}); err == nil {
+		t.Errorf("expected error without games")
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	store, err := results.Open(filepath.Join(t.TempDir(), "results.jsonl"))
+	if err != nil {
+		t.Fatalf("could not open store: %v", err)
+	}
+	defer store.Close()
+
+	cfg := Config{
+		Contenders: []Contender{
+			{
+				Name:      "turret",
+				New:       turret,
+				Version:   "v2",
+				Telemetry: func(game int) string { return fmt.Sprintf("turret-%v.jsonl", game) },
+			},
+			{Name: "duck", New: duck, Version: "v1"},
+		},
+		Games:      2,
+		Seed:       10,
+		Store:      store,
+		Tournament: "test",
+	}
+	if _, err := Run(cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ms := store.Matches(results.Query{Tournament: "test"})
+	if len(ms) != 2 {
+		t.Fatalf("wrong number of matches: got=%v want=2", len(ms))
+	}
+	for i, m := range ms {
+		if m.Seed != int64(10+i) {
+			t.Errorf("wrong seed: got=%v want=%v", m.Seed, 10+i)
+		}
+		r := m.Robots[0]
+		if r.Name != "turret" || r.Version != "v2" || !r.Won || r.Score != 1 {
+			t.Errorf("unexpected turret result: %#v", r)
+		}
+		if want := fmt.Sprintf("turret-%v.jsonl", i); r.Telemetry != want {
+			t.Errorf("wrong telemetry: got=%q want=%q", r.Telemetry, want)
+		}
+		if r := m.Robots[1]; r.Won || r.Score != 0 || r.Telemetry != "" {
+			t.Errorf("unexpected duck result: %#v", r)
+		}
+	}
+}
+
+func TestRunControl(t *testing.T) {
+	c := sim.NewControl()
+	c.Pause()
+	cfg := Config{
+		Contenders: []Contender{
+			{Name: "turret", New: turret},
+			{Name: "duck", New: duck},
+		},
+		Games:   2,
+		Control: c,
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := Run(cfg)
+		done <- err
+	}()
+
+	c.Step()
+	for c.Ticks() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("run finished while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Ticks() <= 1 {
+		t.Errorf("unexpected ticks: %v", c.Ticks())
+	}
+}
+
+func TestOpponent(t *testing.T) {
+	var contenders []Contender
+	for _, name := range []string{"spinner", "duck"} {
+		c, err := Opponent(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		contenders = append(contenders, c)
+	}
+
+	rep, err := Run(Config{Contenders: contenders, Games: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := rep.Contenders[0]; c.Name != "spinner" || c.AvgDamageDealt == 0 {
+		t.Errorf("unexpected spinner report: %#v", c)
+	}
+
+	if _, err := Opponent("sniper"); err == nil {
+		t.Errorf("expected error for unknown opponent")
+	}
+}
@@ -0,0 +1,570 @@
+// Package serverlog reads and writes the log files of the RealTimeBattle
+// server, the ones replayed by its own replay viewer. Parse reconstructs the
+// state of the world after every tick, so real tournament games can be
+// rendered and analyzed with the same tools as the simulated ones. Writer
+// does the opposite, writing simulated games as server logs.
+//
+// A log file is a sequence of lines, whose first character is the kind of
+// the line:
+//
+//	H games robots sequences total    header of the tournament
+//	O name: value                     game option
+//	L id colour name                  robot of the tournament
+//	G sequence game                   start of a game
+//	A line                            line of the arena file of the game
+//	T time                            start of a tick
+//	R id x y angle cannon radar energy
+//	                                  position of a robot
+//	S id x y dx dy                    shot fired, with its velocity
+//	C id x y                          cookie placed
+//	M id x y                          mine placed
+//	P id text                         message printed by a robot
+//	D R id points position            robot died
+//	D C id, D M id, D S id            cookie, mine or shot removed
+//
+// The angles of the robot parts are absolute. Lines of unknown kinds are
+// ignored.
+package serverlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Log is a parsed log file.
+type Log struct {
+	// Header is the header of the tournament.
+	Header Header
+
+	// Options are the game options, by name, as written in the log.
+	Options map[string]string
+
+	// Robots are the robots of the tournament.
+	Robots []Robot
+
+	// Games are the games of the log, in order.
+	Games []*Game
+}
+
+// Header is the header of a tournament.
+type Header struct {
+	// GamesPerSequence is the number of games of every sequence.
+	GamesPerSequence int
+
+	// RobotsPerGame is the number of robots of every game.
+	RobotsPerGame int
+
+	// Sequences is the number of sequences.
+	Sequences int
+
+	// Robots is the number of robots of the tournament.
+	Robots int
+}
+
+// Robot is a robot of the tournament.
+type Robot struct {
+	// ID is the identifier of the robot in the log.
+	ID int
+
+	// Colour is the colour of the robot, as a hexadecimal RGB value.
+	Colour string
+
+	// Name is the name of the robot.
+	Name string
+}
+
+// Game is a game of the log.
+type Game struct {
+	// Sequence and Game are the number of the sequence and the number of
+	// the game within the sequence.
+	Sequence, Game int
+
+	// Arena is the arena of the game. It is nil if the log does not
+	// contain the arena file.
+	Arena *arena.Arena
+
+	// Robots are the robots that played the game, in the order they
+	// appear in the log.
+	Robots []Robot
+
+	// Ticks is the state of the world after every tick.
+	Ticks []Tick
+
+	// Deaths are the robots that died, in order.
+	Deaths []Death
+
+	// Prints are the messages printed by the robots.
+	Prints []Print
+}
+
+// Tick is the state of the world after a tick.
+type Tick struct {
+	// Time is the game time.
+	Time float64
+
+	// Robots are the robots alive, in the order of Game.Robots.
+	Robots []RobotState
+
+	// Shots are the shots travelling through the arena. Their positions
+	// are extrapolated from the position and the velocity logged when
+	// they were fired.
+	Shots []Shot
+
+	// Objects are the cookies and mines in the arena.
+	Objects []Object
+}
+
+// RobotState is the state of a robot in a Tick.
+type RobotState struct {
+	// ID is the identifier of the robot.
+	ID int
+
+	// Pos is the position of the robot.
+	Pos arena.Point
+
+	// Angle, CannonAngle and RadarAngle are the absolute angles of the
+	// robot, its cannon and its radar.
+	Angle, CannonAngle, RadarAngle float64
+
+	// Energy is the energy of the robot.
+	Energy float64
+}
+
+// Shot is a shot in a Tick.
+type Shot struct {
+	// ID is the identifier of the shot.
+	ID int
+
+	// Pos and Vel are the position and the velocity of the shot.
+	Pos, Vel arena.Point
+}
+
+// Object is a cookie or a mine in a Tick.
+type Object struct {
+	// ID is the identifier of the object.
+	ID int
+
+	// Kind is rtb.ObjectCookie or rtb.ObjectMine.
+	Kind rtb.Object
+
+	// Pos is the position of the object.
+	Pos arena.Point
+}
+
+// Death is the death of a robot.
+type Death struct {
+	// Time is the game time.
+	Time float64
+
+	// ID is the identifier of the robot.
+	ID int
+
+	// Points are the points scored by the robot in the game.
+	Points float64
+
+	// Position is the final position of the robot in the game, starting
+	// at 1.
+	Position int
+}
+
+// Print is a message printed by a robot.
+type Print struct {
+	// Time is the game time.
+	Time float64
+
+	// ID is the identifier of the robot.
+	ID int
+
+	// Text is the message.
+	Text string
+}
+
+// Load parses the log file at path.
+func Load(path string) (*Log, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open log: %v", err)
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Parse parses the log read from r.
+func Parse(r io.Reader) (*Log, error) {
+	p := &parser{log: &Log{Options: make(map[string]string)}}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		p.line++
+		if err := p.parseLine(s.Text()); err != nil {
+			return nil, fmt.Errorf("line %v: %v", p.line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("could not read log: %v", err)
+	}
+	if err := p.endGame(); err != nil {
+		return nil, fmt.Errorf("line %v: %v", p.line, err)
+	}
+
+	return p.log, nil
+}
+
+// parser is the state of the parser of a log.
+type parser struct {
+	log  *Log
+	line int
+
+	game    *Game
+	arena   strings.Builder
+	time    float64
+	pending bool
+	robots  map[int]RobotState
+	logged  map[int]bool
+	dying   []int
+	shots   map[int]firedShot
+	objects map[int]Object
+	order   []int
+}
+
+// firedShot is a shot and the time it was fired.
+type firedShot struct {
+	Shot
+	time float64
+}
+
+// parseLine parses a line of the log.
+func (p *parser) parseLine(line string) error {
+	if line == "" {
+		return nil
+	}
+	kind, rest := line[0], strings.TrimSpace(line[1:])
+
+	if kind != 'A' && p.arena.Len() > 0 {
+		if err := p.parseArena(); err != nil {
+			return err
+		}
+	}
+	if p.game == nil && strings.IndexByte("ATRSCMPD", kind) >= 0 {
+		return fmt.Errorf("%q line before the start of a game", kind)
+	}
+
+	switch kind {
+	case 'H':
+		v, err := ints(rest, 4)
+		if err != nil {
+			return fmt.Errorf("invalid header: %v", err)
+		}
+		p.log.Header = Header{GamesPerSequence: v[0], RobotsPerGame: v[1], Sequences: v[2], Robots: v[3]}
+	case 'O':
+		name, value, ok := strings.Cut(rest, ":")
+		if !ok {
+			return fmt.Errorf("invalid option %q", rest)
+		}
+		p.log.Options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	case 'L':
+		fields := strings.SplitN(rest, " ", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid robot %q", rest)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid robot ID: %v", err)
+		}
+		p.log.Robots = append(p.log.Robots, Robot{ID: id, Colour: fields[1], Name: fields[2]})
+	case 'G':
+		if err := p.endGame(); err != nil {
+			return err
+		}
+		v, err := ints(rest, 2)
+		if err != nil {
+			return fmt.Errorf("invalid game: %v", err)
+		}
+		p.startGame(v[0], v[1])
+	case 'A':
+		p.arena.WriteString(rest)
+		p.arena.WriteByte('\n')
+	case 'T':
+		t, err := strconv.ParseFloat(rest, 64)
+		if err != nil {
+			return fmt.Errorf("invalid time: %v", err)
+		}
+		p.flush()
+		p.time, p.pending = t, true
+	case 'R':
+		id, v, err := idFloats(rest, 6)
+		if err != nil {
+			return fmt.Errorf("invalid robot position: %v", err)
+		}
+		p.addRobot(RobotState{
+			ID:          id,
+			Pos:         arena.Point{X: v[0], Y: v[1]},
+			Angle:       v[2],
+			CannonAngle: v[3],
+			RadarAngle:  v[4],
+			Energy:      v[5],
+		})
+	case 'S':
+		id, v, err := idFloats(rest, 4)
+		if err != nil {
+			return fmt.Errorf("invalid shot: %v", err)
+		}
+		s := Shot{ID: id, Pos: arena.Point{X: v[0], Y: v[1]}, Vel: arena.Point{X: v[2], Y: v[3]}}
+		p.shots[id] = firedShot{Shot: s, time: p.time}
+	case 'C', 'M':
+		id, v, err := idFloats(rest, 2)
+		if err != nil {
+			return fmt.Errorf("invalid object: %v", err)
+		}
+		o := Object{ID: id, Kind: rtb.ObjectCookie, Pos: arena.Point{X: v[0], Y: v[1]}}
+		if kind == 'M' {
+			o.Kind = rtb.ObjectMine
+		}
+		p.objects[id] = o
+	case 'P':
+		idStr, text, _ := strings.Cut(rest, " ")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return fmt.Errorf("invalid robot ID: %v", err)
+		}
+		p.game.Prints = append(p.game.Prints, Print{Time: p.time, ID: id, Text: text})
+	case 'D':
+		return p.parseDeath(rest)
+	}
+	return nil
+}
+
+// parseArena parses the arena file of the current game.
+func (p *parser) parseArena() error {
+	a, err := arena.Parse(strings.NewReader(p.arena.String()))
+	p.arena.Reset()
+	if err != nil {
+		return fmt.Errorf("invalid arena: %v", err)
+	}
+	p.game.Arena = a
+	return nil
+}
+
+// parseDeath parses a D line.
+func (p *parser) parseDeath(rest string) error {
+	kind, args, _ := strings.Cut(rest, " ")
+	if kind == "R" {
+		id, v, err := idFloats(args, 2)
+		if err != nil {
+			return fmt.Errorf("invalid robot death: %v", err)
+		}
+		// Robots logged in the current tick, like the ones alive at
+		// the end of the game, are removed after it.
+		if p.logged[id] {
+			p.dying = append(p.dying, id)
+		} else {
+			delete(p.robots, id)
+		}
+		p.game.Deaths = append(p.game.Deaths, Death{Time: p.time, ID: id, Points: v[0], Position: int(v[1])})
+		return nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil {
+		return fmt.Errorf("invalid ID: %v", err)
+	}
+	switch kind {
+	case "S":
+		delete(p.shots, id)
+	case "C", "M":
+		delete(p.objects, id)
+	default:
+		return fmt.Errorf("unknown object %q", kind)
+	}
+	return nil
+}
+
+// startGame starts a new game.
+func (p *parser) startGame(seq, game int) {
+	p.game = &Game{Sequence: seq, Game: game}
+	p.time, p.pending = 0, false
+	p.robots = make(map[int]RobotState)
+	p.logged = make(map[int]bool)
+	p.dying = nil
+	p.shots = make(map[int]firedShot)
+	p.objects = make(map[int]Object)
+	p.order = nil
+}
+
+// endGame finishes the current game, if any.
+func (p *parser) endGame() error {
+	if p.game == nil {
+		return nil
+	}
+	if p.arena.Len() > 0 {
+		if err := p.parseArena(); err != nil {
+			return err
+		}
+	}
+	p.flush()
+	p.log.Games = append(p.log.Games, p.game)
+	p.game = nil
+	return nil
+}
+
+// addRobot updates the state of a robot, adding it to the game the first
+// time it appears.
+func (p *parser) addRobot(r RobotState) {
+	if _, ok := p.robots[r.ID]; !ok && !p.played(r.ID) {
+		p.order = append(p.order, r.ID)
+		robot := Robot{ID: r.ID}
+		for _, lr := range p.log.Robots {
+			if lr.ID == r.ID {
+				robot = lr
+				break
+			}
+		}
+		p.game.Robots = append(p.game.Robots, robot)
+	}
+	p.robots[r.ID] = r
+	p.logged[r.ID] = true
+}
+
+// played returns whether the robot id has appeared in the current game.
+func (p *parser) played(id int) bool {
+	for _, o := range p.order {
+		if o == id {
+			return true
+		}
+	}
+	return false
+}
+
+// flush appends the state of the world to the ticks of the current game,
+// if a tick is pending.
+func (p *parser) flush() {
+	if !p.pending {
+		return
+	}
+	p.pending = false
+
+	t := Tick{Time: p.time}
+	for _, id := range p.order {
+		if r, ok := p.robots[id]; ok {
+			t.Robots = append(t.Robots, r)
+		}
+	}
+	for _, s := range p.shots {
+		s.Pos = s.Pos.Add(s.Vel.Mul(p.time - s.time))
+		t.Shots = append(t.Shots, s.Shot)
+	}
+	sort.Slice(t.Shots, func(i, j int) bool { return t.Shots[i].ID < t.Shots[j].ID })
+	for _, o := range p.objects {
+		t.Objects = append(t.Objects, o)
+	}
+	sort.Slice(t.Objects, func(i, j int) bool { return t.Objects[i].ID < t.Objects[j].ID })
+	p.game.Ticks = append(p.game.Ticks, t)
+
+	for _, id := range p.dying {
+		delete(p.robots, id)
+	}
+	p.dying = nil
+	clear(p.logged)
+}
+
+// ints parses n space-separated integers.
+func ints(s string, n int) ([]int, error) {
+	fields := strings.Fields(s)
+	if len(fields) != n {
+		return nil, fmt.Errorf("got %v fields, want %v", len(fields), n)
+	}
+	v := make([]int, n)
+	for i, f := range fields {
+		x, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = x
+	}
+	return v, nil
+}
+
+// idFloats parses an integer ID followed by n space-separated floats.
+func idFloats(s string, n int) (int, []float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != n+1 {
+		return 0, nil, fmt.Errorf("got %v fields, want %v", len(fields), n+1)
+	}
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	v := make([]float64, n)
+	for i, f := range fields[1:] {
+		x, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		v[i] = x
+	}
+	return id, v, nil
+}
+
+// Frames returns the ticks of g as simulator frames, so they can be
+// rendered with the render package or compared with simulated games. The
+// radius of the cookies and the mines is read from the options of l, if
+// present. The frames have no radar hits nor debug shapes.
+func (l *Log) Frames(g *Game) []sim.Frame {
+	cookieRadius := l.option("Cookie radius", 0.3)
+	mineRadius := l.option("Mine radius", 0.3)
+
+	frames := make([]sim.Frame, len(g.Ticks))
+	for i, t := range g.Ticks {
+		f := sim.Frame{Time: t.Time, Arena: g.Arena}
+
+		alive := make(map[int]RobotState)
+		for _, r := range t.Robots {
+			alive[r.ID] = r
+		}
+		for _, robot := range g.Robots {
+			rf := sim.RobotFrame{Name: robot.Name, Colour: robot.Colour}
+			if r, ok := alive[robot.ID]; ok {
+				rf.Alive = true
+				rf.Energy = r.Energy
+				rf.Pos = r.Pos
+				rf.Angle = r.Angle
+				rf.CannonAngle = r.CannonAngle
+				rf.RadarAngle = r.RadarAngle
+			}
+			f.Robots = append(f.Robots, rf)
+		}
+
+		for _, s := range t.Shots {
+			f.Shots = append(f.Shots, sim.ShotFrame{Pos: s.Pos, Vel: s.Vel})
+		}
+		for _, o := range t.Objects {
+			radius := cookieRadius
+			if o.Kind == rtb.ObjectMine {
+				radius = mineRadius
+			}
+			f.Objects = append(f.Objects, sim.Object{Kind: o.Kind, Pos: o.Pos, Radius: radius})
+		}
+		frames[i] = f
+	}
+	return frames
+}
+
+// option returns the value of the numeric option name or def if it is not
+// present.
+func (l *Log) option(name string, def float64) float64 {
+	v, err := strconv.ParseFloat(l.Options[name], 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,213 @@
+package serverlog
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/opponents"
+	"github.com/jroimartin/rtb/sim"
+)
+
+func TestLoad(t *testing.T) {
+	l, err := Load("testdata/game.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := (Header{1, 2, 1, 2}); l.Header != want {
+		t.Errorf("unexpected header: got=%+v want=%+v", l.Header, want)
+	}
+	if v := l.Options["Robot max acceleration"]; v != "2" {
+		t.Errorf("unexpected option: got=%q want=%q", v, "2")
+	}
+	wantRobots := []Robot{{0, "ff0000", "turret bot"}, {1, "0000ff", "spinner"}}
+	if !reflect.DeepEqual(l.Robots, wantRobots) {
+		t.Errorf("unexpected robots: got=%+v want=%+v", l.Robots, wantRobots)
+	}
+	if len(l.Games) != 2 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(l.Games), 2)
+	}
+
+	g := l.Games[0]
+	if g.Arena == nil || g.Arena.Boundary.Max != (arena.Point{X: 20, Y: 20}) {
+		t.Errorf("unexpected arena: %+v", g.Arena)
+	}
+	if !reflect.DeepEqual(g.Robots, wantRobots) {
+		t.Errorf("unexpected game robots: got=%+v want=%+v", g.Robots, wantRobots)
+	}
+	if len(g.Ticks) != 4 {
+		t.Fatalf("wrong number of ticks: got=%v want=%v", len(g.Ticks), 4)
+	}
+
+	tick := g.Ticks[1]
+	if len(tick.Robots) != 2 || tick.Robots[0].RadarAngle != 0.2 {
+		t.Errorf("unexpected robots at tick 1: %+v", tick.Robots)
+	}
+	if len(tick.Objects) != 1 || tick.Objects[0].Kind != rtb.ObjectCookie {
+		t.Errorf("unexpected objects at tick 1: %+v", tick.Objects)
+	}
+
+	// The shot is extrapolated from its velocity.
+	tick = g.Ticks[2]
+	if len(tick.Shots) != 1 || math.Abs(tick.Shots[0].Pos.X-5.6) > 1e-9 {
+		t.Errorf("unexpected shots at tick 2: %+v", tick.Shots)
+	}
+	if len(tick.Objects) != 1 || tick.Objects[0].Kind != rtb.ObjectMine {
+		t.Errorf("unexpected objects at tick 2: %+v", tick.Objects)
+	}
+
+	// The winner is kept in the last tick.
+	tick = g.Ticks[3]
+	if len(tick.Robots) != 1 || tick.Robots[0].ID != 0 || len(tick.Shots) != 0 {
+		t.Errorf("unexpected tick 3: %+v", tick)
+	}
+
+	wantDeaths := []Death{{0.15, 1, 0, 2}, {0.15, 0, 1, 1}}
+	if !reflect.DeepEqual(g.Deaths, wantDeaths) {
+		t.Errorf("unexpected deaths: got=%+v want=%+v", g.Deaths, wantDeaths)
+	}
+	wantPrints := []Print{{0.05, 0, "hello world"}}
+	if !reflect.DeepEqual(g.Prints, wantPrints) {
+		t.Errorf("unexpected prints: got=%+v want=%+v", g.Prints, wantPrints)
+	}
+
+	g = l.Games[1]
+	if g.Arena != nil || len(g.Robots) != 1 || len(g.Ticks) != 1 {
+		t.Errorf("unexpected second game: %+v", g)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	l, err := Load("testdata/game.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	frames := l.Frames(l.Games[0])
+	if len(frames) != 4 {
+		t.Fatalf("wrong number of frames: got=%v want=%v", len(frames), 4)
+	}
+
+	f := frames[0]
+	if len(f.Robots) != 2 || f.Robots[0].Name != "turret bot" || f.Robots[1].Colour != "0000ff" {
+		t.Errorf("unexpected robots: %+v", f.Robots)
+	}
+	if len(f.Objects) != 1 || f.Objects[0].Radius != 0.25 {
+		t.Errorf("unexpected objects: %+v", f.Objects)
+	}
+
+	// Dead robots are kept in the frames.
+	f = frames[3]
+	if len(f.Robots) != 2 || !f.Robots[0].Alive || f.Robots[1].Alive {
+		t.Errorf("unexpected robots in last frame: %+v", f.Robots)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+	}{
+		{"before game", "T 0\n"},
+		{"bad header", "H 1 2\n"},
+		{"bad option", "O Robot max acceleration 2\n"},
+		{"bad robot", "G 1 1\nR 0 1 2 3\n"},
+		{"bad death", "G 1 1\nD X 0\n"},
+		{"bad arena", "G 1 1\nA boundary 0\nT 0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.log)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var frames []sim.Frame
+	var b bytes.Buffer
+	lw := NewWriter(&b)
+
+	players := []*sim.Player{
+		sim.NewPlayer(opponents.Spinner(), rtb.ListenSettings{}),
+		sim.NewPlayer(opponents.Duck(), rtb.ListenSettings{}),
+	}
+	opts := sim.DefaultOptions()
+	opts.Timeout = 10
+	for i := 0; i < 2; i++ {
+		cfg := sim.Config{
+			Options: opts,
+			Seed:    int64(i + 1),
+			Cookies: &sim.Spawn{Frequency: 1, MinEnergy: 10, MaxEnergy: 15},
+			Frames: func(f sim.Frame) {
+				frames = append(frames, f)
+				lw.Add(f)
+			},
+		}
+		g, err := sim.NewGame(cfg, players)
+		if err != nil {
+			t.Fatalf("could not create game: %v", err)
+		}
+		g.Run()
+	}
+	if err := lw.Close(); err != nil {
+		t.Fatalf("could not close writer: %v", err)
+	}
+
+	l, err := Parse(&b)
+	if err != nil {
+		t.Fatalf("could not parse log: %v", err)
+	}
+	if len(l.Games) != 2 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(l.Games), 2)
+	}
+	if l.Robots[0].Name != "spinner" || l.Robots[1].Colour != "ffff00" {
+		t.Errorf("unexpected robots: %+v", l.Robots)
+	}
+
+	var got []sim.Frame
+	for _, g := range l.Games {
+		if g.Arena == nil {
+			t.Fatalf("missing arena")
+		}
+		got = append(got, l.Frames(g)...)
+	}
+	if len(got) != len(frames) {
+		t.Fatalf("wrong number of frames: got=%v want=%v", len(got), len(frames))
+	}
+
+	var shots, objects int
+	for i, want := range frames {
+		f := got[i]
+		if math.Abs(f.Time-want.Time) > 1e-4 {
+			t.Fatalf("frame %v: unexpected time: got=%v want=%v", i, f.Time, want.Time)
+		}
+		for j, r := range want.Robots {
+			if f.Robots[j].Alive != r.Alive || r.Alive && f.Robots[j].Pos.Sub(r.Pos).Len() > 1e-3 {
+				t.Fatalf("frame %v: unexpected robot %v: got=%+v want=%+v", i, j, f.Robots[j], r)
+			}
+		}
+		if len(f.Shots) != len(want.Shots) || len(f.Objects) != len(want.Objects) {
+			t.Fatalf("frame %v: unexpected shots or objects: got=%v/%v want=%v/%v",
+				i, len(f.Shots), len(f.Objects), len(want.Shots), len(want.Objects))
+		}
+		shots += len(f.Shots)
+		objects += len(f.Objects)
+	}
+	if shots == 0 || objects == 0 {
+		t.Errorf("the games have no shots or no objects")
+	}
+
+	for _, g := range l.Games {
+		if n := len(g.Deaths); n != 2 {
+			t.Errorf("wrong number of deaths: got=%v want=%v", n, 2)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package serverlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Writer writes simulated games as a log file of the server, so they can be
+// watched with its replay viewer and processed by the tools that read its
+// logs. Games are written frame by frame with Add, which can be used as
+// sim.Config.Frames:
+//
+//	lw := serverlog.NewWriter(f)
+//	g, err := sim.NewGame(sim.Config{Frames: lw.Add}, players)
+//	...
+//	g.Run()
+//	err = lw.Close()
+//
+// A new game starts when the time of a frame is not after the time of the
+// previous one. All the games must have the same robots, in the same order.
+// The robots are identified by their index in the frames. The log contains
+// no game options nor messages printed by the robots.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+
+	games int
+	time  float64
+
+	alive   []bool
+	dead    int
+	shots   map[int]bool
+	objects map[int]rtb.Object
+}
+
+// NewWriter returns a Writer that writes the log to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Add writes the state of the world in f.
+func (lw *Writer) Add(f sim.Frame) {
+	if lw.err != nil {
+		return
+	}
+
+	if lw.games == 0 || f.Time <= lw.time {
+		lw.startGame(f)
+	}
+	lw.time = f.Time
+
+	lw.printf("T %v\n", num(f.Time))
+	for i, r := range f.Robots {
+		if r.Alive {
+			lw.printf("R %v %v %v %v %v %v %v\n", i, num(r.Pos.X), num(r.Pos.Y),
+				num(r.Angle), num(r.CannonAngle), num(r.RadarAngle), num(r.Energy))
+		}
+	}
+
+	shots := make(map[int]bool)
+	for _, s := range f.Shots {
+		shots[s.ID] = true
+		if !lw.shots[s.ID] {
+			lw.printf("S %v %v %v %v %v\n", s.ID, num(s.Pos.X), num(s.Pos.Y), num(s.Vel.X), num(s.Vel.Y))
+		}
+	}
+	for _, id := range removed(lw.shots, shots) {
+		lw.printf("D S %v\n", id)
+	}
+	lw.shots = shots
+
+	objects := make(map[int]rtb.Object)
+	for _, o := range f.Objects {
+		objects[o.ID] = o.Kind
+		if _, ok := lw.objects[o.ID]; ok {
+			continue
+		}
+		kind := "C"
+		if o.Kind == rtb.ObjectMine {
+			kind = "M"
+		}
+		lw.printf("%v %v %v %v\n", kind, o.ID, num(o.Pos.X), num(o.Pos.Y))
+	}
+	for _, id := range removed(lw.objects, objects) {
+		kind := "C"
+		if lw.objects[id] == rtb.ObjectMine {
+			kind = "M"
+		}
+		lw.printf("D %v %v\n", kind, id)
+	}
+	lw.objects = objects
+
+	var dying []int
+	for i, r := range f.Robots {
+		if i < len(lw.alive) && lw.alive[i] && !r.Alive {
+			dying = append(dying, i)
+		}
+	}
+	lw.kill(dying, len(f.Robots))
+}
+
+// Close finishes the last game, logging the surviving robots, and flushes
+// the log. It does not close the underlying writer. It returns the first
+// error found writing the log.
+func (lw *Writer) Close() error {
+	lw.endGame()
+	if lw.err == nil {
+		if err := lw.w.Flush(); err != nil {
+			lw.err = fmt.Errorf("could not write log: %v", err)
+		}
+	}
+	return lw.err
+}
+
+// startGame finishes the current game and starts a new one with the robots
+// of f. The header and the robots of the tournament are written before the
+// first game.
+func (lw *Writer) startGame(f sim.Frame) {
+	if lw.games == 0 {
+		n := len(f.Robots)
+		lw.printf("H 1 %v 1 %v\n", n, n)
+		for i, r := range f.Robots {
+			colour := r.Colour
+			if colour == "" {
+				colour = "000000"
+			}
+			lw.printf("L %v %v %v\n", i, colour, r.Name)
+		}
+	} else {
+		lw.endGame()
+	}
+
+	lw.games++
+	lw.printf("G 1 %v\n", lw.games)
+	if f.Arena != nil {
+		var b bytes.Buffer
+		if _, err := f.Arena.WriteTo(&b); err != nil {
+			lw.err = fmt.Errorf("could not write arena: %v", err)
+			return
+		}
+		for _, line := range bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n")) {
+			lw.printf("A %s\n", line)
+		}
+	}
+
+	lw.alive = make([]bool, len(f.Robots))
+	for i := range lw.alive {
+		lw.alive[i] = true
+	}
+	lw.dead = 0
+	lw.shots = nil
+	lw.objects = nil
+}
+
+// endGame logs the robots alive at the end of the current game.
+func (lw *Writer) endGame() {
+	var survivors []int
+	for i, alive := range lw.alive {
+		if alive {
+			survivors = append(survivors, i)
+		}
+	}
+	lw.kill(survivors, len(lw.alive))
+}
+
+// kill logs the death of the robots ids, that die at the same time. They
+// share the position and get one point for every robot that died before.
+func (lw *Writer) kill(ids []int, robots int) {
+	if len(ids) == 0 {
+		return
+	}
+	position := robots - lw.dead - len(ids) + 1
+	for _, id := range ids {
+		lw.printf("D R %v %v %v\n", id, lw.dead, position)
+		lw.alive[id] = false
+	}
+	lw.dead += len(ids)
+}
+
+// printf writes a formatted line, unless a previous write failed.
+func (lw *Writer) printf(format string, a ...any) {
+	if lw.err != nil {
+		return
+	}
+	if _, err := fmt.Fprintf(lw.w, format, a...); err != nil {
+		lw.err = fmt.Errorf("could not write log: %v", err)
+	}
+}
+
+// removed returns the sorted keys of prev that are not in cur.
+func removed[V, W any](prev map[int]V, cur map[int]W) []int {
+	var ids []int
+	for id := range prev {
+		if _, ok := cur[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// num formats v for the log.
+func num(v float64) string {
+	return fmt.Sprintf("%.6g", v)
+}
@@ -0,0 +1,221 @@
+// Package shutdown stops robots in an orderly way, whatever the reason: the
+// server sending ExitRobot, the process receiving SIGINT or SIGTERM, or a
+// fatal error in the robot.
+//
+// Subsystems register the functions that stop them. On shutdown, they are
+// called in the order they were added, so the strategy can be stopped before
+// the telemetry is flushed and the output is closed, and the process exits
+// when they finish or when the deadline is reached, whichever comes first.
+// This way, a robot exits on its own before the server kills it, with its
+// logs and recordings complete:
+//
+//	m := shutdown.New(shutdown.Config{Logger: r.Logger()})
+//	m.Add("telemetry", shutdown.Closer(rec))
+//	m.Add("output", shutdown.Flusher(out))
+//	m.Add("log", shutdown.Closer(logFile))
+//	m.Watch()
+//	r.AddObserver(m)
+package shutdown
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Exit codes.
+const (
+	// CodeExit is the exit code after an ExitRobot message.
+	CodeExit = 0
+
+	// CodeSignal is the exit code after a signal.
+	CodeSignal = 1
+
+	// CodeFatal is the exit code after a fatal error.
+	CodeFatal = 2
+
+	// CodeStalled is the exit code after the server stalls.
+	CodeStalled = 3
+)
+
+// Config is the configuration of a Manager.
+type Config struct {
+	// Timeout is the maximum time spent stopping the subsystems. If
+	// zero, 2s is used.
+	Timeout time.Duration
+
+	// Exit is called with the exit code when the shutdown finishes. If
+	// nil, os.Exit is used.
+	Exit func(code int)
+
+	// Logger receives the shutdown events. If nil, they are discarded.
+	Logger *slog.Logger
+
+	// ExitOnStall makes the Manager shut down with CodeStalled when it
+	// observes a MessageServerStalled, delivered by Listen when
+	// rtb.ListenSettings.StallTimeout is set.
+	ExitOnStall bool
+}
+
+// StopFunc stops a subsystem. It must return when ctx is done.
+type StopFunc func(ctx context.Context) error
+
+// step is a subsystem to stop.
+type step struct {
+	name string
+	stop StopFunc
+}
+
+// Manager coordinates the shutdown of a robot. Manager methods can be called
+// concurrently.
+type Manager struct {
+	rtb.NopCommand
+
+	cfg Config
+
+	mu    sync.Mutex
+	steps []step
+
+	once sync.Once
+	done chan struct{}
+	code int
+}
+
+// New returns a Manager with the configuration cfg.
+func New(cfg Config) *Manager {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.Exit == nil {
+		cfg.Exit = os.Exit
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(rtb.DiscardHandler{})
+	}
+	return &Manager{cfg: cfg, done: make(chan struct{})}
+}
+
+// Add adds a subsystem to stop on shutdown. Subsystems are stopped in the
+// order they were added.
+func (m *Manager) Add(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.steps = append(m.steps, step{name, stop})
+}
+
+// Closer returns a StopFunc that closes c.
+func Closer(c io.Closer) StopFunc {
+	return func(context.Context) error {
+		return c.Close()
+	}
+}
+
+// Flusher returns a StopFunc that flushes f, e.g. a bufio.Writer used as the
+// output of the robot.
+func Flusher(f interface{ Flush() error }) StopFunc {
+	return func(context.Context) error {
+		return f.Flush()
+	}
+}
+
+// Shutdown stops the subsystems and exits with code. Only the first call has
+// effect; the next ones wait for it to finish. If Config.Exit returns, so
+// does Shutdown.
+func (m *Manager) Shutdown(code int, reason string) {
+	m.once.Do(func() {
+		m.cfg.Logger.Info("shutting down", "reason", reason, "code", code)
+		m.stop()
+		m.code = code
+		close(m.done)
+		m.cfg.Exit(code)
+	})
+	<-m.done
+}
+
+// stop calls the stop functions until they finish or the timeout expires.
+func (m *Manager) stop() {
+	m.mu.Lock()
+	steps := append([]step(nil), m.steps...)
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+	defer cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for _, s := range steps {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.stop(ctx); err != nil {
+				m.cfg.Logger.Error("could not stop subsystem", "subsystem", s.name, "err", err)
+			}
+		}
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		m.cfg.Logger.Error("shutdown timed out", "timeout", m.cfg.Timeout)
+	}
+}
+
+// Fatal logs err and shuts down with CodeFatal.
+func (m *Manager) Fatal(err error) {
+	m.cfg.Logger.Error("fatal error", "err", err)
+	m.Shutdown(CodeFatal, "fatal error")
+}
+
+// Done returns a channel that is closed when the subsystems have been stopped.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Code returns the exit code. It is only valid after Done is closed.
+func (m *Manager) Code() int {
+	<-m.done
+	return m.code
+}
+
+// Watch shuts down with CodeSignal when the process receives one of sigs. If
+// no signals are passed, SIGINT and SIGTERM are used.
+func (m *Manager) Watch(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		select {
+		case sig := <-c:
+			m.Shutdown(CodeSignal, "signal "+sig.String())
+		case <-m.done:
+		}
+		signal.Stop(c)
+	}()
+}
+
+// Message shuts down with CodeExit when msg is ExitRobot and, if
+// Config.ExitOnStall is set, with CodeStalled when msg is ServerStalled. It
+// implements the rtb.Observer interface. Observers are called before the
+// strategy, so the strategy does not get the message if Config.Exit exits
+// the process.
+func (m *Manager) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageExitRobot, *rtb.MessageExitRobot:
+		m.Shutdown(CodeExit, "exit robot")
+	case rtb.MessageServerStalled:
+		if m.cfg.ExitOnStall {
+			m.Shutdown(CodeStalled, "server stalled")
+		}
+	}
+}
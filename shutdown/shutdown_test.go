@@ -0,0 +1,126 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+)
+
+// recorder records the stopped subsystems and the exit code.
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+	exits   []int
+}
+
+func (rec *recorder) stop(name string, err error) StopFunc {
+	return func(context.Context) error {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.stopped = append(rec.stopped, name)
+		return err
+	}
+}
+
+func (rec *recorder) exit(code int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.exits = append(rec.exits, code)
+}
+
+func TestExitRobot(t *testing.T) {
+	var rec recorder
+	m := New(Config{Exit: rec.exit})
+	m.Add("strategy", rec.stop("strategy", nil))
+	m.Add("telemetry", rec.stop("telemetry", errors.New("disk full")))
+	m.Add("output", rec.stop("output", nil))
+
+	r := rtb.NewRobot(nil, &strings.Builder{})
+	r.AddObserver(m)
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(s, rtb.MessageGameFinishes{})
+	select {
+	case <-m.Done():
+		t.Fatalf("shutdown before ExitRobot")
+	default:
+	}
+	r.Deliver(s, &rtb.MessageExitRobot{})
+
+	// Only the first shutdown has effect.
+	m.Fatal(errors.New("late error"))
+
+	if got := strings.Join(rec.stopped, " "); got != "strategy telemetry output" {
+		t.Errorf("wrong stop order: got=%v want=strategy telemetry output", got)
+	}
+	if len(rec.exits) != 1 || rec.exits[0] != CodeExit {
+		t.Errorf("wrong exits: got=%v want=[%v]", rec.exits, CodeExit)
+	}
+	if m.Code() != CodeExit {
+		t.Errorf("wrong code: got=%v want=%v", m.Code(), CodeExit)
+	}
+}
+
+func TestStall(t *testing.T) {
+	for _, exitOnStall := range []bool{false, true} {
+		var rec recorder
+		m := New(Config{Exit: rec.exit, ExitOnStall: exitOnStall})
+		m.Message(rtb.MessageServerStalled{Elapsed: 5})
+
+		var want []int
+		if exitOnStall {
+			want = []int{CodeStalled}
+		}
+		if fmt.Sprint(rec.exits) != fmt.Sprint(want) {
+			t.Errorf("wrong exits with ExitOnStall=%v: got=%v want=%v", exitOnStall, rec.exits, want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var rec recorder
+	m := New(Config{Timeout: 50 * time.Millisecond, Exit: rec.exit})
+	m.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return ctx.Err()
+	})
+	m.Add("next", rec.stop("next", nil))
+
+	start := time.Now()
+	m.Fatal(errors.New("boom"))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("shutdown took too long: %v", elapsed)
+	}
+	if len(rec.stopped) != 0 {
+		t.Errorf("subsystems stopped after the timeout: %v", rec.stopped)
+	}
+	if len(rec.exits) != 1 || rec.exits[0] != CodeFatal {
+		t.Errorf("wrong exits: got=%v want=[%v]", rec.exits, CodeFatal)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var rec recorder
+	m := New(Config{Exit: rec.exit})
+	m.Add("strategy", rec.stop("strategy", nil))
+	m.Watch(syscall.SIGUSR1)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("could not send signal: %v", err)
+	}
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("signal did not shut down")
+	}
+	if m.Code() != CodeSignal {
+		t.Errorf("wrong code: got=%v want=%v", m.Code(), CodeSignal)
+	}
+}
@@ -0,0 +1,135 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+)
+
+// Collisions configures the collisions of the robots with the walls and with
+// other robots. Its fields follow the robot options of the RealTimeBattle
+// server. The hardness and the bounce coefficient of the walls are given by
+// their material in the arena.
+//
+// The front of a robot is the sector of FrontSize radians centered on its
+// direction, and the rest of the robot is its side. When a robot hits
+// something, the component of its velocity along the normal of the
+// collision bounces back, scaled by the bounce coefficients of both sides
+// of the collision. The robot loses energy proportional to the impact
+// speed, scaled by the hardness of what it hits, and reduced by the
+// protection of its part that is hit. So, ramming another robot with the
+// front hurts it more than it hurts the rammer.
+type Collisions struct {
+	// Hardness, Protection and Bounce are the hardness, the protection
+	// and the bounce coefficient of the side of the robots.
+	Hardness   float64
+	Protection float64
+	Bounce     float64
+
+	// FrontHardness, FrontProtection and FrontBounce are the hardness,
+	// the protection and the bounce coefficient of the front of the
+	// robots.
+	FrontHardness   float64
+	FrontProtection float64
+	FrontBounce     float64
+
+	// FrontSize is the angular size of the front of the robots, in
+	// radians.
+	FrontSize float64
+}
+
+// DefaultCollisions returns the default collision options of the
+// RealTimeBattle server.
+func DefaultCollisions() Collisions {
+	return Collisions{
+		Hardness:        0.5,
+		Protection:      0.5,
+		Bounce:          0.7,
+		FrontHardness:   0.9,
+		FrontProtection: 0.9,
+		FrontBounce:     0.7,
+		FrontSize:       2 * math.Pi / 3,
+	}
+}
+
+// armour are the collision properties of a part of a robot.
+type armour struct {
+	hardness   float64
+	protection float64
+	bounce     float64
+}
+
+// armour returns the collision properties of the part of r facing p.
+func (c Collisions) armour(r *robot, p point) armour {
+	if math.Abs(r.relAngle(p)) <= c.FrontSize/2 {
+		return armour{c.FrontHardness, c.FrontProtection, c.FrontBounce}
+	}
+	return armour{c.Hardness, c.Protection, c.Bounce}
+}
+
+// hitWall bounces r off a wall of material m whose closest point is q.
+func (g *Game) hitWall(r *robot, q point, m arena.Material) {
+	c := g.cfg.Collisions
+	n := unit(r.pos.Sub(q), r.vel.Mul(-1))
+	a := c.armour(r, q)
+
+	if impact := -r.vel.Dot(n); impact > 0 {
+		r.vel = r.vel.Add(n.Mul((1 + m.BounceCoeff*a.bounce) * impact))
+		g.injure(r, (1-a.protection)*m.Hardness*impact)
+	}
+	g.send(r, rtb.MessageCollision{Object: rtb.ObjectWall, Angle: r.relAngle(q)})
+}
+
+// hitRobot bounces r and other off each other. Both robots have the same
+// mass, so they share the impulse.
+func (g *Game) hitRobot(r, other *robot) {
+	c := g.cfg.Collisions
+	n := unit(other.pos.Sub(r.pos), r.vel)
+	ar, ao := c.armour(r, other.pos), c.armour(other, r.pos)
+
+	if impact := r.vel.Sub(other.vel).Dot(n); impact > 0 {
+		j := (1 + ar.bounce*ao.bounce) * impact / 2
+		r.vel = r.vel.Sub(n.Mul(j))
+		other.vel = other.vel.Add(n.Mul(j))
+		g.injure(r, (1-ar.protection)*ao.hardness*impact)
+		g.injure(other, (1-ao.protection)*ar.hardness*impact)
+	}
+	g.send(r, rtb.MessageCollision{Object: rtb.ObjectRobot, Angle: r.relAngle(other.pos)})
+	g.send(other, rtb.MessageCollision{Object: rtb.ObjectRobot, Angle: other.relAngle(r.pos)})
+}
+
+// wallMaterial returns the material of w.
+func wallMaterial(w arena.Wall) arena.Material {
+	switch w := w.(type) {
+	case arena.Line:
+		return w.Material
+	case arena.Circle:
+		return w.Material
+	case arena.InnerCircle:
+		return w.Material
+	case arena.Arc:
+		return w.Material
+	case arena.Polygon:
+		return w.Material
+	}
+	return arena.DefaultMaterial
+}
+
+// injure takes energy from r.
+func (g *Game) injure(r *robot, energy float64) {
+	r.energy -= energy
+	r.result.DamageTaken += energy
+}
+
+// unit returns the unit vector in the direction of v or, if v is zero, in
+// the direction of fallback. It returns the zero vector if both are zero.
+func unit(v, fallback point) point {
+	if l := v.Len(); l > 0 {
+		return v.Mul(1 / l)
+	}
+	if l := fallback.Len(); l > 0 {
+		return fallback.Mul(1 / l)
+	}
+	return point{}
+}
@@ -0,0 +1,89 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/rtb"
+)
+
+// command is a command sent by a robot.
+type command struct {
+	// Keyword is the command name, e.g. "Rotate".
+	Keyword string
+
+	// Part is the robot part affected by rotation commands.
+	Part rtb.Part
+
+	// Args are the numeric arguments of the command, excluding Part.
+	Args []float64
+
+	// Text is the argument of Name, Colour, Print and Debug.
+	Text string
+}
+
+// numArgs is the number of numeric arguments of each command, excluding the
+// robot part of rotation commands.
+var numArgs = map[string]int{
+	"RobotOption":  2,
+	"Rotate":       1,
+	"RotateTo":     2,
+	"RotateAmount": 2,
+	"Sweep":        3,
+	"Accelerate":   1,
+	"Brake":        1,
+	"Shoot":        1,
+	"DebugLine":    4,
+	"DebugCircle":  3,
+}
+
+// parseCommand parses a command sent by a robot.
+func parseCommand(s string) (command, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return command{}, errors.New("empty string")
+	}
+
+	keyword, rest, _ := strings.Cut(s, " ")
+	cmd := command{Keyword: keyword}
+
+	switch keyword {
+	case "Name", "Colour", "Print", "Debug":
+		cmd.Text = rest
+		return cmd, nil
+	}
+
+	n, ok := numArgs[keyword]
+	if !ok {
+		return command{}, fmt.Errorf("unknown command %q", keyword)
+	}
+
+	fields := strings.Fields(rest)
+	switch keyword {
+	case "Rotate", "RotateTo", "RotateAmount", "Sweep":
+		if len(fields) == 0 {
+			return command{}, errors.New("wrong number of arguments")
+		}
+		part, err := strconv.ParseInt(fields[0], 10, 0)
+		if err != nil {
+			return command{}, fmt.Errorf("could not parse robot part %q: %v", fields[0], err)
+		}
+		cmd.Part = rtb.Part(part)
+		fields = fields[1:]
+	}
+
+	if len(fields) != n {
+		return command{}, errors.New("wrong number of arguments")
+	}
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return command{}, fmt.Errorf("could not parse argument %q: %v", f, err)
+		}
+		cmd.Args = append(cmd.Args, v)
+	}
+
+	return cmd, nil
+}
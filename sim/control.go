@@ -0,0 +1,144 @@
+package sim
+
+import (
+	"sync"
+	"time"
+)
+
+// Control controls the pace of the games run with Game.Run. It can pause a
+// game, let it advance one tick at a time and run it at a multiple of real
+// time. A Control can be shared by a sequence of games, e.g. the games of a
+// self-play run. Control methods can be called concurrently.
+//
+// While a game is paused, its state can be inspected from other goroutines
+// once Ticks reports the expected tick:
+//
+//	c := sim.NewControl()
+//	c.Pause()
+//	go g.Run()
+//	c.Step()
+//	for c.Ticks() < 1 {
+//		time.Sleep(time.Millisecond)
+//	}
+//	fmt.Println(g.Time())
+type Control struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	paused bool
+	steps  int
+	speed  float64
+	next   time.Time
+	ticks  int64
+}
+
+// NewControl returns a Control that runs the games as fast as possible.
+func NewControl() *Control {
+	c := &Control{}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Pause pauses the games. The tick in progress, if any, is finished.
+func (c *Control) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paused = true
+}
+
+// Resume resumes the games and discards the pending steps.
+func (c *Control) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.paused = false
+	c.steps = 0
+	c.next = time.Time{}
+	c.cond.Broadcast()
+}
+
+// Paused reports whether the games are paused.
+func (c *Control) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.paused
+}
+
+// Step lets a paused game advance one tick. It does not wait for the tick to
+// run. It has no effect if the games are not paused.
+func (c *Control) Step() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return
+	}
+	c.steps++
+	c.cond.Broadcast()
+}
+
+// SetSpeed sets the speed of the games as a multiple of real time, so 1 is
+// real time and 10 is ten times faster. If speed is zero or negative, the
+// games run as fast as possible.
+func (c *Control) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.speed = speed
+	c.next = time.Time{}
+}
+
+// Speed returns the speed of the games. See SetSpeed.
+func (c *Control) Speed() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.speed
+}
+
+// Ticks returns the number of ticks run under the control.
+func (c *Control) Ticks() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ticks
+}
+
+// wait blocks until the next tick, which lasts dt seconds of game time, can
+// run.
+func (c *Control) wait(dt float64) {
+	c.mu.Lock()
+	for c.paused && c.steps == 0 {
+		c.cond.Wait()
+	}
+	if c.paused {
+		c.steps--
+		c.next = time.Time{}
+		c.mu.Unlock()
+		return
+	}
+
+	// The ticks that run late are not caught up, so the game does not
+	// speed up after a slow tick.
+	var d time.Duration
+	if c.speed > 0 {
+		now := time.Now()
+		if c.next.Before(now) {
+			c.next = now
+		}
+		d = c.next.Sub(now)
+		c.next = c.next.Add(time.Duration(dt / c.speed * float64(time.Second)))
+	}
+	c.mu.Unlock()
+
+	time.Sleep(d)
+}
+
+// done records that a tick has run.
+func (c *Control) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ticks++
+}
@@ -0,0 +1,85 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jroimartin/rtb"
+)
+
+// CPULimits are the limits of the processing time of the robots in the
+// competition mode of the RealTimeBattle server. A robot starts with Start
+// seconds and gets Extra seconds more every Period seconds of game time. It
+// is warned with WarningProcessTimeLow when it uses the ratio Warning of its
+// time, and killed when it uses all of it.
+//
+// The server measures the CPU time of the robot processes. Go does not
+// expose the CPU time of goroutines, so the simulator measures the time
+// spent by the strategies handling messages, which is similar when every
+// robot has a core of its own. The limits are kept during the whole
+// sequence of games of a Player.
+type CPULimits struct {
+	// Start is the time available at the beginning of the sequence, in
+	// seconds. If zero, 5 is used.
+	Start float64
+
+	// Extra is the time added every period, in seconds. If zero, 2 is
+	// used.
+	Extra float64
+
+	// Period is the game time between additions, in seconds. If zero,
+	// 60 is used.
+	Period float64
+
+	// Warning is the ratio of the available time after which the robot
+	// is warned. If zero, 0.9 is used.
+	Warning float64
+}
+
+// withDefaults returns l with the zero fields set to their defaults.
+func (l CPULimits) withDefaults() CPULimits {
+	if l.Start == 0 {
+		l.Start = 5
+	}
+	if l.Extra == 0 {
+		l.Extra = 2
+	}
+	if l.Period == 0 {
+		l.Period = 60
+	}
+	if l.Warning == 0 {
+		l.Warning = 0.9
+	}
+	return l
+}
+
+// available returns the time in seconds available to a player that has
+// played gameTime seconds.
+func (l CPULimits) available(gameTime float64) float64 {
+	return l.Start + l.Extra*math.Floor(gameTime/l.Period)
+}
+
+// checkCPU warns or kills r if it exceeds the CPU limits.
+func (g *Game) checkCPU(r *robot) {
+	l := g.cfg.CPU
+	if l == nil || r.energy <= 0 {
+		return
+	}
+	p := r.player
+
+	// The warning is sent again if the robot gets back under the
+	// threshold, thanks to the extra time, and then exceeds it again.
+	avail := l.available(p.gameTime)
+	used := p.cpu.Seconds()
+	switch {
+	case used > avail:
+		r.energy = 0
+		r.result.CPUExceeded = true
+	case used > l.Warning*avail && !p.warned:
+		p.warned = true
+		msg := fmt.Sprintf("%.2fs of %.2fs used", used, avail)
+		g.send(r, rtb.MessageWarning{Warning: rtb.WarningProcessTimeLow, Message: msg})
+	case used <= l.Warning*avail:
+		p.warned = false
+	}
+}
@@ -0,0 +1,146 @@
+package sim
+
+import (
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// Frame is a snapshot of a game after a tick. It is meant for rendering the
+// game, so it contains absolute positions and angles.
+type Frame struct {
+	// Time is the game time.
+	Time float64
+
+	// Arena is the arena of the game.
+	Arena *arena.Arena
+
+	// Robots contains the state of each robot, in the same order as the
+	// players passed to NewGame.
+	Robots []RobotFrame
+
+	// Shots contains the shots travelling through the arena.
+	Shots []ShotFrame
+
+	// Objects contains the cookies and mines in the arena.
+	Objects []Object
+}
+
+// RobotFrame is the state of a robot in a Frame.
+type RobotFrame struct {
+	// Name and Colour are the name and the home colour sent by the
+	// robot.
+	Name, Colour string
+
+	// Alive is true if the robot is alive.
+	Alive bool
+
+	// Energy is the energy of the robot.
+	Energy float64
+
+	// Pos is the position of the robot.
+	Pos arena.Point
+
+	// Angle, CannonAngle and RadarAngle are the absolute angles of the
+	// robot, its cannon and its radar.
+	Angle, CannonAngle, RadarAngle float64
+
+	// RadarHit is the point detected by the radar in the last tick. It
+	// is nil if the radar did not detect anything.
+	RadarHit *arena.Point
+
+	// Lines and Circles are the debug shapes drawn by the robot in the
+	// last tick with DebugLine and DebugCircle.
+	Lines   []Line
+	Circles []Circle
+}
+
+// Line is a line segment.
+type Line struct {
+	A, B arena.Point
+}
+
+// Circle is a circle.
+type Circle struct {
+	Center arena.Point
+	Radius float64
+}
+
+// ShotFrame is the state of a shot in a Frame.
+type ShotFrame struct {
+	// ID identifies the shot during the game.
+	ID int
+
+	// Pos, Vel and Energy are the position, the velocity and the energy
+	// of the shot.
+	Pos    arena.Point
+	Vel    arena.Point
+	Energy float64
+}
+
+// Object is a cookie or a mine.
+type Object struct {
+	// ID identifies the object during the game. It is ignored in
+	// Config.Objects.
+	ID int
+
+	// Kind is rtb.ObjectCookie or rtb.ObjectMine.
+	Kind rtb.Object
+
+	// Pos is the position of the object.
+	Pos arena.Point
+
+	// Radius is the radius of the object. When the object is placed
+	// with Config.Objects, if zero, 0.3 is used.
+	Radius float64
+
+	// Energy is the energy gained or lost by the robot that touches
+	// the object.
+	Energy float64
+}
+
+// Frame returns a snapshot of the game.
+func (g *Game) Frame() Frame {
+	f := Frame{Time: g.time, Arena: g.cfg.Arena}
+	for _, r := range g.robots {
+		rf := RobotFrame{
+			Name:        r.player.name,
+			Colour:      r.player.colour,
+			Alive:       r.energy > 0,
+			Energy:      r.energy,
+			Pos:         r.pos,
+			Angle:       r.angle,
+			CannonAngle: physics.NormalizeAngle(r.angle + r.cannon),
+			RadarAngle:  physics.NormalizeAngle(r.angle + r.radar),
+			Lines:       append([]Line(nil), r.lines...),
+			Circles:     append([]Circle(nil), r.circles...),
+		}
+		if rf.Alive {
+			if hit, ok := g.scan(r); ok {
+				p := r.pos.Add(arena.Polar(rf.RadarAngle, hit.dist))
+				rf.RadarHit = &p
+			}
+		}
+		f.Robots = append(f.Robots, rf)
+	}
+	for _, s := range g.shots {
+		f.Shots = append(f.Shots, ShotFrame{ID: s.id, Pos: s.pos, Vel: s.vel, Energy: s.energy})
+	}
+	for _, o := range g.objects {
+		f.Objects = append(f.Objects, Object{ID: o.id, Kind: o.kind, Pos: o.pos, Radius: o.radius, Energy: o.energy})
+	}
+	return f
+}
+
+// debugLine records a line drawn by r with DebugLine.
+func (r *robot) debugLine(angle1, radius1, angle2, radius2 float64) {
+	a := r.pos.Add(arena.Polar(r.angle+angle1, radius1))
+	b := r.pos.Add(arena.Polar(r.angle+angle2, radius2))
+	r.lines = append(r.lines, Line{A: a, B: b})
+}
+
+// debugCircle records a circle drawn by r with DebugCircle.
+func (r *robot) debugCircle(centerAngle, centerRadius, circleRadius float64) {
+	c := r.pos.Add(arena.Polar(r.angle+centerAngle, centerRadius))
+	r.circles = append(r.circles, Circle{Center: c, Radius: circleRadius})
+}
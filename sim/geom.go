@@ -0,0 +1,145 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb/arena"
+)
+
+type point = arena.Point
+
+// raySegment returns the distance from o to the intersection of the ray with
+// origin o and direction dir (unit vector) with the segment ab. It returns
+// false if they do not intersect.
+func raySegment(o, dir, a, b point) (float64, bool) {
+	ab := b.Sub(a)
+	den := dir.X*ab.Y - dir.Y*ab.X
+	if math.Abs(den) < 1e-12 {
+		return 0, false
+	}
+	ao := a.Sub(o)
+	t := (ao.X*ab.Y - ao.Y*ab.X) / den
+	u := (ao.X*dir.Y - ao.Y*dir.X) / den
+	if t < 0 || u < 0 || u > 1 {
+		return 0, false
+	}
+	return t, true
+}
+
+// rayCircle returns the distance from o to the first intersection of the ray
+// with origin o and direction dir (unit vector) with the circumference with
+// center c and radius r. If o is inside the circle, the exit point is
+// returned. It returns false if they do not intersect.
+func rayCircle(o, dir, c point, r float64) (float64, bool) {
+	oc := o.Sub(c)
+	b := oc.Dot(dir)
+	disc := b*b - (oc.Dot(oc) - r*r)
+	if disc < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(disc)
+	if t := -b - sq; t >= 0 {
+		return t, true
+	}
+	if t := -b + sq; t >= 0 {
+		return t, true
+	}
+	return 0, false
+}
+
+// inArc reports whether angle is inside the counterclockwise sector that goes
+// from angle1 to angle2.
+func inArc(angle, angle1, angle2 float64) bool {
+	span := math.Mod(angle2-angle1, 2*math.Pi)
+	if span < 0 {
+		span += 2 * math.Pi
+	}
+	d := math.Mod(angle-angle1, 2*math.Pi)
+	if d < 0 {
+		d += 2 * math.Pi
+	}
+	return d <= span
+}
+
+// rayWall returns the distance from o to the first intersection of the ray
+// with origin o and direction dir (unit vector) with the wall w. It returns
+// false if they do not intersect.
+func rayWall(o, dir point, w arena.Wall) (float64, bool) {
+	best, hit := math.Inf(1), false
+	try := func(t float64, ok bool) {
+		if ok && t < best {
+			best, hit = t, true
+		}
+	}
+
+	switch w := w.(type) {
+	case arena.Line:
+		try(raySegment(o, dir, w.Start, w.End))
+	case arena.Polygon:
+		for _, s := range w.Segments() {
+			try(raySegment(o, dir, s.A, s.B))
+		}
+	case arena.Circle:
+		try(rayCircle(o, dir, w.Center, w.Radius))
+	case arena.InnerCircle:
+		try(rayCircle(o, dir, w.Center, w.Radius))
+	case arena.Arc:
+		for _, r := range []float64{w.InnerRadius, w.OuterRadius} {
+			t, ok := rayCircle(o, dir, w.Center, r)
+			if !ok {
+				continue
+			}
+			p := o.Add(dir.Mul(t)).Sub(w.Center)
+			try(t, inArc(math.Atan2(p.Y, p.X), w.Angle1, w.Angle2))
+		}
+	}
+
+	return best, hit
+}
+
+// wallOverlap reports whether a circle with center c and radius r overlaps
+// the wall w. If so, it also returns the point of the wall closest to c.
+func wallOverlap(c point, r float64, w arena.Wall) (point, bool) {
+	switch w := w.(type) {
+	case arena.Line:
+		return segmentOverlap(c, r, arena.Segment{Thickness: w.Thickness, A: w.Start, B: w.End})
+	case arena.Polygon:
+		for _, s := range w.Segments() {
+			if p, ok := segmentOverlap(c, r, s); ok {
+				return p, true
+			}
+		}
+	case arena.Circle:
+		d := c.Sub(w.Center)
+		if d.Len() < w.Radius+r {
+			return w.Center.Add(d.Mul(w.Radius / d.Len())), true
+		}
+	case arena.InnerCircle:
+		d := c.Sub(w.Center)
+		if d.Len() > w.Radius-r {
+			return w.Center.Add(d.Mul(w.Radius / d.Len())), true
+		}
+	case arena.Arc:
+		d := c.Sub(w.Center)
+		l := d.Len()
+		if l > w.InnerRadius-r && l < w.OuterRadius+r && inArc(math.Atan2(d.Y, d.X), w.Angle1, w.Angle2) {
+			return w.Center.Add(d.Mul(math.Max(w.InnerRadius, math.Min(l, w.OuterRadius)) / l)), true
+		}
+	}
+	return point{}, false
+}
+
+// segmentOverlap reports whether a circle with center c and radius r overlaps
+// the segment s. If so, it also returns the point of the segment closest to c.
+func segmentOverlap(c point, r float64, s arena.Segment) (point, bool) {
+	if arena.SegmentDistance(c, s.A, s.B) >= r+s.Thickness/2 {
+		return point{}, false
+	}
+	ab := s.B.Sub(s.A)
+	l2 := ab.Dot(ab)
+	if l2 == 0 {
+		return s.A, true
+	}
+	t := math.Max(0, math.Min(1, c.Sub(s.A).Dot(ab)/l2))
+	return s.A.Add(ab.Mul(t)), true
+}
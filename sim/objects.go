@@ -0,0 +1,181 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// Spawn configures how cookies or mines appear in the arena. Its fields
+// follow the cookie and mine options of the RealTimeBattle server.
+//
+// In every tick, an object is placed with probability Frequency times the
+// duration of the tick, at a random position where it does not overlap any
+// wall, robot or other object. Its energy is chosen uniformly between
+// MinEnergy and MaxEnergy. A robot that touches a cookie gains its energy,
+// up to the RobotMaxEnergy option, and a robot that touches a mine loses
+// it. Shots that hit a cookie or a mine destroy it.
+type Spawn struct {
+	// Frequency is the mean number of objects placed per second.
+	Frequency float64
+
+	// MinEnergy is the minimum energy of an object.
+	MinEnergy float64
+
+	// MaxEnergy is the maximum energy of an object.
+	MaxEnergy float64
+
+	// Radius is the radius of the objects. If zero, 0.3 is used.
+	Radius float64
+
+	// Max is the maximum number of objects of this kind in the arena at
+	// the same time. If zero, it is not limited, like in the server.
+	Max int
+}
+
+// DefaultCookies returns the default cookie options of the RealTimeBattle
+// server.
+func DefaultCookies() Spawn {
+	return Spawn{
+		Frequency: 0.03,
+		MinEnergy: 10,
+		MaxEnergy: 15,
+		Radius:    0.3,
+	}
+}
+
+// DefaultMines returns the default mine options of the RealTimeBattle
+// server.
+func DefaultMines() Spawn {
+	return Spawn{
+		Frequency: 0.06,
+		MinEnergy: 15,
+		MaxEnergy: 25,
+		Radius:    0.3,
+	}
+}
+
+// withDefaults returns s with the zero fields set to their defaults.
+func (s Spawn) withDefaults() Spawn {
+	if s.Radius == 0 {
+		s.Radius = 0.3
+	}
+	return s
+}
+
+// object is a cookie or a mine.
+type object struct {
+	id     int
+	kind   rtb.Object
+	pos    point
+	radius float64
+	energy float64
+}
+
+// spawnObjects places the cookies and mines of the current tick.
+func (g *Game) spawnObjects(dt float64) {
+	if g.cfg.Cookies != nil {
+		g.spawn(rtb.ObjectCookie, *g.cfg.Cookies, dt)
+	}
+	if g.cfg.Mines != nil {
+		g.spawn(rtb.ObjectMine, *g.cfg.Mines, dt)
+	}
+}
+
+// spawn places an object of the given kind with probability s.Frequency*dt.
+func (g *Game) spawn(kind rtb.Object, s Spawn, dt float64) {
+	if g.rnd.Float64() >= s.Frequency*dt {
+		return
+	}
+	if s.Max > 0 && g.countObjects(kind) >= s.Max {
+		return
+	}
+	pos, ok := g.placeObject(s.Radius)
+	if !ok {
+		// The arena is too crowded. The server does not place the
+		// object either.
+		return
+	}
+	o := &object{
+		id:     g.newID(),
+		kind:   kind,
+		pos:    pos,
+		radius: s.Radius,
+		energy: s.MinEnergy + g.rnd.Float64()*(s.MaxEnergy-s.MinEnergy),
+	}
+	g.objects = append(g.objects, o)
+}
+
+// countObjects returns the number of objects of the given kind.
+func (g *Game) countObjects(kind rtb.Object) int {
+	n := 0
+	for _, o := range g.objects {
+		if o.kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// placeObject returns a random position where an object with the given
+// radius does not overlap any wall, robot or other object.
+func (g *Game) placeObject(radius float64) (point, bool) {
+	b := g.cfg.Arena.Boundary
+outer:
+	for i := 0; i < 100; i++ {
+		p := point{
+			X: b.Min.X + radius + g.rnd.Float64()*(b.Dx()-2*radius),
+			Y: b.Min.Y + radius + g.rnd.Float64()*(b.Dy()-2*radius),
+		}
+		for _, w := range g.cfg.Arena.Walls {
+			if _, ok := wallOverlap(p, radius, w); ok {
+				continue outer
+			}
+		}
+		for _, r := range g.robots {
+			if r.energy > 0 && r.pos.Sub(p).Len() < physics.RobotRadius+radius {
+				continue outer
+			}
+		}
+		for _, o := range g.objects {
+			if o.pos.Sub(p).Len() < o.radius+radius {
+				continue outer
+			}
+		}
+		return p, true
+	}
+	return point{}, false
+}
+
+// touchObjects applies the effects of the objects touched by r and removes
+// them.
+func (g *Game) touchObjects(r *robot) {
+	objects := g.objects[:0]
+	for _, o := range g.objects {
+		if r.energy <= 0 || r.pos.Sub(o.pos).Len() >= physics.RobotRadius+o.radius {
+			objects = append(objects, o)
+			continue
+		}
+		switch o.kind {
+		case rtb.ObjectCookie:
+			r.energy = math.Min(r.energy+o.energy, g.cfg.Options.RobotMaxEnergy)
+			r.result.CookiesEaten++
+		case rtb.ObjectMine:
+			g.injure(r, o.energy)
+			r.result.MinesHit++
+		}
+		g.send(r, rtb.MessageCollision{Object: o.kind, Angle: r.relAngle(o.pos)})
+	}
+	g.objects = objects
+}
+
+// removeObject removes o from the arena.
+func (g *Game) removeObject(o *object) {
+	for i, other := range g.objects {
+		if other == o {
+			g.objects = append(g.objects[:i], g.objects[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Options are the game options of a simulation. They are sent to the robots
+// at the beginning of each game. See rtb.GOption for a description of each
+// option.
+type Options struct {
+	RobotMaxRotate          float64
+	RobotCannonMaxRotate    float64
+	RobotRadarMaxRotate     float64
+	RobotMaxAcceleration    float64
+	RobotMinAcceleration    float64
+	RobotStartEnergy        float64
+	RobotMaxEnergy          float64
+	RobotEnergyLevels       float64
+	ShotSpeed               float64
+	ShotMinEnergy           float64
+	ShotMaxEnergy           float64
+	ShotEnergyIncreaseSpeed float64
+	Timeout                 float64
+	DebugLevel              float64
+	SendRobotCoordinates    float64
+}
+
+// DefaultOptions returns the default game options of the RealTimeBattle
+// server.
+func DefaultOptions() Options {
+	return Options{
+		RobotMaxRotate:          math.Pi / 4,
+		RobotCannonMaxRotate:    math.Pi / 2,
+		RobotRadarMaxRotate:     2 * math.Pi / 3,
+		RobotMaxAcceleration:    2,
+		RobotMinAcceleration:    -0.5,
+		RobotStartEnergy:        100,
+		RobotMaxEnergy:          120,
+		RobotEnergyLevels:       10,
+		ShotSpeed:               10,
+		ShotMinEnergy:           0.5,
+		ShotMaxEnergy:           30,
+		ShotEnergyIncreaseSpeed: 10,
+		Timeout:                 120,
+		DebugLevel:              0,
+		SendRobotCoordinates:    0,
+	}
+}
+
+// messages returns the GameOption messages sent to the robots.
+func (o Options) messages() []rtb.MessageGameOption {
+	return []rtb.MessageGameOption{
+		{Option: rtb.GOptionRobotMaxRotate, Value: o.RobotMaxRotate},
+		{Option: rtb.GOptionRobotCannonMaxRotate, Value: o.RobotCannonMaxRotate},
+		{Option: rtb.GOptionRobotRadarMaxRotate, Value: o.RobotRadarMaxRotate},
+		{Option: rtb.GOptionRobotMaxAcceleration, Value: o.RobotMaxAcceleration},
+		{Option: rtb.GOptionRobotMinAcceleration, Value: o.RobotMinAcceleration},
+		{Option: rtb.GOptionRobotStartEnergy, Value: o.RobotStartEnergy},
+		{Option: rtb.GOptionRobotMaxEnergy, Value: o.RobotMaxEnergy},
+		{Option: rtb.GOptionRobotEnergyLevels, Value: o.RobotEnergyLevels},
+		{Option: rtb.GOptionShotSpeed, Value: o.ShotSpeed},
+		{Option: rtb.GOptionShotMinEnergy, Value: o.ShotMinEnergy},
+		{Option: rtb.GOptionShotMaxEnergy, Value: o.ShotMaxEnergy},
+		{Option: rtb.GOptionShotEnergyIncreaseSpeed, Value: o.ShotEnergyIncreaseSpeed},
+		{Option: rtb.GOptionTimeout, Value: o.Timeout},
+		{Option: rtb.GOptionDebugLevel, Value: o.DebugLevel},
+		{Option: rtb.GOptionSendRobotCoordinates, Value: o.SendRobotCoordinates},
+	}
+}
+
+// Physical constants of the simulation.
+const (
+	// airResistance is the air resistance coefficient.
+	airResistance = 0.005
+
+	// rollFriction is the friction in the direction of the robot.
+	rollFriction = 0.002
+
+	// slideFriction is the friction perpendicular to the robot direction
+	// and the friction in the robot direction when fully braking.
+	slideFriction = 0.98
+
+	// gravity is the gravitational constant.
+	gravity = 9.82
+)
@@ -0,0 +1,49 @@
+package sim
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// radarHit is an object detected by the radar.
+type radarHit struct {
+	dist   float64
+	object rtb.Object
+
+	// robot is the detected robot, if the object is a robot.
+	robot *robot
+}
+
+// scan casts a ray from the center of r in the direction of its radar and
+// returns the nearest wall, robot, cookie or mine it intersects. Shots are
+// not detected, like in the server. The ray is only cast at the end of each
+// tick, so a radar that sweeps fast can miss small objects between two
+// ticks. It returns false if the ray does not hit anything, which only
+// happens in open arenas.
+func (g *Game) scan(r *robot) (radarHit, bool) {
+	dir := arena.Polar(r.angle+r.radar, 1)
+	hit := radarHit{dist: math.Inf(1), object: rtb.ObjectNoObject}
+
+	for _, w := range g.cfg.Arena.Walls {
+		if t, ok := rayWall(r.pos, dir, w); ok && t < hit.dist {
+			hit = radarHit{dist: t, object: rtb.ObjectWall}
+		}
+	}
+	for _, other := range g.robots {
+		if other == r || other.energy <= 0 {
+			continue
+		}
+		if t, ok := rayCircle(r.pos, dir, other.pos, physics.RobotRadius); ok && t < hit.dist {
+			hit = radarHit{dist: t, object: rtb.ObjectRobot, robot: other}
+		}
+	}
+	for _, o := range g.objects {
+		if t, ok := rayCircle(r.pos, dir, o.pos, o.radius); ok && t < hit.dist {
+			hit = radarHit{dist: t, object: o.kind}
+		}
+	}
+	return hit, hit.object != rtb.ObjectNoObject
+}
@@ -0,0 +1,944 @@
+// Package sim implements a simplified RealTimeBattle server that runs robot
+// strategies in the same process. It allows to develop and test strategies
+// without a RealTimeBattle installation.
+//
+// The simulation is deterministic: the same arena, options, seed and
+// strategies always produce the same game, unless CPU limits are enforced,
+// because they depend on the time spent by the strategies.
+package sim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// DefaultTimeStep is the default duration of a simulation tick in seconds.
+const DefaultTimeStep = 0.05
+
+// Player is a robot taking part in a sequence of games. The same Player can
+// be used in consecutive games, like a robot process is kept alive by the
+// RealTimeBattle server during a sequence.
+type Player struct {
+	strategy rtb.Strategy
+	settings rtb.ListenSettings
+
+	out    bytes.Buffer
+	client *rtb.Robot
+	log    io.Writer
+
+	initialized bool
+	name        string
+	colour      string
+	team        string
+
+	// cpu is the processing time used by the strategy, gameTime is the
+	// game time played and warned is true if the player has been warned
+	// in the current CPU period. They are kept for the whole sequence,
+	// like the server does.
+	cpu      time.Duration
+	gameTime float64
+	warned   bool
+}
+
+// NewPlayer returns a Player that runs the strategy s. Settings are
+// interpreted like rtb.Robot.Listen does. ChanBufferCapacity, Overflow and Pool
+// are ignored.
+func NewPlayer(s rtb.Strategy, settings rtb.ListenSettings) *Player {
+	p := &Player{strategy: s, settings: settings}
+	p.client = rtb.NewRobot(nil, &p.out)
+	return p
+}
+
+// Record writes every message sent to the player to w, in the format used by
+// the RealTimeBattle server. The log can be replayed using the replay
+// package.
+func (p *Player) Record(w io.Writer) {
+	p.log = w
+}
+
+// SetTeam sets the team of the player. Robots of the same team are reported
+// as team mates in RobotInfo messages. If team is empty, the player has no
+// team mates.
+func (p *Player) SetTeam(team string) {
+	p.team = team
+}
+
+// CPUTime returns the time spent by the strategy handling messages in all the
+// games played.
+func (p *Player) CPUTime() time.Duration {
+	return p.cpu
+}
+
+// Name returns the name sent by the robot.
+func (p *Player) Name() string {
+	return p.name
+}
+
+// Colour returns the home colour sent by the robot.
+func (p *Player) Colour() string {
+	return p.colour
+}
+
+// Config is the configuration of a game.
+type Config struct {
+	// Arena is the arena of the game. If nil, a 20x20 rectangular arena
+	// is used.
+	Arena *arena.Arena
+
+	// Options are the game options. If zero, DefaultOptions is used.
+	Options Options
+
+	// TimeStep is the duration of a tick in seconds. If zero,
+	// DefaultTimeStep is used.
+	TimeStep float64
+
+	// Seed is used to place the robots in the arena.
+	Seed int64
+
+	// Placements are the initial states of the robots, in the same
+	// order as the players. The robots without placement, or with a nil
+	// one, are placed randomly.
+	Placements []*Placement
+
+	// Objects are the cookies and mines in the arena at the beginning of
+	// the game.
+	Objects []Object
+
+	// Concurrent delivers the messages of each tick to the robots in
+	// parallel, one goroutine per robot, like robot processes run in
+	// parallel with the real server. The commands are executed after all
+	// the robots have handled their messages, in the order of the
+	// players, so the game is still deterministic as long as the
+	// strategies do not share state. Otherwise, the messages are
+	// delivered one at a time and the commands are executed
+	// immediately.
+	Concurrent bool
+
+	// CPU limits the processing time of the robots, like the competition
+	// mode of the server. If nil, it is not limited.
+	CPU *CPULimits
+
+	// Collisions configures the collisions of the robots. If zero,
+	// DefaultCollisions is used.
+	Collisions Collisions
+
+	// Cookies configures the cookies placed in the arena during the
+	// game. If nil, there are no cookies.
+	Cookies *Spawn
+
+	// Mines configures the mines placed in the arena during the game. If
+	// nil, there are no mines.
+	Mines *Spawn
+
+	// Frames, if not nil, is called with a snapshot of the game after
+	// every tick, e.g. to render the game.
+	Frames func(f Frame)
+
+	// Control controls the pace of Game.Run. If nil, the game runs as
+	// fast as possible.
+	Control *Control
+}
+
+// Placement is the initial state of a robot.
+type Placement struct {
+	// Pos is the position of the robot.
+	Pos arena.Point
+
+	// Angle is the direction of the robot.
+	Angle float64
+
+	// Energy is the energy of the robot. If zero, the RobotStartEnergy
+	// option is used.
+	Energy float64
+}
+
+// RobotResult is the outcome of a game for a robot.
+type RobotResult struct {
+	// Alive is true if the robot survived the game.
+	Alive bool
+
+	// Energy is the energy of the robot at the end of the game.
+	Energy float64
+
+	// DeathTime is the game time when the robot died.
+	DeathTime float64
+
+	// DamageTaken is the energy lost by the robot.
+	DamageTaken float64
+
+	// DamageDealt is the energy taken to other robots by the shots of the
+	// robot.
+	DamageDealt float64
+
+	// ShotsFired is the number of shots fired by the robot.
+	ShotsFired int
+
+	// CookiesEaten is the number of cookies eaten by the robot.
+	CookiesEaten int
+
+	// MinesHit is the number of mines touched by the robot.
+	MinesHit int
+
+	// CPUTime is the time spent by the strategy handling the messages of
+	// the game.
+	CPUTime time.Duration
+
+	// CPUExceeded is true if the robot was killed for exceeding its CPU
+	// time.
+	CPUExceeded bool
+}
+
+// Result is the outcome of a game.
+type Result struct {
+	// Time is the duration of the game.
+	Time float64
+
+	// Winner is the index of the only robot alive at the end of the
+	// game. It is -1 if all robots died or the game timed out with more
+	// than one robot alive.
+	Winner int
+
+	// Robots contains the result of each robot, in the same order as the
+	// players passed to NewGame.
+	Robots []RobotResult
+}
+
+// rotation is the rotation state of a robot part.
+type rotation struct {
+	mode        rotationMode
+	speed       float64
+	left, right float64
+
+	// amount is the rotation left to complete a rotationTo, so it ends
+	// even if the angle is normalized meanwhile.
+	amount float64
+}
+
+// rotationMode is the kind of rotation of a robot part.
+type rotationMode int
+
+const (
+	rotationNone rotationMode = iota
+	rotationSpeed
+	rotationTo
+	rotationSweep
+)
+
+// robot is the state of a robot in a game.
+type robot struct {
+	player *Player
+	result RobotResult
+
+	pos    point
+	vel    point
+	accel  float64
+	brake  float64
+	angle  float64
+	cannon float64
+	radar  float64
+	energy float64
+
+	// shotEnergy is the energy available for shooting.
+	shotEnergy float64
+
+	rotations [3]rotation
+
+	// pending are the messages waiting to be delivered in concurrent
+	// mode.
+	pending []rtb.Message
+
+	// lines and circles are the debug shapes drawn in the current tick.
+	lines   []Line
+	circles []Circle
+}
+
+// shot is a shot travelling through the arena.
+type shot struct {
+	id     int
+	owner  *robot
+	pos    point
+	vel    point
+	energy float64
+}
+
+// Game is a RealTimeBattle game.
+type Game struct {
+	cfg     Config
+	robots  []*robot
+	shots   []*shot
+	objects []*object
+	rnd     *rand.Rand
+	time    float64
+	alive   int
+	done    bool
+	lastID  int
+}
+
+// NewGame returns a new game with the given players. The players are
+// initialized if it is their first game.
+func NewGame(cfg Config, players []*Player) (*Game, error) {
+	if len(players) == 0 {
+		return nil, errors.New("no players")
+	}
+	if cfg.Arena == nil {
+		cfg.Arena = arena.Rectangle(20, 20)
+	}
+	if cfg.Options == (Options{}) {
+		cfg.Options = DefaultOptions()
+	}
+	if cfg.Collisions == (Collisions{}) {
+		cfg.Collisions = DefaultCollisions()
+	}
+	if cfg.TimeStep <= 0 {
+		cfg.TimeStep = DefaultTimeStep
+	}
+	if cfg.CPU != nil {
+		cpu := cfg.CPU.withDefaults()
+		cfg.CPU = &cpu
+	}
+	if cfg.Cookies != nil {
+		cookies := cfg.Cookies.withDefaults()
+		cfg.Cookies = &cookies
+	}
+	if cfg.Mines != nil {
+		mines := cfg.Mines.withDefaults()
+		cfg.Mines = &mines
+	}
+
+	g := &Game{cfg: cfg, rnd: rand.New(rand.NewSource(cfg.Seed))}
+
+	// The robots are not taken into account until they are placed,
+	// because they have no energy. The robots with a placement are
+	// placed first, so the random ones do not overlap them.
+	for _, p := range players {
+		g.robots = append(g.robots, &robot{player: p, shotEnergy: cfg.Options.ShotMaxEnergy})
+	}
+	for i, pl := range cfg.Placements {
+		if pl == nil || i >= len(g.robots) {
+			continue
+		}
+		if _, _, ok := g.overlapsWall(pl.Pos); ok {
+			return nil, fmt.Errorf("robot %v placed on a wall", i)
+		}
+		if g.overlapsRobot(nil, pl.Pos) != nil {
+			return nil, fmt.Errorf("robot %v placed on another robot", i)
+		}
+		r := g.robots[i]
+		r.pos, r.angle, r.energy = pl.Pos, physics.NormalizeAngle(pl.Angle), pl.Energy
+		if r.energy == 0 {
+			r.energy = cfg.Options.RobotStartEnergy
+		}
+	}
+	for _, r := range g.robots {
+		if r.energy > 0 {
+			continue
+		}
+		pos, err := g.placeRobot(g.rnd)
+		if err != nil {
+			return nil, err
+		}
+		r.pos = pos
+		r.angle = physics.NormalizeAngle(g.rnd.Float64() * 2 * math.Pi)
+		r.energy = cfg.Options.RobotStartEnergy
+	}
+	g.alive = len(g.robots)
+
+	for _, o := range cfg.Objects {
+		if o.Kind != rtb.ObjectCookie && o.Kind != rtb.ObjectMine {
+			return nil, fmt.Errorf("invalid object %v", o.Kind)
+		}
+		if o.Radius == 0 {
+			o.Radius = 0.3
+		}
+		g.objects = append(g.objects, &object{id: g.newID(), kind: o.Kind, pos: o.Pos, radius: o.Radius, energy: o.Energy})
+	}
+
+	for _, r := range g.robots {
+		if !r.player.initialized {
+			g.send(r, rtb.MessageInitialize{First: true})
+			r.player.initialized = true
+		} else {
+			g.send(r, rtb.MessageYourName{Name: r.player.name})
+			g.send(r, rtb.MessageYourColour{Colour: r.player.colour})
+		}
+		for _, opt := range cfg.Options.messages() {
+			g.send(r, opt)
+		}
+	}
+	for _, r := range g.robots {
+		g.send(r, rtb.MessageGameStarts{})
+		g.send(r, rtb.MessageRobotsLeft{NumRobots: g.alive})
+	}
+	g.flush()
+
+	return g, nil
+}
+
+// placeRobot returns a random position where a robot does not overlap any
+// wall or other robot.
+func (g *Game) placeRobot(rnd *rand.Rand) (point, error) {
+	b := g.cfg.Arena.Boundary
+	for i := 0; i < 1000; i++ {
+		p := point{
+			X: b.Min.X + physics.RobotRadius + rnd.Float64()*(b.Dx()-2*physics.RobotRadius),
+			Y: b.Min.Y + physics.RobotRadius + rnd.Float64()*(b.Dy()-2*physics.RobotRadius),
+		}
+		if _, _, ok := g.overlapsWall(p); ok {
+			continue
+		}
+		if g.overlapsRobot(nil, p) != nil {
+			continue
+		}
+		return p, nil
+	}
+	return point{}, errors.New("could not place robot")
+}
+
+// overlapsWall returns the first wall overlapped by a robot at p and its
+// closest point. It returns false if there is none.
+func (g *Game) overlapsWall(p point) (point, arena.Wall, bool) {
+	for _, w := range g.cfg.Arena.Walls {
+		if q, ok := wallOverlap(p, physics.RobotRadius, w); ok {
+			return q, w, true
+		}
+	}
+	return point{}, nil, false
+}
+
+// overlapsRobot returns the first alive robot, other than self, overlapped by
+// a robot at p. It returns nil if there is none.
+func (g *Game) overlapsRobot(self *robot, p point) *robot {
+	for _, r := range g.robots {
+		if r == self || r.energy <= 0 {
+			continue
+		}
+		if r.pos.Sub(p).Len() < 2*physics.RobotRadius {
+			return r
+		}
+	}
+	return nil
+}
+
+// send delivers msg to the strategy of r and executes the commands sent in
+// response. In concurrent mode, msg is queued until the next flush.
+func (g *Game) send(r *robot, msg rtb.Message) {
+	if g.cfg.Concurrent {
+		r.pending = append(r.pending, msg)
+		return
+	}
+	g.deliver(r, msg)
+	g.execOutput(r)
+	g.checkCPU(r)
+}
+
+// deliver delivers msgs to the strategy of r, measuring the time spent.
+func (g *Game) deliver(r *robot, msgs ...rtb.Message) {
+	p := r.player
+	start := time.Now()
+	for _, msg := range msgs {
+		if p.log != nil {
+			s, _ := rtb.EncodeMessage(msg)
+			fmt.Fprintln(p.log, s)
+		}
+		p.client.Deliver(p.strategy, msg)
+	}
+	elapsed := time.Since(start)
+	p.cpu += elapsed
+	r.result.CPUTime += elapsed
+}
+
+// flush delivers the pending messages in concurrent mode. The robots handle
+// their messages in parallel and then their commands are executed in order.
+// Executing the commands may queue new messages, so it is repeated until
+// there are no pending messages.
+func (g *Game) flush() {
+	for {
+		var busy []*robot
+		for _, r := range g.robots {
+			if len(r.pending) > 0 {
+				busy = append(busy, r)
+			}
+		}
+		if len(busy) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, r := range busy {
+			msgs := r.pending
+			r.pending = nil
+			wg.Add(1)
+			go func(r *robot) {
+				defer wg.Done()
+				g.deliver(r, msgs...)
+			}(r)
+		}
+		wg.Wait()
+
+		for _, r := range busy {
+			g.execOutput(r)
+			g.checkCPU(r)
+		}
+	}
+}
+
+// Do calls f with the client of the player i, the same *rtb.Robot passed to
+// its strategy, and executes the commands sent by f. It allows to control a
+// robot from outside its strategy, e.g. from a learning environment.
+func (g *Game) Do(i int, f func(r *rtb.Robot)) {
+	r := g.robots[i]
+	f(r.player.client)
+	g.execOutput(r)
+	g.flush()
+}
+
+// execOutput executes the commands written by the client of r.
+func (g *Game) execOutput(r *robot) {
+	p := r.player
+
+	// The commands are copied before executing them, because executing a
+	// command may send new messages to the robot.
+	lines := strings.Split(p.out.String(), "\n")
+	p.out.Reset()
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		cmd, err := parseCommand(line)
+		if err != nil {
+			g.send(r, rtb.MessageWarning{Warning: rtb.WarningUnknownMessage, Message: line})
+			continue
+		}
+		g.exec(r, cmd)
+	}
+}
+
+// exec executes a command sent by r.
+func (g *Game) exec(r *robot, cmd command) {
+	opts := g.cfg.Options
+
+	switch cmd.Keyword {
+	case "Name":
+		r.player.name = cmd.Text
+	case "Colour":
+		if fields := strings.Fields(cmd.Text); len(fields) > 0 {
+			r.player.colour = fields[0]
+		}
+	case "Rotate":
+		g.rotate(r, cmd.Part, rotation{mode: rotationSpeed, speed: cmd.Args[0]})
+	case "RotateTo":
+		if cmd.Part&rtb.PartRobot != 0 {
+			g.send(r, rtb.MessageWarning{Warning: rtb.WarningUnknownOption, Message: "RotateTo robot"})
+			return
+		}
+		for i, part := range []rtb.Part{rtb.PartRobot, rtb.PartCannon, rtb.PartRadar} {
+			if cmd.Part&part == 0 {
+				continue
+			}
+			amount := cmd.Args[1] - r.partAngle(i)
+			g.rotate(r, part, rotation{mode: rotationTo, speed: cmd.Args[0], amount: amount})
+		}
+	case "RotateAmount":
+		g.rotate(r, cmd.Part, rotation{mode: rotationTo, speed: cmd.Args[0], amount: cmd.Args[1]})
+	case "Sweep":
+		if cmd.Part&rtb.PartRobot != 0 {
+			g.send(r, rtb.MessageWarning{Warning: rtb.WarningUnknownOption, Message: "Sweep robot"})
+			return
+		}
+		right, left := cmd.Args[1], cmd.Args[2]
+		if right > left {
+			right, left = left, right
+		}
+		g.rotate(r, cmd.Part, rotation{mode: rotationSweep, speed: cmd.Args[0], left: left, right: right})
+	case "Accelerate":
+		r.accel = math.Max(opts.RobotMinAcceleration, math.Min(cmd.Args[0], opts.RobotMaxAcceleration))
+	case "Brake":
+		r.brake = math.Max(0, math.Min(cmd.Args[0], 1))
+	case "Shoot":
+		g.shoot(r, cmd.Args[0])
+	case "DebugLine":
+		r.debugLine(cmd.Args[0], cmd.Args[1], cmd.Args[2], cmd.Args[3])
+	case "DebugCircle":
+		r.debugCircle(cmd.Args[0], cmd.Args[1], cmd.Args[2])
+	}
+}
+
+// rotate sets the rotation of the given parts of r.
+func (g *Game) rotate(r *robot, parts rtb.Part, rot rotation) {
+	for i, part := range []rtb.Part{rtb.PartRobot, rtb.PartCannon, rtb.PartRadar} {
+		if parts&part == 0 {
+			continue
+		}
+		max := g.maxRotate(i)
+		rot := rot
+		rot.speed = math.Max(-max, math.Min(rot.speed, max))
+		if rot.mode == rotationTo || rot.mode == rotationSweep {
+			rot.speed = math.Abs(rot.speed)
+		}
+		r.rotations[i] = rot
+	}
+}
+
+// maxRotate returns the maximum rotation speed of the i-th part.
+func (g *Game) maxRotate(i int) float64 {
+	switch i {
+	case 0:
+		return g.cfg.Options.RobotMaxRotate
+	case 1:
+		return g.cfg.Options.RobotCannonMaxRotate
+	default:
+		return g.cfg.Options.RobotRadarMaxRotate
+	}
+}
+
+// partAnglePtr returns a pointer to the angle of the i-th part.
+func (r *robot) partAnglePtr(i int) *float64 {
+	switch i {
+	case 0:
+		return &r.angle
+	case 1:
+		return &r.cannon
+	default:
+		return &r.radar
+	}
+}
+
+// partAngle returns the angle of the i-th part.
+func (r *robot) partAngle(i int) float64 {
+	return *r.partAnglePtr(i)
+}
+
+// shoot fires a shot with the given energy.
+func (g *Game) shoot(r *robot, energy float64) {
+	opts := g.cfg.Options
+
+	energy = math.Min(energy, opts.ShotMaxEnergy)
+	if energy < opts.ShotMinEnergy || energy > r.shotEnergy {
+		return
+	}
+	r.shotEnergy -= energy
+
+	dir := arena.Polar(r.angle+r.cannon, 1)
+	s := &shot{
+		id:     g.newID(),
+		owner:  r,
+		pos:    r.pos.Add(dir.Mul(physics.RobotRadius + 0.01)),
+		vel:    dir.Mul(opts.ShotSpeed).Add(r.vel),
+		energy: energy,
+	}
+	g.shots = append(g.shots, s)
+	r.result.ShotsFired++
+}
+
+// Time returns the elapsed game time.
+func (g *Game) Time() float64 {
+	return g.time
+}
+
+// Done reports whether the game is finished.
+func (g *Game) Done() bool {
+	return g.done
+}
+
+// Step advances the game one tick. It returns false if the game is finished.
+func (g *Game) Step() bool {
+	if g.done {
+		return false
+	}
+
+	// In concurrent mode, the messages of the tick are delivered at the
+	// end, before taking the frame.
+	if g.cfg.Frames != nil {
+		defer func() { g.cfg.Frames(g.Frame()) }()
+	}
+	defer g.flush()
+
+	for _, r := range g.robots {
+		r.lines, r.circles = r.lines[:0], r.circles[:0]
+	}
+
+	dt := g.cfg.TimeStep
+	g.time += dt
+	for _, r := range g.robots {
+		r.player.gameTime += dt
+	}
+
+	for _, r := range g.robots {
+		if r.energy > 0 {
+			g.moveRobot(r, dt)
+		}
+	}
+	g.moveShots(dt)
+	g.spawnObjects(dt)
+
+	for _, r := range g.robots {
+		if r.energy > 0 || r.result.DeathTime != 0 {
+			continue
+		}
+		r.result.DeathTime = g.time
+		g.alive--
+		g.send(r, rtb.MessageDead{})
+		for _, other := range g.robots {
+			g.send(other, rtb.MessageRobotsLeft{NumRobots: g.alive})
+		}
+	}
+
+	for _, r := range g.robots {
+		if r.energy > 0 {
+			g.sense(r)
+		}
+	}
+
+	if g.alive <= 1 && len(g.robots) > 1 || g.alive == 0 || g.time >= g.cfg.Options.Timeout {
+		g.finish()
+		return false
+	}
+
+	return true
+}
+
+// moveRobot updates the rotations and the position of r.
+func (g *Game) moveRobot(r *robot, dt float64) {
+	for i := range r.rotations {
+		g.updateRotation(r, i, dt)
+	}
+	r.angle = physics.NormalizeAngle(r.angle)
+
+	r.shotEnergy = math.Min(r.shotEnergy+g.cfg.Options.ShotEnergyIncreaseSpeed*dt, g.cfg.Options.ShotMaxEnergy)
+
+	r.vel = g.velocity(r, dt)
+
+	next := r.pos.Add(r.vel.Mul(dt))
+	if q, w, ok := g.overlapsWall(next); ok {
+		g.hitWall(r, q, wallMaterial(w))
+		return
+	}
+	if other := g.overlapsRobot(r, next); other != nil {
+		g.hitRobot(r, other)
+		return
+	}
+	r.pos = next
+	g.touchObjects(r)
+}
+
+// velocity returns the velocity of r after dt seconds, following the
+// kinematics of the RealTimeBattle server. The velocity is split into its
+// components along the robot direction and perpendicular to it. The former
+// is reduced by the roll friction or, when braking, by a mix of roll and
+// slide friction, and the latter by the slide friction, so a turning robot
+// loses the speed it is not carrying in its new direction. The air
+// resistance is applied to the whole velocity.
+func (g *Game) velocity(r *robot, dt float64) point {
+	dir := arena.Polar(r.angle, 1)
+	side := point{X: -dir.Y, Y: dir.X}
+	gt := gravity * dt
+	friction := rollFriction*(1-r.brake) + slideFriction*r.brake
+
+	air := r.vel.Mul(-math.Min(airResistance*dt, 0.5))
+	accel := dir.Mul(r.accel * dt)
+	roll := dir.Mul(r.vel.Dot(dir) * math.Max(0, 1-gt*friction))
+	slide := side.Mul(r.vel.Dot(side) * math.Max(0, 1-gt*slideFriction))
+	return air.Add(accel).Add(roll).Add(slide)
+}
+
+// speed returns the speed of r in its direction, which is negative when the
+// robot moves backwards. It is the speed reported in Info messages.
+func (r *robot) speed() float64 {
+	return r.vel.Dot(arena.Polar(r.angle, 1))
+}
+
+// updateRotation updates the angle of the i-th part of r.
+func (g *Game) updateRotation(r *robot, i int, dt float64) {
+	rot := &r.rotations[i]
+	angle := r.partAnglePtr(i)
+	part := []rtb.Part{rtb.PartRobot, rtb.PartCannon, rtb.PartRadar}[i]
+
+	switch rot.mode {
+	case rotationSpeed:
+		*angle += rot.speed * dt
+	case rotationTo:
+		step := rot.speed * dt
+		if math.Abs(rot.amount) <= step {
+			*angle += rot.amount
+			rot.mode, rot.amount = rotationNone, 0
+			if g.rotationReached(r, 1) {
+				g.send(r, rtb.MessageRotationReached{Part: part})
+			}
+			return
+		}
+		*angle += math.Copysign(step, rot.amount)
+		rot.amount -= math.Copysign(step, rot.amount)
+	case rotationSweep:
+		*angle += rot.speed * dt
+		if *angle >= rot.left && rot.speed > 0 || *angle <= rot.right && rot.speed < 0 {
+			*angle = math.Max(rot.right, math.Min(*angle, rot.left))
+			rot.speed = -rot.speed
+			if g.rotationReached(r, 2) {
+				g.send(r, rtb.MessageRotationReached{Part: part})
+			}
+		}
+	}
+}
+
+// rotationReached reports whether RotationReached messages must be sent to r
+// for the given level of the SendRotationReached option.
+func (g *Game) rotationReached(r *robot, level int) bool {
+	return r.player.settings.SendRotationReached >= level
+}
+
+// relAngle returns the angle of p relative to the front of r.
+func (r *robot) relAngle(p point) float64 {
+	d := p.Sub(r.pos)
+	return physics.NormalizeAngle(math.Atan2(d.Y, d.X) - r.angle)
+}
+
+// moveShots moves the shots and checks their collisions.
+func (g *Game) moveShots(dt float64) {
+	shots := g.shots[:0]
+	for _, s := range g.shots {
+		speed := s.vel.Len()
+		if speed == 0 {
+			// A still shot has no direction to look for
+			// collisions along, so it just stays in place.
+			shots = append(shots, s)
+			continue
+		}
+		dir := s.vel.Mul(1 / speed)
+		step := speed * dt
+
+		dist, victim := step, (*robot)(nil)
+		for _, r := range g.robots {
+			if r.energy <= 0 {
+				continue
+			}
+			if t, ok := rayCircle(s.pos, dir, r.pos, physics.RobotRadius); ok && t <= dist {
+				dist, victim = t, r
+			}
+		}
+		wall := false
+		for _, w := range g.cfg.Arena.Walls {
+			if t, ok := rayWall(s.pos, dir, w); ok && t <= dist {
+				dist, victim, wall = t, nil, true
+			}
+		}
+		var obj *object
+		for _, o := range g.objects {
+			if t, ok := rayCircle(s.pos, dir, o.pos, o.radius); ok && t <= dist {
+				dist, victim, wall, obj = t, nil, false, o
+			}
+		}
+
+		switch {
+		case obj != nil:
+			g.removeObject(obj)
+		case victim != nil:
+			victim.energy -= s.energy
+			victim.result.DamageTaken += s.energy
+			s.owner.result.DamageDealt += s.energy
+			g.send(victim, rtb.MessageCollision{Object: rtb.ObjectShot, Angle: victim.relAngle(s.pos)})
+		case wall:
+		case g.cfg.Arena.Boundary.Contains(s.pos.Add(s.vel.Mul(dt))):
+			s.pos = s.pos.Add(s.vel.Mul(dt))
+			shots = append(shots, s)
+		}
+	}
+	g.shots = shots
+}
+
+// sense sends the radar and status messages of the current tick to r.
+func (g *Game) sense(r *robot) {
+	opts := g.cfg.Options
+
+	if hit, ok := g.scan(r); ok {
+		g.send(r, rtb.MessageRadar{Distance: hit.dist, Object: hit.object, RadarAngle: r.radar})
+		if hit.robot != nil {
+			g.send(r, rtb.MessageRobotInfo{
+				EnergyLevel: g.energyLevel(hit.robot.energy),
+				TeamMate:    r.player.team != "" && r.player.team == hit.robot.player.team,
+			})
+		}
+	}
+
+	g.send(r, rtb.MessageInfo{Time: g.time, Speed: r.speed(), CannonAngle: r.cannon})
+	if opts.SendRobotCoordinates != 0 {
+		g.send(r, rtb.MessageCoordinates{X: r.pos.X, Y: r.pos.Y, Angle: r.angle})
+	}
+	g.send(r, rtb.MessageEnergy{EnergyLevel: g.energyLevel(r.energy)})
+}
+
+// energyLevel discretizes energy into the number of levels given by the
+// RobotEnergyLevels option.
+func (g *Game) energyLevel(energy float64) float64 {
+	opts := g.cfg.Options
+	if opts.RobotEnergyLevels <= 0 {
+		return energy
+	}
+	step := opts.RobotMaxEnergy / opts.RobotEnergyLevels
+	return math.Max(0, math.Floor(energy/step)*step)
+}
+
+// finish ends the game.
+func (g *Game) finish() {
+	g.done = true
+	for _, r := range g.robots {
+		g.send(r, rtb.MessageGameFinishes{})
+	}
+}
+
+// Result returns the outcome of the game.
+func (g *Game) Result() Result {
+	res := Result{Time: g.time, Winner: -1}
+	for i, r := range g.robots {
+		rr := r.result
+		rr.Alive = r.energy > 0
+		rr.Energy = math.Max(0, r.energy)
+		res.Robots = append(res.Robots, rr)
+		if rr.Alive && g.alive == 1 {
+			res.Winner = i
+		}
+	}
+	return res
+}
+
+// Run runs the game until it finishes and returns its result. The pace of
+// the game is controlled by Config.Control.
+func (g *Game) Run() Result {
+	c := g.cfg.Control
+	if c == nil {
+		for g.Step() {
+		}
+		return g.Result()
+	}
+	for !g.done {
+		c.wait(g.cfg.TimeStep)
+		g.Step()
+		c.done()
+	}
+	return g.Result()
+}
+
+// Exit sends MessageExitRobot to the players. It must be called at the end of
+// a sequence of games.
+func Exit(players []*Player) {
+	for _, p := range players {
+		p.client.Deliver(p.strategy, rtb.MessageExitRobot{})
+		p.out.Reset()
+	}
+}
+
+// newID returns a new identifier for a shot or an object.
+func (g *Game) newID() int {
+	g.lastID++
+	return g.lastID
+}
@@ -0,0 +1,1062 @@
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/internal/rtbtest"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// turret is a strategy that rotates until the radar detects a robot and then
+// shoots it.
+var turret = rtbtest.Turret{Name: "turret", Home: "ff0000", Away: "00ff00", Speed: 1, Energy: 5}
+
+// duck is a strategy that does nothing.
+func duck(r *rtb.Robot, msg rtb.Message) {
+	if _, ok := msg.(rtb.MessageInitialize); ok {
+		r.Name("duck")
+		r.Colour("0000ff", "00ff00")
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		cmd    command
+		nilErr bool
+	}{
+		{
+			"Name",
+			"Name foo bar",
+			command{Keyword: "Name", Text: "foo bar"},
+			true,
+		},
+		{
+			"Rotate",
+			"Rotate 6 1.230000",
+			command{Keyword: "Rotate", Part: rtb.PartCannon | rtb.PartRadar, Args: []float64{1.23}},
+			true,
+		},
+		{
+			"Sweep",
+			"Sweep 4 1 -2 3",
+			command{Keyword: "Sweep", Part: rtb.PartRadar, Args: []float64{1, -2, 3}},
+			true,
+		},
+		{
+			"Shoot",
+			"Shoot 1.5",
+			command{Keyword: "Shoot", Args: []float64{1.5}},
+			true,
+		},
+		{
+			"Wrong number of arguments",
+			"Shoot 1.5 2",
+			command{},
+			false,
+		},
+		{
+			"Invalid argument",
+			"Accelerate foo",
+			command{},
+			false,
+		},
+		{
+			"Unknown command",
+			"Foo 1",
+			command{},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := parseCommand(tt.line)
+			if (err == nil) != tt.nilErr {
+				t.Errorf("unexpected error: got=%v", err)
+			}
+			if !reflect.DeepEqual(cmd, tt.cmd) {
+				t.Errorf("wrong command: got=%#v want=%#v", cmd, tt.cmd)
+			}
+		})
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	r := rtb.NewRobot(nil, &buf)
+
+	f := func(seed int64) bool {
+		rnd := rand.New(rand.NewSource(seed))
+		part := rtb.Part(1 + rnd.Intn(7))
+		args := []float64{rnd.NormFloat64(), rnd.NormFloat64(), rnd.NormFloat64(), rnd.NormFloat64()}
+
+		sends := []struct {
+			f    func()
+			want command
+		}{
+			{func() { r.Rotate(part, args[0]) }, command{Keyword: "Rotate", Part: part, Args: args[:1]}},
+			{func() { r.RotateTo(part, args[0], args[1]) }, command{Keyword: "RotateTo", Part: part, Args: args[:2]}},
+			{func() { r.RotateAmount(part, args[0], args[1]) }, command{Keyword: "RotateAmount", Part: part, Args: args[:2]}},
+			{func() { r.Sweep(part, args[0], args[1], args[2]) }, command{Keyword: "Sweep", Part: part, Args: args[:3]}},
+			{func() { r.Accelerate(args[0]) }, command{Keyword: "Accelerate", Args: args[:1]}},
+			{func() { r.Brake(args[0]) }, command{Keyword: "Brake", Args: args[:1]}},
+			{func() { r.Shoot(args[0]) }, command{Keyword: "Shoot", Args: args[:1]}},
+			{func() { r.DebugLine(args[0], args[1], args[2], args[3]) }, command{Keyword: "DebugLine", Args: args}},
+			{func() { r.DebugCircle(args[0], args[1], args[2]) }, command{Keyword: "DebugCircle", Args: args[:3]}},
+			{func() { r.Printf("foo %v", args[0]) }, command{Keyword: "Print", Text: "foo " + fmt.Sprint(args[0])}},
+		}
+
+		for _, s := range sends {
+			buf.Reset()
+			s.f()
+			got, err := parseCommand(strings.TrimSuffix(buf.String(), "\n"))
+			if err != nil {
+				t.Logf("parse error: %v", err)
+				return false
+			}
+			if got.Keyword != s.want.Keyword || got.Part != s.want.Part || got.Text != s.want.Text || len(got.Args) != len(s.want.Args) {
+				t.Logf("round trip mismatch: got=%#v want=%#v", got, s.want)
+				return false
+			}
+			for i := range got.Args {
+				// Commands are sent with 6 decimals.
+				if math.Abs(got.Args[i]-s.want.Args[i]) > 5e-7 {
+					t.Logf("argument mismatch: got=%v want=%v", got.Args, s.want.Args)
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRayWall(t *testing.T) {
+	m := arena.DefaultMaterial
+	o := point{X: 0, Y: 0}
+	dir := point{X: 1, Y: 0}
+
+	tests := []struct {
+		name string
+		w    arena.Wall
+		dist float64
+		hit  bool
+	}{
+		{"Line", arena.Line{Material: m, Start: point{X: 2, Y: -1}, End: point{X: 2, Y: 1}}, 2, true},
+		{"Line miss", arena.Line{Material: m, Start: point{X: 2, Y: 1}, End: point{X: 2, Y: 3}}, 0, false},
+		{"Circle", arena.Circle{Material: m, Center: point{X: 5, Y: 0}, Radius: 1}, 4, true},
+		{"InnerCircle", arena.InnerCircle{Material: m, Center: point{X: 0, Y: 0}, Radius: 3}, 3, true},
+		{"Arc", arena.Arc{Material: m, InnerRadius: 2, OuterRadius: 3, Angle1: -0.1, Angle2: 0.1}, 2, true},
+		{"Arc miss", arena.Arc{Material: m, InnerRadius: 2, OuterRadius: 3, Angle1: 1, Angle2: 2}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist, hit := rayWall(o, dir, tt.w)
+			if hit != tt.hit {
+				t.Fatalf("unexpected hit: got=%v want=%v", hit, tt.hit)
+			}
+			if hit && math.Abs(dist-tt.dist) > 1e-9 {
+				t.Errorf("unexpected distance: got=%v want=%v", dist, tt.dist)
+			}
+		})
+	}
+}
+
+func TestGame(t *testing.T) {
+	players := []*Player{
+		NewPlayer(turret, rtb.ListenSettings{}),
+		NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+	}
+
+	g, err := NewGame(Config{Seed: 1}, players)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res := g.Run()
+
+	if res.Winner != 0 {
+		t.Fatalf("unexpected winner: got=%v want=%v", res.Winner, 0)
+	}
+	if res.Robots[1].Alive || res.Robots[1].DamageTaken < DefaultOptions().RobotStartEnergy {
+		t.Errorf("unexpected loser result: %#v", res.Robots[1])
+	}
+	if res.Robots[0].DamageDealt != res.Robots[1].DamageTaken {
+		t.Errorf("damage mismatch: dealt=%v taken=%v", res.Robots[0].DamageDealt, res.Robots[1].DamageTaken)
+	}
+	if players[0].Name() != "turret" || players[1].Colour() != "0000ff" {
+		t.Errorf("unexpected player info: %q %q", players[0].Name(), players[1].Colour())
+	}
+}
+
+func TestRecord(t *testing.T) {
+	var log bytes.Buffer
+	p := NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})
+	p.Record(&log)
+
+	g, err := NewGame(Config{}, []*Player{p})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.Step()
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if lines[0] != "Initialize 1" {
+		t.Errorf("unexpected first message: %q", lines[0])
+	}
+	for _, line := range lines {
+		if _, err := rtb.ParseMessage(line); err != nil {
+			t.Errorf("could not parse recorded message %q: %v", line, err)
+		}
+	}
+}
+
+func TestRotationReached(t *testing.T) {
+	var got []rtb.Part
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		switch m := msg.(type) {
+		case rtb.MessageGameStarts:
+			r.RotateAmount(rtb.PartCannon, 1, 0.5)
+		case rtb.MessageRotationReached:
+			got = append(got, m.Part)
+		}
+	}
+
+	p := NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{SendRotationReached: 1})
+	g, err := NewGame(Config{}, []*Player{p})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		g.Step()
+	}
+
+	if !reflect.DeepEqual(got, []rtb.Part{rtb.PartCannon}) {
+		t.Errorf("unexpected rotations: got=%v", got)
+	}
+}
+
+func TestRotationWraparound(t *testing.T) {
+	var got []rtb.Part
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		if m, ok := msg.(rtb.MessageRotationReached); ok {
+			got = append(got, m.Part)
+		}
+	}
+
+	p := NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{SendRotationReached: 1})
+	g, err := NewGame(Config{}, []*Player{p})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := g.robots[0]
+	r.angle = 3
+	g.Do(0, func(r *rtb.Robot) {
+		r.RotateAmount(rtb.PartRobot, 1, 1)
+	})
+	for i := 0; i < 100; i++ {
+		g.Step()
+	}
+
+	if !reflect.DeepEqual(got, []rtb.Part{rtb.PartRobot}) {
+		t.Errorf("unexpected rotations: got=%v", got)
+	}
+	if want := physics.NormalizeAngle(4); math.Abs(r.angle-want) > 1e-9 {
+		t.Errorf("unexpected angle: got=%v want=%v", r.angle, want)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var speed float64
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		if m, ok := msg.(rtb.MessageInfo); ok {
+			speed = m.Speed
+		}
+	}
+
+	p := NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})
+	g, err := NewGame(Config{}, []*Player{p})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.Do(0, func(r *rtb.Robot) {
+		r.Accelerate(1)
+	})
+	g.Step()
+
+	if speed <= 0 {
+		t.Errorf("robot not accelerating: speed=%v", speed)
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	// The expected velocities are computed by hand with the update
+	// equations of the RealTimeBattle server for a tick of 0.05s. They
+	// are not checked against logs recorded from a real server.
+	tests := []struct {
+		name  string
+		angle float64
+		vel   arena.Point
+		accel float64
+		brake float64
+		want  arena.Point
+	}{
+		{
+			name:  "accelerate from rest",
+			accel: 2,
+			want:  arena.Point{X: 0.1},
+		},
+		{
+			name: "roll",
+			vel:  arena.Point{X: 1},
+			want: arena.Point{X: 0.998768},
+		},
+		{
+			name:  "brake",
+			vel:   arena.Point{X: 1},
+			brake: 1,
+			want:  arena.Point{X: 0.51857},
+		},
+		{
+			name:  "half brake",
+			vel:   arena.Point{X: 1},
+			brake: 0.5,
+			want:  arena.Point{X: 0.758669},
+		},
+		{
+			name: "slide",
+			vel:  arena.Point{Y: 1},
+			want: arena.Point{Y: 0.51857},
+		},
+		{
+			name:  "turned",
+			angle: math.Pi / 2,
+			vel:   arena.Point{X: 1, Y: 1},
+			want:  arena.Point{X: 0.51857, Y: 0.998768},
+		},
+		{
+			name:  "reverse",
+			angle: math.Pi,
+			accel: -0.5,
+			want:  arena.Point{X: 0.025},
+		},
+	}
+
+	g, err := NewGame(Config{}, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &robot{angle: tt.angle, vel: tt.vel, accel: tt.accel, brake: tt.brake}
+			got := g.velocity(r, 0.05)
+			if math.Abs(got.X-tt.want.X) > 1e-6 || math.Abs(got.Y-tt.want.Y) > 1e-6 {
+				t.Errorf("unexpected velocity: got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKinematics(t *testing.T) {
+	var speeds []float64
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		if m, ok := msg.(rtb.MessageInfo); ok {
+			speeds = append(speeds, m.Speed)
+		}
+	}
+
+	p := NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})
+	g, err := NewGame(Config{}, []*Player{p})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := g.robots[0]
+	g.Do(0, func(r *rtb.Robot) {
+		// The client checks are bypassed, so the simulator has to clamp
+		// the acceleration and the rotation speeds to the game options.
+		r.SendRaw("Accelerate", "100")
+		r.SendRaw("Rotate", rtb.PartRobot|rtb.PartCannon, "100")
+	})
+
+	angle, cannon := r.angle, r.cannon
+	g.Step()
+	opts := DefaultOptions()
+	if want := opts.RobotMaxAcceleration * g.cfg.TimeStep; math.Abs(speeds[len(speeds)-1]-want) > 1e-9 {
+		t.Errorf("unexpected speed: got=%v want=%v", speeds[len(speeds)-1], want)
+	}
+	if d := physics.NormalizeAngle(r.angle - angle); math.Abs(d-opts.RobotMaxRotate*g.cfg.TimeStep) > 1e-9 {
+		t.Errorf("unexpected robot rotation: got=%v", d)
+	}
+	if d := r.cannon - cannon; math.Abs(d-opts.RobotCannonMaxRotate*g.cfg.TimeStep) > 1e-9 {
+		t.Errorf("unexpected cannon rotation: got=%v", d)
+	}
+
+	// Shots inherit the velocity of the robot.
+	r.shotEnergy = opts.ShotMaxEnergy
+	vel := r.vel
+	g.Do(0, func(rr *rtb.Robot) {
+		rr.Shoot(5)
+	})
+	if len(g.shots) != 1 {
+		t.Fatalf("unexpected shots: %v", len(g.shots))
+	}
+	want := arena.Polar(r.angle+r.cannon, opts.ShotSpeed).Add(vel)
+	if got := g.shots[0].vel; got.Sub(want).Len() > 1e-9 {
+		t.Errorf("unexpected shot velocity: got=%v want=%v", got, want)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		angle      float64
+		vel        arena.Point
+		other      *robot
+		wantVel    arena.Point
+		wantEnergy float64
+		wantOther  arena.Point
+		wantOtherE float64
+	}{
+		{
+			name:       "wall front",
+			vel:        arena.Point{X: 2},
+			wantVel:    arena.Point{X: -0.7},
+			wantEnergy: 99.9,
+		},
+		{
+			name:       "wall side",
+			angle:      math.Pi / 2,
+			vel:        arena.Point{X: 2},
+			wantVel:    arena.Point{X: -0.7},
+			wantEnergy: 99.5,
+		},
+		{
+			name:       "wall leaving",
+			vel:        arena.Point{X: -2},
+			wantVel:    arena.Point{X: -2},
+			wantEnergy: 100,
+		},
+		{
+			name:       "ram",
+			vel:        arena.Point{X: 2},
+			other:      &robot{pos: arena.Point{X: 6, Y: 10}, angle: math.Pi / 2, energy: 100},
+			wantVel:    arena.Point{X: 0.51},
+			wantEnergy: 99.9,
+			wantOther:  arena.Point{X: 1.49},
+			wantOtherE: 99.1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []rtb.Object
+			s := func(r *rtb.Robot, msg rtb.Message) {
+				if m, ok := msg.(rtb.MessageCollision); ok {
+					got = append(got, m.Object)
+				}
+			}
+
+			g, err := NewGame(Config{}, []*Player{NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := g.robots[0]
+			r.pos, r.angle, r.vel = arena.Point{X: 5, Y: 10}, tt.angle, tt.vel
+
+			want := rtb.ObjectWall
+			if tt.other != nil {
+				tt.other.player = NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})
+				g.hitRobot(r, tt.other)
+				want = rtb.ObjectRobot
+			} else {
+				g.hitWall(r, arena.Point{X: 5.5, Y: 10}, arena.DefaultMaterial)
+			}
+
+			if r.vel.Sub(tt.wantVel).Len() > 1e-9 {
+				t.Errorf("unexpected velocity: got=%v want=%v", r.vel, tt.wantVel)
+			}
+			if math.Abs(r.energy-tt.wantEnergy) > 1e-9 {
+				t.Errorf("unexpected energy: got=%v want=%v", r.energy, tt.wantEnergy)
+			}
+			if math.Abs(r.result.DamageTaken-(100-tt.wantEnergy)) > 1e-9 {
+				t.Errorf("unexpected damage taken: got=%v want=%v", r.result.DamageTaken, 100-tt.wantEnergy)
+			}
+			if tt.other != nil {
+				if tt.other.vel.Sub(tt.wantOther).Len() > 1e-9 {
+					t.Errorf("unexpected other velocity: got=%v want=%v", tt.other.vel, tt.wantOther)
+				}
+				if math.Abs(tt.other.energy-tt.wantOtherE) > 1e-9 {
+					t.Errorf("unexpected other energy: got=%v want=%v", tt.other.energy, tt.wantOtherE)
+				}
+			}
+			if !reflect.DeepEqual(got, []rtb.Object{want}) {
+				t.Errorf("unexpected collisions: got=%v want=%v", got, []rtb.Object{want})
+			}
+		})
+	}
+}
+
+func TestWallMaterials(t *testing.T) {
+	tests := []struct {
+		name       string
+		material   arena.Material
+		wantVel    arena.Point
+		wantEnergy float64
+	}{
+		{
+			name:       "default",
+			material:   arena.DefaultMaterial,
+			wantVel:    arena.Point{X: -0.7},
+			wantEnergy: 99.5,
+		},
+		{
+			name:       "hard",
+			material:   arena.Material{BounceCoeff: 1, Hardness: 1},
+			wantVel:    arena.Point{X: -1.4},
+			wantEnergy: 99,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Arena: &arena.Arena{
+				Boundary: arena.Rect{Max: arena.Point{X: 20, Y: 20}},
+				Walls:    []arena.Wall{arena.Line{Material: tt.material, Thickness: 0.1, Start: arena.Point{X: 6, Y: 0}, End: arena.Point{X: 6, Y: 20}}},
+			}}
+			g, err := NewGame(cfg, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := g.robots[0]
+			r.pos, r.angle, r.vel = arena.Point{X: 5, Y: 10}, math.Pi/2, arena.Point{X: 2}
+
+			q, w, ok := g.overlapsWall(arena.Point{X: 5.5, Y: 10})
+			if !ok {
+				t.Fatal("expected wall overlap")
+			}
+			g.hitWall(r, q, wallMaterial(w))
+
+			if r.vel.Sub(tt.wantVel).Len() > 1e-9 {
+				t.Errorf("unexpected velocity: got=%v want=%v", r.vel, tt.wantVel)
+			}
+			if math.Abs(r.energy-tt.wantEnergy) > 1e-9 {
+				t.Errorf("unexpected energy: got=%v want=%v", r.energy, tt.wantEnergy)
+			}
+		})
+	}
+}
+
+func TestRadar(t *testing.T) {
+	tests := []struct {
+		name    string
+		team    string
+		objects []*object
+		shot    bool
+		want    []rtb.Message
+	}{
+		{
+			name: "robot",
+			want: []rtb.Message{
+				rtb.MessageRadar{Distance: 2.5, Object: rtb.ObjectRobot, RadarAngle: -math.Pi / 2},
+				rtb.MessageRobotInfo{EnergyLevel: 96},
+			},
+		},
+		{
+			name: "team mate",
+			team: "red",
+			want: []rtb.Message{
+				rtb.MessageRadar{Distance: 2.5, Object: rtb.ObjectRobot, RadarAngle: -math.Pi / 2},
+				rtb.MessageRobotInfo{EnergyLevel: 96, TeamMate: true},
+			},
+		},
+		{
+			name:    "cookie in front",
+			objects: []*object{{kind: rtb.ObjectCookie, pos: arena.Point{X: 7, Y: 10}, radius: 0.3}},
+			want: []rtb.Message{
+				rtb.MessageRadar{Distance: 1.7, Object: rtb.ObjectCookie, RadarAngle: -math.Pi / 2},
+			},
+		},
+		{
+			name:    "mine behind",
+			objects: []*object{{kind: rtb.ObjectMine, pos: arena.Point{X: 10, Y: 10}, radius: 0.3}},
+			want: []rtb.Message{
+				rtb.MessageRadar{Distance: 2.5, Object: rtb.ObjectRobot, RadarAngle: -math.Pi / 2},
+				rtb.MessageRobotInfo{EnergyLevel: 96},
+			},
+		},
+		{
+			name: "shots are not detected",
+			shot: true,
+			want: []rtb.Message{
+				rtb.MessageRadar{Distance: 2.5, Object: rtb.ObjectRobot, RadarAngle: -math.Pi / 2},
+				rtb.MessageRobotInfo{EnergyLevel: 96},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []rtb.Message
+			s := func(r *rtb.Robot, msg rtb.Message) {
+				switch msg.(type) {
+				case rtb.MessageRadar, rtb.MessageRobotInfo:
+					got = append(got, msg)
+				}
+			}
+
+			players := []*Player{
+				NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{}),
+				NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+			}
+			players[0].SetTeam(tt.team)
+			players[1].SetTeam(tt.team)
+			g, err := NewGame(Config{}, players)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := g.robots[0]
+			r.pos, r.angle, r.radar = arena.Point{X: 5, Y: 10}, math.Pi/2, -math.Pi/2
+			g.robots[1].pos = arena.Point{X: 8, Y: 10}
+			g.objects = tt.objects
+			if tt.shot {
+				g.shots = []*shot{{pos: arena.Point{X: 6, Y: 10}, vel: arena.Point{Y: 1}}}
+			}
+			g.sense(r)
+
+			for i, m := range got {
+				if radar, ok := m.(rtb.MessageRadar); ok {
+					radar.Distance = math.Round(radar.Distance*1e9) / 1e9
+					got[i] = radar
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected messages: got=%#v want=%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	players := func() []*Player {
+		return []*Player{
+			NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+			NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+			NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+		}
+	}
+
+	cfg := Config{
+		Seed: 1,
+		Placements: []*Placement{
+			nil,
+			{Pos: arena.Point{X: 3, Y: 4}, Angle: 1, Energy: 50},
+		},
+		Objects: []Object{{Kind: rtb.ObjectCookie, Pos: arena.Point{X: 6, Y: 6}, Energy: 10}},
+	}
+	g, err := NewGame(cfg, players())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := g.robots[1]; r.pos != (arena.Point{X: 3, Y: 4}) || r.angle != 1 || r.energy != 50 {
+		t.Errorf("unexpected placed robot: pos=%v angle=%v energy=%v", r.pos, r.angle, r.energy)
+	}
+	for _, i := range []int{0, 2} {
+		r := g.robots[i]
+		if r.energy != DefaultOptions().RobotStartEnergy {
+			t.Errorf("unexpected energy of robot %v: %v", i, r.energy)
+		}
+		if other := g.overlapsRobot(r, r.pos); other != nil {
+			t.Errorf("robot %v overlaps another robot", i)
+		}
+	}
+	if len(g.objects) != 1 || g.objects[0].radius != 0.3 {
+		t.Errorf("unexpected objects: %v", g.objects)
+	}
+
+	errs := []struct {
+		name string
+		cfg  Config
+	}{
+		{"wall", Config{Placements: []*Placement{{Pos: arena.Point{X: 0.2, Y: 10}}}}},
+		{"robot", Config{Placements: []*Placement{{Pos: arena.Point{X: 5, Y: 5}}, {Pos: arena.Point{X: 5.5, Y: 5}}}}},
+		{"object", Config{Objects: []Object{{Kind: rtb.ObjectWall}}}},
+	}
+	for _, tt := range errs {
+		if _, err := NewGame(tt.cfg, players()); err == nil {
+			t.Errorf("%v: expected error", tt.name)
+		}
+	}
+}
+
+func TestSpawn(t *testing.T) {
+	cfg := Config{
+		Seed:    1,
+		Cookies: &Spawn{Frequency: 1 / DefaultTimeStep, MinEnergy: 10, MaxEnergy: 15, Max: 3},
+		Mines:   &Spawn{Frequency: 1 / DefaultTimeStep, MinEnergy: 15, MaxEnergy: 25, Max: 2},
+	}
+	g, err := NewGame(cfg, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		g.Step()
+	}
+
+	if n := g.countObjects(rtb.ObjectCookie); n != 3 {
+		t.Errorf("unexpected number of cookies: got=%v want=%v", n, 3)
+	}
+	if n := g.countObjects(rtb.ObjectMine); n != 2 {
+		t.Errorf("unexpected number of mines: got=%v want=%v", n, 2)
+	}
+	for i, o := range g.objects {
+		s := cfg.Cookies
+		if o.kind == rtb.ObjectMine {
+			s = cfg.Mines
+		}
+		if o.energy < s.MinEnergy || o.energy > s.MaxEnergy {
+			t.Errorf("energy out of range: %v", o.energy)
+		}
+		if o.radius != 0.3 {
+			t.Errorf("unexpected radius: got=%v want=%v", o.radius, 0.3)
+		}
+		if !g.cfg.Arena.Boundary.Contains(o.pos) {
+			t.Errorf("object out of the arena: %v", o.pos)
+		}
+		if d := o.pos.Sub(g.robots[0].pos).Len(); d < physics.RobotRadius+o.radius {
+			t.Errorf("object overlaps robot: distance=%v", d)
+		}
+		for _, other := range g.objects[i+1:] {
+			if d := o.pos.Sub(other.pos).Len(); d < o.radius+other.radius {
+				t.Errorf("objects overlap: distance=%v", d)
+			}
+		}
+	}
+}
+
+func TestObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       rtb.Object
+		energy     float64
+		wantEnergy float64
+		wantResult RobotResult
+	}{
+		{
+			name:       "cookie",
+			kind:       rtb.ObjectCookie,
+			energy:     12,
+			wantEnergy: 112,
+			wantResult: RobotResult{CookiesEaten: 1},
+		},
+		{
+			name:       "cookie max energy",
+			kind:       rtb.ObjectCookie,
+			energy:     30,
+			wantEnergy: 120,
+			wantResult: RobotResult{CookiesEaten: 1},
+		},
+		{
+			name:       "mine",
+			kind:       rtb.ObjectMine,
+			energy:     20,
+			wantEnergy: 80,
+			wantResult: RobotResult{MinesHit: 1, DamageTaken: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []rtb.Object
+			s := func(r *rtb.Robot, msg rtb.Message) {
+				if m, ok := msg.(rtb.MessageCollision); ok {
+					got = append(got, m.Object)
+				}
+			}
+
+			g, err := NewGame(Config{}, []*Player{NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := g.robots[0]
+			g.objects = []*object{{kind: tt.kind, pos: r.pos, radius: 0.3, energy: tt.energy}}
+			g.Do(0, func(r *rtb.Robot) {
+				r.Accelerate(1)
+			})
+			g.Step()
+
+			if len(g.objects) != 0 {
+				t.Errorf("object not removed")
+			}
+			if r.energy != tt.wantEnergy {
+				t.Errorf("unexpected energy: got=%v want=%v", r.energy, tt.wantEnergy)
+			}
+			r.result.CPUTime = 0
+			if r.result != tt.wantResult {
+				t.Errorf("unexpected result: got=%+v want=%+v", r.result, tt.wantResult)
+			}
+			if !reflect.DeepEqual(got, []rtb.Object{tt.kind}) {
+				t.Errorf("unexpected collisions: got=%v want=%v", got, []rtb.Object{tt.kind})
+			}
+		})
+	}
+}
+
+func TestShootObject(t *testing.T) {
+	var objects []rtb.Object
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		if m, ok := msg.(rtb.MessageRadar); ok {
+			objects = append(objects, m.Object)
+		}
+	}
+
+	g, err := NewGame(Config{}, []*Player{NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := g.robots[0]
+	r.pos, r.angle = arena.Point{X: 5, Y: 10}, 0
+	g.objects = []*object{{kind: rtb.ObjectMine, pos: arena.Point{X: 8, Y: 10}, radius: 0.3, energy: 20}}
+	g.Step()
+	g.Do(0, func(r *rtb.Robot) {
+		r.Shoot(1)
+	})
+	for i := 0; i < 10; i++ {
+		g.Step()
+	}
+
+	if objects[0] != rtb.ObjectMine {
+		t.Errorf("mine not detected: got=%v", objects[0])
+	}
+	if len(g.objects) != 0 || len(g.shots) != 0 {
+		t.Errorf("mine not destroyed: objects=%v shots=%v", len(g.objects), len(g.shots))
+	}
+	if r.energy != DefaultOptions().RobotStartEnergy {
+		t.Errorf("unexpected energy: %v", r.energy)
+	}
+}
+
+func TestStillShot(t *testing.T) {
+	g, err := NewGame(Config{}, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := g.robots[0]
+	r.pos = arena.Point{X: 5, Y: 10}
+	g.shots = []*shot{{owner: r, pos: arena.Point{X: 10, Y: 10}, energy: 1}}
+	g.Step()
+
+	if len(g.shots) != 1 || g.shots[0].pos != (arena.Point{X: 10, Y: 10}) {
+		t.Errorf("unexpected shots: %+v", g.shots)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	s := func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageInfo); ok {
+			r.DebugLine(0, 0, 0, 1)
+			r.DebugCircle(math.Pi/2, 2, 0.5)
+		}
+	}
+
+	var frames []Frame
+	cfg := Config{Frames: func(f Frame) { frames = append(frames, f) }}
+	g, err := NewGame(cfg, []*Player{NewPlayer(rtb.StrategyFunc(s), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g.Step()
+	g.Step()
+
+	if len(frames) != 2 {
+		t.Fatalf("unexpected number of frames: got=%v want=%v", len(frames), 2)
+	}
+	rf := frames[1].Robots[0]
+	if len(rf.Lines) != 1 || len(rf.Circles) != 1 {
+		t.Fatalf("debug shapes not cleared: lines=%v circles=%v", len(rf.Lines), len(rf.Circles))
+	}
+	if want := rf.Pos.Add(arena.Polar(rf.Angle, 1)); rf.Lines[0].A != rf.Pos || rf.Lines[0].B.Sub(want).Len() > 1e-5 {
+		t.Errorf("unexpected line: got=%v want=%v", rf.Lines[0], Line{A: rf.Pos, B: want})
+	}
+	if want := rf.Pos.Add(arena.Polar(rf.Angle+math.Pi/2, 2)); rf.Circles[0].Center.Sub(want).Len() > 1e-5 || rf.Circles[0].Radius != 0.5 {
+		t.Errorf("unexpected circle: got=%v want=%v", rf.Circles[0], Circle{Center: want, Radius: 0.5})
+	}
+	if rf.RadarHit == nil {
+		t.Errorf("missing radar hit")
+	}
+	if frames[1].Time != 2*DefaultTimeStep {
+		t.Errorf("unexpected time: got=%v want=%v", frames[1].Time, 2*DefaultTimeStep)
+	}
+}
+
+func TestControlStep(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Timeout = 1
+
+	c := NewControl()
+	c.Pause()
+	g, err := NewGame(Config{Options: opts, Control: c}, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := make(chan Result)
+	go func() {
+		done <- g.Run()
+	}()
+
+	c.Step()
+	c.Step()
+	for c.Ticks() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := c.Ticks(); got != 2 {
+		t.Errorf("unexpected ticks: got=%v want=%v", got, 2)
+	}
+	if got, want := g.Time(), 2*DefaultTimeStep; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected time: got=%v want=%v", got, want)
+	}
+
+	c.Resume()
+	select {
+	case res := <-done:
+		if math.Abs(res.Time-opts.Timeout) > 1e-9 {
+			t.Errorf("unexpected game time: got=%v want=%v", res.Time, opts.Timeout)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("game not resumed")
+	}
+}
+
+func TestControlSpeed(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Timeout = 1
+
+	c := NewControl()
+	c.SetSpeed(10)
+	g, err := NewGame(Config{Options: opts, Control: c}, []*Player{NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	g.Run()
+
+	// The first tick runs immediately and the next ones every 5ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("game too fast: %v", elapsed)
+	}
+	if got, want := c.Ticks(), int64(20); got != want {
+		t.Errorf("unexpected ticks: got=%v want=%v", got, want)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	run := func() Result {
+		players := []*Player{
+			NewPlayer(turret, rtb.ListenSettings{}),
+			NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+		}
+		g, err := NewGame(Config{Seed: 1, Concurrent: true}, players)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res := g.Run()
+		for i := range res.Robots {
+			res.Robots[i].CPUTime = 0
+		}
+		return res
+	}
+
+	res := run()
+	if res.Winner != 0 {
+		t.Fatalf("unexpected winner: got=%v want=%v", res.Winner, 0)
+	}
+	if again := run(); !reflect.DeepEqual(res, again) {
+		t.Errorf("concurrent games differ:\n%#v\n%#v", res, again)
+	}
+}
+
+func TestConcurrentParallel(t *testing.T) {
+	// Both strategies wait for each other when the game starts, which
+	// only succeeds if they run in parallel.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	met := make(chan bool, 2)
+	meet := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageGameStarts); !ok {
+			return
+		}
+		wg.Done()
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			met <- true
+		case <-time.After(5 * time.Second):
+			met <- false
+		}
+	})
+
+	players := []*Player{NewPlayer(meet, rtb.ListenSettings{}), NewPlayer(meet, rtb.ListenSettings{})}
+	if _, err := NewGame(Config{Concurrent: true}, players); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !<-met || !<-met {
+		t.Errorf("strategies did not run in parallel")
+	}
+}
+
+func TestCPULimits(t *testing.T) {
+	var warnings []string
+	slow := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		switch m := msg.(type) {
+		case rtb.MessageInfo:
+			time.Sleep(2 * time.Millisecond)
+		case rtb.MessageWarning:
+			if m.Warning == rtb.WarningProcessTimeLow {
+				warnings = append(warnings, m.Message)
+			}
+		}
+	})
+
+	players := []*Player{
+		NewPlayer(slow, rtb.ListenSettings{}),
+		NewPlayer(rtb.StrategyFunc(duck), rtb.ListenSettings{}),
+	}
+	g, err := NewGame(Config{CPU: &CPULimits{Start: 0.02, Warning: 0.5}}, players)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res := g.Run()
+
+	if rr := res.Robots[0]; rr.Alive || !rr.CPUExceeded || rr.CPUTime < 20*time.Millisecond {
+		t.Errorf("unexpected slow result: %#v", rr)
+	}
+	if res.Winner != 1 {
+		t.Errorf("unexpected winner: got=%v want=1", res.Winner)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("unexpected warnings: %q", warnings)
+	}
+	if players[0].CPUTime() != res.Robots[0].CPUTime {
+		t.Errorf("unexpected player CPU time: got=%v want=%v", players[0].CPUTime(), res.Robots[0].CPUTime)
+	}
+}
@@ -0,0 +1,341 @@
+// Package stats collects per-game statistics of a robot and accumulates
+// them across the games of a sequence, so a summary can be printed or
+// persisted when the robot exits:
+//
+//	c := stats.New(stats.Config{Print: r, Summary: f})
+//	r.AddObserver(c)
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Stats are the statistics of a game.
+type Stats struct {
+	// Game is the number of the game, starting at 1.
+	Game int
+
+	// ShotsFired is the number of Shoot commands sent.
+	ShotsFired int
+
+	// ShotEnergy is the total energy requested by the Shoot commands.
+	ShotEnergy float64
+
+	// Collisions is the number of collisions by object type.
+	Collisions map[rtb.Object]int
+
+	// CookiesEaten is the number of cookies eaten.
+	CookiesEaten int
+
+	// DamageTaken is the energy lost by source. Energy losses that cannot
+	// be attributed to a collision are accounted to ObjectNoObject. Since
+	// the server discretizes energy levels, it is an approximation.
+	DamageTaken map[rtb.Object]float64
+
+	// TimeSurvived is the game time elapsed until the robot died or the
+	// game finished.
+	TimeSurvived float64
+
+	// Dead is true if the robot died.
+	Dead bool
+
+	// Won is true if the robot was alive when the game finished. Since
+	// the server does not report the winner, it is an approximation.
+	Won bool
+
+	// DamageDealt is an estimate of the energy lost by other robots due
+	// to the robot. Collisions between robots hurt both of them equally,
+	// so it includes the damage taken from robots, plus the shot energy
+	// weighted by Config.HitRate.
+	DamageDealt float64
+}
+
+// TotalDamage returns the total energy lost by the robot.
+func (s Stats) TotalDamage() float64 {
+	var total float64
+	for _, d := range s.DamageTaken {
+		total += d
+	}
+	return total
+}
+
+// clone returns a deep copy of s.
+func (s Stats) clone() Stats {
+	c := s
+	c.Collisions = make(map[rtb.Object]int, len(s.Collisions))
+	for k, v := range s.Collisions {
+		c.Collisions[k] = v
+	}
+	c.DamageTaken = make(map[rtb.Object]float64, len(s.DamageTaken))
+	for k, v := range s.DamageTaken {
+		c.DamageTaken[k] = v
+	}
+	return c
+}
+
+// String returns a short summary of s. It fits in a Print message.
+func (s Stats) String() string {
+	var collisions int
+	for _, n := range s.Collisions {
+		collisions += n
+	}
+	return fmt.Sprintf("game=%v time=%.1f dead=%v shots=%v/%.1f damage=%.1f collisions=%v cookies=%v",
+		s.Game, s.TimeSurvived, s.Dead, s.ShotsFired, s.ShotEnergy, s.TotalDamage(), collisions, s.CookiesEaten)
+}
+
+// Config is the configuration of a Collector.
+type Config struct {
+	// Print, if not nil, is used to print a summary of the statistics
+	// in the message window when the game finishes.
+	Print *rtb.Robot
+
+	// Output, if not nil, receives the statistics of every finished game
+	// as a JSON line, so they can be aggregated across a tournament.
+	Output io.Writer
+
+	// Summary, if not nil, receives the summary of the sequence as a
+	// JSON line when the robot exits. If Print is not nil, the summary
+	// is also printed in the message window.
+	Summary io.Writer
+
+	// HitRate is the estimated fraction of the shot energy that hits
+	// other robots, used to estimate the damage dealt. If zero, only the
+	// damage dealt in collisions with robots is estimated.
+	HitRate float64
+}
+
+// Collector collects the statistics of the games played by a robot. It
+// implements the rtb.Observer interface, so it can be attached to a robot
+// with rtb.Robot.AddObserver.
+type Collector struct {
+	cfg Config
+
+	mu      sync.Mutex
+	cur     Stats
+	games   []Stats
+	energy  float64
+	pending []rtb.Object
+	started bool
+	err     error
+}
+
+// New returns a Collector with the given configuration.
+func New(cfg Config) *Collector {
+	return &Collector{cfg: cfg}
+}
+
+// Message updates the statistics with a message received from the server.
+func (c *Collector) Message(msg rtb.Message) {
+	if _, ok := msg.(rtb.MessageExitRobot); ok {
+		sum := c.Summary()
+		if c.cfg.Print != nil {
+			c.cfg.Print.Printf("%v", sum)
+		}
+		if c.cfg.Summary != nil {
+			c.write(c.cfg.Summary, sum)
+		}
+		return
+	}
+
+	finished, ok := c.message(msg)
+	if !ok {
+		return
+	}
+
+	if c.cfg.Print != nil {
+		c.cfg.Print.Printf("%v", finished)
+	}
+	if c.cfg.Output != nil {
+		c.write(c.cfg.Output, finished)
+	}
+}
+
+// write writes v to w as a JSON line. The first error is returned by Err.
+func (c *Collector) write(w io.Writer, v any) {
+	b, err := json.Marshal(v)
+	if err == nil {
+		_, err = w.Write(append(b, '\n'))
+	}
+	if err != nil {
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = fmt.Errorf("could not write stats: %v", err)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// message updates the statistics. If msg finishes a game, it returns the
+// statistics of the game and true.
+func (c *Collector) message(msg rtb.Message) (Stats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch m := msg.(type) {
+	case rtb.MessageGameOption:
+		if m.Option == rtb.GOptionRobotStartEnergy {
+			c.energy = m.Value
+		}
+	case rtb.MessageGameStarts:
+		c.cur = Stats{
+			Game:        len(c.games) + 1,
+			Collisions:  map[rtb.Object]int{},
+			DamageTaken: map[rtb.Object]float64{},
+		}
+		c.pending = nil
+		c.started = true
+	}
+
+	if !c.started {
+		return Stats{}, false
+	}
+
+	switch m := msg.(type) {
+	case rtb.MessageInfo:
+		if !c.cur.Dead {
+			c.cur.TimeSurvived = m.Time
+		}
+	case rtb.MessageCollision:
+		c.cur.Collisions[m.Object]++
+		if m.Object == rtb.ObjectCookie {
+			c.cur.CookiesEaten++
+		} else {
+			c.pending = append(c.pending, m.Object)
+		}
+	case rtb.MessageEnergy:
+		if loss := c.energy - m.EnergyLevel; loss > 0 {
+			c.attribute(loss)
+		}
+		c.energy = m.EnergyLevel
+		c.pending = nil
+	case rtb.MessageDead:
+		c.cur.Dead = true
+	case rtb.MessageGameFinishes:
+		c.started = false
+		c.cur.Won = !c.cur.Dead
+		c.cur.DamageDealt = c.cur.DamageTaken[rtb.ObjectRobot] + c.cur.ShotEnergy*c.cfg.HitRate
+		c.games = append(c.games, c.cur)
+		return c.cur.clone(), true
+	}
+
+	return Stats{}, false
+}
+
+// attribute distributes an energy loss evenly among the collisions received
+// since the last Energy message. c.mu must be held.
+func (c *Collector) attribute(loss float64) {
+	if len(c.pending) == 0 {
+		c.cur.DamageTaken[rtb.ObjectNoObject] += loss
+		return
+	}
+	for _, obj := range c.pending {
+		c.cur.DamageTaken[obj] += loss / float64(len(c.pending))
+	}
+}
+
+// Command updates the statistics with a command sent to the server.
+func (c *Collector) Command(cmd string) {
+	var energy float64
+	if _, err := fmt.Sscanf(cmd, "Shoot %g", &energy); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.started {
+		return
+	}
+	c.cur.ShotsFired++
+	c.cur.ShotEnergy += energy
+}
+
+// Current returns the statistics of the current game.
+func (c *Collector) Current() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cur.clone()
+}
+
+// Games returns the statistics of the finished games.
+func (c *Collector) Games() []Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Stats(nil), c.games...)
+}
+
+// Summary returns the summary of the finished games.
+func (c *Collector) Summary() Summary {
+	return Summarize(c.Games())
+}
+
+// Err returns the first error found writing the statistics to Output or
+// the summary to Summary.
+func (c *Collector) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Summary are the statistics accumulated across the games of a sequence.
+type Summary struct {
+	// Games is the number of finished games.
+	Games int
+
+	// Wins is the number of games in which the robot was alive when the
+	// game finished.
+	Wins int
+
+	// Deaths is the number of games in which the robot died.
+	Deaths int
+
+	// ShotsFired is the number of Shoot commands sent.
+	ShotsFired int
+
+	// ShotEnergy is the total energy requested by the Shoot commands.
+	ShotEnergy float64
+
+	// DamageTaken is the total energy lost by the robot.
+	DamageTaken float64
+
+	// DamageDealt is the estimated energy lost by other robots due to
+	// the robot.
+	DamageDealt float64
+
+	// TimeSurvived is the total game time survived.
+	TimeSurvived float64
+}
+
+// Summarize returns the summary of games.
+func Summarize(games []Stats) Summary {
+	var sum Summary
+	for _, g := range games {
+		sum.Games++
+		if g.Won {
+			sum.Wins++
+		}
+		if g.Dead {
+			sum.Deaths++
+		}
+		sum.ShotsFired += g.ShotsFired
+		sum.ShotEnergy += g.ShotEnergy
+		sum.DamageTaken += g.TotalDamage()
+		sum.DamageDealt += g.DamageDealt
+		sum.TimeSurvived += g.TimeSurvived
+	}
+	return sum
+}
+
+// String returns a short summary of s. It fits in a Print message.
+func (s Summary) String() string {
+	return fmt.Sprintf("games=%v wins=%v deaths=%v shots=%v/%.1f damage=%.1f/%.1f time=%.1f",
+		s.Games, s.Wins, s.Deaths, s.ShotsFired, s.ShotEnergy, s.DamageDealt, s.DamageTaken, s.TimeSurvived)
+}
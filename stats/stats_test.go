@@ -0,0 +1,166 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestCollector(t *testing.T) {
+	var out, output bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	c := New(Config{Print: r, Output: &output})
+	r.AddObserver(c)
+
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageRadar); ok {
+			r.Shoot(2.5)
+		}
+	})
+
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 1},
+		rtb.MessageRadar{Object: rtb.ObjectRobot},
+		rtb.MessageRadar{Object: rtb.ObjectRobot},
+		rtb.MessageCollision{Object: rtb.ObjectShot},
+		rtb.MessageEnergy{EnergyLevel: 90},
+		rtb.MessageInfo{Time: 2},
+		rtb.MessageCollision{Object: rtb.ObjectCookie},
+		rtb.MessageEnergy{EnergyLevel: 100},
+		rtb.MessageInfo{Time: 3},
+		rtb.MessageEnergy{EnergyLevel: 84},
+		rtb.MessageDead{},
+		rtb.MessageInfo{Time: 4},
+		rtb.MessageGameFinishes{},
+	}
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+
+	games := c.Games()
+	if len(games) != 1 {
+		t.Fatalf("wrong number of games: got=%v want=%v", len(games), 1)
+	}
+	g := games[0]
+
+	if g.Game != 1 || g.ShotsFired != 2 || g.ShotEnergy != 5 || g.CookiesEaten != 1 {
+		t.Errorf("unexpected stats: %+v", g)
+	}
+	if g.TimeSurvived != 3 || !g.Dead {
+		t.Errorf("unexpected survival: time=%v dead=%v", g.TimeSurvived, g.Dead)
+	}
+	if g.DamageTaken[rtb.ObjectShot] != 10 || g.DamageTaken[rtb.ObjectNoObject] != 16 || g.TotalDamage() != 26 {
+		t.Errorf("unexpected damage: %v", g.DamageTaken)
+	}
+	if g.Collisions[rtb.ObjectShot] != 1 || g.Collisions[rtb.ObjectCookie] != 1 {
+		t.Errorf("unexpected collisions: %v", g.Collisions)
+	}
+
+	if !strings.Contains(out.String(), "Print game=1 ") {
+		t.Errorf("summary not printed: %q", out.String())
+	}
+
+	var decoded Stats
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("could not decode output: %v", err)
+	}
+	if decoded.ShotsFired != 2 || decoded.DamageTaken[rtb.ObjectShot] != 10 {
+		t.Errorf("unexpected decoded stats: %+v", decoded)
+	}
+}
+
+func TestStatsString(t *testing.T) {
+	s := Stats{
+		Game:        12,
+		ShotsFired:  1000,
+		ShotEnergy:  5000,
+		Collisions:  map[rtb.Object]int{rtb.ObjectWall: 100},
+		DamageTaken: map[rtb.Object]float64{rtb.ObjectWall: 120},
+	}
+
+	// The summary must fit in a Print message.
+	if n := len("Print " + s.String() + "\n"); n > 128 {
+		t.Errorf("summary is too long (%v)", n)
+	}
+
+	sum := Summary{
+		Games:        1000,
+		Wins:         1000,
+		Deaths:       1000,
+		ShotsFired:   100000,
+		ShotEnergy:   500000,
+		DamageTaken:  100000,
+		DamageDealt:  100000,
+		TimeSurvived: 100000,
+	}
+	if n := len("Print " + sum.String() + "\n"); n > 128 {
+		t.Errorf("sequence summary is too long (%v)", n)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	var out, summary bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	c := New(Config{Print: r, Summary: &summary, HitRate: 0.5})
+	r.AddObserver(c)
+
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageRadar); ok {
+			r.Shoot(2)
+		}
+	})
+
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 1},
+		rtb.MessageEnergy{EnergyLevel: 90},
+		rtb.MessageDead{},
+		rtb.MessageGameFinishes{},
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 2},
+		rtb.MessageRadar{Object: rtb.ObjectRobot},
+		rtb.MessageCollision{Object: rtb.ObjectRobot},
+		rtb.MessageEnergy{EnergyLevel: 96},
+		rtb.MessageGameFinishes{},
+		rtb.MessageExitRobot{},
+	}
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+
+	want := Summary{
+		Games:        2,
+		Wins:         1,
+		Deaths:       1,
+		ShotsFired:   1,
+		ShotEnergy:   2,
+		DamageTaken:  14,
+		DamageDealt:  5,
+		TimeSurvived: 3,
+	}
+	if got := c.Summary(); got != want {
+		t.Errorf("unexpected summary: got=%+v want=%+v", got, want)
+	}
+
+	if !strings.Contains(out.String(), "Print games=2 wins=1 ") {
+		t.Errorf("summary not printed: %q", out.String())
+	}
+
+	var decoded Summary
+	if err := json.Unmarshal(summary.Bytes(), &decoded); err != nil {
+		t.Fatalf("could not decode summary: %v", err)
+	}
+	if decoded != want {
+		t.Errorf("unexpected decoded summary: got=%+v want=%+v", decoded, want)
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package supervisor keeps a robot playing the whole tournament when its
+// strategy crashes.
+//
+// A Supervisor runs a strategy created by a constructor and recovers its
+// panics, so the robot process and its connection with the server survive.
+// After a crash, the robot brakes and ignores the messages of the server
+// until the next Initialize, which starts a new sequence. Then, a new
+// strategy is created and receives the messages of the sequence, starting
+// with the Initialize:
+//
+//	sup := supervisor.New(newStrategy, supervisor.Config{})
+//	r.Run(settings, sup)
+//
+// The Initialize messages after the first one have First set to false, so
+// the new strategy must not rely on the first message to set up its state.
+//
+// Only the panics raised by Handle are recovered. The goroutines started by
+// the strategy must recover their own panics, e.g. with crash.Reporter.
+package supervisor
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Config is the configuration of a Supervisor.
+type Config struct {
+	// MaxRestarts is the maximum number of restarts. After that, the
+	// strategy is not restarted and the robot idles until the end of
+	// the tournament. If zero, there is no limit.
+	MaxRestarts int
+
+	// OnCrash, if not nil, is called after a crash with the panic,
+	// wrapped in an error, and the stack trace. It can be used to write
+	// a crash report, e.g. with crash.Reporter.Write.
+	OnCrash func(err error, stack []byte)
+}
+
+// Supervisor is a strategy that runs another strategy and restarts it at the
+// next sequence when it crashes. Supervisor methods can be called
+// concurrently.
+type Supervisor struct {
+	newStrategy func() rtb.Strategy
+	cfg         Config
+
+	mu       sync.Mutex
+	cur      rtb.Strategy
+	crashes  int
+	restarts int
+}
+
+// New returns a Supervisor that runs the strategies returned by
+// newStrategy. The first one is created when the first message is
+// received.
+func New(newStrategy func() rtb.Strategy, cfg Config) *Supervisor {
+	return &Supervisor{newStrategy: newStrategy, cfg: cfg}
+}
+
+// Handle passes msg to the current strategy. If there is none because it
+// crashed, msg is ignored, unless it is an Initialize and the strategy can
+// be restarted.
+func (s *Supervisor) Handle(r *rtb.Robot, msg rtb.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cur == nil {
+		if !s.start(r, msg) {
+			return
+		}
+	}
+
+	s.run(r, func() { s.cur.Handle(r, msg) })
+}
+
+// start creates a new strategy if it is the first message or msg starts a
+// new sequence. It returns false if there is no strategy.
+func (s *Supervisor) start(r *rtb.Robot, msg rtb.Message) bool {
+	if s.crashes > 0 {
+		switch msg.(type) {
+		case rtb.MessageInitialize, *rtb.MessageInitialize:
+		default:
+			return false
+		}
+		if s.cfg.MaxRestarts > 0 && s.restarts >= s.cfg.MaxRestarts {
+			return false
+		}
+		s.restarts++
+		r.Logger().Info("restarting strategy", "restarts", s.restarts)
+	}
+
+	s.run(r, func() { s.cur = s.newStrategy() })
+	return s.cur != nil
+}
+
+// run calls f, recovering its panic. After a panic, the current strategy is
+// dropped and the robot brakes.
+func (s *Supervisor) run(r *rtb.Robot, f func()) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		stack := debug.Stack()
+		s.cur = nil
+		s.crashes++
+
+		err := fmt.Errorf("panic: %v", v)
+		r.Logger().Error("strategy crashed", "err", err, "crashes", s.crashes)
+		if s.cfg.OnCrash != nil {
+			s.cfg.OnCrash(err, stack)
+		}
+		r.Accelerate(0)
+		r.Brake(1)
+	}()
+
+	f()
+}
+
+// Crashes returns the number of crashes of the strategy.
+func (s *Supervisor) Crashes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.crashes
+}
+
+// Restarts returns the number of times the strategy was restarted.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.restarts
+}
@@ -0,0 +1,99 @@
+package supervisor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestSupervisor(t *testing.T) {
+	var instances int
+	newStrategy := func() rtb.Strategy {
+		instances++
+		n := instances
+		return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+			if _, ok := msg.(rtb.MessageGameFinishes); ok && n == 1 {
+				panic("boom")
+			}
+			line, _ := rtb.EncodeMessage(msg)
+			r.Printf("s%v %v", n, line)
+		})
+	}
+
+	var crashErr error
+	sup := New(newStrategy, Config{OnCrash: func(err error, stack []byte) { crashErr = err }})
+
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	for _, msg := range []rtb.Message{
+		rtb.MessageInitialize{First: true},
+		rtb.MessageGameStarts{},
+		rtb.MessageGameFinishes{},
+		rtb.MessageGameStarts{},
+		rtb.MessageInitialize{First: false},
+		rtb.MessageGameStarts{},
+	} {
+		r.Deliver(sup, msg)
+	}
+
+	want := []string{
+		"Print s1 Initialize 1",
+		"Print s1 GameStarts",
+		"Accelerate 0.000000",
+		"Brake 1.000000",
+		"Print s2 Initialize 0",
+		"Print s2 GameStarts",
+	}
+	if got := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected commands:\ngot:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if crashErr == nil || !strings.Contains(crashErr.Error(), "boom") {
+		t.Errorf("unexpected crash error: %v", crashErr)
+	}
+	if sup.Crashes() != 1 || sup.Restarts() != 1 || instances != 2 {
+		t.Errorf("unexpected counters: crashes=%v restarts=%v instances=%v", sup.Crashes(), sup.Restarts(), instances)
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	var instances int
+	newStrategy := func() rtb.Strategy {
+		instances++
+		return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+			panic(errors.New("always"))
+		})
+	}
+
+	sup := New(newStrategy, Config{MaxRestarts: 2})
+	r := rtb.NewRobot(nil, &bytes.Buffer{})
+	for i := 0; i < 5; i++ {
+		r.Deliver(sup, rtb.MessageInitialize{First: i == 0})
+	}
+
+	if sup.Crashes() != 3 || sup.Restarts() != 2 || instances != 3 {
+		t.Errorf("unexpected counters: crashes=%v restarts=%v instances=%v", sup.Crashes(), sup.Restarts(), instances)
+	}
+}
+
+func TestConstructorPanic(t *testing.T) {
+	fail := true
+	newStrategy := func() rtb.Strategy {
+		if fail {
+			fail = false
+			panic("constructor")
+		}
+		return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	}
+
+	sup := New(newStrategy, Config{})
+	r := rtb.NewRobot(nil, &bytes.Buffer{})
+	r.Deliver(sup, rtb.MessageInitialize{First: true})
+	r.Deliver(sup, rtb.MessageInitialize{First: false})
+
+	if sup.Crashes() != 1 || sup.Restarts() != 1 {
+		t.Errorf("unexpected counters: crashes=%v restarts=%v", sup.Crashes(), sup.Restarts())
+	}
+}
@@ -0,0 +1,219 @@
+// Package target implements the selection of the enemy to attack.
+//
+// A Selector scores the enemy tracks with a Policy and keeps the best one as
+// the target. To avoid switching targets every tick, a new target replaces
+// the current one only after it has been kept for Config.Hold seconds and
+// when it scores better by Config.Margin. The selector can be used as the
+// target selection of the fire control:
+//
+//	sel := target.New(w, tr, target.Config{Policy: target.Weakest})
+//	r.AddObserver(w)
+//	r.AddObserver(tr)
+//	r.AddObserver(sel)
+//	fc := fire.New(r, w, tr, e, fire.Config{Select: sel.Select})
+package target
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// unknownEnergy is the energy assumed for the robots whose energy has not
+// been reported. It is the default RobotStartEnergy game option.
+const unknownEnergy = 100
+
+// Policy returns the score of the enemy track t, given the position of the
+// robot and all the tracks, including t and the team mates. The enemy with
+// the highest score is selected.
+type Policy func(pos arena.Point, t track.Track, tracks []track.Track) float64
+
+// Nearest prefers the closest enemies. The score is the opposite of the
+// distance.
+func Nearest(pos arena.Point, t track.Track, tracks []track.Track) float64 {
+	return -t.Pos.Sub(pos).Len()
+}
+
+// Weakest prefers the enemies with less energy. The score is the opposite of
+// the energy.
+func Weakest(pos arena.Point, t track.Track, tracks []track.Track) float64 {
+	return -energy(t)
+}
+
+// MostDangerous prefers the enemies that are a bigger threat. The score is
+// the energy of the enemy divided by its distance, doubled for the robots
+// labeled as rammers or snipers.
+func MostDangerous(pos arena.Point, t track.Track, tracks []track.Track) float64 {
+	score := energy(t) / math.Max(t.Pos.Sub(pos).Len(), 1)
+	if t.Labels&(track.LabelRammer|track.LabelSniper) != 0 {
+		score *= 2
+	}
+	return score
+}
+
+// Isolated prefers the enemies far from the other enemies, which cannot be
+// helped by their team mates. The score is the distance to the closest
+// other enemy, or zero if there is none.
+func Isolated(pos arena.Point, t track.Track, tracks []track.Track) float64 {
+	score := math.Inf(1)
+	for _, o := range tracks {
+		if o.ID == t.ID || o.TeamMate {
+			continue
+		}
+		score = math.Min(score, o.Pos.Sub(t.Pos).Len())
+	}
+	if math.IsInf(score, 1) {
+		return 0
+	}
+	return score
+}
+
+// energy returns the energy of the robot of t.
+func energy(t track.Track) float64 {
+	if t.Energy == 0 {
+		return unknownEnergy
+	}
+	return t.Energy
+}
+
+// Config is the configuration of a Selector.
+type Config struct {
+	// Policy scores the enemies. If nil, Nearest is used.
+	Policy Policy
+
+	// Hold is the minimum time a target is kept while it is tracked. If
+	// zero, 1 is used.
+	Hold float64
+
+	// Margin is the amount by which the score of an enemy must exceed
+	// the score of the current target to replace it, in the units of the
+	// policy. If zero, any better enemy replaces it.
+	Margin float64
+
+	// OnChange, if not nil, is called when the target changes, with the
+	// new target. ok is false if there is no target.
+	OnChange func(t track.Track, ok bool)
+}
+
+// Selector selects the enemy to attack among the tracks of a tracker. It
+// implements the rtb.Observer interface and must be added to the robot after
+// the world model and the tracker. Selector methods can be called
+// concurrently.
+type Selector struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+
+	mu       sync.Mutex
+	policy   Policy
+	target   int
+	selected float64
+}
+
+// New returns a Selector that selects the target among the tracks of tr.
+func New(w *world.World, tr *track.Tracker, cfg Config) *Selector {
+	if cfg.Policy == nil {
+		cfg.Policy = Nearest
+	}
+	if cfg.Hold == 0 {
+		cfg.Hold = 1
+	}
+	return &Selector{cfg: cfg, w: w, tr: tr, policy: cfg.Policy}
+}
+
+// SetPolicy sets the policy that scores the enemies. If nil, Nearest is
+// used. The current target is kept until a better one is found.
+func (s *Selector) SetPolicy(p Policy) {
+	if p == nil {
+		p = Nearest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = p
+}
+
+// Message updates the target when msg is an Info message.
+func (s *Selector) Message(msg rtb.Message) {
+	switch msg.(type) {
+	case rtb.MessageGameStarts:
+		s.mu.Lock()
+		s.target, s.selected = 0, 0
+		s.mu.Unlock()
+	case rtb.MessageInfo:
+		st := s.w.State()
+		s.update(st.Pos, st.Time, s.tr.Tracks())
+	}
+}
+
+// Target returns the current target. It returns false if there is none.
+func (s *Selector) Target() (track.Track, bool) {
+	s.mu.Lock()
+	id := s.target
+	s.mu.Unlock()
+
+	return find(s.tr.Tracks(), id)
+}
+
+// Select updates the target with the given position of the robot and tracks
+// and returns it. It returns false if there is no target. It can be used as
+// fire.Config.Select.
+func (s *Selector) Select(pos arena.Point, tracks []track.Track) (track.Track, bool) {
+	return s.update(pos, s.w.State().Time, tracks)
+}
+
+// update selects the target among tracks at time now and returns it.
+func (s *Selector) update(pos arena.Point, now float64, tracks []track.Track) (track.Track, bool) {
+	s.mu.Lock()
+	cur, curOK := find(tracks, s.target)
+	next, nextOK := cur, curOK
+	if !curOK || now-s.selected >= s.cfg.Hold {
+		var (
+			best      track.Track
+			bestScore float64
+			found     bool
+		)
+		for _, t := range tracks {
+			if t.TeamMate {
+				continue
+			}
+			score := s.policy(pos, t, tracks)
+			if !found || score > bestScore {
+				best, bestScore, found = t, score, true
+			}
+		}
+		if !curOK || found && best.ID != cur.ID && bestScore > s.policy(pos, cur, tracks)+s.cfg.Margin {
+			next, nextOK = best, found
+		}
+	}
+	changed := next.ID != s.target
+	if changed {
+		s.target, s.selected = next.ID, now
+	}
+	s.mu.Unlock()
+
+	if changed && s.cfg.OnChange != nil {
+		s.cfg.OnChange(next, nextOK)
+	}
+	return next, nextOK
+}
+
+// find returns the enemy track with the given ID.
+func find(tracks []track.Track, id int) (track.Track, bool) {
+	if id == 0 {
+		return track.Track{}, false
+	}
+	for _, t := range tracks {
+		if t.ID == id && !t.TeamMate {
+			return t, true
+		}
+	}
+	return track.Track{}, false
+}
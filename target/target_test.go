@@ -0,0 +1,99 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestPolicies(t *testing.T) {
+	tracks := []track.Track{
+		{ID: 1, Pos: arena.Point{X: 3}, Energy: 80},
+		{ID: 2, Pos: arena.Point{X: 10}, Energy: 20},
+		{ID: 3, Pos: arena.Point{X: 12}, Energy: 90},
+		{ID: 4, Pos: arena.Point{Y: 15}, Energy: 50, Labels: track.LabelRammer},
+		{ID: 5, Pos: arena.Point{X: 1}, TeamMate: true},
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   int
+	}{
+		{"Nearest", Nearest, 1},
+		{"Weakest", Weakest, 2},
+		{"MostDangerous", MostDangerous, 1},
+		{"Isolated", Isolated, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := world.New()
+			sel := New(w, nil, Config{Policy: tt.policy})
+			got, ok := sel.Select(arena.Point{}, tracks)
+			if !ok {
+				t.Fatalf("no target")
+			}
+			if got.ID != tt.want {
+				t.Errorf("got=%v want=%v", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestHysteresis(t *testing.T) {
+	w := world.New()
+
+	var changes []int
+	sel := New(w, nil, Config{
+		Hold:   1,
+		Margin: 2,
+		OnChange: func(t track.Track, ok bool) {
+			if !ok {
+				changes = append(changes, 0)
+				return
+			}
+			changes = append(changes, t.ID)
+		},
+	})
+
+	steps := []struct {
+		time   float64
+		tracks []track.Track
+		want   int
+	}{
+		// The nearest enemy is selected.
+		{0, []track.Track{{ID: 1, Pos: arena.Point{X: 5}}, {ID: 2, Pos: arena.Point{X: 8}}}, 1},
+		// A closer enemy does not replace it before Hold.
+		{0.5, []track.Track{{ID: 1, Pos: arena.Point{X: 5}}, {ID: 2, Pos: arena.Point{X: 1}}}, 1},
+		// Nor after Hold, if it is not closer by Margin.
+		{1.5, []track.Track{{ID: 1, Pos: arena.Point{X: 5}}, {ID: 2, Pos: arena.Point{X: 4}}}, 1},
+		// It does if it is.
+		{2, []track.Track{{ID: 1, Pos: arena.Point{X: 5}}, {ID: 2, Pos: arena.Point{X: 2}}}, 2},
+		// A lost target is replaced immediately.
+		{2.1, []track.Track{{ID: 1, Pos: arena.Point{X: 5}}}, 1},
+		// No enemies, no target.
+		{2.2, nil, 0},
+	}
+
+	for _, s := range steps {
+		w.Message(rtb.MessageInfo{Time: s.time})
+		got, ok := sel.Select(arena.Point{}, s.tracks)
+		if ok != (s.want != 0) || got.ID != s.want {
+			t.Errorf("time %v: got=%v,%v want=%v", s.time, got.ID, ok, s.want)
+		}
+	}
+
+	want := []int{1, 2, 1, 0}
+	if len(changes) != len(want) {
+		t.Fatalf("unexpected changes: got=%v want=%v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %v: got=%v want=%v", i, changes[i], want[i])
+		}
+	}
+}
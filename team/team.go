@@ -0,0 +1,245 @@
+// Package team implements the communication between the robots of a team.
+//
+// RealTimeBattle puts in the same team the robots whose names end with the
+// same "Team: <name>" suffix, but does not provide a way to communicate
+// them. Team members exchange reports through a Transport instead, sharing
+// their position and the enemies they track.
+//
+// Positions are only meaningful between team members if they use the same
+// coordinate system, so the robots must know their absolute position, i.e.
+// the SendRobotCoordinates game option must be 2.
+package team
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Name returns the team of a robot given its name, e.g. "example" for
+// "attacker Team: example". It returns false if the robot is not in a team.
+func Name(robot string) (string, bool) {
+	_, team, ok := strings.Cut(robot, "Team: ")
+	if !ok || team == "" {
+		return "", false
+	}
+	return team, true
+}
+
+// Enemy is an enemy reported by a team member.
+type Enemy struct {
+	// Pos is the position of the enemy.
+	Pos arena.Point
+
+	// Vel is the velocity of the enemy.
+	Vel arena.Point
+
+	// Energy is the energy level of the enemy, or zero if unknown.
+	Energy float64
+
+	// LastSeen is the game time when the enemy was observed.
+	LastSeen float64
+}
+
+// Report is the message sent periodically by every team member.
+type Report struct {
+	// From is the name of the member.
+	From string
+
+	// Time is the game time of the report.
+	Time float64
+
+	// Pos is the position of the member.
+	Pos arena.Point
+
+	// Energy is the energy level of the member.
+	Energy float64
+
+	// Role is the role of the member, as set with Team.SetRole.
+	Role string
+
+	// Enemies are the enemies tracked by the member.
+	Enemies []Enemy
+}
+
+// Config is the configuration of a Team.
+type Config struct {
+	// Name is the name of the member. It must be unique in the team.
+	Name string
+
+	// Interval is the time between reports. If zero, 0.5 is used.
+	Interval float64
+
+	// MaxAge is the time after which the report of a member is
+	// discarded. If zero, 3 is used.
+	MaxAge float64
+}
+
+// Team sends the reports of a member and receives the reports of the other
+// members. Enemies reported by other members are added to the tracker, so
+// the behaviors of the robot take them into account. Team implements the
+// rtb.Observer interface and must be added to the robot after the world
+// model and the tracker. Team methods can be called concurrently.
+type Team struct {
+	rtb.NopCommand
+
+	cfg Config
+	t   Transport
+	w   *world.World
+	tr  *track.Tracker
+
+	mu       sync.Mutex
+	role     string
+	mates    map[string]Report
+	pending  []Report
+	reported float64
+	sent     bool
+}
+
+// New returns a Team that communicates through t. tr can be nil, in which
+// case the enemies reported by other members are not tracked.
+func New(t Transport, w *world.World, tr *track.Tracker, cfg Config) *Team {
+	if cfg.Interval == 0 {
+		cfg.Interval = 0.5
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 3
+	}
+	tm := &Team{cfg: cfg, t: t, w: w, tr: tr, mates: map[string]Report{}}
+	go tm.receive()
+	return tm
+}
+
+// receive queues the reports received from the transport. They are applied
+// on the next Info message, so the tracker is only updated from the
+// goroutine that delivers the messages.
+func (tm *Team) receive() {
+	for b := range tm.t.Receive() {
+		var rep Report
+		if err := json.Unmarshal(b, &rep); err != nil || rep.From == tm.cfg.Name {
+			continue
+		}
+		tm.mu.Lock()
+		tm.pending = append(tm.pending, rep)
+		tm.mu.Unlock()
+	}
+}
+
+// SetRole sets the role of the member, which is sent to the other members.
+func (tm *Team) SetRole(role string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.role = role
+}
+
+// Mates returns the last report of every team member.
+func (tm *Team) Mates() []Report {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	mates := make([]Report, 0, len(tm.mates))
+	for _, rep := range tm.mates {
+		mates = append(mates, rep)
+	}
+	return mates
+}
+
+// Leader returns true if the member has the lowest name among the members
+// known to it. It allows to split roles without further communication.
+func (tm *Team) Leader() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for name := range tm.mates {
+		if name < tm.cfg.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// Message applies the received reports and sends a report when msg is an
+// Info message.
+func (tm *Team) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		tm.mu.Lock()
+		tm.mates, tm.pending, tm.sent = map[string]Report{}, nil, false
+		tm.mu.Unlock()
+	case rtb.MessageInfo:
+		tm.apply(m.Time)
+		tm.report(m.Time)
+	}
+}
+
+// apply updates the mates with the pending reports and adds the reported
+// enemies to the tracker.
+func (tm *Team) apply(now float64) {
+	tm.mu.Lock()
+	pending := tm.pending
+	tm.pending = nil
+	for _, rep := range pending {
+		if prev, ok := tm.mates[rep.From]; !ok || rep.Time >= prev.Time {
+			tm.mates[rep.From] = rep
+		}
+	}
+	for name, rep := range tm.mates {
+		if now-rep.Time > tm.cfg.MaxAge {
+			delete(tm.mates, name)
+		}
+	}
+	tm.mu.Unlock()
+
+	if tm.tr == nil {
+		return
+	}
+	for _, rep := range pending {
+		for _, e := range rep.Enemies {
+			if now-e.LastSeen > tm.cfg.MaxAge {
+				continue
+			}
+			tm.tr.Observe(e.Pos, e.LastSeen, e.Energy)
+		}
+	}
+}
+
+// report sends a report if the interval has elapsed.
+func (tm *Team) report(now float64) {
+	tm.mu.Lock()
+	if tm.sent && now-tm.reported < tm.cfg.Interval {
+		tm.mu.Unlock()
+		return
+	}
+	tm.reported, tm.sent = now, true
+	role := tm.role
+	tm.mu.Unlock()
+
+	s := tm.w.State()
+	rep := Report{
+		From:   tm.cfg.Name,
+		Time:   now,
+		Pos:    s.Pos,
+		Energy: s.Energy,
+		Role:   role,
+	}
+	if tm.tr != nil {
+		for _, t := range tm.tr.Tracks() {
+			if t.TeamMate || now-t.LastSeen > tm.cfg.MaxAge {
+				continue
+			}
+			rep.Enemies = append(rep.Enemies, Enemy{Pos: t.Pos, Vel: t.Vel, Energy: t.Energy, LastSeen: t.LastSeen})
+		}
+	}
+
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return
+	}
+	tm.t.Send(b)
+}
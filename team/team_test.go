@@ -0,0 +1,139 @@
+package team
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		robot  string
+		want   string
+		wantOK bool
+	}{
+		{"attacker Team: example", "example", true},
+		{"attacker", "", false},
+		{"attacker Team: ", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Name(tt.robot)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("unexpected team of %q: got=%v, %v want=%v, %v", tt.robot, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// member is a team member running on a robot without server.
+type member struct {
+	r  *rtb.Robot
+	w  *world.World
+	tr *track.Tracker
+	tm *Team
+}
+
+func newMember(t Transport, name string) member {
+	r := rtb.NewRobot(nil, io.Discard)
+	w := world.New()
+	tr := track.New(w, track.Config{})
+	tm := New(t, w, tr, Config{Name: name})
+	for _, o := range []rtb.Observer{w, tr, tm} {
+		r.AddObserver(o)
+	}
+	return member{r, w, tr, tm}
+}
+
+func (m member) deliver(msgs ...rtb.Message) {
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	for _, msg := range msgs {
+		m.r.Deliver(nop, msg)
+	}
+}
+
+// waitMates waits until m knows n team mates.
+func waitMates(t *testing.T, m member, n int, now float64) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		m.deliver(rtb.MessageInfo{Time: now})
+		if len(m.tm.Mates()) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %v mates", n)
+}
+
+func TestTeam(t *testing.T) {
+	h := NewHub()
+	a, b := newMember(h.Join(), "a"), newMember(h.Join(), "b")
+
+	a.deliver(rtb.MessageGameStarts{}, rtb.MessageCoordinates{X: 1, Y: 2})
+	b.deliver(rtb.MessageGameStarts{}, rtb.MessageCoordinates{X: 5, Y: 5})
+	a.tm.SetRole("attacker")
+
+	a.deliver(
+		rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+		rtb.MessageRobotInfo{EnergyLevel: 42},
+		rtb.MessageInfo{Time: 0.1},
+	)
+	waitMates(t, b, 1, 0.1)
+
+	rep := b.tm.Mates()[0]
+	if rep.From != "a" || rep.Role != "attacker" || rep.Pos != (arena.Point{X: 1, Y: 2}) {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+
+	tracks := b.tr.Tracks()
+	if len(tracks) != 1 {
+		t.Fatalf("unexpected number of tracks: got=%v want=1", len(tracks))
+	}
+	if want := (arena.Point{X: 11, Y: 2}); tracks[0].Pos != want {
+		t.Errorf("unexpected enemy position: got=%v want=%v", tracks[0].Pos, want)
+	}
+	if tracks[0].Energy != 42 {
+		t.Errorf("unexpected enemy energy: got=%v want=42", tracks[0].Energy)
+	}
+
+	if !a.tm.Leader() {
+		t.Errorf("a is not the leader")
+	}
+	if b.tm.Leader() {
+		t.Errorf("b is the leader")
+	}
+}
+
+func TestSocket(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Listen(dir, "a")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer a.Close()
+	b, err := Listen(dir, "b")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	defer b.Close()
+
+	want, _ := json.Marshal(Report{From: "a"})
+	if err := a.Send(want); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+
+	select {
+	case got := <-b.Receive():
+		if string(got) != string(want) {
+			t.Errorf("unexpected message: got=%s want=%s", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
@@ -0,0 +1,182 @@
+package team
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Transport sends and receives the messages of a team.
+type Transport interface {
+	// Send sends b to the other members of the team.
+	Send(b []byte) error
+
+	// Receive returns the channel of the messages received from the
+	// other members of the team. It is closed when the transport is
+	// closed.
+	Receive() <-chan []byte
+
+	// Close closes the transport.
+	Close() error
+}
+
+// Hub connects transports in the same process. It is useful to run team
+// members in the simulator and in tests.
+type Hub struct {
+	mu      sync.Mutex
+	members []*hubTransport
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{}
+}
+
+// Join returns a transport connected to the other transports of the hub.
+func (h *Hub) Join() Transport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := &hubTransport{h: h, c: make(chan []byte, 100)}
+	h.members = append(h.members, t)
+	return t
+}
+
+// hubTransport is a transport connected to a Hub.
+type hubTransport struct {
+	h *Hub
+	c chan []byte
+
+	// closed is protected by the mutex of the hub.
+	closed bool
+}
+
+// Send sends b to the other transports of the hub. Messages are dropped if
+// the receiving buffer of a transport is full.
+func (t *hubTransport) Send(b []byte) error {
+	t.h.mu.Lock()
+	defer t.h.mu.Unlock()
+
+	if t.closed {
+		return errors.New("transport closed")
+	}
+	for _, m := range t.h.members {
+		if m == t || m.closed {
+			continue
+		}
+		select {
+		case m.c <- append([]byte(nil), b...):
+		default:
+		}
+	}
+	return nil
+}
+
+// Receive returns the channel of received messages.
+func (t *hubTransport) Receive() <-chan []byte {
+	return t.c
+}
+
+// Close disconnects the transport from the hub.
+func (t *hubTransport) Close() error {
+	t.h.mu.Lock()
+	defer t.h.mu.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.c)
+	}
+	return nil
+}
+
+// Socket is a transport for team members running in different processes of
+// the same host, like the robots of a RealTimeBattle team. Every member
+// listens on a Unix datagram socket in a directory shared by the team and
+// sends its messages to the sockets of the other members.
+type Socket struct {
+	dir  string
+	path string
+	conn *net.UnixConn
+	c    chan []byte
+}
+
+// Dir returns the default directory of the sockets of a team.
+func Dir(team string) string {
+	return filepath.Join(os.TempDir(), "rtb-team-"+team)
+}
+
+// Listen returns a Socket for the member name of the team whose sockets are
+// in dir. The directory is created if it does not exist.
+func Listen(dir, name string) (*Socket, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("could not create directory: %v", err)
+	}
+	path := filepath.Join(dir, name+".sock")
+	// The socket of a previous run of the member may still exist.
+	os.Remove(path)
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("could not listen: %v", err)
+	}
+
+	s := &Socket{dir: dir, path: path, conn: conn, c: make(chan []byte, 100)}
+	go s.read()
+	return s, nil
+}
+
+// read reads messages from the socket until it is closed.
+func (s *Socket) read() {
+	defer close(s.c)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case s.c <- append([]byte(nil), buf[:n]...):
+		default:
+		}
+	}
+}
+
+// Send sends b to the sockets of the other members. Members that are not
+// listening are ignored.
+func (s *Socket) Send(b []byte) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("could not read directory: %v", err)
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".sock") {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		if path == s.path {
+			continue
+		}
+		s.conn.WriteToUnix(b, &net.UnixAddr{Name: path, Net: "unixgram"})
+	}
+	return nil
+}
+
+// Receive returns the channel of received messages.
+func (s *Socket) Receive() <-chan []byte {
+	return s.c
+}
+
+// Close closes the socket and removes it.
+func (s *Socket) Close() error {
+	err := s.conn.Close()
+	os.Remove(s.path)
+	if err != nil {
+		return fmt.Errorf("could not close socket: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+// Package telemetry records the traffic of a robot as JSON Lines, so
+// post-game analysis tools can be built on a stable schema.
+//
+// Every line is a Record. A new file is created for every game, so each file
+// contains the messages, commands and snapshots of exactly one game,
+// including the game options and, in the first file, the initialization
+// messages.
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+)
+
+// Kinds of records.
+const (
+	// KindMessage is the kind of the records of messages received from
+	// the server.
+	KindMessage = "message"
+
+	// KindCommand is the kind of the records of commands sent to the
+	// server.
+	KindCommand = "command"
+
+	// KindSnapshot is the kind of the records of snapshots.
+	KindSnapshot = "snapshot"
+
+	// KindDecision is the kind of the records of decisions taken by the
+	// components of a strategy.
+	KindDecision = "decision"
+)
+
+// Record is a line of a telemetry file.
+type Record struct {
+	// Time is the game time of the record. It is the time of the last
+	// Info message received before the record.
+	Time float64 `json:"time"`
+
+	// Kind is the kind of record: KindMessage, KindCommand,
+	// KindSnapshot or KindDecision.
+	Kind string `json:"kind"`
+
+	// Type is the message type (e.g. "Radar"), the command keyword
+	// (e.g. "Shoot") or the component that took a decision (e.g.
+	// "dodge"). It is empty for snapshots.
+	Type string `json:"type,omitempty"`
+
+	// Raw is the message or command as sent through the protocol, or
+	// the explanation of a decision. It is empty for snapshots.
+	Raw string `json:"raw,omitempty"`
+
+	// Data is the JSON encoding of the message or the snapshot. It is
+	// empty for commands.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Config is the configuration of a Recorder.
+type Config struct {
+	// Dir is the directory where the telemetry files are written. It is
+	// created if it does not exist.
+	Dir string
+
+	// Prefix is the prefix of the file names. Files are named
+	// "<prefix>-<game number>.jsonl". If empty, "game" is used.
+	Prefix string
+
+	// Snapshot, if not nil, is called after every Info message, which
+	// the server sends once per tick. The returned value is recorded as
+	// a snapshot, usually of the world model of the robot.
+	Snapshot func() any
+}
+
+// Recorder records the traffic of a robot. It implements the rtb.Observer
+// interface, so it can be attached to a robot with rtb.Robot.AddObserver.
+type Recorder struct {
+	cfg Config
+
+	mu   sync.Mutex
+	f    *os.File
+	w    *bufio.Writer
+	game int
+	time float64
+	err  error
+}
+
+// New returns a Recorder with the given configuration.
+func New(cfg Config) (*Recorder, error) {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "game"
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create directory: %v", err)
+	}
+	return &Recorder{cfg: cfg}, nil
+}
+
+// Message records a message received from the server.
+func (rec *Recorder) Message(msg rtb.Message) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		rec.time = 0
+	case rtb.MessageInfo:
+		rec.time = m.Time
+	}
+
+	raw, _ := rtb.EncodeMessage(msg)
+	data, _ := json.Marshal(msg)
+	rec.write(Record{
+		Time: rec.time,
+		Kind: KindMessage,
+		Type: MessageType(msg),
+		Raw:  raw,
+		Data: data,
+	})
+
+	switch msg.(type) {
+	case rtb.MessageInfo:
+		if rec.cfg.Snapshot != nil {
+			rec.snapshot(rec.cfg.Snapshot())
+		}
+	case rtb.MessageGameFinishes:
+		rec.closeFile()
+	}
+}
+
+// Command records a command sent to the server.
+func (rec *Recorder) Command(cmd string) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	keyword, _, _ := strings.Cut(cmd, " ")
+	rec.write(Record{
+		Time: rec.time,
+		Kind: KindCommand,
+		Type: keyword,
+		Raw:  cmd,
+	})
+}
+
+// Snapshot records a snapshot of an arbitrary value.
+func (rec *Recorder) Snapshot(v any) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.snapshot(v)
+}
+
+// Decision records a decision taken by a component of the strategy, with
+// the explanation of why it was taken.
+func (rec *Recorder) Decision(component, reason string) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.write(Record{
+		Time: rec.time,
+		Kind: KindDecision,
+		Type: component,
+		Raw:  reason,
+	})
+}
+
+// snapshot records a snapshot. rec.mu must be held.
+func (rec *Recorder) snapshot(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		rec.setErr(fmt.Errorf("could not marshal snapshot: %v", err))
+		return
+	}
+	rec.write(Record{Time: rec.time, Kind: KindSnapshot, Data: data})
+}
+
+// write writes a record, opening a new file if needed. rec.mu must be held.
+func (rec *Recorder) write(r Record) {
+	if rec.f == nil {
+		rec.game++
+		name := filepath.Join(rec.cfg.Dir, fmt.Sprintf("%v-%04d.jsonl", rec.cfg.Prefix, rec.game))
+		f, err := os.Create(name)
+		if err != nil {
+			rec.setErr(fmt.Errorf("could not create file: %v", err))
+			return
+		}
+		rec.f = f
+		rec.w = bufio.NewWriter(f)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		rec.setErr(fmt.Errorf("could not marshal record: %v", err))
+		return
+	}
+	b = append(b, '\n')
+	if _, err := rec.w.Write(b); err != nil {
+		rec.setErr(fmt.Errorf("could not write record: %v", err))
+	}
+}
+
+// closeFile flushes and closes the current file. rec.mu must be held.
+func (rec *Recorder) closeFile() {
+	if rec.f == nil {
+		return
+	}
+	if err := rec.w.Flush(); err != nil {
+		rec.setErr(fmt.Errorf("could not flush file: %v", err))
+	}
+	if err := rec.f.Close(); err != nil {
+		rec.setErr(fmt.Errorf("could not close file: %v", err))
+	}
+	rec.f, rec.w = nil, nil
+}
+
+// setErr sets the first error of the recorder. rec.mu must be held.
+func (rec *Recorder) setErr(err error) {
+	if rec.err == nil {
+		rec.err = err
+	}
+}
+
+// Err returns the first error found while recording. Errors do not stop the
+// recorder, so the robot keeps working if telemetry fails.
+func (rec *Recorder) Err() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return rec.err
+}
+
+// Close flushes and closes the current file. It returns the first error
+// found while recording.
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.closeFile()
+	return rec.err
+}
+
+// MessageType returns the type of msg without the "Message" prefix, e.g.
+// "Radar" for rtb.MessageRadar.
+func MessageType(msg rtb.Message) string {
+	if msg == nil {
+		return ""
+	}
+	return strings.TrimPrefix(reflect.TypeOf(msg).Name(), "Message")
+}
+
+// Read reads the records of a telemetry file.
+func Read(r io.Reader) ([]Record, error) {
+	var recs []Record
+
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not decode record: %v", err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// Message parses the message of a KindMessage record.
+func (r Record) Message() (rtb.Message, error) {
+	if r.Kind != KindMessage {
+		return nil, fmt.Errorf("not a message record (%v)", r.Kind)
+	}
+	return rtb.ParseMessage(r.Raw)
+}
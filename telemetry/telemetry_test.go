@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+
+	ticks := 0
+	rec, err := New(Config{
+		Dir:      dir,
+		Snapshot: func() any { ticks++; return map[string]int{"ticks": ticks} },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := rtb.NewRobot(nil, io.Discard)
+	r.AddObserver(rec)
+	s := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {
+		if _, ok := msg.(rtb.MessageRadar); ok {
+			r.Shoot(1)
+			rec.Decision("fire", "enemy in range")
+		}
+	})
+
+	msgs := []rtb.Message{
+		rtb.MessageInitialize{First: true},
+		rtb.MessageGameStarts{},
+		rtb.MessageInfo{Time: 0.5},
+		rtb.MessageRadar{Distance: 2, Object: rtb.ObjectRobot},
+		rtb.MessageGameFinishes{},
+		rtb.MessageGameStarts{},
+		rtb.MessageDead{},
+	}
+	for _, msg := range msgs {
+		r.Deliver(s, msg)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "game-0001.jsonl"))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f.Close()
+
+	recs, err := Read(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Record{
+		{Time: 0, Kind: KindMessage, Type: "Initialize", Raw: "Initialize 1", Data: []byte(`{"First":true}`)},
+		{Time: 0, Kind: KindMessage, Type: "GameStarts", Raw: "GameStarts", Data: []byte(`{}`)},
+		{Time: 0.5, Kind: KindMessage, Type: "Info", Raw: "Info 0.5 0 0", Data: []byte(`{"Time":0.5,"Speed":0,"CannonAngle":0}`)},
+		{Time: 0.5, Kind: KindSnapshot, Data: []byte(`{"ticks":1}`)},
+		{Time: 0.5, Kind: KindMessage, Type: "Radar", Raw: "Radar 2 0 0", Data: []byte(`{"Distance":2,"Object":0,"RadarAngle":0}`)},
+		{Time: 0.5, Kind: KindCommand, Type: "Shoot", Raw: "Shoot 1.000000"},
+		{Time: 0.5, Kind: KindDecision, Type: "fire", Raw: "enemy in range"},
+		{Time: 0.5, Kind: KindMessage, Type: "GameFinishes", Raw: "GameFinishes", Data: []byte(`{}`)},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		t.Errorf("unexpected records:\ngot=%+v\nwant=%+v", recs, want)
+	}
+
+	msg, err := recs[4].Message()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg != (rtb.MessageRadar{Distance: 2, Object: rtb.ObjectRobot}) {
+		t.Errorf("unexpected message: %#v", msg)
+	}
+
+	f2, err := os.Open(filepath.Join(dir, "game-0002.jsonl"))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f2.Close()
+
+	recs, err = Read(f2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 2 || recs[1].Type != "Dead" {
+		t.Errorf("unexpected records in second file: %+v", recs)
+	}
+}
+
+func TestMessageType(t *testing.T) {
+	if got := MessageType(rtb.MessageRobotsLeft{}); got != "RobotsLeft" {
+		t.Errorf("unexpected type: got=%q want=%q", got, "RobotsLeft")
+	}
+	if got := MessageType(nil); got != "" {
+		t.Errorf("unexpected type: got=%q want=%q", got, "")
+	}
+}
@@ -0,0 +1,99 @@
+package rtb
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextSplit defines how Printf and Debugf send the texts that do not fit in
+// a message. Texts are split at newlines and every line longer than a
+// message is split in chunks, which are sent as separate messages.
+type TextSplit struct {
+	// Disabled makes Printf and Debugf return ErrMessageTooLong for the
+	// texts that do not fit in a message, instead of splitting them.
+	Disabled bool
+
+	// Prefix is prepended to every message.
+	Prefix string
+
+	// Continuation is prepended to every message but the first one of a
+	// text, after Prefix, so the continuations can be told apart in the
+	// message window.
+	Continuation string
+}
+
+// DefaultTextSplit returns the text split used by robots without a text
+// split. The continuations are marked with "+ ".
+func DefaultTextSplit() TextSplit {
+	return TextSplit{Continuation: "+ "}
+}
+
+// SetTextSplit sets how Printf and Debugf send long texts.
+func (r *Robot) SetTextSplit(s TextSplit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.textSplit = &s
+}
+
+// SetTextSplit calls SetTextSplit on the default Robot.
+func SetTextSplit(s TextSplit) {
+	std.SetTextSplit(s)
+}
+
+// sendText sends text with the command keyword, splitting it according to
+// the text split of r.
+func (r *Robot) sendText(keyword, text string) error {
+	r.mu.Lock()
+	split := DefaultTextSplit()
+	if r.textSplit != nil {
+		split = *r.textSplit
+	}
+	r.mu.Unlock()
+
+	text = strings.TrimSuffix(text, "\n")
+	if split.Disabled {
+		return r.rawf("%v %v", keyword, text)
+	}
+
+	first := true
+	for _, line := range strings.Split(text, "\n") {
+		for {
+			head := split.Prefix
+			if !first {
+				head += split.Continuation
+			}
+
+			// The keyword, the separator and the trailing newline
+			// must also fit in the message.
+			max := 128 - len(keyword) - 2 - len(head)
+			if max <= 0 {
+				return fmt.Errorf("%w: no room for the text after %q", ErrMessageTooLong, head)
+			}
+
+			chunk := line
+			if len(chunk) > max {
+				n := max
+				for n > 0 && !utf8.RuneStart(line[n]) {
+					n--
+				}
+				if n == 0 {
+					// There is no rune start to cut at, as
+					// with invalid UTF-8.
+					n = max
+				}
+				chunk = line[:n]
+			}
+			if err := r.rawf("%v %v%v", keyword, head, chunk); err != nil {
+				return err
+			}
+
+			first = false
+			if line = line[len(chunk):]; line == "" {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package rtb
+
+import "sync"
+
+// Tick groups the messages sent by the server in a single turn. Fields of
+// messages that were not received during the turn are nil.
+type Tick struct {
+	// Time is the game time of the turn. It is the time of the Info
+	// message or, if the turn has no Info message, the game clock.
+	Time float64
+
+	Radar       *MessageRadar
+	RobotInfo   *MessageRobotInfo
+	Info        *MessageInfo
+	Coordinates *MessageCoordinates
+	Energy      *MessageEnergy
+
+	// Events are the Collision, RotationReached and RobotsLeft messages
+	// received since the previous tick, in order.
+	Events []Message
+}
+
+// empty returns true if no message has been added to t.
+func (t *Tick) empty() bool {
+	return t.Radar == nil && t.RobotInfo == nil && t.Info == nil &&
+		t.Coordinates == nil && t.Energy == nil && len(t.Events) == 0
+}
+
+// Aggregator is a strategy that groups the burst of messages sent by the
+// server in every turn into a single Tick, which is passed to OnTick. It
+// makes strategies that evaluate the state once per turn simpler, since they
+// do not need to know which message is the last one of the turn.
+//
+// A turn ends with the Energy message. If it is not received, the turn ends
+// when the first message of the next turn is received. The remaining messages
+// are passed to Other, after delivering the pending tick. Observers keep
+// receiving every message.
+type Aggregator struct {
+	// OnTick is called once per turn.
+	OnTick func(r *Robot, t Tick)
+
+	// Other receives the messages that are not part of a tick. If nil,
+	// they are discarded.
+	Other Strategy
+
+	mu      sync.Mutex
+	pending Tick
+}
+
+// Handle adds msg to the pending tick or passes it to a.Other.
+func (a *Aggregator) Handle(r *Robot, msg Message) {
+	var (
+		ticks []Tick
+		other bool
+	)
+
+	a.mu.Lock()
+	switch m := msg.(type) {
+	case MessageRadar:
+		if a.pending.Info != nil {
+			ticks = a.flush(r)
+		}
+		a.pending.Radar = &m
+	case MessageRobotInfo:
+		a.pending.RobotInfo = &m
+	case MessageInfo:
+		if a.pending.Info != nil {
+			ticks = a.flush(r)
+		}
+		a.pending.Info, a.pending.Time = &m, m.Time
+	case MessageCoordinates:
+		a.pending.Coordinates = &m
+	case MessageEnergy:
+		a.pending.Energy = &m
+		ticks = a.flush(r)
+	case MessageCollision, MessageRotationReached, MessageRobotsLeft:
+		a.pending.Events = append(a.pending.Events, msg)
+	default:
+		ticks, other = a.flush(r), true
+	}
+	a.mu.Unlock()
+
+	a.deliver(r, ticks)
+	if other && a.Other != nil {
+		a.Other.Handle(r, msg)
+	}
+}
+
+// Flush delivers the pending tick, if any. It can be used when no more
+// messages are expected in the current turn.
+func (a *Aggregator) Flush(r *Robot) {
+	a.mu.Lock()
+	ticks := a.flush(r)
+	a.mu.Unlock()
+
+	a.deliver(r, ticks)
+}
+
+// flush returns the pending tick, if any, and starts a new one. a.mu must be
+// held.
+func (a *Aggregator) flush(r *Robot) []Tick {
+	t := a.pending
+	a.pending = Tick{}
+	if t.empty() {
+		return nil
+	}
+	if t.Info == nil {
+		t.Time = r.GameClock()
+	}
+	return []Tick{t}
+}
+
+// deliver passes ticks to a.OnTick. Handlers are called without holding the
+// lock, so they can call back the aggregator.
+func (a *Aggregator) deliver(r *Robot, ticks []Tick) {
+	if a.OnTick == nil {
+		return
+	}
+	for _, t := range ticks {
+		a.OnTick(r, t)
+	}
+}
@@ -0,0 +1,115 @@
+// Package trace records why the components of a strategy take their
+// decisions, e.g. "dodge: enemy 3 fired from 12.5" or "fire: hold fire, not
+// aligned", so the post-mortems of lost games are tractable.
+//
+// The decisions are kept for the current tick and, optionally, written to
+// a telemetry file and sent to the server with Debugf:
+//
+//	tc := trace.New(trace.Config{Recorder: rec, Debug: r})
+//	r.AddObserver(tc)
+//	r.AddObserver(w)
+//	...
+//	fc := fire.New(r, w, tr, e, fire.Config{Trace: tc})
+//
+// The methods of a nil *Tracer do nothing, so the components can hold an
+// optional tracer without checking it.
+package trace
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+// Decision is a decision taken by a component.
+type Decision struct {
+	// Time is the game time of the tick of the decision.
+	Time float64
+
+	// Component is the name of the component, e.g. "dodge".
+	Component string
+
+	// Reason explains the decision.
+	Reason string
+}
+
+func (d Decision) String() string {
+	return fmt.Sprintf("%v: %v", d.Component, d.Reason)
+}
+
+// Config is the configuration of a Tracer.
+type Config struct {
+	// Recorder, if not nil, records the decisions in the telemetry
+	// files.
+	Recorder *telemetry.Recorder
+
+	// Debug, if not nil, is the robot used to send the decisions to the
+	// server with Debugf.
+	Debug *rtb.Robot
+}
+
+// Tracer records the decisions of the components of a strategy. It
+// implements the rtb.Observer interface and must be added to the robot
+// before the components that record decisions, so the decisions are
+// assigned to the right tick. Tracer methods can be called concurrently.
+type Tracer struct {
+	rtb.NopCommand
+
+	cfg Config
+
+	mu   sync.Mutex
+	time float64
+	tick []Decision
+}
+
+// New returns a Tracer with the given configuration.
+func New(cfg Config) *Tracer {
+	return &Tracer{cfg: cfg}
+}
+
+// Recordf records a decision of component, explained by the text formatted
+// with fmt.Sprintf.
+func (tc *Tracer) Recordf(component, format string, a ...any) {
+	if tc == nil {
+		return
+	}
+
+	tc.mu.Lock()
+	d := Decision{Time: tc.time, Component: component, Reason: fmt.Sprintf(format, a...)}
+	tc.tick = append(tc.tick, d)
+	tc.mu.Unlock()
+
+	if tc.cfg.Recorder != nil {
+		tc.cfg.Recorder.Decision(d.Component, d.Reason)
+	}
+	if tc.cfg.Debug != nil {
+		tc.cfg.Debug.Debugf("%v", d)
+	}
+}
+
+// Decisions returns the decisions recorded in the current tick.
+func (tc *Tracer) Decisions() []Decision {
+	if tc == nil {
+		return nil
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	return append([]Decision(nil), tc.tick...)
+}
+
+// Message starts a new tick when msg is an Info message.
+func (tc *Tracer) Message(msg rtb.Message) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		tc.time, tc.tick = 0, nil
+	case rtb.MessageInfo:
+		tc.time, tc.tick = m.Time, nil
+	}
+}
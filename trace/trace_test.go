@@ -0,0 +1,83 @@
+package trace
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/telemetry"
+)
+
+func TestTracer(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := telemetry.New(telemetry.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	r := rtb.NewRobot(nil, &out)
+	tc := New(Config{Recorder: rec, Debug: r})
+	for _, obs := range []rtb.Observer{tc, rec} {
+		r.AddObserver(obs)
+	}
+
+	nop := rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+	r.Deliver(nop, rtb.MessageGameStarts{})
+	r.Deliver(nop, rtb.MessageInfo{Time: 1})
+	tc.Recordf("dodge", "enemy %v fired", 3)
+	tc.Recordf("fire", "hold fire")
+
+	want := []Decision{
+		{Time: 1, Component: "dodge", Reason: "enemy 3 fired"},
+		{Time: 1, Component: "fire", Reason: "hold fire"},
+	}
+	if got := tc.Decisions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected decisions: got=%v want=%v", got, want)
+	}
+	if got, want := out.String(), "Debug dodge: enemy 3 fired\nDebug fire: hold fire\n"; got != want {
+		t.Errorf("unexpected output: got=%q want=%q", got, want)
+	}
+
+	r.Deliver(nop, rtb.MessageInfo{Time: 2})
+	if got := tc.Decisions(); len(got) != 0 {
+		t.Errorf("unexpected decisions in new tick: %v", got)
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := os.Open(filepath.Join(dir, "game-0001.jsonl"))
+	if err != nil {
+		t.Fatalf("could not open file: %v", err)
+	}
+	defer f.Close()
+	recs, err := telemetry.Read(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decisions []telemetry.Record
+	for _, rec := range recs {
+		if rec.Kind == telemetry.KindDecision {
+			decisions = append(decisions, rec)
+		}
+	}
+	wantRecs := []telemetry.Record{
+		{Time: 1, Kind: telemetry.KindDecision, Type: "dodge", Raw: "enemy 3 fired"},
+		{Time: 1, Kind: telemetry.KindDecision, Type: "fire", Raw: "hold fire"},
+	}
+	if !reflect.DeepEqual(decisions, wantRecs) {
+		t.Errorf("unexpected records: got=%+v want=%+v", decisions, wantRecs)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tc *Tracer
+	tc.Recordf("fire", "hold fire")
+	if got := tc.Decisions(); got != nil {
+		t.Errorf("unexpected decisions: %v", got)
+	}
+}
@@ -0,0 +1,180 @@
+package track
+
+import (
+	"strings"
+
+	"github.com/jroimartin/rtb/arena"
+)
+
+// Label is a set of behavioral labels of a robot.
+type Label uint
+
+// Behavioral labels.
+const (
+	// LabelCamper is the label of the robots that barely move.
+	LabelCamper Label = 1 << iota
+
+	// LabelRammer is the label of the robots that approach quickly.
+	LabelRammer
+
+	// LabelOrbiter is the label of the robots that move around at a
+	// constant distance.
+	LabelOrbiter
+
+	// LabelSniper is the label of the robots that fire mostly from far
+	// away.
+	LabelSniper
+)
+
+// labelNames are the names of the labels, in bit order.
+var labelNames = []string{"Camper", "Rammer", "Orbiter", "Sniper"}
+
+// Has returns true if l contains all the labels of other.
+func (l Label) Has(other Label) bool {
+	return l&other == other
+}
+
+func (l Label) String() string {
+	var names []string
+	for i, name := range labelNames {
+		if l&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "|")
+}
+
+// Classification configures the heuristics that label the tracks. The
+// movement of a robot is measured relative to the observer: its speed, the
+// speed at which it closes the distance and the speed perpendicular to the
+// line of sight. A drop in the energy reported for the robot means it has
+// fired, like in the dodge package, although hits cause false alarms too.
+type Classification struct {
+	// MinObservations is the number of observations needed to label a
+	// track. If zero, 10 is used.
+	MinObservations int
+
+	// Smoothing is the weight of a new measurement in the mean speeds,
+	// between 0 and 1. If zero, 0.1 is used.
+	Smoothing float64
+
+	// CamperSpeed is the mean speed under which a robot is a camper. If
+	// zero, 0.2 is used.
+	CamperSpeed float64
+
+	// RammerSpeed is the mean closing speed over which a robot is a
+	// rammer. If zero, 0.5 is used.
+	RammerSpeed float64
+
+	// OrbitRatio is the minimum ratio between the mean perpendicular
+	// speed and the mean speed of an orbiter. If zero, 0.8 is used.
+	OrbitRatio float64
+
+	// SniperRange is the distance from which shots are considered long
+	// range. If zero, 10 is used.
+	SniperRange float64
+
+	// SniperShots is the number of shots needed to label a sniper,
+	// which must fire most of them from long range. If zero, 3 is used.
+	SniperShots int
+
+	// MinDrop is the minimum energy drop of a robot to consider it has
+	// fired. If zero, 0.5 is used.
+	MinDrop float64
+}
+
+// withDefaults returns c with the zero fields set to their defaults.
+func (c Classification) withDefaults() Classification {
+	if c.MinObservations == 0 {
+		c.MinObservations = 10
+	}
+	if c.Smoothing == 0 {
+		c.Smoothing = 0.1
+	}
+	if c.CamperSpeed == 0 {
+		c.CamperSpeed = 0.2
+	}
+	if c.RammerSpeed == 0 {
+		c.RammerSpeed = 0.5
+	}
+	if c.OrbitRatio == 0 {
+		c.OrbitRatio = 0.8
+	}
+	if c.SniperRange == 0 {
+		c.SniperRange = 10
+	}
+	if c.SniperShots == 0 {
+		c.SniperShots = 3
+	}
+	if c.MinDrop == 0 {
+		c.MinDrop = 0.5
+	}
+	return c
+}
+
+// behavior are the measurements used to label a track.
+type behavior struct {
+	speed, closing, lateral float64
+	samples                 int
+	shots, longShots        int
+}
+
+// measure updates the mean speeds of t, observed from own, and its labels.
+func (c Classification) measure(t *Track, own arena.Point) {
+	b := &t.behavior
+	speed := t.Vel.Len()
+	var closing, lateral float64
+	if los := t.Pos.Sub(own); los.Len() > 0 {
+		u := los.Mul(1 / los.Len())
+		closing = -t.Vel.Dot(u)
+		lateral = t.Vel.Sub(u.Mul(-closing)).Len()
+	}
+
+	if b.samples == 0 {
+		b.speed, b.closing, b.lateral = speed, closing, lateral
+	} else {
+		k := c.Smoothing
+		b.speed += k * (speed - b.speed)
+		b.closing += k * (closing - b.closing)
+		b.lateral += k * (lateral - b.lateral)
+	}
+	b.samples++
+	c.label(t)
+}
+
+// energy records the energy reported for t, observed from own, detecting
+// its shots.
+func (c Classification) energy(t *Track, own arena.Point, energy float64) {
+	if t.Energy > 0 && t.Energy-energy >= c.MinDrop {
+		t.behavior.shots++
+		if t.Pos.Sub(own).Len() >= c.SniperRange {
+			t.behavior.longShots++
+		}
+		c.label(t)
+	}
+}
+
+// label sets the labels of t according to its measurements.
+func (c Classification) label(t *Track) {
+	b := t.behavior
+	if t.Observations < c.MinObservations {
+		return
+	}
+
+	var l Label
+	switch {
+	case b.speed < c.CamperSpeed:
+		l |= LabelCamper
+	case b.closing > c.RammerSpeed:
+		l |= LabelRammer
+	case b.lateral >= c.OrbitRatio*b.speed:
+		l |= LabelOrbiter
+	}
+	if b.shots >= c.SniperShots && 2*b.longShots > b.shots {
+		l |= LabelSniper
+	}
+	t.Labels = l
+}
@@ -0,0 +1,182 @@
+package track
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb/arena"
+)
+
+// Observation is a position of a robot observed by the radar.
+type Observation struct {
+	// Time is the game time of the observation.
+	Time float64
+
+	// Pos is the observed position.
+	Pos arena.Point
+}
+
+// Predictor predicts the motion of a robot.
+type Predictor interface {
+	// Predict returns the position of the robot of the track dt seconds
+	// after it was last seen.
+	Predict(t Track, dt float64) arena.Point
+}
+
+// Linear is a Predictor that assumes robots move with constant velocity.
+type Linear struct{}
+
+// Predict returns the position of the robot assuming constant velocity.
+func (Linear) Predict(t Track, dt float64) arena.Point {
+	return t.Pos.Add(t.Vel.Mul(dt))
+}
+
+// Circular is a Predictor that assumes robots move with constant speed and
+// turn rate. It falls back to Linear if there are less than three
+// observations.
+type Circular struct{}
+
+// Predict returns the position of the robot assuming constant speed and turn
+// rate.
+func (Circular) Predict(t Track, dt float64) arena.Point {
+	h := t.History
+	if len(h) < 3 {
+		return Linear{}.Predict(t, dt)
+	}
+	o1, o2, o3 := h[len(h)-3], h[len(h)-2], h[len(h)-1]
+	dt1, dt2 := o2.Time-o1.Time, o3.Time-o2.Time
+	if dt1 <= 0 || dt2 <= 0 {
+		return Linear{}.Predict(t, dt)
+	}
+	v1, v2 := o2.Pos.Sub(o1.Pos).Mul(1/dt1), o3.Pos.Sub(o2.Pos).Mul(1/dt2)
+
+	// The chords between observations are parallel to the tangent at
+	// their midpoints and shorter than the arcs, so the heading and the
+	// speed at the last observation are corrected accordingly.
+	dh := math.Atan2(v2.Y, v2.X) - math.Atan2(v1.Y, v1.X)
+	dh = math.Atan2(math.Sin(dh), math.Cos(dh))
+	omega := dh / ((dt1 + dt2) / 2)
+	half := omega * dt2 / 2
+	heading := math.Atan2(v2.Y, v2.X) + half
+	speed := v2.Len()
+	if math.Abs(half) > 1e-9 {
+		speed *= half / math.Sin(half)
+	}
+
+	if math.Abs(omega) < 1e-9 {
+		return t.Pos.Add(arena.Polar(heading, speed*dt))
+	}
+	// Integrate the position along the arc.
+	r := speed / omega
+	end := heading + omega*dt
+	return t.Pos.Add(arena.Point{
+		X: r * (math.Sin(end) - math.Sin(heading)),
+		Y: -r * (math.Cos(end) - math.Cos(heading)),
+	})
+}
+
+// Pattern is a Predictor that searches the history of the robot for the
+// sequence of movements most similar to the latest one and assumes the robot
+// will repeat what it did after it. Movements are compared relative to the
+// heading of the robot, so patterns are recognized in any direction. It falls
+// back to Linear if the history is too short.
+type Pattern struct {
+	// Window is the number of movements compared. If zero, 8 is used.
+	Window int
+}
+
+// Predict returns the position of the robot replaying the movements that
+// followed the best match of the latest ones.
+func (p Pattern) Predict(t Track, dt float64) arena.Point {
+	window := p.Window
+	if window == 0 {
+		window = 8
+	}
+
+	moves := movements(t.History)
+	if len(moves) < 2*window+1 {
+		return Linear{}.Predict(t, dt)
+	}
+
+	// The latest window is compared with all the previous ones that
+	// are followed by at least one movement.
+	last := moves[len(moves)-window:]
+	best, bestErr := -1, math.Inf(1)
+	for i := 0; i+window < len(moves)-window; i++ {
+		var err float64
+		for j := 0; j < window; j++ {
+			err += moves[i+j].d.Sub(last[j].d).Len() + math.Abs(moves[i+j].dt-last[j].dt)
+		}
+		if err < bestErr {
+			best, bestErr = i, err
+		}
+	}
+	if best < 0 {
+		return Linear{}.Predict(t, dt)
+	}
+
+	// Replay the following movements, starting from the current
+	// heading.
+	pos, heading := t.Pos, last[len(last)-1].heading
+	var elapsed float64
+	for _, m := range moves[best+window:] {
+		d := rotate(m.d, heading)
+		if elapsed+m.dt >= dt {
+			return pos.Add(d.Mul((dt - elapsed) / m.dt))
+		}
+		pos = pos.Add(d)
+		elapsed += m.dt
+		if m.d.Len() > 1e-9 {
+			heading += math.Atan2(m.d.Y, m.d.X)
+		}
+	}
+
+	// The replayed movements are shorter than dt, so the rest is
+	// extrapolated with the current velocity.
+	return pos.Add(t.Vel.Mul(dt - elapsed))
+}
+
+// movement is the displacement between two consecutive observations.
+type movement struct {
+	d       arena.Point
+	dt      float64
+	heading float64
+}
+
+// movements returns the movements of a history. Movements are normalized
+// relative to the heading of the previous movement.
+func movements(h []Observation) []movement {
+	var (
+		moves   []movement
+		heading float64
+	)
+	for i := 1; i < len(h); i++ {
+		d, dt := h[i].Pos.Sub(h[i-1].Pos), h[i].Time-h[i-1].Time
+		if dt <= 0 {
+			continue
+		}
+		m := movement{d: d, dt: dt, heading: heading}
+		if d.Len() > 1e-9 {
+			m.heading = math.Atan2(d.Y, d.X)
+		}
+		heading = m.heading
+		moves = append(moves, m)
+	}
+
+	// Express every displacement relative to the heading of the
+	// previous movement.
+	rel := make([]movement, len(moves))
+	for i, m := range moves {
+		var prev float64
+		if i > 0 {
+			prev = moves[i-1].heading
+		}
+		rel[i] = movement{d: rotate(m.d, -prev), dt: m.dt, heading: m.heading}
+	}
+	return rel
+}
+
+// rotate rotates p by angle.
+func rotate(p arena.Point, angle float64) arena.Point {
+	sin, cos := math.Sincos(angle)
+	return arena.Point{X: p.X*cos - p.Y*sin, Y: p.X*sin + p.Y*cos}
+}
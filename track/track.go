@@ -0,0 +1,352 @@
+// Package track implements an enemy tracker. It turns the robots detected by
+// the radar into tracks with an estimated position and velocity, which can be
+// used to aim at them. Tracks are also labeled with the behavior of the
+// robots, e.g. campers or rammers, so strategies can select counter-tactics.
+package track
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Track is a robot detected by the radar.
+type Track struct {
+	// ID identifies the track. IDs are not reused during a game.
+	ID int
+
+	// Pos is the last observed position of the robot.
+	Pos arena.Point
+
+	// Vel is the estimated velocity of the robot.
+	Vel arena.Point
+
+	// LastSeen is the game time of the last observation.
+	LastSeen float64
+
+	// Energy is the last reported energy level of the robot.
+	Energy float64
+
+	// TeamMate is true if the robot is a team mate.
+	TeamMate bool
+
+	// Observations is the number of times the robot has been observed.
+	Observations int
+
+	// History contains the latest observations, oldest first.
+	History []Observation
+
+	// Predictor is the motion model used by PredictPosition and
+	// Intercept. If nil, Linear is used.
+	Predictor Predictor
+
+	// Labels are the behavioral labels of the robot. They are updated
+	// with every observation, according to Config.Classification.
+	Labels Label
+
+	behavior behavior
+}
+
+// PredictPosition returns the position of the robot dt seconds after it was
+// last seen, according to the motion model of the track.
+func (t Track) PredictPosition(dt float64) arena.Point {
+	if t.Predictor == nil {
+		return Linear{}.Predict(t, dt)
+	}
+	return t.Predictor.Predict(t, dt)
+}
+
+// PositionAt returns the estimated position of the robot at the given game
+// time, assuming it moves with constant velocity.
+func (t Track) PositionAt(time float64) arena.Point {
+	return t.Pos.Add(t.Vel.Mul(time - t.LastSeen))
+}
+
+// Intercept returns the point where a shot fired from shooter at the given
+// game time and speed would hit the robot, according to the motion model of
+// the track, and the flight time of the shot. It returns false if the shot
+// cannot reach the robot.
+//
+// The intercept is solved exactly assuming constant velocity. For other
+// motion models, the solution is refined iteratively.
+func (t Track) Intercept(shooter arena.Point, time, speed float64) (arena.Point, float64, bool) {
+	tau, ok := t.linearIntercept(shooter, time, speed)
+	if !ok {
+		return arena.Point{}, 0, false
+	}
+	if _, linear := t.Predictor.(Linear); t.Predictor == nil || linear {
+		return t.PositionAt(time + tau), tau, true
+	}
+
+	elapsed := time - t.LastSeen
+	for i := 0; i < 20; i++ {
+		p := t.PredictPosition(elapsed + tau)
+		next := p.Sub(shooter).Len() / speed
+		if math.Abs(next-tau) < 1e-6 {
+			break
+		}
+		tau = next
+	}
+	return t.PredictPosition(elapsed + tau), tau, true
+}
+
+// linearIntercept returns the flight time of a shot that intercepts the
+// robot assuming it moves with constant velocity.
+func (t Track) linearIntercept(shooter arena.Point, time, speed float64) (float64, bool) {
+	// Solve |p + v*tau| = speed*tau, where p is the position of the
+	// robot relative to the shooter.
+	p := t.PositionAt(time).Sub(shooter)
+	a := t.Vel.Dot(t.Vel) - speed*speed
+	b := 2 * p.Dot(t.Vel)
+	c := p.Dot(p)
+
+	var tau float64
+	if math.Abs(a) < 1e-12 {
+		if b >= 0 {
+			return 0, false
+		}
+		tau = -c / b
+	} else {
+		disc := b*b - 4*a*c
+		if disc < 0 {
+			return 0, false
+		}
+		sq := math.Sqrt(disc)
+		t1, t2 := (-b-sq)/(2*a), (-b+sq)/(2*a)
+		tau = math.Min(t1, t2)
+		if tau < 0 {
+			tau = math.Max(t1, t2)
+		}
+	}
+	if tau < 0 {
+		return 0, false
+	}
+	return tau, true
+}
+
+// Config is the configuration of a Tracker.
+type Config struct {
+	// Gate is the maximum distance between the predicted position of a
+	// track and an observation to associate them. If zero, 3 is used.
+	Gate float64
+
+	// MaxAge is the time after which a track that has not been observed
+	// is discarded. If zero, 5 is used.
+	MaxAge float64
+
+	// Smoothing is the weight of a new velocity measurement, between 0
+	// and 1. If zero, 0.5 is used.
+	Smoothing float64
+
+	// HistoryLen is the maximum number of observations kept in the
+	// history of the tracks. If zero, 100 is used.
+	HistoryLen int
+
+	// Predictor is the motion model of new tracks. If nil, Linear is
+	// used.
+	Predictor Predictor
+
+	// Classification configures the behavioral labels of the tracks.
+	Classification Classification
+}
+
+// Tracker tracks the robots detected by the radar. It implements the
+// rtb.Observer interface and must be added to the robot after the world
+// model. Tracker methods can be called concurrently.
+type Tracker struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+
+	mu     sync.Mutex
+	tracks []*Track
+	nextID int
+
+	// last is the track updated by the last Radar message. It receives
+	// the data of the following RobotInfo message.
+	last *Track
+}
+
+// New returns a Tracker that uses w to compute the absolute position of the
+// detected robots.
+func New(w *world.World, cfg Config) *Tracker {
+	if cfg.Gate == 0 {
+		cfg.Gate = 3
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 5
+	}
+	if cfg.Smoothing == 0 {
+		cfg.Smoothing = 0.5
+	}
+	if cfg.HistoryLen == 0 {
+		cfg.HistoryLen = 100
+	}
+	if cfg.Predictor == nil {
+		cfg.Predictor = Linear{}
+	}
+	cfg.Classification = cfg.Classification.withDefaults()
+	return &Tracker{cfg: cfg, w: w, nextID: 1}
+}
+
+// Message updates the tracks with a message received from the server.
+func (tr *Tracker) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		tr.mu.Lock()
+		tr.tracks, tr.last, tr.nextID = nil, nil, 1
+		tr.mu.Unlock()
+	case rtb.MessageRadar:
+		if m.Object != rtb.ObjectRobot {
+			return
+		}
+		pos := tr.w.Absolute(m.RadarAngle, m.Distance)
+		tr.observe(pos, tr.w.State().Time)
+	case rtb.MessageRobotInfo:
+		tr.mu.Lock()
+		if tr.last != nil {
+			tr.cfg.Classification.energy(tr.last, tr.w.State().Pos, m.EnergyLevel)
+			tr.last.Energy = m.EnergyLevel
+			tr.last.TeamMate = m.TeamMate
+		}
+		tr.mu.Unlock()
+	case rtb.MessageInfo:
+		tr.prune(m.Time)
+	}
+}
+
+// observe associates an observation with the closest track or creates a new
+// one.
+func (tr *Tracker) observe(pos arena.Point, time float64) {
+	own := tr.w.State().Pos
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var (
+		best  *Track
+		moved bool
+	)
+	bestDist := tr.cfg.Gate
+	for _, t := range tr.tracks {
+		if d := t.PositionAt(time).Sub(pos).Len(); d <= bestDist {
+			best, bestDist = t, d
+		}
+	}
+
+	if best == nil {
+		best = &Track{ID: tr.nextID, Pos: pos, LastSeen: time, Predictor: tr.cfg.Predictor}
+		tr.nextID++
+		tr.tracks = append(tr.tracks, best)
+	} else if dt := time - best.LastSeen; dt > 0 {
+		vel := pos.Sub(best.Pos).Mul(1 / dt)
+		if best.Observations == 1 {
+			best.Vel = vel
+		} else {
+			k := tr.cfg.Smoothing
+			best.Vel = best.Vel.Mul(1 - k).Add(vel.Mul(k))
+		}
+		best.Pos, best.LastSeen = pos, time
+		moved = true
+	} else {
+		best.Pos = pos
+	}
+	best.Observations++
+	if moved {
+		tr.cfg.Classification.measure(best, own)
+	}
+	if n := len(best.History); n > 0 && best.History[n-1].Time == time {
+		best.History[n-1].Pos = pos
+	} else {
+		best.History = append(best.History, Observation{Time: time, Pos: pos})
+	}
+	if len(best.History) > tr.cfg.HistoryLen {
+		best.History = append(best.History[:0], best.History[len(best.History)-tr.cfg.HistoryLen:]...)
+	}
+	tr.last = best
+}
+
+// Observe adds an observation of a robot reported by another source, e.g. a
+// team mate. energy is the energy level of the robot, or zero if unknown.
+// Observations must use the same coordinate system as the world model.
+func (tr *Tracker) Observe(pos arena.Point, time, energy float64) {
+	tr.observe(pos, time)
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if energy > 0 {
+		tr.cfg.Classification.energy(tr.last, tr.w.State().Pos, energy)
+		tr.last.Energy = energy
+	}
+	// The observation does not come from the radar, so it must not
+	// receive the data of the next RobotInfo message.
+	tr.last = nil
+}
+
+// SetPredictor sets the motion model of a track. It returns false if the
+// track does not exist.
+func (tr *Tracker) SetPredictor(id int, p Predictor) bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, t := range tr.tracks {
+		if t.ID == id {
+			t.Predictor = p
+			return true
+		}
+	}
+	return false
+}
+
+// prune discards the tracks that have not been observed recently.
+func (tr *Tracker) prune(now float64) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	live := tr.tracks[:0]
+	for _, t := range tr.tracks {
+		if now-t.LastSeen <= tr.cfg.MaxAge {
+			live = append(live, t)
+		}
+	}
+	for i := len(live); i < len(tr.tracks); i++ {
+		tr.tracks[i] = nil
+	}
+	tr.tracks = live
+}
+
+// Tracks returns the current tracks.
+func (tr *Tracker) Tracks() []Track {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tracks := make([]Track, len(tr.tracks))
+	for i, t := range tr.tracks {
+		tracks[i] = *t
+		tracks[i].History = append([]Observation(nil), t.History...)
+	}
+	return tracks
+}
+
+// Nearest returns the enemy track closest to p. Team mates are ignored. It
+// returns false if there are no enemy tracks.
+func (tr *Tracker) Nearest(p arena.Point) (Track, bool) {
+	var (
+		best  Track
+		found bool
+	)
+	for _, t := range tr.Tracks() {
+		if t.TeamMate {
+			continue
+		}
+		if !found || t.Pos.Sub(p).Len() < best.Pos.Sub(p).Len() {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,336 @@
+package track
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestIntercept(t *testing.T) {
+	tests := []struct {
+		name    string
+		track   Track
+		speed   float64
+		want    arena.Point
+		wantTau float64
+		wantOk  bool
+	}{
+		{
+			"Static",
+			Track{Pos: arena.Point{X: 10, Y: 0}},
+			5,
+			arena.Point{X: 10, Y: 0},
+			2,
+			true,
+		},
+		{
+			"Crossing",
+			Track{Pos: arena.Point{X: 8, Y: 4}, Vel: arena.Point{X: 0, Y: 1}},
+			5,
+			arena.Point{X: 8, Y: 6},
+			2,
+			true,
+		},
+		{
+			"Escaping",
+			Track{Pos: arena.Point{X: 10, Y: 0}, Vel: arena.Point{X: 10, Y: 0}},
+			5,
+			arena.Point{},
+			0,
+			false,
+		},
+		{
+			"Same speed approaching",
+			Track{Pos: arena.Point{X: 10, Y: 0}, Vel: arena.Point{X: -5, Y: 0}},
+			5,
+			arena.Point{X: 5, Y: 0},
+			1,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, tau, ok := tt.track.Intercept(arena.Point{}, 0, tt.speed)
+			if ok != tt.wantOk {
+				t.Fatalf("unexpected ok: got=%v want=%v", ok, tt.wantOk)
+			}
+			if got.Sub(tt.want).Len() > 1e-9 || math.Abs(tau-tt.wantTau) > 1e-9 {
+				t.Errorf("unexpected intercept: got=%v, %v want=%v, %v", got, tau, tt.want, tt.wantTau)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	w := world.New()
+	tr := New(w, Config{})
+
+	msgs := []rtb.Message{
+		rtb.MessageGameStarts{},
+		rtb.MessageCoordinates{X: 0, Y: 0, Angle: 0},
+		rtb.MessageRadar{Distance: 10, Object: rtb.ObjectRobot, RadarAngle: 0},
+		rtb.MessageRobotInfo{EnergyLevel: 50},
+		rtb.MessageInfo{Time: 1},
+		rtb.MessageRadar{Distance: 5, Object: rtb.ObjectRobot, RadarAngle: math.Pi / 2},
+		rtb.MessageRobotInfo{EnergyLevel: 80, TeamMate: true},
+		rtb.MessageInfo{Time: 2},
+		rtb.MessageRadar{Distance: 11, Object: rtb.ObjectRobot, RadarAngle: 0},
+		rtb.MessageRobotInfo{EnergyLevel: 40},
+		rtb.MessageInfo{Time: 3},
+	}
+	for _, msg := range msgs {
+		w.Message(msg)
+		tr.Message(msg)
+	}
+
+	tracks := tr.Tracks()
+	if len(tracks) != 2 {
+		t.Fatalf("wrong number of tracks: got=%v want=%v", len(tracks), 2)
+	}
+
+	enemy := tracks[0]
+	if enemy.ID != 1 || enemy.Observations != 2 || enemy.Energy != 40 || enemy.TeamMate {
+		t.Errorf("unexpected enemy track: %+v", enemy)
+	}
+	if enemy.Vel.Sub(arena.Point{X: 0.5, Y: 0}).Len() > 1e-9 {
+		t.Errorf("unexpected velocity: got=%v want=%v", enemy.Vel, arena.Point{X: 0.5, Y: 0})
+	}
+
+	if got, ok := tr.Nearest(arena.Point{}); !ok || got.ID != enemy.ID {
+		t.Errorf("unexpected nearest track: got=%+v, %v want=%v", got, ok, enemy.ID)
+	}
+
+	// Tracks are discarded after MaxAge.
+	msg := rtb.MessageInfo{Time: 6.5}
+	w.Message(msg)
+	tr.Message(msg)
+	if tracks := tr.Tracks(); len(tracks) != 1 || tracks[0].ID != enemy.ID {
+		t.Errorf("unexpected tracks after pruning: %+v", tracks)
+	}
+}
+
+// trackFrom returns a track with the given history, sampled every dt
+// seconds, and the given predictor.
+func trackFrom(pos func(time float64) arena.Point, n int, dt float64, p Predictor) Track {
+	var t Track
+	for i := 0; i < n; i++ {
+		time := float64(i) * dt
+		t.History = append(t.History, Observation{Time: time, Pos: pos(time)})
+	}
+	last := t.History[n-1]
+	t.Pos, t.LastSeen = last.Pos, last.Time
+	t.Vel = last.Pos.Sub(t.History[n-2].Pos).Mul(1 / dt)
+	t.Predictor = p
+	return t
+}
+
+func TestPredictors(t *testing.T) {
+	circle := func(time float64) arena.Point {
+		return arena.Polar(0.5*time, 10)
+	}
+	zigzag := func(time float64) arena.Point {
+		// Moves at speed 1 along X and alternates the Y direction
+		// every 2 seconds.
+		phase := math.Mod(time, 4)
+		y := phase
+		if phase > 2 {
+			y = 4 - phase
+		}
+		return arena.Point{X: time, Y: y}
+	}
+
+	tests := []struct {
+		name    string
+		pos     func(time float64) arena.Point
+		p       Predictor
+		maxErr  float64
+		wantErr bool
+	}{
+		{"Linear on circle", circle, Linear{}, 0, true},
+		{"Circular on circle", circle, Circular{}, 1e-6, false},
+		{"Linear on zigzag", zigzag, Linear{}, 0, true},
+		{"Pattern on zigzag", zigzag, Pattern{}, 1e-6, false},
+	}
+
+	const (
+		n     = 40
+		dt    = 0.25
+		ahead = 1.5
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := trackFrom(tt.pos, n, dt, tt.p)
+			want := tt.pos(tr.LastSeen + ahead)
+			err := tr.PredictPosition(ahead).Sub(want).Len()
+			if tt.wantErr {
+				if err < 0.1 {
+					t.Errorf("unexpected accurate prediction: err=%v", err)
+				}
+				return
+			}
+			if err > tt.maxErr {
+				t.Errorf("prediction error too big: got=%v want<=%v", err, tt.maxErr)
+			}
+		})
+	}
+}
+
+func TestInterceptPredictor(t *testing.T) {
+	circle := func(time float64) arena.Point {
+		return arena.Polar(0.5*time, 10)
+	}
+	tr := trackFrom(circle, 10, 0.25, Circular{})
+
+	p, tau, ok := tr.Intercept(arena.Point{}, tr.LastSeen, 5)
+	if !ok {
+		t.Fatalf("no intercept")
+	}
+	// The target moves on a circle of radius 10 centered at the
+	// shooter, so the flight time is always 2.
+	if math.Abs(tau-2) > 1e-6 || p.Sub(circle(tr.LastSeen+2)).Len() > 1e-6 {
+		t.Errorf("unexpected intercept: got=%v, %v want=%v, %v", p, tau, circle(tr.LastSeen+2), 2)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	w := world.New()
+	tr := New(w, Config{HistoryLen: 3, Predictor: Circular{}})
+
+	w.Message(rtb.MessageGameStarts{})
+	for i := 0; i < 5; i++ {
+		w.Message(rtb.MessageInfo{Time: float64(i)})
+		tr.Message(rtb.MessageRadar{Distance: 10 + float64(i), Object: rtb.ObjectRobot})
+	}
+
+	tracks := tr.Tracks()
+	if len(tracks) != 1 {
+		t.Fatalf("wrong number of tracks: got=%v want=%v", len(tracks), 1)
+	}
+	h := tracks[0].History
+	if len(h) != 3 || h[0].Time != 2 || h[2].Pos.X != 14 {
+		t.Errorf("unexpected history: %v", h)
+	}
+	if _, ok := tracks[0].Predictor.(Circular); !ok {
+		t.Errorf("unexpected predictor: %T", tracks[0].Predictor)
+	}
+
+	if !tr.SetPredictor(tracks[0].ID, Pattern{}) {
+		t.Fatalf("could not set predictor")
+	}
+	if _, ok := tr.Tracks()[0].Predictor.(Pattern); !ok {
+		t.Errorf("predictor not set")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	w := world.New()
+	tr := New(w, Config{})
+
+	w.Message(rtb.MessageGameStarts{})
+	tr.Observe(arena.Point{X: 10, Y: 0}, 0, 50)
+	tr.Observe(arena.Point{X: 11, Y: 0}, 1, 0)
+	// The RobotInfo message does not belong to the observations.
+	tr.Message(rtb.MessageRobotInfo{EnergyLevel: 80, TeamMate: true})
+
+	tracks := tr.Tracks()
+	if len(tracks) != 1 {
+		t.Fatalf("wrong number of tracks: got=%v want=%v", len(tracks), 1)
+	}
+	if got := tracks[0]; got.Energy != 50 || got.TeamMate || got.Observations != 2 || got.Vel != (arena.Point{X: 1}) {
+		t.Errorf("unexpected track: %+v", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		pos    func(time float64) arena.Point
+		energy func(time float64) float64
+		want   Label
+	}{
+		{
+			"Camper",
+			func(time float64) arena.Point { return arena.Point{X: 5, Y: 5} },
+			nil,
+			LabelCamper,
+		},
+		{
+			"Rammer",
+			func(time float64) arena.Point { return arena.Point{X: 20 - time, Y: 1} },
+			nil,
+			LabelRammer,
+		},
+		{
+			"Orbiter",
+			func(time float64) arena.Point { return arena.Polar(time/8, 8) },
+			nil,
+			LabelOrbiter,
+		},
+		{
+			"Sniper",
+			func(time float64) arena.Point { return arena.Point{X: 15, Y: 0} },
+			// Fires every second.
+			func(time float64) float64 { return 100 - 2*math.Floor(time) },
+			LabelCamper | LabelSniper,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := world.New()
+			tr := New(w, Config{})
+			observers := []rtb.Observer{w, tr}
+			deliver := func(msg rtb.Message) {
+				for _, o := range observers {
+					o.Message(msg)
+				}
+			}
+
+			deliver(rtb.MessageGameStarts{})
+			deliver(rtb.MessageCoordinates{})
+			for i := 0; i < 100; i++ {
+				time := float64(i) * 0.1
+				p := tt.pos(time)
+				energy := 100.0
+				if tt.energy != nil {
+					energy = tt.energy(time)
+				}
+				deliver(rtb.MessageInfo{Time: time})
+				deliver(rtb.MessageRadar{Distance: p.Len(), Object: rtb.ObjectRobot, RadarAngle: math.Atan2(p.Y, p.X)})
+				deliver(rtb.MessageRobotInfo{EnergyLevel: energy})
+			}
+
+			tracks := tr.Tracks()
+			if len(tracks) != 1 {
+				t.Fatalf("wrong number of tracks: got=%v want=%v", len(tracks), 1)
+			}
+			if got := tracks[0].Labels; got != tt.want {
+				t.Errorf("unexpected labels: got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelString(t *testing.T) {
+	tests := []struct {
+		l    Label
+		want string
+	}{
+		{0, "None"},
+		{LabelRammer, "Rammer"},
+		{LabelCamper | LabelSniper, "Camper|Sniper"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.l.String(); got != tt.want {
+			t.Errorf("unexpected string: got=%v want=%v", got, tt.want)
+		}
+		if !tt.l.Has(tt.l) || tt.l != 0 && tt.l.Has(LabelOrbiter) {
+			t.Errorf("unexpected Has result for %v", tt.l)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package tuning searches the parameters of a strategy that perform best
+// against a set of opponents. Every configuration of the parameters is
+// evaluated with a self-play run in the simulator and the runs are
+// parallelized across goroutines.
+package tuning
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/selfplay"
+	"github.com/jroimartin/rtb/sim"
+)
+
+// Param is a parameter of a strategy.
+type Param struct {
+	// Name identifies the parameter.
+	Name string
+
+	// Values are the values of the parameter. They are required by a
+	// grid search. If a random search is used and Values is empty, the
+	// parameter is sampled uniformly from [Min, Max).
+	Values []float64
+
+	// Min and Max are the limits of the parameter in a random search
+	// without Values.
+	Min, Max float64
+}
+
+// Params are the values of the parameters of a configuration, by name.
+type Params map[string]float64
+
+// Config is the configuration of a search.
+type Config struct {
+	// Params are the parameters to tune.
+	Params []Param
+
+	// Candidate returns the contender that uses the given parameters.
+	// It is called concurrently.
+	Candidate func(p Params) selfplay.Contender
+
+	// Opponents returns the contenders the candidate plays against.
+	// Every self-play run needs new instances of the strategies, so it
+	// is called once per configuration. It is called concurrently.
+	Opponents func() []selfplay.Contender
+
+	// Samples is the number of configurations of a random search. If
+	// zero, a grid search over all the combinations of Values is run.
+	Samples int
+
+	// Games is the number of games played by every configuration.
+	Games int
+
+	// Seed is the seed of the random search and of the first game of
+	// every self-play run. All the configurations play the same games,
+	// so they are compared fairly.
+	Seed int64
+
+	// Workers is the number of configurations evaluated concurrently.
+	// If zero, runtime.GOMAXPROCS(0) is used.
+	Workers int
+
+	// Arena and Options are passed to the self-play runs.
+	Arena   *arena.Arena
+	Options sim.Options
+}
+
+// Result is the performance of a configuration.
+type Result struct {
+	// Params are the values of the parameters.
+	Params Params
+
+	// Games and Wins are the number of games played and won.
+	Games, Wins int
+
+	// WinRate is the ratio of games won.
+	WinRate float64
+
+	// Low and High are the limits of the 95% confidence interval of the
+	// win rate, computed with the Wilson score interval.
+	Low, High float64
+}
+
+// Run runs the search described by cfg. It returns the results of all the
+// configurations, sorted from best to worst by win rate and, in case of tie,
+// by the lower limit of the confidence interval.
+func Run(cfg Config) ([]Result, error) {
+	if cfg.Candidate == nil || cfg.Opponents == nil {
+		return nil, errors.New("missing candidate or opponents")
+	}
+	if cfg.Games <= 0 {
+		return nil, errors.New("invalid number of games")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		configs []Params
+		err     error
+	)
+	if cfg.Samples > 0 {
+		configs = sample(cfg.Params, cfg.Samples, cfg.Seed)
+	} else if configs, err = grid(cfg.Params); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(configs))
+	errs := make([]error, len(configs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j], errs[j] = evaluate(cfg, configs[j])
+			}
+		}()
+	}
+	for i := range configs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("could not evaluate %v: %v", configs[i], err)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		ri, rj := results[i], results[j]
+		if ri.WinRate != rj.WinRate {
+			return ri.WinRate > rj.WinRate
+		}
+		return ri.Low > rj.Low
+	})
+	return results, nil
+}
+
+// evaluate runs the games of the configuration p.
+func evaluate(cfg Config, p Params) (Result, error) {
+	contenders := append([]selfplay.Contender{cfg.Candidate(p)}, cfg.Opponents()...)
+	rep, err := selfplay.Run(selfplay.Config{
+		Contenders: contenders,
+		Games:      cfg.Games,
+		Seed:       cfg.Seed,
+		Arena:      cfg.Arena,
+		Options:    cfg.Options,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	c := rep.Contenders[0]
+	low, high := wilson(c.Wins, rep.Games)
+	return Result{
+		Params:  p,
+		Games:   rep.Games,
+		Wins:    c.Wins,
+		WinRate: c.WinRate,
+		Low:     low,
+		High:    high,
+	}, nil
+}
+
+// grid returns all the combinations of the values of params.
+func grid(params []Param) ([]Params, error) {
+	configs := []Params{{}}
+	for _, p := range params {
+		if len(p.Values) == 0 {
+			return nil, fmt.Errorf("parameter %v has no values", p.Name)
+		}
+		var next []Params
+		for _, c := range configs {
+			for _, v := range p.Values {
+				nc := Params{p.Name: v}
+				for k, v := range c {
+					nc[k] = v
+				}
+				next = append(next, nc)
+			}
+		}
+		configs = next
+	}
+	return configs, nil
+}
+
+// sample returns n random configurations of params.
+func sample(params []Param, n int, seed int64) []Params {
+	rnd := rand.New(rand.NewSource(seed))
+	configs := make([]Params, n)
+	for i := range configs {
+		c := Params{}
+		for _, p := range params {
+			if len(p.Values) > 0 {
+				c[p.Name] = p.Values[rnd.Intn(len(p.Values))]
+			} else {
+				c[p.Name] = p.Min + rnd.Float64()*(p.Max-p.Min)
+			}
+		}
+		configs[i] = c
+	}
+	return configs
+}
+
+// wilson returns the 95% Wilson score interval of the proportion of
+// successes in n trials.
+func wilson(successes, n int) (low, high float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	const z = 1.96
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	center := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	margin := z / (1 + z*z/nf) * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	low, high = center-margin, center+margin
+
+	// The limits are exact at the extremes, avoid rounding errors.
+	if successes == 0 {
+		low = 0
+	}
+	if successes == n {
+		high = 1
+	}
+	return low, high
+}
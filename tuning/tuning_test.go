@@ -0,0 +1,120 @@
+package tuning
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/internal/rtbtest"
+	"github.com/jroimartin/rtb/selfplay"
+)
+
+// turret returns a contender that rotates until the radar detects a robot and
+// then shoots it with the energy p["energy"].
+func turret(p Params) selfplay.Contender {
+	return selfplay.Contender{
+		Name: "turret",
+		New: func() rtb.Strategy {
+			return rtbtest.Turret{Speed: p["speed"], Energy: p["energy"]}
+		},
+	}
+}
+
+// ducks returns a contender that does nothing.
+func ducks() []selfplay.Contender {
+	return []selfplay.Contender{{
+		Name: "duck",
+		New: func() rtb.Strategy {
+			return rtb.StrategyFunc(func(r *rtb.Robot, msg rtb.Message) {})
+		},
+	}}
+}
+
+func TestGrid(t *testing.T) {
+	cfg := Config{
+		Params: []Param{
+			{Name: "energy", Values: []float64{0, 5}},
+			{Name: "speed", Values: []float64{0.5, 1}},
+		},
+		Candidate: turret,
+		Opponents: ducks,
+		Games:     2,
+		Workers:   2,
+	}
+	res, err := Run(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res) != 4 {
+		t.Fatalf("wrong number of results: got=%v want=4", len(res))
+	}
+	for i, r := range res {
+		wantWins := 0
+		if i < 2 {
+			wantWins = 2
+		}
+		if r.Games != 2 || r.Wins != wantWins {
+			t.Errorf("unexpected result %v: %+v", i, r)
+		}
+		if (r.Params["energy"] == 5) != (wantWins == 2) {
+			t.Errorf("wrong order: %+v", res)
+		}
+		if r.Low > r.WinRate || r.High < r.WinRate {
+			t.Errorf("win rate out of the confidence interval: %+v", r)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	params := []Param{
+		{Name: "energy", Values: []float64{1, 2}},
+		{Name: "speed", Min: 0.5, Max: 1},
+	}
+	configs := sample(params, 20, 1)
+	if len(configs) != 20 {
+		t.Fatalf("wrong number of configurations: got=%v want=20", len(configs))
+	}
+	for _, c := range configs {
+		if e := c["energy"]; e != 1 && e != 2 {
+			t.Errorf("wrong energy: %v", e)
+		}
+		if s := c["speed"]; s < 0.5 || s >= 1 {
+			t.Errorf("speed out of range: %v", s)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	cfg := Config{
+		Params:    []Param{{Name: "speed", Min: 0, Max: 1}},
+		Candidate: turret,
+		Opponents: ducks,
+		Games:     1,
+	}
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("expected error in grid search without values")
+	}
+
+	cfg.Games = 0
+	if _, err := Run(cfg); err == nil {
+		t.Errorf("expected error without games")
+	}
+}
+
+func TestWilson(t *testing.T) {
+	tests := []struct {
+		successes, n int
+		low, high    float64
+	}{
+		{0, 0, 0, 1},
+		{5, 10, 0.2366, 0.7634},
+		{10, 10, 0.7225, 1},
+		{0, 10, 0, 0.2775},
+	}
+	for _, tt := range tests {
+		low, high := wilson(tt.successes, tt.n)
+		if math.Abs(low-tt.low) > 1e-4 || math.Abs(high-tt.high) > 1e-4 {
+			t.Errorf("wrong interval for %v/%v: got=[%v, %v] want=[%v, %v]", tt.successes, tt.n, low, high, tt.low, tt.high)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package vgun implements a virtual gun: every tick, it fires hypothetical
+// shots at the tracked enemies using every motion model, checks them against
+// the later observations of the targets and selects the model with the best
+// hit rate for each enemy.
+//
+// Virtual shots cost nothing, so the statistics are gathered much faster
+// than with real shots, and they are not biased by the decisions of the fire
+// control.
+package vgun
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+// Model is a named motion model.
+type Model struct {
+	Name      string
+	Predictor track.Predictor
+}
+
+// DefaultModels returns the motion models provided by the track package.
+func DefaultModels() []Model {
+	return []Model{
+		{"linear", track.Linear{}},
+		{"circular", track.Circular{}},
+		{"pattern", track.Pattern{}},
+	}
+}
+
+// Config is the configuration of a Gun.
+type Config struct {
+	// Models are the evaluated motion models. If empty,
+	// DefaultModels is used.
+	Models []Model
+
+	// HitRadius is the maximum distance between the aimed point and the
+	// position of the target to consider a hit. If zero, 0.5 (the radius
+	// of a robot) is used.
+	HitRadius float64
+
+	// MinShots is the number of resolved shots per model needed to
+	// select a model. If zero, 5 is used.
+	MinShots int
+
+	// MaxAge is the time since the last observation of a target after
+	// which no virtual shots are fired at it. If zero, 0.5 is used.
+	MaxAge float64
+}
+
+// Stats are the statistics of a model against a target.
+type Stats struct {
+	Model string
+	Shots int
+	Hits  int
+}
+
+// HitRate returns the hit rate of the model. It is smoothed, so models
+// without shots have a rate of 0.5.
+func (s Stats) HitRate() float64 {
+	return (float64(s.Hits) + 1) / (float64(s.Shots) + 2)
+}
+
+// shot is a virtual shot.
+type shot struct {
+	target int
+	model  int
+	aim    arena.Point
+
+	// arrival is the game time when the shot reaches the aimed point.
+	arrival float64
+}
+
+// Gun is a virtual gun. It implements the rtb.Observer interface and must be
+// added to the robot after the world model and the tracker. Gun methods can
+// be called concurrently.
+type Gun struct {
+	rtb.NopCommand
+
+	cfg Config
+	w   *world.World
+	tr  *track.Tracker
+
+	mu    sync.Mutex
+	shots []shot
+	stats map[int][]Stats
+	best  map[int]int
+}
+
+// New returns a Gun that fires at the tracks of tr and selects their motion
+// models.
+func New(w *world.World, tr *track.Tracker, cfg Config) *Gun {
+	if len(cfg.Models) == 0 {
+		cfg.Models = DefaultModels()
+	}
+	if cfg.HitRadius == 0 {
+		cfg.HitRadius = 0.5
+	}
+	if cfg.MinShots == 0 {
+		cfg.MinShots = 5
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = 0.5
+	}
+	return &Gun{
+		cfg:   cfg,
+		w:     w,
+		tr:    tr,
+		stats: map[int][]Stats{},
+		best:  map[int]int{},
+	}
+}
+
+// Message fires and resolves virtual shots when msg is an Info message.
+func (g *Gun) Message(msg rtb.Message) {
+	switch m := msg.(type) {
+	case rtb.MessageGameStarts:
+		g.mu.Lock()
+		g.shots, g.stats, g.best = nil, map[int][]Stats{}, map[int]int{}
+		g.mu.Unlock()
+	case rtb.MessageInfo:
+		tracks := g.tr.Tracks()
+		g.resolve(tracks, m.Time)
+		g.fire(tracks, m.Time)
+	}
+}
+
+// fire fires a virtual shot per model at every enemy seen recently.
+func (g *Gun) fire(tracks []track.Track, now float64) {
+	speed, ok := g.w.Option(rtb.GOptionShotSpeed)
+	if !ok || speed <= 0 {
+		return
+	}
+	pos := g.w.State().Pos
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, t := range tracks {
+		if t.TeamMate || now-t.LastSeen > g.cfg.MaxAge {
+			continue
+		}
+		for i, m := range g.cfg.Models {
+			t.Predictor = m.Predictor
+			aim, tau, ok := t.Intercept(pos, now, speed)
+			if !ok {
+				continue
+			}
+			g.shots = append(g.shots, shot{target: t.ID, model: i, aim: aim, arrival: now + tau})
+		}
+	}
+}
+
+// resolve checks the virtual shots whose arrival time is covered by the
+// history of their targets.
+func (g *Gun) resolve(tracks []track.Track, now float64) {
+	byID := map[int]track.Track{}
+	for _, t := range tracks {
+		byID[t.ID] = t
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pending := g.shots[:0]
+	var updated []int
+	for _, s := range g.shots {
+		t, ok := byID[s.target]
+		if !ok {
+			// The track was discarded.
+			continue
+		}
+		if t.LastSeen < s.arrival {
+			pending = append(pending, s)
+			continue
+		}
+		pos, ok := positionAt(t.History, s.arrival)
+		if !ok {
+			// The history does not cover the arrival time.
+			continue
+		}
+
+		stats := g.targetStats(s.target)
+		stats[s.model].Shots++
+		if pos.Sub(s.aim).Len() <= g.cfg.HitRadius {
+			stats[s.model].Hits++
+		}
+		updated = append(updated, s.target)
+	}
+	g.shots = pending
+
+	for _, id := range updated {
+		g.selectModel(id)
+	}
+}
+
+// targetStats returns the statistics of a target, creating them if needed.
+// g.mu must be held.
+func (g *Gun) targetStats(id int) []Stats {
+	stats, ok := g.stats[id]
+	if !ok {
+		stats = make([]Stats, len(g.cfg.Models))
+		for i, m := range g.cfg.Models {
+			stats[i].Model = m.Name
+		}
+		g.stats[id] = stats
+	}
+	return stats
+}
+
+// selectModel selects the model with the best hit rate for a target and
+// sets it as the predictor of its track. g.mu must be held.
+func (g *Gun) selectModel(id int) {
+	stats := g.stats[id]
+	best := -1
+	for i, s := range stats {
+		if s.Shots < g.cfg.MinShots {
+			continue
+		}
+		if best < 0 || s.HitRate() > stats[best].HitRate() {
+			best = i
+		}
+	}
+	if best < 0 {
+		return
+	}
+	if cur, ok := g.best[id]; ok && cur == best {
+		return
+	}
+	g.best[id] = best
+	g.tr.SetPredictor(id, g.cfg.Models[best].Predictor)
+}
+
+// Stats returns the statistics of every model against a target, sorted by
+// hit rate in descending order.
+func (g *Gun) Stats(id int) []Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := append([]Stats(nil), g.stats[id]...)
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].HitRate() > stats[j].HitRate()
+	})
+	return stats
+}
+
+// Best returns the model selected for a target. It returns false if no model
+// has been selected yet.
+func (g *Gun) Best(id int) (Model, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	best, ok := g.best[id]
+	if !ok {
+		return Model{}, false
+	}
+	return g.cfg.Models[best], true
+}
+
+// positionAt returns the position at the given time, interpolating the
+// observations of a history. It returns false if time is out of the range of
+// the history.
+func positionAt(h []track.Observation, time float64) (arena.Point, bool) {
+	for i := 1; i < len(h); i++ {
+		a, b := h[i-1], h[i]
+		if time < a.Time || time > b.Time {
+			continue
+		}
+		if b.Time == a.Time {
+			return b.Pos, true
+		}
+		k := (time - a.Time) / (b.Time - a.Time)
+		return a.Pos.Add(b.Pos.Sub(a.Pos).Mul(k)), true
+	}
+	return arena.Point{}, false
+}
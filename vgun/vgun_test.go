@@ -0,0 +1,96 @@
+package vgun
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/track"
+	"github.com/jroimartin/rtb/world"
+)
+
+func TestGun(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  func(time float64) arena.Point
+		want string
+	}{
+		{
+			"Straight",
+			func(time float64) arena.Point { return arena.Point{X: 10 + time, Y: 5} },
+			"linear",
+		},
+		{
+			"Circle",
+			func(time float64) arena.Point { return arena.Point{X: 15, Y: 0}.Add(arena.Polar(time, 5)) },
+			"circular",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := world.New()
+			tr := track.New(w, track.Config{})
+			g := New(w, tr, Config{})
+			observers := []rtb.Observer{w, tr, g}
+
+			deliver := func(msg rtb.Message) {
+				for _, o := range observers {
+					o.Message(msg)
+				}
+			}
+			deliver(rtb.MessageGameOption{Option: rtb.GOptionShotSpeed, Value: 10})
+			deliver(rtb.MessageGameStarts{})
+			deliver(rtb.MessageCoordinates{})
+
+			for i := 0; i < 200; i++ {
+				time := float64(i) * 0.1
+				p := tt.pos(time)
+				deliver(rtb.MessageInfo{Time: time})
+				deliver(rtb.MessageRadar{Distance: p.Len(), Object: rtb.ObjectRobot, RadarAngle: math.Atan2(p.Y, p.X)})
+			}
+
+			m, ok := g.Best(1)
+			if !ok {
+				t.Fatalf("no model selected")
+			}
+			if m.Name != tt.want {
+				t.Errorf("unexpected model: got=%v want=%v (stats: %+v)", m.Name, tt.want, g.Stats(1))
+			}
+			if got := tr.Tracks()[0].Predictor; got != m.Predictor {
+				t.Errorf("unexpected track predictor: got=%T want=%T", got, m.Predictor)
+			}
+			if stats := g.Stats(1); stats[0].Model != tt.want || stats[0].Shots < 5 {
+				t.Errorf("unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestPositionAt(t *testing.T) {
+	h := []track.Observation{
+		{Time: 0, Pos: arena.Point{X: 0, Y: 0}},
+		{Time: 1, Pos: arena.Point{X: 2, Y: 0}},
+		{Time: 3, Pos: arena.Point{X: 2, Y: 4}},
+	}
+
+	tests := []struct {
+		time   float64
+		want   arena.Point
+		wantOk bool
+	}{
+		{0.5, arena.Point{X: 1, Y: 0}, true},
+		{2, arena.Point{X: 2, Y: 2}, true},
+		{3, arena.Point{X: 2, Y: 4}, true},
+		{4, arena.Point{}, false},
+		{-1, arena.Point{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := positionAt(h, tt.time)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("unexpected position at %v: got=%v, %v want=%v, %v", tt.time, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package rtb
+
+import (
+	"context"
+	"errors"
+)
+
+// rotationWaiter is a rotation waiting for a RotationReached message.
+type rotationWaiter struct {
+	// remaining are the parts that have not reached the angle yet.
+	remaining Part
+
+	c    chan error
+	done chan struct{}
+}
+
+// RotateToAndWait is like RotateTo, but returns a channel that receives nil
+// when the server reports that the rotation has finished. If ctx is done
+// before, the channel receives the error of the context. If another
+// rotation of the same parts is requested with RotateToAndWait or
+// RotateAmountAndWait, the channel receives ErrRotationSuperseded. If the
+// command cannot be sent, the channel receives the error.
+//
+// The server only reports finished rotations if SendRotationReached is
+// enabled in ListenSettings. Messages are delivered by the goroutine that
+// calls the strategy, so the channel must not be waited from the Handle
+// method of the strategy.
+func (r *Robot) RotateToAndWait(ctx context.Context, what Part, v, end float64) <-chan error {
+	return r.rotateAndWait(ctx, what, func() error { return r.RotateTo(what, v, end) })
+}
+
+// RotateToAndWait calls RotateToAndWait on the default Robot.
+func RotateToAndWait(ctx context.Context, what Part, v, end float64) <-chan error {
+	return std.RotateToAndWait(ctx, what, v, end)
+}
+
+// RotateAmountAndWait is like RotateAmount, but returns a channel that
+// receives the result of the rotation like RotateToAndWait.
+func (r *Robot) RotateAmountAndWait(ctx context.Context, what Part, v, angle float64) <-chan error {
+	return r.rotateAndWait(ctx, what, func() error { return r.RotateAmount(what, v, angle) })
+}
+
+// RotateAmountAndWait calls RotateAmountAndWait on the default Robot.
+func RotateAmountAndWait(ctx context.Context, what Part, v, angle float64) <-chan error {
+	return std.RotateAmountAndWait(ctx, what, v, angle)
+}
+
+// rotateAndWait registers a waiter for the parts in what and sends the
+// rotation command.
+func (r *Robot) rotateAndWait(ctx context.Context, what Part, send func() error) <-chan error {
+	w := &rotationWaiter{remaining: what, c: make(chan error, 1), done: make(chan struct{})}
+
+	r.mu.Lock()
+	var superseded []*rotationWaiter
+	for _, o := range r.waiters {
+		if o.remaining&what != 0 {
+			superseded = append(superseded, o)
+		}
+	}
+	r.waiters = append(r.waiters, w)
+	r.mu.Unlock()
+
+	for _, o := range superseded {
+		r.resolve(o, ErrRotationSuperseded)
+	}
+
+	// Out of range values are clamped, but the command is sent.
+	var errRange ErrOutOfRange
+	if err := send(); err != nil && !(errors.As(err, &errRange) && errRange.Sent) {
+		r.resolve(w, err)
+		return w.c
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.resolve(w, ctx.Err())
+		case <-w.done:
+		}
+	}()
+
+	return w.c
+}
+
+// resolve sends err to the channel of w and removes it from the waiters. It
+// does nothing if w is already resolved.
+func (r *Robot) resolve(w *rotationWaiter, err error) {
+	r.mu.Lock()
+	found := false
+	for i, o := range r.waiters {
+		if o == w {
+			// waiters is copied, like observers, because it
+			// could be being iterated.
+			r.waiters = append(r.waiters[:i:i], r.waiters[i+1:]...)
+			found = true
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if !found {
+		return
+	}
+	w.c <- err
+	close(w.done)
+}
+
+// rotationReached resolves the waiters whose parts have all reached their
+// angle.
+func (r *Robot) rotationReached(p Part) {
+	r.mu.Lock()
+	var reached []*rotationWaiter
+	for _, w := range r.waiters {
+		if w.remaining&p == 0 {
+			continue
+		}
+		w.remaining &^= p
+		if w.remaining == 0 {
+			reached = append(reached, w)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, w := range reached {
+		r.resolve(w, nil)
+	}
+}
@@ -0,0 +1,137 @@
+package rtb
+
+// WarningAction is an action taken when the server sends a warning.
+// WarningAction values can be or'ed to take several actions.
+type WarningAction int
+
+const (
+	// WarningLog logs the warning with the logger of the robot.
+	WarningLog WarningAction = 1 << iota
+
+	// WarningEmit sends an ErrWarning to the channel returned by
+	// Robot.Errors.
+	WarningEmit
+
+	// WarningCallback calls the callback of the policy.
+	WarningCallback
+
+	// WarningResend sends again the name of the robot on
+	// WarningNameNotGiven and its colours on WarningColourNotGiven. It
+	// has no effect on other warnings.
+	WarningResend
+)
+
+// WarningPolicy decides the actions taken when the server sends a warning.
+// The actions are taken before the warning is passed to the strategy.
+type WarningPolicy struct {
+	// Actions are the actions taken per warning type.
+	Actions map[Warning]WarningAction
+
+	// Default are the actions taken for the warning types not in
+	// Actions.
+	Default WarningAction
+
+	// Callback is called for the warnings with the WarningCallback
+	// action.
+	Callback func(r *Robot, msg MessageWarning)
+}
+
+// DefaultWarningPolicy returns the policy used by robots without a policy.
+// It logs all warnings. Resending the name and the colours of the robot must
+// be enabled explicitly with WarningResend:
+//
+//	p := rtb.DefaultWarningPolicy()
+//	p.Actions = map[rtb.Warning]rtb.WarningAction{
+//		rtb.WarningNameNotGiven:   rtb.WarningLog | rtb.WarningResend,
+//		rtb.WarningColourNotGiven: rtb.WarningLog | rtb.WarningResend,
+//	}
+//	r.SetWarningPolicy(p)
+func DefaultWarningPolicy() WarningPolicy {
+	return WarningPolicy{Default: WarningLog}
+}
+
+// action returns the actions for a warning type.
+func (p WarningPolicy) action(w Warning) WarningAction {
+	if a, ok := p.Actions[w]; ok {
+		return a
+	}
+	return p.Default
+}
+
+// SetWarningPolicy sets the warning policy of r.
+func (r *Robot) SetWarningPolicy(p WarningPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.warnPolicy = &p
+}
+
+// SetWarningPolicy calls SetWarningPolicy on the default Robot.
+func SetWarningPolicy(p WarningPolicy) {
+	std.SetWarningPolicy(p)
+}
+
+// Errors returns a channel that receives the warnings with the WarningEmit
+// action as ErrWarning values and the lines discarded by Listen as errors
+// wrapping ErrLineTooLong. Errors are dropped if the channel is full, so the
+// robot is never blocked.
+func (r *Robot) Errors() <-chan error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.errs == nil {
+		r.errs = make(chan error, 16)
+	}
+	return r.errs
+}
+
+// Errors calls Errors on the default Robot.
+func Errors() <-chan error {
+	return std.Errors()
+}
+
+// warn takes the actions of the warning policy for msg.
+func (r *Robot) warn(msg MessageWarning) {
+	r.mu.Lock()
+	p := DefaultWarningPolicy()
+	if r.warnPolicy != nil {
+		p = *r.warnPolicy
+	}
+	name, home, away := r.name, r.homeColour, r.awayColour
+	r.mu.Unlock()
+
+	a := p.action(msg.Warning)
+	if a&WarningLog != 0 {
+		r.Logger().Warn("server warning", "warning", msg.Warning, "text", msg.Message)
+	}
+	if a&WarningEmit != 0 {
+		r.emit(ErrWarning{Warning: msg.Warning, Message: msg.Message})
+	}
+	if a&WarningResend != 0 {
+		switch {
+		case msg.Warning == WarningNameNotGiven && name != "":
+			r.Name(name)
+		case msg.Warning == WarningColourNotGiven && home != "":
+			r.Colour(home, away)
+		}
+	}
+	if a&WarningCallback != 0 && p.Callback != nil {
+		p.Callback(r, msg)
+	}
+}
+
+// emit sends err to the channel returned by Errors, if it has been created.
+// err is dropped if the channel is full.
+func (r *Robot) emit(err error) {
+	r.mu.Lock()
+	errs := r.errs
+	r.mu.Unlock()
+
+	if errs == nil {
+		return
+	}
+	select {
+	case errs <- err:
+	default:
+	}
+}
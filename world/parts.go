@@ -0,0 +1,193 @@
+package world
+
+import (
+	"math"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// partMode is the kind of rotation of a part of the robot.
+type partMode int
+
+const (
+	partIdle partMode = iota
+	partSpeed
+	partTo
+	partSweep
+)
+
+// parts are the parts of the robot, in the order of World.parts.
+var parts = [...]rtb.Part{rtb.PartRobot, rtb.PartCannon, rtb.PartRadar}
+
+// maxRotateOptions are the game options limiting the rotation speed of the
+// parts, in the order of World.parts.
+var maxRotateOptions = [...]rtb.GOption{rtb.GOptionRobotMaxRotate, rtb.GOptionRobotCannonMaxRotate, rtb.GOptionRobotRadarMaxRotate}
+
+// part is the rotation state of a part of the robot. It follows the
+// behavior of the server.
+type part struct {
+	mode partMode
+
+	// speed is the rotation speed. It is positive for RotateTo, unless
+	// the part rotates towards the target in the negative direction,
+	// and gives the direction of the sweep for Sweep.
+	speed float64
+
+	// target is the commanded angle of a RotateTo or RotateAmount.
+	target float64
+
+	// right and left are the limits of a Sweep.
+	right, left float64
+
+	// angle is the estimated angle of the part. It is relative to the
+	// robot, except for the robot itself. It is not normalized, so
+	// RotateAmount targets work across turns.
+	angle float64
+}
+
+// advance estimates the angle of the part after dt.
+func (p *part) advance(dt float64) {
+	switch p.mode {
+	case partSpeed:
+		p.angle += p.speed * dt
+	case partTo:
+		d := p.target - p.angle
+		step := math.Abs(p.speed) * dt
+		if math.Abs(d) <= step {
+			p.angle, p.mode = p.target, partIdle
+			return
+		}
+		p.angle += math.Copysign(step, d)
+	case partSweep:
+		if p.speed == 0 || p.left <= p.right {
+			return
+		}
+		// The part bounces at the limits, so the time spent after
+		// reaching a limit is used to go back.
+		for dt > 0 {
+			limit := p.left
+			if p.speed < 0 {
+				limit = p.right
+			}
+			t := (limit - p.angle) / p.speed
+			if t > dt {
+				p.angle += p.speed * dt
+				return
+			}
+			t = math.Max(t, 0)
+			p.angle, p.speed, dt = limit, -p.speed, dt-t
+		}
+	}
+}
+
+// reached updates the part when the server reports that a rotation has
+// finished.
+func (p *part) reached() {
+	switch p.mode {
+	case partTo:
+		p.angle, p.mode = p.target, partIdle
+	case partSweep:
+		if p.speed > 0 {
+			p.angle = p.left
+		} else {
+			p.angle = p.right
+		}
+		p.speed = -p.speed
+	}
+}
+
+// remaining returns the time left to finish the rotation. It is infinite
+// for rotations that do not finish.
+func (p *part) remaining() float64 {
+	switch {
+	case p.mode == partIdle:
+		return 0
+	case p.speed == 0:
+		return math.Inf(1)
+	case p.mode == partTo:
+		return math.Abs(p.target-p.angle) / math.Abs(p.speed)
+	default:
+		return math.Inf(1)
+	}
+}
+
+// partIndex returns the index of a single part in World.parts.
+func partIndex(p rtb.Part) (int, bool) {
+	for i, q := range parts {
+		if p == q {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// CannonAbsoluteAngle returns the estimated angle of the cannon in world
+// coordinates.
+func (w *World) CannonAbsoluteAngle() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return physics.NormalizeAngle(w.state.Heading + w.parts[1].angle)
+}
+
+// RadarAbsoluteAngle returns the estimated angle of the radar in world
+// coordinates. Unlike State.RadarAngle, it is estimated between Radar
+// messages.
+func (w *World) RadarAbsoluteAngle() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return physics.NormalizeAngle(w.state.Heading + w.parts[2].angle)
+}
+
+// PartAngle returns the estimated angle of a single part. The angles of the
+// cannon and the radar are relative to the robot front. The angle of the
+// robot is its heading.
+func (w *World) PartAngle(p rtb.Part) float64 {
+	i, ok := partIndex(p)
+	if !ok {
+		return math.NaN()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if i == 0 {
+		return w.state.Heading
+	}
+	return physics.NormalizeAngle(w.parts[i].angle)
+}
+
+// CommandedAngle returns the target angle of the RotateTo or RotateAmount in
+// progress for a single part. It returns false if the part is not rotating
+// to an angle.
+func (w *World) CommandedAngle(p rtb.Part) (float64, bool) {
+	i, ok := partIndex(p)
+	if !ok {
+		return 0, false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.parts[i].mode != partTo {
+		return 0, false
+	}
+	return physics.NormalizeAngle(w.parts[i].target), true
+}
+
+// RotationTime returns the estimated time left to finish the rotation of a
+// single part. It is zero if the part is not rotating and infinite if the
+// rotation does not finish, like Rotate and Sweep.
+func (w *World) RotationTime(p rtb.Part) float64 {
+	i, ok := partIndex(p)
+	if !ok {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.parts[i].remaining()
+}
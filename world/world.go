@@ -0,0 +1,235 @@
+// Package world implements a model of the state of a robot in the arena,
+// built from the messages it receives and the commands it sends.
+//
+// The model is usually attached to a robot as an observer, so it is kept up
+// to date without the help of the strategy:
+//
+//	w := world.New()
+//	r.AddObserver(w)
+package world
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+	"github.com/jroimartin/rtb/physics"
+)
+
+// State is the state of the robot.
+type State struct {
+	// Time is the game time of the last Info message.
+	Time float64
+
+	// Pos is the position of the robot. If Exact is false, it is
+	// estimated by dead reckoning from the start position, which is
+	// considered (0, 0).
+	Pos arena.Point
+
+	// Heading is the angle of the robot in radians. If Exact is false, it
+	// is estimated by dead reckoning from the initial heading, which is
+	// considered 0.
+	Heading float64
+
+	// Exact is true if Pos and Heading were reported by the server in a
+	// Coordinates message.
+	Exact bool
+
+	// Speed is the speed of the robot.
+	Speed float64
+
+	// CannonAngle is the angle of the cannon relative to the robot front.
+	CannonAngle float64
+
+	// RadarAngle is the angle of the radar relative to the robot front,
+	// as reported by the last Radar message.
+	RadarAngle float64
+
+	// Energy is the energy level of the robot.
+	Energy float64
+
+	// RobotsLeft is the number of robots left in the game.
+	RobotsLeft int
+
+	// Dead is true if the robot is dead.
+	Dead bool
+}
+
+// World is a model of the state of a robot. It implements the rtb.Observer
+// interface. World methods can be called concurrently.
+type World struct {
+	mu      sync.Mutex
+	state   State
+	options map[rtb.GOption]float64
+
+	// parts is the rotation state of the robot, the cannon and the
+	// radar. The angle of the robot is only used to estimate the heading
+	// when the server does not send coordinates.
+	parts [len(parts)]part
+}
+
+// New returns a new World.
+func New() *World {
+	return &World{options: map[rtb.GOption]float64{}}
+}
+
+// State returns the current state of the robot.
+func (w *World) State() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.state
+}
+
+// Option returns the value of a game option. It returns false if the option
+// has not been received.
+func (w *World) Option(opt rtb.GOption) (float64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	v, ok := w.options[opt]
+	return v, ok
+}
+
+// Relative returns the angle, relative to the robot front, and the distance
+// from the robot to p. This is the form expected by DebugLine and
+// DebugCircle.
+func (w *World) Relative(p arena.Point) (angle, radius float64) {
+	s := w.State()
+	d := p.Sub(s.Pos)
+	return physics.NormalizeAngle(math.Atan2(d.Y, d.X) - s.Heading), d.Len()
+}
+
+// Absolute returns the point at the given angle, relative to the robot
+// front, and distance from the robot. It is the inverse of Relative.
+func (w *World) Absolute(angle, radius float64) arena.Point {
+	s := w.State()
+	return s.Pos.Add(arena.Polar(s.Heading+angle, radius))
+}
+
+// Message updates the model with a message received from the server.
+func (w *World) Message(msg rtb.Message) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch m := msg.(type) {
+	case rtb.MessageGameOption:
+		w.options[m.Option] = m.Value
+	case rtb.MessageGameStarts:
+		w.state = State{Energy: w.options[rtb.GOptionRobotStartEnergy]}
+		w.parts = [len(parts)]part{}
+	case rtb.MessageInfo:
+		if dt := m.Time - w.state.Time; dt > 0 {
+			w.advance(dt)
+		}
+		w.state.Time, w.state.Speed, w.state.CannonAngle = m.Time, m.Speed, m.CannonAngle
+		w.parts[1].angle = m.CannonAngle
+	case rtb.MessageCoordinates:
+		w.state.Pos = arena.Point{X: m.X, Y: m.Y}
+		w.state.Heading = m.Angle
+		w.state.Exact = true
+		// The target of a rotation in progress is kept relative to
+		// the exact heading.
+		robot := &w.parts[0]
+		robot.target += m.Angle - robot.angle
+		robot.angle = m.Angle
+	case rtb.MessageRadar:
+		w.state.RadarAngle = m.RadarAngle
+		w.parts[2].angle = m.RadarAngle
+	case rtb.MessageRotationReached:
+		for i, p := range parts {
+			if m.Part&p != 0 {
+				w.parts[i].reached()
+			}
+		}
+	case rtb.MessageEnergy:
+		w.state.Energy = m.EnergyLevel
+	case rtb.MessageRobotsLeft:
+		w.state.RobotsLeft = m.NumRobots
+	case rtb.MessageDead:
+		w.state.Dead = true
+	}
+}
+
+// advance estimates the state of the robot after dt. The position and the
+// heading are only estimated if the server does not send coordinates. w.mu
+// must be held.
+func (w *World) advance(dt float64) {
+	for i := range w.parts {
+		w.parts[i].advance(dt)
+	}
+	if w.state.Exact {
+		return
+	}
+	w.state.Heading = physics.NormalizeAngle(w.parts[0].angle)
+	w.state.Pos = w.state.Pos.Add(arena.Polar(w.state.Heading, w.state.Speed*dt))
+}
+
+// Command updates the model with a command sent to the server. It is used to
+// estimate the rotation of the parts of the robot and, when the server does
+// not send coordinates, the heading of the robot.
+func (w *World) Command(cmd string) {
+	var (
+		what    rtb.Part
+		v, a, b float64
+		keyword string
+	)
+	if _, err := fmt.Sscanf(cmd, "%s", &keyword); err != nil {
+		return
+	}
+	switch keyword {
+	case "Rotate":
+		if _, err := fmt.Sscanf(cmd, "Rotate %d %g", &what, &v); err != nil {
+			return
+		}
+	case "RotateTo":
+		if _, err := fmt.Sscanf(cmd, "RotateTo %d %g %g", &what, &v, &a); err != nil {
+			return
+		}
+		// The server does not allow to rotate the robot to an angle.
+		what &^= rtb.PartRobot
+	case "RotateAmount":
+		if _, err := fmt.Sscanf(cmd, "RotateAmount %d %g %g", &what, &v, &a); err != nil {
+			return
+		}
+	case "Sweep":
+		if _, err := fmt.Sscanf(cmd, "Sweep %d %g %g %g", &what, &v, &a, &b); err != nil {
+			return
+		}
+		// The robot cannot sweep.
+		what &^= rtb.PartRobot
+	default:
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, p := range parts {
+		if what&p == 0 {
+			continue
+		}
+		speed := v
+		if max, ok := w.options[maxRotateOptions[i]]; ok {
+			speed = math.Max(-max, math.Min(speed, max))
+		}
+
+		pt := &w.parts[i]
+		switch keyword {
+		case "Rotate":
+			pt.mode, pt.speed = partSpeed, speed
+		case "RotateTo":
+			pt.mode, pt.speed, pt.target = partTo, math.Abs(speed), a
+		case "RotateAmount":
+			pt.mode, pt.speed, pt.target = partTo, math.Abs(speed), pt.angle+a
+		case "Sweep":
+			right, left := a, b
+			if right > left {
+				right, left = left, right
+			}
+			pt.mode, pt.speed, pt.right, pt.left = partSweep, math.Abs(speed), right, left
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jroimartin/rtb"
+	"github.com/jroimartin/rtb/arena"
+)
+
+func TestWorld(t *testing.T) {
+	tests := []struct {
+		name        string
+		msgs        []rtb.Message
+		cmds        []string
+		wantPos     arena.Point
+		wantHeading float64
+		wantExact   bool
+	}{
+		{
+			"Straight",
+			[]rtb.Message{
+				rtb.MessageGameStarts{},
+				rtb.MessageInfo{Time: 0, Speed: 2},
+				rtb.MessageInfo{Time: 1, Speed: 2},
+				rtb.MessageInfo{Time: 2, Speed: 2},
+			},
+			nil,
+			arena.Point{X: 4, Y: 0},
+			0,
+			false,
+		},
+		{
+			"Rotate",
+			[]rtb.Message{
+				rtb.MessageGameOption{Option: rtb.GOptionRobotMaxRotate, Value: math.Pi / 4},
+				rtb.MessageGameStarts{},
+				rtb.MessageInfo{Time: 0, Speed: 0},
+				rtb.MessageInfo{Time: 1, Speed: 0},
+			},
+			[]string{"Rotate 1 1.000000"},
+			arena.Point{},
+			math.Pi / 4,
+			false,
+		},
+		{
+			"RotateAmount",
+			[]rtb.Message{
+				rtb.MessageGameStarts{},
+				rtb.MessageInfo{Time: 0, Speed: 0},
+				rtb.MessageInfo{Time: 1, Speed: 0},
+				rtb.MessageInfo{Time: 2, Speed: 0},
+			},
+			[]string{"RotateAmount 1 1.000000 -1.500000"},
+			arena.Point{},
+			-1.5,
+			false,
+		},
+		{
+			"Coordinates",
+			[]rtb.Message{
+				rtb.MessageGameStarts{},
+				rtb.MessageInfo{Time: 0, Speed: 5},
+				rtb.MessageCoordinates{X: 10, Y: 20, Angle: 1},
+				rtb.MessageInfo{Time: 1, Speed: 5},
+			},
+			nil,
+			arena.Point{X: 10, Y: 20},
+			1,
+			true,
+		},
+		{
+			"Cannon ignored",
+			[]rtb.Message{
+				rtb.MessageGameStarts{},
+				rtb.MessageInfo{Time: 0, Speed: 0},
+				rtb.MessageInfo{Time: 1, Speed: 0},
+			},
+			[]string{"Rotate 2 1.000000"},
+			arena.Point{},
+			0,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New()
+			for i, msg := range tt.msgs {
+				w.Message(msg)
+				// Commands are sent after the second message.
+				if i == 1 {
+					for _, cmd := range tt.cmds {
+						w.Command(cmd)
+					}
+				}
+			}
+
+			s := w.State()
+			if s.Pos.Sub(tt.wantPos).Len() > 1e-9 {
+				t.Errorf("unexpected position: got=%v want=%v", s.Pos, tt.wantPos)
+			}
+			if math.Abs(s.Heading-tt.wantHeading) > 1e-9 {
+				t.Errorf("unexpected heading: got=%v want=%v", s.Heading, tt.wantHeading)
+			}
+			if s.Exact != tt.wantExact {
+				t.Errorf("unexpected exact: got=%v want=%v", s.Exact, tt.wantExact)
+			}
+		})
+	}
+}
+
+func TestRelative(t *testing.T) {
+	w := New()
+	w.Message(rtb.MessageGameStarts{})
+	w.Message(rtb.MessageCoordinates{X: 1, Y: 1, Angle: math.Pi / 2})
+
+	angle, radius := w.Relative(arena.Point{X: 3, Y: 1})
+	if math.Abs(angle+math.Pi/2) > 1e-9 || math.Abs(radius-2) > 1e-9 {
+		t.Errorf("unexpected relative position: got=(%v, %v) want=(%v, %v)", angle, radius, -math.Pi/2, 2)
+	}
+
+	p := w.Absolute(angle, radius)
+	if p.Sub(arena.Point{X: 3, Y: 1}).Len() > 1e-9 {
+		t.Errorf("unexpected absolute position: got=%v want=%v", p, arena.Point{X: 3, Y: 1})
+	}
+}
+
+func TestState(t *testing.T) {
+	w := New()
+	msgs := []rtb.Message{
+		rtb.MessageGameOption{Option: rtb.GOptionRobotStartEnergy, Value: 100},
+		rtb.MessageGameStarts{},
+		rtb.MessageRobotsLeft{NumRobots: 3},
+		rtb.MessageRadar{Distance: 5, Object: rtb.ObjectWall, RadarAngle: 0.5},
+		rtb.MessageInfo{Time: 1, Speed: 0, CannonAngle: 0.25},
+		rtb.MessageEnergy{EnergyLevel: 80},
+		rtb.MessageDead{},
+	}
+	for _, msg := range msgs {
+		w.Message(msg)
+	}
+
+	want := State{
+		Time:        1,
+		CannonAngle: 0.25,
+		RadarAngle:  0.5,
+		Energy:      80,
+		RobotsLeft:  3,
+		Dead:        true,
+	}
+	if got := w.State(); got != want {
+		t.Errorf("unexpected state: got=%+v want=%+v", got, want)
+	}
+
+	if v, ok := w.Option(rtb.GOptionRobotStartEnergy); !ok || v != 100 {
+		t.Errorf("unexpected option: got=%v, %v want=%v, %v", v, ok, 100, true)
+	}
+}
+
+func TestParts(t *testing.T) {
+	w := New()
+	w.Message(rtb.MessageGameOption{Option: rtb.GOptionRobotRadarMaxRotate, Value: 1})
+	w.Message(rtb.MessageGameStarts{})
+	w.Message(rtb.MessageCoordinates{Angle: math.Pi / 2})
+	w.Message(rtb.MessageInfo{Time: 0})
+
+	w.Command("RotateTo 2 0.5 1")
+	w.Command("Sweep 4 2 -0.5 0.5")
+	if got := w.RotationTime(rtb.PartCannon); math.Abs(got-2) > 1e-9 {
+		t.Errorf("unexpected rotation time: got=%v want=%v", got, 2)
+	}
+	if got := w.RotationTime(rtb.PartRadar); !math.IsInf(got, 1) {
+		t.Errorf("unexpected sweep rotation time: got=%v want=%v", got, math.Inf(1))
+	}
+	if got, ok := w.CommandedAngle(rtb.PartCannon); !ok || got != 1 {
+		t.Errorf("unexpected commanded angle: got=%v, %v want=%v, %v", got, ok, 1, true)
+	}
+
+	// The radar speed is clamped to 1, so it reaches the left limit
+	// after 0.5s and goes back.
+	w.Message(rtb.MessageInfo{Time: 1, CannonAngle: 0.5})
+	if got, want := w.CannonAbsoluteAngle(), math.Pi/2+0.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected cannon angle: got=%v want=%v", got, want)
+	}
+	if got, want := w.RadarAbsoluteAngle(), math.Pi/2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("unexpected radar angle: got=%v want=%v", got, want)
+	}
+
+	w.Message(rtb.MessageRotationReached{Part: rtb.PartCannon})
+	if got := w.RotationTime(rtb.PartCannon); got != 0 {
+		t.Errorf("unexpected rotation time after reached: got=%v want=%v", got, 0)
+	}
+	if got := w.PartAngle(rtb.PartCannon); got != 1 {
+		t.Errorf("unexpected cannon angle after reached: got=%v want=%v", got, 1)
+	}
+	if _, ok := w.CommandedAngle(rtb.PartCannon); ok {
+		t.Errorf("unexpected commanded angle after reached")
+	}
+}